@@ -6,6 +6,7 @@ package main
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -17,12 +18,27 @@ import (
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/utils"
 )
 
 func agentCmd() {
+	if len(os.Args) > 2 {
+		switch os.Args[2] {
+		case "export":
+			agentExportCmd(os.Args[3:])
+			return
+		case "import":
+			agentImportCmd(os.Args[3:])
+			return
+		}
+	}
+
 	message := ""
 	sessionKey := "cli:default"
 	modelOverride := ""
+	trace := false
+	explain := false
+	var files []string
 
 	args := os.Args[2:]
 	for i := 0; i < len(args); i++ {
@@ -30,6 +46,10 @@ func agentCmd() {
 		case "--debug", "-d":
 			logger.SetLevel(logger.DEBUG)
 			fmt.Println("🔍 Debug mode enabled")
+		case "--trace":
+			trace = true
+		case "--explain-context":
+			explain = true
 		case "-m", "--message":
 			if i+1 < len(args) {
 				message = args[i+1]
@@ -40,6 +60,11 @@ func agentCmd() {
 				sessionKey = args[i+1]
 				i++
 			}
+		case "-f", "--file":
+			if i+1 < len(args) {
+				files = append(files, args[i+1])
+				i++
+			}
 		case "--model", "-model":
 			if i+1 < len(args) {
 				modelOverride = args[i+1]
@@ -54,6 +79,25 @@ func agentCmd() {
 		os.Exit(1)
 	}
 
+	// Hold the workspace lock while running so a gateway can't start on the
+	// same workspace. If another instance already holds it, refuse to run:
+	// both would write the same session and memory files.
+	if workspaceLock, err := utils.LockWorkspace(cfg.WorkspacePath()); err == nil {
+		defer workspaceLock.Unlock()
+	} else if errors.Is(err, utils.ErrWorkspaceLocked) {
+		fmt.Printf("Error: %s\n", lockHolderMessage(cfg.WorkspacePath()))
+		os.Exit(1)
+	}
+
+	setupCrashReporting(cfg)
+	setupLogging(cfg)
+	setupNetwork(cfg)
+	setupStorage(cfg)
+	setupEncryption(cfg)
+	if trace {
+		enableProviderTrace(cfg)
+	}
+
 	if modelOverride != "" {
 		cfg.Agents.Defaults.Model = modelOverride
 	}
@@ -63,11 +107,14 @@ func agentCmd() {
 		fmt.Printf("Error creating provider: %v\n", err)
 		os.Exit(1)
 	}
+	defer providers.StopCopilotBridges()
 	// Use the resolved model ID from provider creation
 	if modelID != "" {
 		cfg.Agents.Defaults.Model = modelID
 	}
 
+	prewarmProvider(context.Background(), cfg, provider)
+
 	msgBus := bus.NewMessageBus()
 	agentLoop := agent.NewAgentLoop(cfg, msgBus, provider)
 
@@ -82,19 +129,30 @@ func agentCmd() {
 
 	if message != "" {
 		ctx := context.Background()
-		response, err := agentLoop.ProcessDirect(ctx, message, sessionKey)
+		response, err := agentLoop.ProcessDirectWithMedia(ctx, message, sessionKey, "cli", files)
 		if err != nil {
 			fmt.Printf("Error: %v\n", err)
 			os.Exit(1)
 		}
 		fmt.Printf("\n%s %s\n", logo, response)
+		if explain {
+			printContext(agentLoop, sessionKey)
+		}
 	} else {
 		fmt.Printf("%s Interactive mode (Ctrl+C to exit)\n\n", logo)
-		interactiveMode(agentLoop, sessionKey)
+		interactiveMode(agentLoop, sessionKey, explain)
 	}
 }
 
-func interactiveMode(agentLoop *agent.AgentLoop, sessionKey string) {
+// printContext dumps what was sent to the model for the last turn to
+// stderr, so it does not mix with the response on stdout.
+func printContext(agentLoop *agent.AgentLoop, sessionKey string) {
+	if report, ok := agentLoop.DirectContext(sessionKey); ok {
+		fmt.Fprintf(os.Stderr, "\n%s\n", report.Format(true))
+	}
+}
+
+func interactiveMode(agentLoop *agent.AgentLoop, sessionKey string, explain bool) {
 	prompt := fmt.Sprintf("%s You: ", logo)
 
 	rl, err := readline.NewEx(&readline.Config{
@@ -108,7 +166,7 @@ func interactiveMode(agentLoop *agent.AgentLoop, sessionKey string) {
 	if err != nil {
 		fmt.Printf("Error initializing readline: %v\n", err)
 		fmt.Println("Falling back to simple input mode...")
-		simpleInteractiveMode(agentLoop, sessionKey)
+		simpleInteractiveMode(agentLoop, sessionKey, explain)
 		return
 	}
 	defer rl.Close()
@@ -142,10 +200,13 @@ func interactiveMode(agentLoop *agent.AgentLoop, sessionKey string) {
 		}
 
 		fmt.Printf("\n%s %s\n\n", logo, response)
+		if explain {
+			printContext(agentLoop, sessionKey)
+		}
 	}
 }
 
-func simpleInteractiveMode(agentLoop *agent.AgentLoop, sessionKey string) {
+func simpleInteractiveMode(agentLoop *agent.AgentLoop, sessionKey string, explain bool) {
 	reader := bufio.NewReader(os.Stdin)
 	for {
 		fmt.Print(fmt.Sprintf("%s You: ", logo))
@@ -177,5 +238,8 @@ func simpleInteractiveMode(agentLoop *agent.AgentLoop, sessionKey string) {
 		}
 
 		fmt.Printf("\n%s %s\n\n", logo, response)
+		if explain {
+			printContext(agentLoop, sessionKey)
+		}
 	}
 }
@@ -0,0 +1,186 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/agent"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// agentExportCmd writes an agent's bundle, which holds its prompt,
+// skills, tools and models but no keys, to a file or stdout.
+func agentExportCmd(args []string) {
+	agentID := ""
+	output := ""
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-o", "--output":
+			if i+1 < len(args) {
+				output = args[i+1]
+				i++
+			}
+		case "-h", "--help":
+			agentBundleHelp()
+			return
+		default:
+			agentID = args[i]
+		}
+	}
+	if agentID == "" {
+		agentBundleHelp()
+		return
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	setupNetwork(cfg)
+	setupStorage(cfg)
+	setupEncryption(cfg)
+	bundle, err := agent.ExportBundle(cfg, agentID)
+	if err != nil {
+		fmt.Printf("Error exporting agent: %v\n", err)
+		os.Exit(1)
+	}
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		fmt.Printf("Error encoding bundle: %v\n", err)
+		os.Exit(1)
+	}
+	if output == "" {
+		fmt.Println(string(data))
+		return
+	}
+	if err := os.WriteFile(output, append(data, '\n'), 0644); err != nil {
+		fmt.Printf("Error writing bundle: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Exported agent %s to %s (%d prompt files, %d skill files, %d models)\n",
+		bundle.ID, output, len(bundle.Prompt), len(bundle.Skills), len(bundle.Models))
+}
+
+// agentImportCmd adds the agent of a bundle to the config and writes its
+// files to its workspace.
+func agentImportCmd(args []string) {
+	file := ""
+	agentID := ""
+	workspace := ""
+	yes := false
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-y", "--yes":
+			yes = true
+		case "--id":
+			if i+1 < len(args) {
+				agentID = args[i+1]
+				i++
+			}
+		case "-w", "--workspace":
+			if i+1 < len(args) {
+				workspace = args[i+1]
+				i++
+			}
+		case "-h", "--help":
+			agentBundleHelp()
+			return
+		default:
+			file = args[i]
+		}
+	}
+	if file == "" {
+		agentBundleHelp()
+		return
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		fmt.Printf("Error reading bundle: %v\n", err)
+		os.Exit(1)
+	}
+	var bundle agent.Bundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		fmt.Printf("Error reading bundle: %v\n", err)
+		os.Exit(1)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	setupNetwork(cfg)
+	setupStorage(cfg)
+	setupEncryption(cfg)
+	if endpoints := bundle.Endpoints(cfg); len(endpoints) > 0 {
+		fmt.Println("The bundle adds these models; the keys you set for them are sent to:")
+		for _, e := range endpoints {
+			fmt.Printf("  %s (%s): %s\n", e.ModelName, e.Model, describeEndpoint(e))
+		}
+		if !yes {
+			fmt.Print("Import? (y/n): ")
+			var response string
+			fmt.Scanln(&response)
+			if response != "y" {
+				fmt.Println("Aborted.")
+				return
+			}
+		}
+	}
+
+	imported, err := agent.ImportBundle(cfg, &bundle, agentID, workspace)
+	if err != nil {
+		fmt.Printf("Error importing agent: %v\n", err)
+		os.Exit(1)
+	}
+	if err := config.SaveConfig(getConfigPath(), cfg); err != nil {
+		fmt.Printf("Error saving config: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Imported agent %s into %s\n", imported.Agent.ID, imported.Workspace)
+	if len(imported.Models) > 0 {
+		fmt.Printf("  Set api_key in model_list for: %s\n", strings.Join(imported.Models, ", "))
+	}
+	fmt.Println("  Add a binding to route messages to it.")
+}
+
+// describeEndpoint says where the requests of a model a bundle adds go.
+func describeEndpoint(e agent.BundleEndpoint) string {
+	where := e.APIBase
+	if where == "" {
+		where = "the provider's default endpoint"
+	}
+	if e.CustomProvider != "" {
+		where += fmt.Sprintf(" (custom provider %s", e.CustomProvider)
+		if e.AuthHeader != "" {
+			where += ", key sent as " + e.AuthHeader
+		}
+		where += ")"
+	}
+	if e.AuthMethod != "" {
+		where += ", auth_method " + e.AuthMethod
+	}
+	return where
+}
+
+func agentBundleHelp() {
+	fmt.Println("Usage:")
+	fmt.Println("  picoclaw agent export <agent-id> [-o file]")
+	fmt.Println("  picoclaw agent import <file> [--id agent-id] [--workspace dir] [--yes]")
+	fmt.Println()
+	fmt.Println("A bundle holds an agent's system prompt (AGENTS.md, SOUL.md, IDENTITY.md),")
+	fmt.Println("workspace skills, tool allowlist and model profile. It holds no API keys,")
+	fmt.Println("logins, USER.md, memory or sessions, so it can be shared.")
+	fmt.Println()
+	fmt.Println("Importing adds the agent to agents.list, and the bundle's model_list entries")
+	fmt.Println("that are missing, without keys or the programs they would launch; the")
+	fmt.Println("agent's workspace must be empty. The endpoints of these entries are shown")
+	fmt.Println("for confirmation first, as their keys are sent there; --yes skips asking.")
+}
@@ -24,6 +24,11 @@ func authCmd() {
 		authHelp()
 		return
 	}
+	// Selects the configured credential store.
+	if _, err := loadConfig(); err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		return
+	}
 
 	switch os.Args[2] {
 	case "login":
@@ -49,12 +54,15 @@ func authHelp() {
 	fmt.Println()
 	fmt.Println("Login options:")
 	fmt.Println("  --provider <name>    Provider to login with (openai, anthropic, google-antigravity)")
-	fmt.Println("  --device-code        Use device code flow (for headless environments)")
+	fmt.Println("  --device-code        Log in from another device (for headless machines; the default without a browser)")
+	fmt.Println("  --account <label>    Store the login as another account (e.g. work), also for logout")
 	fmt.Println()
 	fmt.Println("Examples:")
 	fmt.Println("  picoclaw auth login --provider openai")
 	fmt.Println("  picoclaw auth login --provider openai --device-code")
 	fmt.Println("  picoclaw auth login --provider anthropic")
+	fmt.Println("  picoclaw auth login --provider anthropic --device-code")
+	fmt.Println("  picoclaw auth login --provider anthropic --account work")
 	fmt.Println("  picoclaw auth login --provider google-antigravity")
 	fmt.Println("  picoclaw auth models")
 	fmt.Println("  picoclaw auth logout --provider openai")
@@ -63,6 +71,7 @@ func authHelp() {
 
 func authLoginCmd() {
 	provider := ""
+	account := ""
 	useDeviceCode := false
 
 	args := os.Args[3:]
@@ -73,6 +82,11 @@ func authLoginCmd() {
 				provider = args[i+1]
 				i++
 			}
+		case "--account", "-a":
+			if i+1 < len(args) {
+				account = args[i+1]
+				i++
+			}
 		case "--device-code":
 			useDeviceCode = true
 		}
@@ -84,25 +98,39 @@ func authLoginCmd() {
 		return
 	}
 
+	if strings.Contains(account, ":") {
+		fmt.Println("Error: --account must not contain ':'")
+		return
+	}
+
 	switch provider {
 	case "openai":
-		authLoginOpenAI(useDeviceCode)
+		authLoginOpenAI(useDeviceCode, account)
 	case "anthropic":
-		authLoginPasteToken(provider)
+		if useDeviceCode {
+			// Anthropic has no device login for other apps; a key created
+			// from any device and pasted here works the same headless.
+			fmt.Println("Create an API key at https://console.anthropic.com/settings/keys from any device, then paste it here.")
+		}
+		authLoginPasteToken(provider, account)
 	case "google-antigravity", "antigravity":
-		authLoginGoogleAntigravity()
+		authLoginGoogleAntigravity(account)
 	default:
 		fmt.Printf("Unsupported provider: %s\n", provider)
 		fmt.Println(supportedProvidersMsg)
 	}
 }
 
-func authLoginOpenAI(useDeviceCode bool) {
+func authLoginOpenAI(useDeviceCode bool, account string) {
 	cfg := auth.OpenAIOAuthConfig()
 
 	var cred *auth.AuthCredential
 	var err error
 
+	if !useDeviceCode && !auth.CanOpenBrowser() {
+		fmt.Println("No browser found on this machine, logging in with a device code.")
+		useDeviceCode = true
+	}
 	if useDeviceCode {
 		cred, err = auth.LoginDeviceCode(cfg)
 	} else {
@@ -114,10 +142,15 @@ func authLoginOpenAI(useDeviceCode bool) {
 		os.Exit(1)
 	}
 
-	if err := auth.SetCredential("openai", cred); err != nil {
+	cred.Account = account
+	if err := auth.SetCredential(auth.AccountKey("openai", account), cred); err != nil {
 		fmt.Printf("Failed to save credentials: %v\n", err)
 		os.Exit(1)
 	}
+	if account != "" {
+		printAccountLogin("openai", account)
+		return
+	}
 
 	appCfg, err := loadConfig()
 	if err == nil {
@@ -158,7 +191,7 @@ func authLoginOpenAI(useDeviceCode bool) {
 	fmt.Println("Default model set to: gpt-5.2")
 }
 
-func authLoginGoogleAntigravity() {
+func authLoginGoogleAntigravity(account string) {
 	cfg := auth.GoogleAntigravityOAuthConfig()
 
 	cred, err := auth.LoginBrowser(cfg)
@@ -188,10 +221,15 @@ func authLoginGoogleAntigravity() {
 		fmt.Printf("Project: %s\n", projectID)
 	}
 
-	if err := auth.SetCredential("google-antigravity", cred); err != nil {
+	cred.Account = account
+	if err := auth.SetCredential(auth.AccountKey("google-antigravity", account), cred); err != nil {
 		fmt.Printf("Failed to save credentials: %v\n", err)
 		os.Exit(1)
 	}
+	if account != "" {
+		printAccountLogin("google-antigravity", account)
+		return
+	}
 
 	appCfg, err := loadConfig()
 	if err == nil {
@@ -258,17 +296,22 @@ func fetchGoogleUserEmail(accessToken string) (string, error) {
 	return userInfo.Email, nil
 }
 
-func authLoginPasteToken(provider string) {
+func authLoginPasteToken(provider, account string) {
 	cred, err := auth.LoginPasteToken(provider, os.Stdin)
 	if err != nil {
 		fmt.Printf("Login failed: %v\n", err)
 		os.Exit(1)
 	}
 
-	if err := auth.SetCredential(provider, cred); err != nil {
+	cred.Account = account
+	if err := auth.SetCredential(auth.AccountKey(provider, account), cred); err != nil {
 		fmt.Printf("Failed to save credentials: %v\n", err)
 		os.Exit(1)
 	}
+	if account != "" {
+		printAccountLogin(provider, account)
+		return
+	}
 
 	appCfg, err := loadConfig()
 	if err == nil {
@@ -323,8 +366,17 @@ func authLoginPasteToken(provider string) {
 	fmt.Printf("Default model set to: %s\n", appCfg.Agents.Defaults.Model)
 }
 
+// printAccountLogin tells how to use a labelled login. Unlike the default
+// login, it leaves the config alone: the account is chosen per model or
+// agent.
+func printAccountLogin(provider, account string) {
+	fmt.Printf("Login saved for %s account %q!\n", provider, account)
+	fmt.Printf("Use it by setting \"account\": %q on a model_list entry or an agent in agents.list.\n", account)
+}
+
 func authLogoutCmd() {
 	provider := ""
+	account := ""
 
 	args := os.Args[3:]
 	for i := 0; i < len(args); i++ {
@@ -334,10 +386,24 @@ func authLogoutCmd() {
 				provider = args[i+1]
 				i++
 			}
+		case "--account", "-a":
+			if i+1 < len(args) {
+				account = args[i+1]
+				i++
+			}
 		}
 	}
 
-	if provider != "" {
+	if provider != "" && account != "" {
+		if provider == "antigravity" {
+			provider = "google-antigravity"
+		}
+		if err := auth.DeleteCredential(auth.AccountKey(provider, account)); err != nil {
+			fmt.Printf("Failed to remove credentials: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Logged out from %s account %q\n", provider, account)
+	} else if provider != "" {
 		if err := auth.DeleteCredential(provider); err != nil {
 			fmt.Printf("Failed to remove credentials: %v\n", err)
 			os.Exit(1)
@@ -399,13 +465,13 @@ func authLogoutCmd() {
 }
 
 func authStatusCmd() {
-	store, err := auth.LoadStore()
+	creds, err := auth.ListCredentials()
 	if err != nil {
 		fmt.Printf("Error loading auth store: %v\n", err)
 		return
 	}
 
-	if len(store.Credentials) == 0 {
+	if len(creds) == 0 {
 		fmt.Println("No authenticated providers.")
 		fmt.Println("Run: picoclaw auth login --provider <name>")
 		return
@@ -413,7 +479,7 @@ func authStatusCmd() {
 
 	fmt.Println("\nAuthenticated Providers:")
 	fmt.Println("------------------------")
-	for provider, cred := range store.Credentials {
+	for provider, cred := range creds {
 		status := "active"
 		if cred.IsExpired() {
 			status = "expired"
@@ -440,23 +506,16 @@ func authStatusCmd() {
 }
 
 func authModelsCmd() {
-	cred, err := auth.GetCredential("google-antigravity")
+	source := auth.NewTokenSource("google-antigravity", func(cred *auth.AuthCredential) (*auth.AuthCredential, error) {
+		return auth.RefreshAccessToken(cred, auth.GoogleAntigravityOAuthConfig())
+	})
+	cred, err := source.Credential()
 	if err != nil || cred == nil {
 		fmt.Println("Not logged in to Google Antigravity.")
 		fmt.Println("Run: picoclaw auth login --provider google-antigravity")
 		return
 	}
 
-	// Refresh token if needed
-	if cred.NeedsRefresh() && cred.RefreshToken != "" {
-		oauthCfg := auth.GoogleAntigravityOAuthConfig()
-		refreshed, refreshErr := auth.RefreshAccessToken(cred, oauthCfg)
-		if refreshErr == nil {
-			cred = refreshed
-			_ = auth.SetCredential("google-antigravity", cred)
-		}
-	}
-
 	projectID := cred.ProjectID
 	if projectID == "" {
 		fmt.Println("No project ID stored. Try logging in again.")
@@ -26,6 +26,9 @@ func cronCmd() {
 		fmt.Printf("Error loading config: %v\n", err)
 		return
 	}
+	setupNetwork(cfg)
+	setupStorage(cfg)
+	setupEncryption(cfg)
 
 	cronStorePath := filepath.Join(cfg.WorkspacePath(), "cron", "jobs.json")
 
@@ -66,6 +69,8 @@ func cronHelp() {
 	fmt.Println("  -d, --deliver     Deliver response to channel")
 	fmt.Println("  --to             Recipient for delivery")
 	fmt.Println("  --channel        Channel for delivery")
+	fmt.Println("  --digest         Produce a daily or weekly conversation digest")
+	fmt.Println("  --digest-scope   Limit the digest to sessions matching this text")
 }
 
 func cronListCmd(storePath string) {
@@ -115,6 +120,8 @@ func cronAddCmd(storePath string) {
 	deliver := false
 	channel := ""
 	to := ""
+	digest := ""
+	digestScope := ""
 
 	args := os.Args[3:]
 	for i := 0; i < len(args); i++ {
@@ -153,6 +160,16 @@ func cronAddCmd(storePath string) {
 				channel = args[i+1]
 				i++
 			}
+		case "--digest":
+			if i+1 < len(args) {
+				digest = args[i+1]
+				i++
+			}
+		case "--digest-scope":
+			if i+1 < len(args) {
+				digestScope = args[i+1]
+				i++
+			}
 		}
 	}
 
@@ -161,6 +178,15 @@ func cronAddCmd(storePath string) {
 		return
 	}
 
+	if digest != "" && digest != "daily" && digest != "weekly" {
+		fmt.Println("Error: --digest must be daily or weekly")
+		return
+	}
+
+	if message == "" && digest != "" {
+		message = digest + " digest"
+	}
+
 	if message == "" {
 		fmt.Println("Error: --message is required")
 		return
@@ -192,6 +218,15 @@ func cronAddCmd(storePath string) {
 		return
 	}
 
+	if digest != "" {
+		job.Payload.Digest = digest
+		job.Payload.DigestScope = digestScope
+		if err := cs.UpdateJob(job); err != nil {
+			fmt.Printf("Error saving digest options: %v\n", err)
+			return
+		}
+	}
+
 	fmt.Printf("✓ Added job '%s' (%s)\n", job.Name, job.ID)
 }
 
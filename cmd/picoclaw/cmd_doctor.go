@@ -0,0 +1,99 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// doctorCmd checks that the providers in model_list can be reached and
+// accept their credentials.
+func doctorCmd() {
+	asJSON := false
+	var names []string
+	for _, arg := range os.Args[2:] {
+		switch arg {
+		case "--json":
+			asJSON = true
+		case "-h", "--help":
+			doctorHelp()
+			return
+		default:
+			names = append(names, arg)
+		}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	setupNetwork(cfg)
+
+	diags := providers.Doctor(context.Background(), cfg, names...)
+	// A bridge launched for the check is not left running.
+	providers.StopCopilotBridges()
+	failed := false
+	for _, d := range diags {
+		if d.Status == providers.DiagnosticError {
+			failed = true
+		}
+	}
+	if asJSON {
+		data, _ := json.MarshalIndent(diags, "", "  ")
+		fmt.Println(string(data))
+	} else {
+		printDiagnostics(diags)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func printDiagnostics(diags []providers.Diagnostic) {
+	if len(diags) == 0 {
+		fmt.Println("No models to check. Add providers to model_list in the config.")
+		return
+	}
+	marks := map[providers.DiagnosticStatus]string{
+		providers.DiagnosticOK:      "✓",
+		providers.DiagnosticWarning: "⚠",
+		providers.DiagnosticError:   "✗",
+	}
+	for _, d := range diags {
+		line := fmt.Sprintf("%s %s (%s)", marks[d.Status], d.ModelName, d.Model)
+		if d.Endpoint != "" {
+			line += " " + d.Endpoint
+		}
+		if d.Proxy != "" {
+			line += " via " + d.Proxy
+		}
+		if d.LatencyMS > 0 {
+			line += fmt.Sprintf(" %dms", d.LatencyMS)
+		}
+		fmt.Println(line)
+		for _, c := range d.Checks {
+			if c.Status != providers.DiagnosticOK {
+				fmt.Printf("    %s %s: %s\n", marks[c.Status], c.Name, c.Message)
+			}
+		}
+	}
+}
+
+func doctorHelp() {
+	fmt.Println("Usage: picoclaw doctor [model_name...] [--json]")
+	fmt.Println()
+	fmt.Println("Checks each model_list entry, or the named ones: that it creates a provider,")
+	fmt.Println("that its proxy and endpoint can be reached, and that the provider accepts its")
+	fmt.Println("credentials, by listing its models or with a one-token request.")
+	fmt.Println("Exits with status 1 if a check fails.")
+	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  --json      Print the diagnostics as JSON")
+}
@@ -0,0 +1,113 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/session"
+)
+
+// exportCmd renders a stored session as a self-contained HTML file. Without
+// a session key it lists the sessions that can be exported, optionally
+// filtered by a search over their keys, titles and tags.
+func exportCmd() {
+	sessionKey := ""
+	output := ""
+	query := ""
+
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-o", "--output":
+			if i+1 < len(args) {
+				output = args[i+1]
+				i++
+			}
+		case "-s", "--search":
+			if i+1 < len(args) {
+				query = args[i+1]
+				i++
+			}
+		case "-h", "--help":
+			exportHelp()
+			return
+		default:
+			sessionKey = args[i]
+		}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	setupNetwork(cfg)
+	setupStorage(cfg)
+	setupEncryption(cfg)
+	sessions := session.NewSessionManager(filepath.Join(cfg.WorkspacePath(), "sessions"))
+
+	if sessionKey == "" {
+		all := sessions.Search(query)
+		if len(all) == 0 {
+			fmt.Println("No sessions found.")
+			return
+		}
+		fmt.Println("Sessions:")
+		for _, s := range all {
+			fmt.Printf("  %-40s %4d messages  updated %s\n", s.Key, len(s.Messages), s.Updated.Format("2006-01-02 15:04"))
+			if s.Title != "" {
+				fmt.Printf("    %s%s\n", s.Title, formatTags(s.Tags))
+			}
+		}
+		fmt.Println()
+		exportHelp()
+		return
+	}
+
+	sess, ok := sessions.Get(sessionKey)
+	if !ok {
+		fmt.Printf("Session %q not found\n", sessionKey)
+		os.Exit(1)
+	}
+
+	if output == "" {
+		output = session.ExportFilename(sessionKey)
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		fmt.Printf("Error creating %s: %v\n", output, err)
+		os.Exit(1)
+	}
+	if err := session.ExportHTML(f, sess); err != nil {
+		f.Close()
+		fmt.Printf("Error exporting session: %v\n", err)
+		os.Exit(1)
+	}
+	if err := f.Close(); err != nil {
+		fmt.Printf("Error writing %s: %v\n", output, err)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Exported %s (%d messages) to %s\n", sessionKey, len(sess.Messages), output)
+}
+
+func exportHelp() {
+	fmt.Println("Usage: picoclaw export <session-key> [-o file.html]")
+	fmt.Println("       picoclaw export [-s query]")
+	fmt.Println()
+	fmt.Println("Writes the conversation as a single HTML file that can be shared or archived.")
+	fmt.Println("Run without a session key to list sessions; -s lists only sessions whose key,")
+	fmt.Println("title or tags contain the query.")
+}
+
+func formatTags(tags []string) string {
+	var sb strings.Builder
+	for _, tag := range tags {
+		sb.WriteString(" #" + tag)
+	}
+	return sb.String()
+}
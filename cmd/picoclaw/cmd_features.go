@@ -0,0 +1,40 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/features"
+)
+
+// featuresCmd reports the build profile and the optional components
+// compiled into this binary.
+func featuresCmd() {
+	fmt.Printf("%s picoclaw %s\n", logo, formatVersion())
+	fmt.Printf("Profile: %s\n", features.Profile)
+	fmt.Printf("Platform: %s/%s\n", runtime.GOOS, runtime.GOARCH)
+
+	byKind := make(map[string][]string)
+	var kinds []string
+	for _, f := range features.List() {
+		if _, ok := byKind[f.Kind]; !ok {
+			kinds = append(kinds, f.Kind)
+		}
+		byKind[f.Kind] = append(byKind[f.Kind], f.Name)
+	}
+
+	for _, kind := range kinds {
+		fmt.Printf("\n%ss (%d):\n", strings.ToUpper(kind[:1])+kind[1:], len(byKind[kind]))
+		for _, name := range byKind[kind] {
+			fmt.Printf("  %s\n", name)
+		}
+	}
+
+	if features.Profile != "full" {
+		fmt.Println("\nBuild without -tags minimal/standard for all channels and providers.")
+	}
+}
@@ -5,36 +5,45 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"syscall"
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/agent"
+	"github.com/sipeed/picoclaw/pkg/alerts"
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/channels"
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/cron"
 	"github.com/sipeed/picoclaw/pkg/devices"
+	"github.com/sipeed/picoclaw/pkg/governor"
 	"github.com/sipeed/picoclaw/pkg/health"
 	"github.com/sipeed/picoclaw/pkg/heartbeat"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/shutdown"
 	"github.com/sipeed/picoclaw/pkg/state"
 	"github.com/sipeed/picoclaw/pkg/tools"
+	"github.com/sipeed/picoclaw/pkg/utils"
 	"github.com/sipeed/picoclaw/pkg/voice"
 )
 
 func gatewayCmd() {
-	// Check for --debug flag
+	// Check for --debug and --trace flags
 	args := os.Args[2:]
+	trace := false
 	for _, arg := range args {
-		if arg == "--debug" || arg == "-d" {
+		switch arg {
+		case "--debug", "-d":
 			logger.SetLevel(logger.DEBUG)
 			fmt.Println("🔍 Debug mode enabled")
-			break
+		case "--trace":
+			trace = true
 		}
 	}
 
@@ -44,6 +53,24 @@ func gatewayCmd() {
 		os.Exit(1)
 	}
 
+	workspaceLock, err := utils.LockWorkspace(cfg.WorkspacePath())
+	if errors.Is(err, utils.ErrWorkspaceLocked) {
+		fmt.Printf("Error: %s\n", lockHolderMessage(cfg.WorkspacePath()))
+		os.Exit(1)
+	} else if err != nil {
+		fmt.Printf("Warning: could not lock workspace: %v\n", err)
+	}
+	defer workspaceLock.Unlock()
+
+	setupCrashReporting(cfg)
+	setupLogging(cfg)
+	setupNetwork(cfg)
+	setupStorage(cfg)
+	setupEncryption(cfg)
+	if trace {
+		enableProviderTrace(cfg)
+	}
+
 	provider, modelID, err := providers.CreateProvider(cfg)
 	if err != nil {
 		fmt.Printf("Error creating provider: %v\n", err)
@@ -119,22 +146,19 @@ func gatewayCmd() {
 	}
 
 	if transcriber != nil {
-		if telegramChannel, ok := channelManager.GetChannel("telegram"); ok {
-			if tc, ok := telegramChannel.(*channels.TelegramChannel); ok {
-				tc.SetTranscriber(transcriber)
-				logger.InfoC("voice", "Groq transcription attached to Telegram channel")
-			}
-		}
-		if discordChannel, ok := channelManager.GetChannel("discord"); ok {
-			if dc, ok := discordChannel.(*channels.DiscordChannel); ok {
-				dc.SetTranscriber(transcriber)
-				logger.InfoC("voice", "Groq transcription attached to Discord channel")
-			}
+		// Not every channel is compiled into every build profile, so attach
+		// through the method rather than the concrete channel types.
+		type transcribable interface {
+			SetTranscriber(transcriber *voice.GroqTranscriber)
 		}
-		if slackChannel, ok := channelManager.GetChannel("slack"); ok {
-			if sc, ok := slackChannel.(*channels.SlackChannel); ok {
-				sc.SetTranscriber(transcriber)
-				logger.InfoC("voice", "Groq transcription attached to Slack channel")
+		for _, name := range []string{"telegram", "discord", "slack"} {
+			if ch, ok := channelManager.GetChannel(name); ok {
+				if tc, ok := ch.(transcribable); ok {
+					tc.SetTranscriber(transcriber)
+					logger.InfoCF("voice", "Groq transcription attached to channel", map[string]interface{}{
+						"channel": name,
+					})
+				}
 			}
 		}
 	}
@@ -152,6 +176,30 @@ func gatewayCmd() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	prewarmProvider(ctx, cfg, provider)
+	if cfg.FileUploads.Enabled {
+		agentLoop.StartUploadCleanup(ctx)
+	}
+	if cfg.Memory.Maintenance {
+		agentLoop.StartMemoryMaintenance(ctx)
+	}
+
+	memGovernor := governor.New(cfg.Governor)
+	if cfg.Governor.Enabled {
+		idle := time.Duration(cfg.Governor.IdleSessionMinutes) * time.Minute
+		memGovernor.OnPressure(func(level governor.Level) {
+			if level >= governor.LevelHard {
+				agentLoop.ReleaseMemory(time.Minute)
+			} else {
+				agentLoop.ReleaseMemory(idle)
+			}
+		})
+		cronService.SetDeferCheck(memGovernor.Deferring)
+		heartbeatService.SetDeferCheck(memGovernor.Deferring)
+		memGovernor.Start(ctx)
+		fmt.Println("✓ Memory governor started")
+	}
+
 	if err := cronService.Start(); err != nil {
 		fmt.Printf("Error starting cron service: %v\n", err)
 	}
@@ -178,31 +226,81 @@ func gatewayCmd() {
 		fmt.Printf("Error starting channels: %v\n", err)
 	}
 
+	alertMonitor := alerts.NewMonitor(cfg.Observability.Alerts, cfg.WorkspacePath(), msgBus)
+	if cfg.Observability.Alerts.Enabled {
+		agentLoop.SetProviderFailureHook(alertMonitor.RecordProviderFailure)
+		alertMonitor.Start(ctx)
+		fmt.Println("✓ Alert monitor started")
+	}
+
 	healthServer := health.NewServer(cfg.Gateway.Host, cfg.Gateway.Port)
+	if tracker := agentLoop.Usage(); tracker != nil {
+		healthServer.HandleAdmin("/usage", tracker, cfg.Gateway.AdminToken)
+	}
+	healthServer.HandleAdmin("/tasks", agentLoop.TasksHandler(), cfg.Gateway.AdminToken)
 	go func() {
 		if err := healthServer.Start(); err != nil && err != http.ErrServerClosed {
 			logger.ErrorCF("health", "Health server error", map[string]interface{}{"error": err.Error()})
 		}
 	}()
 	fmt.Printf("✓ Health endpoints available at http://%s:%d/health and /ready\n", cfg.Gateway.Host, cfg.Gateway.Port)
+	if agentLoop.Usage() != nil {
+		fmt.Printf("✓ Usage report available at http://%s:%d/usage%s\n", cfg.Gateway.Host, cfg.Gateway.Port, adminAccess(cfg))
+	}
+	fmt.Printf("✓ Background tasks available at http://%s:%d/tasks%s\n", cfg.Gateway.Host, cfg.Gateway.Port, adminAccess(cfg))
+
+	var recorder *shutdown.Recorder
+	if cfg.Observability.ShutdownReport.Enabled {
+		recorder = shutdown.NewRecorder(agentLoop.Usage())
+	}
 
 	go agentLoop.Run(ctx)
 
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt)
+	// Service managers stop the gateway with SIGTERM.
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 	<-sigChan
 
 	fmt.Println("\nShutting down...")
+	if recorder != nil {
+		reportShutdown(ctx, cfg.Observability.ShutdownReport, recorder, agentLoop, msgBus, channelManager)
+	}
 	cancel()
 	healthServer.Stop(context.Background())
+	alertMonitor.Stop()
+	memGovernor.Stop()
 	deviceService.Stop()
 	heartbeatService.Stop()
 	cronService.Stop()
 	agentLoop.Stop()
 	channelManager.StopAll(ctx)
+	providers.StopCopilotBridges()
 	fmt.Println("✓ Gateway stopped")
 }
 
+// reportShutdown logs the shutdown report of the run and sends it to the
+// configured chat, if any.
+func reportShutdown(ctx context.Context, rc config.ShutdownReportConfig, recorder *shutdown.Recorder, agentLoop *agent.AgentLoop, msgBus *bus.MessageBus, channelManager *channels.Manager) {
+	pending := agentLoop.Pending()
+	pending["inbound_messages"], pending["outbound_messages"] = msgBus.Pending()
+	pending["held_messages"] = channelManager.HeldMessages()
+
+	report := recorder.Report(agentLoop.TurnsServed(), pending)
+	logger.InfoCF("gateway", "Shutdown report", report.Fields())
+
+	if rc.Channel == "" || rc.ChatID == "" {
+		return
+	}
+	sendCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := channelManager.SendToChannel(sendCtx, rc.Channel, rc.ChatID, report.String()); err != nil {
+		logger.WarnCF("gateway", "Failed to send shutdown report", map[string]interface{}{
+			"channel": rc.Channel,
+			"error":   err.Error(),
+		})
+	}
+}
+
 func setupCronTool(agentLoop *agent.AgentLoop, msgBus *bus.MessageBus, workspace string, restrict bool, execTimeout time.Duration, cfg *config.Config) *cron.CronService {
 	cronStorePath := filepath.Join(workspace, "cron", "jobs.json")
 
@@ -221,3 +319,20 @@ func setupCronTool(agentLoop *agent.AgentLoop, msgBus *bus.MessageBus, workspace
 
 	return cronService
 }
+
+// adminAccess says who may use the gateway's admin endpoints.
+func adminAccess(cfg *config.Config) string {
+	if cfg.Gateway.AdminToken != "" {
+		return " (with gateway.admin_token)"
+	}
+	return " (from this machine only)"
+}
+
+// lockHolderMessage explains that another picoclaw instance holds the
+// workspace lock, naming its PID when the lock file records one.
+func lockHolderMessage(workspace string) string {
+	if pid := utils.ReadWorkspaceLockPID(workspace); pid > 0 {
+		return fmt.Sprintf("another picoclaw instance (pid %d) is already running on workspace %s", pid, workspace)
+	}
+	return fmt.Sprintf("another picoclaw instance is already running on workspace %s", workspace)
+}
@@ -0,0 +1,185 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/routing"
+	"github.com/sipeed/picoclaw/pkg/session"
+)
+
+// importCmd seeds a session with the contents of files, what another
+// session knows, or a conversation exported from ChatGPT, Claude or
+// PicoClaw.
+func importCmd() {
+	sessionKey := ""
+	fromSession := ""
+	pick := ""
+	var files []string
+
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--from":
+			if i+1 < len(args) {
+				fromSession = args[i+1]
+				i++
+			}
+		case "-c", "--conversation":
+			if i+1 < len(args) {
+				pick = args[i+1]
+				i++
+			}
+		case "-h", "--help":
+			importHelp()
+			return
+		default:
+			if sessionKey == "" {
+				sessionKey = args[i]
+			} else {
+				files = append(files, args[i])
+			}
+		}
+	}
+	if sessionKey == "" || (fromSession == "" && len(files) == 0) {
+		importHelp()
+		os.Exit(1)
+	}
+	// The agent only keeps session keys of this form; others are replaced
+	// by the routed one.
+	if !strings.HasPrefix(sessionKey, "agent:") {
+		sessionKey = "agent:" + routing.DefaultAgentID + ":" + sessionKey
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	setupNetwork(cfg)
+	setupStorage(cfg)
+	setupEncryption(cfg)
+	sessions := session.NewSessionManager(filepath.Join(cfg.WorkspacePath(), "sessions"))
+
+	var (
+		title    string
+		summary  string
+		messages []providers.Message
+	)
+	if fromSession != "" {
+		from, ok := sessions.Get(fromSession)
+		if !ok {
+			fmt.Printf("Session %q not found\n", fromSession)
+			os.Exit(1)
+		}
+		summary = session.Carryover(from)
+	}
+
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Printf("Error reading %s: %v\n", path, err)
+			os.Exit(1)
+		}
+		if strings.EqualFold(filepath.Ext(path), ".json") {
+			if convs, err := session.ParseConversations(data); err == nil {
+				conv, err := pickConversation(convs, pick)
+				if err != nil {
+					fmt.Printf("%s: %v\n", path, err)
+					os.Exit(1)
+				}
+				if title == "" {
+					title = conv.Title
+				}
+				messages = append(messages, conv.Messages...)
+				fmt.Printf("  %s: %d messages%s\n", path, len(conv.Messages), formatConversationTitle(conv))
+				continue
+			}
+		}
+		msg, err := session.FileMessage(filepath.Base(path), data)
+		if err != nil {
+			fmt.Printf("Error importing: %v\n", err)
+			os.Exit(1)
+		}
+		messages = append(messages, msg)
+		fmt.Printf("  %s: file\n", path)
+	}
+
+	sessions.Import(sessionKey, title, summary, messages)
+	if err := sessions.Save(sessionKey); err != nil {
+		fmt.Printf("Error saving session %s: %v\n", sessionKey, err)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Imported into %s; continue it with: picoclaw agent -s %s\n", sessionKey, sessionKey)
+}
+
+// pickConversation returns the conversation of an export chosen by pick: its
+// number in the list or a part of its title. Exports of one conversation
+// need no pick.
+func pickConversation(convs []session.Conversation, pick string) (session.Conversation, error) {
+	if pick == "" {
+		if len(convs) == 1 {
+			return convs[0], nil
+		}
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "the export has %d conversations; choose one with -c <number or title>:\n", len(convs))
+		for i, c := range convs {
+			created := "          "
+			if !c.Created.IsZero() {
+				created = c.Created.Format("2006-01-02")
+			}
+			fmt.Fprintf(&sb, "  %4d  %s  %s\n", i+1, created, c.Title)
+		}
+		return session.Conversation{}, fmt.Errorf("%s", strings.TrimRight(sb.String(), "\n"))
+	}
+
+	if n, err := strconv.Atoi(pick); err == nil {
+		if n < 1 || n > len(convs) {
+			return session.Conversation{}, fmt.Errorf("there is no conversation %d; the export has %d", n, len(convs))
+		}
+		return convs[n-1], nil
+	}
+	var matches []session.Conversation
+	for _, c := range convs {
+		if strings.Contains(strings.ToLower(c.Title), strings.ToLower(pick)) {
+			matches = append(matches, c)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return session.Conversation{}, fmt.Errorf("no conversation title contains %q", pick)
+	case 1:
+		return matches[0], nil
+	}
+	return session.Conversation{}, fmt.Errorf("%d conversation titles contain %q; use its number instead", len(matches), pick)
+}
+
+func formatConversationTitle(c session.Conversation) string {
+	if c.Title == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (%q)", c.Title)
+}
+
+func importHelp() {
+	fmt.Println("Usage: picoclaw import <session-key> [file...] [--from <session-key>] [-c <conversation>]")
+	fmt.Println()
+	fmt.Println("Seeds a session of the default agent with earlier context, adding to it if it")
+	fmt.Println("already exists. A key without an agent:<id>: prefix is stored as agent:main:<key>.")
+	fmt.Println()
+	fmt.Println("  file               A text file, shared with the model as a message, or a JSON")
+	fmt.Println("                     export: ChatGPT's or Claude's conversations.json, or a")
+	fmt.Println("                     PicoClaw session file")
+	fmt.Println("  --from <key>       The summary of another session, or its latest messages")
+	fmt.Println("  -c <conversation>  Which conversation of a multi-conversation export to import,")
+	fmt.Println("                     by number or by a part of its title")
+	fmt.Println()
+	fmt.Println("Import while the gateway is stopped, or into a session it is not using.")
+}
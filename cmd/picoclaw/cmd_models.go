@@ -0,0 +1,80 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// modelsCmd lists the models the configured providers serve.
+func modelsCmd() {
+	filter := ""
+	asJSON := false
+	for _, arg := range os.Args[2:] {
+		switch arg {
+		case "--json":
+			asJSON = true
+		case "-h", "--help":
+			modelsHelp()
+			return
+		default:
+			filter = strings.ToLower(arg)
+		}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	setupNetwork(cfg)
+
+	models, errs := providers.ListAvailableModels(context.Background(), cfg)
+	for _, err := range errs {
+		fmt.Fprintf(os.Stderr, "Warning: could not list models of %v\n", err)
+	}
+	if filter != "" {
+		matching := models[:0]
+		for _, m := range models {
+			if strings.Contains(strings.ToLower(m.Model), filter) || strings.Contains(strings.ToLower(m.ModelName), filter) {
+				matching = append(matching, m)
+			}
+		}
+		models = matching
+	}
+
+	if asJSON {
+		data, _ := json.MarshalIndent(models, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	if len(models) == 0 {
+		fmt.Println("No models found. Add providers to model_list in the config.")
+		return
+	}
+	for _, m := range models {
+		if m.ModelName != "" {
+			fmt.Printf("  %-50s (model_name: %s)\n", m.Model, m.ModelName)
+		} else {
+			fmt.Printf("  %s\n", m.Model)
+		}
+	}
+}
+
+func modelsHelp() {
+	fmt.Println("Usage: picoclaw models [filter] [--json]")
+	fmt.Println()
+	fmt.Println("Lists the models served by the providers in model_list, as they can be")
+	fmt.Println("used in a model_list entry. Models already configured show their model_name.")
+	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  filter      Only list models containing this text")
+	fmt.Println("  --json      Print the list as JSON")
+}
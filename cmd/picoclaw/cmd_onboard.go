@@ -4,6 +4,7 @@
 package main
 
 import (
+	"context"
 	"embed"
 	"fmt"
 	"io/fs"
@@ -11,6 +12,7 @@ import (
 	"path/filepath"
 
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
 )
 
 //go:generate cp -r ../../workspace .
@@ -49,6 +51,14 @@ func onboard() {
 	fmt.Println("     - Ollama:     https://ollama.com (local, free)")
 	fmt.Println("")
 	fmt.Println("     See README.md for 17+ supported providers.")
+	for _, server := range providers.DetectLocalServers(context.Background()) {
+		fmt.Printf("\n     Found %s running at %s; to use it, add a model_list entry\n", server.Name, server.APIBase)
+		fmt.Printf("     with \"model\": \"%s/<model>\"", server.Protocol)
+		if len(server.Models) > 0 {
+			fmt.Printf(", e.g. \"%s/%s\"", server.Protocol, server.Models[0])
+		}
+		fmt.Println(" (no API key needed).")
+	}
 	fmt.Println("")
 	fmt.Println("  2. Chat: picoclaw agent -m \"Hello!\"")
 }
@@ -0,0 +1,111 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/sipeed/picoclaw/pkg/agent"
+	"github.com/sipeed/picoclaw/pkg/session"
+	"github.com/sipeed/picoclaw/pkg/transcript"
+)
+
+func sessionCmd() {
+	if len(os.Args) < 3 {
+		sessionHelp()
+		return
+	}
+	switch os.Args[2] {
+	case "redact":
+		sessionRedactCmd(os.Args[3:])
+	case "-h", "--help":
+		sessionHelp()
+	default:
+		fmt.Printf("Unknown session command: %s\n", os.Args[2])
+		sessionHelp()
+	}
+}
+
+// sessionRedactCmd removes the last turns of a stored session and redacts
+// the matches of a pattern from it and from memory, like /forget in a chat
+// but also in the memory all conversations share.
+func sessionRedactCmd(args []string) {
+	sessionKey := ""
+	turns := 0
+	var pattern *regexp.Regexp
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-l", "--last":
+			if i+1 < len(args) {
+				n, err := strconv.Atoi(args[i+1])
+				if err != nil || n <= 0 {
+					fmt.Printf("Invalid --last: %s\n", args[i+1])
+					os.Exit(1)
+				}
+				turns = n
+				i++
+			}
+		case "-p", "--pattern":
+			if i+1 < len(args) {
+				re, err := regexp.Compile(args[i+1])
+				if err != nil {
+					fmt.Printf("Invalid --pattern: %v\n", err)
+					os.Exit(1)
+				}
+				pattern = re
+				i++
+			}
+		case "-h", "--help":
+			sessionHelp()
+			return
+		default:
+			sessionKey = args[i]
+		}
+	}
+	if sessionKey == "" || (turns == 0 && pattern == nil) {
+		sessionHelp()
+		return
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	setupNetwork(cfg)
+	setupStorage(cfg)
+	setupEncryption(cfg)
+	workspace := cfg.WorkspacePath()
+	sessions := session.NewSessionManager(filepath.Join(workspace, "sessions"))
+	if _, ok := sessions.Get(sessionKey); !ok {
+		fmt.Printf("Session %q not found\n", sessionKey)
+		os.Exit(1)
+	}
+
+	f, err := agent.Forget(workspace, transcript.Dir(workspace), sessions, sessionKey, turns, pattern, true)
+	if err != nil {
+		fmt.Printf("Error redacting session: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Redacted %s: %d messages removed, %d matches in the session and %d in memory replaced, %d transcript entries changed, %d artifacts deleted\n",
+		sessionKey, f.Removed, f.Replaced, f.Memory, f.Transcripts, f.Artifacts)
+}
+
+func sessionHelp() {
+	fmt.Println("Usage: picoclaw session redact <session-key> [--last n] [--pattern regexp]")
+	fmt.Println()
+	fmt.Println("Removes the last n turns of the session and replaces the matches of the")
+	fmt.Println("pattern with \"" + session.Redacted + "\" in the session and in memory. The session's")
+	fmt.Println("transcript entries are removed with its turns, and matches are redacted")
+	fmt.Println("from all transcripts. The session's HTML export and the response cache")
+	fmt.Println("are deleted as well.")
+	fmt.Println()
+	fmt.Println("Stop the gateway first, or it may save its copy of the session over the")
+	fmt.Println("redacted one.")
+}
@@ -4,10 +4,15 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/sipeed/picoclaw/pkg/agent"
 	"github.com/sipeed/picoclaw/pkg/auth"
+	"github.com/sipeed/picoclaw/pkg/governor"
+	"github.com/sipeed/picoclaw/pkg/providers"
 )
 
 func statusCmd() {
@@ -17,6 +22,9 @@ func statusCmd() {
 		return
 	}
 
+	setupNetwork(cfg)
+	setupStorage(cfg)
+	setupEncryption(cfg)
 	configPath := getConfigPath()
 
 	fmt.Printf("%s picoclaw Status\n", logo)
@@ -36,10 +44,19 @@ func statusCmd() {
 	workspace := cfg.WorkspacePath()
 	if _, err := os.Stat(workspace); err == nil {
 		fmt.Println("Workspace:", workspace, "✓")
+		if health, err := agent.NewMemoryStore(workspace).Health(); err == nil {
+			fmt.Println("Memory:", health)
+		} else {
+			fmt.Println("Memory: ✗", err)
+		}
 	} else {
 		fmt.Println("Workspace:", workspace, "✗")
 	}
 
+	if power := governor.ReadPower(); power.HasBattery || power.HasThermal {
+		fmt.Println("Device:", power)
+	}
+
 	if _, err := os.Stat(configPath); err == nil {
 		fmt.Printf("Model: %s\n", cfg.Agents.Defaults.Model)
 
@@ -84,11 +101,14 @@ func statusCmd() {
 		} else {
 			fmt.Println("Ollama: not set")
 		}
+		for _, server := range providers.DetectLocalServers(context.Background()) {
+			fmt.Printf("%s: ✓ running at %s (models: %s)\n", server.Name, server.APIBase, strings.Join(server.Models, ", "))
+		}
 
-		store, _ := auth.LoadStore()
-		if store != nil && len(store.Credentials) > 0 {
+		creds, _ := auth.ListCredentials()
+		if len(creds) > 0 {
 			fmt.Println("\nOAuth/Token Auth:")
-			for provider, cred := range store.Credentials {
+			for provider, cred := range creds {
 				status := "authenticated"
 				if cred.IsExpired() {
 					status = "expired"
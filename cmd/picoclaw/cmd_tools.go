@@ -0,0 +1,189 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/agent"
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+func toolsCmd() {
+	if len(os.Args) < 3 {
+		toolsHelp()
+		return
+	}
+	switch os.Args[2] {
+	case "call":
+		toolsCallCmd(os.Args[3:])
+	default:
+		toolsHelp()
+	}
+}
+
+// toolsCallCmd calls one tool the way the agent would, to test it and its
+// schema without a model in the loop. It is a dry run unless --exec is
+// given.
+func toolsCallCmd(args []string) {
+	name := ""
+	argsFile := ""
+	argsJSON := ""
+	agentID := ""
+	exec := false
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--args":
+			if i+1 < len(args) {
+				argsFile = args[i+1]
+				i++
+			}
+		case "--json":
+			if i+1 < len(args) {
+				argsJSON = args[i+1]
+				i++
+			}
+		case "--agent":
+			if i+1 < len(args) {
+				agentID = args[i+1]
+				i++
+			}
+		case "--exec":
+			exec = true
+		case "--dry-run":
+			exec = false
+		case "-h", "--help":
+			toolsHelp()
+			return
+		default:
+			name = args[i]
+		}
+	}
+	if name == "" {
+		toolsHelp()
+		os.Exit(1)
+	}
+
+	var raw []byte
+	switch {
+	case argsJSON != "":
+		raw = []byte(argsJSON)
+	case argsFile == "-":
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Printf("Error reading arguments: %v\n", err)
+			os.Exit(1)
+		}
+		raw = data
+	case argsFile != "":
+		data, err := os.ReadFile(argsFile)
+		if err != nil {
+			fmt.Printf("Error reading %s: %v\n", argsFile, err)
+			os.Exit(1)
+		}
+		raw = data
+	}
+	toolArgs := map[string]interface{}{}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &toolArgs); err != nil {
+			fmt.Printf("Arguments are not a JSON object: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	if exec {
+		if workspaceLock, err := utils.LockWorkspace(cfg.WorkspacePath()); err == nil {
+			defer workspaceLock.Unlock()
+		} else if errors.Is(err, utils.ErrWorkspaceLocked) {
+			fmt.Printf("⚠ Warning: %v; session and memory writes may conflict\n", err)
+		}
+	}
+	setupCrashReporting(cfg)
+	setupLogging(cfg)
+	setupNetwork(cfg)
+	setupStorage(cfg)
+	setupEncryption(cfg)
+
+	provider, modelID, err := providers.CreateProvider(cfg)
+	if err != nil {
+		fmt.Printf("Error creating provider: %v\n", err)
+		os.Exit(1)
+	}
+	if modelID != "" {
+		cfg.Agents.Defaults.Model = modelID
+	}
+	agentLoop := agent.NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+
+	report, err := agentLoop.CallTool(context.Background(), agentID, name, toolArgs, !exec)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	def := report.Definition.Function
+	fmt.Printf("Tool:      %s (agent %s)\n", def.Name, report.AgentID)
+	fmt.Printf("           %s\n", def.Description)
+	params, _ := json.MarshalIndent(def.Parameters, "           ", "  ")
+	fmt.Printf("Schema:    %s\n", params)
+	pretty, _ := json.MarshalIndent(toolArgs, "           ", "  ")
+	fmt.Printf("Arguments: %s\n", pretty)
+	if report.ArgsErr != nil {
+		fmt.Printf("✗ Arguments do not match the schema: %v\n", report.ArgsErr)
+	} else {
+		fmt.Println("✓ Arguments match the schema")
+	}
+	if report.NeedsApproval {
+		fmt.Println("⚠ Calls to this tool need approval")
+	}
+
+	if !exec {
+		fmt.Println()
+		fmt.Println("Dry run; the tool was not called. Add --exec to call it.")
+		if report.ArgsErr != nil {
+			os.Exit(1)
+		}
+		return
+	}
+
+	result := report.Result
+	status := "ok"
+	switch {
+	case result.IsError:
+		status = "error"
+	case result.Async:
+		status = "running in the background"
+	}
+	fmt.Printf("\nResult (%s, %s):\n%s\n", status, report.Duration.Round(time.Millisecond), result.ForLLM)
+	if result.ForUser != "" && !result.Silent {
+		fmt.Printf("\nShown to the user:\n%s\n", result.ForUser)
+	}
+	if result.IsError {
+		os.Exit(1)
+	}
+}
+
+func toolsHelp() {
+	fmt.Println("Usage: picoclaw tools call <name> [--args file.json | --json '{...}'] [--agent id] [--dry-run | --exec]")
+	fmt.Println()
+	fmt.Println("Calls a tool the way the agent would, to test it and its schema:")
+	fmt.Println("  --args <file>   Read the arguments, a JSON object, from file (- for stdin)")
+	fmt.Println("  --json <json>   Give the arguments inline")
+	fmt.Println("  --agent <id>    Use the tools of this agent instead of the default one")
+	fmt.Println("  --dry-run       Only check the arguments against the tool's schema (default)")
+	fmt.Println("  --exec          Call the tool, with approvals and the injection guard as configured")
+}
@@ -0,0 +1,139 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+
+	"github.com/sipeed/picoclaw/pkg/usage"
+)
+
+// usageTopSessions is how many sessions usageCmd lists without --all.
+const usageTopSessions = 10
+
+// usageCmd shows the tokens used and their cost, in total, per model and
+// per session.
+func usageCmd() {
+	sessionKey := ""
+	all := false
+	asJSON := false
+
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-s", "--session":
+			if i+1 < len(args) {
+				sessionKey = args[i+1]
+				i++
+			}
+		case "--all":
+			all = true
+		case "--json":
+			asJSON = true
+		case "-h", "--help":
+			usageHelp()
+			return
+		}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	setupNetwork(cfg)
+	setupStorage(cfg)
+	setupEncryption(cfg)
+
+	report, err := usage.Load(usage.Path(cfg.WorkspacePath()))
+	if errors.Is(err, fs.ErrNotExist) {
+		fmt.Println("No usage recorded yet.")
+		return
+	}
+	if err != nil {
+		fmt.Printf("Error loading usage: %v\n", err)
+		os.Exit(1)
+	}
+
+	if sessionKey != "" {
+		counts, ok := report.Sessions[sessionKey]
+		if !ok {
+			fmt.Printf("No usage recorded for session %q\n", sessionKey)
+			os.Exit(1)
+		}
+		report.Sessions = map[string]usage.Counts{sessionKey: counts}
+	}
+
+	if asJSON {
+		data, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+
+	fmt.Printf("Usage since %s\n\n", report.Since.Format("2006-01-02 15:04"))
+	fmt.Println("Total:")
+	printUsageRow("all models", report.Total)
+
+	fmt.Println("\nBy model:")
+	for _, name := range sortedByTokens(report.Models) {
+		printUsageRow(name, report.Models[name])
+	}
+
+	sessions := sortedByTokens(report.Sessions)
+	if len(sessions) == 0 {
+		return
+	}
+	fmt.Println("\nBy session:")
+	if !all && len(sessions) > usageTopSessions {
+		sessions = sessions[:usageTopSessions]
+		defer fmt.Printf("  (top %d of %d sessions; use --all to list all)\n", usageTopSessions, len(report.Sessions))
+	}
+	for _, key := range sessions {
+		printUsageRow(key, report.Sessions[key])
+	}
+}
+
+func printUsageRow(name string, c usage.Counts) {
+	feedback := ""
+	if c.PositiveFeedback > 0 || c.NegativeFeedback > 0 {
+		feedback = fmt.Sprintf("  +%d/-%d", c.PositiveFeedback, c.NegativeFeedback)
+	}
+	fmt.Printf("  %-40s %5d requests  %9d in (%d cached)  %8d out  $%.4f%s\n",
+		name, c.Requests, c.PromptTokens, c.CachedTokens, c.CompletionTokens, c.Cost, feedback)
+}
+
+// sortedByTokens returns the keys of m, most tokens first.
+func sortedByTokens(m map[string]usage.Counts) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := m[keys[i]], m[keys[j]]
+		if a.TotalTokens != b.TotalTokens {
+			return a.TotalTokens > b.TotalTokens
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+func usageHelp() {
+	fmt.Println("Usage: picoclaw usage [-s session-key] [--all] [--json]")
+	fmt.Println()
+	fmt.Println("Shows the tokens used and their cost, in total, per model and per session.")
+	fmt.Println("Costs use the prices under usage.pricing in the config, or the built-in")
+	fmt.Println("prices of well-known models. Reactions to replies are shown as")
+	fmt.Println("+positive/-negative.")
+	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  -s, --session <key>   Show only this session")
+	fmt.Println("  --all                 List all sessions instead of the top 10")
+	fmt.Println("  --json                Print the report as JSON")
+}
@@ -7,14 +7,26 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"runtime"
+	"time"
 
+	"github.com/sipeed/picoclaw/pkg/atrest"
+	"github.com/sipeed/picoclaw/pkg/auth"
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/crash"
+	"github.com/sipeed/picoclaw/pkg/egress"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/providers/httpcapture"
+	"github.com/sipeed/picoclaw/pkg/providers/httpretry"
+	"github.com/sipeed/picoclaw/pkg/providers/httpwarm"
 	"github.com/sipeed/picoclaw/pkg/skills"
+	"github.com/sipeed/picoclaw/pkg/vfs"
 )
 
 var (
@@ -164,6 +176,22 @@ func main() {
 			fmt.Printf("Unknown skills command: %s\n", subcommand)
 			skillsHelp()
 		}
+	case "export":
+		exportCmd()
+	case "import":
+		importCmd()
+	case "session":
+		sessionCmd()
+	case "tools":
+		toolsCmd()
+	case "usage":
+		usageCmd()
+	case "models":
+		modelsCmd()
+	case "doctor":
+		doctorCmd()
+	case "features":
+		featuresCmd()
 	case "version", "--version", "-v":
 		printVersion()
 	default:
@@ -179,13 +207,21 @@ func printHelp() {
 	fmt.Println()
 	fmt.Println("Commands:")
 	fmt.Println("  onboard     Initialize picoclaw configuration and workspace")
-	fmt.Println("  agent       Interact with the agent directly")
+	fmt.Println("  agent       Interact with the agent directly, or export/import agent bundles")
 	fmt.Println("  auth        Manage authentication (login, logout, status)")
 	fmt.Println("  gateway     Start picoclaw gateway")
 	fmt.Println("  status      Show picoclaw status")
 	fmt.Println("  cron        Manage scheduled tasks")
 	fmt.Println("  migrate     Migrate from OpenClaw to PicoClaw")
 	fmt.Println("  skills      Manage skills (install, list, remove)")
+	fmt.Println("  tools       Call a tool directly to test it")
+	fmt.Println("  export      Export a conversation as a shareable HTML file")
+	fmt.Println("  import      Seed a session with files, another session or a chat export")
+	fmt.Println("  session     Redact stored conversations")
+	fmt.Println("  usage       Show token usage and cost per model and session")
+	fmt.Println("  models      List the models the configured providers serve")
+	fmt.Println("  doctor      Check that the configured providers are reachable and accept their keys")
+	fmt.Println("  features    Show the build profile and compiled-in channels/providers")
 	fmt.Println("  version     Show version information")
 }
 
@@ -195,5 +231,168 @@ func getConfigPath() string {
 }
 
 func loadConfig() (*config.Config, error) {
-	return config.LoadConfig(getConfigPath())
+	cfg, err := config.LoadConfig(getConfigPath())
+	if err != nil {
+		return nil, err
+	}
+	if err := providers.RegisterCustomProviders(cfg.CustomProviders); err != nil {
+		return nil, err
+	}
+	if err := providers.RegisterProviderPlugins(cfg.ProviderPlugins); err != nil {
+		return nil, err
+	}
+	if err := setupCredentialStore(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// setupCredentialStore selects where auth credentials are kept.
+func setupCredentialStore(cfg *config.Config) error {
+	switch cfg.Auth.Store {
+	case "", "file":
+		auth.SetCredentialStore(nil)
+	case "keyring":
+		store, err := auth.NewKeyringStore()
+		if err != nil {
+			return err
+		}
+		auth.SetCredentialStore(store)
+	default:
+		return fmt.Errorf("unknown auth store %q", cfg.Auth.Store)
+	}
+	return nil
+}
+
+// setupCrashReporting forwards recovered panics to the configured
+// error-reporting webhook.
+func setupCrashReporting(cfg *config.Config) {
+	er := cfg.Observability.ErrorReporting
+	if er.Enabled && er.WebhookURL != "" {
+		crash.SetWebhook(er.WebhookURL, er.Environment)
+	}
+}
+
+// setupLogging applies the log escalation policy.
+func setupLogging(cfg *config.Config) {
+	le := cfg.Observability.LogEscalation
+	if !le.Enabled {
+		return
+	}
+	level, ok := logger.ParseLevel(le.Level)
+	if !ok {
+		level = logger.DEBUG
+	}
+	logger.ConfigureEscalation(logger.EscalationPolicy{
+		Threshold: le.Threshold,
+		Window:    time.Duration(le.WindowSeconds) * time.Second,
+		Duration:  time.Duration(le.DurationMinutes) * time.Minute,
+		Level:     level,
+	})
+}
+
+// setupNetwork enables the provider DNS cache and retries and loads the
+// egress policy. It must run before providers and tools are created so
+// their transports pick them up. A policy that fails to load is fatal rather than silently
+// leaving the agent unrestricted.
+func setupNetwork(cfg *config.Config) {
+	if ttl := cfg.Network.DNSCacheTTLSeconds; ttl > 0 {
+		httpwarm.EnableDNSCache(time.Duration(ttl) * time.Second)
+	}
+	if r := cfg.Network.ProviderRetry; r.Enabled {
+		httpretry.Configure(httpretry.Policy{
+			MaxAttempts:  r.MaxAttempts,
+			InitialDelay: time.Duration(r.InitialDelayMS) * time.Millisecond,
+			MaxDelay:     time.Duration(r.MaxDelaySeconds) * time.Second,
+			Budget:       time.Duration(r.BudgetSeconds) * time.Second,
+		})
+	}
+	if path := cfg.EgressPolicyPath(); path != "" {
+		if err := egress.LoadFile(path); err != nil {
+			fmt.Printf("Error loading egress policy: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// setupStorage mounts the configured storage backend on the workspace. It
+// must run after setupNetwork, so the egress policy applies to it, and
+// before any store is created.
+func setupStorage(cfg *config.Config) {
+	var fsys vfs.FS
+	var err error
+	switch cfg.Storage.Backend {
+	case "", "local":
+		return
+	case "webdav":
+		d := cfg.Storage.WebDAV
+		fsys, err = vfs.WebDAV(d.URL, d.Username, d.Password)
+	default:
+		err = fmt.Errorf("unknown backend %q", cfg.Storage.Backend)
+	}
+	if err == nil {
+		err = vfs.Mount(cfg.WorkspacePath(), fsys)
+	}
+	if err == nil {
+		// Skills stay on the device: they are unpacked there when
+		// installed, and exec runs their scripts from there.
+		skills := filepath.Join(cfg.WorkspacePath(), "skills")
+		err = vfs.Mount(skills, vfs.Dir(skills))
+	}
+	if err != nil {
+		fmt.Printf("Error setting up storage: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// setupEncryption loads the workspace key so session and memory stores
+// encrypt at rest. It must run before any store is created. Failing to
+// load the key is fatal, since continuing would write plaintext or skip
+// existing encrypted sessions.
+func setupEncryption(cfg *config.Config) {
+	if !cfg.Encryption.Enabled {
+		return
+	}
+	var key []byte
+	var err error
+	if cfg.Encryption.Passphrase != "" {
+		params := filepath.Join(cfg.WorkspacePath(), "state", "encryption.json")
+		key, err = atrest.PassphraseKey(params, cfg.Encryption.Passphrase)
+	} else if path := cfg.EncryptionKeyPath(); path != "" {
+		key, err = atrest.FileKey(path)
+	} else {
+		err = fmt.Errorf("set a passphrase or key_file")
+	}
+	if err == nil {
+		err = atrest.SetKey(key)
+	}
+	if err != nil {
+		fmt.Printf("Error loading encryption key: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// prewarmProvider opens a connection to the provider's API in the
+// background, and keeps it warm if configured, to save the DNS lookup and
+// TLS handshake on the first request.
+func prewarmProvider(ctx context.Context, cfg *config.Config, provider providers.LLMProvider) {
+	w, ok := provider.(providers.Warmer)
+	if !ok || !cfg.Network.PrewarmConnections {
+		return
+	}
+	interval := time.Duration(cfg.Network.PrewarmIntervalSeconds) * time.Second
+	httpwarm.KeepWarm(ctx, interval, w.Warm)
+}
+
+// enableProviderTrace turns on debug logging and raw provider HTTP capture.
+// Each request/response pair is written, with credentials redacted, to
+// <workspace>/debug/providers so it can be attached to bug reports.
+func enableProviderTrace(cfg *config.Config) {
+	logger.SetLevel(logger.DEBUG)
+	dir := filepath.Join(cfg.WorkspacePath(), "debug", "providers")
+	if err := httpcapture.Enable(dir); err != nil {
+		fmt.Printf("Warning: could not enable provider trace: %v\n", err)
+		return
+	}
+	fmt.Printf("🔍 Provider trace enabled, capturing to %s\n", dir)
 }
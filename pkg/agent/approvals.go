@@ -0,0 +1,198 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/constants"
+	"github.com/sipeed/picoclaw/pkg/i18n"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+// approvalBroker asks a person to approve tool calls before they run. The
+// request is sent to the approver's chat and the reply is picked off the
+// inbound bus, so the conversation being processed can wait for it. A call
+// that is denied or not answered is reported to the conversing chat.
+type approvalBroker struct {
+	cfg     config.ApprovalsConfig
+	tools   map[string]bool
+	bus     *bus.MessageBus
+	render  func(channel, senderID string, key i18n.Key, data map[string]interface{}) string
+	timeout time.Duration
+
+	mu      sync.Mutex
+	pending []*pendingApproval
+}
+
+type pendingApproval struct {
+	approver config.ApproverConfig
+	result   chan bool
+}
+
+// newApprovalBroker returns a broker that renders its messages with
+// render, in the language of senderID on channel.
+func newApprovalBroker(cfg config.ApprovalsConfig, msgBus *bus.MessageBus, render func(channel, senderID string, key i18n.Key, data map[string]interface{}) string) *approvalBroker {
+	b := &approvalBroker{
+		cfg:     cfg,
+		tools:   make(map[string]bool, len(cfg.Tools)),
+		bus:     msgBus,
+		render:  render,
+		timeout: time.Duration(cfg.TimeoutSeconds) * time.Second,
+	}
+	if b.timeout <= 0 {
+		b.timeout = 5 * time.Minute
+	}
+	for _, name := range cfg.Tools {
+		b.tools[name] = true
+	}
+	msgBus.Intercept(b.intercept)
+	return b
+}
+
+// setupApprovals installs the approval broker on every agent's tools.
+func (al *AgentLoop) setupApprovals() {
+	broker := newApprovalBroker(al.cfg.Tools.Approvals, al.bus, func(channel, senderID string, key i18n.Key, data map[string]interface{}) string {
+		return al.catalog.Render(al.languageFor(bus.InboundMessage{Channel: channel, SenderID: senderID}), key, data)
+	})
+	for _, id := range al.registry.ListAgentIDs() {
+		if agent, ok := al.registry.GetAgent(id); ok {
+			agent.Tools.SetApprover(broker)
+		}
+	}
+}
+
+// approverFor returns who approves calls made from channel/chatID. Without
+// a matching mapping the conversing chat approves its own calls.
+func (b *approvalBroker) approverFor(channel, chatID string) (config.ApproverConfig, bool) {
+	for _, a := range b.cfg.Approvers {
+		if (a.Channel == "*" || a.Channel == channel) && (a.ChatID == "" || a.ChatID == chatID) {
+			return a, true
+		}
+	}
+	return config.ApproverConfig{ApproverChannel: channel, ApproverChatID: chatID}, false
+}
+
+// Approve implements tools.Approver.
+func (b *approvalBroker) Approve(ctx context.Context, tool string, args map[string]interface{}, channel, chatID string) error {
+	if !b.tools[tool] {
+		return nil
+	}
+	approver, delegated := b.approverFor(channel, chatID)
+	if !delegated && (channel == "" || constants.IsInternalChannel(channel)) {
+		// Local use (CLI): the operator is the approver.
+		return nil
+	}
+
+	summary := "{}"
+	if data, err := json.Marshal(args); err == nil {
+		summary = utils.Truncate(string(data), 300)
+	}
+	if delegated {
+		summary += fmt.Sprintf(" (%s:%s)", channel, chatID)
+	}
+
+	p := &pendingApproval{approver: approver, result: make(chan bool, 1)}
+	b.mu.Lock()
+	b.pending = append(b.pending, p)
+	b.mu.Unlock()
+	defer b.remove(p)
+
+	logger.InfoCF("agent", "Waiting for tool approval", map[string]interface{}{
+		"tool":     tool,
+		"channel":  channel,
+		"chat_id":  chatID,
+		"approver": approver.ApproverChannel + ":" + approver.ApproverChatID,
+	})
+	b.bus.PublishOutbound(bus.OutboundMessage{
+		Channel: approver.ApproverChannel,
+		ChatID:  approver.ApproverChatID,
+		Content: b.render(approver.ApproverChannel, approver.ApproverSenderID, i18n.ApprovalRequest, map[string]interface{}{
+			"Tool":    tool,
+			"Summary": summary,
+		}),
+	})
+
+	timer := time.NewTimer(b.timeout)
+	defer timer.Stop()
+	var err error
+	select {
+	case approved := <-p.result:
+		if approved {
+			return nil
+		}
+		err = errors.New("denied by approver")
+	case <-timer.C:
+		err = fmt.Errorf("no answer from approver within %s", b.timeout)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	// The chat ID stands in for the sender, as it is in private chats.
+	b.bus.PublishOutbound(bus.OutboundMessage{
+		Channel: channel,
+		ChatID:  chatID,
+		Content: b.render(channel, chatID, i18n.ToolDenied, map[string]interface{}{
+			"Tool":   tool,
+			"Reason": err.Error(),
+		}),
+	})
+	return err
+}
+
+// intercept consumes approve/deny replies from a chat with a pending
+// request, answering the oldest request first.
+func (b *approvalBroker) intercept(msg bus.InboundMessage) bool {
+	approved, ok := parseApprovalAnswer(msg.Content)
+	if !ok {
+		return false
+	}
+	senderID := msg.SenderID
+	if idx := strings.Index(senderID, "|"); idx > 0 {
+		senderID = senderID[:idx]
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, p := range b.pending {
+		a := p.approver
+		if a.ApproverChannel != msg.Channel || a.ApproverChatID != msg.ChatID {
+			continue
+		}
+		if a.ApproverSenderID != "" && a.ApproverSenderID != senderID && a.ApproverSenderID != msg.SenderID {
+			continue
+		}
+		b.pending = append(b.pending[:i], b.pending[i+1:]...)
+		p.result <- approved
+		return true
+	}
+	return false
+}
+
+func (b *approvalBroker) remove(p *pendingApproval) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, q := range b.pending {
+		if q == p {
+			b.pending = append(b.pending[:i], b.pending[i+1:]...)
+			return
+		}
+	}
+}
+
+func parseApprovalAnswer(content string) (approved bool, ok bool) {
+	answer := strings.ToLower(strings.Trim(strings.TrimSpace(content), ".!。！"))
+	switch answer {
+	case "approve", "approved", "批准":
+		return true, true
+	case "deny", "denied", "拒绝":
+		return false, true
+	}
+	return false, false
+}
@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/i18n"
+)
+
+func newTestBroker(t *testing.T, timeout int) (*approvalBroker, *bus.MessageBus) {
+	t.Helper()
+	msgBus := bus.NewMessageBus()
+	b := newApprovalBroker(config.ApprovalsConfig{
+		Enabled:        true,
+		Tools:          []string{"exec"},
+		TimeoutSeconds: timeout,
+		Approvers: []config.ApproverConfig{{
+			Channel:          "discord",
+			ApproverChannel:  "telegram",
+			ApproverChatID:   "parent",
+			ApproverSenderID: "42",
+		}},
+	}, msgBus, func(channel, senderID string, key i18n.Key, data map[string]interface{}) string {
+		return string(key) + ": " + fmt.Sprint(data["Tool"])
+	})
+	return b, msgBus
+}
+
+func TestApprovalBroker_DelegatedApproval(t *testing.T) {
+	b, msgBus := newTestBroker(t, 5)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- b.Approve(ctx, "exec", map[string]interface{}{"command": "ls"}, "discord", "kid")
+	}()
+
+	req, ok := msgBus.SubscribeOutbound(ctx)
+	if !ok || req.Channel != "telegram" || req.ChatID != "parent" {
+		t.Fatalf("approval request = %+v", req)
+	}
+
+	// A reply from someone else in the approver chat does not count and
+	// is delivered as a normal message.
+	msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "parent", SenderID: "7", Content: "approve"})
+	if msg, _ := msgBus.ConsumeInbound(ctx); msg.SenderID != "7" {
+		t.Fatalf("unrelated reply was consumed")
+	}
+
+	msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "parent", SenderID: "42|mum", Content: "Approve"})
+	if err := <-done; err != nil {
+		t.Errorf("Approve() = %v, want approved", err)
+	}
+}
+
+func TestApprovalBroker_DenyAndTimeout(t *testing.T) {
+	b, msgBus := newTestBroker(t, 1)
+	ctx := context.Background()
+
+	done := make(chan error, 1)
+	go func() { done <- b.Approve(ctx, "exec", nil, "discord", "kid") }()
+	msgBus.SubscribeOutbound(ctx)
+	msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "parent", SenderID: "42", Content: "deny"})
+	if err := <-done; err == nil {
+		t.Error("expected denial")
+	}
+	// The conversing chat is told the call was blocked.
+	if msg, _ := msgBus.SubscribeOutbound(ctx); msg.Channel != "discord" || msg.ChatID != "kid" || msg.Content != "tool_denied: exec" {
+		t.Errorf("denial notice = %+v", msg)
+	}
+
+	if err := b.Approve(ctx, "exec", nil, "discord", "kid"); err == nil {
+		t.Error("expected a timeout to deny the call")
+	}
+}
+
+func TestApprovalBroker_Unlisted(t *testing.T) {
+	b, _ := newTestBroker(t, 1)
+	ctx := context.Background()
+	if err := b.Approve(ctx, "read_file", nil, "discord", "kid"); err != nil {
+		t.Errorf("unlisted tool: %v", err)
+	}
+	if err := b.Approve(ctx, "exec", nil, "cli", "direct"); err != nil {
+		t.Errorf("local CLI call: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package agent
+
+import (
+	"context"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/session"
+)
+
+// budgetSkippedTool is the result of tool calls skipped because the turn
+// ran past its latency budget.
+const budgetSkippedTool = "Skipped: this turn is over its time budget. Answer with what you have."
+
+// latencyBudget tracks a turn against the latency budget of its channel.
+// A nil *latencyBudget has no budget and is never over it.
+type latencyBudget struct {
+	budget    time.Duration
+	fastModel string
+	start     time.Time
+	nowFunc   func() time.Time
+	// overAt is when the turn was first found over its budget.
+	overAt time.Time
+}
+
+// newLatencyBudget starts tracking a turn on channel, or returns nil if
+// turns on channel have no budget.
+func newLatencyBudget(cfg config.LatencyBudgetConfig, channel string) *latencyBudget {
+	budget := cfg.For(channel)
+	if budget <= 0 {
+		return nil
+	}
+	return &latencyBudget{budget: budget, fastModel: cfg.FastModel, start: time.Now(), nowFunc: time.Now}
+}
+
+// over reports whether the turn has run past its budget, logging when it
+// first does.
+func (b *latencyBudget) over(ctx context.Context, agentID string, iteration int) bool {
+	if b == nil {
+		return false
+	}
+	if !b.overAt.IsZero() {
+		return true
+	}
+	now := b.nowFunc()
+	if now.Sub(b.start) < b.budget {
+		return false
+	}
+	b.overAt = now
+	logger.InfoCF("agent", "Turn is over its latency budget, hurrying", logger.TraceFields(ctx, map[string]interface{}{
+		"agent_id":   agentID,
+		"iteration":  iteration,
+		"budget":     b.budget.String(),
+		"elapsed":    now.Sub(b.start).Round(time.Millisecond).String(),
+		"fast_model": b.fastModel,
+	}))
+	return true
+}
+
+// resolveFastModel creates the provider of the fast model of the agent's
+// latency budget, a model_list entry like the agent's own model. A model
+// that cannot be used is logged and left out, so that turns over their
+// budget keep the agent's models.
+func resolveFastModel(agentID string, defaults *config.AgentDefaults, cfg *config.Config) (providers.LLMProvider, string) {
+	name := defaults.LatencyBudget.FastModel
+	if cfg == nil || name == "" {
+		return nil, ""
+	}
+	provider, modelID, err := providers.CreateModelProvider(cfg, name)
+	if err != nil {
+		logger.WarnCF("agent", "Fast model unavailable", map[string]interface{}{
+			"agent_id":   agentID,
+			"fast_model": name,
+			"error":      err.Error(),
+		})
+		return nil, ""
+	}
+	return provider, modelID
+}
+
+// record adds the outcome of the turn, which has ended, to gen.
+func (b *latencyBudget) record(ctx context.Context, agentID string, gen *session.Generation) {
+	if b == nil {
+		return
+	}
+	elapsed := b.nowFunc().Sub(b.start)
+	gen.ElapsedMs = elapsed.Milliseconds()
+	gen.LatencyBudgetMs = b.budget.Milliseconds()
+	gen.OverBudget = elapsed >= b.budget
+	logger.DebugCF("agent", "Latency budget outcome", logger.TraceFields(ctx, map[string]interface{}{
+		"agent_id":    agentID,
+		"budget":      b.budget.String(),
+		"elapsed":     elapsed.Round(time.Millisecond).String(),
+		"over_budget": gen.OverBudget,
+		"hurried":     !b.overAt.IsZero(),
+	}))
+}
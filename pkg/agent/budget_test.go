@@ -0,0 +1,122 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/session"
+)
+
+// toolThenAnswerProvider asks for the probe tool once, then answers, and
+// records the models it was asked for and the tools it was sent.
+type toolThenAnswerProvider struct {
+	models []string
+	tools  int
+	last   []providers.Message
+}
+
+func (p *toolThenAnswerProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	p.models = append(p.models, model)
+	p.tools += len(tools)
+	p.last = messages
+	if len(p.models) == 1 {
+		return &providers.LLMResponse{ToolCalls: []providers.ToolCall{{ID: "call_1", Name: "probe", Arguments: map[string]interface{}{}}}}, nil
+	}
+	return &providers.LLMResponse{Content: "done"}, nil
+}
+
+func (p *toolThenAnswerProvider) GetDefaultModel() string { return "test-model" }
+
+func TestLatencyBudgetConfig_For(t *testing.T) {
+	cfg := config.LatencyBudgetConfig{Seconds: 30, Channels: map[string]int{"telegram": 10, "cli": 0}}
+	if got := cfg.For("telegram"); got != 10*time.Second {
+		t.Errorf("For(telegram) = %v", got)
+	}
+	if got := cfg.For("cli"); got != 0 {
+		t.Errorf("For(cli) = %v, want no budget", got)
+	}
+	if got := cfg.For("slack"); got != 30*time.Second {
+		t.Errorf("For(slack) = %v, want the default", got)
+	}
+	if newLatencyBudget(cfg, "cli") != nil {
+		t.Error("a channel without a budget should not be tracked")
+	}
+}
+
+func TestRunLLMIteration_OverBudgetHurries(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	provider := &toolThenAnswerProvider{}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+	probe := &probeTool{ran: make(chan struct{})}
+	al.RegisterTool(probe)
+	agent := al.registry.GetDefaultAgent()
+	fast := &toolThenAnswerProvider{}
+	agent.FastProvider, agent.FastModel = fast, "fast-model"
+
+	budget := newLatencyBudget(config.LatencyBudgetConfig{Seconds: 10, FastModel: "fast-model"}, "telegram")
+	// The turn is already past its budget when the first request is sent.
+	budget.start = time.Now().Add(-time.Minute)
+
+	gen := &session.Generation{}
+	messages := []providers.Message{{Role: "system", Content: "sys"}, {Role: "user", Content: "probe it"}}
+	content, _, err := al.runLLMIteration(context.Background(), agent, messages, processOptions{SessionKey: "s", Channel: "telegram", ChatID: "1"}, gen, budget)
+	if err != nil || content != "done" {
+		t.Fatalf("runLLMIteration() = %q, %v", content, err)
+	}
+	if len(provider.models) != 0 {
+		t.Errorf("agent's provider got requests for %v, want none", provider.models)
+	}
+	if len(fast.models) != 2 || fast.models[0] != "fast-model" || fast.models[1] != "fast-model" {
+		t.Errorf("models = %v, want the fast model for every request", fast.models)
+	}
+	if fast.tools != 0 {
+		t.Errorf("fast model was sent %d tool definitions, want none", fast.tools)
+	}
+	if n := probe.runs.Load(); n != 0 {
+		t.Errorf("probe ran %d times, want it skipped", n)
+	}
+	if tool := fast.last[len(fast.last)-1]; tool.Role != "tool" || !strings.Contains(tool.Content, "time budget") {
+		t.Errorf("tool result = %+v", tool)
+	}
+
+	budget.record(context.Background(), agent.ID, gen)
+	if !gen.OverBudget || gen.LatencyBudgetMs != 10000 || gen.ElapsedMs < 60000 || gen.Model != "fast-model" {
+		t.Errorf("generation = %+v", gen)
+	}
+	if report := formatGeneration(*gen); !strings.Contains(report, "over its 10s budget") {
+		t.Errorf("formatGeneration() = %q", report)
+	}
+}
+
+func TestResolveFastModel(t *testing.T) {
+	defaults := &config.AgentDefaults{LatencyBudget: config.LatencyBudgetConfig{FastModel: "quick"}}
+	cfg := &config.Config{ModelList: []config.ModelConfig{{
+		ModelName: "quick",
+		Model:     "openai/gpt-4o-mini",
+		APIBase:   "http://localhost:1/v1",
+		APIKey:    "sk-test",
+	}}}
+	provider, model := resolveFastModel("main", defaults, cfg)
+	if provider == nil || model != "gpt-4o-mini" {
+		t.Errorf("resolveFastModel() = %v, %q; want a provider for gpt-4o-mini", provider, model)
+	}
+
+	defaults.LatencyBudget.FastModel = "missing"
+	if provider, _ := resolveFastModel("main", defaults, cfg); provider != nil {
+		t.Error("resolveFastModel() created a provider for a model not in model_list")
+	}
+}
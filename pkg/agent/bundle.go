@@ -0,0 +1,381 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/routing"
+	"github.com/sipeed/picoclaw/pkg/vfs"
+)
+
+// BundleVersion is the version of the bundles ExportBundle writes. It
+// changes only when an older PicoClaw could misread a bundle.
+const BundleVersion = 1
+
+// maxBundleFile bounds the size of each file a bundle carries.
+const maxBundleFile = 1 << 20
+
+// bundlePromptFiles are the system prompt files of a workspace that a
+// bundle carries. USER.md, which describes the user, stays behind.
+var bundlePromptFiles = []string{"AGENTS.md", "SOUL.md", "IDENTITY.md"}
+
+// Bundle is an agent definition that can be shared: its system prompt,
+// skills, tools and models, without keys, logins or conversations.
+type Bundle struct {
+	Version int    `json:"version"`
+	ID      string `json:"id"`
+	Name    string `json:"name,omitempty"`
+	// Prompt holds the system prompt files of the agent's workspace, by
+	// file name.
+	Prompt map[string]string `json:"prompt,omitempty"`
+	// Skills holds the files of the agent's workspace skills, by path in
+	// the skills directory, such as "weather/SKILL.md".
+	Skills map[string]string `json:"skills,omitempty"`
+	// SkillsFilter and Tools are the agent's skills and tools settings.
+	SkillsFilter []string `json:"skills_filter,omitempty"`
+	Tools        []string `json:"tools,omitempty"`
+	// Model is the agent's model and fallbacks, and Models their
+	// model_list entries without keys or local settings. CustomProviders
+	// are the custom providers these entries use.
+	Model           *config.AgentModelConfig      `json:"model,omitempty"`
+	Models          []config.ModelConfig          `json:"models,omitempty"`
+	CustomProviders []config.CustomProviderConfig `json:"custom_providers,omitempty"`
+	Subagents       *config.SubagentsConfig       `json:"subagents,omitempty"`
+	Summarizer      *config.SummarizerConfig      `json:"summarizer,omitempty"`
+}
+
+// findAgentConfig returns the agents.list entry of the agent id, or nil
+// for the implicit main agent of a config without agents.list.
+func findAgentConfig(cfg *config.Config, id string) (*config.AgentConfig, error) {
+	id = routing.NormalizeAgentID(id)
+	for i := range cfg.Agents.List {
+		if routing.NormalizeAgentID(cfg.Agents.List[i].ID) == id {
+			return &cfg.Agents.List[i], nil
+		}
+	}
+	if len(cfg.Agents.List) == 0 && id == routing.DefaultAgentID {
+		return nil, nil
+	}
+	return nil, fmt.Errorf("no agent %q", id)
+}
+
+// ExportBundle returns the bundle of the agent id. Workspace skill files
+// that are not text or are over 1 MB are left out with a warning.
+func ExportBundle(cfg *config.Config, id string) (*Bundle, error) {
+	agentCfg, err := findAgentConfig(cfg, id)
+	if err != nil {
+		return nil, err
+	}
+	defaults := &cfg.Agents.Defaults
+	workspace := resolveAgentWorkspace(agentCfg, defaults)
+
+	b := &Bundle{
+		Version: BundleVersion,
+		ID:      routing.NormalizeAgentID(id),
+		Prompt:  map[string]string{},
+		Skills:  map[string]string{},
+		Model: &config.AgentModelConfig{
+			Primary:   resolveAgentModel(agentCfg, defaults),
+			Fallbacks: resolveAgentFallbacks(agentCfg, defaults),
+		},
+	}
+	if agentCfg != nil {
+		b.Name = agentCfg.Name
+		b.SkillsFilter = agentCfg.Skills
+		b.Tools = agentCfg.Tools
+		b.Subagents = agentCfg.Subagents
+		b.Summarizer = agentCfg.Summarizer
+	}
+
+	for _, name := range bundlePromptFiles {
+		data, err := vfs.ReadFile(filepath.Join(workspace, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		b.Prompt[name] = string(data)
+	}
+
+	if err := b.addSkills(filepath.Join(workspace, "skills")); err != nil {
+		return nil, err
+	}
+	b.addModels(cfg)
+	return b, nil
+}
+
+// addSkills adds the files of the skills in dir that the bundle's skills
+// filter allows.
+func (b *Bundle) addSkills(dir string) error {
+	err := vfs.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		skill, _, ok := strings.Cut(rel, "/")
+		if !ok || !skillAllowed(skill, b.SkillsFilter) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.Size() > maxBundleFile {
+			logger.WarnCF("agent", "Skill file too large for a bundle, left out", map[string]interface{}{"file": rel})
+			return nil
+		}
+		data, err := vfs.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if !utf8.Valid(data) {
+			logger.WarnCF("agent", "Skill file is not text, left out of the bundle", map[string]interface{}{"file": rel})
+			return nil
+		}
+		b.Skills[rel] = string(data)
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// skillAllowed reports whether a skills filter allows the skill name. An
+// empty filter allows all skills.
+func skillAllowed(name string, filter []string) bool {
+	if len(filter) == 0 {
+		return true
+	}
+	for _, f := range filter {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// addModels adds the model_list entries of the bundle's models, and the
+// custom providers they use, clearing their keys, logins and the settings
+// that only make sense on this machine.
+func (b *Bundle) addModels(cfg *config.Config) {
+	names := append([]string{b.Model.Primary}, b.Model.Fallbacks...)
+	providers := map[string]bool{}
+	for _, name := range names {
+		for _, m := range cfg.ModelList {
+			if m.ModelName != name {
+				continue
+			}
+			clearLocalSettings(&m)
+			b.Models = append(b.Models, m)
+			if protocol, _, ok := strings.Cut(m.Model, "/"); ok {
+				providers[protocol] = true
+			}
+		}
+	}
+	for _, c := range cfg.CustomProviders {
+		if providers[c.Name] {
+			b.CustomProviders = append(b.CustomProviders, c)
+		}
+	}
+}
+
+// clearLocalSettings clears the settings of a model_list entry that hold
+// keys or logins, that only make sense on this machine, or that run
+// programs or code on it: the Copilot bridge, middleware and mock
+// fixtures.
+func clearLocalSettings(m *config.ModelConfig) {
+	m.APIKey = ""
+	m.Account = ""
+	m.Proxy = ""
+	m.Workspace = ""
+	m.Bridge = nil
+	m.Middleware = nil
+	m.Fixture = ""
+}
+
+// ImportedBundle reports what ImportBundle added.
+type ImportedBundle struct {
+	Agent     config.AgentConfig
+	Workspace string
+	// Models are the model_list entries added, which need keys.
+	Models []string
+}
+
+// BundleEndpoint is where the requests of a model_list entry a bundle
+// adds are sent, for the user to check before giving it a key: a bundle
+// can point a familiar model name at any server.
+type BundleEndpoint struct {
+	ModelName  string
+	Model      string
+	APIBase    string // empty for the provider's own endpoint
+	AuthMethod string
+	// CustomProvider is the custom provider of the entry that the bundle
+	// adds too, if any, and AuthHeader the header it sends the key in.
+	CustomProvider string
+	AuthHeader     string
+}
+
+// Endpoints returns the endpoints of the model_list entries ImportBundle
+// would add to cfg.
+func (b *Bundle) Endpoints(cfg *config.Config) []BundleEndpoint {
+	known := map[string]bool{}
+	for _, m := range cfg.ModelList {
+		known[m.ModelName] = true
+	}
+	added := map[string]config.CustomProviderConfig{}
+	for _, c := range b.CustomProviders {
+		added[c.Name] = c
+	}
+	for _, c := range cfg.CustomProviders {
+		delete(added, c.Name)
+	}
+
+	var endpoints []BundleEndpoint
+	for _, m := range b.Models {
+		if known[m.ModelName] {
+			continue
+		}
+		e := BundleEndpoint{ModelName: m.ModelName, Model: m.Model, APIBase: m.APIBase, AuthMethod: m.AuthMethod}
+		if protocol, _, ok := strings.Cut(m.Model, "/"); ok {
+			if c, ok := added[protocol]; ok {
+				e.CustomProvider = c.Name
+				e.AuthHeader = c.AuthHeader
+				if e.APIBase == "" {
+					e.APIBase = c.APIBase
+				}
+			}
+		}
+		endpoints = append(endpoints, e)
+	}
+	return endpoints
+}
+
+// ImportBundle adds the agent of b to cfg, as id if it is not empty, and
+// writes its files to workspace, or the agent's default workspace if
+// workspace is empty. It fails if the agent exists or the workspace is
+// not empty. Model entries and custom providers are added only if cfg
+// lacks them, and entries without the settings ExportBundle clears, as a
+// bundle from elsewhere could set a bridge to launch. The caller saves
+// cfg.
+func ImportBundle(cfg *config.Config, b *Bundle, id, workspace string) (*ImportedBundle, error) {
+	if b.Version < 1 || b.Version > BundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d", b.Version)
+	}
+	if id == "" {
+		id = b.ID
+	}
+	if strings.TrimSpace(id) == "" {
+		return nil, fmt.Errorf("bundle has no agent id")
+	}
+	id = routing.NormalizeAgentID(id)
+	if id == routing.DefaultAgentID {
+		return nil, fmt.Errorf("cannot import over the %s agent; choose another id", routing.DefaultAgentID)
+	}
+	if _, err := findAgentConfig(cfg, id); err == nil {
+		return nil, fmt.Errorf("agent %q already exists", id)
+	}
+
+	agentCfg := config.AgentConfig{
+		ID:         id,
+		Name:       b.Name,
+		Workspace:  workspace,
+		Model:      b.Model,
+		Skills:     b.SkillsFilter,
+		Subagents:  b.Subagents,
+		Summarizer: b.Summarizer,
+		Tools:      b.Tools,
+	}
+	dir := resolveAgentWorkspace(&agentCfg, &cfg.Agents.Defaults)
+	if entries, err := vfs.ReadDir(dir); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("workspace %s is not empty", dir)
+	}
+
+	files := map[string]string{}
+	for name, content := range b.Prompt {
+		if !isPromptFile(name) {
+			return nil, fmt.Errorf("bundle has unknown prompt file %q", name)
+		}
+		files[name] = content
+	}
+	for rel, content := range b.Skills {
+		clean := filepath.Clean(filepath.FromSlash(rel))
+		if !filepath.IsLocal(clean) || !strings.Contains(filepath.ToSlash(clean), "/") {
+			return nil, fmt.Errorf("bundle has invalid skill file path %q", rel)
+		}
+		files[filepath.Join("skills", clean)] = content
+	}
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		full := filepath.Join(dir, p)
+		if err := vfs.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			return nil, err
+		}
+		if err := vfs.WriteFile(full, []byte(files[p]), 0644); err != nil {
+			return nil, err
+		}
+	}
+
+	imported := &ImportedBundle{Agent: agentCfg, Workspace: dir}
+	known := map[string]bool{}
+	for _, m := range cfg.ModelList {
+		known[m.ModelName] = true
+	}
+	for _, m := range b.Models {
+		if known[m.ModelName] {
+			continue
+		}
+		clearLocalSettings(&m)
+		cfg.ModelList = append(cfg.ModelList, m)
+		if len(imported.Models) == 0 || imported.Models[len(imported.Models)-1] != m.ModelName {
+			imported.Models = append(imported.Models, m.ModelName)
+		}
+	}
+	for _, c := range b.CustomProviders {
+		exists := false
+		for _, have := range cfg.CustomProviders {
+			if have.Name == c.Name {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			cfg.CustomProviders = append(cfg.CustomProviders, c)
+		}
+	}
+
+	// Keep the implicit main agent the default once agents.list exists.
+	if len(cfg.Agents.List) == 0 {
+		cfg.Agents.List = append(cfg.Agents.List, config.AgentConfig{ID: routing.DefaultAgentID, Default: true})
+	}
+	cfg.Agents.List = append(cfg.Agents.List, agentCfg)
+	return imported, nil
+}
+
+func isPromptFile(name string) bool {
+	for _, f := range bundlePromptFiles {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strings"
 	"time"
@@ -12,6 +13,7 @@ import (
 	"github.com/sipeed/picoclaw/pkg/providers"
 	"github.com/sipeed/picoclaw/pkg/skills"
 	"github.com/sipeed/picoclaw/pkg/tools"
+	"github.com/sipeed/picoclaw/pkg/vfs"
 )
 
 type ContextBuilder struct {
@@ -43,6 +45,23 @@ func NewContextBuilder(workspace string) *ContextBuilder {
 	}
 }
 
+// currentTimeLine matches the current time in the identity section of the
+// system prompt.
+var currentTimeLine = regexp.MustCompile(`(?m)^## Current Time\n.*$`)
+
+// cacheKeyMessages returns messages as the agent's response cache keys
+// them: without the current time in the system prompt, which would make
+// every request a minute later miss.
+func cacheKeyMessages(agent *AgentInstance, messages []providers.Message) []providers.Message {
+	if agent.Cache == nil || len(messages) == 0 || messages[0].Role != "system" {
+		return messages
+	}
+	key := make([]providers.Message, len(messages))
+	copy(key, messages)
+	key[0].Content = currentTimeLine.ReplaceAllString(key[0].Content, "## Current Time")
+	return key
+}
+
 // SetToolsRegistry sets the tools registry for dynamic tool summary generation.
 func (cb *ContextBuilder) SetToolsRegistry(registry *tools.ToolRegistry) {
 	cb.tools = registry
@@ -106,35 +125,53 @@ func (cb *ContextBuilder) buildToolsSection() string {
 	return sb.String()
 }
 
-func (cb *ContextBuilder) BuildSystemPrompt() string {
-	parts := []string{}
+// PromptSection is a named part of the system prompt.
+type PromptSection struct {
+	Name    string
+	Content string
+}
 
+// SystemPromptSections returns the parts the system prompt is built from,
+// in order. Empty parts are omitted.
+func (cb *ContextBuilder) SystemPromptSections() []PromptSection {
 	// Core identity section
-	parts = append(parts, cb.getIdentity())
+	sections := []PromptSection{{Name: "identity", Content: cb.getIdentity()}}
 
 	// Bootstrap files
 	bootstrapContent := cb.LoadBootstrapFiles()
 	if bootstrapContent != "" {
-		parts = append(parts, bootstrapContent)
+		sections = append(sections, PromptSection{Name: "bootstrap", Content: bootstrapContent})
 	}
 
 	// Skills - show summary, AI can read full content with read_file tool
 	skillsSummary := cb.skillsLoader.BuildSkillsSummary()
 	if skillsSummary != "" {
-		parts = append(parts, fmt.Sprintf(`# Skills
+		sections = append(sections, PromptSection{Name: "skills", Content: fmt.Sprintf(`# Skills
 
 The following skills extend your capabilities. To use a skill, read its SKILL.md file using the read_file tool.
 
-%s`, skillsSummary))
+%s`, skillsSummary)})
 	}
 
 	// Memory context
 	memoryContext := cb.memory.GetMemoryContext()
 	if memoryContext != "" {
-		parts = append(parts, "# Memory\n\n"+memoryContext)
+		sections = append(sections, PromptSection{Name: "memory", Content: "# Memory\n\n" + memoryContext})
 	}
 
-	// Join with "---" separator
+	return sections
+}
+
+func (cb *ContextBuilder) BuildSystemPrompt() string {
+	return joinSections(cb.SystemPromptSections())
+}
+
+// joinSections joins prompt sections with "---" separators.
+func joinSections(sections []PromptSection) string {
+	parts := make([]string, len(sections))
+	for i, s := range sections {
+		parts[i] = s.Content
+	}
 	return strings.Join(parts, "\n\n---\n\n")
 }
 
@@ -149,7 +186,7 @@ func (cb *ContextBuilder) LoadBootstrapFiles() string {
 	var sb strings.Builder
 	for _, filename := range bootstrapFiles {
 		filePath := filepath.Join(cb.workspace, filename)
-		if data, err := os.ReadFile(filePath); err == nil {
+		if data, err := vfs.ReadFile(filePath); err == nil {
 			fmt.Fprintf(&sb, "## %s\n\n%s\n\n", filename, data)
 		}
 	}
@@ -158,13 +195,24 @@ func (cb *ContextBuilder) LoadBootstrapFiles() string {
 }
 
 func (cb *ContextBuilder) BuildMessages(history []providers.Message, summary string, currentMessage string, media []string, channel, chatID string) []providers.Message {
+	messages, _ := cb.BuildMessagesWithSections(history, summary, currentMessage, media, channel, chatID)
+	return messages
+}
+
+// BuildMessagesWithSections is BuildMessages that also returns the
+// sections the system prompt was assembled from, including the session
+// and summary parts, for context inspection.
+func (cb *ContextBuilder) BuildMessagesWithSections(history []providers.Message, summary string, currentMessage string, media []string, channel, chatID string) ([]providers.Message, []PromptSection) {
 	messages := []providers.Message{}
 
-	systemPrompt := cb.BuildSystemPrompt()
+	sections := cb.SystemPromptSections()
+	systemPrompt := joinSections(sections)
 
 	// Add Current Session info if provided
 	if channel != "" && chatID != "" {
-		systemPrompt += fmt.Sprintf("\n\n## Current Session\nChannel: %s\nChat ID: %s", channel, chatID)
+		session := fmt.Sprintf("\n\n## Current Session\nChannel: %s\nChat ID: %s", channel, chatID)
+		systemPrompt += session
+		sections = append(sections, PromptSection{Name: "session", Content: session})
 	}
 
 	// Log system prompt summary for debugging (debug mode only)
@@ -186,7 +234,9 @@ func (cb *ContextBuilder) BuildMessages(history []providers.Message, summary str
 		})
 
 	if summary != "" {
-		systemPrompt += "\n\n## Summary of Previous Conversation\n\n" + summary
+		summarySection := "\n\n## Summary of Previous Conversation\n\n" + summary
+		systemPrompt += summarySection
+		sections = append(sections, PromptSection{Name: "summary", Content: summarySection})
 	}
 
 	history = sanitizeHistoryForProvider(history)
@@ -205,7 +255,7 @@ func (cb *ContextBuilder) BuildMessages(history []providers.Message, summary str
 		})
 	}
 
-	return messages
+	return messages, sections
 }
 
 func sanitizeHistoryForProvider(history []providers.Message) []providers.Message {
@@ -0,0 +1,142 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/utils"
+	"github.com/sipeed/picoclaw/pkg/vfs"
+)
+
+const (
+	// digestMessagesPerSession caps how many recent messages of each
+	// session are fed into a digest.
+	digestMessagesPerSession = 30
+	// digestCharsPerMessage truncates long messages in the digest prompt.
+	digestCharsPerMessage = 500
+)
+
+// digestPeriods maps supported digest periods to their look-back window.
+var digestPeriods = map[string]time.Duration{
+	"daily":  24 * time.Hour,
+	"weekly": 7 * 24 * time.Hour,
+}
+
+// GenerateDigest summarizes conversations and agent activity of the default
+// agent over the given period ("daily" or "weekly"). scope limits the digest
+// to sessions whose key contains it (e.g. a channel name); empty means all
+// sessions. The digest is stored under memory/digests/ in the agent
+// workspace and returned. An empty string with no error means there was no
+// activity to summarize.
+func (al *AgentLoop) GenerateDigest(ctx context.Context, period, scope string) (string, error) {
+	window, ok := digestPeriods[period]
+	if !ok {
+		return "", fmt.Errorf("unknown digest period %q (use daily or weekly)", period)
+	}
+
+	agent := al.registry.GetDefaultAgent()
+	if agent == nil {
+		return "", fmt.Errorf("no default agent configured")
+	}
+
+	now := time.Now()
+	var sb strings.Builder
+	count := 0
+	for _, s := range agent.Sessions.UpdatedSince(now.Add(-window)) {
+		if scope != "" && !strings.Contains(s.Key, scope) {
+			continue
+		}
+
+		msgs := s.Messages
+		if len(msgs) > digestMessagesPerSession {
+			msgs = msgs[len(msgs)-digestMessagesPerSession:]
+		}
+
+		var body strings.Builder
+		for _, m := range msgs {
+			if (m.Role != "user" && m.Role != "assistant") || m.Content == "" {
+				continue
+			}
+			fmt.Fprintf(&body, "%s: %s\n", m.Role, utils.Truncate(m.Content, digestCharsPerMessage))
+		}
+		if body.Len() == 0 && s.Summary == "" {
+			continue
+		}
+
+		fmt.Fprintf(&sb, "\n## Session %s (last active %s)\n", s.Key, s.Updated.Format("2006-01-02 15:04"))
+		if s.Summary != "" {
+			fmt.Fprintf(&sb, "Earlier summary: %s\n", s.Summary)
+		}
+		sb.WriteString(body.String())
+		count++
+	}
+
+	if count == 0 {
+		logger.InfoCF("agent", "No activity for digest", map[string]interface{}{
+			"period": period,
+			"scope":  scope,
+		})
+		return "", nil
+	}
+
+	prompt := fmt.Sprintf("Write a concise %s digest of the following conversations and agent activity. "+
+		"Group related topics, list decisions made, open questions and follow-ups, and skip small talk. "+
+		"Use short markdown bullet points.\n%s", period, sb.String())
+
+	resp, err := al.scheduler.Provider(agent.Provider).Chat(ctx, []providers.Message{{Role: "user", Content: prompt}}, nil, agent.Model, map[string]interface{}{
+		"max_tokens":  1024,
+		"temperature": 0.3,
+	})
+	al.recordUsage("", agent.Model, resp)
+	if err != nil {
+		return "", fmt.Errorf("digest generation failed: %w", err)
+	}
+	digest := strings.TrimSpace(resp.Content)
+	if digest == "" {
+		return "", nil
+	}
+
+	path, err := saveDigest(agent.Workspace, period, scope, digest, now)
+	if err != nil {
+		return digest, err
+	}
+
+	logger.InfoCF("agent", "Digest generated", map[string]interface{}{
+		"period":   period,
+		"scope":    scope,
+		"sessions": count,
+		"path":     path,
+	})
+	return digest, nil
+}
+
+// saveDigest writes a digest note to memory/digests/YYYYMMDD-<period>[-scope].md.
+func saveDigest(workspace, period, scope, digest string, now time.Time) (string, error) {
+	dir := filepath.Join(workspace, "memory", "digests")
+	if err := vfs.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create digest directory: %w", err)
+	}
+
+	name := now.Format("20060102") + "-" + period
+	title := fmt.Sprintf("# %s digest — %s", strings.ToUpper(period[:1])+period[1:], now.Format("2006-01-02"))
+	if scope != "" {
+		name += "-" + strings.NewReplacer("/", "_", ":", "_", " ", "_").Replace(scope)
+		title += fmt.Sprintf(" (%s)", scope)
+	}
+
+	path := filepath.Join(dir, name+".md")
+	if err := vfs.WriteFile(path, []byte(title+"\n\n"+digest+"\n"), 0644); err != nil {
+		return "", fmt.Errorf("failed to write digest: %w", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,101 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+type promptCapturingProvider struct {
+	prompts []string
+}
+
+func (p *promptCapturingProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	p.prompts = append(p.prompts, messages[len(messages)-1].Content)
+	return &providers.LLMResponse{Content: "- discussed the garden"}, nil
+}
+
+func (p *promptCapturingProvider) GetDefaultModel() string {
+	return "mock-model"
+}
+
+func newDigestTestLoop(t *testing.T, provider providers.LLMProvider) (*AgentLoop, string) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         tmpDir,
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	return NewAgentLoop(cfg, bus.NewMessageBus(), provider), tmpDir
+}
+
+// TestGenerateDigest_SummarizesScopedSessions verifies that only sessions
+// matching the scope are sent to the model and that the digest is saved.
+func TestGenerateDigest_SummarizesScopedSessions(t *testing.T) {
+	provider := &promptCapturingProvider{}
+	al, workspace := newDigestTestLoop(t, provider)
+	agent := al.registry.GetDefaultAgent()
+
+	agent.Sessions.AddMessage("agent:main:telegram:direct:1", "user", "How are the tomatoes?")
+	agent.Sessions.AddMessage("agent:main:telegram:direct:1", "assistant", "Growing well.")
+	agent.Sessions.AddMessage("agent:main:discord:direct:2", "user", "secret discord topic")
+
+	digest, err := al.GenerateDigest(context.Background(), "daily", "telegram")
+	if err != nil {
+		t.Fatalf("GenerateDigest() error: %v", err)
+	}
+	if digest != "- discussed the garden" {
+		t.Errorf("digest = %q", digest)
+	}
+
+	if len(provider.prompts) != 1 {
+		t.Fatalf("expected 1 LLM call, got %d", len(provider.prompts))
+	}
+	prompt := provider.prompts[0]
+	if !strings.Contains(prompt, "How are the tomatoes?") {
+		t.Error("prompt is missing the telegram conversation")
+	}
+	if strings.Contains(prompt, "secret discord topic") {
+		t.Error("prompt should not include sessions outside the scope")
+	}
+
+	files, _ := filepath.Glob(filepath.Join(workspace, "memory", "digests", "*-daily-telegram.md"))
+	if len(files) != 1 {
+		t.Fatalf("expected 1 digest note, got %d", len(files))
+	}
+	data, _ := os.ReadFile(files[0])
+	if !strings.Contains(string(data), "discussed the garden") {
+		t.Errorf("digest note content = %q", data)
+	}
+}
+
+// TestGenerateDigest_NoActivity verifies that no LLM call is made when there
+// is nothing to summarize, and that unknown periods are rejected.
+func TestGenerateDigest_NoActivity(t *testing.T) {
+	provider := &promptCapturingProvider{}
+	al, _ := newDigestTestLoop(t, provider)
+
+	digest, err := al.GenerateDigest(context.Background(), "weekly", "")
+	if err != nil || digest != "" {
+		t.Errorf("GenerateDigest() = %q, %v; want empty, nil", digest, err)
+	}
+	if len(provider.prompts) != 0 {
+		t.Errorf("expected no LLM calls, got %d", len(provider.prompts))
+	}
+
+	if _, err := al.GenerateDigest(context.Background(), "hourly", ""); err == nil {
+		t.Error("expected error for unknown period")
+	}
+}
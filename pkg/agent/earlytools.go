@@ -0,0 +1,124 @@
+package agent
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/sipeed/picoclaw/pkg/crash"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+// earlyTools runs the tool calls of a streaming response while the model is
+// still generating. Calls run one at a time in the order they arrive, just
+// as they would once the response is complete. Only calls of read-only
+// tools run early, as the response may still fail and be requested again:
+// from the first other call on, calls are left to run once the response
+// is complete, so that none runs before a call it follows.
+type earlyTools struct {
+	exec     func(providers.ToolCall) *tools.ToolResult
+	readOnly func(providers.ToolCall) bool
+
+	mu      sync.Mutex
+	pending []providers.ToolCall
+	ran     []earlyResult
+	closed  bool
+
+	wake chan struct{}
+	done chan struct{}
+}
+
+type earlyResult struct {
+	call   providers.ToolCall
+	result *tools.ToolResult
+}
+
+func newEarlyTools(ctx context.Context, exec func(providers.ToolCall) *tools.ToolResult, readOnly func(providers.ToolCall) bool) *earlyTools {
+	e := &earlyTools{
+		exec:     exec,
+		readOnly: readOnly,
+		wake:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	crash.Go(ctx, "agent", e.run)
+	return e
+}
+
+// submit queues a call. It does not block, so it is safe to call from a
+// provider's stream callback.
+func (e *earlyTools) submit(tc providers.ToolCall) {
+	e.mu.Lock()
+	if !e.closed && !e.readOnly(tc) {
+		e.closed = true
+	}
+	if !e.closed {
+		e.pending = append(e.pending, tc)
+	}
+	e.mu.Unlock()
+	e.signal()
+}
+
+// wait stops accepting calls and returns the results of all submitted ones
+// in the order they ran.
+func (e *earlyTools) wait() []earlyResult {
+	e.mu.Lock()
+	e.closed = true
+	e.mu.Unlock()
+	e.signal()
+
+	<-e.done
+	return e.ran
+}
+
+func (e *earlyTools) signal() {
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (e *earlyTools) run() {
+	defer close(e.done)
+	for {
+		e.mu.Lock()
+		if len(e.pending) == 0 {
+			closed := e.closed
+			e.mu.Unlock()
+			if closed {
+				return
+			}
+			<-e.wake
+			continue
+		}
+		tc := e.pending[0]
+		e.pending = e.pending[1:]
+		e.mu.Unlock()
+
+		result := e.exec(tc)
+
+		e.mu.Lock()
+		e.ran = append(e.ran, earlyResult{call: tc, result: result})
+		e.mu.Unlock()
+	}
+}
+
+// resultFor returns the result of the i-th call of the final response if it
+// already ran early, with the same arguments.
+func resultFor(ran []earlyResult, i int, tc providers.ToolCall) *tools.ToolResult {
+	if i < len(ran) && ran[i].call.ID == tc.ID && ran[i].call.Name == tc.Name && reflect.DeepEqual(ran[i].call.Arguments, tc.Arguments) {
+		return ran[i].result
+	}
+	return nil
+}
+
+// readOnlyCall reports whether tc calls a tool of registry that declares
+// itself read-only.
+func readOnlyCall(registry *tools.ToolRegistry, tc providers.ToolCall) bool {
+	tool, ok := registry.Get(tc.Name)
+	if !ok {
+		return false
+	}
+	ro, ok := tool.(tools.ReadOnlyTool)
+	return ok && ro.ReadOnly()
+}
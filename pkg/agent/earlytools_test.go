@@ -0,0 +1,161 @@
+package agent
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+// streamingMockProvider hands over a tool call mid-stream and only finishes
+// the response once the tool has run.
+type streamingMockProvider struct {
+	toolRan chan struct{}
+	calls   int
+	last    []providers.Message
+}
+
+func (m *streamingMockProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	return &providers.LLMResponse{Content: "not streamed"}, nil
+}
+
+func (m *streamingMockProvider) ChatStream(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}, onEvent func(providers.StreamEvent)) (*providers.LLMResponse, error) {
+	m.calls++
+	m.last = messages
+	if m.calls > 1 {
+		return &providers.LLMResponse{Content: "done"}, nil
+	}
+
+	tc := providers.ToolCall{ID: "call_1", Name: "probe", Arguments: map[string]interface{}{}}
+	onEvent(providers.StreamEvent{ToolCall: &tc})
+	select {
+	case <-m.toolRan:
+	case <-time.After(2 * time.Second):
+		return nil, context.DeadlineExceeded
+	}
+	return &providers.LLMResponse{ToolCalls: []providers.ToolCall{tc}, FinishReason: "tool_calls"}, nil
+}
+
+func (m *streamingMockProvider) GetDefaultModel() string {
+	return "mock-model"
+}
+
+type probeTool struct {
+	ran  chan struct{}
+	runs atomic.Int32
+}
+
+func (p *probeTool) Name() string        { return "probe" }
+func (p *probeTool) Description() string { return "test probe" }
+func (p *probeTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+}
+
+func (p *probeTool) ReadOnly() bool { return true }
+
+func (p *probeTool) Execute(ctx context.Context, args map[string]interface{}) *tools.ToolResult {
+	if p.runs.Add(1) == 1 {
+		close(p.ran)
+	}
+	return tools.SilentResult("probed")
+}
+
+func TestRunLLMIteration_StartsStreamedToolCallsEarly(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+				StreamToolCalls:   true,
+			},
+		},
+	}
+	probe := &probeTool{ran: make(chan struct{})}
+	provider := &streamingMockProvider{toolRan: probe.ran}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+	al.RegisterTool(probe)
+
+	response, err := al.ProcessDirectWithChannel(context.Background(), "probe it", "test-session", "test", "chat1")
+	if err != nil {
+		t.Fatalf("ProcessDirectWithChannel() error: %v", err)
+	}
+	if response != "done" {
+		t.Errorf("response = %q, want %q", response, "done")
+	}
+	if n := probe.runs.Load(); n != 1 {
+		t.Errorf("probe ran %d times, want 1", n)
+	}
+
+	var toolMsg *providers.Message
+	for i := range provider.last {
+		if provider.last[i].Role == "tool" {
+			toolMsg = &provider.last[i]
+		}
+	}
+	if toolMsg == nil || toolMsg.ToolCallID != "call_1" || toolMsg.Content != "probed" {
+		t.Errorf("tool result message = %+v", toolMsg)
+	}
+}
+
+func TestEarlyTools_RunsInOrder(t *testing.T) {
+	var order []string
+	e := newEarlyTools(context.Background(), func(tc providers.ToolCall) *tools.ToolResult {
+		order = append(order, tc.ID)
+		return tools.SilentResult(tc.ID)
+	}, func(providers.ToolCall) bool { return true })
+	e.submit(providers.ToolCall{ID: "a", Name: "t"})
+	e.submit(providers.ToolCall{ID: "b", Name: "t"})
+	ran := e.wait()
+	e.submit(providers.ToolCall{ID: "c", Name: "t"}) // ignored after wait
+
+	if len(ran) != 2 || len(order) != 2 || order[0] != "a" || order[1] != "b" {
+		t.Fatalf("ran = %+v, order = %v", ran, order)
+	}
+	if r := resultFor(ran, 1, providers.ToolCall{ID: "b", Name: "t"}); r == nil || r.ForLLM != "b" {
+		t.Errorf("resultFor(1) = %+v", r)
+	}
+	if r := resultFor(ran, 1, providers.ToolCall{ID: "x", Name: "t"}); r != nil {
+		t.Errorf("resultFor() with another call = %+v, want nil", r)
+	}
+}
+
+func TestEarlyTools_StopsAtFirstWritingCall(t *testing.T) {
+	var order []string
+	e := newEarlyTools(context.Background(), func(tc providers.ToolCall) *tools.ToolResult {
+		order = append(order, tc.ID)
+		return tools.SilentResult(tc.ID)
+	}, func(tc providers.ToolCall) bool { return tc.Name == "read" })
+	e.submit(providers.ToolCall{ID: "a", Name: "read"})
+	e.submit(providers.ToolCall{ID: "b", Name: "write"})
+	e.submit(providers.ToolCall{ID: "c", Name: "read"}) // after b, so not early
+	ran := e.wait()
+
+	if len(ran) != 1 || len(order) != 1 || order[0] != "a" {
+		t.Fatalf("ran = %+v, order = %v; want only the first call", ran, order)
+	}
+	changed := providers.ToolCall{ID: "a", Name: "read", Arguments: map[string]interface{}{"path": "x"}}
+	if r := resultFor(ran, 0, changed); r != nil {
+		t.Errorf("resultFor() with other arguments = %+v, want nil", r)
+	}
+}
+
+func TestReadOnlyCall(t *testing.T) {
+	registry := tools.NewToolRegistry()
+	registry.Register(&probeTool{})
+	registry.Register(tools.NewMessageTool())
+	if !readOnlyCall(registry, providers.ToolCall{Name: "probe"}) {
+		t.Error("probe is not read-only")
+	}
+	for _, name := range []string{"message", "missing"} {
+		if readOnlyCall(registry, providers.ToolCall{Name: name}) {
+			t.Errorf("%s is read-only", name)
+		}
+	}
+}
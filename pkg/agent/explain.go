@@ -0,0 +1,169 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// ContextReport describes what was sent to the model on the first request
+// of a turn, for debugging context assembly.
+type ContextReport struct {
+	Time       time.Time
+	AgentID    string
+	Model      string
+	SessionKey string
+
+	// Sections are the parts of the system prompt, in order.
+	Sections []PromptSection
+	// HistoryMessages is the number of history messages sent, after
+	// HistoryDropped orphaned tool messages were removed.
+	HistoryMessages int
+	HistoryDropped  int
+	HistoryTokens   int
+	CurrentTokens   int
+	Files           []providers.FileRef
+
+	Messages []providers.Message
+	Tools    []providers.ToolDefinition
+}
+
+// estimateTextTokens uses the same 2.5 characters per token heuristic as
+// estimateTokens.
+func estimateTextTokens(s string) int {
+	return utf8.RuneCountInString(s) * 2 / 5
+}
+
+func toolTokens(def providers.ToolDefinition) int {
+	data, _ := json.Marshal(def)
+	return estimateTextTokens(string(data))
+}
+
+// SystemTokens returns the estimated size of the system prompt.
+func (r *ContextReport) SystemTokens() int {
+	total := 0
+	for _, s := range r.Sections {
+		total += estimateTextTokens(s.Content)
+	}
+	return total
+}
+
+// ToolTokens returns the estimated size of the tool schemas.
+func (r *ContextReport) ToolTokens() int {
+	total := 0
+	for _, def := range r.Tools {
+		total += toolTokens(def)
+	}
+	return total
+}
+
+// TotalTokens returns the estimated size of the whole request.
+func (r *ContextReport) TotalTokens() int {
+	return r.SystemTokens() + r.HistoryTokens + r.CurrentTokens + r.ToolTokens()
+}
+
+// Format renders the report. The summary lists token estimates per part;
+// verbose adds the full system prompt, messages and tool schemas.
+func (r *ContextReport) Format(verbose bool) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Context for %s at %s (agent %s, model %s): ~%d tokens\n",
+		r.SessionKey, r.Time.Format("15:04:05"), r.AgentID, r.Model, r.TotalTokens())
+	fmt.Fprintf(&sb, "System prompt: ~%d tokens\n", r.SystemTokens())
+	for _, s := range r.Sections {
+		fmt.Fprintf(&sb, "  %-10s ~%d tokens\n", s.Name, estimateTextTokens(s.Content))
+	}
+	fmt.Fprintf(&sb, "History: %d messages, ~%d tokens", r.HistoryMessages, r.HistoryTokens)
+	if r.HistoryDropped > 0 {
+		fmt.Fprintf(&sb, " (%d orphaned tool messages dropped)", r.HistoryDropped)
+	}
+	sb.WriteString("\n")
+	fmt.Fprintf(&sb, "Current message: ~%d tokens", r.CurrentTokens)
+	if len(r.Files) > 0 {
+		fmt.Fprintf(&sb, ", %d attached files", len(r.Files))
+	}
+	sb.WriteString("\n")
+	fmt.Fprintf(&sb, "Tools: %d schemas, ~%d tokens\n", len(r.Tools), r.ToolTokens())
+	if !verbose {
+		return sb.String()
+	}
+
+	for _, s := range r.Sections {
+		fmt.Fprintf(&sb, "\n===== system: %s =====\n%s\n", s.Name, strings.TrimSpace(s.Content))
+	}
+	for i, m := range r.Messages[1:] {
+		fmt.Fprintf(&sb, "\n===== message %d: %s =====\n", i+1, m.Role)
+		for _, tc := range m.ToolCalls {
+			name, args := tc.Name, tc.Arguments
+			if tc.Function != nil {
+				name = tc.Function.Name
+			}
+			data, _ := json.Marshal(args)
+			fmt.Fprintf(&sb, "[tool call %s %s %s]\n", tc.ID, name, data)
+		}
+		if m.ToolCallID != "" {
+			fmt.Fprintf(&sb, "[result of %s]\n", m.ToolCallID)
+		}
+		for _, f := range m.Files {
+			fmt.Fprintf(&sb, "[file %s %s]\n", f.ID, f.Name)
+		}
+		sb.WriteString(m.Content)
+		sb.WriteString("\n")
+	}
+	for _, def := range r.Tools {
+		data, _ := json.MarshalIndent(def.Function, "", "  ")
+		fmt.Fprintf(&sb, "\n===== tool: %s (~%d tokens) =====\n%s\n", def.Function.Name, toolTokens(def), data)
+	}
+	return sb.String()
+}
+
+// recordContext keeps a report of the request about to be sent for the
+// session, replacing the previous one. hasCurrent tells whether the last
+// message is the current user message rather than history.
+func (al *AgentLoop) recordContext(agent *AgentInstance, sessionKey string, history []providers.Message, sections []PromptSection, messages []providers.Message, hasCurrent bool) {
+	report := &ContextReport{
+		Time:       time.Now(),
+		AgentID:    agent.ID,
+		Model:      agent.Model,
+		SessionKey: sessionKey,
+		Sections:   sections,
+		Messages:   messages,
+	}
+	_, report.Tools = agent.chatTools()
+
+	sent := messages[1:]
+	if n := len(sent); hasCurrent && n > 0 {
+		report.CurrentTokens = estimateTextTokens(sent[n-1].Content)
+		report.Files = sent[n-1].Files
+		sent = sent[:n-1]
+	}
+	report.HistoryMessages = len(sent)
+	report.HistoryDropped = len(history) - len(sent)
+	report.HistoryTokens = al.estimateTokens(sent)
+
+	al.contextReports.Store(sessionKey, report)
+}
+
+// LastContext returns the report of the most recent turn in the session.
+func (al *AgentLoop) LastContext(sessionKey string) (*ContextReport, bool) {
+	v, ok := al.contextReports.Load(sessionKey)
+	if !ok {
+		return nil, false
+	}
+	return v.(*ContextReport), true
+}
+
+// DirectContext returns the report of the most recent turn of a
+// conversation driven through ProcessDirect with sessionKey.
+func (al *AgentLoop) DirectContext(sessionKey string) (*ContextReport, bool) {
+	_, key, _ := al.routeMessage(bus.InboundMessage{
+		Channel:    "cli",
+		ChatID:     "direct",
+		SessionKey: sessionKey,
+	})
+	return al.LastContext(key)
+}
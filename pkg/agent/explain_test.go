@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestContextReport_RecordedPerTurn(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &mockProvider{})
+	ctx := context.Background()
+
+	if _, ok := al.DirectContext("cli:explain"); ok {
+		t.Fatal("expected no report before the first turn")
+	}
+	for _, msg := range []string{"hello", "how are you?"} {
+		if _, err := al.ProcessDirect(ctx, msg, "cli:explain"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, ok := al.DirectContext("cli:explain")
+	if !ok {
+		t.Fatal("no context report recorded")
+	}
+	if report.HistoryMessages != 2 {
+		t.Errorf("HistoryMessages = %d, want 2", report.HistoryMessages)
+	}
+	if report.CurrentTokens == 0 || report.SystemTokens() == 0 || len(report.Tools) == 0 {
+		t.Errorf("missing estimates: %+v", report)
+	}
+	var names []string
+	for _, s := range report.Sections {
+		names = append(names, s.Name)
+	}
+	if got := strings.Join(names, ","); !strings.HasPrefix(got, "identity") || !strings.HasSuffix(got, "session") {
+		t.Errorf("sections = %s", got)
+	}
+
+	// The sections add up to the system prompt actually sent.
+	var joined strings.Builder
+	for i, s := range report.Sections {
+		if i > 0 && s.Name != "session" && s.Name != "summary" {
+			joined.WriteString("\n\n---\n\n")
+		}
+		joined.WriteString(s.Content)
+	}
+	if joined.String() != report.Messages[0].Content {
+		t.Error("sections do not match the system prompt that was sent")
+	}
+
+	full := report.Format(true)
+	if !strings.Contains(full, "how are you?") || !strings.Contains(full, "===== tool: ") {
+		t.Errorf("verbose report is missing content:\n%s", full)
+	}
+
+	why, err := al.ProcessDirect(ctx, "/why", "cli:explain")
+	if err != nil || !strings.Contains(why, "History: 2 messages") {
+		t.Errorf("/why = %q, %v", why, err)
+	}
+}
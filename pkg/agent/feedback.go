@@ -0,0 +1,154 @@
+package agent
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/session"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+const (
+	// maxFeedbackTurns is how many recent replies reactions are attributed
+	// to. Reactions to older replies, or to replies sent before a restart,
+	// are ignored.
+	maxFeedbackTurns = 500
+	// feedbackReplyRunes is how much of a reply is kept with its feedback.
+	feedbackReplyRunes = 200
+)
+
+// positiveReactions and negativeReactions score reactions, given as emoji
+// or, by Slack, as emoji names.
+var (
+	positiveReactions = map[string]bool{
+		"👍": true, "❤": true, "🔥": true, "🎉": true, "👏": true,
+		"😍": true, "💯": true, "🥰": true, "🏆": true, "👌": true, "🙏": true,
+		"+1": true, "thumbsup": true, "heart": true, "tada": true, "fire": true,
+		"clap": true, "100": true, "ok_hand": true, "pray": true,
+	}
+	negativeReactions = map[string]bool{
+		"👎": true, "😡": true, "🤬": true, "💩": true, "🤮": true, "💔": true,
+		"-1": true, "thumbsdown": true, "rage": true, "poop": true, "broken_heart": true,
+	}
+)
+
+// reactionScore returns 1 for a positive reaction, -1 for a negative one
+// and 0 for any other.
+func reactionScore(reaction string) int {
+	// Skin tones and variations don't change the meaning.
+	reaction = strings.TrimSuffix(reaction, "\ufe0f")
+	if i := strings.Index(reaction, "::skin-tone"); i >= 0 {
+		reaction = reaction[:i]
+	}
+	for _, tone := range []string{"🏻", "🏼", "🏽", "🏾", "🏿"} {
+		reaction = strings.TrimSuffix(reaction, tone)
+	}
+	switch {
+	case positiveReactions[reaction]:
+		return 1
+	case negativeReactions[reaction]:
+		return -1
+	}
+	return 0
+}
+
+// feedbackTurn is a reply reactions can be attributed to.
+type feedbackTurn struct {
+	agent      *AgentInstance
+	sessionKey string
+	model      string
+	reply      string
+	at         time.Time
+}
+
+// feedbackTurns remembers the most recent replies by turn ID.
+type feedbackTurns struct {
+	mu    sync.Mutex
+	next  uint64
+	turns map[string]feedbackTurn
+	order []string
+}
+
+// add remembers t and returns its turn ID.
+func (f *feedbackTurns) add(t feedbackTurn) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.turns == nil {
+		f.turns = make(map[string]feedbackTurn)
+	}
+	f.next++
+	id := strconv.FormatUint(f.next, 36) + "-" + strconv.FormatInt(t.at.UnixMilli(), 36)
+	f.turns[id] = t
+	f.order = append(f.order, id)
+	if len(f.order) > maxFeedbackTurns {
+		delete(f.turns, f.order[0])
+		f.order = f.order[1:]
+	}
+	return id
+}
+
+func (f *feedbackTurns) get(id string) (feedbackTurn, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t, ok := f.turns[id]
+	return t, ok
+}
+
+// trackReply registers the reply of a turn for feedback and returns the
+// turn ID to send it with.
+func (al *AgentLoop) trackReply(agent *AgentInstance, sessionKey, reply string) string {
+	return al.feedback.add(feedbackTurn{
+		agent:      agent,
+		sessionKey: sessionKey,
+		model:      agent.Model,
+		reply:      utils.Truncate(reply, feedbackReplyRunes),
+		at:         time.Now(),
+	})
+}
+
+// handleReaction records a reaction to a reply as feedback on the turn's
+// session, and counts positive and negative ones in the usage stats.
+func (al *AgentLoop) handleReaction(r bus.Reaction) {
+	turn, ok := al.feedback.get(r.TurnID)
+	if !ok {
+		return
+	}
+
+	fb := session.Feedback{
+		RepliedAt: turn.at,
+		Reply:     turn.reply,
+		Channel:   r.Channel,
+		SenderID:  r.SenderID,
+		Reaction:  r.Emoji,
+		Score:     reactionScore(r.Emoji),
+		At:        time.Now(),
+	}
+	sessions := turn.agent.Sessions
+	if r.Removed {
+		if !sessions.RemoveFeedback(turn.sessionKey, fb) {
+			return
+		}
+	} else {
+		sessions.AddFeedback(turn.sessionKey, fb)
+	}
+	if err := sessions.Save(turn.sessionKey); err != nil {
+		logger.WarnCF("agent", "Failed to save feedback", map[string]interface{}{
+			"session_key": turn.sessionKey,
+			"error":       err.Error(),
+		})
+	}
+
+	if al.usage != nil && fb.Score != 0 {
+		al.usage.RecordFeedback(turn.sessionKey, turn.model, fb.Score, !r.Removed)
+	}
+	logger.DebugCF("agent", "Recorded reaction", map[string]interface{}{
+		"session_key": turn.sessionKey,
+		"reaction":    r.Emoji,
+		"score":       fb.Score,
+		"removed":     r.Removed,
+	})
+}
@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestReactionScore(t *testing.T) {
+	tests := map[string]int{
+		"👍":                     1,
+		"👍🏽":                    1,
+		"❤️":                    1,
+		"+1":                    1,
+		"thumbsup::skin-tone-2": 1,
+		"👎":                     -1,
+		"thumbsdown":            -1,
+		"🤔":                     0,
+		"eyes":                  0,
+	}
+	for reaction, want := range tests {
+		if got := reactionScore(reaction); got != want {
+			t.Errorf("reactionScore(%q) = %d, want %d", reaction, got, want)
+		}
+	}
+}
+
+// TestAgentLoop_ReactionsBecomeFeedback verifies that a reaction to a reply
+// is stored on the session of its turn and counted in the usage stats.
+func TestAgentLoop_ReactionsBecomeFeedback(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		Usage: config.UsageConfig{Enabled: true},
+	}
+	msgBus := bus.NewMessageBus()
+	al := NewAgentLoop(cfg, msgBus, &usageMockProvider{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	go al.Run(ctx)
+	defer al.Stop()
+
+	inbound := bus.InboundMessage{Channel: "telegram", ChatID: "1", SenderID: "u", Content: "hello"}
+	msgBus.PublishInbound(inbound)
+	out, ok := msgBus.SubscribeOutbound(ctx)
+	if !ok || out.TurnID == "" {
+		t.Fatalf("reply = %+v, want one with a turn ID", out)
+	}
+
+	reaction := bus.Reaction{Channel: "telegram", ChatID: "1", SenderID: "u", TurnID: out.TurnID, Emoji: "👍"}
+	msgBus.PublishReaction(reaction)
+	msgBus.PublishReaction(bus.Reaction{Channel: "telegram", ChatID: "1", SenderID: "u", TurnID: "unknown", Emoji: "👎"})
+
+	agent := al.registry.GetDefaultAgent()
+	_, sessionKey, _ := al.routeMessage(inbound)
+	sess, _ := agent.Sessions.Get(sessionKey)
+	if len(sess.Feedback) != 1 || sess.Feedback[0].Score != 1 || sess.Feedback[0].Reply != "ok" {
+		t.Fatalf("feedback = %+v, want one positive on the reply", sess.Feedback)
+	}
+	if got := al.Usage().Report().Sessions[sessionKey]; got.PositiveFeedback != 1 {
+		t.Errorf("session usage = %+v, want 1 positive feedback", got)
+	}
+
+	reaction.Removed = true
+	msgBus.PublishReaction(reaction)
+	sess, _ = agent.Sessions.Get(sessionKey)
+	if len(sess.Feedback) != 0 {
+		t.Errorf("feedback after removal = %+v", sess.Feedback)
+	}
+	if got := al.Usage().Report().Total; got.PositiveFeedback != 0 {
+		t.Errorf("total usage = %+v, want no feedback", got)
+	}
+}
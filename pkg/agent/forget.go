@@ -0,0 +1,159 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/session"
+	"github.com/sipeed/picoclaw/pkg/transcript"
+	"github.com/sipeed/picoclaw/pkg/vfs"
+)
+
+// Forgotten reports what Forget removed.
+type Forgotten struct {
+	session.Redaction
+	// Memory is the number of matches replaced in memory files.
+	Memory int
+	// Artifacts is the number of exports and cached responses removed.
+	Artifacts int
+	// Transcripts is the number of transcript entries removed or redacted.
+	Transcripts int
+}
+
+// Matches returns the number of matches replaced in the session and in
+// memory.
+func (f Forgotten) Matches() int {
+	return f.Replaced + f.Memory
+}
+
+// Forget removes the last turns of the session stored under key and
+// replaces the matches of pattern, which may be nil, in the session and,
+// if memory is set, in the memory files of workspace, which all of the
+// agent's conversations share. The transcripts in the transcripts
+// directory are changed the same way: the session's entries are removed
+// with its turns, and matches are replaced in its entries, or in all of
+// them with memory, as any request may have carried memory. Once anything
+// is removed, the session's export and the response cache of workspace,
+// which may still hold it, are deleted too.
+func Forget(workspace, transcripts string, sessions *session.SessionManager, key string, turns int, pattern *regexp.Regexp, memory bool) (Forgotten, error) {
+	var f Forgotten
+	var err error
+	if f.Redaction, _, err = sessions.Redact(key, turns, pattern); err != nil {
+		return f, fmt.Errorf("redacting session: %w", err)
+	}
+	if pattern != nil && memory {
+		if f.Memory, err = NewMemoryStore(workspace).Redact(pattern); err != nil {
+			return f, fmt.Errorf("redacting memory: %w", err)
+		}
+	}
+	if f.Transcripts, err = transcript.Forget(transcripts, key, f.Removed > 0, pattern, memory); err != nil {
+		return f, fmt.Errorf("redacting transcripts: %w", err)
+	}
+	if !f.Changed() && f.Memory == 0 {
+		return f, nil
+	}
+
+	if err := vfs.Remove(filepath.Join(workspace, "exports", session.ExportFilename(key))); err == nil {
+		f.Artifacts++
+	} else if !os.IsNotExist(err) {
+		return f, fmt.Errorf("removing export: %w", err)
+	}
+	if dir := responseCacheDir(workspace); dirExists(dir) {
+		f.Artifacts += providers.NewResponseCache(dir, 0).Clear()
+	}
+
+	logger.InfoCF("agent", "Forgot conversation content", map[string]interface{}{
+		"session_key": key,
+		"removed":     f.Removed,
+		"matches":     f.Matches(),
+		"artifacts":   f.Artifacts,
+		"transcripts": f.Transcripts,
+	})
+	return f, nil
+}
+
+func dirExists(path string) bool {
+	info, err := vfs.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// minForgetText is the shortest text /forget redacts, so that a few
+// letters cannot blank out a whole conversation.
+const minForgetText = 4
+
+// ParseForget parses the arguments of /forget: "last [n]" forgets the
+// last n turns, one by default, and anything else is text to redact,
+// ignoring case, where it is not part of a longer word. ok is false if
+// args are not valid or the text is shorter than minForgetText.
+func ParseForget(args string) (turns int, pattern *regexp.Regexp, ok bool) {
+	args = strings.TrimSpace(args)
+	if args == "" {
+		return 0, nil, false
+	}
+	fields := strings.Fields(args)
+	if fields[0] == "last" && len(fields) <= 2 {
+		if len(fields) == 1 {
+			return 1, nil, true
+		}
+		n, err := strconv.Atoi(fields[1])
+		if err != nil || n <= 0 {
+			return 0, nil, false
+		}
+		return n, nil, true
+	}
+	if utf8.RuneCountInString(args) < minForgetText {
+		return 0, nil, false
+	}
+	expr := regexp.QuoteMeta(args)
+	if isWordRune(firstRune(args)) {
+		expr = `\b` + expr
+	}
+	if isWordRune(lastRune(args)) {
+		expr += `\b`
+	}
+	return 0, regexp.MustCompile("(?i)" + expr), true
+}
+
+// isWordRune reports whether \b treats r as part of a word.
+func isWordRune(r rune) bool {
+	return r == '_' || r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+func firstRune(s string) rune {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r
+}
+
+func lastRune(s string) rune {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return r
+}
+
+// forget handles /forget for the conversation of msg. Only that session
+// is changed: memory is shared by everyone who talks to the agent, so it
+// is redacted with "picoclaw session redact" by whoever runs it.
+func (al *AgentLoop) forget(msg bus.InboundMessage, args string) (Forgotten, bool, error) {
+	turns, pattern, ok := ParseForget(args)
+	if !ok {
+		return Forgotten{}, false, nil
+	}
+	agent, sessionKey, _ := al.routeMessage(msg)
+	if agent == nil {
+		return Forgotten{}, true, fmt.Errorf("no agent configured")
+	}
+	f, err := Forget(agent.Workspace, transcript.Dir(al.cfg.WorkspacePath()), agent.Sessions, sessionKey, turns, pattern, false)
+	if f.Changed() {
+		// /why would still show what the model was sent.
+		al.contextReports.Delete(sessionKey)
+	}
+	return f, true, err
+}
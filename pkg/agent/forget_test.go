@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/session"
+	"github.com/sipeed/picoclaw/pkg/transcript"
+)
+
+func TestParseForget(t *testing.T) {
+	tests := []struct {
+		args    string
+		turns   int
+		pattern string
+		ok      bool
+	}{
+		{"", 0, "", false},
+		{"last", 1, "", true},
+		{"last 3", 3, "", true},
+		{"last zero", 0, "", false},
+		{"my (secret) word", 0, `(?i)\bmy \(secret\) word\b`, true},
+		{"4111-1111", 0, `(?i)\b4111-1111\b`, true},
+		{"(pw)", 0, `(?i)\(pw\)`, true},
+		{"e", 0, "", false},
+		{"abc", 0, "", false},
+	}
+	for _, tt := range tests {
+		turns, pattern, ok := ParseForget(tt.args)
+		got := ""
+		if pattern != nil {
+			got = pattern.String()
+		}
+		if turns != tt.turns || got != tt.pattern || ok != tt.ok {
+			t.Errorf("ParseForget(%q) = %d, %q, %v", tt.args, turns, got, ok)
+		}
+	}
+}
+
+func TestForget(t *testing.T) {
+	workspace := t.TempDir()
+	sessions := session.NewSessionManager(filepath.Join(workspace, "sessions"))
+	key := "cli:direct"
+	sessions.AddMessage(key, "user", "my password is hunter2")
+	sessions.AddMessage(key, "assistant", "Stored.")
+
+	memory := NewMemoryStore(workspace)
+	memory.WriteLongTerm("Password: hunter2\n")
+	memory.AppendToday("User mentioned hunter2.")
+
+	exports := filepath.Join(workspace, "exports")
+	os.MkdirAll(exports, 0755)
+	os.WriteFile(filepath.Join(exports, session.ExportFilename(key)), []byte("hunter2"), 0644)
+	cache := providers.NewResponseCache(responseCacheDir(workspace), time.Hour)
+	cache.Put("m", []providers.Message{{Role: "user", Content: "hunter2"}}, nil, nil, &providers.LLMResponse{Content: "ok"})
+
+	transcripts := transcript.Dir(workspace)
+	rec, _ := transcript.NewRecorder(transcripts, nil)
+	rec.Record(transcript.Entry{Time: time.Now(), Session: key, Model: "m", Messages: []providers.Message{{Role: "user", Content: "hunter2"}}})
+
+	f, err := Forget(workspace, transcripts, sessions, key, 0, regexp.MustCompile("hunter2"), true)
+	if err != nil {
+		t.Fatalf("Forget() error = %v", err)
+	}
+	if f.Replaced != 1 || f.Memory != 2 || f.Artifacts != 2 || f.Transcripts != 1 {
+		t.Errorf("Forget() = %+v", f)
+	}
+	if strings.Contains(memory.ReadLongTerm()+memory.ReadToday(), "hunter2") {
+		t.Error("memory still holds the match")
+	}
+	if h := sessions.GetHistory(key); h[0].Content != "my password is "+session.Redacted {
+		t.Errorf("history = %+v", h)
+	}
+
+	if f, _ := Forget(workspace, transcripts, sessions, key, 0, regexp.MustCompile("nothing"), true); f.Matches() != 0 || f.Artifacts != 0 || f.Transcripts != 0 {
+		t.Errorf("Forget() without matches = %+v", f)
+	}
+
+	// Removing turns removes the session's transcript entries.
+	if f, _ := Forget(workspace, transcripts, sessions, key, 1, nil, false); f.Removed != 2 || f.Transcripts != 1 {
+		t.Errorf("Forget() of the last turn = %+v", f)
+	}
+}
+
+func TestHandleCommand_Forget(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &mockProvider{})
+	msg := bus.InboundMessage{Channel: "telegram", SenderID: "7", ChatID: "7"}
+	agent, key, _ := al.routeMessage(msg)
+	agent.Sessions.AddMessage(key, "user", "hello")
+	agent.Sessions.AddMessage(key, "assistant", "hi")
+	memory := NewMemoryStore(agent.Workspace)
+	memory.WriteLongTerm("Someone said hello.\n")
+
+	for _, tt := range []struct{ content, want string }{
+		{"/forget", "Usage: /forget last [n] | /forget <text of at least 4 characters>"},
+		{"/forget e", "Usage: /forget last [n] | /forget <text of at least 4 characters>"},
+		{"/forget nothing like this", "Nothing matched, so nothing was forgotten"},
+		{"/forget hello", "Forgotten: 0 message(s) removed, 1 match(es) redacted"},
+		{"/forget last", "Forgotten: 2 message(s) removed, 0 match(es) redacted"},
+	} {
+		msg.Content = tt.content
+		if got, _ := al.handleCommand(context.Background(), msg); got != tt.want {
+			t.Errorf("handleCommand(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+	if h := agent.Sessions.GetHistory(key); len(h) != 0 {
+		t.Errorf("history = %+v, want it empty", h)
+	}
+	// Memory is shared by all conversations, so a chat cannot redact it.
+	if got := memory.ReadLongTerm(); !strings.Contains(got, "hello") {
+		t.Errorf("memory = %q, want it unchanged", got)
+	}
+}
@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/session"
+)
+
+// newGeneration starts the record of a turn's generation settings. If the
+// provider takes seeds, the turn gets the configured seed or a random one.
+func newGeneration(agent *AgentInstance) *session.Generation {
+	g := &session.Generation{
+		Model:       agent.Model,
+		Temperature: agent.Temperature,
+		MaxTokens:   agent.MaxTokens,
+	}
+	if s, ok := agent.Provider.(providers.Seeder); ok && s.SupportsSeed() {
+		seed := int(rand.Int32())
+		if agent.Seed != nil {
+			seed = *agent.Seed
+		}
+		g.Seed = &seed
+	}
+	return g
+}
+
+// generationOptions returns the request options of the turn's model calls.
+func generationOptions(g *session.Generation) map[string]interface{} {
+	options := map[string]interface{}{
+		"max_tokens":  g.MaxTokens,
+		"temperature": g.Temperature,
+	}
+	if g.Seed != nil {
+		options["seed"] = *g.Seed
+	}
+	return options
+}
+
+// observeGeneration records the model that answered a call of the turn.
+func observeGeneration(g *session.Generation, model string, resp *providers.LLMResponse) {
+	if resp == nil {
+		return
+	}
+	g.Model = model
+	g.ModelVersion = resp.Model
+	g.Fingerprint = resp.SystemFingerprint
+}
+
+// formatGeneration describes how a reply was generated, for /why.
+func formatGeneration(g session.Generation) string {
+	model := g.Model
+	if g.ModelVersion != "" && g.ModelVersion != g.Model {
+		model += " (" + g.ModelVersion + ")"
+	}
+	parts := []string{
+		"model " + model,
+		fmt.Sprintf("temperature %g", g.Temperature),
+		fmt.Sprintf("max tokens %d", g.MaxTokens),
+	}
+	if g.Seed != nil {
+		parts = append(parts, fmt.Sprintf("seed %d", *g.Seed))
+	}
+	if g.Fingerprint != "" {
+		parts = append(parts, "fingerprint "+g.Fingerprint)
+	}
+	parts = append(parts, fmt.Sprintf("%d model calls", g.Calls))
+	if g.LatencyBudgetMs > 0 {
+		took := (time.Duration(g.ElapsedMs) * time.Millisecond).Round(100 * time.Millisecond)
+		budget := time.Duration(g.LatencyBudgetMs) * time.Millisecond
+		if g.OverBudget {
+			parts = append(parts, fmt.Sprintf("took %s, over its %s budget", took, budget))
+		} else {
+			parts = append(parts, fmt.Sprintf("took %s of its %s budget", took, budget))
+		}
+	}
+	return fmt.Sprintf("Last reply at %s: %s\n", g.RepliedAt.Format("15:04:05"), strings.Join(parts, ", "))
+}
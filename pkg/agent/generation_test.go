@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/session"
+)
+
+// seedMockProvider takes seeds and reports the model version it used.
+type seedMockProvider struct {
+	seeds []interface{}
+}
+
+func (m *seedMockProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	m.seeds = append(m.seeds, opts["seed"])
+	return &providers.LLMResponse{Content: "ok", Model: "test-model-2026-01-01", SystemFingerprint: "fp_1"}, nil
+}
+
+func (m *seedMockProvider) GetDefaultModel() string { return "test-model" }
+func (m *seedMockProvider) SupportsSeed() bool      { return true }
+
+// lastSession returns the session direct messages were routed to.
+func lastSession(al *AgentLoop) session.Session {
+	_, sessionKey, _ := al.routeMessage(bus.InboundMessage{Channel: "cli", ChatID: "direct", SenderID: "cron"})
+	sess, _ := al.registry.GetDefaultAgent().Sessions.Get(sessionKey)
+	return sess
+}
+
+func newGenerationLoop(t *testing.T, seed *int, provider providers.LLMProvider) *AgentLoop {
+	t.Helper()
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+				Seed:              seed,
+			},
+		},
+	}
+	return NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+}
+
+func TestAgentLoop_RecordsGenerationSettings(t *testing.T) {
+	provider := &seedMockProvider{}
+	al := newGenerationLoop(t, nil, provider)
+	for range 2 {
+		if _, err := al.ProcessDirect(context.Background(), "hello", "s1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sess := lastSession(al)
+	if len(sess.Generations) != 2 {
+		t.Fatalf("generations = %+v, want one per turn", sess.Generations)
+	}
+	g := sess.Generations[1]
+	if g.Seed == nil || provider.seeds[1] != *g.Seed {
+		t.Errorf("recorded seed %v, sent %v", g.Seed, provider.seeds[1])
+	}
+	if g.Model != "test-model" || g.ModelVersion != "test-model-2026-01-01" || g.Fingerprint != "fp_1" ||
+		g.MaxTokens != 4096 || g.Reply != "ok" || g.Calls != 1 {
+		t.Errorf("generation = %+v", g)
+	}
+
+	report := formatGeneration(g)
+	for _, want := range []string{"test-model (test-model-2026-01-01)", "temperature 0.7", "seed ", "fingerprint fp_1"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("formatGeneration() = %q, missing %q", report, want)
+		}
+	}
+}
+
+func TestAgentLoop_FixedSeedAndProvidersWithoutSeeds(t *testing.T) {
+	seed := 42
+	provider := &seedMockProvider{}
+	al := newGenerationLoop(t, &seed, provider)
+	al.ProcessDirect(context.Background(), "hello", "s1")
+	al.ProcessDirect(context.Background(), "again", "s1")
+	if provider.seeds[0] != 42 || provider.seeds[1] != 42 {
+		t.Errorf("seeds sent = %v, want the configured one", provider.seeds)
+	}
+
+	al = newGenerationLoop(t, &seed, &mockProvider{})
+	al.ProcessDirect(context.Background(), "hello", "s1")
+	sess := lastSession(al)
+	if len(sess.Generations) != 1 || sess.Generations[0].Seed != nil {
+		t.Errorf("generations = %+v, want one without a seed", sess.Generations)
+	}
+}
+
+func TestAgentLoop_ResponseCacheAnswersRepeatedPrompts(t *testing.T) {
+	provider := &seedMockProvider{}
+	al := newGenerationLoop(t, nil, provider)
+	agent := al.registry.GetDefaultAgent()
+	agent.Cache = resolveAgentCache(&config.AgentConfig{
+		ResponseCache: &config.ResponseCacheConfig{Enabled: true},
+	}, &config.AgentDefaults{}, agent.Workspace)
+
+	// New sessions send the same messages, but with another random seed.
+	for _, key := range []string{"agent:main:a", "agent:main:b"} {
+		if reply, err := al.ProcessDirect(context.Background(), "hello", key); err != nil || reply != "ok" {
+			t.Fatalf("ProcessDirect() = %q, %v", reply, err)
+		}
+	}
+	if len(provider.seeds) != 1 {
+		t.Errorf("provider asked %d times, want 1", len(provider.seeds))
+	}
+}
@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"encoding/base64"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// maxImageBytes is the largest image sent inline; providers reject larger
+// ones.
+const maxImageBytes = 5 << 20
+
+// imageType returns the MIME type of an image the providers accept, or ""
+// for anything else.
+func imageType(data []byte) string {
+	switch mimeType := http.DetectContentType(data); mimeType {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+		return mimeType
+	}
+	return ""
+}
+
+// attachImages returns the images among media to send with the user
+// message: image URLs as they are and local files inline. Models that
+// cannot see images get none.
+func attachImages(agent *AgentInstance, media []string) []providers.Image {
+	if len(media) == 0 {
+		return nil
+	}
+	var images []providers.Image
+	for _, item := range media {
+		if strings.HasPrefix(item, "http://") || strings.HasPrefix(item, "https://") {
+			if u, err := url.Parse(item); err == nil {
+				if mimeType := mime.TypeByExtension(strings.ToLower(path.Ext(u.Path))); strings.HasPrefix(mimeType, "image/") {
+					images = append(images, providers.Image{URL: item, MimeType: mimeType})
+				}
+			}
+			continue
+		}
+		info, err := os.Stat(item)
+		if err != nil || info.IsDir() || info.Size() > maxImageBytes {
+			continue
+		}
+		data, err := os.ReadFile(item)
+		if err != nil {
+			continue
+		}
+		if mimeType := imageType(data); mimeType != "" {
+			images = append(images, providers.Image{Data: base64.StdEncoding.EncodeToString(data), MimeType: mimeType})
+		}
+	}
+	if len(images) > 0 && !agent.Capabilities.Vision {
+		logger.InfoCF("agent", "Model cannot see images; not sending them", map[string]interface{}{
+			"agent_id": agent.ID,
+			"model":    agent.Model,
+			"images":   len(images),
+		})
+		return nil
+	}
+	return images
+}
@@ -0,0 +1,40 @@
+package agent
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// onePixelPNG is a valid 1x1 PNG.
+const onePixelPNG = "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNkYPhfDwAChwGA60e6kgAAAABJRU5ErkJggg=="
+
+func TestAttachImages(t *testing.T) {
+	dir := t.TempDir()
+	png, _ := base64.StdEncoding.DecodeString(onePixelPNG)
+	photo := filepath.Join(dir, "photo.png")
+	notes := filepath.Join(dir, "notes.txt")
+	os.WriteFile(photo, png, 0644)
+	os.WriteFile(notes, []byte("not an image"), 0644)
+
+	agent := &AgentInstance{ID: "main", Model: "gpt-4o", Capabilities: providers.Capabilities{Vision: true}}
+	media := []string{photo, notes, "https://example.com/cat.jpg?size=large", "https://example.com/report.pdf"}
+	images := attachImages(agent, media)
+	if len(images) != 2 {
+		t.Fatalf("got %d images, want 2: %+v", len(images), images)
+	}
+	if images[0].MimeType != "image/png" || images[0].Data != onePixelPNG {
+		t.Errorf("local image = %+v", images[0])
+	}
+	if images[1].URL != "https://example.com/cat.jpg?size=large" || images[1].MimeType != "image/jpeg" {
+		t.Errorf("URL image = %+v", images[1])
+	}
+
+	agent.Capabilities.Vision = false
+	if images := attachImages(agent, media); images != nil {
+		t.Errorf("model without vision got %d images", len(images))
+	}
+}
@@ -4,11 +4,14 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/providers"
 	"github.com/sipeed/picoclaw/pkg/routing"
 	"github.com/sipeed/picoclaw/pkg/session"
+	"github.com/sipeed/picoclaw/pkg/skills"
 	"github.com/sipeed/picoclaw/pkg/tools"
 )
 
@@ -28,9 +31,36 @@ type AgentInstance struct {
 	Sessions       *session.SessionManager
 	ContextBuilder *ContextBuilder
 	Tools          *tools.ToolRegistry
+	SearchCache    *skills.SearchCache
+	Uploads        *UploadRegistry // nil unless file uploads are enabled
 	Subagents      *config.SubagentsConfig
+	Tasks          *tools.SubagentManager // background tasks started with spawn
 	SkillsFilter   []string
 	Candidates     []providers.FallbackCandidate
+	StreamTools    bool // start tool calls while the response is still streaming
+	StreamReplies  bool // show replies on channels as they are generated
+	Summary        config.SummarizerConfig
+	Seed           *int // sent to providers that take one; random per turn if nil
+	// Capabilities are those of Model; MaxTokens and ContextWindow are
+	// sized to them.
+	Capabilities providers.Capabilities
+	// Embeddings is the configured embedding model, nil if there is none.
+	Embeddings providers.EmbeddingsProvider
+	// Cache answers repeated requests; nil unless enabled.
+	Cache *providers.ResponseCache
+	// LatencyBudget sets how long turns should take, per channel.
+	LatencyBudget config.LatencyBudgetConfig
+	// FastProvider and FastModel take the requests of turns over their
+	// latency budget; nil if no fast model is set or it is unavailable.
+	FastProvider providers.LLMProvider
+	FastModel    string
+	// Truncation is what is done with requests too long for the context
+	// window of Model: one of the Truncate policies.
+	Truncation string
+
+	// maxTokens is the configured output limit, before capping it to the
+	// model's.
+	maxTokens int
 }
 
 // NewAgentInstance creates an agent instance from config.
@@ -48,6 +78,9 @@ func NewAgentInstance(
 
 	restrict := defaults.RestrictToWorkspace
 	toolsRegistry := tools.NewToolRegistry()
+	if agentCfg != nil {
+		toolsRegistry.Allow(agentCfg.Tools)
+	}
 	toolsRegistry.Register(tools.NewReadFileTool(workspace, restrict))
 	toolsRegistry.Register(tools.NewWriteFileTool(workspace, restrict))
 	toolsRegistry.Register(tools.NewListDirTool(workspace, restrict))
@@ -55,6 +88,16 @@ func NewAgentInstance(
 	toolsRegistry.Register(tools.NewEditFileTool(workspace, restrict))
 	toolsRegistry.Register(tools.NewAppendFileTool(workspace, restrict))
 
+	if cfg != nil && cfg.Tools.InjectionGuard.Enabled {
+		guardCfg := cfg.Tools.InjectionGuard
+		toolsRegistry.SetInjectionGuard(tools.NewInjectionGuard(tools.InjectionGuardOptions{
+			Action:          guardCfg.Action,
+			CustomPatterns:  guardCfg.CustomPatterns,
+			DisableDefaults: guardCfg.DisableDefaults,
+			Tools:           guardCfg.Tools,
+		}))
+	}
+
 	sessionsDir := filepath.Join(workspace, "sessions")
 	sessionsManager := session.NewSessionManager(sessionsDir)
 
@@ -95,16 +138,18 @@ func NewAgentInstance(
 	}
 	candidates := providers.ResolveCandidates(modelCfg, defaults.Provider)
 
-	return &AgentInstance{
+	var uploads *UploadRegistry
+	if cfg != nil && cfg.FileUploads.Enabled {
+		uploads = NewUploadRegistry(workspace)
+	}
+
+	instance := &AgentInstance{
 		ID:             agentID,
 		Name:           agentName,
-		Model:          model,
 		Fallbacks:      fallbacks,
 		Workspace:      workspace,
 		MaxIterations:  maxIter,
-		MaxTokens:      maxTokens,
 		Temperature:    temperature,
-		ContextWindow:  maxTokens,
 		Provider:       provider,
 		Sessions:       sessionsManager,
 		ContextBuilder: contextBuilder,
@@ -112,7 +157,100 @@ func NewAgentInstance(
 		Subagents:      subagents,
 		SkillsFilter:   skillsFilter,
 		Candidates:     candidates,
+		Uploads:        uploads,
+		StreamTools:    defaults.StreamToolCalls,
+		StreamReplies:  defaults.StreamReplies,
+		Summary:        resolveAgentSummarizer(agentCfg, defaults),
+		Seed:           defaults.Seed,
+		Embeddings:     resolveAgentEmbeddings(agentID, defaults, cfg),
+		Cache:          resolveAgentCache(agentCfg, defaults, workspace),
+		LatencyBudget:  defaults.LatencyBudget,
+		Truncation:     defaults.Truncation,
+		maxTokens:      maxTokens,
 	}
+	instance.FastProvider, instance.FastModel = resolveFastModel(agentID, defaults, cfg)
+	instance.setModel(model, providers.ResolveCapabilities(cfg, model))
+	return instance
+}
+
+// setModel makes model the agent's primary model, with the output limit
+// capped to what it can generate and the context window, which decides
+// when history is summarized, set to its own if known.
+func (a *AgentInstance) setModel(model string, caps providers.Capabilities) {
+	a.Model = model
+	a.Capabilities = caps
+	a.MaxTokens = a.maxTokens
+	if caps.MaxOutputTokens > 0 && a.MaxTokens > caps.MaxOutputTokens {
+		a.MaxTokens = caps.MaxOutputTokens
+	}
+	a.ContextWindow = a.maxTokens
+	if caps.ContextWindow > 0 {
+		a.ContextWindow = caps.ContextWindow
+	}
+}
+
+// toolDefs returns the definitions of the tools sent to the model, none if
+// it cannot call tools.
+func (a *AgentInstance) toolDefs() []providers.ToolDefinition {
+	if !a.Capabilities.Tools {
+		return nil
+	}
+	return a.Tools.ToProviderDefs()
+}
+
+// chatTools returns the provider to send a turn's requests to and the
+// tool definitions to send with them. Models that cannot call tools
+// natively are told about the tools in the prompt, ReAct style.
+func (a *AgentInstance) chatTools() (providers.LLMProvider, []providers.ToolDefinition) {
+	if a.Capabilities.Tools {
+		return a.Provider, a.toolDefs()
+	}
+	return providers.PromptTools(a.Provider), a.Tools.ToProviderDefs()
+}
+
+// resolveAgentEmbeddings creates the embeddings provider of the configured
+// embedding model. A model that cannot be used is logged and left out,
+// so that the agent still starts.
+func resolveAgentEmbeddings(agentID string, defaults *config.AgentDefaults, cfg *config.Config) providers.EmbeddingsProvider {
+	if cfg == nil || defaults.EmbeddingModel == "" {
+		return nil
+	}
+	embeddings, err := providers.CreateEmbeddingsProvider(cfg, defaults.EmbeddingModel)
+	if err != nil {
+		logger.WarnCF("agent", "Embedding model unavailable", map[string]interface{}{
+			"agent_id": agentID,
+			"model":    defaults.EmbeddingModel,
+			"error":    err.Error(),
+		})
+		return nil
+	}
+	return embeddings
+}
+
+// resolveAgentCache returns the agent's response cache, stored in its
+// workspace, or nil if it is not enabled.
+func resolveAgentCache(agentCfg *config.AgentConfig, defaults *config.AgentDefaults, workspace string) *providers.ResponseCache {
+	cacheCfg := defaults.ResponseCache
+	if agentCfg != nil && agentCfg.ResponseCache != nil {
+		cacheCfg = *agentCfg.ResponseCache
+	}
+	if !cacheCfg.Enabled {
+		return nil
+	}
+	ttl := cacheCfg.TTLMinutes
+	if ttl <= 0 {
+		ttl = defaults.ResponseCache.TTLMinutes
+	}
+	if ttl <= 0 {
+		ttl = 1440
+	}
+	return providers.NewResponseCache(responseCacheDir(workspace), time.Duration(ttl)*time.Minute)
+}
+
+// responseCacheDir returns where the response cache of the agent with
+// workspace is stored.
+func responseCacheDir(workspace string) string {
+	return filepath.Join(workspace, "cache", "responses")
 }
 
 // resolveAgentWorkspace determines the workspace directory for an agent.
@@ -144,6 +282,33 @@ func resolveAgentFallbacks(agentCfg *config.AgentConfig, defaults *config.AgentD
 	return defaults.ModelFallbacks
 }
 
+// resolveAgentSummarizer resolves the summarizer settings for an agent,
+// taking those the agent sets over the defaults.
+func resolveAgentSummarizer(agentCfg *config.AgentConfig, defaults *config.AgentDefaults) config.SummarizerConfig {
+	resolved := defaults.Summarizer
+	resolved.MaxTokens = make(map[string]int, len(defaults.Summarizer.MaxTokens))
+	for k, v := range defaults.Summarizer.MaxTokens {
+		resolved.MaxTokens[k] = v
+	}
+	if agentCfg == nil || agentCfg.Summarizer == nil {
+		return resolved
+	}
+	own := agentCfg.Summarizer
+	if own.Strategy != "" {
+		resolved.Strategy = own.Strategy
+	}
+	if own.Model != "" {
+		resolved.Model = own.Model
+	}
+	if own.ChunkTokens > 0 {
+		resolved.ChunkTokens = own.ChunkTokens
+	}
+	for k, v := range own.MaxTokens {
+		resolved.MaxTokens[k] = v
+	}
+	return resolved
+}
+
 func expandHome(path string) string {
 	if path == "" {
 		return path
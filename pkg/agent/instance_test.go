@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/tools"
 )
 
 func TestNewAgentInstance_UsesDefaultsTemperatureAndMaxTokens(t *testing.T) {
@@ -39,6 +41,39 @@ func TestNewAgentInstance_UsesDefaultsTemperatureAndMaxTokens(t *testing.T) {
 	}
 }
 
+func TestNewAgentInstance_SizesToModelCapabilities(t *testing.T) {
+	noTools := false
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace: t.TempDir(),
+				Model:     "deepseek",
+				MaxTokens: 32768,
+			},
+		},
+		ModelList: []config.ModelConfig{
+			{ModelName: "deepseek", Model: "deepseek/deepseek-chat"},
+			{ModelName: "tiny", Model: "ollama/tiny", Capabilities: &config.ModelCapabilities{ContextWindow: 4096, Tools: &noTools}},
+		},
+	}
+
+	agent := NewAgentInstance(nil, &cfg.Agents.Defaults, cfg, &mockProvider{})
+	if agent.MaxTokens != 8192 || agent.ContextWindow != 128000 {
+		t.Errorf("MaxTokens = %d, ContextWindow = %d; want 8192, 128000", agent.MaxTokens, agent.ContextWindow)
+	}
+	if len(agent.toolDefs()) == 0 {
+		t.Error("toolDefs() is empty for a model with tool support")
+	}
+
+	agent.setModel("tiny", providers.ResolveCapabilities(cfg, "tiny"))
+	if agent.MaxTokens != 32768 || agent.ContextWindow != 4096 {
+		t.Errorf("after switching, MaxTokens = %d, ContextWindow = %d; want 32768, 4096", agent.MaxTokens, agent.ContextWindow)
+	}
+	if defs := agent.toolDefs(); defs != nil {
+		t.Errorf("toolDefs() = %d tools for a model without tool support", len(defs))
+	}
+}
+
 func TestNewAgentInstance_DefaultsTemperatureWhenZero(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "agent-instance-test-*")
 	if err != nil {
@@ -93,3 +128,85 @@ func TestNewAgentInstance_DefaultsTemperatureWhenUnset(t *testing.T) {
 		t.Fatalf("Temperature = %f, want %f", agent.Temperature, 0.7)
 	}
 }
+
+func TestResolveAgentSummarizer_OverridesDefaults(t *testing.T) {
+	defaults := &config.AgentDefaults{
+		Summarizer: config.SummarizerConfig{
+			Strategy:  "model",
+			MaxTokens: map[string]int{"model": 1024, "extractive": 512},
+		},
+	}
+	agentCfg := &config.AgentConfig{
+		ID: "notes",
+		Summarizer: &config.SummarizerConfig{
+			Strategy:  "extractive",
+			MaxTokens: map[string]int{"extractive": 200},
+		},
+	}
+
+	got := resolveAgentSummarizer(agentCfg, defaults)
+	if got.Strategy != "extractive" || got.MaxTokens["extractive"] != 200 || got.MaxTokens["model"] != 1024 {
+		t.Fatalf("resolved = %+v", got)
+	}
+	if defaults.Summarizer.MaxTokens["extractive"] != 512 {
+		t.Error("resolving an agent changed the defaults")
+	}
+}
+
+func TestResolveAgentEmbeddings(t *testing.T) {
+	cfg := &config.Config{}
+	if got := resolveAgentEmbeddings("main", &config.AgentDefaults{}, cfg); got != nil {
+		t.Errorf("no embedding model resolved to %v", got)
+	}
+	if got := resolveAgentEmbeddings("main", &config.AgentDefaults{EmbeddingModel: "local/hash"}, cfg); got == nil {
+		t.Error("local/hash did not resolve")
+	}
+	if got := resolveAgentEmbeddings("main", &config.AgentDefaults{EmbeddingModel: "missing"}, cfg); got != nil {
+		t.Errorf("unknown model resolved to %v", got)
+	}
+}
+
+func TestChatTools_PromptsModelsWithoutToolCalling(t *testing.T) {
+	noTools := false
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{Workspace: t.TempDir(), Model: "tiny"},
+		},
+		ModelList: []config.ModelConfig{
+			{ModelName: "tiny", Model: "ollama/tiny", Capabilities: &config.ModelCapabilities{Tools: &noTools}},
+		},
+	}
+
+	provider := &mockProvider{}
+	agent := NewAgentInstance(nil, &cfg.Agents.Defaults, cfg, provider)
+	p, defs := agent.chatTools()
+	if p == providers.LLMProvider(provider) {
+		t.Error("chatTools() returned the provider without prompted tool calling")
+	}
+	if len(defs) == 0 {
+		t.Error("chatTools() returned no tools for the prompt")
+	}
+
+	agent.setModel("other", providers.Capabilities{Tools: true})
+	if p, _ := agent.chatTools(); p != providers.LLMProvider(provider) {
+		t.Error("chatTools() wrapped the provider of a model with native tool calling")
+	}
+}
+
+func TestNewAgentInstance_ToolAllowlist(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{Workspace: t.TempDir(), Model: "test-model"},
+		},
+	}
+	agentCfg := &config.AgentConfig{ID: "reader", Tools: []string{"read_file", "list_dir"}}
+	agent := NewAgentInstance(agentCfg, &cfg.Agents.Defaults, cfg, &mockProvider{})
+
+	if got := agent.Tools.List(); len(got) != 2 {
+		t.Fatalf("tools = %v, want only read_file and list_dir", got)
+	}
+	agent.Tools.Register(tools.NewWriteFileTool(agent.Workspace, true))
+	if _, ok := agent.Tools.Get("write_file"); ok {
+		t.Error("write_file registered later despite the allowlist")
+	}
+}
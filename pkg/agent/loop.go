@@ -7,26 +7,35 @@
 package agent
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"path/filepath"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 	"unicode/utf8"
 
+	"github.com/sipeed/picoclaw/pkg/atrest"
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/channels"
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/constants"
+	"github.com/sipeed/picoclaw/pkg/crash"
+	"github.com/sipeed/picoclaw/pkg/i18n"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/providers"
 	"github.com/sipeed/picoclaw/pkg/routing"
+	"github.com/sipeed/picoclaw/pkg/session"
 	"github.com/sipeed/picoclaw/pkg/skills"
 	"github.com/sipeed/picoclaw/pkg/state"
 	"github.com/sipeed/picoclaw/pkg/tools"
+	"github.com/sipeed/picoclaw/pkg/transcript"
+	"github.com/sipeed/picoclaw/pkg/usage"
 	"github.com/sipeed/picoclaw/pkg/utils"
+	"github.com/sipeed/picoclaw/pkg/vfs"
 )
 
 type AgentLoop struct {
@@ -36,27 +45,55 @@ type AgentLoop struct {
 	state          *state.Manager
 	running        atomic.Bool
 	summarizing    sync.Map
+	titled         sync.Map // agent ID + session key -> true once titling was tried
 	fallback       *providers.FallbackChain
 	channelManager *channels.Manager
+	catalog        *i18n.Catalog
+	contextReports sync.Map             // session key -> *ContextReport of the last turn
+	retry          *retryQueue          // nil unless the retry queue is enabled
+	usage          *usage.Tracker       // nil unless usage tracking is enabled
+	transcripts    *transcript.Recorder // nil unless transcripts are recorded
+	tokens         *TokenCounter        // estimates the prompt tokens of requests
+	scheduler      *providers.Scheduler // nil unless a rate limit is set
+	feedback       feedbackTurns        // replies reactions can be attributed to
+	replyExtras    sync.Map             // channel + chat ID -> replyExtras of the reply Run sends
+	prefetched     sync.Map             // agent ID + tool name -> time the tool was last prefetched
+	turns          atomic.Int64         // turns answered since start
+
+	// onProviderFailure is called when an LLM call fails after retries.
+	onProviderFailure func(err error)
+}
+
+// replyExtras is what a turn adds to its reply besides the content.
+type replyExtras struct {
+	turnID  string
+	actions []bus.Action
 }
 
 // processOptions configures how a message is processed
 type processOptions struct {
-	SessionKey      string // Session identifier for history/context
-	Channel         string // Target channel for tool execution
-	ChatID          string // Target chat ID for tool execution
-	UserMessage     string // User message content (may include prefix)
-	DefaultResponse string // Response when LLM returns empty
-	EnableSummary   bool   // Whether to trigger summarization
-	SendResponse    bool   // Whether to send response via bus
-	NoHistory       bool   // If true, don't load session history (for heartbeat)
+	SessionKey      string   // Session identifier for history/context
+	Channel         string   // Target channel for tool execution
+	ChatID          string   // Target chat ID for tool execution
+	UserMessage     string   // User message content (may include prefix)
+	Media           []string // Local media paths sent with the user message
+	DefaultResponse string   // Response when LLM returns empty
+	EnableSummary   bool     // Whether to trigger summarization
+	SendResponse    bool     // Whether to send response via bus
+	NoHistory       bool     // If true, don't load session history (for heartbeat)
+	Language        string   // Language for system messages sent during processing
 }
 
 func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers.LLMProvider) *AgentLoop {
 	registry := NewAgentRegistry(cfg, provider)
 
+	var scheduler *providers.Scheduler
+	if rl := cfg.Agents.Defaults.RateLimit; rl.RequestsPerMinute > 0 || rl.TokensPerMinute > 0 {
+		scheduler = providers.NewScheduler(rl.RequestsPerMinute, rl.TokensPerMinute, rl.BackgroundReserve)
+	}
+
 	// Register shared tools to all agents
-	registerSharedTools(cfg, msgBus, registry, provider)
+	registerSharedTools(cfg, msgBus, registry, scheduler.Provider(provider))
 
 	// Set up shared fallback chain
 	cooldown := providers.NewCooldownTracker()
@@ -69,18 +106,90 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 		stateManager = state.NewManager(defaultAgent.Workspace)
 	}
 
-	return &AgentLoop{
+	al := &AgentLoop{
 		bus:         msgBus,
 		cfg:         cfg,
 		registry:    registry,
 		state:       stateManager,
 		summarizing: sync.Map{},
 		fallback:    fallbackChain,
+		catalog:     i18n.NewCatalog(cfg.Messages.Language, cfg.Messages.Templates),
+		scheduler:   scheduler,
+		tokens:      NewTokenCounter(),
+	}
+	if cfg.Tools.Approvals.Enabled {
+		al.setupApprovals()
+	}
+	if cfg.RetryQueue.Enabled {
+		al.retry = newRetryQueue(cfg.RetryQueue)
+	}
+	if cfg.Usage.Enabled {
+		al.usage = usage.NewTracker(usage.Path(cfg.WorkspacePath()), providers.ModelPrices(cfg))
+	}
+	if tc := cfg.Observability.Transcripts; tc.Enabled && atrest.Enabled() {
+		// Transcripts are appended to in plaintext.
+		logger.WarnCF("agent", "Transcripts are not recorded in an encrypted workspace", nil)
+	} else if tc.Enabled {
+		rec, err := transcript.NewRecorder(transcript.Dir(cfg.WorkspacePath()), tc.Redact)
+		if err != nil {
+			logger.WarnCF("agent", "Transcripts disabled", map[string]interface{}{"error": err.Error()})
+		} else {
+			al.transcripts = rec
+		}
 	}
+	msgBus.OnReaction(al.handleReaction)
+	return al
 }
 
 // registerSharedTools registers tools that are shared across all agents (web, message, spawn).
 func registerSharedTools(cfg *config.Config, msgBus *bus.MessageBus, registry *AgentRegistry, provider providers.LLMProvider) {
+	// The MQTT tool holds one broker connection shared by all agents, as
+	// brokers drop the older of two connections with the same client ID.
+	var mqttTool *tools.MQTTTool
+	if mqttCfg := cfg.Tools.MQTT; mqttCfg.Enabled && mqttCfg.Broker != "" {
+		mqttTool = tools.NewMQTTTool(tools.MQTTToolOptions{
+			Broker:         mqttCfg.Broker,
+			ClientID:       mqttCfg.ClientID,
+			Username:       mqttCfg.Username,
+			Password:       mqttCfg.Password,
+			AllowPublish:   mqttCfg.AllowPublish,
+			AllowSubscribe: mqttCfg.AllowSubscribe,
+		})
+	}
+
+	var hardwareTool tools.Tool
+	if hwCfg := cfg.Tools.Hardware; hwCfg.Enabled {
+		opts := tools.HardwareToolOptions{MaxOpsPerMinute: hwCfg.MaxOpsPerMinute}
+		for _, p := range hwCfg.GPIOPins {
+			opts.GPIOPins = append(opts.GPIOPins, tools.GPIOPin{Name: p.Name, Pin: p.Pin, Output: p.Mode == "output"})
+		}
+		for _, s := range hwCfg.I2CSensors {
+			opts.I2CSensors = append(opts.I2CSensors, tools.I2CSensor{Name: s.Name, Bus: s.Bus, Address: s.Address})
+		}
+		if hardwareTool = tools.NewHardwareTool(opts); hardwareTool == nil {
+			logger.WarnCF("agent", "Hardware tool is enabled but not included in this build (build with -tags hardware)", nil)
+		}
+	}
+
+	// The notify tool's rate limits apply to all agents together.
+	var notifyTool *tools.NotifyTool
+	if notifyCfg := cfg.Tools.Notify; notifyCfg.Enabled && len(notifyCfg.Targets) > 0 {
+		opts := tools.NotifyToolOptions{MaxPerHour: notifyCfg.MaxPerHour}
+		for _, target := range notifyCfg.Targets {
+			var agents []string
+			for _, id := range target.Agents {
+				agents = append(agents, routing.NormalizeAgentID(id))
+			}
+			opts.Targets = append(opts.Targets, tools.NotifyTarget{
+				Name:    target.Name,
+				Channel: target.Channel,
+				ChatID:  target.ChatID,
+				Agents:  agents,
+			})
+		}
+		notifyTool = tools.NewNotifyTool(opts, msgBus.PublishOutbound)
+	}
+
 	for _, agentID := range registry.ListAgentIDs() {
 		agent, ok := registry.GetAgent(agentID)
 		if !ok {
@@ -105,6 +214,19 @@ func registerSharedTools(cfg *config.Config, msgBus *bus.MessageBus, registry *A
 		// Hardware tools (I2C, SPI) - Linux only, returns error on other platforms
 		agent.Tools.Register(tools.NewI2CTool())
 		agent.Tools.Register(tools.NewSPITool())
+		agent.Tools.Register(tools.NewDeviceStatusTool())
+
+		if mqttTool != nil {
+			agent.Tools.Register(mqttTool)
+		}
+		if notifyTool != nil {
+			if tool := notifyTool.ForAgent(agentID); tool != nil {
+				agent.Tools.Register(tool)
+			}
+		}
+		if hardwareTool != nil {
+			agent.Tools.Register(hardwareTool)
+		}
 
 		// Message tool
 		messageTool := tools.NewMessageTool()
@@ -123,13 +245,15 @@ func registerSharedTools(cfg *config.Config, msgBus *bus.MessageBus, registry *A
 			MaxConcurrentSearches: cfg.Tools.Skills.MaxConcurrentSearches,
 			ClawHub:               skills.ClawHubConfig(cfg.Tools.Skills.Registries.ClawHub),
 		})
-		searchCache := skills.NewSearchCache(cfg.Tools.Skills.SearchCache.MaxSize, time.Duration(cfg.Tools.Skills.SearchCache.TTLSeconds)*time.Second)
-		agent.Tools.Register(tools.NewFindSkillsTool(registryMgr, searchCache))
+		agent.SearchCache = skills.NewSearchCache(cfg.Tools.Skills.SearchCache.MaxSize, time.Duration(cfg.Tools.Skills.SearchCache.TTLSeconds)*time.Second)
+		agent.Tools.Register(tools.NewFindSkillsTool(registryMgr, agent.SearchCache))
 		agent.Tools.Register(tools.NewInstallSkillTool(registryMgr, agent.Workspace))
 
 		// Spawn tool with allowlist checker
 		subagentManager := tools.NewSubagentManager(provider, agent.Model, agent.Workspace, msgBus)
 		subagentManager.SetLLMOptions(agent.MaxTokens, agent.Temperature)
+		subagentManager.SetMaxRunning(cfg.Agents.Defaults.MaxBackgroundTasks)
+		agent.Tasks = subagentManager
 		spawnTool := tools.NewSpawnTool(subagentManager)
 		currentAgentID := agentID
 		spawnTool.SetAllowlistChecker(func(targetAgentID string) bool {
@@ -144,6 +268,9 @@ func registerSharedTools(cfg *config.Config, msgBus *bus.MessageBus, registry *A
 
 func (al *AgentLoop) Run(ctx context.Context) error {
 	al.running.Store(true)
+	if al.retry != nil {
+		al.startRetryQueue(ctx)
+	}
 
 	for al.running.Load() {
 		select {
@@ -155,9 +282,14 @@ func (al *AgentLoop) Run(ctx context.Context) error {
 				continue
 			}
 
-			response, err := al.processMessage(ctx, msg)
-			if err != nil {
-				response = fmt.Sprintf("Error processing message: %v", err)
+			replyKey := msg.Channel + "\x00" + msg.ChatID
+			al.replyExtras.Delete(replyKey)
+			response, err := al.processMessageSafe(ctx, msg)
+			extras, _ := al.replyExtras.LoadAndDelete(replyKey)
+			if reply, parked := al.handleTurnResult(msg, err); parked {
+				response = reply
+			} else if err != nil {
+				response = al.catalog.Render(al.languageFor(msg), i18n.ProcessingError, map[string]interface{}{"Error": err})
 			}
 
 			if response != "" {
@@ -175,11 +307,16 @@ func (al *AgentLoop) Run(ctx context.Context) error {
 				}
 
 				if !alreadySent {
-					al.bus.PublishOutbound(bus.OutboundMessage{
+					out := bus.OutboundMessage{
 						Channel: msg.Channel,
 						ChatID:  msg.ChatID,
 						Content: response,
-					})
+					}
+					if extras, ok := extras.(replyExtras); ok && err == nil {
+						out.TurnID = extras.turnID
+						out.Actions = extras.actions
+					}
+					al.bus.PublishOutbound(out)
 				}
 			}
 		}
@@ -188,8 +325,49 @@ func (al *AgentLoop) Run(ctx context.Context) error {
 	return nil
 }
 
+// processMessageSafe runs processMessage and turns a panic into an error,
+// so a single bad message can't stop the loop. The turn's trace starts
+// here so that a crash report names it.
+func (al *AgentLoop) processMessageSafe(ctx context.Context, msg bus.InboundMessage) (response string, err error) {
+	ctx = logger.WithTrace(ctx)
+	defer func() {
+		if r := recover(); r != nil {
+			crash.Capture("agent", r, logger.TraceFields(ctx, map[string]interface{}{
+				"channel":     msg.Channel,
+				"chat_id":     msg.ChatID,
+				"sender_id":   msg.SenderID,
+				"session_key": msg.SessionKey,
+			}))
+			err = fmt.Errorf("internal error")
+		}
+	}()
+	return al.processMessage(ctx, msg)
+}
+
+// ReleaseMemory frees in-memory state to relieve memory pressure: it drops
+// cached skill searches and evicts sessions idle for longer than idle to
+// disk, where they are reloaded on next use.
+func (al *AgentLoop) ReleaseMemory(idle time.Duration) {
+	evicted := 0
+	for _, agentID := range al.registry.ListAgentIDs() {
+		agent, ok := al.registry.GetAgent(agentID)
+		if !ok {
+			continue
+		}
+		if agent.SearchCache != nil {
+			agent.SearchCache.Clear()
+		}
+		evicted += agent.Sessions.Evict(idle)
+	}
+	al.pruneTitled()
+	logger.InfoCF("agent", "Released memory", map[string]interface{}{
+		"evicted_sessions": evicted,
+	})
+}
+
 func (al *AgentLoop) Stop() {
 	al.running.Store(false)
+	al.saveUsage()
 }
 
 func (al *AgentLoop) RegisterTool(tool tools.Tool) {
@@ -202,6 +380,91 @@ func (al *AgentLoop) RegisterTool(tool tools.Tool) {
 
 func (al *AgentLoop) SetChannelManager(cm *channels.Manager) {
 	al.channelManager = cm
+
+	// Offer choices as buttons if any channel can show them. Like the
+	// message tool, each agent gets its own, as it holds the round's state.
+	for _, name := range cm.GetEnabledChannels() {
+		if _, ok := cm.ActionSchema(name); !ok {
+			continue
+		}
+		for _, agentID := range al.registry.ListAgentIDs() {
+			if agent, ok := al.registry.GetAgent(agentID); ok {
+				agent.Tools.Register(tools.NewActionsTool(cm.ActionSchema))
+			}
+		}
+		break
+	}
+}
+
+// Usage returns the token usage tracker, or nil if usage tracking is
+// disabled.
+func (al *AgentLoop) Usage() *usage.Tracker {
+	return al.usage
+}
+
+// TurnsServed returns how many turns the agents have answered since the
+// loop was created.
+func (al *AgentLoop) TurnsServed() int64 {
+	return al.turns.Load()
+}
+
+// Pending returns the work the loop has not finished, by kind: turns
+// parked until the provider recovers and background tasks not done yet.
+func (al *AgentLoop) Pending() map[string]int {
+	pending := map[string]int{}
+	if al.retry != nil {
+		al.retry.mu.Lock()
+		pending["parked_turns"] = len(al.retry.parked)
+		al.retry.mu.Unlock()
+	}
+	for _, t := range al.Tasks() {
+		if t.Status == "queued" || t.Status == "running" {
+			pending["background_tasks"]++
+		}
+	}
+	return pending
+}
+
+// recordUsage adds the tokens used by a response to the usage of the
+// session and model.
+func (al *AgentLoop) recordUsage(sessionKey, model string, resp *providers.LLMResponse) {
+	if al.usage != nil && resp != nil && resp.Usage != nil {
+		al.usage.Record(sessionKey, model, *resp.Usage)
+	}
+}
+
+// recordTranscript completes e, a request made for sessionKey at started,
+// with its outcome and adds it to the transcripts, if they are recorded.
+func (al *AgentLoop) recordTranscript(ctx context.Context, sessionKey string, e transcript.Entry, started time.Time, resp *providers.LLMResponse, err error) {
+	if al.transcripts == nil {
+		return
+	}
+	e.Time = started
+	e.TraceID = logger.TraceID(ctx)
+	e.Session = sessionKey
+	e.Response = resp
+	if err != nil {
+		e.Error = err.Error()
+	}
+	e.DurationMS = time.Since(started).Milliseconds()
+	if err := al.transcripts.Record(e); err != nil {
+		logger.WarnCF("agent", "Failed to record transcript", map[string]interface{}{"error": err.Error()})
+	}
+}
+
+func (al *AgentLoop) saveUsage() {
+	if al.usage == nil {
+		return
+	}
+	if err := al.usage.Save(); err != nil {
+		logger.WarnCF("agent", "Failed to save usage", map[string]interface{}{"error": err.Error()})
+	}
+}
+
+// SetProviderFailureHook registers a callback invoked whenever an LLM call
+// fails after retries and fallbacks.
+func (al *AgentLoop) SetProviderFailureHook(hook func(err error)) {
+	al.onProviderFailure = hook
 }
 
 // RecordLastChannel records the last active channel for this workspace.
@@ -226,6 +489,21 @@ func (al *AgentLoop) ProcessDirect(ctx context.Context, content, sessionKey stri
 	return al.ProcessDirectWithChannel(ctx, content, sessionKey, "cli", "direct")
 }
 
+// ProcessDirectWithMedia is ProcessDirect from senderID with local files
+// attached to the message, e.g. documents to upload to the provider.
+func (al *AgentLoop) ProcessDirectWithMedia(ctx context.Context, content, sessionKey, senderID string, media []string) (string, error) {
+	msg := bus.InboundMessage{
+		Channel:    "cli",
+		SenderID:   senderID,
+		ChatID:     "direct",
+		Content:    content,
+		Media:      media,
+		SessionKey: sessionKey,
+	}
+
+	return al.processMessage(ctx, msg)
+}
+
 func (al *AgentLoop) ProcessDirectWithChannel(ctx context.Context, content, sessionKey, channel, chatID string) (string, error) {
 	msg := bus.InboundMessage{
 		Channel:    channel,
@@ -242,12 +520,13 @@ func (al *AgentLoop) ProcessDirectWithChannel(ctx context.Context, content, sess
 // Each heartbeat is independent and doesn't accumulate context.
 func (al *AgentLoop) ProcessHeartbeat(ctx context.Context, content, channel, chatID string) (string, error) {
 	agent := al.registry.GetDefaultAgent()
+	ctx = providers.WithPriority(ctx, providers.PriorityBackground)
 	return al.runAgentLoop(ctx, agent, processOptions{
 		SessionKey:      "heartbeat",
 		Channel:         channel,
 		ChatID:          chatID,
 		UserMessage:     content,
-		DefaultResponse: "I've completed processing but have no response to give.",
+		DefaultResponse: al.catalog.Get(i18n.NoResponse, nil),
 		EnableSummary:   false,
 		SendResponse:    false,
 		NoHistory:       true, // Don't load session history for heartbeat
@@ -280,26 +559,7 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 		return response, nil
 	}
 
-	// Route to determine agent and session key
-	route := al.registry.ResolveRoute(routing.RouteInput{
-		Channel:    msg.Channel,
-		AccountID:  msg.Metadata["account_id"],
-		Peer:       extractPeer(msg),
-		ParentPeer: extractParentPeer(msg),
-		GuildID:    msg.Metadata["guild_id"],
-		TeamID:     msg.Metadata["team_id"],
-	})
-
-	agent, ok := al.registry.GetAgent(route.AgentID)
-	if !ok {
-		agent = al.registry.GetDefaultAgent()
-	}
-
-	// Use routed session key, but honor pre-set agent-scoped keys (for ProcessDirect/cron)
-	sessionKey := route.SessionKey
-	if msg.SessionKey != "" && strings.HasPrefix(msg.SessionKey, "agent:") {
-		sessionKey = msg.SessionKey
-	}
+	agent, sessionKey, route := al.routeMessage(msg)
 
 	logger.InfoCF("agent", "Routed message",
 		map[string]interface{}{
@@ -308,14 +568,19 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 			"matched_by":  route.MatchedBy,
 		})
 
+	al.prefetchTools(ctx, agent, msg.Content)
+
+	lang := al.languageFor(msg)
 	return al.runAgentLoop(ctx, agent, processOptions{
 		SessionKey:      sessionKey,
 		Channel:         msg.Channel,
 		ChatID:          msg.ChatID,
 		UserMessage:     msg.Content,
-		DefaultResponse: "I've completed processing but have no response to give.",
+		Media:           msg.Media,
+		DefaultResponse: al.catalog.Render(lang, i18n.NoResponse, nil),
 		EnableSummary:   true,
 		SendResponse:    false,
+		Language:        lang,
 	})
 }
 
@@ -369,7 +634,7 @@ func (al *AgentLoop) processSystemMessage(ctx context.Context, msg bus.InboundMe
 		Channel:         originChannel,
 		ChatID:          originChatID,
 		UserMessage:     fmt.Sprintf("[System: %s] %s", msg.SenderID, msg.Content),
-		DefaultResponse: "Background task completed.",
+		DefaultResponse: al.catalog.Get(i18n.BackgroundDone, nil),
 		EnableSummary:   false,
 		SendResponse:    true,
 	})
@@ -377,6 +642,11 @@ func (al *AgentLoop) processSystemMessage(ctx context.Context, msg bus.InboundMe
 
 // runAgentLoop is the core message processing logic.
 func (al *AgentLoop) runAgentLoop(ctx context.Context, agent *AgentInstance, opts processOptions) (string, error) {
+	// Entries logged for this turn, here and in tools and providers, share
+	// a trace so that log escalation can tell which components took part.
+	ctx = logger.WithTrace(ctx)
+	budget := newLatencyBudget(agent.LatencyBudget, opts.Channel)
+
 	// 0. Record last channel for heartbeat notifications (skip internal channels)
 	if opts.Channel != "" && opts.ChatID != "" {
 		// Don't record internal channels (cli, system, subagent)
@@ -398,7 +668,7 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, agent *AgentInstance, opt
 		history = agent.Sessions.GetHistory(opts.SessionKey)
 		summary = agent.Sessions.GetSummary(opts.SessionKey)
 	}
-	messages := agent.ContextBuilder.BuildMessages(
+	messages, sections := agent.ContextBuilder.BuildMessagesWithSections(
 		history,
 		summary,
 		opts.UserMessage,
@@ -407,12 +677,39 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, agent *AgentInstance, opt
 		opts.ChatID,
 	)
 
+	// Documents go to the provider's file store and are referenced by ID,
+	// now and in later turns, as long as the upload is kept.
+	for i := range messages {
+		messages[i].Files = agent.Uploads.filter(messages[i].Files)
+	}
+	userMsg := providers.Message{Role: "user", Content: opts.UserMessage}
+	userMsg.Files = al.attachFiles(ctx, agent, opts.Media)
+	if n := len(messages); n > 0 && len(userMsg.Files) > 0 && messages[n-1].Role == "user" {
+		messages[n-1].Files = userMsg.Files
+	}
+	// Images are only sent with the message they came with; the session
+	// keeps the text.
+	if n := len(messages); n > 0 && messages[n-1].Role == "user" {
+		messages[n-1].Images = attachImages(agent, opts.Media)
+	}
+
+	al.recordContext(agent, opts.SessionKey, history, sections, messages, strings.TrimSpace(opts.UserMessage) != "")
+
 	// 3. Save user message to session
-	agent.Sessions.AddMessage(opts.SessionKey, "user", opts.UserMessage)
+	agent.Sessions.AddFullMessage(opts.SessionKey, userMsg)
 
 	// 4. Run LLM iteration loop
-	finalContent, iteration, err := al.runLLMIteration(ctx, agent, messages, opts)
+	gen := newGeneration(agent)
+	finalContent, iteration, err := al.runLLMIteration(ctx, agent, messages, opts, gen, budget)
 	if err != nil {
+		if !opts.NoHistory && al.shouldPark(opts.Channel, err) {
+			// If the turn is parked it will be run again from the start
+			// once the provider is back, so what it added to the session
+			// is dropped then.
+			return "", &parkableError{err: err, rollback: func() {
+				agent.Sessions.SetHistory(opts.SessionKey, history)
+			}}
+		}
 		return "", err
 	}
 
@@ -424,22 +721,43 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, agent *AgentInstance, opt
 		finalContent = opts.DefaultResponse
 	}
 
-	// 6. Save final assistant message to session
+	// 6. Save final assistant message to session, with how it was generated
 	agent.Sessions.AddMessage(opts.SessionKey, "assistant", finalContent)
+	gen.RepliedAt = time.Now()
+	gen.Reply = utils.Truncate(finalContent, feedbackReplyRunes)
+	gen.Calls = iteration
+	budget.record(ctx, agent.ID, gen)
+	agent.Sessions.AddGeneration(opts.SessionKey, *gen)
 	agent.Sessions.Save(opts.SessionKey)
+	al.saveUsage()
 
-	// 7. Optional: summarization
+	// 7. Optional: summarization and session title
 	if opts.EnableSummary {
-		al.maybeSummarize(agent, opts.SessionKey, opts.Channel, opts.ChatID)
+		al.maybeSummarize(agent, opts.SessionKey, opts.Channel, opts.ChatID, opts.Language)
+		al.maybeTitle(ctx, agent, opts.SessionKey)
 	}
 
-	// 8. Optional: send response via bus
+	// 8. Optional: send response via bus, with a turn ID reactions to it
+	// are attributed by and the choices the agent offered
+	var extras replyExtras
+	if !opts.NoHistory && !constants.IsInternalChannel(opts.Channel) {
+		extras.turnID = al.trackReply(agent, opts.SessionKey, finalContent)
+	}
+	if tool, ok := agent.Tools.Get("offer_actions"); ok {
+		if at, ok := tool.(*tools.ActionsTool); ok {
+			extras.actions = at.TakeActions()
+		}
+	}
 	if opts.SendResponse {
 		al.bus.PublishOutbound(bus.OutboundMessage{
 			Channel: opts.Channel,
 			ChatID:  opts.ChatID,
 			Content: finalContent,
+			TurnID:  extras.turnID,
+			Actions: extras.actions,
 		})
+	} else if extras.turnID != "" || len(extras.actions) > 0 {
+		al.replyExtras.Store(opts.Channel+"\x00"+opts.ChatID, extras)
 	}
 
 	// 9. Log response
@@ -452,30 +770,39 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, agent *AgentInstance, opt
 			"final_length": len(finalContent),
 		})
 
+	al.turns.Add(1)
 	return finalContent, nil
 }
 
 // runLLMIteration executes the LLM call loop with tool handling.
-func (al *AgentLoop) runLLMIteration(ctx context.Context, agent *AgentInstance, messages []providers.Message, opts processOptions) (string, int, error) {
+// The settings and model of its calls are recorded in gen. Once the turn
+// is over its latency budget, requests go to the budget's fast model and
+// no more tools are run.
+func (al *AgentLoop) runLLMIteration(ctx context.Context, agent *AgentInstance, messages []providers.Message, opts processOptions, gen *session.Generation, budget *latencyBudget) (string, int, error) {
 	iteration := 0
 	var finalContent string
 
 	for iteration < agent.MaxIterations {
 		iteration++
+		hurry := budget.over(ctx, agent.ID, iteration)
 
 		logger.DebugCF("agent", "LLM iteration",
-			map[string]interface{}{
+			logger.TraceFields(ctx, map[string]interface{}{
 				"agent_id":  agent.ID,
 				"iteration": iteration,
 				"max":       agent.MaxIterations,
-			})
+			}))
 
-		// Build tool definitions
-		providerToolDefs := agent.Tools.ToProviderDefs()
+		// Build tool definitions. A turn over its latency budget is sent
+		// none, so that the model answers with what it has.
+		provider, providerToolDefs := agent.chatTools()
+		if hurry {
+			providerToolDefs = nil
+		}
 
 		// Log LLM request details
 		logger.DebugCF("agent", "LLM request",
-			map[string]interface{}{
+			logger.TraceFields(ctx, map[string]interface{}{
 				"agent_id":          agent.ID,
 				"iteration":         iteration,
 				"model":             agent.Model,
@@ -484,28 +811,99 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, agent *AgentInstance,
 				"max_tokens":        agent.MaxTokens,
 				"temperature":       agent.Temperature,
 				"system_prompt_len": len(messages[0].Content),
-			})
+			}))
 
-		// Log full messages (detailed)
-		logger.DebugCF("agent", "Full LLM request",
-			map[string]interface{}{
-				"iteration":     iteration,
-				"messages_json": formatMessagesForLog(messages),
-				"tools_json":    formatToolsForLog(providerToolDefs),
-			})
+		// Log full messages (detailed), only formatted when they are logged.
+		if logger.EnabledFor("agent", logger.DEBUG) {
+			logger.DebugCF("agent", "Full LLM request",
+				map[string]interface{}{
+					"iteration":     iteration,
+					"messages_json": formatMessagesForLog(messages),
+					"tools_json":    formatToolsForLog(providerToolDefs),
+				})
+		}
 
 		// Call LLM with fallback chain if candidates are configured.
 		var response *providers.LLMResponse
 		var err error
 
+		// With streaming, the reply is shown as it is generated and tool
+		// calls start as soon as they are complete; their results are picked
+		// up below.
+		streamReply := agent.StreamReplies && opts.SendResponse && !constants.IsInternalChannel(opts.Channel)
+		var early *earlyTools
+		chat := func(callCtx context.Context, provider providers.LLMProvider, model string) (*providers.LLMResponse, error) {
+			options := generationOptions(gen)
+			cacheKey := cacheKeyMessages(agent, messages)
+			if resp := agent.Cache.Get(model, cacheKey, providerToolDefs, options); resp != nil {
+				// Cached responses cost nothing and use no quota.
+				observeGeneration(gen, model, resp)
+				return resp, nil
+			}
+			if err := al.scheduler.Wait(callCtx); err != nil {
+				return nil, err
+			}
+			started := time.Now()
+			streamer, ok := provider.(providers.StreamingProvider)
+			if !ok || (!agent.StreamTools && !streamReply) {
+				resp, err := provider.Chat(callCtx, messages, providerToolDefs, model, options)
+				al.scheduler.Record(resp)
+				al.observePromptTokens(agent, model, messages, providerToolDefs, resp)
+				al.recordUsage(opts.SessionKey, model, resp)
+				al.recordTranscript(ctx, opts.SessionKey, transcript.Entry{Model: model, Messages: messages, Tools: providerToolDefs, Options: options}, started, resp, err)
+				observeGeneration(gen, model, resp)
+				if err == nil {
+					agent.Cache.Put(model, cacheKey, providerToolDefs, options, resp)
+				}
+				return resp, err
+			}
+
+			var runner *earlyTools
+			if agent.StreamTools && !hurry {
+				if early != nil {
+					// From a failed attempt; its calls were read-only, so
+					// running them again does no harm.
+					early.wait()
+				}
+				runner = newEarlyTools(ctx, func(tc providers.ToolCall) *tools.ToolResult {
+					return al.executeToolCall(ctx, agent, tc, iteration, opts)
+				}, func(tc providers.ToolCall) bool {
+					return readOnlyCall(agent.Tools, tc)
+				})
+				early = runner
+			}
+			var reply *partialReply
+			if streamReply {
+				reply = &partialReply{bus: al.bus, channel: opts.Channel, chatID: opts.ChatID}
+			}
+
+			resp, err := streamer.ChatStream(callCtx, messages, providerToolDefs, model, options, func(ev providers.StreamEvent) {
+				if ev.Text != "" && reply != nil {
+					reply.add(ev.Text)
+				}
+				if ev.ToolCall != nil && runner != nil {
+					runner.submit(providers.NormalizeToolCall(*ev.ToolCall))
+				}
+			})
+			al.scheduler.Record(resp)
+			al.observePromptTokens(agent, model, messages, providerToolDefs, resp)
+			al.recordUsage(opts.SessionKey, model, resp)
+			al.recordTranscript(ctx, opts.SessionKey, transcript.Entry{Model: model, Messages: messages, Tools: providerToolDefs, Options: options}, started, resp, err)
+			observeGeneration(gen, model, resp)
+			if err == nil {
+				agent.Cache.Put(model, cacheKey, providerToolDefs, options, resp)
+			}
+			return resp, err
+		}
+
 		callLLM := func() (*providers.LLMResponse, error) {
+			if hurry && agent.FastProvider != nil {
+				return chat(ctx, agent.FastProvider, agent.FastModel)
+			}
 			if len(agent.Candidates) > 1 && al.fallback != nil {
 				fbResult, fbErr := al.fallback.Execute(ctx, agent.Candidates,
-					func(ctx context.Context, provider, model string) (*providers.LLMResponse, error) {
-						return agent.Provider.Chat(ctx, messages, providerToolDefs, model, map[string]interface{}{
-							"max_tokens":  agent.MaxTokens,
-							"temperature": agent.Temperature,
-						})
+					func(ctx context.Context, _, model string) (*providers.LLMResponse, error) {
+						return chat(ctx, provider, model)
 					},
 				)
 				if fbErr != nil {
@@ -518,10 +916,14 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, agent *AgentInstance,
 				}
 				return fbResult.Response, nil
 			}
-			return agent.Provider.Chat(ctx, messages, providerToolDefs, agent.Model, map[string]interface{}{
-				"max_tokens":  agent.MaxTokens,
-				"temperature": agent.Temperature,
-			})
+			return chat(ctx, provider, agent.Model)
+		}
+
+		// Requests too long for the model's context window are truncated
+		// as the agent's policy says, or fail here.
+		messages, err = al.fitContext(agent, opts, messages, providerToolDefs)
+		if err != nil {
+			return "", iteration, err
 		}
 
 		// Retry loop for context/token errors
@@ -539,16 +941,16 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, agent *AgentInstance,
 				strings.Contains(errMsg, "length")
 
 			if isContextError && retry < maxRetries {
-				logger.WarnCF("agent", "Context window error detected, attempting compression", map[string]interface{}{
+				logger.WarnCF("agent", "Context window error detected, attempting compression", logger.TraceFields(ctx, map[string]interface{}{
 					"error": err.Error(),
 					"retry": retry,
-				})
+				}))
 
 				if retry == 0 && !constants.IsInternalChannel(opts.Channel) {
 					al.bus.PublishOutbound(bus.OutboundMessage{
 						Channel: opts.Channel,
 						ChatID:  opts.ChatID,
-						Content: "Context window exceeded. Compressing history and retrying...",
+						Content: al.catalog.Render(opts.Language, i18n.ContextCompressing, nil),
 					})
 				}
 
@@ -559,18 +961,29 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, agent *AgentInstance,
 					newHistory, newSummary, "",
 					nil, opts.Channel, opts.ChatID,
 				)
+				if fitted, err := al.fitContext(agent, opts, messages, providerToolDefs); err == nil {
+					messages = fitted
+				}
 				continue
 			}
 			break
 		}
 
+		var ran []earlyResult
+		if early != nil {
+			ran = early.wait()
+		}
+
 		if err != nil {
 			logger.ErrorCF("agent", "LLM call failed",
-				map[string]interface{}{
+				logger.TraceFields(ctx, map[string]interface{}{
 					"agent_id":  agent.ID,
 					"iteration": iteration,
 					"error":     err.Error(),
-				})
+				}))
+			if al.onProviderFailure != nil {
+				al.onProviderFailure(err)
+			}
 			return "", iteration, fmt.Errorf("LLM call failed after retries: %w", err)
 		}
 
@@ -578,11 +991,11 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, agent *AgentInstance,
 		if len(response.ToolCalls) == 0 {
 			finalContent = response.Content
 			logger.InfoCF("agent", "LLM response without tool calls (direct answer)",
-				map[string]interface{}{
+				logger.TraceFields(ctx, map[string]interface{}{
 					"agent_id":      agent.ID,
 					"iteration":     iteration,
 					"content_chars": len(finalContent),
-				})
+				}))
 			break
 		}
 
@@ -597,12 +1010,12 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, agent *AgentInstance,
 			toolNames = append(toolNames, tc.Name)
 		}
 		logger.InfoCF("agent", "LLM requested tool calls",
-			map[string]interface{}{
+			logger.TraceFields(ctx, map[string]interface{}{
 				"agent_id":  agent.ID,
 				"tools":     toolNames,
 				"count":     len(normalizedToolCalls),
 				"iteration": iteration,
-			})
+			}))
 
 		// Build assistant message with tool calls
 		assistantMsg := providers.Message{
@@ -636,49 +1049,17 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, agent *AgentInstance,
 		// Save assistant message with tool calls to session
 		agent.Sessions.AddFullMessage(opts.SessionKey, assistantMsg)
 
-		// Execute tool calls
-		for _, tc := range normalizedToolCalls {
-			argsJSON, _ := json.Marshal(tc.Arguments)
-			argsPreview := utils.Truncate(string(argsJSON), 200)
-			logger.InfoCF("agent", fmt.Sprintf("Tool call: %s(%s)", tc.Name, argsPreview),
-				map[string]interface{}{
-					"agent_id":  agent.ID,
-					"tool":      tc.Name,
-					"iteration": iteration,
-				})
-
-			// Create async callback for tools that implement AsyncTool
-			// NOTE: Following openclaw's design, async tools do NOT send results directly to users.
-			// Instead, they notify the agent via PublishInbound, and the agent decides
-			// whether to forward the result to the user (in processSystemMessage).
-			asyncCallback := func(callbackCtx context.Context, result *tools.ToolResult) {
-				// Log the async completion but don't send directly to user
-				// The agent will handle user notification via processSystemMessage
-				if !result.Silent && result.ForUser != "" {
-					logger.InfoCF("agent", "Async tool completed, agent will handle notification",
-						map[string]interface{}{
-							"tool":        tc.Name,
-							"content_len": len(result.ForUser),
-						})
+		// Execute tool calls that did not already run during streaming
+		for i, tc := range normalizedToolCalls {
+			toolResult := resultFor(ran, i, tc)
+			if toolResult == nil {
+				if budget.over(ctx, agent.ID, iteration) {
+					toolResult = tools.ErrorResult(budgetSkippedTool)
+				} else {
+					toolResult = al.executeToolCall(ctx, agent, tc, iteration, opts)
 				}
 			}
 
-			toolResult := agent.Tools.ExecuteWithContext(ctx, tc.Name, tc.Arguments, opts.Channel, opts.ChatID, asyncCallback)
-
-			// Send ForUser content to user immediately if not Silent
-			if !toolResult.Silent && toolResult.ForUser != "" && opts.SendResponse {
-				al.bus.PublishOutbound(bus.OutboundMessage{
-					Channel: opts.Channel,
-					ChatID:  opts.ChatID,
-					Content: toolResult.ForUser,
-				})
-				logger.DebugCF("agent", "Sent tool result to user",
-					map[string]interface{}{
-						"tool":        tc.Name,
-						"content_len": len(toolResult.ForUser),
-					})
-			}
-
 			// Determine content for LLM based on tool result
 			contentForLLM := toolResult.ForLLM
 			if contentForLLM == "" && toolResult.Err != nil {
@@ -700,6 +1081,53 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, agent *AgentInstance,
 	return finalContent, iteration, nil
 }
 
+// executeToolCall runs one tool call of an LLM response and sends any
+// output meant for the user right away.
+func (al *AgentLoop) executeToolCall(ctx context.Context, agent *AgentInstance, tc providers.ToolCall, iteration int, opts processOptions) *tools.ToolResult {
+	argsJSON, _ := json.Marshal(tc.Arguments)
+	argsPreview := utils.Truncate(string(argsJSON), 200)
+	logger.InfoCF("agent", fmt.Sprintf("Tool call: %s(%s)", tc.Name, argsPreview),
+		logger.TraceFields(ctx, map[string]interface{}{
+			"agent_id":  agent.ID,
+			"tool":      tc.Name,
+			"iteration": iteration,
+		}))
+
+	// Create async callback for tools that implement AsyncTool
+	// NOTE: Following openclaw's design, async tools do NOT send results directly to users.
+	// Instead, they notify the agent via PublishInbound, and the agent decides
+	// whether to forward the result to the user (in processSystemMessage).
+	asyncCallback := func(callbackCtx context.Context, result *tools.ToolResult) {
+		// Log the async completion but don't send directly to user
+		// The agent will handle user notification via processSystemMessage
+		if !result.Silent && result.ForUser != "" {
+			logger.InfoCF("agent", "Async tool completed, agent will handle notification",
+				map[string]interface{}{
+					"tool":        tc.Name,
+					"content_len": len(result.ForUser),
+				})
+		}
+	}
+
+	toolResult := agent.Tools.ExecuteWithContext(ctx, tc.Name, tc.Arguments, opts.Channel, opts.ChatID, asyncCallback)
+
+	// Send ForUser content to user immediately if not Silent
+	if !toolResult.Silent && toolResult.ForUser != "" && opts.SendResponse {
+		al.bus.PublishOutbound(bus.OutboundMessage{
+			Channel: opts.Channel,
+			ChatID:  opts.ChatID,
+			Content: toolResult.ForUser,
+		})
+		logger.DebugCF("agent", "Sent tool result to user",
+			map[string]interface{}{
+				"tool":        tc.Name,
+				"content_len": len(toolResult.ForUser),
+			})
+	}
+
+	return toolResult
+}
+
 // updateToolContexts updates the context for tools that need channel/chatID info.
 func (al *AgentLoop) updateToolContexts(agent *AgentInstance, channel, chatID string) {
 	// Use ContextualTool interface instead of type assertions
@@ -718,10 +1146,15 @@ func (al *AgentLoop) updateToolContexts(agent *AgentInstance, channel, chatID st
 			st.SetContext(channel, chatID)
 		}
 	}
+	if tool, ok := agent.Tools.Get("offer_actions"); ok {
+		if at, ok := tool.(tools.ContextualTool); ok {
+			at.SetContext(channel, chatID)
+		}
+	}
 }
 
 // maybeSummarize triggers summarization if the session history exceeds thresholds.
-func (al *AgentLoop) maybeSummarize(agent *AgentInstance, sessionKey, channel, chatID string) {
+func (al *AgentLoop) maybeSummarize(agent *AgentInstance, sessionKey, channel, chatID, lang string) {
 	newHistory := agent.Sessions.GetHistory(sessionKey)
 	tokenEstimate := al.estimateTokens(newHistory)
 	threshold := agent.ContextWindow * 75 / 100
@@ -731,11 +1164,12 @@ func (al *AgentLoop) maybeSummarize(agent *AgentInstance, sessionKey, channel, c
 		if _, loading := al.summarizing.LoadOrStore(summarizeKey, true); !loading {
 			go func() {
 				defer al.summarizing.Delete(summarizeKey)
+				defer crash.Recover("agent", map[string]interface{}{"session_key": sessionKey})
 				if !constants.IsInternalChannel(channel) {
 					al.bus.PublishOutbound(bus.OutboundMessage{
 						Channel: channel,
 						ChatID:  chatID,
-						Content: "Memory threshold reached. Optimizing conversation history...",
+						Content: al.catalog.Render(lang, i18n.MemoryCompressing, nil),
 					})
 				}
 				al.summarizeSession(agent, sessionKey)
@@ -875,7 +1309,7 @@ func formatToolsForLog(toolDefs []providers.ToolDefinition) string {
 
 // summarizeSession summarizes the conversation history for a session.
 func (al *AgentLoop) summarizeSession(agent *AgentInstance, sessionKey string) {
-	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	ctx, cancel := context.WithTimeout(providers.WithPriority(context.Background(), providers.PriorityBackground), 120*time.Second)
 	defer cancel()
 
 	history := agent.Sessions.GetHistory(sessionKey)
@@ -909,28 +1343,24 @@ func (al *AgentLoop) summarizeSession(agent *AgentInstance, sessionKey string) {
 		return
 	}
 
-	// Multi-Part Summarization
-	var finalSummary string
-	if len(validMessages) > 10 {
-		mid := len(validMessages) / 2
-		part1 := validMessages[:mid]
-		part2 := validMessages[mid:]
-
-		s1, _ := al.summarizeBatch(ctx, agent, part1, "")
-		s2, _ := al.summarizeBatch(ctx, agent, part2, "")
-
-		mergePrompt := fmt.Sprintf("Merge these two conversation summaries into one cohesive summary:\n\n1: %s\n\n2: %s", s1, s2)
-		resp, err := agent.Provider.Chat(ctx, []providers.Message{{Role: "user", Content: mergePrompt}}, nil, agent.Model, map[string]interface{}{
-			"max_tokens":  1024,
-			"temperature": 0.3,
+	record := func(model string, resp *providers.LLMResponse) { al.recordUsage(sessionKey, model, resp) }
+	provider := al.scheduler.Provider(agent.Provider)
+	summarizer, err := NewSummarizer(agent.Summary, provider, agent.Model, agent.ContextWindow, record)
+	if err != nil {
+		logger.WarnCF("agent", "Invalid summarizer, using the default", map[string]interface{}{
+			"agent_id": agent.ID,
+			"error":    err.Error(),
 		})
-		if err == nil {
-			finalSummary = resp.Content
-		} else {
-			finalSummary = s1 + " " + s2
-		}
-	} else {
-		finalSummary, _ = al.summarizeBatch(ctx, agent, validMessages, summary)
+		summarizer, _ = NewSummarizer(config.SummarizerConfig{}, provider, agent.Model, agent.ContextWindow, record)
+	}
+	finalSummary, err := summarizer.Summarize(ctx, validMessages, summary)
+	if err != nil {
+		logger.WarnCF("agent", "Failed to summarize session", map[string]interface{}{
+			"session_key": sessionKey,
+			"strategy":    agent.Summary.Strategy,
+			"error":       err.Error(),
+		})
+		return
 	}
 
 	if omitted && finalSummary != "" {
@@ -944,31 +1374,6 @@ func (al *AgentLoop) summarizeSession(agent *AgentInstance, sessionKey string) {
 	}
 }
 
-// summarizeBatch summarizes a batch of messages.
-func (al *AgentLoop) summarizeBatch(ctx context.Context, agent *AgentInstance, batch []providers.Message, existingSummary string) (string, error) {
-	var sb strings.Builder
-	sb.WriteString("Provide a concise summary of this conversation segment, preserving core context and key points.\n")
-	if existingSummary != "" {
-		sb.WriteString("Existing context: ")
-		sb.WriteString(existingSummary)
-		sb.WriteString("\n")
-	}
-	sb.WriteString("\nCONVERSATION:\n")
-	for _, m := range batch {
-		fmt.Fprintf(&sb, "%s: %s\n", m.Role, m.Content)
-	}
-	prompt := sb.String()
-
-	response, err := agent.Provider.Chat(ctx, []providers.Message{{Role: "user", Content: prompt}}, nil, agent.Model, map[string]interface{}{
-		"max_tokens":  1024,
-		"temperature": 0.3,
-	})
-	if err != nil {
-		return "", err
-	}
-	return response.Content, nil
-}
-
 // estimateTokens estimates the number of tokens in a message list.
 // Uses a safe heuristic of 2.5 characters per token to account for CJK and other
 // overheads better than the previous 3 chars/token.
@@ -995,53 +1400,120 @@ func (al *AgentLoop) handleCommand(ctx context.Context, msg bus.InboundMessage)
 	cmd := parts[0]
 	args := parts[1:]
 
+	lang := al.languageFor(msg)
+	t := func(key i18n.Key, data map[string]interface{}) (string, bool) {
+		return al.catalog.Render(lang, key, data), true
+	}
+	unknown := func(target string) (string, bool) {
+		return t(i18n.CmdUnknownTarget, map[string]interface{}{"Command": strings.TrimPrefix(cmd, "/"), "Target": target})
+	}
+
 	switch cmd {
 	case "/show":
 		if len(args) < 1 {
-			return "Usage: /show [model|channel|agents]", true
+			return t(i18n.CmdShowUsage, nil)
 		}
 		switch args[0] {
 		case "model":
 			defaultAgent := al.registry.GetDefaultAgent()
 			if defaultAgent == nil {
-				return "No default agent configured", true
+				return t(i18n.CmdNoDefaultAgent, nil)
 			}
-			return fmt.Sprintf("Current model: %s", defaultAgent.Model), true
+			return t(i18n.CmdCurrentModel, map[string]interface{}{"Model": defaultAgent.Model})
 		case "channel":
-			return fmt.Sprintf("Current channel: %s", msg.Channel), true
+			return t(i18n.CmdCurrentChannel, map[string]interface{}{"Channel": msg.Channel})
 		case "agents":
 			agentIDs := al.registry.ListAgentIDs()
-			return fmt.Sprintf("Registered agents: %s", strings.Join(agentIDs, ", ")), true
+			return t(i18n.CmdRegisteredAgents, map[string]interface{}{"Agents": strings.Join(agentIDs, ", ")})
 		default:
-			return fmt.Sprintf("Unknown show target: %s", args[0]), true
+			return unknown(args[0])
 		}
 
 	case "/list":
 		if len(args) < 1 {
-			return "Usage: /list [models|channels|agents]", true
+			return t(i18n.CmdListUsage, nil)
 		}
 		switch args[0] {
 		case "models":
-			return "Available models: configured in config.json per agent", true
+			return t(i18n.CmdAvailableModels, nil)
 		case "channels":
 			if al.channelManager == nil {
-				return "Channel manager not initialized", true
+				return t(i18n.CmdNoChannelManager, nil)
 			}
 			channels := al.channelManager.GetEnabledChannels()
 			if len(channels) == 0 {
-				return "No channels enabled", true
+				return t(i18n.CmdNoChannels, nil)
 			}
-			return fmt.Sprintf("Enabled channels: %s", strings.Join(channels, ", ")), true
+			return t(i18n.CmdEnabledChannels, map[string]interface{}{"Channels": strings.Join(channels, ", ")})
 		case "agents":
 			agentIDs := al.registry.ListAgentIDs()
-			return fmt.Sprintf("Registered agents: %s", strings.Join(agentIDs, ", ")), true
+			return t(i18n.CmdRegisteredAgents, map[string]interface{}{"Agents": strings.Join(agentIDs, ", ")})
 		default:
-			return fmt.Sprintf("Unknown list target: %s", args[0]), true
+			return unknown(args[0])
+		}
+
+	case "/export":
+		path, err := al.exportSession(msg)
+		if err != nil {
+			return t(i18n.CmdExportFailed, map[string]interface{}{"Error": err.Error()})
+		}
+		if path == "" {
+			return t(i18n.CmdExportEmpty, nil)
+		}
+		return t(i18n.CmdExported, map[string]interface{}{"Path": path})
+
+	case "/forget":
+		f, ok, err := al.forget(msg, strings.TrimPrefix(content, cmd))
+		if !ok {
+			return t(i18n.CmdForgetUsage, nil)
 		}
+		if err != nil {
+			return t(i18n.CmdForgetFailed, map[string]interface{}{"Error": err.Error()})
+		}
+		if !f.Changed() && f.Memory == 0 {
+			return t(i18n.CmdForgetNothing, nil)
+		}
+		return t(i18n.CmdForgotten, map[string]interface{}{"Messages": f.Removed, "Matches": f.Matches()})
+
+	case "/why":
+		_, sessionKey, _ := al.routeMessage(msg)
+		report, ok := al.LastContext(sessionKey)
+		if !ok {
+			return t(i18n.CmdWhyEmpty, nil)
+		}
+		out := report.Format(false)
+		if agent, ok := al.registry.GetAgent(report.AgentID); ok {
+			if sess, ok := agent.Sessions.Get(sessionKey); ok && len(sess.Generations) > 0 {
+				out += formatGeneration(sess.Generations[len(sess.Generations)-1])
+			}
+		}
+		return out, true
+
+	case "/tasks":
+		if len(args) == 0 {
+			infos := al.chatTasks(msg)
+			if len(infos) == 0 {
+				return t(i18n.CmdTasksEmpty, nil)
+			}
+			return formatTasks(infos), true
+		}
+		if args[0] != "cancel" || len(args) != 2 {
+			return t(i18n.CmdTasksUsage, nil)
+		}
+		// Only tasks started from this chat can be cancelled from it.
+		for _, info := range al.chatTasks(msg) {
+			if info.ID == args[1] {
+				if err := al.CancelTask(info.ID); err != nil {
+					return t(i18n.CmdTaskCancelFailed, map[string]interface{}{"Error": err.Error()})
+				}
+				return t(i18n.CmdTaskCancelled, map[string]interface{}{"ID": info.ID})
+			}
+		}
+		return t(i18n.CmdTaskCancelFailed, map[string]interface{}{"Error": "no task " + args[1]})
 
 	case "/switch":
 		if len(args) < 3 || args[1] != "to" {
-			return "Usage: /switch [model|channel] to <name>", true
+			return t(i18n.CmdSwitchUsage, nil)
 		}
 		target := args[0]
 		value := args[2]
@@ -1050,27 +1522,90 @@ func (al *AgentLoop) handleCommand(ctx context.Context, msg bus.InboundMessage)
 		case "model":
 			defaultAgent := al.registry.GetDefaultAgent()
 			if defaultAgent == nil {
-				return "No default agent configured", true
+				return t(i18n.CmdNoDefaultAgent, nil)
 			}
 			oldModel := defaultAgent.Model
-			defaultAgent.Model = value
-			return fmt.Sprintf("Switched model from %s to %s", oldModel, value), true
+			defaultAgent.setModel(value, providers.ResolveCapabilities(al.cfg, value))
+			return t(i18n.CmdSwitchedModel, map[string]interface{}{"From": oldModel, "To": value})
 		case "channel":
 			if al.channelManager == nil {
-				return "Channel manager not initialized", true
+				return t(i18n.CmdNoChannelManager, nil)
 			}
 			if _, exists := al.channelManager.GetChannel(value); !exists && value != "cli" {
-				return fmt.Sprintf("Channel '%s' not found or not enabled", value), true
+				return t(i18n.CmdChannelNotFound, map[string]interface{}{"Channel": value})
 			}
-			return fmt.Sprintf("Switched target channel to %s", value), true
+			return t(i18n.CmdSwitchedChannel, map[string]interface{}{"Channel": value})
 		default:
-			return fmt.Sprintf("Unknown switch target: %s", target), true
+			return unknown(target)
 		}
 	}
 
 	return "", false
 }
 
+// routeMessage resolves the agent and session key that handle msg.
+func (al *AgentLoop) routeMessage(msg bus.InboundMessage) (*AgentInstance, string, routing.ResolvedRoute) {
+	route := al.registry.ResolveRoute(routing.RouteInput{
+		Channel:    msg.Channel,
+		AccountID:  msg.Metadata["account_id"],
+		Peer:       extractPeer(msg),
+		ParentPeer: extractParentPeer(msg),
+		GuildID:    msg.Metadata["guild_id"],
+		TeamID:     msg.Metadata["team_id"],
+	})
+
+	agent, ok := al.registry.GetAgent(route.AgentID)
+	if !ok {
+		agent = al.registry.GetDefaultAgent()
+	}
+
+	// Use routed session key, but honor pre-set agent-scoped keys (for ProcessDirect/cron)
+	sessionKey := route.SessionKey
+	if msg.SessionKey != "" && strings.HasPrefix(msg.SessionKey, "agent:") {
+		sessionKey = msg.SessionKey
+	}
+	return agent, sessionKey, route
+}
+
+// exportSession writes the HTML export of the conversation msg belongs to
+// into the agent workspace and returns its path.
+func (al *AgentLoop) exportSession(msg bus.InboundMessage) (string, error) {
+	agent, sessionKey, _ := al.routeMessage(msg)
+	if agent == nil {
+		return "", fmt.Errorf("no agent configured")
+	}
+	sess, ok := agent.Sessions.Get(sessionKey)
+	if !ok || len(sess.Messages) == 0 {
+		return "", nil
+	}
+
+	dir := filepath.Join(agent.Workspace, "exports")
+	if err := vfs.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := session.ExportHTML(&buf, sess); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, session.ExportFilename(sessionKey))
+	return path, vfs.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// languageFor picks the language for system messages sent in reply to msg:
+// a per-user setting from config first, then the language the channel
+// reports for the sender, then the configured default.
+func (al *AgentLoop) languageFor(msg bus.InboundMessage) string {
+	var userLang string
+	if al.cfg != nil {
+		senderID := msg.SenderID
+		if idx := strings.Index(senderID, "|"); idx > 0 {
+			senderID = senderID[:idx]
+		}
+		userLang = al.cfg.Messages.UserLanguages[msg.Channel+":"+senderID]
+	}
+	return al.catalog.Resolve(userLang, msg.Metadata["language"])
+}
+
 // extractPeer extracts the routing peer from inbound message metadata.
 func extractPeer(msg bus.InboundMessage) *routing.RoutePeer {
 	peerKind := msg.Metadata["peer_kind"]
@@ -8,10 +8,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/sipeed/picoclaw/pkg/atrest"
 	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels"
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/providers"
 	"github.com/sipeed/picoclaw/pkg/tools"
+	"github.com/sipeed/picoclaw/pkg/transcript"
+	"github.com/sipeed/picoclaw/pkg/usage"
 )
 
 func TestRecordLastChannel(t *testing.T) {
@@ -613,3 +617,310 @@ func TestAgentLoop_ContextExhaustionRetry(t *testing.T) {
 		t.Errorf("Expected history to be compressed (len < 8), got %d", len(finalHistory))
 	}
 }
+
+type usageMockProvider struct{}
+
+func (m *usageMockProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	return &providers.LLMResponse{
+		Content: "ok",
+		Usage:   &providers.UsageInfo{PromptTokens: 1000, CompletionTokens: 100, TotalTokens: 1100},
+	}, nil
+}
+
+func (m *usageMockProvider) GetDefaultModel() string {
+	return "mock-model"
+}
+
+// TestAgentLoop_RecordsUsage verifies that the tokens of each turn are
+// accumulated per session and model and saved after the turn.
+func TestAgentLoop_RecordsUsage(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		Usage: config.UsageConfig{
+			Enabled: true,
+			Pricing: map[string]config.ModelPricing{"test-model": {Input: 1, Output: 2}},
+		},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &usageMockProvider{})
+
+	for i := 0; i < 2; i++ {
+		if _, err := al.ProcessDirectWithChannel(context.Background(), "hello", "usage-session", "test", "chat1"); err != nil {
+			t.Fatalf("ProcessDirectWithChannel() error: %v", err)
+		}
+	}
+
+	report, err := usage.Load(usage.Path(cfg.WorkspacePath()))
+	if err != nil {
+		t.Fatalf("usage was not saved: %v", err)
+	}
+	model := report.Models["test-model"]
+	if model.Requests != 2 || model.TotalTokens != 2200 || model.Cost != 2.4 {
+		t.Errorf("model usage = %+v, want 2 requests, 2200 tokens, $2.4", model)
+	}
+	if len(report.Sessions) != 1 {
+		t.Fatalf("sessions = %+v, want one", report.Sessions)
+	}
+	for _, s := range report.Sessions {
+		if s.TotalTokens != 2200 {
+			t.Errorf("session usage = %+v, want 2200 tokens", s)
+		}
+	}
+}
+
+// TestAgentLoop_RecordsTranscripts verifies that each provider request of
+// a turn is recorded with its session and trace, and can be replayed.
+func TestAgentLoop_RecordsTranscripts(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		Observability: config.ObservabilityConfig{
+			Transcripts: config.TranscriptsConfig{Enabled: true},
+		},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &usageMockProvider{})
+
+	if _, err := al.ProcessDirectWithChannel(context.Background(), "hello", "transcript-session", "test", "chat1"); err != nil {
+		t.Fatalf("ProcessDirectWithChannel() error: %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(transcript.Dir(cfg.WorkspacePath()), "*.jsonl"))
+	if len(files) != 1 {
+		t.Fatalf("transcript files = %v, want one", files)
+	}
+	entries, err := transcript.Load(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("entries = %d, want 1", len(entries))
+	}
+	e := entries[0]
+	if e.Session == "" || e.TraceID == "" || e.Response == nil || e.Response.Content != "ok" {
+		t.Errorf("entry = %+v", e)
+	}
+
+	replay, err := transcript.NewReplay(entries, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp, err := replay.Chat(context.Background(), e.Messages, e.Tools, e.Model, nil); err != nil || resp.Content != "ok" {
+		t.Errorf("replayed Chat() = %+v, %v", resp, err)
+	}
+}
+
+func TestAgentLoop_NoTranscriptsWhenEncrypted(t *testing.T) {
+	if err := atrest.SetKey(make([]byte, atrest.KeySize)); err != nil {
+		t.Fatal(err)
+	}
+	defer atrest.SetKey(nil)
+
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		Observability: config.ObservabilityConfig{
+			Transcripts: config.TranscriptsConfig{Enabled: true},
+		},
+	}
+	if al := NewAgentLoop(cfg, bus.NewMessageBus(), &usageMockProvider{}); al.transcripts != nil {
+		t.Error("transcripts are recorded in an encrypted workspace")
+	}
+}
+
+// TestAgentLoop_MockProviderEndToEnd runs a turn against the scripted
+// mock provider: the agent writes a file with a tool, then replies.
+func TestAgentLoop_MockProviderEndToEnd(t *testing.T) {
+	workspace := t.TempDir()
+	fixture := filepath.Join(t.TempDir(), "fixture.json")
+	script := `{"responses": [
+		{"match": "note", "tool_calls": [{"name": "write_file", "arguments": {"path": "note.txt", "content": "buy milk"}}]},
+		{"content": "Saved your note."}
+	]}`
+	if err := os.WriteFile(fixture, []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+	provider, modelID, err := providers.CreateProviderFromConfig(&config.ModelConfig{ModelName: "scripted", Model: "mock/scripted", Fixture: fixture})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:           workspace,
+				RestrictToWorkspace: true,
+				Model:               modelID,
+				MaxTokens:           4096,
+				MaxToolIterations:   10,
+			},
+		},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+
+	reply, err := al.ProcessDirectWithChannel(context.Background(), "Take a note: buy milk", "mock-session", "test", "chat1")
+	if err != nil {
+		t.Fatalf("ProcessDirectWithChannel() error: %v", err)
+	}
+	if reply != "Saved your note." {
+		t.Errorf("reply = %q", reply)
+	}
+	data, err := os.ReadFile(filepath.Join(workspace, "note.txt"))
+	if err != nil || string(data) != "buy milk" {
+		t.Errorf("note.txt = %q, %v; want the file the tool wrote", data, err)
+	}
+	if n := provider.(*providers.MockProvider).Remaining(); n != 0 {
+		t.Errorf("%d scripted responses unused", n)
+	}
+}
+
+// TestHandleCommand_LocalizedReplies verifies that command replies follow the
+// per-user language setting and the language reported by the channel.
+func TestHandleCommand_LocalizedReplies(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         tmpDir,
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		Messages: config.MessagesConfig{
+			UserLanguages: map[string]string{"telegram:42": "zh"},
+		},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &mockProvider{})
+
+	tests := []struct {
+		name string
+		msg  bus.InboundMessage
+		want string
+	}{
+		{
+			name: "default language",
+			msg:  bus.InboundMessage{Channel: "telegram", SenderID: "7", Content: "/show model"},
+			want: "Current model: test-model",
+		},
+		{
+			name: "per-user language with compound sender",
+			msg:  bus.InboundMessage{Channel: "telegram", SenderID: "42|alice", Content: "/show model"},
+			want: "当前模型：test-model",
+		},
+		{
+			name: "channel reported language",
+			msg: bus.InboundMessage{Channel: "telegram", SenderID: "7", Content: "/show nothing",
+				Metadata: map[string]string{"language": "zh-hans"}},
+			want: "未知的 show 目标：nothing",
+		},
+		{
+			name: "unsupported channel language falls back",
+			msg: bus.InboundMessage{Channel: "telegram", SenderID: "7", Content: "/show nothing",
+				Metadata: map[string]string{"language": "fr"}},
+			want: "Unknown show target: nothing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, handled := al.handleCommand(context.Background(), tt.msg)
+			if !handled {
+				t.Fatal("expected command to be handled")
+			}
+			if got != tt.want {
+				t.Errorf("handleCommand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// buttonChannel is a channel that can show two actions per reply.
+type buttonChannel struct{}
+
+func (c *buttonChannel) Name() string                                            { return "buttons" }
+func (c *buttonChannel) Start(ctx context.Context) error                         { return nil }
+func (c *buttonChannel) Stop(ctx context.Context) error                          { return nil }
+func (c *buttonChannel) Send(ctx context.Context, msg bus.OutboundMessage) error { return nil }
+func (c *buttonChannel) IsRunning() bool                                         { return true }
+func (c *buttonChannel) IsAllowed(senderID string) bool                          { return true }
+func (c *buttonChannel) ActionSchema() bus.ActionSchema {
+	return bus.ActionSchema{MaxActions: 2, MaxLabelRunes: 20}
+}
+
+// actionsMockProvider offers two choices, then replies.
+type actionsMockProvider struct {
+	calls int
+}
+
+func (m *actionsMockProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	m.calls++
+	if m.calls > 1 {
+		return &providers.LLMResponse{Content: "Shall I book it?"}, nil
+	}
+	tc := providers.ToolCall{ID: "call_1", Name: "offer_actions", Arguments: map[string]interface{}{
+		"actions": []interface{}{
+			map[string]interface{}{"label": "Yes"},
+			map[string]interface{}{"label": "No", "value": "Don't book it"},
+		},
+	}}
+	return &providers.LLMResponse{ToolCalls: []providers.ToolCall{tc}, FinishReason: "tool_calls"}, nil
+}
+
+func (m *actionsMockProvider) GetDefaultModel() string {
+	return "mock-model"
+}
+
+// TestAgentLoop_ReplyCarriesOfferedActions verifies that choices offered
+// with the offer_actions tool are sent with the reply.
+func TestAgentLoop_ReplyCarriesOfferedActions(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	msgBus := bus.NewMessageBus()
+	al := NewAgentLoop(cfg, msgBus, &actionsMockProvider{})
+	cm, err := channels.NewManager(cfg, msgBus)
+	if err != nil {
+		t.Fatalf("NewManager() error: %v", err)
+	}
+	cm.RegisterChannel("buttons", &buttonChannel{})
+	al.SetChannelManager(cm)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	go al.Run(ctx)
+	defer al.Stop()
+
+	msgBus.PublishInbound(bus.InboundMessage{Channel: "buttons", ChatID: "1", SenderID: "u", Content: "find a table"})
+	out, ok := msgBus.SubscribeOutbound(ctx)
+	if !ok || out.Content != "Shall I book it?" {
+		t.Fatalf("reply = %+v", out)
+	}
+	if len(out.Actions) != 2 || out.Actions[0].Label != "Yes" || out.Actions[1].Value != "Don't book it" {
+		t.Errorf("actions = %+v", out.Actions)
+	}
+}
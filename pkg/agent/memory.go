@@ -10,8 +10,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
+
+	"github.com/sipeed/picoclaw/pkg/atrest"
+	"github.com/sipeed/picoclaw/pkg/session"
+	"github.com/sipeed/picoclaw/pkg/vfs"
 )
 
 // MemoryStore manages persistent memory for the agent.
@@ -24,13 +29,15 @@ type MemoryStore struct {
 }
 
 // NewMemoryStore creates a new MemoryStore with the given workspace path.
-// It ensures the memory directory exists.
+// It ensures the memory directory exists and is encrypted at rest when a
+// workspace key is set.
 func NewMemoryStore(workspace string) *MemoryStore {
 	memoryDir := filepath.Join(workspace, "memory")
 	memoryFile := filepath.Join(memoryDir, "MEMORY.md")
 
 	// Ensure memory directory exists
-	os.MkdirAll(memoryDir, 0755)
+	vfs.MkdirAll(memoryDir, 0755)
+	atrest.Protect(memoryDir)
 
 	return &MemoryStore{
 		workspace:  workspace,
@@ -50,7 +57,7 @@ func (ms *MemoryStore) getTodayFile() string {
 // ReadLongTerm reads the long-term memory (MEMORY.md).
 // Returns empty string if the file doesn't exist.
 func (ms *MemoryStore) ReadLongTerm() string {
-	if data, err := os.ReadFile(ms.memoryFile); err == nil {
+	if data, err := vfs.ReadFile(ms.memoryFile); err == nil {
 		return string(data)
 	}
 	return ""
@@ -58,14 +65,14 @@ func (ms *MemoryStore) ReadLongTerm() string {
 
 // WriteLongTerm writes content to the long-term memory file (MEMORY.md).
 func (ms *MemoryStore) WriteLongTerm(content string) error {
-	return os.WriteFile(ms.memoryFile, []byte(content), 0644)
+	return vfs.WriteFile(ms.memoryFile, []byte(content), 0644)
 }
 
 // ReadToday reads today's daily note.
 // Returns empty string if the file doesn't exist.
 func (ms *MemoryStore) ReadToday() string {
 	todayFile := ms.getTodayFile()
-	if data, err := os.ReadFile(todayFile); err == nil {
+	if data, err := vfs.ReadFile(todayFile); err == nil {
 		return string(data)
 	}
 	return ""
@@ -78,10 +85,10 @@ func (ms *MemoryStore) AppendToday(content string) error {
 
 	// Ensure month directory exists
 	monthDir := filepath.Dir(todayFile)
-	os.MkdirAll(monthDir, 0755)
+	vfs.MkdirAll(monthDir, 0755)
 
 	var existingContent string
-	if data, err := os.ReadFile(todayFile); err == nil {
+	if data, err := vfs.ReadFile(todayFile); err == nil {
 		existingContent = string(data)
 	}
 
@@ -95,7 +102,7 @@ func (ms *MemoryStore) AppendToday(content string) error {
 		newContent = existingContent + "\n" + content
 	}
 
-	return os.WriteFile(todayFile, []byte(newContent), 0644)
+	return vfs.WriteFile(todayFile, []byte(newContent), 0644)
 }
 
 // GetRecentDailyNotes returns daily notes from the last N days.
@@ -110,7 +117,7 @@ func (ms *MemoryStore) GetRecentDailyNotes(days int) string {
 		monthDir := dateStr[:6]            // YYYYMM
 		filePath := filepath.Join(ms.memoryDir, monthDir, dateStr+".md")
 
-		if data, err := os.ReadFile(filePath); err == nil {
+		if data, err := vfs.ReadFile(filePath); err == nil {
 			if !first {
 				sb.WriteString("\n\n---\n\n")
 			}
@@ -149,3 +156,49 @@ func (ms *MemoryStore) GetMemoryContext() string {
 
 	return sb.String()
 }
+
+// Redact replaces the matches of pattern with session.Redacted in the
+// long-term memory and all daily notes. It returns the number of matches
+// replaced.
+func (ms *MemoryStore) Redact(pattern *regexp.Regexp) (int, error) {
+	return redactDir(ms.memoryDir, pattern)
+}
+
+// redactDir redacts the Markdown files in dir and its subdirectories.
+func redactDir(dir string, pattern *regexp.Regexp) (int, error) {
+	entries, err := vfs.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	replaced := 0
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		if e.IsDir() {
+			n, err := redactDir(path, pattern)
+			replaced += n
+			if err != nil {
+				return replaced, err
+			}
+			continue
+		}
+		if !strings.HasSuffix(e.Name(), ".md") {
+			continue
+		}
+		data, err := vfs.ReadFile(path)
+		if err != nil {
+			return replaced, err
+		}
+		n := len(pattern.FindAllIndex(data, -1))
+		if n == 0 {
+			continue
+		}
+		if err := vfs.WriteFile(path, pattern.ReplaceAllLiteral(data, []byte(session.Redacted)), 0644); err != nil {
+			return replaced, err
+		}
+		replaced += n
+	}
+	return replaced, nil
+}
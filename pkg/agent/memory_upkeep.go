@@ -0,0 +1,288 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/sipeed/picoclaw/pkg/crash"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/vfs"
+)
+
+// MemoryHealth describes the memory files of a workspace.
+type MemoryHealth struct {
+	// LongTermBytes is the size of MEMORY.md and LongTermEntries its
+	// number of entries: its lines of text outside headings and code.
+	LongTermBytes   int
+	LongTermEntries int
+	// Duplicates counts the entries, in MEMORY.md and the daily notes,
+	// that repeat an earlier one of the same section.
+	Duplicates int
+	Notes      int
+	NotesBytes int
+	// OldestNote is the date of the oldest daily note, zero if none.
+	OldestNote time.Time
+	// EmptyNotes counts the daily notes with nothing but their header.
+	EmptyNotes int
+}
+
+// String returns the health as a one-line summary.
+func (h MemoryHealth) String() string {
+	s := fmt.Sprintf("MEMORY.md %d entries (%.1f KB), %d daily notes (%.1f KB)",
+		h.LongTermEntries, float64(h.LongTermBytes)/1024, h.Notes, float64(h.NotesBytes)/1024)
+	if !h.OldestNote.IsZero() {
+		s += " since " + h.OldestNote.Format("2006-01-02")
+	}
+	if h.Duplicates > 0 || h.EmptyNotes > 0 {
+		s += fmt.Sprintf("; %d duplicate entries, %d empty notes", h.Duplicates, h.EmptyNotes)
+	}
+	return s
+}
+
+// MemoryCompaction reports what Compact changed.
+type MemoryCompaction struct {
+	// Duplicates is the number of repeated entries removed.
+	Duplicates int
+	// Expired is the number of daily notes deleted for their age.
+	Expired int
+	// Empty is the number of empty daily notes deleted.
+	Empty int
+}
+
+// Changed reports whether Compact changed anything.
+func (c MemoryCompaction) Changed() bool {
+	return c.Duplicates > 0 || c.Expired > 0 || c.Empty > 0
+}
+
+// Health reads the memory files and describes them.
+func (ms *MemoryStore) Health() (MemoryHealth, error) {
+	var h MemoryHealth
+	if data, err := vfs.ReadFile(ms.memoryFile); err == nil {
+		h.LongTermBytes = len(data)
+		_, h.Duplicates, h.LongTermEntries = dedupeEntries(string(data))
+	}
+	err := ms.eachNote(func(path string, date time.Time, data []byte) error {
+		h.Notes++
+		h.NotesBytes += len(data)
+		if h.OldestNote.IsZero() || date.Before(h.OldestNote) {
+			h.OldestNote = date
+		}
+		if emptyNote(data) {
+			h.EmptyNotes++
+		}
+		_, n, _ := dedupeEntries(string(data))
+		h.Duplicates += n
+		return nil
+	})
+	return h, err
+}
+
+// Compact removes repeated entries from MEMORY.md and the daily notes,
+// deletes the daily notes older than retention, if it is positive, and the
+// empty ones, and then the month directories left empty.
+func (ms *MemoryStore) Compact(retention time.Duration) (MemoryCompaction, error) {
+	var c MemoryCompaction
+	if data, err := vfs.ReadFile(ms.memoryFile); err == nil {
+		text, n, _ := dedupeEntries(string(data))
+		if n > 0 {
+			if err := vfs.WriteFile(ms.memoryFile, []byte(text), 0644); err != nil {
+				return c, err
+			}
+			c.Duplicates += n
+		}
+	}
+
+	var cutoff time.Time
+	if retention > 0 {
+		y, m, d := time.Now().Add(-retention).Date()
+		cutoff = time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+	}
+	err := ms.eachNote(func(path string, date time.Time, data []byte) error {
+		switch {
+		case !cutoff.IsZero() && date.Before(cutoff):
+			c.Expired++
+			return vfs.Remove(path)
+		case emptyNote(data):
+			c.Empty++
+			return vfs.Remove(path)
+		}
+		text, n, _ := dedupeEntries(string(data))
+		if n == 0 {
+			return nil
+		}
+		c.Duplicates += n
+		return vfs.WriteFile(path, []byte(text), 0644)
+	})
+	if err != nil {
+		return c, err
+	}
+
+	months, err := vfs.ReadDir(ms.memoryDir)
+	if err != nil {
+		return c, nil
+	}
+	for _, m := range months {
+		if !m.IsDir() {
+			continue
+		}
+		dir := filepath.Join(ms.memoryDir, m.Name())
+		if entries, err := vfs.ReadDir(dir); err == nil && len(entries) == 0 {
+			vfs.Remove(dir)
+		}
+	}
+	return c, nil
+}
+
+// eachNote calls fn with each daily note, memory/YYYYMM/YYYYMMDD.md, its
+// date and its contents.
+func (ms *MemoryStore) eachNote(fn func(path string, date time.Time, data []byte) error) error {
+	months, err := vfs.ReadDir(ms.memoryDir)
+	if err != nil {
+		return nil
+	}
+	for _, m := range months {
+		if !m.IsDir() {
+			continue
+		}
+		dir := filepath.Join(ms.memoryDir, m.Name())
+		notes, err := vfs.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, n := range notes {
+			date, err := time.ParseInLocation("20060102", strings.TrimSuffix(n.Name(), ".md"), time.Local)
+			if n.IsDir() || !strings.HasSuffix(n.Name(), ".md") || err != nil {
+				continue
+			}
+			path := filepath.Join(dir, n.Name())
+			data, err := vfs.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if err := fn(path, date, data); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// emptyNote reports whether a daily note holds nothing but its header.
+func emptyNote(data []byte) bool {
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "# ") {
+			return false
+		}
+	}
+	return true
+}
+
+// dedupeEntries removes the lines of text that repeat an earlier entry of
+// the same section, ignoring case, spacing, list markers and final
+// punctuation. Headings, code blocks and lines without letters or digits
+// are kept. It returns the text, the number of lines removed and the
+// number of entries kept.
+func dedupeEntries(text string) (out string, removed, entries int) {
+	lines := strings.Split(text, "\n")
+	kept := lines[:0:0]
+	seen := map[string]bool{}
+	inCode := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			inCode = !inCode
+		case inCode:
+		case strings.HasPrefix(trimmed, "#"):
+			seen = map[string]bool{}
+		default:
+			if key := entryKey(line); key != "" {
+				if seen[key] {
+					removed++
+					continue
+				}
+				seen[key] = true
+				entries++
+			}
+		}
+		kept = append(kept, line)
+	}
+	if removed == 0 {
+		return text, 0, entries
+	}
+	return strings.Join(kept, "\n"), removed, entries
+}
+
+// entryKey returns what two entries that say the same thing have in
+// common, or "" for a line that is not an entry.
+func entryKey(line string) string {
+	s := strings.TrimSpace(line)
+	s = strings.TrimLeft(s, "-*+ \t")
+	if i := strings.IndexAny(s, ".)"); i > 0 && strings.Trim(s[:i], "0123456789") == "" {
+		s = s[i+1:]
+	}
+	s = strings.ToLower(strings.Join(strings.Fields(s), " "))
+	s = strings.TrimRight(s, ".!;,")
+	if strings.IndexFunc(s, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) < 0 {
+		return ""
+	}
+	return s
+}
+
+// MaintainMemory compacts the memory of every agent, as configured in
+// memory. It returns what was changed in total.
+func (al *AgentLoop) MaintainMemory() MemoryCompaction {
+	var total MemoryCompaction
+	if al.cfg == nil {
+		return total
+	}
+	retention := time.Duration(al.cfg.Memory.NotesRetentionDays) * 24 * time.Hour
+	done := map[string]bool{}
+	for _, id := range al.registry.ListAgentIDs() {
+		agent, ok := al.registry.GetAgent(id)
+		if !ok || done[agent.Workspace] {
+			continue
+		}
+		done[agent.Workspace] = true
+		c, err := NewMemoryStore(agent.Workspace).Compact(retention)
+		if err != nil {
+			logger.WarnCF("agent", "Memory maintenance failed", map[string]interface{}{
+				"agent_id": id,
+				"error":    err.Error(),
+			})
+		}
+		if c.Changed() {
+			logger.InfoCF("agent", "Compacted memory", map[string]interface{}{
+				"agent_id":   id,
+				"duplicates": c.Duplicates,
+				"expired":    c.Expired,
+				"empty":      c.Empty,
+			})
+		}
+		total.Duplicates += c.Duplicates
+		total.Expired += c.Expired
+		total.Empty += c.Empty
+	}
+	return total
+}
+
+// StartMemoryMaintenance runs MaintainMemory now and then daily until ctx
+// is done.
+func (al *AgentLoop) StartMemoryMaintenance(ctx context.Context) {
+	crash.Go(ctx, "agent", func() {
+		ticker := time.NewTicker(24 * time.Hour)
+		defer ticker.Stop()
+		for {
+			al.MaintainMemory()
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
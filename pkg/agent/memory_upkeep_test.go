@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeNote(t *testing.T, ms *MemoryStore, date time.Time, content string) string {
+	t.Helper()
+	day := date.Format("20060102")
+	path := filepath.Join(ms.memoryDir, day[:6], day+".md")
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDedupeEntries(t *testing.T) {
+	text := strings.Join([]string{
+		"## Alice",
+		"- Prefers tea.",
+		"- prefers  TEA",
+		"* Prefers tea!",
+		"1. Prefers tea",
+		"",
+		"## Bob",
+		"- Prefers tea",
+		"---",
+		"---",
+		"```",
+		"x = 1",
+		"x = 1",
+		"```",
+	}, "\n")
+	out, removed, entries := dedupeEntries(text)
+	if removed != 3 {
+		t.Errorf("removed = %d, want 3:\n%s", removed, out)
+	}
+	if entries != 2 {
+		t.Errorf("entries = %d, want 2", entries)
+	}
+	if strings.Count(out, "x = 1") != 2 || strings.Count(out, "---") != 2 || strings.Count(out, "Prefers tea") != 2 {
+		t.Errorf("kept lines that are not repeated entries wrongly:\n%s", out)
+	}
+}
+
+func TestMemoryStore_Compact(t *testing.T) {
+	ms := NewMemoryStore(t.TempDir())
+	ms.WriteLongTerm("# Memory\n\n- Lives in Lyon\n- lives in lyon.\n- Has a cat\n")
+	now := time.Now()
+	old := writeNote(t, ms, now.AddDate(0, 0, -40), "# old\n\nSomething\n")
+	empty := writeNote(t, ms, now.AddDate(0, 0, -2), "# 2 days ago\n\n")
+	recent := writeNote(t, ms, now, "# today\n\n- Called the vet\n- Called the vet\n")
+
+	h, err := ms.Health()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.LongTermEntries != 2 || h.Duplicates != 2 || h.Notes != 3 || h.EmptyNotes != 1 || h.OldestNote.IsZero() {
+		t.Errorf("health = %+v", h)
+	}
+
+	c, err := ms.Compact(30 * 24 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Duplicates != 2 || c.Expired != 1 || c.Empty != 1 {
+		t.Errorf("compaction = %+v", c)
+	}
+	// The old note is over 31 days older than the others, so alone in its
+	// month.
+	for _, path := range []string{old, empty, filepath.Dir(old)} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s was not removed", path)
+		}
+	}
+	if data, _ := os.ReadFile(recent); strings.Count(string(data), "Called the vet") != 1 {
+		t.Errorf("today's note = %q", data)
+	}
+	if lt := ms.ReadLongTerm(); strings.Count(strings.ToLower(lt), "lives in lyon") != 1 || !strings.Contains(lt, "Has a cat") {
+		t.Errorf("MEMORY.md = %q", lt)
+	}
+
+	h, _ = ms.Health()
+	if h.Duplicates != 0 || h.EmptyNotes != 0 || h.Notes != 1 {
+		t.Errorf("health after compaction = %+v", h)
+	}
+	if c, _ := ms.Compact(0); c.Changed() {
+		t.Errorf("second compaction changed %+v", c)
+	}
+}
@@ -0,0 +1,37 @@
+package agent
+
+import (
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+// partialInterval is the minimum time between updates of a streamed reply,
+// so channels that edit a message in place stay within their rate limits.
+const partialInterval = 700 * time.Millisecond
+
+// partialReply publishes a reply to its chat as it is streamed. It is fed
+// from a single stream callback and needs no locking.
+type partialReply struct {
+	bus     *bus.MessageBus
+	channel string
+	chatID  string
+
+	text strings.Builder
+	last time.Time
+}
+
+func (r *partialReply) add(text string) {
+	r.text.WriteString(text)
+	if time.Since(r.last) < partialInterval {
+		return
+	}
+	r.last = time.Now()
+	r.bus.PublishOutbound(bus.OutboundMessage{
+		Channel: r.channel,
+		ChatID:  r.chatID,
+		Content: r.text.String(),
+		Partial: true,
+	})
+}
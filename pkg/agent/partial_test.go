@@ -0,0 +1,37 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+func TestPartialReply_ThrottlesUpdates(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	r := &partialReply{bus: msgBus, channel: "telegram", chatID: "1"}
+
+	r.add("Hel")
+	r.add("lo") // within partialInterval, held back
+	r.last = time.Now().Add(-partialInterval)
+	r.add(" there")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for _, want := range []string{"Hel", "Hello there"} {
+		msg, ok := msgBus.SubscribeOutbound(ctx)
+		if !ok {
+			t.Fatalf("no update for %q", want)
+		}
+		if !msg.Partial || msg.Channel != "telegram" || msg.ChatID != "1" || msg.Content != want {
+			t.Errorf("update = %+v, want partial %q", msg, want)
+		}
+	}
+
+	short, cancelShort := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancelShort()
+	if msg, ok := msgBus.SubscribeOutbound(short); ok {
+		t.Errorf("unexpected update %+v", msg)
+	}
+}
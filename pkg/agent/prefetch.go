@@ -0,0 +1,75 @@
+package agent
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/crash"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+const (
+	// prefetchInterval is how soon a tool is prepared again at the earliest.
+	// Prepared connections stay pooled for longer than this.
+	prefetchInterval = 30 * time.Second
+	prefetchTimeout  = 15 * time.Second
+)
+
+// prefetchTools prepares, in the background, the tools of agent that the
+// keywords in content suggest the model is about to call, so their calls
+// start faster.
+func (al *AgentLoop) prefetchTools(ctx context.Context, agent *AgentInstance, content string) {
+	if al.cfg == nil || !al.cfg.Tools.Prefetch.Enabled {
+		return
+	}
+
+	for _, name := range prefetchCandidates(al.cfg.Tools.Prefetch.Keywords, content) {
+		tool, ok := agent.Tools.Get(name)
+		if !ok {
+			continue
+		}
+		w, ok := tool.(tools.Prewarmer)
+		if !ok {
+			continue
+		}
+
+		key := agent.ID + ":" + name
+		now := time.Now()
+		if last, ok := al.prefetched.Load(key); ok && now.Sub(last.(time.Time)) < prefetchInterval {
+			continue
+		}
+		al.prefetched.Store(key, now)
+
+		crash.Go(ctx, "agent", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), prefetchTimeout)
+			defer cancel()
+			fields := map[string]interface{}{"agent_id": agent.ID, "tool": name}
+			if err := w.Prewarm(ctx); err != nil {
+				fields["error"] = err.Error()
+				logger.DebugCF("agent", "Tool prefetch failed", fields)
+				return
+			}
+			fields["duration_ms"] = time.Since(now).Milliseconds()
+			logger.DebugCF("agent", "Tool prefetched", fields)
+		})
+	}
+}
+
+// prefetchCandidates returns, sorted, the tools with a keyword in content.
+func prefetchCandidates(keywords map[string][]string, content string) []string {
+	content = strings.ToLower(content)
+	var names []string
+	for name, words := range keywords {
+		for _, w := range words {
+			if w != "" && strings.Contains(content, strings.ToLower(w)) {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
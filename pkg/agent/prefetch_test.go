@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// prewarmTool is mockCustomTool with a Prewarm that reports its calls.
+type prewarmTool struct {
+	mockCustomTool
+	calls chan struct{}
+}
+
+func (p *prewarmTool) Prewarm(ctx context.Context) error {
+	p.calls <- struct{}{}
+	return nil
+}
+
+func TestPrefetchTools_PrewarmsMatchingToolOnce(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		Tools: config.ToolsConfig{
+			Prefetch: config.PrefetchConfig{
+				Enabled:  true,
+				Keywords: map[string][]string{"mock_custom": {"Weather"}},
+			},
+		},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &mockProvider{})
+	tool := &prewarmTool{calls: make(chan struct{}, 4)}
+	al.RegisterTool(tool)
+	agent := al.registry.GetDefaultAgent()
+
+	al.prefetchTools(context.Background(), agent, "hello")
+	al.prefetchTools(context.Background(), agent, "what's the weather in Lisbon?")
+	al.prefetchTools(context.Background(), agent, "and the weather tomorrow?") // within prefetchInterval
+
+	select {
+	case <-tool.calls:
+	case <-time.After(5 * time.Second):
+		t.Fatal("tool was not prewarmed")
+	}
+	select {
+	case <-tool.calls:
+		t.Error("tool was prewarmed again within the interval")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestPrefetchCandidates(t *testing.T) {
+	keywords := map[string][]string{
+		"web_search": {"search", "最新"},
+		"web_fetch":  {"https://"},
+		"exec":       {""},
+	}
+	got := prefetchCandidates(keywords, "SEARCH for https://example.com")
+	if len(got) != 2 || got[0] != "web_fetch" || got[1] != "web_search" {
+		t.Errorf("candidates = %v", got)
+	}
+	if got := prefetchCandidates(keywords, "最新的消息"); len(got) != 1 || got[0] != "web_search" {
+		t.Errorf("candidates = %v", got)
+	}
+}
@@ -39,7 +39,7 @@ func NewAgentRegistry(
 		for i := range agentConfigs {
 			ac := &agentConfigs[i]
 			id := routing.NormalizeAgentID(ac.ID)
-			instance := NewAgentInstance(ac, &cfg.Agents.Defaults, cfg, provider)
+			instance := NewAgentInstance(ac, &cfg.Agents.Defaults, cfg, accountProvider(cfg, ac, provider))
 			registry.agents[id] = instance
 			logger.InfoCF("agent", "Registered agent",
 				map[string]interface{}{
@@ -54,6 +54,26 @@ func NewAgentRegistry(
 	return registry
 }
 
+// accountProvider returns the provider for an agent: its own, logged in
+// with the agent's account, if it sets one, or the shared provider.
+func accountProvider(cfg *config.Config, ac *config.AgentConfig, shared providers.LLMProvider) providers.LLMProvider {
+	if ac.Account == "" {
+		return shared
+	}
+	model := resolveAgentModel(ac, &cfg.Agents.Defaults)
+	provider, _, err := providers.CreateAccountProvider(cfg, model, ac.Account)
+	if err != nil {
+		logger.WarnCF("agent", "Could not create provider for agent account, using the shared provider",
+			map[string]interface{}{
+				"agent_id": ac.ID,
+				"account":  ac.Account,
+				"error":    err.Error(),
+			})
+		return shared
+	}
+	return provider
+}
+
 // GetAgent returns the agent instance for a given ID.
 func (r *AgentRegistry) GetAgent(agentID string) (*AgentInstance, bool) {
 	r.mu.RLock()
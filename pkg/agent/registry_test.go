@@ -76,6 +76,29 @@ func TestNewAgentRegistry_ExplicitAgents(t *testing.T) {
 	}
 }
 
+func TestNewAgentRegistry_AccountProvider(t *testing.T) {
+	cfg := testCfg([]config.AgentConfig{
+		{ID: "personal", Default: true},
+		{ID: "work", Account: "work"},
+		{ID: "broken", Account: "work", Model: &config.AgentModelConfig{Primary: "missing"}},
+	})
+	cfg.ModelList = []config.ModelConfig{
+		{ModelName: "gpt-4", Model: "openai/gpt-4", APIKey: "sk-test"},
+	}
+	shared := &mockRegistryProvider{}
+	registry := NewAgentRegistry(cfg, shared)
+
+	if personal, _ := registry.GetAgent("personal"); personal.Provider != shared {
+		t.Errorf("agent without account got provider %T, want the shared one", personal.Provider)
+	}
+	if work, _ := registry.GetAgent("work"); work.Provider == shared {
+		t.Error("agent with account uses the shared provider")
+	}
+	if broken, _ := registry.GetAgent("broken"); broken.Provider != shared {
+		t.Errorf("agent whose account provider fails got %T, want the shared one", broken.Provider)
+	}
+}
+
 func TestAgentRegistry_GetAgent_Normalize(t *testing.T) {
 	cfg := testCfg([]config.AgentConfig{
 		{ID: "my-agent", Default: true},
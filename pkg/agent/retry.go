@@ -0,0 +1,311 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/constants"
+	"github.com/sipeed/picoclaw/pkg/crash"
+	"github.com/sipeed/picoclaw/pkg/i18n"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// retryMetadataKey marks inbound messages re-published by the retry queue.
+// The value is "probe" for the message testing whether the provider is
+// back, and "release" for the rest once it is. parkedAtMetadataKey keeps
+// the time the turn was first parked across retries.
+const (
+	retryMetadataKey    = "picoclaw_retry"
+	parkedAtMetadataKey = "picoclaw_parked_at"
+)
+
+// maxProbeInterval caps the backoff between probes.
+const maxProbeInterval = 10 * time.Minute
+
+// retryQueue parks turns that failed because the provider is unavailable.
+// The oldest parked turn is retried with backoff; once it succeeds the
+// provider is considered recovered and the rest are released.
+type retryQueue struct {
+	maxQueued int
+	maxAge    time.Duration
+	interval  time.Duration
+
+	mu      sync.Mutex
+	parked  []parkedTurn
+	wake    chan struct{}
+	results chan bool
+}
+
+type parkedTurn struct {
+	msg      bus.InboundMessage
+	parkedAt time.Time
+	err      error
+}
+
+func newRetryQueue(cfg config.RetryQueueConfig) *retryQueue {
+	q := &retryQueue{
+		maxQueued: cfg.MaxQueued,
+		maxAge:    time.Duration(cfg.MaxAgeMinutes) * time.Minute,
+		interval:  time.Duration(cfg.ProbeIntervalSeconds) * time.Second,
+		wake:      make(chan struct{}, 1),
+		results:   make(chan bool, 1),
+	}
+	if q.interval <= 0 {
+		q.interval = 30 * time.Second
+	}
+	return q
+}
+
+// isProviderOutage reports whether err means the provider could not be
+// reached or is overloaded, as opposed to rejecting the request.
+func isProviderOutage(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var exhausted *providers.FallbackExhaustedError
+	if errors.As(err, &exhausted) {
+		for _, a := range exhausted.Attempts {
+			if !a.Skipped && !isOutageReason(a.Reason) {
+				return false
+			}
+		}
+		return len(exhausted.Attempts) > 0
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	if fe := providers.ClassifyError(err, "", ""); fe != nil {
+		return isOutageReason(fe.Reason)
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "connection refused") ||
+		strings.Contains(msg, "connection reset") ||
+		strings.Contains(msg, "no such host")
+}
+
+func isOutageReason(reason providers.FailoverReason) bool {
+	switch reason {
+	case providers.FailoverTimeout, providers.FailoverRateLimit, providers.FailoverOverloaded:
+		return true
+	}
+	return false
+}
+
+// shouldPark reports whether a turn from channel that failed with err
+// goes to the retry queue. Internal channels (CLI, cron) are answered
+// directly.
+func (al *AgentLoop) shouldPark(channel string, err error) bool {
+	return al.retry != nil && !constants.IsInternalChannel(channel) && isProviderOutage(err)
+}
+
+// parkableError is returned by a turn that failed during an outage. It
+// carries how to undo the turn's session changes, which handleTurnResult
+// applies only if the turn is actually parked.
+type parkableError struct {
+	err      error
+	rollback func()
+}
+
+func (e *parkableError) Error() string { return e.err.Error() }
+func (e *parkableError) Unwrap() error { return e.err }
+
+// park queues msg for retry and reports whether it was queued. Turns are
+// kept in the order they were first parked, so messages that are
+// themselves retries go back in their place, ahead of newer ones.
+func (q *retryQueue) park(msg bus.InboundMessage, err error) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	turn := parkedTurn{msg: msg, parkedAt: time.Now(), err: err}
+	if _, retried := msg.Metadata[retryMetadataKey]; retried {
+		if at, ok := msg.Metadata[parkedAtMetadataKey]; ok {
+			if t, perr := time.Parse(time.RFC3339Nano, at); perr == nil {
+				turn.parkedAt = t
+			}
+		}
+	} else if q.maxQueued > 0 && len(q.parked) >= q.maxQueued {
+		return false
+	}
+	i := sort.Search(len(q.parked), func(i int) bool {
+		return q.parked[i].parkedAt.After(turn.parkedAt)
+	})
+	q.parked = slices.Insert(q.parked, i, turn)
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// report passes the outcome of a probe to the retry worker.
+func (q *retryQueue) report(ok bool) {
+	select {
+	case q.results <- ok:
+	default:
+	}
+}
+
+// peek returns the oldest parked turn without removing it.
+func (q *retryQueue) peek() (parkedTurn, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.parked) == 0 {
+		return parkedTurn{}, false
+	}
+	return q.parked[0], true
+}
+
+// pop removes and returns the oldest parked turn.
+func (q *retryQueue) pop() (parkedTurn, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.parked) == 0 {
+		return parkedTurn{}, false
+	}
+	turn := q.parked[0]
+	q.parked = q.parked[1:]
+	return turn, true
+}
+
+// handleTurnResult is called by the loop for every processed message. It
+// parks turns that failed because of an outage and reports probe results.
+// It returns the reply to send, which replaces the error reply for parked
+// turns.
+func (al *AgentLoop) handleTurnResult(msg bus.InboundMessage, err error) (reply string, parked bool) {
+	if al.retry == nil {
+		return "", false
+	}
+	kind := msg.Metadata[retryMetadataKey]
+	var pe *parkableError
+	if errors.As(err, &pe) {
+		err = pe.err
+	}
+	parked = err != nil && al.shouldPark(msg.Channel, err) && al.retry.park(msg, err)
+	if parked && pe != nil {
+		pe.rollback()
+	}
+	if kind == "probe" {
+		// Report after parking so a failed probe is back at the front
+		// before the worker looks at the queue again.
+		al.retry.report(!parked)
+	}
+	if !parked {
+		return "", false
+	}
+	logger.WarnCF("agent", "Provider unavailable, turn parked for retry", map[string]interface{}{
+		"channel": msg.Channel,
+		"chat_id": msg.ChatID,
+		"error":   err.Error(),
+	})
+	if kind != "" {
+		// The user was already told their answer is delayed.
+		return "", true
+	}
+	return al.catalog.Render(al.languageFor(msg), i18n.Offline, nil), true
+}
+
+// runRetryQueue probes the provider with the oldest parked turn, backing
+// off while it keeps failing, and releases the remaining turns once a
+// probe succeeds.
+func (al *AgentLoop) runRetryQueue(ctx context.Context) {
+	q := al.retry
+	backoff := q.interval
+	for {
+		turn, ok := q.peek()
+		if !ok {
+			backoff = q.interval
+			select {
+			case <-ctx.Done():
+				return
+			case <-q.wake:
+				continue
+			}
+		}
+		if q.maxAge > 0 && time.Since(turn.parkedAt) > q.maxAge {
+			q.pop()
+			al.expireTurn(turn)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if turn, ok = q.pop(); !ok {
+			continue
+		}
+
+		al.bus.PublishInbound(retryMessage(turn, "probe"))
+		var recovered bool
+		select {
+		case <-ctx.Done():
+			return
+		case recovered = <-q.results:
+		}
+		if !recovered {
+			backoff = min(backoff*2, maxProbeInterval)
+			continue
+		}
+
+		logger.InfoCF("agent", "Provider recovered, releasing parked turns", nil)
+		backoff = q.interval
+		for {
+			next, ok := q.pop()
+			if !ok {
+				break
+			}
+			if q.maxAge > 0 && time.Since(next.parkedAt) > q.maxAge {
+				al.expireTurn(next)
+				continue
+			}
+			al.bus.PublishInbound(retryMessage(next, "release"))
+		}
+	}
+}
+
+// retryMessage copies the parked message, marking it as a retry.
+func retryMessage(turn parkedTurn, kind string) bus.InboundMessage {
+	msg := turn.msg
+	msg.Metadata = make(map[string]string, len(turn.msg.Metadata)+2)
+	for k, v := range turn.msg.Metadata {
+		msg.Metadata[k] = v
+	}
+	msg.Metadata[retryMetadataKey] = kind
+	msg.Metadata[parkedAtMetadataKey] = turn.parkedAt.Format(time.RFC3339Nano)
+	return msg
+}
+
+// expireTurn gives up on a turn parked for longer than the maximum age.
+func (al *AgentLoop) expireTurn(turn parkedTurn) {
+	logger.WarnCF("agent", "Dropping parked turn after provider outage", map[string]interface{}{
+		"channel": turn.msg.Channel,
+		"chat_id": turn.msg.ChatID,
+		"parked":  time.Since(turn.parkedAt).Round(time.Second).String(),
+	})
+	al.bus.PublishOutbound(bus.OutboundMessage{
+		Channel: turn.msg.Channel,
+		ChatID:  turn.msg.ChatID,
+		Content: al.catalog.Render(al.languageFor(turn.msg), i18n.ProcessingError, map[string]interface{}{"Error": turn.err}),
+	})
+}
+
+// startRetryQueue runs the retry worker until ctx is done.
+func (al *AgentLoop) startRetryQueue(ctx context.Context) {
+	crash.Go(ctx, "agent", func() { al.runRetryQueue(ctx) })
+}
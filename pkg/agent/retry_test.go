@@ -0,0 +1,193 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/i18n"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// outageProvider fails with a 503 until up is set.
+type outageProvider struct {
+	mu    sync.Mutex
+	up    bool
+	calls int
+}
+
+func (p *outageProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	if !p.up {
+		return nil, errors.New("API request failed:\n  Status: 503\n  Body:   service unavailable")
+	}
+	return &providers.LLMResponse{Content: fmt.Sprintf("answer to: %s", messages[len(messages)-1].Content)}, nil
+}
+
+func (p *outageProvider) GetDefaultModel() string { return "mock-model" }
+
+func (p *outageProvider) setUp() {
+	p.mu.Lock()
+	p.up = true
+	p.mu.Unlock()
+}
+
+func TestRetryQueue_AnswersAfterRecovery(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		RetryQueue: config.RetryQueueConfig{Enabled: true, MaxQueued: 5, MaxAgeMinutes: 5},
+	}
+	provider := &outageProvider{}
+	msgBus := bus.NewMessageBus()
+	al := NewAgentLoop(cfg, msgBus, provider)
+	al.retry.interval = 20 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	go al.Run(ctx)
+
+	msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "1", SenderID: "u", Content: "first"})
+	msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "1", SenderID: "u", Content: "second"})
+	for i := 0; i < 2; i++ {
+		out, ok := msgBus.SubscribeOutbound(ctx)
+		if !ok || out.Content != al.catalog.Render("", i18n.Offline, nil) {
+			t.Fatalf("reply %d = %q, want the offline notice", i, out.Content)
+		}
+	}
+
+	// Let a few probes fail before the provider comes back.
+	time.Sleep(100 * time.Millisecond)
+	provider.setUp()
+
+	var answers []string
+	for len(answers) < 2 {
+		out, ok := msgBus.SubscribeOutbound(ctx)
+		if !ok {
+			t.Fatalf("timed out waiting for delayed answers, got %v", answers)
+		}
+		answers = append(answers, out.Content)
+	}
+	if answers[0] != "answer to: first" || answers[1] != "answer to: second" {
+		t.Errorf("answers = %v", answers)
+	}
+
+	// The failed attempts left no trace in the session.
+	agent := al.registry.GetDefaultAgent()
+	_, sessionKey, _ := al.routeMessage(bus.InboundMessage{Channel: "telegram", ChatID: "1", SenderID: "u"})
+	if history := agent.Sessions.GetHistory(sessionKey); len(history) != 4 {
+		t.Errorf("session has %d messages, want 4", len(history))
+	}
+}
+
+func TestRetryQueue_FullQueueKeepsTurn(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		RetryQueue: config.RetryQueueConfig{Enabled: true, MaxQueued: 1, MaxAgeMinutes: 5, ProbeIntervalSeconds: 3600},
+	}
+	msgBus := bus.NewMessageBus()
+	al := NewAgentLoop(cfg, msgBus, &outageProvider{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	go al.Run(ctx)
+
+	msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "1", SenderID: "u", Content: "first"})
+	msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "2", SenderID: "u", Content: "second"})
+	if out, ok := msgBus.SubscribeOutbound(ctx); !ok || out.Content != al.catalog.Render("", i18n.Offline, nil) {
+		t.Fatalf("first reply = %q, want the offline notice", out.Content)
+	}
+	if out, ok := msgBus.SubscribeOutbound(ctx); !ok || out.ChatID != "2" || out.Content == al.catalog.Render("", i18n.Offline, nil) {
+		t.Fatalf("second reply = %+v, want the processing error", out)
+	}
+
+	// Both chats share the main session: the parked turn was dropped from
+	// it, the one that did not fit in the queue stays.
+	agent := al.registry.GetDefaultAgent()
+	_, sessionKey, _ := al.routeMessage(bus.InboundMessage{Channel: "telegram", ChatID: "2", SenderID: "u"})
+	if history := agent.Sessions.GetHistory(sessionKey); len(history) != 1 || history[0].Content != "second" {
+		t.Errorf("session = %+v, want only the refused turn's message", history)
+	}
+}
+
+func TestIsProviderOutage(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{errors.New("Status: 503 service unavailable"), true},
+		{errors.New("rate limit exceeded"), true},
+		{fmt.Errorf("wrapped: %w", context.DeadlineExceeded), true},
+		{errors.New("dial tcp: connection refused"), true},
+		{errors.New("Status: 401 invalid api key"), false},
+		{errors.New("Status: 400 bad request"), false},
+		{context.Canceled, false},
+		{&providers.FallbackExhaustedError{Attempts: []providers.FallbackAttempt{
+			{Reason: providers.FailoverTimeout}, {Skipped: true},
+		}}, true},
+		{&providers.FallbackExhaustedError{Attempts: []providers.FallbackAttempt{
+			{Reason: providers.FailoverTimeout}, {Reason: providers.FailoverAuth},
+		}}, false},
+	}
+	for _, tt := range tests {
+		if got := isProviderOutage(tt.err); got != tt.want {
+			t.Errorf("isProviderOutage(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRetryQueue_ParkKeepsOrder(t *testing.T) {
+	q := newRetryQueue(config.RetryQueueConfig{MaxQueued: 1})
+	start := time.Now().Add(-time.Minute)
+	retried := func(text string, at time.Time) bus.InboundMessage {
+		return retryMessage(parkedTurn{msg: bus.InboundMessage{Content: text}, parkedAt: at}, "release")
+	}
+
+	q.park(bus.InboundMessage{Content: "new"}, nil)
+	<-q.wake
+	// Released turns that fail again go back in the order they were first
+	// parked, past the limit, and wake the worker.
+	q.park(retried("second", start.Add(time.Second)), nil)
+	q.park(retried("first", start), nil)
+	select {
+	case <-q.wake:
+	default:
+		t.Error("park() of a retried turn did not wake the worker")
+	}
+	if q.park(bus.InboundMessage{Content: "over"}, nil) {
+		t.Error("park() queued a new turn past the limit")
+	}
+
+	var got []string
+	for {
+		turn, ok := q.pop()
+		if !ok {
+			break
+		}
+		got = append(got, turn.msg.Content)
+	}
+	if want := []string{"first", "second", "new"}; !slices.Equal(got, want) {
+		t.Errorf("parked turns = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,299 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+// Summarizer strategies, as set in SummarizerConfig.Strategy.
+const (
+	SummarizeModel      = "model"
+	SummarizeMapReduce  = "map_reduce"
+	SummarizeExtractive = "extractive"
+)
+
+const (
+	defaultSummaryTokens = 1024
+	// extractRunes is how much of each message an extractive summary keeps
+	// at most.
+	extractRunes = 200
+)
+
+// Summarizer condenses the old messages of a session into a summary that
+// replaces them in the context of later turns.
+type Summarizer interface {
+	// Summarize returns a summary of messages that carries on existing,
+	// the summary of the messages before them, which may be empty.
+	Summarize(ctx context.Context, messages []providers.Message, existing string) (string, error)
+}
+
+// NewSummarizer returns the summarizer cfg selects. Summaries made with a
+// model use model unless cfg names one, and each response is passed to
+// onResponse, which may be nil, to account for its usage.
+func NewSummarizer(
+	cfg config.SummarizerConfig,
+	provider providers.LLMProvider,
+	model string,
+	contextWindow int,
+	onResponse func(model string, resp *providers.LLMResponse),
+) (Summarizer, error) {
+	strategy := cfg.Strategy
+	if strategy == "" {
+		strategy = SummarizeModel
+	}
+	maxTokens := cfg.MaxTokens[strategy]
+	if maxTokens <= 0 {
+		maxTokens = defaultSummaryTokens
+	}
+	if cfg.Model != "" {
+		model = cfg.Model
+	}
+	llm := llmSummarizer{provider: provider, model: model, maxTokens: maxTokens, onResponse: onResponse}
+
+	switch strategy {
+	case SummarizeModel:
+		return &modelSummarizer{llm}, nil
+	case SummarizeMapReduce:
+		chunkTokens := cfg.ChunkTokens
+		if chunkTokens <= 0 {
+			chunkTokens = max(contextWindow/2, maxTokens)
+		}
+		return &mapReduceSummarizer{llmSummarizer: llm, chunkTokens: chunkTokens}, nil
+	case SummarizeExtractive:
+		return &extractiveSummarizer{maxTokens: maxTokens}, nil
+	}
+	return nil, fmt.Errorf("unknown summarizer strategy %q", strategy)
+}
+
+// llmSummarizer makes the model requests of the strategies that use one.
+type llmSummarizer struct {
+	provider   providers.LLMProvider
+	model      string
+	maxTokens  int
+	onResponse func(model string, resp *providers.LLMResponse)
+}
+
+func (s *llmSummarizer) chat(ctx context.Context, prompt string) (string, error) {
+	resp, err := s.provider.Chat(ctx, []providers.Message{{Role: "user", Content: prompt}}, nil, s.model, map[string]interface{}{
+		"max_tokens":  s.maxTokens,
+		"temperature": 0.3,
+	})
+	if s.onResponse != nil {
+		s.onResponse(s.model, resp)
+	}
+	if err != nil {
+		return "", err
+	}
+	return resp.Content, nil
+}
+
+// summarizeBatch summarizes a batch of messages.
+func (s *llmSummarizer) summarizeBatch(ctx context.Context, batch []providers.Message, existing string) (string, error) {
+	var sb strings.Builder
+	sb.WriteString("Provide a concise summary of this conversation segment, preserving core context and key points.\n")
+	if existing != "" {
+		sb.WriteString("Existing context: ")
+		sb.WriteString(existing)
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\nCONVERSATION:\n")
+	for _, m := range batch {
+		fmt.Fprintf(&sb, "%s: %s\n", m.Role, m.Content)
+	}
+	return s.chat(ctx, sb.String())
+}
+
+// merge combines summaries of consecutive parts of a conversation.
+func (s *llmSummarizer) merge(ctx context.Context, summaries []string) (string, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Merge these %d conversation summaries, oldest first, into one cohesive summary:\n", len(summaries))
+	for i, summary := range summaries {
+		fmt.Fprintf(&sb, "\n%d: %s\n", i+1, summary)
+	}
+	return s.chat(ctx, sb.String())
+}
+
+// modelSummarizer summarizes with one request, or with two and a merge
+// for more than ten messages.
+type modelSummarizer struct {
+	llmSummarizer
+}
+
+func (s *modelSummarizer) Summarize(ctx context.Context, messages []providers.Message, existing string) (string, error) {
+	if len(messages) <= 10 {
+		return s.summarizeBatch(ctx, messages, existing)
+	}
+
+	mid := len(messages) / 2
+	s1, _ := s.summarizeBatch(ctx, messages[:mid], "")
+	s2, _ := s.summarizeBatch(ctx, messages[mid:], "")
+	merged, err := s.merge(ctx, []string{s1, s2})
+	if err != nil {
+		return strings.TrimSpace(s1 + " " + s2), nil
+	}
+	return merged, nil
+}
+
+// mapReduceSummarizer summarizes chunks of at most chunkTokens each, then
+// merges the summaries, as many at a time as fit in a chunk, until one is
+// left.
+type mapReduceSummarizer struct {
+	llmSummarizer
+	chunkTokens int
+}
+
+func (s *mapReduceSummarizer) Summarize(ctx context.Context, messages []providers.Message, existing string) (string, error) {
+	chunks := chunkMessages(messages, s.chunkTokens)
+	if len(chunks) == 1 {
+		return s.summarizeBatch(ctx, chunks[0], existing)
+	}
+
+	summaries := make([]string, 0, len(chunks)+1)
+	if existing != "" {
+		summaries = append(summaries, existing)
+	}
+	for _, chunk := range chunks {
+		summary, err := s.summarizeBatch(ctx, chunk, "")
+		if err != nil {
+			return "", err
+		}
+		summaries = append(summaries, summary)
+	}
+
+	for len(summaries) > 1 {
+		var merged []string
+		for _, group := range groupSummaries(summaries, s.chunkTokens) {
+			if len(group) == 1 {
+				merged = append(merged, group[0])
+				continue
+			}
+			summary, err := s.merge(ctx, group)
+			if err != nil {
+				return "", err
+			}
+			merged = append(merged, summary)
+		}
+		summaries = merged
+	}
+	return summaries[0], nil
+}
+
+// chunkMessages splits messages into consecutive chunks of at most
+// maxTokens each. A message larger than that gets a chunk of its own.
+func chunkMessages(messages []providers.Message, maxTokens int) [][]providers.Message {
+	var chunks [][]providers.Message
+	var chunk []providers.Message
+	tokens := 0
+	for _, m := range messages {
+		t := estimateTextTokens(m.Content)
+		if len(chunk) > 0 && tokens+t > maxTokens {
+			chunks = append(chunks, chunk)
+			chunk, tokens = nil, 0
+		}
+		chunk = append(chunk, m)
+		tokens += t
+	}
+	if len(chunk) > 0 || len(chunks) == 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// groupSummaries groups consecutive summaries of at most maxTokens in
+// total, and at least two per group so that merging them always shortens
+// the list.
+func groupSummaries(summaries []string, maxTokens int) [][]string {
+	var groups [][]string
+	var group []string
+	tokens := 0
+	for _, s := range summaries {
+		t := estimateTextTokens(s)
+		if len(group) >= 2 && tokens+t > maxTokens {
+			groups = append(groups, group)
+			group, tokens = nil, 0
+		}
+		group = append(group, s)
+		tokens += t
+	}
+	if len(group) == 1 && len(groups) > 0 {
+		groups[len(groups)-1] = append(groups[len(groups)-1], group[0])
+	} else if len(group) > 0 {
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// extractiveSummarizer summarizes without a model: it keeps the first
+// sentence of each message, the most recent ones first when the summary
+// would exceed maxTokens, and at most half of the existing summary.
+type extractiveSummarizer struct {
+	maxTokens int
+}
+
+func (s *extractiveSummarizer) Summarize(ctx context.Context, messages []providers.Message, existing string) (string, error) {
+	budget := s.maxTokens
+	if existing != "" {
+		existing = truncateTokens(existing, budget/2)
+		budget -= estimateTextTokens(existing)
+	}
+
+	var lines []string
+	for i := len(messages) - 1; i >= 0; i-- {
+		sentence := firstSentence(messages[i].Content)
+		if sentence == "" {
+			continue
+		}
+		line := messages[i].Role + ": " + sentence
+		t := estimateTextTokens(line)
+		if t > budget {
+			break
+		}
+		budget -= t
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 && existing == "" {
+		return "", nil
+	}
+
+	var sb strings.Builder
+	if existing != "" {
+		sb.WriteString(existing)
+		sb.WriteString("\n")
+	}
+	for i := len(lines) - 1; i >= 0; i-- {
+		sb.WriteString(lines[i])
+		sb.WriteString("\n")
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// firstSentence returns the first sentence of text on a single line,
+// shortened to extractRunes.
+func firstSentence(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	for i, r := range text {
+		if strings.ContainsRune(".!?。！？", r) {
+			end := i + utf8.RuneLen(r)
+			if end == len(text) || unicode.IsSpace(rune(text[end])) || r > unicode.MaxASCII {
+				text = text[:end]
+				break
+			}
+		}
+	}
+	return utils.Truncate(text, extractRunes)
+}
+
+// truncateTokens shortens text to about maxTokens.
+func truncateTokens(text string, maxTokens int) string {
+	if estimateTextTokens(text) <= maxTokens {
+		return text
+	}
+	return utils.Truncate(text, maxTokens*5/2)
+}
@@ -0,0 +1,172 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// summaryProvider answers every request with a short summary and records
+// the prompts it was given.
+type summaryProvider struct {
+	mu      sync.Mutex
+	prompts []string
+}
+
+func (p *summaryProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prompts = append(p.prompts, messages[0].Content)
+	return &providers.LLMResponse{Content: "summary"}, nil
+}
+
+func (p *summaryProvider) GetDefaultModel() string {
+	return "mock-model"
+}
+
+func TestNewSummarizer_Strategies(t *testing.T) {
+	for _, tt := range []struct {
+		strategy string
+		want     Summarizer
+	}{
+		{"", &modelSummarizer{}},
+		{SummarizeModel, &modelSummarizer{}},
+		{SummarizeMapReduce, &mapReduceSummarizer{}},
+		{SummarizeExtractive, &extractiveSummarizer{}},
+	} {
+		s, err := NewSummarizer(config.SummarizerConfig{Strategy: tt.strategy}, &summaryProvider{}, "m", 8192, nil)
+		if err != nil {
+			t.Fatalf("NewSummarizer(%q) error: %v", tt.strategy, err)
+		}
+		if got, want := typeName(s), typeName(tt.want); got != want {
+			t.Errorf("NewSummarizer(%q) = %s, want %s", tt.strategy, got, want)
+		}
+	}
+	if _, err := NewSummarizer(config.SummarizerConfig{Strategy: "magic"}, nil, "m", 8192, nil); err == nil {
+		t.Error("NewSummarizer() accepted an unknown strategy")
+	}
+}
+
+func typeName(s Summarizer) string {
+	switch s.(type) {
+	case *modelSummarizer:
+		return "model"
+	case *mapReduceSummarizer:
+		return "map_reduce"
+	case *extractiveSummarizer:
+		return "extractive"
+	}
+	return "unknown"
+}
+
+func TestModelSummarizer_UsesConfiguredModelAndBudget(t *testing.T) {
+	var models []string
+	provider := &optsProvider{}
+	s, _ := NewSummarizer(config.SummarizerConfig{
+		Model:     "cheap",
+		MaxTokens: map[string]int{SummarizeModel: 300},
+	}, provider, "main", 8192, func(model string, resp *providers.LLMResponse) {
+		models = append(models, model)
+	})
+
+	if _, err := s.Summarize(context.Background(), []providers.Message{{Role: "user", Content: "hi"}}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if provider.model != "cheap" || provider.maxTokens != 300 {
+		t.Errorf("request used model %q with max_tokens %v", provider.model, provider.maxTokens)
+	}
+	if len(models) != 1 || models[0] != "cheap" {
+		t.Errorf("usage recorded for %v", models)
+	}
+}
+
+type optsProvider struct {
+	model     string
+	maxTokens interface{}
+}
+
+func (p *optsProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	p.model, p.maxTokens = model, opts["max_tokens"]
+	return &providers.LLMResponse{Content: "summary"}, nil
+}
+
+func (p *optsProvider) GetDefaultModel() string {
+	return "mock-model"
+}
+
+func TestMapReduceSummarizer_ChunksAndMerges(t *testing.T) {
+	provider := &summaryProvider{}
+	s, _ := NewSummarizer(config.SummarizerConfig{
+		Strategy:    SummarizeMapReduce,
+		ChunkTokens: 100,
+	}, provider, "m", 8192, nil)
+
+	// Each message is about 80 tokens, so each gets a chunk of its own.
+	var messages []providers.Message
+	for i := 0; i < 5; i++ {
+		messages = append(messages, providers.Message{Role: "user", Content: strings.Repeat("word ", 40)})
+	}
+	got, err := s.Summarize(context.Background(), messages, "earlier")
+	if err != nil || got != "summary" {
+		t.Fatalf("Summarize() = %q, %v", got, err)
+	}
+
+	maps, merges := 0, 0
+	for _, p := range provider.prompts {
+		if strings.HasPrefix(p, "Merge these") {
+			merges++
+			if merges == 1 && !strings.Contains(p, "1: earlier") {
+				t.Errorf("first merge does not start with the existing summary:\n%s", p)
+			}
+		} else {
+			maps++
+		}
+	}
+	if maps != 5 || merges != 1 {
+		t.Errorf("%d map and %d merge requests, want 5 and 1", maps, merges)
+	}
+}
+
+func TestExtractiveSummarizer(t *testing.T) {
+	s := &extractiveSummarizer{maxTokens: 30}
+	messages := []providers.Message{
+		{Role: "user", Content: "Plan a trip to Lisbon. We have three days."},
+		{Role: "assistant", Content: "Sure!  Day one:\nthe old town. Day two: Belém."},
+		{Role: "user", Content: "Book the hotel near the river please"},
+	}
+
+	got, err := s.Summarize(context.Background(), messages, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "assistant: Sure!\nuser: Book the hotel near the river please"
+	if got != want {
+		t.Errorf("Summarize() = %q, want %q", got, want)
+	}
+
+	got, _ = s.Summarize(context.Background(), messages[2:], strings.Repeat("x", 100))
+	if !strings.HasPrefix(got, strings.Repeat("x", 34)+"...\nuser: Book") {
+		t.Errorf("existing summary was not shortened to half the budget: %q", got)
+	}
+}
+
+func TestChunkMessages(t *testing.T) {
+	messages := []providers.Message{
+		{Content: strings.Repeat("a", 100)}, // 40 tokens
+		{Content: strings.Repeat("b", 100)},
+		{Content: strings.Repeat("c", 500)}, // 200 tokens, over the limit
+		{Content: strings.Repeat("d", 100)},
+	}
+	chunks := chunkMessages(messages, 100)
+	var sizes []int
+	for _, c := range chunks {
+		sizes = append(sizes, len(c))
+	}
+	if len(sizes) != 3 || sizes[0] != 2 || sizes[1] != 1 || sizes[2] != 1 {
+		t.Errorf("chunk sizes = %v, want [2 1 1]", sizes)
+	}
+}
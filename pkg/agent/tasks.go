@@ -0,0 +1,147 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/tools"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+// TaskInfo describes a background task for the /tasks endpoint.
+type TaskInfo struct {
+	ID         string    `json:"id"`
+	AgentID    string    `json:"agent_id"`
+	Label      string    `json:"label,omitempty"`
+	Task       string    `json:"task"`
+	Status     string    `json:"status"`
+	Progress   string    `json:"progress,omitempty"`
+	Iterations int       `json:"iterations"`
+	Channel    string    `json:"channel"`
+	ChatID     string    `json:"chat_id"`
+	Created    time.Time `json:"created"`
+	Updated    time.Time `json:"updated"`
+	Result     string    `json:"result,omitempty"`
+}
+
+// Tasks returns the background tasks of all agents, oldest first per agent.
+func (al *AgentLoop) Tasks() []TaskInfo {
+	var infos []TaskInfo
+	for _, id := range al.registry.ListAgentIDs() {
+		agent, ok := al.registry.GetAgent(id)
+		if !ok || agent.Tasks == nil {
+			continue
+		}
+		for _, t := range agent.Tasks.ListTasks() {
+			infos = append(infos, taskInfo(id, t))
+		}
+	}
+	return infos
+}
+
+// CancelTask cancels a queued or running background task.
+func (al *AgentLoop) CancelTask(taskID string) error {
+	manager, _, ok := al.findTask(taskID)
+	if !ok {
+		return fmt.Errorf("no task %s", taskID)
+	}
+	return manager.Cancel(taskID)
+}
+
+func (al *AgentLoop) findTask(taskID string) (*tools.SubagentManager, *tools.SubagentTask, bool) {
+	for _, id := range al.registry.ListAgentIDs() {
+		agent, ok := al.registry.GetAgent(id)
+		if !ok || agent.Tasks == nil {
+			continue
+		}
+		if t, ok := agent.Tasks.GetTask(taskID); ok {
+			return agent.Tasks, t, true
+		}
+	}
+	return nil, nil, false
+}
+
+func taskInfo(agentID string, t *tools.SubagentTask) TaskInfo {
+	return TaskInfo{
+		ID:         t.ID,
+		AgentID:    agentID,
+		Label:      t.Label,
+		Task:       t.Task,
+		Status:     t.Status,
+		Progress:   t.Progress,
+		Iterations: t.Iterations,
+		Channel:    t.OriginChannel,
+		ChatID:     t.OriginChatID,
+		Created:    time.UnixMilli(t.Created),
+		Updated:    time.UnixMilli(t.Updated),
+		Result:     t.Result,
+	}
+}
+
+// TasksHandler serves the background tasks as JSON. POST with an id query
+// parameter cancels that task.
+func (al *AgentLoop) TasksHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			infos := al.Tasks()
+			if infos == nil {
+				infos = []TaskInfo{}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(infos)
+		case http.MethodPost:
+			id := r.URL.Query().Get("id")
+			if id == "" {
+				http.Error(w, "id is required", http.StatusBadRequest)
+				return
+			}
+			if _, _, ok := al.findTask(id); !ok {
+				http.Error(w, "task not found", http.StatusNotFound)
+				return
+			}
+			if err := al.CancelTask(id); err != nil {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// chatTasks returns the background tasks started from msg's chat.
+func (al *AgentLoop) chatTasks(msg bus.InboundMessage) []TaskInfo {
+	var infos []TaskInfo
+	for _, info := range al.Tasks() {
+		if info.Channel == msg.Channel && info.ChatID == msg.ChatID {
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
+// formatTasks lists tasks one per line for /tasks.
+func formatTasks(infos []TaskInfo) string {
+	var sb strings.Builder
+	for i, info := range infos {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		name := info.Label
+		if name == "" {
+			name = info.Task
+		}
+		fmt.Fprintf(&sb, "%s [%s, %d steps] %s", info.ID, info.Status, info.Iterations, utils.Truncate(name, 60))
+		if info.Progress != "" {
+			fmt.Fprintf(&sb, ": %s", info.Progress)
+		}
+	}
+	return sb.String()
+}
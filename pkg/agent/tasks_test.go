@@ -0,0 +1,122 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// hangingProvider answers only once the request is cancelled.
+type hangingProvider struct{}
+
+func (p *hangingProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (p *hangingProvider) GetDefaultModel() string { return "test-model" }
+
+func TestHandleCommand_Tasks(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &hangingProvider{})
+	tasks := al.registry.GetDefaultAgent().Tasks
+	if _, err := tasks.Spawn(context.Background(), "crawl the docs", "crawl", "", "telegram", "c1", nil); err != nil {
+		t.Fatal(err)
+	}
+	taskID := tasks.ListTasks()[0].ID
+
+	here := bus.InboundMessage{Channel: "telegram", ChatID: "c1", SenderID: "7"}
+	elsewhere := bus.InboundMessage{Channel: "telegram", ChatID: "c2", SenderID: "8"}
+	command := func(msg bus.InboundMessage, content string) string {
+		t.Helper()
+		msg.Content = content
+		got, handled := al.handleCommand(context.Background(), msg)
+		if !handled {
+			t.Fatalf("%s not handled", content)
+		}
+		return got
+	}
+
+	if got := command(here, "/tasks"); !strings.HasPrefix(got, taskID+" [") || !strings.Contains(got, "crawl") {
+		t.Errorf("/tasks = %q", got)
+	}
+	if got := command(elsewhere, "/tasks"); got != "No background tasks were started in this chat" {
+		t.Errorf("/tasks in another chat = %q", got)
+	}
+	if got := command(elsewhere, "/tasks cancel "+taskID); !strings.HasPrefix(got, "Failed to cancel task") {
+		t.Errorf("cancel from another chat = %q", got)
+	}
+	if got := command(here, "/tasks cancel "+taskID); got != "Cancelled task "+taskID {
+		t.Errorf("cancel = %q", got)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		task, _ := tasks.GetTask(taskID)
+		if task.Status == "cancelled" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("status = %s, want cancelled", task.Status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestTasksHandler(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &hangingProvider{})
+	tasks := al.registry.GetDefaultAgent().Tasks
+	if _, err := tasks.Spawn(context.Background(), "summarize", "", "", "slack", "c1", nil); err != nil {
+		t.Fatal(err)
+	}
+	taskID := tasks.ListTasks()[0].ID
+	handler := al.TasksHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/tasks", nil))
+	var infos []TaskInfo
+	if err := json.NewDecoder(rec.Body).Decode(&infos); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(infos) != 1 || infos[0].ID != taskID || infos[0].AgentID != "main" || infos[0].Channel != "slack" {
+		t.Errorf("tasks = %+v", infos)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/tasks?id=missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("cancel missing status = %d, want 404", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/tasks?id="+taskID, nil))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("cancel status = %d, want 204", rec.Code)
+	}
+}
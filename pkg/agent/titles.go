@@ -0,0 +1,151 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/crash"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+const (
+	maxTitleRunes = 60
+	maxTitleTags  = 5
+	// titleMessages is how many messages from the start of a session the
+	// title is based on.
+	titleMessages = 12
+)
+
+const titlePrompt = `Give this conversation a short title of at most six words and up to five one-word lowercase tags describing its topics. Write the title in the language of the conversation. Reply with JSON only, in the form {"title": "...", "tags": ["..."]}.
+
+CONVERSATION:
+`
+
+// maybeTitle names the session in the background once it has enough user
+// turns. Each session is tried at most once per run, so a model that keeps
+// failing does not cost a request on every turn.
+func (al *AgentLoop) maybeTitle(ctx context.Context, agent *AgentInstance, sessionKey string) {
+	if al.cfg == nil || !al.cfg.Session.Titles.Enabled {
+		return
+	}
+	sess, ok := agent.Sessions.Get(sessionKey)
+	if !ok || sess.Title != "" {
+		return
+	}
+	turns := 0
+	for _, m := range sess.Messages {
+		if m.Role == "user" {
+			turns++
+		}
+	}
+	if turns < max(al.cfg.Session.Titles.AfterTurns, 1) {
+		return
+	}
+
+	titleKey := agent.ID + ":" + sessionKey
+	if _, tried := al.titled.LoadOrStore(titleKey, true); tried {
+		return
+	}
+	crash.Go(ctx, "agent", func() { al.titleSession(agent, sessionKey, sess.Messages) })
+}
+
+// pruneTitled forgets the titling attempts of sessions no longer held in
+// memory, so that the record does not outgrow the sessions themselves.
+func (al *AgentLoop) pruneTitled() {
+	al.titled.Range(func(k, _ any) bool {
+		agentID, sessionKey, _ := strings.Cut(k.(string), ":")
+		if agent, ok := al.registry.GetAgent(agentID); !ok || !agent.Sessions.Loaded(sessionKey) {
+			al.titled.Delete(k)
+		}
+		return true
+	})
+}
+
+func (al *AgentLoop) titleSession(agent *AgentInstance, sessionKey string, messages []providers.Message) {
+	ctx, cancel := context.WithTimeout(providers.WithPriority(context.Background(), providers.PriorityBackground), 60*time.Second)
+	defer cancel()
+
+	var sb strings.Builder
+	sb.WriteString(titlePrompt)
+	n := 0
+	for _, m := range messages {
+		if (m.Role != "user" && m.Role != "assistant") || m.Content == "" {
+			continue
+		}
+		fmt.Fprintf(&sb, "%s: %s\n", m.Role, utils.Truncate(m.Content, 500))
+		if n++; n == titleMessages {
+			break
+		}
+	}
+
+	model := al.cfg.Session.Titles.Model
+	if model == "" {
+		model = agent.Model
+	}
+	response, err := al.scheduler.Provider(agent.Provider).Chat(ctx, []providers.Message{{Role: "user", Content: sb.String()}}, nil, model, map[string]interface{}{
+		"max_tokens":  100,
+		"temperature": 0.3,
+	})
+	al.recordUsage(sessionKey, model, response)
+	if err == nil {
+		var title string
+		var tags []string
+		if title, tags, err = parseTitle(response.Content); err == nil {
+			agent.Sessions.SetTitle(sessionKey, title, tags)
+			agent.Sessions.Save(sessionKey)
+			logger.DebugCF("agent", "Titled session", map[string]interface{}{
+				"session_key": sessionKey,
+				"title":       title,
+				"tags":        tags,
+			})
+			return
+		}
+	}
+	logger.WarnCF("agent", "Failed to title session", map[string]interface{}{
+		"session_key": sessionKey,
+		"error":       err.Error(),
+	})
+}
+
+// parseTitle extracts the title and tags from a model reply, tolerating
+// text or code fences around the JSON object.
+func parseTitle(reply string) (string, []string, error) {
+	start, end := strings.Index(reply, "{"), strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return "", nil, fmt.Errorf("no JSON object in reply %q", utils.Truncate(reply, 80))
+	}
+	var parsed struct {
+		Title string   `json:"title"`
+		Tags  []string `json:"tags"`
+	}
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &parsed); err != nil {
+		return "", nil, fmt.Errorf("invalid title JSON: %w", err)
+	}
+
+	title := strings.Trim(strings.TrimSpace(parsed.Title), `"'`)
+	if title == "" {
+		return "", nil, fmt.Errorf("empty title")
+	}
+	title = utils.Truncate(strings.Join(strings.Fields(title), " "), maxTitleRunes)
+
+	var tags []string
+	seen := make(map[string]bool)
+	for _, tag := range parsed.Tags {
+		tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+		tag = strings.Join(strings.Fields(tag), "-")
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+		if len(tags) == maxTitleTags {
+			break
+		}
+	}
+	return title, tags, nil
+}
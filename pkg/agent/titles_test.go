@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+func TestParseTitle(t *testing.T) {
+	title, tags, err := parseTitle("Here you go:\n```json\n{\"title\": \" \\\"Fixing  the heater\\\" \", \"tags\": [\"Home\", \"#home\", \"space heater\", \"\"]}\n```")
+	if err != nil {
+		t.Fatalf("parseTitle() error: %v", err)
+	}
+	if title != "Fixing the heater" {
+		t.Errorf("title = %q", title)
+	}
+	if strings.Join(tags, ",") != "home,space-heater" {
+		t.Errorf("tags = %v", tags)
+	}
+
+	for _, reply := range []string{"no json here", `{"title": ""}`, `{"title": `} {
+		if _, _, err := parseTitle(reply); err == nil {
+			t.Errorf("parseTitle(%q) should fail", reply)
+		}
+	}
+}
+
+type titleMockProvider struct {
+	mu         sync.Mutex
+	titleModel string
+	titleCalls int
+}
+
+func (m *titleMockProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	if strings.HasPrefix(messages[0].Content, titlePrompt) {
+		m.mu.Lock()
+		m.titleModel = model
+		m.titleCalls++
+		m.mu.Unlock()
+		return &providers.LLMResponse{Content: `{"title": "Trip planning", "tags": ["travel", "budget"]}`}, nil
+	}
+	return &providers.LLMResponse{Content: "ok"}, nil
+}
+
+func (m *titleMockProvider) GetDefaultModel() string {
+	return "mock-model"
+}
+
+func TestMaybeTitle_TitlesSessionAfterTurns(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		Session: config.SessionConfig{
+			Titles: config.SessionTitlesConfig{Enabled: true, Model: "cheap-model", AfterTurns: 2},
+		},
+	}
+	provider := &titleMockProvider{}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+	sessions := al.registry.GetDefaultAgent().Sessions
+
+	for _, text := range []string{"I want to visit Lisbon", "on a budget", "in May"} {
+		if _, err := al.ProcessDirectWithChannel(context.Background(), text, "test-session", "test", "chat1"); err != nil {
+			t.Fatalf("ProcessDirectWithChannel() error: %v", err)
+		}
+		if text == "I want to visit Lisbon" {
+			time.Sleep(50 * time.Millisecond)
+			if got := sessions.Search("trip"); len(got) != 0 {
+				t.Fatalf("session titled after one turn: %+v", got)
+			}
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(sessions.Search("trip")) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("session was not titled")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	s := sessions.Search("trip")[0]
+	if s.Title != "Trip planning" || strings.Join(s.Tags, ",") != "travel,budget" {
+		t.Errorf("session title = %q, tags = %v", s.Title, s.Tags)
+	}
+
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	if provider.titleModel != "cheap-model" || provider.titleCalls != 1 {
+		t.Errorf("title requests = %d with model %q, want 1 with cheap-model", provider.titleCalls, provider.titleModel)
+	}
+}
+
+func TestPruneTitled_ForgetsEvictedSessions(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &titleMockProvider{})
+	agent := al.registry.GetDefaultAgent()
+	agent.Sessions.AddMessage("kept", "user", "hi")
+	al.titled.Store(agent.ID+":kept", true)
+	al.titled.Store(agent.ID+":evicted", true)
+	al.titled.Store("gone:kept", true)
+
+	al.pruneTitled()
+	if _, ok := al.titled.Load(agent.ID + ":kept"); !ok {
+		t.Error("pruneTitled() forgot a session held in memory")
+	}
+	for _, key := range []string{agent.ID + ":evicted", "gone:kept"} {
+		if _, ok := al.titled.Load(key); ok {
+			t.Errorf("pruneTitled() kept %s", key)
+		}
+	}
+}
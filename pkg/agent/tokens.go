@@ -0,0 +1,164 @@
+package agent
+
+import (
+	"encoding/json"
+	"sync"
+	"unicode"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// Token counts that do not depend on the text: the framing of each message
+// and of the reply, as in OpenAI's chat format, and a typical image.
+const (
+	messageOverheadTokens = 4
+	replyOverheadTokens   = 3
+	imageTokens           = 800
+)
+
+// TokenCounter estimates how many tokens a request takes up of the model's
+// context window. Text is counted the way BPE tokenizers like tiktoken
+// split it: short words are one token, long ones several, digits in groups
+// of three, and CJK characters one each. No tokenizer matches every
+// provider, so the estimate of each model is scaled by how the prompt
+// token counts the provider reported compared to it.
+type TokenCounter struct {
+	mu    sync.Mutex
+	scale map[string]float64 // by model
+}
+
+// NewTokenCounter returns a counter without any reported counts yet.
+func NewTokenCounter() *TokenCounter {
+	return &TokenCounter{scale: make(map[string]float64)}
+}
+
+// Count returns the estimated number of prompt tokens of a request to
+// model with messages and tools.
+func (c *TokenCounter) Count(model string, messages []providers.Message, tools []providers.ToolDefinition) int {
+	raw := countRequestTokens(messages, tools)
+	c.mu.Lock()
+	scale, ok := c.scale[model]
+	c.mu.Unlock()
+	if !ok {
+		return raw
+	}
+	return int(float64(raw)*scale + 0.5)
+}
+
+// Observe calibrates the estimates of model with the prompt tokens the
+// provider reported for a request with messages and tools. Each report
+// moves the scale part of the way, so one odd count does not throw it off.
+func (c *TokenCounter) Observe(model string, messages []providers.Message, tools []providers.ToolDefinition, reported int) {
+	raw := countRequestTokens(messages, tools)
+	if reported <= 0 || raw <= 0 {
+		return
+	}
+	ratio := min(max(float64(reported)/float64(raw), 0.5), 2)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if scale, ok := c.scale[model]; ok {
+		ratio = scale + (ratio-scale)*0.3
+	}
+	c.scale[model] = ratio
+}
+
+func countRequestTokens(messages []providers.Message, tools []providers.ToolDefinition) int {
+	total := replyOverheadTokens
+	for _, m := range messages {
+		total += countMessageTokens(m)
+	}
+	for _, def := range tools {
+		data, _ := json.Marshal(def)
+		total += countTextTokens(string(data))
+	}
+	return total
+}
+
+// countMessageTokens estimates the tokens of one message. Files uploaded
+// to the provider are not counted: their size is not known here.
+func countMessageTokens(m providers.Message) int {
+	total := messageOverheadTokens + countTextTokens(m.Role) + countTextTokens(m.Content)
+	for _, tc := range m.ToolCalls {
+		name, args := tc.Name, ""
+		if tc.Function != nil {
+			name, args = tc.Function.Name, tc.Function.Arguments
+		} else if tc.Arguments != nil {
+			data, _ := json.Marshal(tc.Arguments)
+			args = string(data)
+		}
+		total += messageOverheadTokens + countTextTokens(name) + countTextTokens(args)
+	}
+	total += len(m.Images) * imageTokens
+	return total
+}
+
+type runeClass int
+
+const (
+	classSpace runeClass = iota
+	classLatin
+	classDigit
+	classPunct
+	classIdeograph
+	classOther
+)
+
+func classifyRune(r rune) runeClass {
+	switch {
+	case unicode.IsSpace(r):
+		return classSpace
+	case r < 0x250 && unicode.IsLetter(r):
+		return classLatin
+	case unicode.IsDigit(r):
+		return classDigit
+	case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+		return classIdeograph
+	case unicode.IsLetter(r) || unicode.IsMark(r):
+		return classOther
+	default:
+		return classPunct
+	}
+}
+
+// countTextTokens estimates the tokens of s by splitting it into runs of
+// the same class of characters, as tokenizers pre-split text, and counting
+// the tokens of each run.
+func countTextTokens(s string) int {
+	tokens := 0
+	class, run := classSpace, 0
+	space := false // the whitespace run is a single space
+	flush := func() {
+		if run == 0 {
+			return
+		}
+		switch class {
+		case classSpace:
+			// A single space joins the next word's token.
+			if !space {
+				tokens++
+			}
+		case classLatin:
+			tokens += (run + 5) / 6
+		case classDigit:
+			tokens += (run + 2) / 3
+		case classPunct, classOther:
+			tokens += (run + 1) / 2
+		case classIdeograph:
+			tokens += run
+		}
+	}
+	for _, r := range s {
+		c := classifyRune(r)
+		if c != class || run == 0 {
+			flush()
+			class, run = c, 0
+			space = r == ' '
+		} else if c == classSpace {
+			space = false
+		}
+		run++
+	}
+	flush()
+	return tokens
+}
@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+func TestCountTextTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"the quick brown fox jumps over the lazy dog", 9},
+		{"internationalization", 4},
+		{"1234567", 3},
+		{"你好世界", 4},
+		{"Hello, world!\n\nBye.", 7},
+	}
+	for _, tt := range tests {
+		if got := countTextTokens(tt.text); got != tt.want {
+			t.Errorf("countTextTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTokenCounter_Calibrates(t *testing.T) {
+	c := NewTokenCounter()
+	messages := []providers.Message{
+		{Role: "system", Content: "You are a helpful assistant."},
+		{Role: "user", Content: "What is the capital of France?"},
+	}
+	raw := c.Count("m", messages, nil)
+	if raw == 0 {
+		t.Fatal("Count() = 0")
+	}
+
+	c.Observe("m", messages, nil, raw*3/2)
+	if got := c.Count("m", messages, nil); got != raw*3/2 {
+		t.Errorf("Count() = %d after the first report, want %d", got, raw*3/2)
+	}
+	// Later reports move the scale part of the way, and it stays within
+	// bounds however far off they are.
+	for range 50 {
+		c.Observe("m", messages, nil, raw*100)
+	}
+	if got := c.Count("m", messages, nil); got != raw*2 {
+		t.Errorf("Count() = %d, want it capped at %d", got, raw*2)
+	}
+	if got := c.Count("other", messages, nil); got != raw {
+		t.Errorf("Count() = %d for another model, want %d", got, raw)
+	}
+}
@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+// ToolCallReport describes a tool call made with CallTool.
+type ToolCallReport struct {
+	AgentID    string
+	Definition providers.ToolDefinition
+	// ArgsErr is set when the arguments do not match the tool's parameters.
+	// The model would have been sent this call all the same.
+	ArgsErr error
+	// NeedsApproval is whether the call waits for approval before it runs.
+	NeedsApproval bool
+
+	// Result is nil for a dry run.
+	Result   *tools.ToolResult
+	Duration time.Duration
+}
+
+// CallTool calls tool name of the agent agentID (the default agent if
+// empty) with args the way the agent calls it from the CLI: through the
+// same registry, with its approvals, injection guard and logging. With
+// dryRun the arguments are only checked against the tool's parameters.
+func (al *AgentLoop) CallTool(ctx context.Context, agentID, name string, args map[string]interface{}, dryRun bool) (*ToolCallReport, error) {
+	agent := al.registry.GetDefaultAgent()
+	if agentID != "" {
+		var ok bool
+		if agent, ok = al.registry.GetAgent(agentID); !ok {
+			return nil, fmt.Errorf("agent %q not found", agentID)
+		}
+	}
+	if agent == nil {
+		return nil, fmt.Errorf("no agent configured")
+	}
+	tool, ok := agent.Tools.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("agent %s has no tool %q; it has %s", agent.ID, name, strings.Join(agent.Tools.List(), ", "))
+	}
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+
+	report := &ToolCallReport{
+		AgentID: agent.ID,
+		Definition: providers.ToolDefinition{
+			Type: "function",
+			Function: providers.ToolFunctionDefinition{
+				Name:        tool.Name(),
+				Description: tool.Description(),
+				Parameters:  tool.Parameters(),
+			},
+		},
+		ArgsErr: checkToolArgs(tool.Parameters(), args),
+	}
+	if al.cfg.Tools.Approvals.Enabled {
+		for _, t := range al.cfg.Tools.Approvals.Tools {
+			if t == name {
+				report.NeedsApproval = true
+			}
+		}
+	}
+	if dryRun {
+		return report, nil
+	}
+
+	opts := processOptions{Channel: "cli", ChatID: "direct"}
+	al.updateToolContexts(agent, opts.Channel, opts.ChatID)
+	tc := providers.ToolCall{
+		ID:        fmt.Sprintf("call_%d", time.Now().UnixNano()),
+		Type:      "function",
+		Name:      name,
+		Arguments: args,
+	}
+	start := time.Now()
+	report.Result = al.executeToolCall(ctx, agent, tc, 0, opts)
+	report.Duration = time.Since(start)
+	return report, nil
+}
+
+// checkToolArgs checks args against a tool's parameters as the model sees
+// them, in JSON.
+func checkToolArgs(params map[string]interface{}, args map[string]interface{}) error {
+	var schema, value map[string]interface{}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return err
+	}
+	if data, err = json.Marshal(args); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	return providers.ValidateSchema(schema, value)
+}
@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestCallTool(t *testing.T) {
+	workspace := t.TempDir()
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         workspace,
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	cfg.Tools.Approvals.Enabled = true
+	cfg.Tools.Approvals.Tools = []string{"write_file"}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &mockProvider{})
+	ctx := context.Background()
+	args := map[string]interface{}{"path": "note.txt", "content": "hi"}
+
+	report, err := al.CallTool(ctx, "", "write_file", args, true)
+	if err != nil {
+		t.Fatalf("CallTool() error = %v", err)
+	}
+	if report.Result != nil || report.ArgsErr != nil || !report.NeedsApproval {
+		t.Errorf("dry run report = %+v", report)
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "note.txt")); !os.IsNotExist(err) {
+		t.Fatal("dry run wrote the file")
+	}
+
+	// CLI calls are approved by the operator.
+	report, err = al.CallTool(ctx, "", "write_file", args, false)
+	if err != nil {
+		t.Fatalf("CallTool() error = %v", err)
+	}
+	if report.Result == nil || report.Result.IsError {
+		t.Fatalf("result = %+v", report.Result)
+	}
+	if data, _ := os.ReadFile(filepath.Join(workspace, "note.txt")); string(data) != "hi" {
+		t.Errorf("file = %q", data)
+	}
+
+	report, _ = al.CallTool(ctx, "", "write_file", map[string]interface{}{"path": 3}, true)
+	if report.ArgsErr == nil {
+		t.Error("expected invalid arguments to be reported")
+	}
+
+	if _, err := al.CallTool(ctx, "", "no_such_tool", nil, true); err == nil || !strings.Contains(err.Error(), "read_file") {
+		t.Errorf("unknown tool error = %v", err)
+	}
+	if _, err := al.CallTool(ctx, "nobody", "read_file", nil, true); err == nil {
+		t.Error("expected an error for an unknown agent")
+	}
+}
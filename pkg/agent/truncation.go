@@ -0,0 +1,162 @@
+package agent
+
+import (
+	"fmt"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// Truncation policies, as set in AgentDefaults.Truncation.
+const (
+	TruncateDropOldest = "drop_oldest"
+	TruncateSummarize  = "summarize"
+	TruncateError      = "error"
+)
+
+// ContextOverflowError is returned for a request that does not fit the
+// model's context window, when the truncation policy is "error" or when
+// nothing more can be left out of it.
+type ContextOverflowError struct {
+	Model  string
+	Tokens int // estimated prompt tokens
+	Limit  int // prompt tokens the model has room for
+}
+
+func (e *ContextOverflowError) Error() string {
+	return fmt.Sprintf("request of about %d tokens exceeds the %d the context window of %s leaves for it", e.Tokens, e.Limit, e.Model)
+}
+
+// promptLimit returns how many prompt tokens the agent's model has room
+// for: its context window less what is reserved for the reply, capped at
+// half the window. It is zero if the context window is not known.
+func (a *AgentInstance) promptLimit() int {
+	window := a.Capabilities.ContextWindow
+	if window <= 0 {
+		return 0
+	}
+	return window - min(a.MaxTokens, window/2)
+}
+
+// fitContext makes messages fit the context window of the agent's model
+// before they are sent, as the agent's truncation policy says. Only the
+// request is truncated; the session keeps its history.
+func (al *AgentLoop) fitContext(agent *AgentInstance, opts processOptions, messages []providers.Message, tools []providers.ToolDefinition) ([]providers.Message, error) {
+	limit := agent.promptLimit()
+	if limit == 0 {
+		return messages, nil
+	}
+	tokens := al.tokens.Count(agent.Model, messages, tools)
+	if tokens <= limit {
+		return messages, nil
+	}
+	overflow := &ContextOverflowError{Model: agent.Model, Tokens: tokens, Limit: limit}
+
+	switch agent.Truncation {
+	case TruncateError:
+		return nil, overflow
+	case TruncateSummarize:
+		summarizeKey := agent.ID + ":" + opts.SessionKey
+		if _, running := al.summarizing.LoadOrStore(summarizeKey, true); !running {
+			al.summarizeSession(agent, opts.SessionKey)
+			al.summarizing.Delete(summarizeKey)
+			rebuilt := agent.ContextBuilder.BuildMessages(
+				agent.Sessions.GetHistory(opts.SessionKey),
+				agent.Sessions.GetSummary(opts.SessionKey), "",
+				nil, opts.Channel, opts.ChatID,
+			)
+			carryAttachments(messages, rebuilt)
+			messages = rebuilt
+			tokens = al.tokens.Count(agent.Model, messages, tools)
+			if tokens <= limit {
+				logger.InfoCF("agent", "Summarized session to fit the context window", map[string]interface{}{
+					"agent_id":    agent.ID,
+					"session_key": opts.SessionKey,
+					"tokens":      tokens,
+					"limit":       limit,
+				})
+				return messages, nil
+			}
+		}
+		// What the summary left is still too long: drop the oldest of it.
+	}
+
+	kept, dropped := dropOldest(messages, func(m []providers.Message) bool {
+		return al.tokens.Count(agent.Model, m, tools) <= limit
+	})
+	if kept == nil {
+		return nil, overflow
+	}
+	logger.WarnCF("agent", "Dropped oldest messages to fit the context window", map[string]interface{}{
+		"agent_id":     agent.ID,
+		"session_key":  opts.SessionKey,
+		"dropped_msgs": dropped,
+		"tokens":       tokens,
+		"limit":        limit,
+	})
+	return kept, nil
+}
+
+// carryAttachments copies the images and files of the last user message in
+// from onto the last one in to. The session does not keep the images of the
+// current message, nor whether its files are still uploaded, so messages
+// rebuilt from it lack them.
+func carryAttachments(from, to []providers.Message) {
+	last := func(messages []providers.Message) int {
+		for i := len(messages) - 1; i >= 0; i-- {
+			if messages[i].Role == "user" {
+				return i
+			}
+		}
+		return -1
+	}
+	i, j := last(from), last(to)
+	if i < 0 || j < 0 {
+		return
+	}
+	to[j].Images = from[i].Images
+	to[j].Files = from[i].Files
+}
+
+// dropOldest leaves out the oldest messages after the system prompt until
+// fits accepts what is left, with a note on the system prompt. Tool
+// results are dropped with the call they answer, and the last message,
+// with the call it is the result of, is always kept. It returns nil if no
+// number of dropped messages fits.
+func dropOldest(messages []providers.Message, fits func([]providers.Message) bool) ([]providers.Message, int) {
+	head := 0
+	if len(messages) > 0 && messages[0].Role == "system" {
+		head = 1
+	}
+	tail := len(messages) - 1
+	for tail > head && messages[tail].Role == "tool" {
+		tail--
+	}
+	for start := head + 1; start <= tail; start++ {
+		if messages[start].Role == "tool" {
+			continue
+		}
+		dropped := start - head
+
+		kept := make([]providers.Message, 0, head+len(messages)-start)
+		if head == 1 {
+			system := messages[0]
+			system.Content += fmt.Sprintf("\n\n[System Note: %d earlier messages were left out to fit the context window]", dropped)
+			kept = append(kept, system)
+		}
+		kept = append(kept, messages[start:]...)
+		if fits(kept) {
+			return kept, dropped
+		}
+	}
+	return nil, 0
+}
+
+// observePromptTokens calibrates the token counter with the prompt tokens
+// resp reports for a request.
+func (al *AgentLoop) observePromptTokens(agent *AgentInstance, model string, messages []providers.Message, tools []providers.ToolDefinition, resp *providers.LLMResponse) {
+	if resp == nil || resp.Usage == nil || model != agent.Model {
+		return
+	}
+	al.tokens.Observe(model, messages, tools, resp.Usage.PromptTokens)
+}
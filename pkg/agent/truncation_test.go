@@ -0,0 +1,172 @@
+package agent
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+func TestDropOldest_KeepsToolResultsWithTheirCall(t *testing.T) {
+	messages := []providers.Message{
+		{Role: "system", Content: "system"},
+		{Role: "user", Content: "first"},
+		{Role: "assistant", ToolCalls: []providers.ToolCall{{ID: "1", Name: "read_file"}}},
+		{Role: "tool", ToolCallID: "1", Content: "file"},
+		{Role: "assistant", Content: "done"},
+		{Role: "user", Content: "second"},
+		{Role: "assistant", ToolCalls: []providers.ToolCall{{ID: "2", Name: "exec"}}},
+		{Role: "tool", ToolCallID: "2", Content: "output"},
+	}
+
+	kept, dropped := dropOldest(messages, func(m []providers.Message) bool { return len(m) <= 6 })
+	if dropped != 3 || len(kept) != 5 {
+		t.Fatalf("dropOldest() kept %d, dropped %d; want 5 and 3", len(kept), dropped)
+	}
+	if kept[1].Role != "assistant" || kept[1].Content != "done" {
+		t.Errorf("first kept message = %+v, want the reply after the tool result", kept[1])
+	}
+	if !strings.Contains(kept[0].Content, "3 earlier messages were left out") {
+		t.Errorf("system prompt = %q, want a note", kept[0].Content)
+	}
+	if messages[0].Content != "system" {
+		t.Error("dropOldest() changed the original system prompt")
+	}
+
+	// The last tool call and its result are never dropped.
+	if kept, _ := dropOldest(messages, func(m []providers.Message) bool { return len(m) <= 2 }); kept != nil {
+		t.Errorf("dropOldest() = %d messages, want nil when the call and its result do not fit", len(kept))
+	}
+	kept, _ = dropOldest(messages, func(m []providers.Message) bool { return len(m) <= 3 })
+	if len(kept) != 3 || kept[1].Role != "assistant" || kept[2].Role != "tool" {
+		t.Errorf("dropOldest() = %+v, want the system prompt, the last call and its result", kept)
+	}
+}
+
+func newTruncationLoop(t *testing.T, policy string) (*AgentLoop, *AgentInstance) {
+	t.Helper()
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "small",
+				MaxTokens:         500,
+				MaxToolIterations: 10,
+				Truncation:        policy,
+				Summarizer:        config.SummarizerConfig{Strategy: "extractive"},
+			},
+		},
+		ModelList: []config.ModelConfig{{
+			ModelName:    "small",
+			Model:        "openai/small",
+			Capabilities: &config.ModelCapabilities{ContextWindow: 2000},
+		}},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &mockProvider{})
+	agent := al.registry.GetDefaultAgent()
+	if got := agent.promptLimit(); got != 1500 {
+		t.Fatalf("promptLimit() = %d, want 1500", got)
+	}
+	return al, agent
+}
+
+// longHistory adds messages of about 200 tokens each to the session, more
+// than fit the context window.
+func longHistory(agent *AgentInstance, sessionKey string) []providers.Message {
+	for range 15 {
+		agent.Sessions.AddMessage(sessionKey, "user", "Question. "+strings.Repeat("word ", 200))
+		agent.Sessions.AddMessage(sessionKey, "assistant", "Answer. "+strings.Repeat("reply ", 200))
+	}
+	agent.Sessions.AddMessage(sessionKey, "user", "And now?")
+	return agent.ContextBuilder.BuildMessages(agent.Sessions.GetHistory(sessionKey), "", "", nil, "test", "chat1")
+}
+
+func TestFitContext_Policies(t *testing.T) {
+	opts := processOptions{SessionKey: "s1", Channel: "test", ChatID: "chat1"}
+
+	t.Run("drop_oldest", func(t *testing.T) {
+		al, agent := newTruncationLoop(t, TruncateDropOldest)
+		messages := longHistory(agent, opts.SessionKey)
+		fitted, err := al.fitContext(agent, opts, messages, nil)
+		if err != nil {
+			t.Fatalf("fitContext() error: %v", err)
+		}
+		if n := al.tokens.Count(agent.Model, fitted, nil); n > 1500 || len(fitted) >= len(messages) {
+			t.Errorf("fitContext() = %d messages of %d tokens", len(fitted), n)
+		}
+		if last := fitted[len(fitted)-1]; last.Content != "And now?" {
+			t.Errorf("last message = %q, want the current one", last.Content)
+		}
+		if got := len(agent.Sessions.GetHistory(opts.SessionKey)); got != 31 {
+			t.Errorf("session has %d messages, want all 31 kept", got)
+		}
+	})
+
+	t.Run("summarize", func(t *testing.T) {
+		al, agent := newTruncationLoop(t, TruncateSummarize)
+		messages := longHistory(agent, opts.SessionKey)
+		messages[len(messages)-1].Files = []providers.FileRef{{ID: "file-1", Name: "report.pdf"}}
+		fitted, err := al.fitContext(agent, opts, messages, nil)
+		if err != nil {
+			t.Fatalf("fitContext() error: %v", err)
+		}
+		if agent.Sessions.GetSummary(opts.SessionKey) == "" {
+			t.Error("session was not summarized")
+		}
+		if n := al.tokens.Count(agent.Model, fitted, nil); n > 1500 {
+			t.Errorf("fitContext() = %d tokens", n)
+		}
+		last := fitted[len(fitted)-1]
+		if last.Content != "And now?" {
+			t.Errorf("last message = %q, want the current one", last.Content)
+		}
+		if len(last.Files) != 1 {
+			t.Errorf("current message lost its files: %v", last.Files)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		al, agent := newTruncationLoop(t, TruncateError)
+		messages := longHistory(agent, opts.SessionKey)
+		_, err := al.fitContext(agent, opts, messages, nil)
+		var overflow *ContextOverflowError
+		if !errors.As(err, &overflow) || overflow.Limit != 1500 || overflow.Tokens <= 1500 {
+			t.Fatalf("fitContext() error = %v, want a context overflow", err)
+		}
+
+		short := messages[:1]
+		if fitted, err := al.fitContext(agent, opts, short, nil); err != nil || len(fitted) != 1 {
+			t.Errorf("fitContext() = %d messages, %v for a request that fits", len(fitted), err)
+		}
+	})
+}
+
+func TestCarryAttachments(t *testing.T) {
+	from := []providers.Message{
+		{Role: "system", Content: "prompt"},
+		{Role: "user", Content: "look", Images: []providers.Image{{URL: "https://example.com/cat.png"}}},
+		{Role: "tool", Content: "result"},
+	}
+	to := []providers.Message{
+		{Role: "system", Content: "prompt"},
+		{Role: "user", Content: "earlier"},
+		{Role: "user", Content: "look"},
+		{Role: "tool", Content: "result"},
+	}
+	carryAttachments(from, to)
+	if len(to[2].Images) != 1 || len(to[1].Images) != 0 {
+		t.Errorf("images went to the wrong message: %+v", to)
+	}
+}
+
+func TestFitContext_UnknownContextWindow(t *testing.T) {
+	al, agent := newTruncationLoop(t, TruncateError)
+	agent.Capabilities.ContextWindow = 0
+	messages := longHistory(agent, "s1")
+	if fitted, err := al.fitContext(agent, processOptions{SessionKey: "s1"}, messages, nil); err != nil || len(fitted) != len(messages) {
+		t.Errorf("fitContext() = %d messages, %v; want the request unchanged", len(fitted), err)
+	}
+}
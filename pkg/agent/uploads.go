@@ -0,0 +1,258 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/crash"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/vfs"
+)
+
+// UploadedFile records a document uploaded to the provider's file store.
+type UploadedFile struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	MimeType string    `json:"mime_type"`
+	Size     int64     `json:"size"`
+	Uploaded time.Time `json:"uploaded"`
+}
+
+// UploadRegistry tracks documents uploaded for an agent, keyed by content
+// hash so the same document is uploaded once. It is persisted in the
+// workspace state directory so uploads survive restarts and can be
+// cleaned up later.
+type UploadRegistry struct {
+	mu    sync.Mutex
+	path  string
+	files map[string]UploadedFile
+}
+
+// NewUploadRegistry loads the registry for workspace.
+func NewUploadRegistry(workspace string) *UploadRegistry {
+	r := &UploadRegistry{
+		path:  filepath.Join(workspace, "state", "uploads.json"),
+		files: make(map[string]UploadedFile),
+	}
+	if data, err := vfs.ReadFile(r.path); err == nil {
+		if err := json.Unmarshal(data, &r.files); err != nil {
+			logger.WarnCF("agent", "Ignoring unreadable upload registry", map[string]interface{}{
+				"path":  r.path,
+				"error": err.Error(),
+			})
+			r.files = make(map[string]UploadedFile)
+		}
+	}
+	return r
+}
+
+func (r *UploadRegistry) get(hash string) (UploadedFile, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f, ok := r.files[hash]
+	return f, ok
+}
+
+func (r *UploadRegistry) add(hash string, f UploadedFile) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.files[hash] = f
+	r.saveLocked()
+}
+
+func (r *UploadRegistry) remove(hash string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.files, hash)
+	r.saveLocked()
+}
+
+// expired returns uploads older than cutoff, keyed by hash.
+func (r *UploadRegistry) expired(cutoff time.Time) map[string]UploadedFile {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make(map[string]UploadedFile)
+	for hash, f := range r.files {
+		if f.Uploaded.Before(cutoff) {
+			out[hash] = f
+		}
+	}
+	return out
+}
+
+// filter drops references to files that are no longer uploaded, so old
+// history does not point the provider at deleted files. A nil registry
+// (uploads disabled) drops all references.
+func (r *UploadRegistry) filter(refs []providers.FileRef) []providers.FileRef {
+	if r == nil || len(refs) == 0 {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	live := make(map[string]bool, len(r.files))
+	for _, f := range r.files {
+		live[f.ID] = true
+	}
+	var out []providers.FileRef
+	for _, ref := range refs {
+		if live[ref.ID] {
+			out = append(out, ref)
+		}
+	}
+	return out
+}
+
+func (r *UploadRegistry) saveLocked() {
+	data, err := json.MarshalIndent(r.files, "", "  ")
+	if err != nil {
+		return
+	}
+	vfs.MkdirAll(filepath.Dir(r.path), 0755)
+	if err := vfs.WriteFile(r.path, data, 0644); err != nil {
+		logger.WarnCF("agent", "Failed to save upload registry", map[string]interface{}{"error": err.Error()})
+	}
+}
+
+// documentType returns the MIME type of a document the provider file APIs
+// accept, or "" for anything else.
+func documentType(path string, data []byte) string {
+	mimeType := mime.TypeByExtension(strings.ToLower(filepath.Ext(path)))
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	if i := strings.Index(mimeType, ";"); i >= 0 {
+		mimeType = mimeType[:i]
+	}
+	switch mimeType {
+	case "application/pdf":
+		return mimeType
+	case "text/plain", "text/markdown", "text/csv":
+		return "text/plain"
+	}
+	return ""
+}
+
+// attachFiles uploads the documents among media to the agent's provider
+// and returns references to attach to the user message. Media that is not
+// a local document, is too large, or fails to upload is skipped.
+func (al *AgentLoop) attachFiles(ctx context.Context, agent *AgentInstance, media []string) []providers.FileRef {
+	store, ok := agent.Provider.(providers.FileStore)
+	if !ok || agent.Uploads == nil || len(media) == 0 {
+		return nil
+	}
+	maxSize := int64(al.cfg.FileUploads.MaxSizeMB) << 20
+
+	var refs []providers.FileRef
+	for _, path := range media {
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() || (maxSize > 0 && info.Size() > maxSize) {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		mimeType := documentType(path, data)
+		if mimeType == "" {
+			continue
+		}
+
+		sum := sha256.Sum256(data)
+		hash := hex.EncodeToString(sum[:])
+		file, ok := agent.Uploads.get(hash)
+		if !ok {
+			name := filepath.Base(path)
+			id, err := store.UploadFile(ctx, name, mimeType, data)
+			if err != nil {
+				logger.WarnCF("agent", "Document upload failed", map[string]interface{}{
+					"file":  name,
+					"error": err.Error(),
+				})
+				continue
+			}
+			file = UploadedFile{ID: id, Name: name, MimeType: mimeType, Size: info.Size(), Uploaded: time.Now()}
+			agent.Uploads.add(hash, file)
+			logger.InfoCF("agent", "Uploaded document to provider", map[string]interface{}{
+				"file":    name,
+				"file_id": id,
+				"bytes":   info.Size(),
+			})
+		}
+		refs = append(refs, providers.FileRef{ID: file.ID, Name: file.Name, MimeType: file.MimeType})
+	}
+	return refs
+}
+
+// CleanupUploads deletes documents uploaded longer ago than the configured
+// retention from the provider and forgets them. It returns the number of
+// files removed.
+func (al *AgentLoop) CleanupUploads(ctx context.Context) int {
+	if al.cfg == nil {
+		return 0
+	}
+	hours := al.cfg.FileUploads.RetentionHours
+	if hours <= 0 {
+		return 0
+	}
+	cutoff := time.Now().Add(-time.Duration(hours) * time.Hour)
+
+	removed := 0
+	for _, id := range al.registry.ListAgentIDs() {
+		agent, ok := al.registry.GetAgent(id)
+		if !ok || agent.Uploads == nil {
+			continue
+		}
+		store, ok := agent.Provider.(providers.FileStore)
+		if !ok {
+			continue
+		}
+		for hash, f := range agent.Uploads.expired(cutoff) {
+			if err := store.DeleteFile(ctx, f.ID); err != nil {
+				logger.WarnCF("agent", "Failed to delete uploaded document", map[string]interface{}{
+					"file_id": f.ID,
+					"error":   err.Error(),
+				})
+				// Keep the record to retry later, unless the provider
+				// no longer knows the file.
+				if !errors.Is(err, providers.ErrFileNotFound) {
+					continue
+				}
+			}
+			agent.Uploads.remove(hash)
+			removed++
+		}
+	}
+	if removed > 0 {
+		logger.InfoCF("agent", fmt.Sprintf("Removed %d expired document uploads", removed), nil)
+	}
+	return removed
+}
+
+// StartUploadCleanup runs CleanupUploads now and then hourly until ctx is
+// done.
+func (al *AgentLoop) StartUploadCleanup(ctx context.Context) {
+	crash.Go(ctx, "agent", func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			al.CleanupUploads(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
@@ -0,0 +1,135 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// fileStoreProvider is a provider with a Files API that records what it
+// is sent.
+type fileStoreProvider struct {
+	mu       sync.Mutex
+	uploads  int
+	deleted  []string
+	lastMsgs []providers.Message
+}
+
+func (p *fileStoreProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastMsgs = messages
+	return &providers.LLMResponse{Content: "ok"}, nil
+}
+
+func (p *fileStoreProvider) GetDefaultModel() string { return "mock-model" }
+
+func (p *fileStoreProvider) UploadFile(ctx context.Context, name, mimeType string, data []byte) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.uploads++
+	return fmt.Sprintf("file_%d", p.uploads), nil
+}
+
+func (p *fileStoreProvider) DeleteFile(ctx context.Context, id string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.deleted = append(p.deleted, id)
+	return nil
+}
+
+func (p *fileStoreProvider) filesSent() []providers.FileRef {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var refs []providers.FileRef
+	for _, m := range p.lastMsgs {
+		refs = append(refs, m.Files...)
+	}
+	return refs
+}
+
+func newUploadTestLoop(t *testing.T, provider providers.LLMProvider) (*AgentLoop, string) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         tmpDir,
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		FileUploads: config.FileUploadsConfig{Enabled: true, MaxSizeMB: 1, RetentionHours: 1},
+	}
+	return NewAgentLoop(cfg, bus.NewMessageBus(), provider), tmpDir
+}
+
+func TestUploads_AttachedAndReferencedAcrossTurns(t *testing.T) {
+	provider := &fileStoreProvider{}
+	al, dir := newUploadTestLoop(t, provider)
+
+	doc := filepath.Join(dir, "report.pdf")
+	os.WriteFile(doc, []byte("%PDF-1.4 test"), 0644)
+	image := filepath.Join(dir, "photo.jpg")
+	os.WriteFile(image, []byte{0xff, 0xd8, 0xff, 0xe0}, 0644)
+
+	ctx := context.Background()
+	if _, err := al.ProcessDirectWithMedia(ctx, "summarize [file]", "cli:test", "cli", []string{doc, image}); err != nil {
+		t.Fatalf("ProcessDirectWithMedia() error: %v", err)
+	}
+	refs := provider.filesSent()
+	if len(refs) != 1 || refs[0].ID != "file_1" || refs[0].MimeType != "application/pdf" {
+		t.Fatalf("files sent = %+v, want the PDF only", refs)
+	}
+
+	// The next turn sends the same reference from history without
+	// uploading again, and re-sending the document reuses the upload.
+	if _, err := al.ProcessDirectWithMedia(ctx, "and again [file]", "cli:test", "cli", []string{doc}); err != nil {
+		t.Fatal(err)
+	}
+	if refs := provider.filesSent(); len(refs) != 2 || refs[0].ID != "file_1" || refs[1].ID != "file_1" {
+		t.Errorf("files sent = %+v", refs)
+	}
+	if provider.uploads != 1 {
+		t.Errorf("uploads = %d, want 1", provider.uploads)
+	}
+
+	// After cleanup the stale reference is no longer sent.
+	agent := al.registry.GetDefaultAgent()
+	for hash, f := range agent.Uploads.files {
+		f.Uploaded = time.Now().Add(-2 * time.Hour)
+		agent.Uploads.files[hash] = f
+	}
+	if n := al.CleanupUploads(ctx); n != 1 {
+		t.Fatalf("CleanupUploads() = %d, want 1", n)
+	}
+	if len(provider.deleted) != 1 || provider.deleted[0] != "file_1" {
+		t.Errorf("deleted = %v", provider.deleted)
+	}
+	if _, err := al.ProcessDirect(ctx, "what was in it?", "cli:test"); err != nil {
+		t.Fatal(err)
+	}
+	if refs := provider.filesSent(); len(refs) != 0 {
+		t.Errorf("expected no file references after cleanup, got %+v", refs)
+	}
+}
+
+func TestUploads_RegistryPersists(t *testing.T) {
+	dir := t.TempDir()
+	r := NewUploadRegistry(dir)
+	r.add("abc", UploadedFile{ID: "file_1", Name: "a.pdf", Uploaded: time.Now()})
+
+	reloaded := NewUploadRegistry(dir)
+	if f, ok := reloaded.get("abc"); !ok || f.ID != "file_1" {
+		t.Errorf("reloaded registry = %+v, %v", f, ok)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build linux || darwin || freebsd
+
+package alerts
+
+import "syscall"
+
+func diskUsage(path string) (free, total uint64, err error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, 0, err
+	}
+	bsize := uint64(st.Bsize)
+	return uint64(st.Bavail) * bsize, uint64(st.Blocks) * bsize, nil
+}
@@ -0,0 +1,27 @@
+//go:build windows
+
+package alerts
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var procGetDiskFreeSpaceExW = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+func diskUsage(path string) (free, total uint64, err error) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	r1, _, callErr := procGetDiskFreeSpaceExW.Call(
+		uintptr(unsafe.Pointer(p)),
+		uintptr(unsafe.Pointer(&free)),
+		uintptr(unsafe.Pointer(&total)),
+		0,
+	)
+	if r1 == 0 {
+		return 0, 0, callErr
+	}
+	return free, total, nil
+}
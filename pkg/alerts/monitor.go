@@ -0,0 +1,246 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package alerts evaluates simple alert rules in-process and delivers
+// notifications to a chat channel, so small deployments get told about
+// trouble without running a metrics stack.
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// component is the logger component used by the monitor. Entries logged
+// under it are not counted towards the error rate rule.
+const component = "alerts"
+
+// Monitor collects events and periodically evaluates the configured rules.
+type Monitor struct {
+	cfg       config.AlertsConfig
+	diskPath  string
+	bus       *bus.MessageBus
+	diskUsage func(path string) (free, total uint64, err error)
+
+	mu               sync.Mutex
+	componentErrors  map[string][]time.Time
+	providerFailures []time.Time
+	lastFired        map[string]time.Time
+
+	removeHook func()
+	cancel     context.CancelFunc
+}
+
+// NewMonitor creates a monitor. workspace is used as the disk rule path
+// when none is configured.
+func NewMonitor(cfg config.AlertsConfig, workspace string, msgBus *bus.MessageBus) *Monitor {
+	diskPath := cfg.Disk.Path
+	if diskPath == "" {
+		diskPath = workspace
+	}
+	return &Monitor{
+		cfg:             cfg,
+		diskPath:        diskPath,
+		bus:             msgBus,
+		diskUsage:       diskUsage,
+		componentErrors: make(map[string][]time.Time),
+		lastFired:       make(map[string]time.Time),
+	}
+}
+
+// Start subscribes to error logs and begins periodic rule evaluation.
+func (m *Monitor) Start(ctx context.Context) {
+	if !m.cfg.Enabled {
+		return
+	}
+
+	m.removeHook = logger.AddHook(func(level logger.LogLevel, entry logger.LogEntry) {
+		if level >= logger.ERROR && entry.Component != component {
+			m.RecordError(entry.Component, time.Now())
+		}
+	})
+
+	interval := time.Duration(m.cfg.CheckIntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	m.cancel = cancel
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-runCtx.Done():
+				return
+			case now := <-ticker.C:
+				m.Evaluate(now)
+			}
+		}
+	}()
+
+	logger.InfoCF(component, "Alert monitor started", map[string]interface{}{
+		"channel":  m.cfg.Channel,
+		"interval": interval.String(),
+	})
+}
+
+// Stop ends evaluation and unsubscribes from logs.
+func (m *Monitor) Stop() {
+	if m.cancel != nil {
+		m.cancel()
+	}
+	if m.removeHook != nil {
+		m.removeHook()
+	}
+}
+
+// RecordError counts an error logged by component. Errors are not kept
+// while the error rate rule is off, and only for its window otherwise.
+func (m *Monitor) RecordError(comp string, at time.Time) {
+	rule := m.cfg.ErrorRate
+	if !rule.Enabled || rule.Threshold <= 0 {
+		return
+	}
+	if comp == "" {
+		comp = "general"
+	}
+	m.mu.Lock()
+	m.componentErrors[comp] = prune(append(m.componentErrors[comp], at), at.Add(-ruleWindow(rule)))
+	m.mu.Unlock()
+}
+
+// RecordProviderFailure counts a failed LLM call, like RecordError for the
+// provider failures rule.
+func (m *Monitor) RecordProviderFailure(err error) {
+	rule := m.cfg.ProviderFailures
+	if !rule.Enabled || rule.Threshold <= 0 {
+		return
+	}
+	now := time.Now()
+	m.mu.Lock()
+	m.providerFailures = prune(append(m.providerFailures, now), now.Add(-ruleWindow(rule)))
+	m.mu.Unlock()
+}
+
+// Evaluate checks all rules at now and sends alerts for those that fire.
+func (m *Monitor) Evaluate(now time.Time) {
+	for _, alert := range m.check(now) {
+		m.fire(alert.key, alert.text, now)
+	}
+}
+
+type firing struct {
+	key  string
+	text string
+}
+
+func (m *Monitor) check(now time.Time) []firing {
+	var out []firing
+
+	m.mu.Lock()
+	if rule := m.cfg.ErrorRate; rule.Enabled && rule.Threshold > 0 {
+		window := ruleWindow(rule)
+		comps := make([]string, 0, len(m.componentErrors))
+		for comp := range m.componentErrors {
+			comps = append(comps, comp)
+		}
+		sort.Strings(comps)
+		for _, comp := range comps {
+			events := prune(m.componentErrors[comp], now.Add(-window))
+			if len(events) == 0 {
+				delete(m.componentErrors, comp)
+				continue
+			}
+			m.componentErrors[comp] = events
+			if len(events) >= rule.Threshold {
+				out = append(out, firing{
+					key:  "error_rate:" + comp,
+					text: fmt.Sprintf("%d errors from %s in the last %s", len(events), comp, window),
+				})
+			}
+		}
+	}
+
+	if rule := m.cfg.ProviderFailures; rule.Enabled && rule.Threshold > 0 {
+		window := ruleWindow(rule)
+		m.providerFailures = prune(m.providerFailures, now.Add(-window))
+		if n := len(m.providerFailures); n >= rule.Threshold {
+			out = append(out, firing{
+				key:  "provider_failures",
+				text: fmt.Sprintf("%d failed LLM provider calls in the last %s", n, window),
+			})
+		}
+	}
+	m.mu.Unlock()
+
+	if rule := m.cfg.Disk; rule.Enabled && rule.MinFreePercent > 0 && m.diskPath != "" {
+		free, total, err := m.diskUsage(m.diskPath)
+		if err == nil && total > 0 {
+			pct := float64(free) * 100 / float64(total)
+			if pct < rule.MinFreePercent {
+				out = append(out, firing{
+					key: "disk",
+					text: fmt.Sprintf("Disk nearly full: %.1f%% free (%d MB) on %s",
+						pct, free/(1024*1024), m.diskPath),
+				})
+			}
+		}
+	}
+
+	return out
+}
+
+// fire delivers an alert unless the same alert fired within the cooldown.
+func (m *Monitor) fire(key, text string, now time.Time) {
+	cooldown := time.Duration(m.cfg.CooldownMinutes) * time.Minute
+
+	m.mu.Lock()
+	if last, ok := m.lastFired[key]; ok && now.Sub(last) < cooldown {
+		m.mu.Unlock()
+		return
+	}
+	m.lastFired[key] = now
+	m.mu.Unlock()
+
+	logger.WarnCF(component, "Alert fired", map[string]interface{}{
+		"rule":    key,
+		"message": text,
+	})
+
+	if m.bus == nil || m.cfg.Channel == "" || m.cfg.ChatID == "" {
+		return
+	}
+	m.bus.PublishOutbound(bus.OutboundMessage{
+		Channel:   m.cfg.Channel,
+		ChatID:    m.cfg.ChatID,
+		Content:   "⚠️ picoclaw alert: " + text,
+		Proactive: true,
+	})
+}
+
+func ruleWindow(rule config.RateAlertConfig) time.Duration {
+	if rule.WindowMinutes <= 0 {
+		return 5 * time.Minute
+	}
+	return time.Duration(rule.WindowMinutes) * time.Minute
+}
+
+// prune drops events older than cutoff. events are in insertion order.
+func prune(events []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(events) && events[i].Before(cutoff) {
+		i++
+	}
+	return events[i:]
+}
@@ -0,0 +1,115 @@
+package alerts
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func testConfig() config.AlertsConfig {
+	return config.AlertsConfig{
+		Enabled:         true,
+		Channel:         "telegram",
+		ChatID:          "42",
+		CooldownMinutes: 30,
+		ErrorRate:       config.RateAlertConfig{Enabled: true, Threshold: 3, WindowMinutes: 5},
+		ProviderFailures: config.RateAlertConfig{
+			Enabled: true, Threshold: 2, WindowMinutes: 10,
+		},
+		Disk: config.DiskAlertConfig{Enabled: true, MinFreePercent: 10},
+	}
+}
+
+func newTestMonitor(cfg config.AlertsConfig) (*Monitor, *bus.MessageBus) {
+	msgBus := bus.NewMessageBus()
+	m := NewMonitor(cfg, "/workspace", msgBus)
+	m.diskUsage = func(string) (uint64, uint64, error) { return 50, 100, nil }
+	return m, msgBus
+}
+
+func expectAlert(t *testing.T, msgBus *bus.MessageBus, substr string) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msg, ok := msgBus.SubscribeOutbound(ctx)
+	if !ok {
+		t.Fatalf("expected an alert containing %q", substr)
+	}
+	if !msg.Proactive || msg.Channel != "telegram" || msg.ChatID != "42" {
+		t.Errorf("unexpected alert routing: %+v", msg)
+	}
+	if !strings.Contains(msg.Content, substr) {
+		t.Errorf("alert = %q, want it to contain %q", msg.Content, substr)
+	}
+}
+
+func TestMonitor_ErrorRateWindowAndCooldown(t *testing.T) {
+	m, msgBus := newTestMonitor(testConfig())
+	now := time.Now()
+
+	// An old error outside the window does not count.
+	m.RecordError("telegram", now.Add(-10*time.Minute))
+	m.RecordError("telegram", now.Add(-time.Minute))
+	m.RecordError("telegram", now)
+	if got := m.check(now); len(got) != 0 {
+		t.Fatalf("expected no alerts below threshold, got %+v", got)
+	}
+
+	m.RecordError("telegram", now)
+	m.Evaluate(now)
+	expectAlert(t, msgBus, "3 errors from telegram")
+
+	// Within the cooldown the same rule stays quiet.
+	m.RecordError("telegram", now)
+	m.Evaluate(now.Add(time.Minute))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if msg, ok := msgBus.SubscribeOutbound(ctx); ok {
+		t.Errorf("unexpected alert during cooldown: %q", msg.Content)
+	}
+}
+
+func TestMonitor_ProviderFailuresAndDisk(t *testing.T) {
+	m, msgBus := newTestMonitor(testConfig())
+	m.diskUsage = func(string) (uint64, uint64, error) { return 5, 100, nil }
+
+	m.RecordProviderFailure(nil)
+	m.RecordProviderFailure(nil)
+	m.Evaluate(time.Now())
+
+	expectAlert(t, msgBus, "2 failed LLM provider calls")
+	expectAlert(t, msgBus, "5.0% free")
+}
+
+func TestMonitor_DisabledRules(t *testing.T) {
+	cfg := testConfig()
+	cfg.ErrorRate.Enabled = false
+	cfg.Disk.Enabled = false
+	m, _ := newTestMonitor(cfg)
+	m.diskUsage = func(string) (uint64, uint64, error) { return 0, 100, nil }
+
+	for i := 0; i < 10; i++ {
+		m.RecordError("agent", time.Now())
+	}
+	if got := m.check(time.Now()); len(got) != 0 {
+		t.Errorf("expected no alerts from disabled rules, got %+v", got)
+	}
+	if n := len(m.componentErrors["agent"]); n != 0 {
+		t.Errorf("kept %d errors for a disabled rule", n)
+	}
+}
+
+func TestMonitor_RecordKeepsOnlyTheWindow(t *testing.T) {
+	m, _ := newTestMonitor(testConfig())
+	now := time.Now()
+	for i := range 100 {
+		m.RecordError("agent", now.Add(time.Duration(i-99)*time.Minute))
+	}
+	if n := len(m.componentErrors["agent"]); n != 6 {
+		t.Errorf("kept %d errors, want the 6 of the last 5 minutes", n)
+	}
+}
@@ -0,0 +1,318 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package atrest encrypts workspace files at rest. Stores register the
+// directories they keep private data in with Protect; once a key is set,
+// files written there are sealed with AES-256-GCM and transparently opened
+// again on read. Files without the sealed header are read as they are, so
+// existing plaintext workspaces keep working and are sealed on next write.
+package atrest
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// KeySize is the length of workspace keys in bytes.
+const KeySize = 32
+
+// magic prefixes every sealed file.
+var magic = []byte("picoclaw:sealed:v1\n")
+
+// ErrLocked is returned when reading a sealed file without a key.
+var ErrLocked = errors.New("file is encrypted and no key is configured")
+
+// iterations is the PBKDF2 work factor for new passphrase parameters.
+var iterations = 600_000
+
+var (
+	mu        sync.RWMutex
+	aead      cipher.AEAD
+	protected []string
+)
+
+// SetKey sets the workspace key. A nil key disables sealing; sealed files
+// can then no longer be read.
+func SetKey(key []byte) error {
+	if key == nil {
+		mu.Lock()
+		aead = nil
+		mu.Unlock()
+		return nil
+	}
+	if len(key) != KeySize {
+		return fmt.Errorf("key must be %d bytes, got %d", KeySize, len(key))
+	}
+	gcm, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	aead = gcm
+	mu.Unlock()
+	return nil
+}
+
+// Enabled reports whether a key is set.
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return aead != nil
+}
+
+// Protect marks dir as holding private data: files written there are
+// sealed while a key is set. Plaintext files already in dir are sealed
+// right away.
+func Protect(dir string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	known := false
+	for _, p := range protected {
+		if p == abs {
+			known = true
+			break
+		}
+	}
+	if !known {
+		protected = append(protected, abs)
+	}
+	mu.Unlock()
+
+	if !Enabled() {
+		return nil
+	}
+	return filepath.WalkDir(abs, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() || strings.HasSuffix(path, ".tmp") {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil || IsSealed(data) {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return WriteFile(path, data, info.Mode().Perm())
+	})
+}
+
+// Protected reports whether path is inside a protected directory.
+func Protected(path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, dir := range protected {
+		rel, err := filepath.Rel(dir, abs)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSealed reports whether data is a sealed file.
+func IsSealed(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
+
+// Encode returns data as it should be stored at path: sealed if path is
+// protected and a key is set, unchanged otherwise.
+func Encode(path string, data []byte) ([]byte, error) {
+	if !Protected(path) {
+		return data, nil
+	}
+	mu.RLock()
+	gcm := aead
+	mu.RUnlock()
+	if gcm == nil {
+		return data, nil
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(magic)+len(nonce)+len(data)+gcm.Overhead())
+	out = append(out, magic...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, data, magic), nil
+}
+
+// Decode opens sealed data. Plaintext is returned unchanged.
+func Decode(data []byte) ([]byte, error) {
+	if !IsSealed(data) {
+		return data, nil
+	}
+	mu.RLock()
+	gcm := aead
+	mu.RUnlock()
+	if gcm == nil {
+		return nil, ErrLocked
+	}
+
+	body := data[len(magic):]
+	if len(body) < gcm.NonceSize() {
+		return nil, errors.New("sealed file is truncated")
+	}
+	nonce, ciphertext := body[:gcm.NonceSize()], body[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, ciphertext, magic)
+	if err != nil {
+		return nil, errors.New("failed to decrypt file: wrong key or corrupted data")
+	}
+	return plain, nil
+}
+
+// ReadFile reads path, opening it if sealed.
+func ReadFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return plain, nil
+}
+
+// WriteFile writes data to path, sealing it if path is protected.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	out, err := Encode(path, data)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, out, perm)
+}
+
+// passphraseParams is persisted next to the workspace so the same
+// passphrase derives the same key. Check is a sealed empty value used to
+// tell a wrong passphrase apart from corrupted files.
+type passphraseParams struct {
+	Salt       []byte `json:"salt"`
+	Iterations int    `json:"iterations"`
+	Check      []byte `json:"check"`
+}
+
+// PassphraseKey derives the workspace key from passphrase with PBKDF2. The
+// salt and a key check are stored at paramsPath, created on first use.
+func PassphraseKey(paramsPath, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("empty passphrase")
+	}
+
+	var params passphraseParams
+	data, err := os.ReadFile(paramsPath)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &params); err != nil {
+			return nil, fmt.Errorf("invalid encryption parameters %s: %w", paramsPath, err)
+		}
+	case errors.Is(err, fs.ErrNotExist):
+		params.Salt = make([]byte, 16)
+		if _, err := rand.Read(params.Salt); err != nil {
+			return nil, err
+		}
+		params.Iterations = iterations
+	default:
+		return nil, err
+	}
+
+	key, err := pbkdf2.Key(sha256.New, passphrase, params.Salt, params.Iterations, KeySize)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if params.Check != nil {
+		if len(params.Check) < gcm.NonceSize() {
+			return nil, errors.New("invalid encryption parameters: bad key check")
+		}
+		nonce := params.Check[:gcm.NonceSize()]
+		if _, err := gcm.Open(nil, nonce, params.Check[gcm.NonceSize():], magic); err != nil {
+			return nil, errors.New("wrong encryption passphrase")
+		}
+		return key, nil
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	params.Check = gcm.Seal(nonce, nonce, nil, magic)
+	data, err = json.MarshalIndent(params, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(paramsPath), 0700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(paramsPath, data, 0600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// FileKey reads the workspace key from path, generating a random one with
+// owner-only permissions if it does not exist yet.
+func FileKey(path string) ([]byte, error) {
+	key, err := os.ReadFile(path)
+	if err == nil {
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("key file %s must hold %d bytes, has %d", path, KeySize, len(key))
+		}
+		return key, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	key = make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, key, 0600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,103 @@
+package atrest
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func withKey(t *testing.T) {
+	t.Helper()
+	if err := SetKey(bytes.Repeat([]byte{7}, KeySize)); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetKey(nil) })
+}
+
+func TestProtect_SealsExistingAndNewFiles(t *testing.T) {
+	withKey(t)
+	dir := t.TempDir()
+	private := filepath.Join(dir, "memory")
+	os.MkdirAll(private, 0755)
+	old := filepath.Join(private, "MEMORY.md")
+	os.WriteFile(old, []byte("likes tea"), 0644)
+
+	if err := Protect(private); err != nil {
+		t.Fatalf("Protect() error: %v", err)
+	}
+	raw, _ := os.ReadFile(old)
+	if !IsSealed(raw) || bytes.Contains(raw, []byte("tea")) {
+		t.Fatalf("existing file not sealed: %q", raw)
+	}
+	if got, err := ReadFile(old); err != nil || string(got) != "likes tea" {
+		t.Errorf("ReadFile() = %q, %v", got, err)
+	}
+
+	note := filepath.Join(private, "202601", "20260101.md")
+	os.MkdirAll(filepath.Dir(note), 0755)
+	if err := WriteFile(note, []byte("walked the dog"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if raw, _ := os.ReadFile(note); !IsSealed(raw) {
+		t.Error("new file in protected dir was not sealed")
+	}
+
+	public := filepath.Join(dir, "notes.txt")
+	WriteFile(public, []byte("plain"), 0644)
+	if raw, _ := os.ReadFile(public); string(raw) != "plain" {
+		t.Errorf("unprotected file = %q, want plaintext", raw)
+	}
+}
+
+func TestDecode_WithoutKey(t *testing.T) {
+	withKey(t)
+	dir := t.TempDir()
+	Protect(dir)
+	path := filepath.Join(dir, "s.json")
+	WriteFile(path, []byte("{}"), 0644)
+
+	SetKey(nil)
+	if _, err := ReadFile(path); !errors.Is(err, ErrLocked) {
+		t.Errorf("err = %v, want ErrLocked", err)
+	}
+	if got, err := Decode([]byte("plain")); err != nil || string(got) != "plain" {
+		t.Errorf("Decode(plaintext) = %q, %v", got, err)
+	}
+}
+
+func TestPassphraseKey(t *testing.T) {
+	orig := iterations
+	iterations = 1000
+	defer func() { iterations = orig }()
+
+	params := filepath.Join(t.TempDir(), "state", "encryption.json")
+	first, err := PassphraseKey(params, "correct horse")
+	if err != nil {
+		t.Fatalf("PassphraseKey() error: %v", err)
+	}
+	again, err := PassphraseKey(params, "correct horse")
+	if err != nil || !bytes.Equal(first, again) {
+		t.Errorf("second derivation = %x, %v; want %x", again, err, first)
+	}
+	if _, err := PassphraseKey(params, "battery staple"); err == nil {
+		t.Error("expected an error for a wrong passphrase")
+	}
+}
+
+func TestFileKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "encryption.key")
+	key, err := FileKey(path)
+	if err != nil || len(key) != KeySize {
+		t.Fatalf("FileKey() = %d bytes, %v", len(key), err)
+	}
+	if info, _ := os.Stat(path); runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("key file mode = %v, want 0600", info.Mode().Perm())
+	}
+	again, _ := FileKey(path)
+	if !bytes.Equal(key, again) {
+		t.Error("FileKey() did not reuse the existing key")
+	}
+}
@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Where KeyringStore keeps the credentials: one secret holding all of
+// them, as the JSON of an AuthStore.
+const (
+	keyringService = "picoclaw"
+	keyringAccount = "credentials"
+)
+
+// runCommand runs a program with stdin and returns its standard output
+// and, on failure, its exit code; replaced in tests.
+var runCommand = func(stdin string, name string, args ...string) (string, int, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = exitErr.Error()
+		}
+		return stdout.String(), exitErr.ExitCode(), fmt.Errorf("%s: %s", name, msg)
+	}
+	return stdout.String(), 0, err
+}
+
+// KeyringStore keeps the credentials in the OS keyring: the login keychain
+// on macOS, through the security tool, and the Secret Service (GNOME
+// Keyring, KWallet) on Linux, through secret-tool.
+type KeyringStore struct {
+	goos string
+
+	mu sync.Mutex
+}
+
+// NewKeyringStore returns the keyring store of this system, or an error if
+// it has none picoclaw can use.
+func NewKeyringStore() (*KeyringStore, error) {
+	return newKeyringStore(runtime.GOOS)
+}
+
+func newKeyringStore(goos string) (*KeyringStore, error) {
+	var tool string
+	switch goos {
+	case "darwin":
+		tool = "security"
+	case "linux":
+		tool = "secret-tool"
+	default:
+		return nil, fmt.Errorf("keyring credential store is not supported on %s", goos)
+	}
+	if _, err := exec.LookPath(tool); err != nil {
+		return nil, fmt.Errorf("keyring credential store needs %s: %w", tool, err)
+	}
+	return &KeyringStore{goos: goos}, nil
+}
+
+func (k *KeyringStore) Get(key string) (*AuthCredential, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	s, err := k.load()
+	if err != nil {
+		return nil, err
+	}
+	return s.Credentials[key], nil
+}
+
+func (k *KeyringStore) Set(key string, cred *AuthCredential) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	s, err := k.load()
+	if err != nil {
+		return err
+	}
+	s.Credentials[key] = cred
+	return k.save(s)
+}
+
+func (k *KeyringStore) Delete(key string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	s, err := k.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := s.Credentials[key]; !ok {
+		return nil
+	}
+	delete(s.Credentials, key)
+	if len(s.Credentials) == 0 {
+		return k.clear()
+	}
+	return k.save(s)
+}
+
+func (k *KeyringStore) List() (map[string]*AuthCredential, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	s, err := k.load()
+	if err != nil {
+		return nil, err
+	}
+	return s.Credentials, nil
+}
+
+func (k *KeyringStore) load() (*AuthStore, error) {
+	secret, found, err := k.read()
+	if err != nil {
+		return nil, fmt.Errorf("reading credentials from the keyring: %w", err)
+	}
+	s := &AuthStore{}
+	if found {
+		if err := json.Unmarshal([]byte(secret), s); err != nil {
+			return nil, fmt.Errorf("parsing credentials from the keyring: %w", err)
+		}
+	}
+	if s.Credentials == nil {
+		s.Credentials = make(map[string]*AuthCredential)
+	}
+	return s, nil
+}
+
+func (k *KeyringStore) save(s *AuthStore) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err := k.write(string(data)); err != nil {
+		return fmt.Errorf("saving credentials to the keyring: %w", err)
+	}
+	return nil
+}
+
+func (k *KeyringStore) read() (string, bool, error) {
+	switch k.goos {
+	case "darwin":
+		out, code, err := runCommand("", "security", "find-generic-password", "-s", keyringService, "-a", keyringAccount, "-w")
+		if code == 44 { // errSecItemNotFound
+			return "", false, nil
+		}
+		if err != nil {
+			return "", false, err
+		}
+		return strings.TrimSuffix(out, "\n"), true, nil
+	default:
+		out, code, err := runCommand("", "secret-tool", "lookup", "service", keyringService, "account", keyringAccount)
+		if code == 1 && out == "" {
+			return "", false, nil
+		}
+		if err != nil {
+			return "", false, err
+		}
+		return out, true, nil
+	}
+}
+
+func (k *KeyringStore) write(secret string) error {
+	switch k.goos {
+	case "darwin":
+		// The secret goes through security's own prompt rather than its
+		// arguments, which other processes could see.
+		cmd := fmt.Sprintf("add-generic-password -U -s %s -a %s -X %s\n", keyringService, keyringAccount, hex.EncodeToString([]byte(secret)))
+		_, _, err := runCommand(cmd, "security", "-i")
+		return err
+	default:
+		_, _, err := runCommand(secret, "secret-tool", "store", "--label=PicoClaw credentials", "service", keyringService, "account", keyringAccount)
+		return err
+	}
+}
+
+func (k *KeyringStore) clear() error {
+	switch k.goos {
+	case "darwin":
+		_, code, err := runCommand("", "security", "delete-generic-password", "-s", keyringService, "-a", keyringAccount)
+		if code == 44 {
+			return nil
+		}
+		return err
+	default:
+		_, _, err := runCommand("", "secret-tool", "clear", "service", keyringService, "account", keyringAccount)
+		return err
+	}
+}
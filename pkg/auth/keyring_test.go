@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeKeyring stands in for security and secret-tool, holding one secret.
+type fakeKeyring struct {
+	secret string
+	found  bool
+}
+
+func (f *fakeKeyring) install(t *testing.T) {
+	orig := runCommand
+	runCommand = f.run
+	t.Cleanup(func() { runCommand = orig })
+}
+
+func (f *fakeKeyring) run(stdin string, name string, args ...string) (string, int, error) {
+	switch {
+	case name == "secret-tool" && args[0] == "lookup",
+		name == "security" && args[0] == "find-generic-password":
+		if !f.found {
+			code := 1
+			if name == "security" {
+				code = 44
+			}
+			return "", code, errors.New(name + ": not found")
+		}
+		if name == "security" {
+			return f.secret + "\n", 0, nil
+		}
+		return f.secret, 0, nil
+	case name == "secret-tool" && args[0] == "store":
+		f.secret, f.found = stdin, true
+	case name == "security" && args[0] == "-i":
+		fields := strings.Fields(stdin)
+		data, err := hex.DecodeString(fields[len(fields)-1])
+		if err != nil {
+			return "", 1, err
+		}
+		f.secret, f.found = string(data), true
+	case name == "secret-tool" && args[0] == "clear",
+		name == "security" && args[0] == "delete-generic-password":
+		f.secret, f.found = "", false
+	default:
+		return "", 1, errors.New("unexpected command " + name + " " + strings.Join(args, " "))
+	}
+	return "", 0, nil
+}
+
+func TestKeyringStore(t *testing.T) {
+	for _, goos := range []string{"linux", "darwin"} {
+		t.Run(goos, func(t *testing.T) {
+			kr := &fakeKeyring{}
+			kr.install(t)
+			k := &KeyringStore{goos: goos}
+
+			if got, err := k.Get("openai"); got != nil || err != nil {
+				t.Fatalf("Get() on an empty keyring = %+v, %v", got, err)
+			}
+			if err := k.Set("openai", &AuthCredential{AccessToken: "a"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := k.Set("anthropic", &AuthCredential{AccessToken: "b"}); err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := k.Get("openai"); got == nil || got.AccessToken != "a" {
+				t.Errorf("Get() = %+v", got)
+			}
+			if creds, _ := k.List(); len(creds) != 2 {
+				t.Errorf("List() = %d credentials, want 2", len(creds))
+			}
+
+			k.Delete("openai")
+			k.Delete("anthropic")
+			if kr.found {
+				t.Error("secret left in the keyring after deleting every credential")
+			}
+		})
+	}
+}
+
+func TestNewKeyringStoreUnsupported(t *testing.T) {
+	if _, err := newKeyringStore("plan9"); err == nil {
+		t.Error("newKeyringStore(plan9) error = nil")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -224,12 +225,33 @@ func parseFlexibleInt(raw json.RawMessage) (int, error) {
 	return 0, fmt.Errorf("invalid integer value: %s", string(raw))
 }
 
+// deviceLoginTimeout bounds a device code login, from showing the code to
+// the user approving it.
+const deviceLoginTimeout = 15 * time.Minute
+
+// deviceClient makes the requests of device code logins, which run on
+// devices whose network may drop.
+var deviceClient = &http.Client{Timeout: 30 * time.Second}
+
+// deviceWait waits between polls of a device code login; replaced in tests.
+var deviceWait = time.After
+
+// errDevicePending and errDeviceSlowDown are the poll results of a login
+// the user has not approved yet.
+var (
+	errDevicePending  = errors.New("authorization pending")
+	errDeviceSlowDown = errors.New("polling too fast")
+)
+
+// LoginDeviceCode logs in without a browser on this machine: it shows a URL
+// and a code to enter there from any other device, and waits for the user
+// to approve the login.
 func LoginDeviceCode(cfg OAuthProviderConfig) (*AuthCredential, error) {
 	reqBody, _ := json.Marshal(map[string]string{
 		"client_id": cfg.ClientID,
 	})
 
-	resp, err := http.Post(
+	resp, err := deviceClient.Post(
 		cfg.Issuer+"/api/accounts/deviceauth/usercode",
 		"application/json",
 		strings.NewReader(string(reqBody)),
@@ -253,25 +275,35 @@ func LoginDeviceCode(cfg OAuthProviderConfig) (*AuthCredential, error) {
 		deviceResp.Interval = 5
 	}
 
-	fmt.Printf("\nTo authenticate, open this URL in your browser:\n\n  %s/codex/device\n\nThen enter this code: %s\n\nWaiting for authentication...\n",
+	fmt.Printf("\nTo authenticate, open this URL in a browser on any device:\n\n  %s/codex/device\n\nThen enter this code: %s\n\nWaiting for authentication...\n",
 		cfg.Issuer, deviceResp.UserCode)
 
-	deadline := time.After(15 * time.Minute)
-	ticker := time.NewTicker(time.Duration(deviceResp.Interval) * time.Second)
-	defer ticker.Stop()
+	return pollDeviceLogin(cfg, deviceResp)
+}
 
+// pollDeviceLogin polls until the user approves the login, slowing down
+// when the server asks to. Network errors are retried, since the login is
+// usually approved from another device while this one waits; a rejected
+// or expired login ends it.
+func pollDeviceLogin(cfg OAuthProviderConfig, deviceResp deviceCodeResponse) (*AuthCredential, error) {
+	interval := time.Duration(deviceResp.Interval) * time.Second
+	deadline := time.Now().Add(deviceLoginTimeout)
 	for {
-		select {
-		case <-deadline:
-			return nil, fmt.Errorf("device code authentication timed out after 15 minutes")
-		case <-ticker.C:
-			cred, err := pollDeviceCode(cfg, deviceResp.DeviceAuthID, deviceResp.UserCode)
-			if err != nil {
-				continue
-			}
-			if cred != nil {
-				return cred, nil
-			}
+		<-deviceWait(interval)
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("device code authentication timed out after %s", deviceLoginTimeout)
+		}
+
+		cred, err := pollDeviceCode(cfg, deviceResp.DeviceAuthID, deviceResp.UserCode)
+		var netErr net.Error
+		switch {
+		case err == nil:
+			return cred, nil
+		case errors.Is(err, errDevicePending), errors.As(err, &netErr):
+		case errors.Is(err, errDeviceSlowDown):
+			interval += 5 * time.Second
+		default:
+			return nil, err
 		}
 	}
 }
@@ -282,7 +314,7 @@ func pollDeviceCode(cfg OAuthProviderConfig, deviceAuthID, userCode string) (*Au
 		"user_code":      userCode,
 	})
 
-	resp, err := http.Post(
+	resp, err := deviceClient.Post(
 		cfg.Issuer+"/api/accounts/deviceauth/token",
 		"application/json",
 		strings.NewReader(string(reqBody)),
@@ -292,11 +324,17 @@ func pollDeviceCode(cfg OAuthProviderConfig, deviceAuthID, userCode string) (*Au
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("pending")
-	}
-
 	body, _ := io.ReadAll(resp.Body)
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusForbidden, http.StatusNotFound:
+		// Not approved yet.
+		return nil, errDevicePending
+	case http.StatusTooManyRequests:
+		return nil, errDeviceSlowDown
+	default:
+		return nil, fmt.Errorf("device code login failed: %s", strings.TrimSpace(string(body)))
+	}
 
 	var tokenResp struct {
 		AuthorizationCode string `json:"authorization_code"`
@@ -542,6 +580,24 @@ func base64URLDecode(s string) ([]byte, error) {
 	return base64.StdEncoding.DecodeString(s)
 }
 
+// CanOpenBrowser reports whether this machine seems to have a browser
+// picoclaw can open for a login: not over SSH without a display, or on a
+// board without a desktop.
+func CanOpenBrowser() bool {
+	switch runtime.GOOS {
+	case "darwin", "windows":
+		return os.Getenv("SSH_CONNECTION") == ""
+	case "linux":
+		if os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == "" {
+			return false
+		}
+		_, err := exec.LookPath("xdg-open")
+		return err == nil
+	default:
+		return false
+	}
+}
+
 func openBrowser(url string) error {
 	switch runtime.GOOS {
 	case "darwin":
@@ -7,7 +7,9 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func makeJWTForClaims(t *testing.T, claims map[string]interface{}) string {
@@ -371,3 +373,69 @@ func TestParseDeviceCodeResponseInvalidInterval(t *testing.T) {
 		t.Fatal("expected error for invalid interval")
 	}
 }
+
+// deviceServer fakes the device code endpoints, answering the polls for
+// the token with statuses until it runs out, then approving the login.
+func deviceServer(t *testing.T, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var polls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/accounts/deviceauth/usercode":
+			w.Write([]byte(`{"device_auth_id":"dev-1","user_code":"ABCD-1234","interval":"1"}`))
+		case "/api/accounts/deviceauth/token":
+			n := int(polls.Add(1))
+			if n <= len(statuses) {
+				http.Error(w, "status", statuses[n-1])
+				return
+			}
+			w.Write([]byte(`{"authorization_code":"code-1","code_verifier":"verifier-1"}`))
+		case "/oauth/token":
+			r.ParseForm()
+			if r.FormValue("code") != "code-1" || r.FormValue("code_verifier") != "verifier-1" {
+				http.Error(w, "bad code", http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"access_token":"device-token","refresh_token":"device-refresh","expires_in":3600}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	orig := deviceWait
+	deviceWait = func(time.Duration) <-chan time.Time {
+		ch := make(chan time.Time, 1)
+		ch <- time.Now()
+		return ch
+	}
+	t.Cleanup(func() { deviceWait = orig })
+	return server, &polls
+}
+
+func TestLoginDeviceCode(t *testing.T) {
+	server, polls := deviceServer(t, http.StatusForbidden, http.StatusTooManyRequests, http.StatusNotFound)
+
+	cred, err := LoginDeviceCode(OAuthProviderConfig{Issuer: server.URL, ClientID: "test-client"})
+	if err != nil {
+		t.Fatalf("LoginDeviceCode() error: %v", err)
+	}
+	if cred.AccessToken != "device-token" || cred.RefreshToken != "device-refresh" {
+		t.Errorf("credential = %+v", cred)
+	}
+	if n := polls.Load(); n != 4 {
+		t.Errorf("polled %d times, want 4", n)
+	}
+}
+
+func TestLoginDeviceCodeRejected(t *testing.T) {
+	server, polls := deviceServer(t, http.StatusForbidden, http.StatusBadRequest)
+
+	_, err := LoginDeviceCode(OAuthProviderConfig{Issuer: server.URL, ClientID: "test-client"})
+	if err == nil || !strings.Contains(err.Error(), "device code login failed") {
+		t.Fatalf("LoginDeviceCode() error = %v, want the login to fail", err)
+	}
+	if n := polls.Load(); n != 2 {
+		t.Errorf("polled %d times after the rejection, want it to stop at 2", n)
+	}
+}
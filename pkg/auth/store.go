@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 )
 
@@ -16,6 +17,9 @@ type AuthCredential struct {
 	AuthMethod   string    `json:"auth_method"`
 	Email        string    `json:"email,omitempty"`
 	ProjectID    string    `json:"project_id,omitempty"`
+	// Account is the label the credential was stored under, for providers
+	// with several accounts logged in.
+	Account string `json:"account,omitempty"`
 }
 
 type AuthStore struct {
@@ -41,8 +45,108 @@ func authFilePath() string {
 	return filepath.Join(home, ".picoclaw", "auth.json")
 }
 
+// CredentialStore keeps the credentials of the providers logged in to, by
+// the keys of AccountKey. Get returns nil, and no error, for a key without
+// a credential.
+type CredentialStore interface {
+	Get(key string) (*AuthCredential, error)
+	Set(key string, cred *AuthCredential) error
+	Delete(key string) error
+	List() (map[string]*AuthCredential, error)
+}
+
+var (
+	storeMu sync.RWMutex
+	current CredentialStore = &FileStore{}
+)
+
+// SetCredentialStore makes s the store behind GetCredential,
+// SetCredential and the token sources; nil restores the file store. It is
+// meant to be called at startup, before credentials are read, by picoclaw
+// for the configured store or by a program embedding it for its own.
+func SetCredentialStore(s CredentialStore) {
+	if s == nil {
+		s = &FileStore{}
+	}
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	current = s
+}
+
+func credentialStore() CredentialStore {
+	storeMu.RLock()
+	defer storeMu.RUnlock()
+	return current
+}
+
+// FileStore keeps the credentials in a JSON file readable only by its
+// owner, ~/.picoclaw/auth.json unless Path is set.
+type FileStore struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+func (f *FileStore) path() string {
+	if f.Path != "" {
+		return f.Path
+	}
+	return authFilePath()
+}
+
+func (f *FileStore) Get(key string) (*AuthCredential, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, err := loadStoreFile(f.path())
+	if err != nil {
+		return nil, err
+	}
+	return s.Credentials[key], nil
+}
+
+func (f *FileStore) Set(key string, cred *AuthCredential) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, err := loadStoreFile(f.path())
+	if err != nil {
+		return err
+	}
+	s.Credentials[key] = cred
+	return saveStoreFile(f.path(), s)
+}
+
+func (f *FileStore) Delete(key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, err := loadStoreFile(f.path())
+	if err != nil {
+		return err
+	}
+	delete(s.Credentials, key)
+	return saveStoreFile(f.path(), s)
+}
+
+func (f *FileStore) List() (map[string]*AuthCredential, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, err := loadStoreFile(f.path())
+	if err != nil {
+		return nil, err
+	}
+	return s.Credentials, nil
+}
+
+// LoadStore reads the credentials file, ~/.picoclaw/auth.json.
 func LoadStore() (*AuthStore, error) {
-	path := authFilePath()
+	return loadStoreFile(authFilePath())
+}
+
+// SaveStore writes the credentials file, ~/.picoclaw/auth.json.
+func SaveStore(store *AuthStore) error {
+	return saveStoreFile(authFilePath(), store)
+}
+
+func loadStoreFile(path string) (*AuthStore, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -61,8 +165,7 @@ func LoadStore() (*AuthStore, error) {
 	return &store, nil
 }
 
-func SaveStore(store *AuthStore) error {
-	path := authFilePath()
+func saveStoreFile(path string, store *AuthStore) error {
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return err
@@ -75,40 +178,51 @@ func SaveStore(store *AuthStore) error {
 	return os.WriteFile(path, data, 0600)
 }
 
-func GetCredential(provider string) (*AuthCredential, error) {
-	store, err := LoadStore()
-	if err != nil {
-		return nil, err
-	}
-	cred, ok := store.Credentials[provider]
-	if !ok {
-		return nil, nil
+// AccountKey returns the key the credential of a provider account is
+// stored under: the provider name, or "provider:account" for a labelled
+// account such as "anthropic:work".
+func AccountKey(provider, account string) string {
+	if account == "" {
+		return provider
 	}
-	return cred, nil
+	return provider + ":" + account
+}
+
+// GetCredential returns the credential stored under key, the provider
+// name or an AccountKey, or nil if there is none.
+func GetCredential(provider string) (*AuthCredential, error) {
+	return credentialStore().Get(provider)
 }
 
 func SetCredential(provider string, cred *AuthCredential) error {
-	store, err := LoadStore()
-	if err != nil {
-		return err
-	}
-	store.Credentials[provider] = cred
-	return SaveStore(store)
+	return credentialStore().Set(provider, cred)
 }
 
 func DeleteCredential(provider string) error {
-	store, err := LoadStore()
-	if err != nil {
-		return err
-	}
-	delete(store.Credentials, provider)
-	return SaveStore(store)
+	return credentialStore().Delete(provider)
+}
+
+// ListCredentials returns the stored credentials by key.
+func ListCredentials() (map[string]*AuthCredential, error) {
+	return credentialStore().List()
 }
 
 func DeleteAllCredentials() error {
-	path := authFilePath()
-	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+	s := credentialStore()
+	if f, ok := s.(*FileStore); ok {
+		if err := os.Remove(f.path()); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	creds, err := s.List()
+	if err != nil {
 		return err
 	}
+	for key := range creds {
+		if err := s.Delete(key); err != nil {
+			return err
+		}
+	}
 	return nil
 }
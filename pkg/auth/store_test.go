@@ -3,6 +3,7 @@ package auth
 import (
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 )
@@ -187,3 +188,126 @@ func TestLoadStoreEmpty(t *testing.T) {
 		t.Errorf("expected empty credentials, got %d", len(store.Credentials))
 	}
 }
+
+func TestStoreAccounts(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	for _, account := range []string{"", "work", "personal"} {
+		cred := &AuthCredential{AccessToken: "token-" + account, Provider: "anthropic", AuthMethod: "token", Account: account}
+		if err := SetCredential(AccountKey("anthropic", account), cred); err != nil {
+			t.Fatalf("SetCredential(%q) error: %v", account, err)
+		}
+	}
+
+	for _, account := range []string{"", "work", "personal"} {
+		got, err := GetCredential(AccountKey("anthropic", account))
+		if err != nil || got == nil || got.AccessToken != "token-"+account {
+			t.Errorf("account %q: credential = %+v, err = %v", account, got, err)
+		}
+	}
+
+	if err := DeleteCredential(AccountKey("anthropic", "work")); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := GetCredential("anthropic:work"); got != nil {
+		t.Error("work credential still stored after delete")
+	}
+	if got, _ := GetCredential("anthropic"); got == nil {
+		t.Error("deleting an account removed the default credential")
+	}
+}
+
+// memStore is a CredentialStore in memory, for tests.
+type memStore struct {
+	mu    sync.Mutex
+	creds map[string]*AuthCredential
+}
+
+func newMemStore(t *testing.T) *memStore {
+	s := &memStore{creds: make(map[string]*AuthCredential)}
+	SetCredentialStore(s)
+	t.Cleanup(func() { SetCredentialStore(nil) })
+	return s
+}
+
+func (s *memStore) Get(key string) (*AuthCredential, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.creds[key]; ok {
+		cp := *c
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (s *memStore) Set(key string, cred *AuthCredential) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cp := *cred
+	s.creds[key] = &cp
+	return nil
+}
+
+func (s *memStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.creds, key)
+	return nil
+}
+
+func (s *memStore) List() (map[string]*AuthCredential, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]*AuthCredential, len(s.creds))
+	for k, c := range s.creds {
+		cp := *c
+		out[k] = &cp
+	}
+	return out, nil
+}
+
+func TestCustomCredentialStore(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	s := newMemStore(t)
+
+	if err := SetCredential("openai", &AuthCredential{AccessToken: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetCredential("anthropic:work", &AuthCredential{AccessToken: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := GetCredential("openai"); got == nil || got.AccessToken != "a" {
+		t.Errorf("GetCredential() = %+v, want the custom store's credential", got)
+	}
+	if creds, _ := ListCredentials(); len(creds) != 2 {
+		t.Errorf("ListCredentials() = %d credentials, want 2", len(creds))
+	}
+	if _, err := os.Stat(filepath.Join(home, ".picoclaw", "auth.json")); !os.IsNotExist(err) {
+		t.Error("credentials written to auth.json with a custom store set")
+	}
+
+	if err := DeleteAllCredentials(); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.creds) != 0 {
+		t.Errorf("%d credentials left after DeleteAllCredentials()", len(s.creds))
+	}
+}
+
+func TestFileStorePath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "creds.json")
+	f := &FileStore{Path: path}
+	if err := f.Set("openai", &AuthCredential{AccessToken: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("credentials file not written at Path: %v", err)
+	}
+	if got, _ := f.Get("openai"); got == nil || got.AccessToken != "a" {
+		t.Errorf("Get() = %+v", got)
+	}
+	if got, _ := f.Get("missing"); got != nil {
+		t.Errorf("Get(missing) = %+v, want nil", got)
+	}
+}
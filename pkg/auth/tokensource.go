@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// RefreshFunc exchanges a credential for a fresh one, such as
+// RefreshAccessToken with the provider's OAuth configuration.
+type RefreshFunc func(cred *AuthCredential) (*AuthCredential, error)
+
+// Refresh margins: a token is refreshed when it expires within
+// refreshMargin plus a random part of refreshJitter, so that processes
+// sharing a credential do not all refresh it at the same moment.
+const (
+	refreshMargin = 5 * time.Minute
+	refreshJitter = 2 * time.Minute
+)
+
+// refreshLocks serialises the refreshes of each stored credential, across
+// all the token sources of the process.
+var refreshLocks sync.Map // key -> *sync.Mutex
+
+func refreshLock(key string) *sync.Mutex {
+	mu, _ := refreshLocks.LoadOrStore(key, &sync.Mutex{})
+	return mu.(*sync.Mutex)
+}
+
+// TokenSource hands out the credential stored under a key, refreshing it
+// before it expires.
+type TokenSource struct {
+	key     string
+	refresh RefreshFunc
+	margin  time.Duration
+}
+
+// NewTokenSource returns the token source of the credential stored under
+// key. refresh may be nil for credentials that cannot be refreshed.
+func NewTokenSource(key string, refresh RefreshFunc) *TokenSource {
+	return &TokenSource{
+		key:     key,
+		refresh: refresh,
+		margin:  refreshMargin + rand.N(refreshJitter),
+	}
+}
+
+// Credential returns the current credential, or nil if none is stored.
+// A credential about to expire is refreshed and stored first; callers
+// asking at the same time wait for that one refresh rather than starting
+// their own. If the refresh fails while the old token is still valid, the
+// old token is used and the refresh retried on the next call.
+func (s *TokenSource) Credential() (*AuthCredential, error) {
+	cred, err := GetCredential(s.key)
+	if err != nil || cred == nil || !s.needsRefresh(cred) {
+		return cred, err
+	}
+
+	mu := refreshLock(s.key)
+	mu.Lock()
+	defer mu.Unlock()
+
+	// Another caller may have refreshed it while this one waited.
+	cred, err = GetCredential(s.key)
+	if err != nil || cred == nil || !s.needsRefresh(cred) {
+		return cred, err
+	}
+
+	refreshed, err := s.refresh(cred)
+	if err != nil {
+		if !cred.IsExpired() {
+			logger.WarnCF("auth", "Token refresh failed, using the current token", map[string]interface{}{
+				"credential": s.key,
+				"expires_at": cred.ExpiresAt,
+				"error":      err.Error(),
+			})
+			return cred, nil
+		}
+		return nil, fmt.Errorf("refreshing token: %w", err)
+	}
+
+	if refreshed.RefreshToken == "" {
+		refreshed.RefreshToken = cred.RefreshToken
+	}
+	if refreshed.AccountID == "" {
+		refreshed.AccountID = cred.AccountID
+	}
+	if refreshed.Email == "" {
+		refreshed.Email = cred.Email
+	}
+	if refreshed.ProjectID == "" {
+		refreshed.ProjectID = cred.ProjectID
+	}
+	refreshed.Account = cred.Account
+	if err := SetCredential(s.key, refreshed); err != nil {
+		return nil, fmt.Errorf("saving refreshed token: %w", err)
+	}
+	return refreshed, nil
+}
+
+func (s *TokenSource) needsRefresh(cred *AuthCredential) bool {
+	if s.refresh == nil || cred.RefreshToken == "" || cred.ExpiresAt.IsZero() {
+		return false
+	}
+	return time.Now().Add(s.margin).After(cred.ExpiresAt)
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTokenSource_NoRefreshNeeded(t *testing.T) {
+	s := newMemStore(t)
+	s.Set("openai", &AuthCredential{AccessToken: "old", RefreshToken: "r", ExpiresAt: time.Now().Add(time.Hour)})
+
+	ts := NewTokenSource("openai", func(*AuthCredential) (*AuthCredential, error) {
+		t.Fatal("refreshed a credential far from expiry")
+		return nil, nil
+	})
+	cred, err := ts.Credential()
+	if err != nil || cred == nil || cred.AccessToken != "old" {
+		t.Fatalf("Credential() = %+v, %v", cred, err)
+	}
+}
+
+func TestTokenSource_Missing(t *testing.T) {
+	newMemStore(t)
+	cred, err := NewTokenSource("openai", nil).Credential()
+	if cred != nil || err != nil {
+		t.Errorf("Credential() = %+v, %v; want nil, nil", cred, err)
+	}
+}
+
+func TestTokenSource_RefreshKeepsIdentity(t *testing.T) {
+	s := newMemStore(t)
+	s.Set("google-antigravity:work", &AuthCredential{
+		AccessToken:  "old",
+		RefreshToken: "r",
+		ExpiresAt:    time.Now().Add(time.Minute),
+		Email:        "me@example.com",
+		ProjectID:    "proj",
+		Account:      "work",
+	})
+
+	ts := NewTokenSource("google-antigravity:work", func(cred *AuthCredential) (*AuthCredential, error) {
+		return &AuthCredential{AccessToken: "new", ExpiresAt: time.Now().Add(time.Hour)}, nil
+	})
+	cred, err := ts.Credential()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cred.AccessToken != "new" || cred.RefreshToken != "r" || cred.Email != "me@example.com" || cred.ProjectID != "proj" || cred.Account != "work" {
+		t.Errorf("refreshed credential = %+v", cred)
+	}
+	if stored, _ := s.Get("google-antigravity:work"); stored.AccessToken != "new" {
+		t.Errorf("stored token = %q, want the refreshed one", stored.AccessToken)
+	}
+}
+
+func TestTokenSource_ConcurrentRefreshesOnce(t *testing.T) {
+	s := newMemStore(t)
+	s.Set("openai", &AuthCredential{AccessToken: "old", RefreshToken: "r", ExpiresAt: time.Now().Add(time.Minute)})
+
+	var calls atomic.Int32
+	refresh := func(*AuthCredential) (*AuthCredential, error) {
+		calls.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		return &AuthCredential{AccessToken: "new", ExpiresAt: time.Now().Add(time.Hour)}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Separate sources share the credential, as two model_list
+			// entries on one account do.
+			cred, err := NewTokenSource("openai", refresh).Credential()
+			if err != nil || cred.AccessToken != "new" {
+				t.Errorf("Credential() = %+v, %v", cred, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := calls.Load(); n != 1 {
+		t.Errorf("refreshed %d times, want once", n)
+	}
+}
+
+func TestTokenSource_RefreshFailure(t *testing.T) {
+	s := newMemStore(t)
+	failing := func(*AuthCredential) (*AuthCredential, error) { return nil, errors.New("token endpoint down") }
+
+	s.Set("openai", &AuthCredential{AccessToken: "old", RefreshToken: "r", ExpiresAt: time.Now().Add(time.Minute)})
+	cred, err := NewTokenSource("openai", failing).Credential()
+	if err != nil || cred.AccessToken != "old" {
+		t.Errorf("still valid: Credential() = %+v, %v; want the old token", cred, err)
+	}
+
+	s.Set("openai", &AuthCredential{AccessToken: "old", RefreshToken: "r", ExpiresAt: time.Now().Add(-time.Minute)})
+	if _, err := NewTokenSource("openai", failing).Credential(); err == nil {
+		t.Error("expired: Credential() error = nil, want the refresh error")
+	}
+}
+
+func TestTokenSource_Jitter(t *testing.T) {
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 20; i++ {
+		m := NewTokenSource("openai", nil).margin
+		if m < refreshMargin || m >= refreshMargin+refreshJitter {
+			t.Fatalf("margin = %s, want within [%s, %s)", m, refreshMargin, refreshMargin+refreshJitter)
+		}
+		seen[m] = true
+	}
+	if len(seen) < 2 {
+		t.Error("every token source got the same refresh margin")
+	}
+}
@@ -6,11 +6,13 @@ import (
 )
 
 type MessageBus struct {
-	inbound  chan InboundMessage
-	outbound chan OutboundMessage
-	handlers map[string]MessageHandler
-	closed   bool
-	mu       sync.RWMutex
+	inbound      chan InboundMessage
+	outbound     chan OutboundMessage
+	handlers     map[string]MessageHandler
+	interceptors []InboundInterceptor
+	reactions    []ReactionHandler
+	closed       bool
+	mu           sync.RWMutex
 }
 
 func NewMessageBus() *MessageBus {
@@ -27,9 +29,44 @@ func (mb *MessageBus) PublishInbound(msg InboundMessage) {
 	if mb.closed {
 		return
 	}
+	for _, intercept := range mb.interceptors {
+		if intercept(msg) {
+			return
+		}
+	}
 	mb.inbound <- msg
 }
 
+// Intercept registers fn to see every inbound message before it is queued.
+// Messages fn consumes are not delivered to consumers. Interceptors run on
+// the publishing goroutine, so they see replies even while the consumer is
+// busy.
+func (mb *MessageBus) Intercept(fn InboundInterceptor) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	mb.interceptors = append(mb.interceptors, fn)
+}
+
+// OnReaction registers fn to receive reactions to replies.
+func (mb *MessageBus) OnReaction(fn ReactionHandler) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	mb.reactions = append(mb.reactions, fn)
+}
+
+// PublishReaction passes r to the reaction handlers on the publishing
+// goroutine. Reactions are not queued, so they never wait behind messages.
+func (mb *MessageBus) PublishReaction(r Reaction) {
+	mb.mu.RLock()
+	defer mb.mu.RUnlock()
+	if mb.closed {
+		return
+	}
+	for _, fn := range mb.reactions {
+		fn(r)
+	}
+}
+
 func (mb *MessageBus) ConsumeInbound(ctx context.Context) (InboundMessage, bool) {
 	select {
 	case msg := <-mb.inbound:
@@ -70,6 +107,12 @@ func (mb *MessageBus) GetHandler(channel string) (MessageHandler, bool) {
 	return handler, ok
 }
 
+// Pending returns how many inbound and outbound messages are queued and
+// not consumed yet.
+func (mb *MessageBus) Pending() (inbound, outbound int) {
+	return len(mb.inbound), len(mb.outbound)
+}
+
 func (mb *MessageBus) Close() {
 	mb.mu.Lock()
 	defer mb.mu.Unlock()
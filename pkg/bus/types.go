@@ -14,6 +14,49 @@ type OutboundMessage struct {
 	Channel string `json:"channel"`
 	ChatID  string `json:"chat_id"`
 	Content string `json:"content"`
+	// Proactive marks messages the user did not ask for (heartbeat, cron,
+	// alerts). They are subject to per-channel working hours.
+	Proactive bool `json:"proactive,omitempty"`
+	// Partial carries a reply that is still being generated: the text so
+	// far, superseded by later messages for the chat and finally by the
+	// complete reply. Channels that cannot update a message never see it.
+	Partial bool `json:"partial,omitempty"`
+	// TurnID identifies the agent turn a reply answers, so that channels
+	// can attribute reactions to it. It is empty for other messages.
+	TurnID string `json:"turn_id,omitempty"`
+	// Actions are choices offered with the reply, which channels with an
+	// ActionSchema show as buttons.
+	Actions []Action `json:"actions,omitempty"`
 }
 
+// Action is a choice offered with a reply. Choosing it sends Value, or
+// Label if Value is empty, to the agent as a message from the user.
+type Action struct {
+	Label string `json:"label"`
+	Value string `json:"value,omitempty"`
+}
+
+// ActionSchema describes the actions a channel can show with a reply.
+type ActionSchema struct {
+	MaxActions    int `json:"max_actions"`
+	MaxLabelRunes int `json:"max_label_runes"`
+}
+
+// Reaction is an emoji reaction a user added to or removed from a reply of
+// the agent.
+type Reaction struct {
+	Channel  string `json:"channel"`
+	ChatID   string `json:"chat_id"`
+	SenderID string `json:"sender_id"`
+	TurnID   string `json:"turn_id"`
+	Emoji    string `json:"emoji"`
+	Removed  bool   `json:"removed,omitempty"`
+}
+
+type ReactionHandler func(Reaction)
+
 type MessageHandler func(InboundMessage) error
+
+// InboundInterceptor inspects an inbound message and reports whether it
+// consumed it.
+type InboundInterceptor func(InboundMessage) bool
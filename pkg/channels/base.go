@@ -2,7 +2,9 @@ package channels
 
 import (
 	"context"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
 )
@@ -16,12 +18,47 @@ type Channel interface {
 	IsAllowed(senderID string) bool
 }
 
+// PartialSender is implemented by channels that can show a reply while it
+// is being generated, typically by editing one message in place.
+type PartialSender interface {
+	SendPartial(ctx context.Context, msg bus.OutboundMessage) error
+}
+
+// ActionChannel is implemented by channels that can show the actions of a
+// reply, such as buttons, and pass the chosen one back with HandleAction.
+type ActionChannel interface {
+	Channel
+	ActionSchema() bus.ActionSchema
+}
+
+const (
+	// maxTrackedReplies is how many recent replies a channel remembers to
+	// attribute reactions to.
+	maxTrackedReplies = 500
+	// maxTrackedActions is how many recently shown actions can be chosen.
+	maxTrackedActions = 500
+)
+
 type BaseChannel struct {
 	config    interface{}
 	bus       *bus.MessageBus
 	running   bool
 	name      string
 	allowList []string
+
+	repliesMu    sync.Mutex
+	replies      map[string]string // chat ID + message ID -> turn ID
+	repliesOrder []string
+
+	actionsMu    sync.Mutex
+	actions      map[string]trackedAction // token -> action
+	actionsOrder []string
+	nextAction   uint64
+}
+
+type trackedAction struct {
+	chatID string
+	action bus.Action
 }
 
 func NewBaseChannel(name string, config interface{}, bus *bus.MessageBus, allowList []string) *BaseChannel {
@@ -98,6 +135,100 @@ func (c *BaseChannel) HandleMessage(senderID, chatID, content string, media []st
 	c.bus.PublishInbound(msg)
 }
 
+// TrackReply remembers that message messageID in chatID carries a reply of
+// turn turnID, so that reactions to it can be attributed to the turn.
+func (c *BaseChannel) TrackReply(chatID, messageID, turnID string) {
+	if turnID == "" || messageID == "" {
+		return
+	}
+	key := chatID + "\x00" + messageID
+
+	c.repliesMu.Lock()
+	defer c.repliesMu.Unlock()
+	if c.replies == nil {
+		c.replies = make(map[string]string)
+	}
+	if _, ok := c.replies[key]; !ok {
+		c.repliesOrder = append(c.repliesOrder, key)
+	}
+	c.replies[key] = turnID
+	if len(c.repliesOrder) > maxTrackedReplies {
+		delete(c.replies, c.repliesOrder[0])
+		c.repliesOrder = c.repliesOrder[1:]
+	}
+}
+
+// HandleReaction publishes a reaction of senderID to message messageID in
+// chatID. Reactions to messages other than tracked replies are ignored.
+func (c *BaseChannel) HandleReaction(senderID, chatID, messageID, emoji string, removed bool) {
+	if emoji == "" || !c.IsAllowed(senderID) {
+		return
+	}
+
+	c.repliesMu.Lock()
+	turnID, ok := c.replies[chatID+"\x00"+messageID]
+	c.repliesMu.Unlock()
+	if !ok {
+		return
+	}
+
+	c.bus.PublishReaction(bus.Reaction{
+		Channel:  c.name,
+		ChatID:   chatID,
+		SenderID: senderID,
+		TurnID:   turnID,
+		Emoji:    emoji,
+		Removed:  removed,
+	})
+}
+
+// TrackActions remembers actions shown in chatID and returns a short token
+// for each, for the channel to identify the chosen one by.
+func (c *BaseChannel) TrackActions(chatID string, actions []bus.Action) []string {
+	c.actionsMu.Lock()
+	defer c.actionsMu.Unlock()
+	if c.actions == nil {
+		c.actions = make(map[string]trackedAction)
+	}
+
+	tokens := make([]string, len(actions))
+	for i, a := range actions {
+		c.nextAction++
+		token := "a" + strconv.FormatUint(c.nextAction, 36)
+		c.actions[token] = trackedAction{chatID: chatID, action: a}
+		c.actionsOrder = append(c.actionsOrder, token)
+		tokens[i] = token
+	}
+	for len(c.actionsOrder) > maxTrackedActions {
+		delete(c.actions, c.actionsOrder[0])
+		c.actionsOrder = c.actionsOrder[1:]
+	}
+	return tokens
+}
+
+// HandleAction publishes the choice by senderID of the action with token
+// in chatID as a message. It reports whether the action was known; actions
+// shown long ago or before a restart are not.
+func (c *BaseChannel) HandleAction(senderID, chatID, token string, metadata map[string]string) bool {
+	c.actionsMu.Lock()
+	tracked, ok := c.actions[token]
+	c.actionsMu.Unlock()
+	if !ok || tracked.chatID != chatID {
+		return false
+	}
+
+	content := tracked.action.Value
+	if content == "" {
+		content = tracked.action.Label
+	}
+	if metadata == nil {
+		metadata = make(map[string]string)
+	}
+	metadata["action"] = tracked.action.Label
+	c.HandleMessage(senderID, chatID, content, nil, metadata)
+	return true
+}
+
 func (c *BaseChannel) setRunning(running bool) {
 	c.running = running
 }
@@ -1,6 +1,13 @@
 package channels
 
-import "testing"
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
 
 func TestBaseChannelIsAllowed(t *testing.T) {
 	tests := []struct {
@@ -50,3 +57,60 @@ func TestBaseChannelIsAllowed(t *testing.T) {
 		})
 	}
 }
+
+func TestBaseChannelHandleReaction(t *testing.T) {
+	mb := bus.NewMessageBus()
+	var got []bus.Reaction
+	mb.OnReaction(func(r bus.Reaction) { got = append(got, r) })
+
+	ch := NewBaseChannel("test", nil, mb, []string{"alice"})
+	ch.TrackReply("chat", "m1", "turn-1")
+
+	ch.HandleReaction("alice", "chat", "m1", "👍", false)
+	ch.HandleReaction("alice", "chat", "m2", "👍", false)   // not a reply
+	ch.HandleReaction("mallory", "chat", "m1", "👎", false) // not allowed
+	ch.HandleReaction("alice", "chat", "m1", "👍", true)
+
+	if len(got) != 2 || got[0].TurnID != "turn-1" || got[0].Emoji != "👍" || got[0].Removed || !got[1].Removed {
+		t.Fatalf("reactions = %+v", got)
+	}
+}
+
+func TestBaseChannelTrackReply_Bounded(t *testing.T) {
+	ch := NewBaseChannel("test", nil, nil, nil)
+	for i := 0; i < maxTrackedReplies+10; i++ {
+		ch.TrackReply("chat", strconv.Itoa(i), "turn")
+	}
+	if len(ch.replies) != maxTrackedReplies {
+		t.Fatalf("tracked %d replies, want %d", len(ch.replies), maxTrackedReplies)
+	}
+	if _, ok := ch.replies["chat\x00"+"0"]; ok {
+		t.Error("oldest reply should have been forgotten")
+	}
+}
+
+func TestBaseChannelHandleAction(t *testing.T) {
+	mb := bus.NewMessageBus()
+	ch := NewBaseChannel("test", nil, mb, nil)
+	tokens := ch.TrackActions("chat", []bus.Action{{Label: "Yes"}, {Label: "Later", Value: "Remind me tomorrow"}})
+	if len(tokens) != 2 || tokens[0] == tokens[1] {
+		t.Fatalf("tokens = %v", tokens)
+	}
+
+	if ch.HandleAction("alice", "other-chat", tokens[1], nil) {
+		t.Error("action was accepted from another chat")
+	}
+	if ch.HandleAction("alice", "chat", "unknown", nil) {
+		t.Error("unknown action was accepted")
+	}
+	if !ch.HandleAction("alice", "chat", tokens[1], map[string]string{"peer_kind": "direct"}) {
+		t.Fatal("HandleAction() = false for a shown action")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msg, ok := mb.ConsumeInbound(ctx)
+	if !ok || msg.Content != "Remind me tomorrow" || msg.Metadata["action"] != "Later" || msg.Metadata["peer_kind"] != "direct" {
+		t.Fatalf("inbound = %+v", msg)
+	}
+}
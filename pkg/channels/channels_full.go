@@ -0,0 +1,33 @@
+//go:build !minimal && !standard
+
+package channels
+
+import (
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// Channels included only in the full build profile.
+func init() {
+	registerChannel("feishu", func(cfg *config.Config, b *bus.MessageBus) (Channel, error) {
+		return NewFeishuChannel(cfg.Channels.Feishu, b)
+	})
+	registerChannel("qq", func(cfg *config.Config, b *bus.MessageBus) (Channel, error) {
+		return NewQQChannel(cfg.Channels.QQ, b)
+	})
+	registerChannel("dingtalk", func(cfg *config.Config, b *bus.MessageBus) (Channel, error) {
+		return NewDingTalkChannel(cfg.Channels.DingTalk, b)
+	})
+	registerChannel("line", func(cfg *config.Config, b *bus.MessageBus) (Channel, error) {
+		return NewLINEChannel(cfg.Channels.LINE, b)
+	})
+	registerChannel("onebot", func(cfg *config.Config, b *bus.MessageBus) (Channel, error) {
+		return NewOneBotChannel(cfg.Channels.OneBot, b)
+	})
+	registerChannel("wecom", func(cfg *config.Config, b *bus.MessageBus) (Channel, error) {
+		return NewWeComBotChannel(cfg.Channels.WeCom, b)
+	})
+	registerChannel("wecom_app", func(cfg *config.Config, b *bus.MessageBus) (Channel, error) {
+		return NewWeComAppChannel(cfg.Channels.WeComApp, b)
+	})
+}
@@ -0,0 +1,33 @@
+//go:build !minimal
+
+package channels
+
+import (
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// Channels included in the standard and full build profiles.
+func init() {
+	registerChannel("telegram", func(cfg *config.Config, b *bus.MessageBus) (Channel, error) {
+		return NewTelegramChannel(cfg, b)
+	})
+	registerChannel("discord", func(cfg *config.Config, b *bus.MessageBus) (Channel, error) {
+		return NewDiscordChannel(cfg.Channels.Discord, b)
+	})
+	registerChannel("slack", func(cfg *config.Config, b *bus.MessageBus) (Channel, error) {
+		return NewSlackChannel(cfg.Channels.Slack, b)
+	})
+	registerChannel("whatsapp", func(cfg *config.Config, b *bus.MessageBus) (Channel, error) {
+		return NewWhatsAppChannel(cfg.Channels.WhatsApp, b)
+	})
+	registerChannel("maixcam", func(cfg *config.Config, b *bus.MessageBus) (Channel, error) {
+		return NewMaixCamChannel(cfg.Channels.MaixCam, b)
+	})
+	registerChannel("mqtt", func(cfg *config.Config, b *bus.MessageBus) (Channel, error) {
+		return NewMQTTChannel(cfg.Channels.MQTT, b)
+	})
+	registerChannel("serial", func(cfg *config.Config, b *bus.MessageBus) (Channel, error) {
+		return NewSerialChannel(cfg.Channels.Serial, b)
+	})
+}
@@ -1,3 +1,5 @@
+//go:build !minimal
+
 package channels
 
 import (
@@ -74,6 +76,12 @@ func (c *DiscordChannel) Start(ctx context.Context) error {
 	c.botUserID = botUser.ID
 
 	c.session.AddHandler(c.handleMessage)
+	c.session.AddHandler(func(s *discordgo.Session, r *discordgo.MessageReactionAdd) {
+		c.handleReaction(s, r.MessageReaction, false)
+	})
+	c.session.AddHandler(func(s *discordgo.Session, r *discordgo.MessageReactionRemove) {
+		c.handleReaction(s, r.MessageReaction, true)
+	})
 
 	if err := c.session.Open(); err != nil {
 		return fmt.Errorf("failed to open discord session: %w", err)
@@ -128,34 +136,49 @@ func (c *DiscordChannel) Send(ctx context.Context, msg bus.OutboundMessage) erro
 	chunks := utils.SplitMessage(msg.Content, 2000) // Split messages into chunks, Discord length limit: 2000 chars
 
 	for _, chunk := range chunks {
-		if err := c.sendChunk(ctx, channelID, chunk); err != nil {
+		messageID, err := c.sendChunk(ctx, channelID, chunk)
+		if err != nil {
 			return err
 		}
+		c.TrackReply(channelID, messageID, msg.TurnID)
 	}
 
 	return nil
 }
 
-func (c *DiscordChannel) sendChunk(ctx context.Context, channelID, content string) error {
+func (c *DiscordChannel) sendChunk(ctx context.Context, channelID, content string) (string, error) {
 	// Use the passed ctx for timeout control
 	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
 	defer cancel()
 
-	done := make(chan error, 1)
+	type result struct {
+		msg *discordgo.Message
+		err error
+	}
+	done := make(chan result, 1)
 	go func() {
-		_, err := c.session.ChannelMessageSend(channelID, content)
-		done <- err
+		msg, err := c.session.ChannelMessageSend(channelID, content)
+		done <- result{msg, err}
 	}()
 
 	select {
-	case err := <-done:
-		if err != nil {
-			return fmt.Errorf("failed to send discord message: %w", err)
+	case r := <-done:
+		if r.err != nil {
+			return "", fmt.Errorf("failed to send discord message: %w", r.err)
 		}
-		return nil
+		return r.msg.ID, nil
 	case <-sendCtx.Done():
-		return fmt.Errorf("send message timeout: %w", sendCtx.Err())
+		return "", fmt.Errorf("send message timeout: %w", sendCtx.Err())
+	}
+}
+
+// handleReaction passes on a reaction added to or removed from a message.
+// Custom server emoji are passed by name.
+func (c *DiscordChannel) handleReaction(s *discordgo.Session, r *discordgo.MessageReaction, removed bool) {
+	if r == nil || r.UserID == s.State.User.ID {
+		return
 	}
+	c.HandleReaction(r.UserID, r.ChannelID, r.MessageID, r.Emoji.Name, removed)
 }
 
 // appendContent safely appends content to existing text
@@ -1,3 +1,5 @@
+//go:build !minimal && !standard
+
 package channels
 
 import (
@@ -17,6 +19,7 @@ import (
 
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/crash"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/utils"
 )
@@ -265,6 +268,8 @@ type lineMentionee struct {
 }
 
 func (c *LINEChannel) processEvent(event lineEvent) {
+	defer crash.Recover("line", nil)
+
 	if event.Type != "message" {
 		logger.DebugCF("line", "Ignoring non-message event", map[string]interface{}{
 			"type": event.Type,
@@ -1,3 +1,5 @@
+//go:build !minimal
+
 package channels
 
 import (
@@ -9,6 +11,7 @@ import (
 
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/crash"
 	"github.com/sipeed/picoclaw/pkg/logger"
 )
 
@@ -92,6 +95,8 @@ func (c *MaixCamChannel) acceptConnections(ctx context.Context) {
 }
 
 func (c *MaixCamChannel) handleConnection(conn net.Conn, ctx context.Context) {
+	defer crash.Recover("maixcam", nil)
+
 	logger.DebugC("maixcam", "Handling MaixCam connection")
 
 	defer func() {
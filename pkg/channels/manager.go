@@ -9,12 +9,18 @@ package channels
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"sync"
+	"time"
 
+	"github.com/sipeed/picoclaw/pkg/atrest"
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/constants"
+	"github.com/sipeed/picoclaw/pkg/crash"
+	"github.com/sipeed/picoclaw/pkg/features"
 	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/vfs"
 )
 
 type Manager struct {
@@ -23,6 +29,11 @@ type Manager struct {
 	config       *config.Config
 	dispatchTask *asyncTask
 	mu           sync.RWMutex
+
+	workingHours map[string]*workingHours
+	held         map[string][]bus.OutboundMessage
+	heldMu       sync.Mutex
+	heldFile     string // where held messages persist; empty to keep them in memory only
 }
 
 type asyncTask struct {
@@ -34,6 +45,21 @@ func NewManager(cfg *config.Config, messageBus *bus.MessageBus) (*Manager, error
 		channels: make(map[string]Channel),
 		bus:      messageBus,
 		config:   cfg,
+		held:     make(map[string][]bus.OutboundMessage),
+	}
+
+	var errs []error
+	m.workingHours, errs = loadWorkingHours(cfg.Channels.WorkingHours)
+	for _, err := range errs {
+		logger.WarnCF("channels", "Ignoring invalid working hours", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	if len(m.workingHours) > 0 {
+		m.heldFile = heldFile(cfg.WorkspacePath())
+		vfs.MkdirAll(filepath.Dir(m.heldFile), 0755)
+		atrest.Protect(filepath.Dir(m.heldFile))
+		m.loadHeld()
 	}
 
 	if err := m.initChannels(); err != nil {
@@ -43,163 +69,73 @@ func NewManager(cfg *config.Config, messageBus *bus.MessageBus) (*Manager, error
 	return m, nil
 }
 
-func (m *Manager) initChannels() error {
-	logger.InfoC("channels", "Initializing channel manager")
+// channelFactory creates a channel from the full configuration.
+type channelFactory func(cfg *config.Config, messageBus *bus.MessageBus) (Channel, error)
 
-	if m.config.Channels.Telegram.Enabled && m.config.Channels.Telegram.Token != "" {
-		logger.DebugC("channels", "Attempting to initialize Telegram channel")
-		telegram, err := NewTelegramChannel(m.config, m.bus)
-		if err != nil {
-			logger.ErrorCF("channels", "Failed to initialize Telegram channel", map[string]interface{}{
-				"error": err.Error(),
-			})
-		} else {
-			m.channels["telegram"] = telegram
-			logger.InfoC("channels", "Telegram channel enabled successfully")
-		}
-	}
-
-	if m.config.Channels.WhatsApp.Enabled && m.config.Channels.WhatsApp.BridgeURL != "" {
-		logger.DebugC("channels", "Attempting to initialize WhatsApp channel")
-		whatsapp, err := NewWhatsAppChannel(m.config.Channels.WhatsApp, m.bus)
-		if err != nil {
-			logger.ErrorCF("channels", "Failed to initialize WhatsApp channel", map[string]interface{}{
-				"error": err.Error(),
-			})
-		} else {
-			m.channels["whatsapp"] = whatsapp
-			logger.InfoC("channels", "WhatsApp channel enabled successfully")
-		}
-	}
-
-	if m.config.Channels.Feishu.Enabled {
-		logger.DebugC("channels", "Attempting to initialize Feishu channel")
-		feishu, err := NewFeishuChannel(m.config.Channels.Feishu, m.bus)
-		if err != nil {
-			logger.ErrorCF("channels", "Failed to initialize Feishu channel", map[string]interface{}{
-				"error": err.Error(),
-			})
-		} else {
-			m.channels["feishu"] = feishu
-			logger.InfoC("channels", "Feishu channel enabled successfully")
-		}
-	}
-
-	if m.config.Channels.Discord.Enabled && m.config.Channels.Discord.Token != "" {
-		logger.DebugC("channels", "Attempting to initialize Discord channel")
-		discord, err := NewDiscordChannel(m.config.Channels.Discord, m.bus)
-		if err != nil {
-			logger.ErrorCF("channels", "Failed to initialize Discord channel", map[string]interface{}{
-				"error": err.Error(),
-			})
-		} else {
-			m.channels["discord"] = discord
-			logger.InfoC("channels", "Discord channel enabled successfully")
-		}
-	}
-
-	if m.config.Channels.MaixCam.Enabled {
-		logger.DebugC("channels", "Attempting to initialize MaixCam channel")
-		maixcam, err := NewMaixCamChannel(m.config.Channels.MaixCam, m.bus)
-		if err != nil {
-			logger.ErrorCF("channels", "Failed to initialize MaixCam channel", map[string]interface{}{
-				"error": err.Error(),
-			})
-		} else {
-			m.channels["maixcam"] = maixcam
-			logger.InfoC("channels", "MaixCam channel enabled successfully")
-		}
-	}
+// channelSpec describes a channel the manager can configure. The list is
+// compiled into every build so that a channel enabled in the config but left
+// out of the build profile can be reported.
+type channelSpec struct {
+	name    string
+	label   string
+	enabled func(cfg *config.Config) bool
+}
 
-	if m.config.Channels.QQ.Enabled {
-		logger.DebugC("channels", "Attempting to initialize QQ channel")
-		qq, err := NewQQChannel(m.config.Channels.QQ, m.bus)
-		if err != nil {
-			logger.ErrorCF("channels", "Failed to initialize QQ channel", map[string]interface{}{
-				"error": err.Error(),
-			})
-		} else {
-			m.channels["qq"] = qq
-			logger.InfoC("channels", "QQ channel enabled successfully")
-		}
-	}
+var channelSpecs = []channelSpec{
+	{"telegram", "Telegram", func(c *config.Config) bool { return c.Channels.Telegram.Enabled && c.Channels.Telegram.Token != "" }},
+	{"whatsapp", "WhatsApp", func(c *config.Config) bool { return c.Channels.WhatsApp.Enabled && c.Channels.WhatsApp.BridgeURL != "" }},
+	{"feishu", "Feishu", func(c *config.Config) bool { return c.Channels.Feishu.Enabled }},
+	{"discord", "Discord", func(c *config.Config) bool { return c.Channels.Discord.Enabled && c.Channels.Discord.Token != "" }},
+	{"maixcam", "MaixCam", func(c *config.Config) bool { return c.Channels.MaixCam.Enabled }},
+	{"qq", "QQ", func(c *config.Config) bool { return c.Channels.QQ.Enabled }},
+	{"dingtalk", "DingTalk", func(c *config.Config) bool { return c.Channels.DingTalk.Enabled && c.Channels.DingTalk.ClientID != "" }},
+	{"slack", "Slack", func(c *config.Config) bool { return c.Channels.Slack.Enabled && c.Channels.Slack.BotToken != "" }},
+	{"line", "LINE", func(c *config.Config) bool {
+		return c.Channels.LINE.Enabled && c.Channels.LINE.ChannelAccessToken != ""
+	}},
+	{"onebot", "OneBot", func(c *config.Config) bool { return c.Channels.OneBot.Enabled && c.Channels.OneBot.WSUrl != "" }},
+	{"wecom", "WeCom", func(c *config.Config) bool { return c.Channels.WeCom.Enabled && c.Channels.WeCom.Token != "" }},
+	{"wecom_app", "WeCom App", func(c *config.Config) bool { return c.Channels.WeComApp.Enabled && c.Channels.WeComApp.CorpID != "" }},
+	{"mqtt", "MQTT", func(c *config.Config) bool { return c.Channels.MQTT.Enabled && c.Channels.MQTT.Broker != "" }},
+	{"serial", "Serial", func(c *config.Config) bool { return c.Channels.Serial.Enabled && c.Channels.Serial.Device != "" }},
+}
 
-	if m.config.Channels.DingTalk.Enabled && m.config.Channels.DingTalk.ClientID != "" {
-		logger.DebugC("channels", "Attempting to initialize DingTalk channel")
-		dingtalk, err := NewDingTalkChannel(m.config.Channels.DingTalk, m.bus)
-		if err != nil {
-			logger.ErrorCF("channels", "Failed to initialize DingTalk channel", map[string]interface{}{
-				"error": err.Error(),
-			})
-		} else {
-			m.channels["dingtalk"] = dingtalk
-			logger.InfoC("channels", "DingTalk channel enabled successfully")
-		}
-	}
+// channelFactories holds the channels compiled into this build. They are
+// registered from the build-profile files channels_standard.go and
+// channels_full.go.
+var channelFactories = map[string]channelFactory{}
 
-	if m.config.Channels.Slack.Enabled && m.config.Channels.Slack.BotToken != "" {
-		logger.DebugC("channels", "Attempting to initialize Slack channel")
-		slackCh, err := NewSlackChannel(m.config.Channels.Slack, m.bus)
-		if err != nil {
-			logger.ErrorCF("channels", "Failed to initialize Slack channel", map[string]interface{}{
-				"error": err.Error(),
-			})
-		} else {
-			m.channels["slack"] = slackCh
-			logger.InfoC("channels", "Slack channel enabled successfully")
-		}
-	}
+func registerChannel(name string, factory channelFactory) {
+	channelFactories[name] = factory
+	features.Register("channel", name)
+}
 
-	if m.config.Channels.LINE.Enabled && m.config.Channels.LINE.ChannelAccessToken != "" {
-		logger.DebugC("channels", "Attempting to initialize LINE channel")
-		line, err := NewLINEChannel(m.config.Channels.LINE, m.bus)
-		if err != nil {
-			logger.ErrorCF("channels", "Failed to initialize LINE channel", map[string]interface{}{
-				"error": err.Error(),
-			})
-		} else {
-			m.channels["line"] = line
-			logger.InfoC("channels", "LINE channel enabled successfully")
-		}
-	}
+func (m *Manager) initChannels() error {
+	logger.InfoC("channels", "Initializing channel manager")
 
-	if m.config.Channels.OneBot.Enabled && m.config.Channels.OneBot.WSUrl != "" {
-		logger.DebugC("channels", "Attempting to initialize OneBot channel")
-		onebot, err := NewOneBotChannel(m.config.Channels.OneBot, m.bus)
-		if err != nil {
-			logger.ErrorCF("channels", "Failed to initialize OneBot channel", map[string]interface{}{
-				"error": err.Error(),
-			})
-		} else {
-			m.channels["onebot"] = onebot
-			logger.InfoC("channels", "OneBot channel enabled successfully")
+	for _, spec := range channelSpecs {
+		if !spec.enabled(m.config) {
+			continue
 		}
-	}
 
-	if m.config.Channels.WeCom.Enabled && m.config.Channels.WeCom.Token != "" {
-		logger.DebugC("channels", "Attempting to initialize WeCom channel")
-		wecom, err := NewWeComBotChannel(m.config.Channels.WeCom, m.bus)
-		if err != nil {
-			logger.ErrorCF("channels", "Failed to initialize WeCom channel", map[string]interface{}{
-				"error": err.Error(),
+		create, ok := channelFactories[spec.name]
+		if !ok {
+			logger.WarnCF("channels", spec.label+" channel is enabled but not included in this build", map[string]interface{}{
+				"profile": features.Profile,
 			})
-		} else {
-			m.channels["wecom"] = wecom
-			logger.InfoC("channels", "WeCom channel enabled successfully")
+			continue
 		}
-	}
 
-	if m.config.Channels.WeComApp.Enabled && m.config.Channels.WeComApp.CorpID != "" {
-		logger.DebugC("channels", "Attempting to initialize WeCom App channel")
-		wecomApp, err := NewWeComAppChannel(m.config.Channels.WeComApp, m.bus)
+		logger.DebugC("channels", "Attempting to initialize "+spec.label+" channel")
+		channel, err := create(m.config, m.bus)
 		if err != nil {
-			logger.ErrorCF("channels", "Failed to initialize WeCom App channel", map[string]interface{}{
+			logger.ErrorCF("channels", "Failed to initialize "+spec.label+" channel", map[string]interface{}{
 				"error": err.Error(),
 			})
-		} else {
-			m.channels["wecom_app"] = wecomApp
-			logger.InfoC("channels", "WeCom App channel enabled successfully")
+			continue
 		}
+		m.channels[spec.name] = channel
+		logger.InfoC("channels", spec.label+" channel enabled successfully")
 	}
 
 	logger.InfoCF("channels", "Channel initialization completed", map[string]interface{}{
@@ -224,6 +160,9 @@ func (m *Manager) StartAll(ctx context.Context) error {
 	m.dispatchTask = &asyncTask{cancel: cancel}
 
 	go m.dispatchOutbound(dispatchCtx)
+	if len(m.workingHours) > 0 {
+		go m.releaseHeldLoop(dispatchCtx)
+	}
 
 	for name, channel := range m.channels {
 		logger.InfoCF("channels", "Starting channel", map[string]interface{}{
@@ -252,6 +191,19 @@ func (m *Manager) StopAll(ctx context.Context) error {
 		m.dispatchTask = nil
 	}
 
+	if n := m.HeldMessages(); n > 0 {
+		if m.heldFile != "" {
+			logger.InfoCF("channels", "Keeping messages held until working hours for the next start", map[string]interface{}{
+				"count": n,
+				"file":  m.heldFile,
+			})
+		} else {
+			logger.WarnCF("channels", "Dropping messages held until working hours", map[string]interface{}{
+				"count": n,
+			})
+		}
+	}
+
 	for name, channel := range m.channels {
 		logger.InfoCF("channels", "Stopping channel", map[string]interface{}{
 			"channel": name,
@@ -298,16 +250,38 @@ func (m *Manager) dispatchOutbound(ctx context.Context) {
 				continue
 			}
 
-			if err := channel.Send(ctx, msg); err != nil {
-				logger.ErrorCF("channels", "Error sending message to channel", map[string]interface{}{
-					"channel": msg.Channel,
-					"error":   err.Error(),
-				})
+			if msg.Proactive && m.holdOutsideWorkingHours(msg, time.Now()) {
+				continue
 			}
+			if _, ok := channel.(PartialSender); msg.Partial && !ok {
+				continue
+			}
+
+			m.send(ctx, channel, msg)
 		}
 	}
 }
 
+// send delivers msg, recovering from a panic in the channel so the
+// dispatcher keeps running.
+func (m *Manager) send(ctx context.Context, channel Channel, msg bus.OutboundMessage) {
+	defer crash.Recover("channels", map[string]interface{}{
+		"channel": msg.Channel,
+		"chat_id": msg.ChatID,
+	})
+
+	send := channel.Send
+	if msg.Partial {
+		send = channel.(PartialSender).SendPartial
+	}
+	if err := send(ctx, msg); err != nil {
+		logger.ErrorCF("channels", "Error sending message to channel", map[string]interface{}{
+			"channel": msg.Channel,
+			"error":   err.Error(),
+		})
+	}
+}
+
 func (m *Manager) GetChannel(name string) (Channel, bool) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -315,6 +289,17 @@ func (m *Manager) GetChannel(name string) (Channel, bool) {
 	return channel, ok
 }
 
+// ActionSchema returns the schema of the actions channel can show, and
+// false if it cannot show any.
+func (m *Manager) ActionSchema(channel string) (bus.ActionSchema, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if ac, ok := m.channels[channel].(ActionChannel); ok {
+		return ac.ActionSchema(), true
+	}
+	return bus.ActionSchema{}, false
+}
+
 func (m *Manager) GetStatus() map[string]interface{} {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -369,3 +354,102 @@ func (m *Manager) SendToChannel(ctx context.Context, channelName, chatID, conten
 
 	return channel.Send(ctx, msg)
 }
+
+// holdOutsideWorkingHours queues msg if its channel is currently outside
+// its working hours and reports whether it did so.
+func (m *Manager) holdOutsideWorkingHours(msg bus.OutboundMessage, now time.Time) bool {
+	wh := m.windowFor(msg.Channel)
+	if wh == nil || wh.isOpen(now) {
+		return false
+	}
+
+	m.heldMu.Lock()
+	defer m.heldMu.Unlock()
+
+	queue := append(m.held[msg.Channel], msg)
+	if len(queue) > maxQueuedPerChannel {
+		logger.WarnCF("channels", "Working hours queue full, dropping oldest message", map[string]interface{}{
+			"channel": msg.Channel,
+		})
+		queue = queue[1:]
+	}
+	m.held[msg.Channel] = queue
+	m.saveHeld()
+
+	logger.InfoCF("channels", "Holding proactive message until working hours", map[string]interface{}{
+		"channel": msg.Channel,
+		"queued":  len(queue),
+	})
+	return true
+}
+
+func (m *Manager) windowFor(channel string) *workingHours {
+	if wh, ok := m.workingHours[channel]; ok {
+		return wh
+	}
+	return m.workingHours["*"]
+}
+
+func (m *Manager) releaseHeldLoop(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.releaseHeld(ctx, now)
+		}
+	}
+}
+
+// HeldMessages returns how many messages are queued until their channel's
+// working hours.
+func (m *Manager) HeldMessages() int {
+	m.heldMu.Lock()
+	defer m.heldMu.Unlock()
+	n := 0
+	for _, queue := range m.held {
+		n += len(queue)
+	}
+	return n
+}
+
+// releaseHeld sends queued messages for every channel whose window is open.
+func (m *Manager) releaseHeld(ctx context.Context, now time.Time) {
+	m.heldMu.Lock()
+	ready := make(map[string][]bus.OutboundMessage)
+	for name, queue := range m.held {
+		if wh := m.windowFor(name); wh == nil || wh.isOpen(now) {
+			ready[name] = queue
+			delete(m.held, name)
+		}
+	}
+	if len(ready) > 0 {
+		m.saveHeld()
+	}
+	m.heldMu.Unlock()
+
+	for name, queue := range ready {
+		m.mu.RLock()
+		channel, exists := m.channels[name]
+		m.mu.RUnlock()
+		if !exists {
+			continue
+		}
+
+		logger.InfoCF("channels", "Working hours opened, sending held messages", map[string]interface{}{
+			"channel": name,
+			"count":   len(queue),
+		})
+		for _, msg := range queue {
+			if err := channel.Send(ctx, msg); err != nil {
+				logger.ErrorCF("channels", "Error sending held message to channel", map[string]interface{}{
+					"channel": name,
+					"error":   err.Error(),
+				})
+			}
+		}
+	}
+}
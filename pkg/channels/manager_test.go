@@ -0,0 +1,67 @@
+package channels
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+// loggingChannel reports each delivery as "<name> <kind>: <content>".
+type loggingChannel struct {
+	name string
+	log  chan string
+}
+
+func (c *loggingChannel) Name() string                    { return c.name }
+func (c *loggingChannel) Start(ctx context.Context) error { return nil }
+func (c *loggingChannel) Stop(ctx context.Context) error  { return nil }
+func (c *loggingChannel) IsRunning() bool                 { return true }
+func (c *loggingChannel) IsAllowed(senderID string) bool  { return true }
+func (c *loggingChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	c.log <- c.name + " send: " + msg.Content
+	return nil
+}
+
+type editingChannel struct {
+	loggingChannel
+}
+
+func (c *editingChannel) SendPartial(ctx context.Context, msg bus.OutboundMessage) error {
+	c.log <- c.name + " partial: " + msg.Content
+	return nil
+}
+
+func TestManagerDeliversPartialRepliesOnlyToPartialSenders(t *testing.T) {
+	log := make(chan string, 8)
+	msgBus := bus.NewMessageBus()
+	m := &Manager{
+		bus: msgBus,
+		channels: map[string]Channel{
+			"plain":   &loggingChannel{name: "plain", log: log},
+			"editing": &editingChannel{loggingChannel{name: "editing", log: log}},
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.dispatchOutbound(ctx)
+
+	msgBus.PublishOutbound(bus.OutboundMessage{Channel: "plain", ChatID: "1", Content: "Hel", Partial: true})
+	msgBus.PublishOutbound(bus.OutboundMessage{Channel: "editing", ChatID: "1", Content: "Hel", Partial: true})
+	msgBus.PublishOutbound(bus.OutboundMessage{Channel: "plain", ChatID: "1", Content: "Hello"})
+	msgBus.PublishOutbound(bus.OutboundMessage{Channel: "editing", ChatID: "1", Content: "Hello"})
+
+	want := []string{"editing partial: Hel", "plain send: Hello", "editing send: Hello"}
+	for _, w := range want {
+		select {
+		case got := <-log:
+			if got != w {
+				t.Fatalf("delivery = %q, want %q", got, w)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for %q", w)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+//go:build !minimal
+
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/crash"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/mqtt"
+)
+
+// MQTTChannel handles messages published to a command topic and publishes
+// the replies. The chat ID is the topic replies go to, so proactive
+// messages (cron, heartbeat) can target any topic.
+type MQTTChannel struct {
+	*BaseChannel
+	config config.MQTTConfig
+
+	mu     sync.Mutex
+	client *mqtt.Client
+	cancel context.CancelFunc
+}
+
+// MQTTCommand is the optional JSON form of a command payload. Plain text
+// payloads are taken as the message content. The sender is always the
+// topic, which the broker's ACLs can restrict, never the payload, which
+// any publisher can write. ReplyTo is only followed to topics matching
+// allow_reply_to.
+type MQTTCommand struct {
+	Text    string `json:"text"`
+	ReplyTo string `json:"reply_to"`
+}
+
+func NewMQTTChannel(cfg config.MQTTConfig, bus *bus.MessageBus) (*MQTTChannel, error) {
+	if cfg.Broker == "" {
+		return nil, fmt.Errorf("mqtt broker not configured")
+	}
+	if cfg.CommandTopic == "" || cfg.ResponseTopic == "" {
+		return nil, fmt.Errorf("mqtt command_topic and response_topic are required")
+	}
+	base := NewBaseChannel("mqtt", cfg, bus, cfg.AllowFrom)
+
+	return &MQTTChannel{
+		BaseChannel: base,
+		config:      cfg,
+	}, nil
+}
+
+func (c *MQTTChannel) Start(ctx context.Context) error {
+	logger.InfoCF("mqtt", "Starting MQTT channel", map[string]interface{}{
+		"broker":        c.config.Broker,
+		"command_topic": c.config.CommandTopic,
+	})
+
+	client, err := c.connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	c.mu.Lock()
+	c.client = client
+	c.cancel = cancel
+	c.mu.Unlock()
+	c.setRunning(true)
+
+	crash.Go(runCtx, "mqtt", func() { c.maintain(runCtx, client) })
+	return nil
+}
+
+func (c *MQTTChannel) connect(ctx context.Context) (*mqtt.Client, error) {
+	dialCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	client, err := mqtt.Dial(dialCtx, mqtt.Options{
+		Broker:   c.config.Broker,
+		ClientID: c.config.ClientID,
+		Username: c.config.Username,
+		Password: c.config.Password,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := client.Subscribe(dialCtx, c.config.CommandTopic, c.qos(), c.handleCommand); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// maintain reconnects with backoff whenever the connection drops.
+func (c *MQTTChannel) maintain(ctx context.Context, client *mqtt.Client) {
+	backoff := 5 * time.Second
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-client.Done():
+		}
+		logger.WarnCF("mqtt", "Connection to broker lost", map[string]interface{}{
+			"error": fmt.Sprint(client.Err()),
+		})
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			next, err := c.connect(ctx)
+			if err == nil {
+				client = next
+				break
+			}
+			logger.WarnCF("mqtt", "Reconnect failed", map[string]interface{}{
+				"error": err.Error(),
+			})
+			backoff = min(backoff*2, time.Minute)
+		}
+
+		c.mu.Lock()
+		c.client = client
+		c.mu.Unlock()
+		backoff = 5 * time.Second
+		logger.InfoC("mqtt", "Reconnected to broker")
+	}
+}
+
+func (c *MQTTChannel) handleCommand(msg mqtt.Message) {
+	content := strings.TrimSpace(string(msg.Payload))
+	senderID := msg.Topic
+	replyTo := c.config.ResponseTopic
+
+	var cmd MQTTCommand
+	if strings.HasPrefix(content, "{") && json.Unmarshal(msg.Payload, &cmd) == nil {
+		content = strings.TrimSpace(cmd.Text)
+		if cmd.ReplyTo != "" {
+			if !c.replyAllowed(cmd.ReplyTo) {
+				logger.WarnCF("mqtt", "Ignoring reply_to outside allow_reply_to", map[string]interface{}{
+					"topic":    msg.Topic,
+					"reply_to": cmd.ReplyTo,
+				})
+			} else {
+				replyTo = cmd.ReplyTo
+			}
+		}
+	}
+	if content == "" || msg.Retained {
+		// Retained commands would replay on every reconnect.
+		return
+	}
+
+	c.HandleMessage(senderID, replyTo, content, nil, map[string]string{
+		"topic": msg.Topic,
+	})
+}
+
+// replyAllowed reports whether replies may be published to topic, which
+// must be the response topic or match an allow_reply_to filter.
+func (c *MQTTChannel) replyAllowed(topic string) bool {
+	if topic == c.config.ResponseTopic {
+		return true
+	}
+	if strings.ContainsAny(topic, "+#") {
+		return false
+	}
+	for _, filter := range c.config.AllowReplyTo {
+		if mqtt.Match(filter, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *MQTTChannel) Stop(ctx context.Context) error {
+	logger.InfoC("mqtt", "Stopping MQTT channel")
+	c.setRunning(false)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cancel != nil {
+		c.cancel()
+	}
+	if c.client != nil {
+		c.client.Close()
+	}
+	return nil
+}
+
+func (c *MQTTChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	if !c.IsRunning() {
+		return fmt.Errorf("mqtt channel not running")
+	}
+
+	c.mu.Lock()
+	client := c.client
+	c.mu.Unlock()
+
+	return client.Publish(ctx, msg.ChatID, []byte(msg.Content), c.qos(), false)
+}
+
+func (c *MQTTChannel) qos() byte {
+	if c.config.QoS > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,58 @@
+//go:build !minimal
+
+package channels
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/mqtt"
+)
+
+func TestMQTTChannel_SenderAndReplyTopic(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	ch, err := NewMQTTChannel(config.MQTTConfig{
+		Broker:        "tcp://localhost:1883",
+		CommandTopic:  "devices/+/command",
+		ResponseTopic: "picoclaw/response",
+		AllowFrom:     config.FlexibleStringSlice{"devices/door/command"},
+		AllowReplyTo:  config.FlexibleStringSlice{"devices/+/reply"},
+	}, msgBus)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next := func() (bus.InboundMessage, bool) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		return msgBus.ConsumeInbound(ctx)
+	}
+
+	// A payload cannot claim an allowed sender.
+	ch.handleCommand(mqtt.Message{Topic: "devices/garage/command", Payload: []byte(`{"text": "open", "sender": "devices/door/command"}`)})
+	if msg, ok := next(); ok {
+		t.Fatalf("message from %q was accepted", msg.SenderID)
+	}
+
+	tests := []struct {
+		payload, replyTo string
+	}{
+		{`{"text": "status", "reply_to": "devices/door/reply"}`, "devices/door/reply"},
+		{`{"text": "status", "reply_to": "actuators/valve/set"}`, "picoclaw/response"},
+		{`{"text": "status", "reply_to": "devices/+/reply"}`, "picoclaw/response"},
+		{"status", "picoclaw/response"},
+	}
+	for _, tt := range tests {
+		ch.handleCommand(mqtt.Message{Topic: "devices/door/command", Payload: []byte(tt.payload)})
+		msg, ok := next()
+		if !ok {
+			t.Fatalf("%s: no inbound message", tt.payload)
+		}
+		if msg.SenderID != "devices/door/command" || msg.ChatID != tt.replyTo {
+			t.Errorf("%s: sender %q, reply topic %q; want %q", tt.payload, msg.SenderID, msg.ChatID, tt.replyTo)
+		}
+	}
+}
@@ -1,3 +1,5 @@
+//go:build !minimal && !standard
+
 package channels
 
 import (
@@ -15,6 +17,7 @@ import (
 
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/crash"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/utils"
 	"github.com/sipeed/picoclaw/pkg/voice"
@@ -462,6 +465,8 @@ func (c *OneBotChannel) buildSendRequest(msg bus.OutboundMessage) (string, inter
 }
 
 func (c *OneBotChannel) listen() {
+	defer crash.Recover("onebot", nil)
+
 	c.mu.Lock()
 	conn := c.conn
 	c.mu.Unlock()
@@ -1,3 +1,5 @@
+//go:build !minimal && !standard
+
 package channels
 
 import (
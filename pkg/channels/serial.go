@@ -0,0 +1,116 @@
+//go:build !minimal
+
+package channels
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/crash"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+const serialPrompt = "> "
+
+// SerialChannel serves the agent on a serial console. The port is put in
+// canonical mode with echo, so the kernel handles line editing and each
+// line typed is one message. Whoever is on the other end of the cable is
+// the user; there is no allow list.
+type SerialChannel struct {
+	*BaseChannel
+	config config.SerialConfig
+
+	mu   sync.Mutex
+	port io.ReadWriteCloser
+}
+
+func NewSerialChannel(cfg config.SerialConfig, bus *bus.MessageBus) (*SerialChannel, error) {
+	if cfg.Device == "" {
+		return nil, fmt.Errorf("serial device not configured")
+	}
+	base := NewBaseChannel("serial", cfg, bus, nil)
+
+	return &SerialChannel{
+		BaseChannel: base,
+		config:      cfg,
+	}, nil
+}
+
+func (c *SerialChannel) Start(ctx context.Context) error {
+	logger.InfoCF("serial", "Starting serial console", map[string]interface{}{
+		"device":    c.config.Device,
+		"baud_rate": c.config.BaudRate,
+	})
+
+	port, err := openSerial(c.config.Device, c.config.BaudRate)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", c.config.Device, err)
+	}
+	c.attach(port)
+	return nil
+}
+
+// attach starts serving on an open port.
+func (c *SerialChannel) attach(port io.ReadWriteCloser) {
+	c.mu.Lock()
+	c.port = port
+	c.mu.Unlock()
+	c.setRunning(true)
+
+	c.write(serialPrompt)
+	crash.Go(context.Background(), "serial", func() { c.readLines(port) })
+}
+
+func (c *SerialChannel) readLines(port io.Reader) {
+	scanner := bufio.NewScanner(port)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			c.write(serialPrompt)
+			continue
+		}
+		c.HandleMessage("serial", c.config.Device, line, nil, nil)
+	}
+	if err := scanner.Err(); err != nil && c.IsRunning() {
+		logger.ErrorCF("serial", "Serial console read failed", map[string]interface{}{
+			"device": c.config.Device,
+			"error":  err.Error(),
+		})
+	}
+}
+
+func (c *SerialChannel) write(s string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.port == nil {
+		return fmt.Errorf("serial port not open")
+	}
+	_, err := io.WriteString(c.port, s)
+	return err
+}
+
+func (c *SerialChannel) Stop(ctx context.Context) error {
+	logger.InfoC("serial", "Stopping serial console")
+	c.setRunning(false)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.port != nil {
+		c.port.Close()
+		c.port = nil
+	}
+	return nil
+}
+
+func (c *SerialChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	if !c.IsRunning() {
+		return fmt.Errorf("serial channel not running")
+	}
+	return c.write(strings.TrimRight(msg.Content, "\n") + "\n\n" + serialPrompt)
+}
@@ -0,0 +1,66 @@
+//go:build !minimal
+
+package channels
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var serialBaudRates = map[int]uint32{
+	9600:   syscall.B9600,
+	19200:  syscall.B19200,
+	38400:  syscall.B38400,
+	57600:  syscall.B57600,
+	115200: syscall.B115200,
+	230400: syscall.B230400,
+	460800: syscall.B460800,
+	921600: syscall.B921600,
+}
+
+// openSerial opens device as a console: 8N1 at baud, canonical input with
+// echo and erase handling, and CR/LF translation both ways so ordinary
+// terminal programs work.
+func openSerial(device string, baud int) (io.ReadWriteCloser, error) {
+	speed, ok := serialBaudRates[baud]
+	if !ok {
+		return nil, fmt.Errorf("unsupported baud rate %d", baud)
+	}
+
+	f, err := os.OpenFile(device, os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := f.SyscallConn()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	var ioctlErr error
+	err = conn.Control(func(fd uintptr) {
+		var t syscall.Termios
+		if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TCGETS, uintptr(unsafe.Pointer(&t))); errno != 0 {
+			ioctlErr = fmt.Errorf("not a terminal: %w", errno)
+			return
+		}
+		t.Iflag = syscall.ICRNL
+		t.Oflag = syscall.OPOST | syscall.ONLCR
+		t.Cflag = speed | syscall.CS8 | syscall.CREAD | syscall.CLOCAL
+		t.Lflag = syscall.ICANON | syscall.ECHO | syscall.ECHOE | syscall.ECHOK
+		if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TCSETS, uintptr(unsafe.Pointer(&t))); errno != 0 {
+			ioctlErr = fmt.Errorf("configure terminal: %w", errno)
+		}
+	})
+	if err == nil {
+		err = ioctlErr
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,13 @@
+//go:build !minimal && !linux
+
+package channels
+
+import (
+	"fmt"
+	"io"
+)
+
+// openSerial is a stub for non-Linux platforms.
+func openSerial(device string, baud int) (io.ReadWriteCloser, error) {
+	return nil, fmt.Errorf("serial console is only supported on Linux")
+}
@@ -0,0 +1,70 @@
+//go:build !minimal
+
+package channels
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestSerialChannel_Conversation(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	ch, err := NewSerialChannel(config.SerialConfig{Device: "/dev/ttyTEST", BaudRate: 115200}, msgBus)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	port, terminal := net.Pipe()
+	output := make(chan string, 16)
+	go func() {
+		r := bufio.NewReader(terminal)
+		buf := make([]byte, 256)
+		for {
+			n, err := r.Read(buf)
+			if err != nil {
+				return
+			}
+			output <- string(buf[:n])
+		}
+	}()
+	ch.attach(port)
+	defer ch.Stop(context.Background())
+
+	expectOutput := func(want string) {
+		t.Helper()
+		var got string
+		for got != want {
+			select {
+			case s := <-output:
+				got += s
+			case <-time.After(2 * time.Second):
+				t.Fatalf("output = %q, want %q", got, want)
+			}
+		}
+	}
+	expectOutput("> ")
+
+	terminal.Write([]byte("\n  what time is it?\n"))
+	expectOutput("> ")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	msg, ok := msgBus.ConsumeInbound(ctx)
+	if !ok {
+		t.Fatal("no inbound message")
+	}
+	if msg.Channel != "serial" || msg.ChatID != "/dev/ttyTEST" || msg.Content != "what time is it?" {
+		t.Errorf("inbound = %+v", msg)
+	}
+
+	if err := ch.Send(ctx, bus.OutboundMessage{ChatID: msg.ChatID, Content: "Noon.\n"}); err != nil {
+		t.Fatal(err)
+	}
+	expectOutput("Noon.\n\n> ")
+}
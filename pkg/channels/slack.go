@@ -1,3 +1,5 @@
+//go:build !minimal
+
 package channels
 
 import (
@@ -14,6 +16,7 @@ import (
 
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/crash"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/utils"
 	"github.com/sipeed/picoclaw/pkg/voice"
@@ -127,10 +130,11 @@ func (c *SlackChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 		opts = append(opts, slack.MsgOptionTS(threadTS))
 	}
 
-	_, _, err := c.api.PostMessageContext(ctx, channelID, opts...)
+	_, ts, err := c.api.PostMessageContext(ctx, channelID, opts...)
 	if err != nil {
 		return fmt.Errorf("failed to send slack message: %w", err)
 	}
+	c.TrackReply(channelID, ts, msg.TurnID)
 
 	if ref, ok := c.pendingAcks.LoadAndDelete(msg.ChatID); ok {
 		msgRef := ref.(slackMessageRef)
@@ -149,6 +153,8 @@ func (c *SlackChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 }
 
 func (c *SlackChannel) eventLoop() {
+	defer crash.Recover("slack", nil)
+
 	for {
 		select {
 		case <-c.ctx.Done():
@@ -186,7 +192,20 @@ func (c *SlackChannel) handleEventsAPI(event socketmode.Event) {
 		c.handleMessageEvent(ev)
 	case *slackevents.AppMentionEvent:
 		c.handleAppMention(ev)
+	case *slackevents.ReactionAddedEvent:
+		c.handleReaction(ev.User, ev.Item, ev.Reaction, false)
+	case *slackevents.ReactionRemovedEvent:
+		c.handleReaction(ev.User, ev.Item, ev.Reaction, true)
+	}
+}
+
+// handleReaction passes on a reaction to a message. Slack names emoji
+// rather than sending them, e.g. "+1" or "heart".
+func (c *SlackChannel) handleReaction(user string, item slackevents.Item, reaction string, removed bool) {
+	if user == c.botUserID || item.Type != "message" {
+		return
 	}
+	c.HandleReaction(user, item.Channel, item.Timestamp, reaction, removed)
 }
 
 func (c *SlackChannel) handleMessageEvent(ev *slackevents.MessageEvent) {
@@ -1,3 +1,5 @@
+//go:build !minimal
+
 package channels
 
 import (
@@ -7,6 +9,7 @@ import (
 	"net/url"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -19,6 +22,7 @@ import (
 
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/crash"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/utils"
 	"github.com/sipeed/picoclaw/pkg/voice"
@@ -96,6 +100,8 @@ func (c *TelegramChannel) Start(ctx context.Context) error {
 
 	updates, err := c.bot.UpdatesViaLongPolling(ctx, &telego.GetUpdatesParams{
 		Timeout: 30,
+		// Reactions are only delivered when asked for explicitly.
+		AllowedUpdates: []string{"message", "message_reaction", "callback_query"},
 	})
 	if err != nil {
 		return fmt.Errorf("failed to start long polling: %w", err)
@@ -126,12 +132,25 @@ func (c *TelegramChannel) Start(ctx context.Context) error {
 		return c.handleMessage(ctx, &message)
 	}, th.AnyMessage())
 
+	bh.HandleMessageReaction(func(ctx *th.Context, reaction telego.MessageReactionUpdated) error {
+		c.handleReaction(reaction)
+		return nil
+	})
+
+	bh.HandleCallbackQuery(func(ctx *th.Context, query telego.CallbackQuery) error {
+		c.handleCallback(ctx, query)
+		return nil
+	})
+
 	c.setRunning(true)
 	logger.InfoCF("telegram", "Telegram bot connected", map[string]interface{}{
 		"username": c.bot.Username(),
 	})
 
-	go bh.Start()
+	go func() {
+		defer crash.Recover("telegram", nil)
+		bh.Start()
+	}()
 
 	go func() {
 		<-ctx.Done()
@@ -165,14 +184,17 @@ func (c *TelegramChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 	}
 
 	htmlContent := markdownToTelegramHTML(msg.Content)
+	keyboard := c.actionKeyboard(msg)
 
 	// Try to edit placeholder
 	if pID, ok := c.placeholders.Load(msg.ChatID); ok {
 		c.placeholders.Delete(msg.ChatID)
 		editMsg := tu.EditMessageText(tu.ID(chatID), pID.(int), htmlContent)
 		editMsg.ParseMode = telego.ModeHTML
+		editMsg.ReplyMarkup = keyboard
 
 		if _, err = c.bot.EditMessageText(ctx, editMsg); err == nil {
+			c.TrackReply(msg.ChatID, strconv.Itoa(pID.(int)), msg.TurnID)
 			return nil
 		}
 		// Fallback to new message if edit fails
@@ -180,19 +202,149 @@ func (c *TelegramChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 
 	tgMsg := tu.Message(tu.ID(chatID), htmlContent)
 	tgMsg.ParseMode = telego.ModeHTML
+	if keyboard != nil {
+		tgMsg.ReplyMarkup = keyboard
+	}
 
-	if _, err = c.bot.SendMessage(ctx, tgMsg); err != nil {
+	sent, err := c.bot.SendMessage(ctx, tgMsg)
+	if err != nil {
 		logger.ErrorCF("telegram", "HTML parse failed, falling back to plain text", map[string]interface{}{
 			"error": err.Error(),
 		})
 		tgMsg.ParseMode = ""
-		_, err = c.bot.SendMessage(ctx, tgMsg)
-		return err
+		if sent, err = c.bot.SendMessage(ctx, tgMsg); err != nil {
+			return err
+		}
 	}
+	c.TrackReply(msg.ChatID, strconv.Itoa(sent.MessageID), msg.TurnID)
 
 	return nil
 }
 
+// ActionSchema implements ActionChannel: actions are shown as inline
+// keyboard buttons, one per row.
+func (c *TelegramChannel) ActionSchema() bus.ActionSchema {
+	return bus.ActionSchema{MaxActions: 8, MaxLabelRunes: 40}
+}
+
+// actionKeyboard returns the inline keyboard for the actions of msg, or
+// nil if it has none.
+func (c *TelegramChannel) actionKeyboard(msg bus.OutboundMessage) *telego.InlineKeyboardMarkup {
+	if len(msg.Actions) == 0 {
+		return nil
+	}
+	tokens := c.TrackActions(msg.ChatID, msg.Actions)
+	rows := make([][]telego.InlineKeyboardButton, len(msg.Actions))
+	for i, a := range msg.Actions {
+		rows[i] = tu.InlineKeyboardRow(tu.InlineKeyboardButton(a.Label).WithCallbackData(tokens[i]))
+	}
+	return tu.InlineKeyboard(rows...)
+}
+
+// handleCallback passes on the button a user pressed as a message, and
+// removes the buttons so the choice is made once.
+func (c *TelegramChannel) handleCallback(ctx context.Context, query telego.CallbackQuery) {
+	if query.Message == nil {
+		c.bot.AnswerCallbackQuery(ctx, tu.CallbackQuery(query.ID))
+		return
+	}
+	user := query.From
+	chat := query.Message.GetChat()
+	chatID := fmt.Sprintf("%d", chat.ID)
+
+	peerKind := "direct"
+	peerID := fmt.Sprintf("%d", user.ID)
+	if chat.Type != "private" {
+		peerKind = "group"
+		peerID = chatID
+	}
+	metadata := map[string]string{
+		"user_id":    fmt.Sprintf("%d", user.ID),
+		"username":   user.Username,
+		"first_name": user.FirstName,
+		"is_group":   fmt.Sprintf("%t", chat.Type != "private"),
+		"peer_kind":  peerKind,
+		"peer_id":    peerID,
+	}
+	if user.LanguageCode != "" {
+		metadata["language"] = user.LanguageCode
+	}
+
+	senderID := fmt.Sprintf("%d", user.ID)
+	if user.Username != "" {
+		senderID = fmt.Sprintf("%d|%s", user.ID, user.Username)
+	}
+	if !c.IsAllowed(senderID) {
+		c.bot.AnswerCallbackQuery(ctx, tu.CallbackQuery(query.ID))
+		return
+	}
+	if !c.HandleAction(fmt.Sprintf("%d", user.ID), chatID, query.Data, metadata) {
+		c.bot.AnswerCallbackQuery(ctx, tu.CallbackQuery(query.ID).WithText("This choice is no longer available."))
+		return
+	}
+	c.bot.AnswerCallbackQuery(ctx, tu.CallbackQuery(query.ID))
+	c.bot.EditMessageReplyMarkup(ctx, tu.EditMessageReplyMarkup(tu.ID(chat.ID), query.Message.GetMessageID(), nil))
+}
+
+// handleReaction passes on the emoji a user added to or removed from a
+// message. Custom and paid reactions are ignored.
+func (c *TelegramChannel) handleReaction(reaction telego.MessageReactionUpdated) {
+	user := reaction.User
+	if user == nil {
+		return // anonymous
+	}
+	senderID := fmt.Sprintf("%d", user.ID)
+	if user.Username != "" {
+		senderID = fmt.Sprintf("%d|%s", user.ID, user.Username)
+	}
+	chatID := fmt.Sprintf("%d", reaction.Chat.ID)
+	messageID := strconv.Itoa(reaction.MessageID)
+
+	old := reactionEmojis(reaction.OldReaction)
+	current := reactionEmojis(reaction.NewReaction)
+	for emoji := range current {
+		if !old[emoji] {
+			c.HandleReaction(senderID, chatID, messageID, emoji, false)
+		}
+	}
+	for emoji := range old {
+		if !current[emoji] {
+			c.HandleReaction(senderID, chatID, messageID, emoji, true)
+		}
+	}
+}
+
+func reactionEmojis(reactions []telego.ReactionType) map[string]bool {
+	emojis := make(map[string]bool)
+	for _, r := range reactions {
+		if e, ok := r.(*telego.ReactionTypeEmoji); ok {
+			emojis[e.Emoji] = true
+		}
+	}
+	return emojis
+}
+
+// SendPartial shows a reply that is still being generated in the
+// "Thinking..." placeholder. Send later replaces it with the formatted reply.
+func (c *TelegramChannel) SendPartial(ctx context.Context, msg bus.OutboundMessage) error {
+	if !c.IsRunning() {
+		return fmt.Errorf("telegram bot not running")
+	}
+
+	pID, ok := c.placeholders.Load(msg.ChatID)
+	if !ok {
+		return nil
+	}
+	chatID, err := parseChatID(msg.ChatID)
+	if err != nil {
+		return fmt.Errorf("invalid chat ID: %w", err)
+	}
+
+	// Plain text, since the markdown of a partial reply may be unbalanced.
+	_, err = c.bot.EditMessageText(ctx, tu.EditMessageText(tu.ID(chatID), pID.(int), msg.Content+" …"))
+	return err
+}
+
 func (c *TelegramChannel) handleMessage(ctx context.Context, message *telego.Message) error {
 	if message == nil {
 		return fmt.Errorf("message is nil")
@@ -370,6 +522,9 @@ func (c *TelegramChannel) handleMessage(ctx context.Context, message *telego.Mes
 		"peer_kind":  peerKind,
 		"peer_id":    peerID,
 	}
+	if user.LanguageCode != "" {
+		metadata["language"] = user.LanguageCode
+	}
 
 	c.HandleMessage(fmt.Sprintf("%d", user.ID), fmt.Sprintf("%d", chatID), content, mediaPaths, metadata)
 	return nil
@@ -1,12 +1,16 @@
+//go:build !minimal
+
 package channels
 
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/mymmrac/telego"
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
 )
 
 type TelegramCommander interface {
@@ -112,12 +116,8 @@ func (c *cmd) List(ctx context.Context, message telego.Message) error {
 	var response string
 	switch args {
 	case "models":
-		provider := c.config.Agents.Defaults.Provider
-		if provider == "" {
-			provider = "configured default"
-		}
-		response = fmt.Sprintf("Configured Model: %s\nProvider: %s\n\nTo change models, update config.yaml",
-			c.config.Agents.Defaults.Model, provider)
+		response = fmt.Sprintf("Configured Model: %s\n\n%s\nTo change models, update config.json",
+			c.config.Agents.Defaults.Model, c.availableModels(ctx))
 
 	case "channels":
 		var enabled []string
@@ -151,3 +151,30 @@ func (c *cmd) List(ctx context.Context, message telego.Message) error {
 	})
 	return err
 }
+
+// maxListedModels is how many models /list models shows at most.
+const maxListedModels = 30
+
+// availableModels lists the models of the configured providers, those in
+// model_list first.
+func (c *cmd) availableModels(ctx context.Context) string {
+	models, _ := providers.ListAvailableModels(ctx, c.config)
+	sort.SliceStable(models, func(i, j int) bool {
+		return models[i].ModelName != "" && models[j].ModelName == ""
+	})
+
+	var sb strings.Builder
+	sb.WriteString("Available Models:\n")
+	for i, m := range models {
+		if i == maxListedModels {
+			fmt.Fprintf(&sb, "... and %d more (picoclaw models lists all)\n", len(models)-i)
+			break
+		}
+		if m.ModelName != "" {
+			fmt.Fprintf(&sb, "- %s (%s)\n", m.ModelName, m.Model)
+		} else {
+			fmt.Fprintf(&sb, "- %s\n", m.Model)
+		}
+	}
+	return sb.String()
+}
@@ -1,3 +1,5 @@
+//go:build !minimal && !standard
+
 // PicoClaw - Ultra-lightweight personal AI agent
 // WeCom Bot (企业微信智能机器人) channel implementation
 // Uses webhook callback mode for receiving messages and webhook API for sending replies
@@ -24,6 +26,7 @@ import (
 
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/crash"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/utils"
 )
@@ -319,6 +322,8 @@ func (c *WeComBotChannel) handleMessageCallback(ctx context.Context, w http.Resp
 
 // processMessage processes the received message
 func (c *WeComBotChannel) processMessage(ctx context.Context, msg WeComBotMessage) {
+	defer crash.Recover("wecom", nil)
+
 	// Skip unsupported message types
 	if msg.MsgType != "text" && msg.MsgType != "image" && msg.MsgType != "voice" && msg.MsgType != "file" && msg.MsgType != "mixed" {
 		logger.DebugCF("wecom", "Skipping non-supported message type", map[string]interface{}{
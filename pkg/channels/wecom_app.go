@@ -1,3 +1,5 @@
+//go:build !minimal && !standard
+
 // PicoClaw - Ultra-lightweight personal AI agent
 // WeCom App (企业微信自建应用) channel implementation
 // Supports receiving messages via webhook callback and sending messages proactively
@@ -19,6 +21,7 @@ import (
 
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/crash"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/utils"
 )
@@ -394,6 +397,8 @@ func (c *WeComAppChannel) handleMessageCallback(ctx context.Context, w http.Resp
 
 // processMessage processes the received message
 func (c *WeComAppChannel) processMessage(ctx context.Context, msg WeComXMLMessage) {
+	defer crash.Recover("wecom_app", nil)
+
 	// Skip non-text messages for now (can be extended)
 	if msg.MsgType != "text" && msg.MsgType != "image" && msg.MsgType != "voice" {
 		logger.DebugCF("wecom_app", "Skipping non-supported message type", map[string]interface{}{
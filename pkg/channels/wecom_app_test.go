@@ -1,3 +1,5 @@
+//go:build !minimal && !standard
+
 // PicoClaw - Ultra-lightweight personal AI agent
 // WeCom App (企业微信自建应用) channel tests
 
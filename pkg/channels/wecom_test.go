@@ -1,3 +1,5 @@
+//go:build !minimal && !standard
+
 // PicoClaw - Ultra-lightweight personal AI agent
 // WeCom Bot (企业微信智能机器人) channel tests
 
@@ -1,3 +1,5 @@
+//go:build !minimal
+
 package channels
 
 import (
@@ -12,6 +14,7 @@ import (
 
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/crash"
 	"github.com/sipeed/picoclaw/pkg/utils"
 )
 
@@ -105,6 +108,8 @@ func (c *WhatsAppChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 }
 
 func (c *WhatsAppChannel) listen(ctx context.Context) {
+	defer crash.Recover("whatsapp", nil)
+
 	for {
 		select {
 		case <-ctx.Done():
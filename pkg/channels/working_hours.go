@@ -0,0 +1,187 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+package channels
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/vfs"
+)
+
+// maxQueuedPerChannel bounds how many proactive messages are held while a
+// channel is outside its working hours. The oldest message is dropped first.
+const maxQueuedPerChannel = 50
+
+// heldFile returns where the messages held for working hours are kept in
+// workspace, so that a restart does not lose them.
+func heldFile(workspace string) string {
+	return filepath.Join(workspace, "state", "held", "messages.json")
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// workingHours is a parsed config.WorkingHoursConfig.
+type workingHours struct {
+	loc   *time.Location
+	start int // minutes since midnight
+	end   int
+	days  map[time.Weekday]bool // nil means every day
+}
+
+func parseWorkingHours(cfg config.WorkingHoursConfig) (*workingHours, error) {
+	wh := &workingHours{loc: time.Local}
+
+	if cfg.Timezone != "" {
+		loc, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone %q: %w", cfg.Timezone, err)
+		}
+		wh.loc = loc
+	}
+
+	var err error
+	if wh.start, err = parseClock(cfg.Start); err != nil {
+		return nil, fmt.Errorf("invalid start: %w", err)
+	}
+	if wh.end, err = parseClock(cfg.End); err != nil {
+		return nil, fmt.Errorf("invalid end: %w", err)
+	}
+
+	if len(cfg.Days) > 0 {
+		wh.days = make(map[time.Weekday]bool, len(cfg.Days))
+		for _, d := range cfg.Days {
+			key := strings.ToLower(strings.TrimSpace(d))
+			if len(key) > 3 {
+				key = key[:3] // accept "monday" as well as "mon"
+			}
+			day, ok := weekdayNames[key]
+			if !ok {
+				return nil, fmt.Errorf("invalid day %q", d)
+			}
+			wh.days[day] = true
+		}
+	}
+
+	return wh, nil
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// isOpen reports whether now falls inside the window. A window that wraps
+// past midnight belongs to the day it started on.
+func (wh *workingHours) isOpen(now time.Time) bool {
+	local := now.In(wh.loc)
+	minute := local.Hour()*60 + local.Minute()
+
+	if wh.start == wh.end {
+		return wh.dayAllowed(local.Weekday())
+	}
+	if wh.start < wh.end {
+		return minute >= wh.start && minute < wh.end && wh.dayAllowed(local.Weekday())
+	}
+	if minute >= wh.start {
+		return wh.dayAllowed(local.Weekday())
+	}
+	if minute < wh.end {
+		return wh.dayAllowed(local.AddDate(0, 0, -1).Weekday())
+	}
+	return false
+}
+
+func (wh *workingHours) dayAllowed(day time.Weekday) bool {
+	return wh.days == nil || wh.days[day]
+}
+
+// loadWorkingHours parses all configured windows, skipping invalid ones.
+func loadWorkingHours(cfg map[string]config.WorkingHoursConfig) (map[string]*workingHours, []error) {
+	windows := make(map[string]*workingHours, len(cfg))
+	var errs []error
+	for name, c := range cfg {
+		wh, err := parseWorkingHours(c)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("working_hours.%s: %w", name, err))
+			continue
+		}
+		windows[name] = wh
+	}
+	return windows, errs
+}
+
+// loadHeld restores the messages held when the gateway last stopped.
+func (m *Manager) loadHeld() {
+	data, err := vfs.ReadFile(m.heldFile)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			logger.WarnCF("channels", "Failed to read held messages", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+		return
+	}
+	var held map[string][]bus.OutboundMessage
+	if err := json.Unmarshal(data, &held); err != nil {
+		logger.WarnCF("channels", "Ignoring invalid held messages file", map[string]interface{}{
+			"file":  m.heldFile,
+			"error": err.Error(),
+		})
+		return
+	}
+	for name, queue := range held {
+		m.held[name] = queue
+	}
+	if n := m.HeldMessages(); n > 0 {
+		logger.InfoCF("channels", "Restored messages held until working hours", map[string]interface{}{
+			"count": n,
+		})
+	}
+}
+
+// saveHeld writes the held messages to disk, or removes the file once
+// none are left. It must be called with heldMu held.
+func (m *Manager) saveHeld() {
+	if m.heldFile == "" {
+		return
+	}
+	var err error
+	if len(m.held) == 0 {
+		if err = vfs.Remove(m.heldFile); errors.Is(err, fs.ErrNotExist) {
+			err = nil
+		}
+	} else {
+		var data []byte
+		if data, err = json.Marshal(m.held); err == nil {
+			err = vfs.WriteFile(m.heldFile, data, 0600)
+		}
+	}
+	if err != nil {
+		logger.WarnCF("channels", "Failed to save held messages; they will be lost on restart", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package channels
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+type recordingChannel struct {
+	sent []bus.OutboundMessage
+}
+
+func (c *recordingChannel) Name() string                    { return "test" }
+func (c *recordingChannel) Start(ctx context.Context) error { return nil }
+func (c *recordingChannel) Stop(ctx context.Context) error  { return nil }
+func (c *recordingChannel) IsRunning() bool                 { return true }
+func (c *recordingChannel) IsAllowed(senderID string) bool  { return true }
+func (c *recordingChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	c.sent = append(c.sent, msg)
+	return nil
+}
+
+func TestWorkingHoursIsOpen(t *testing.T) {
+	// 2026-03-02 is a Monday.
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 3, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name string
+		cfg  config.WorkingHoursConfig
+		now  time.Time
+		want bool
+	}{
+		{"inside daytime window", config.WorkingHoursConfig{Timezone: "UTC", Start: "09:00", End: "22:00"}, at(2, 12, 0), true},
+		{"before daytime window", config.WorkingHoursConfig{Timezone: "UTC", Start: "09:00", End: "22:00"}, at(2, 8, 59), false},
+		{"end is exclusive", config.WorkingHoursConfig{Timezone: "UTC", Start: "09:00", End: "22:00"}, at(2, 22, 0), false},
+		{"overnight window late evening", config.WorkingHoursConfig{Timezone: "UTC", Start: "20:00", End: "02:00"}, at(2, 23, 30), true},
+		{"overnight window after midnight", config.WorkingHoursConfig{Timezone: "UTC", Start: "20:00", End: "02:00"}, at(3, 1, 0), true},
+		{"overnight window midday", config.WorkingHoursConfig{Timezone: "UTC", Start: "20:00", End: "02:00"}, at(2, 12, 0), false},
+		{"weekday only on saturday", config.WorkingHoursConfig{Timezone: "UTC", Start: "09:00", End: "17:00", Days: []string{"mon", "tue", "wed", "thu", "fri"}}, at(7, 10, 0), false},
+		{"weekday only on monday", config.WorkingHoursConfig{Timezone: "UTC", Start: "09:00", End: "17:00", Days: []string{"Monday"}}, at(2, 10, 0), true},
+		{"timezone shifts window", config.WorkingHoursConfig{Timezone: "Asia/Shanghai", Start: "09:00", End: "17:00"}, at(2, 2, 0), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wh, err := parseWorkingHours(tt.cfg)
+			if err != nil {
+				t.Fatalf("parseWorkingHours() error: %v", err)
+			}
+			if got := wh.isOpen(tt.now); got != tt.want {
+				t.Errorf("isOpen(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWorkingHoursRejectsInvalid(t *testing.T) {
+	invalid := []config.WorkingHoursConfig{
+		{Start: "9am", End: "17:00"},
+		{Start: "09:00", End: "25:00"},
+		{Start: "09:00", End: "17:00", Timezone: "Nowhere/City"},
+		{Start: "09:00", End: "17:00", Days: []string{"funday"}},
+	}
+	for _, cfg := range invalid {
+		if _, err := parseWorkingHours(cfg); err == nil {
+			t.Errorf("parseWorkingHours(%+v) expected error", cfg)
+		}
+	}
+}
+
+func TestManagerHoldsProactiveMessagesOutsideWorkingHours(t *testing.T) {
+	ch := &recordingChannel{}
+	windows, errs := loadWorkingHours(map[string]config.WorkingHoursConfig{
+		"*": {Timezone: "UTC", Start: "09:00", End: "17:00"},
+	})
+	if len(errs) > 0 {
+		t.Fatalf("loadWorkingHours() errors: %v", errs)
+	}
+	m := &Manager{
+		channels:     map[string]Channel{"telegram": ch},
+		workingHours: windows,
+		held:         make(map[string][]bus.OutboundMessage),
+	}
+
+	night := time.Date(2026, 3, 2, 23, 0, 0, 0, time.UTC)
+	msg := bus.OutboundMessage{Channel: "telegram", ChatID: "1", Content: "heartbeat", Proactive: true}
+	if !m.holdOutsideWorkingHours(msg, night) {
+		t.Fatal("expected proactive message to be held at night")
+	}
+
+	m.releaseHeld(context.Background(), night.Add(time.Hour))
+	if len(ch.sent) != 0 {
+		t.Fatalf("expected nothing sent before window opens, got %d", len(ch.sent))
+	}
+
+	morning := time.Date(2026, 3, 3, 9, 1, 0, 0, time.UTC)
+	if m.holdOutsideWorkingHours(msg, morning) {
+		t.Error("message should not be held inside working hours")
+	}
+	m.releaseHeld(context.Background(), morning)
+	if len(ch.sent) != 1 || ch.sent[0].Content != "heartbeat" {
+		t.Errorf("expected held message to be released, got %+v", ch.sent)
+	}
+}
+
+func TestManagerKeepsHeldMessagesAcrossRestarts(t *testing.T) {
+	windows, _ := loadWorkingHours(map[string]config.WorkingHoursConfig{
+		"*": {Timezone: "UTC", Start: "09:00", End: "17:00"},
+	})
+	file := heldFile(t.TempDir())
+	os.MkdirAll(filepath.Dir(file), 0755)
+	newManager := func(ch Channel) *Manager {
+		m := &Manager{
+			channels:     map[string]Channel{"telegram": ch},
+			workingHours: windows,
+			held:         make(map[string][]bus.OutboundMessage),
+			heldFile:     file,
+		}
+		m.loadHeld()
+		return m
+	}
+
+	night := time.Date(2026, 3, 2, 23, 0, 0, 0, time.UTC)
+	before := newManager(&recordingChannel{})
+	before.holdOutsideWorkingHours(bus.OutboundMessage{Channel: "telegram", ChatID: "1", Content: "heartbeat", Proactive: true}, night)
+
+	ch := &recordingChannel{}
+	after := newManager(ch)
+	if n := after.HeldMessages(); n != 1 {
+		t.Fatalf("restarted manager holds %d messages, want 1", n)
+	}
+	after.releaseHeld(context.Background(), time.Date(2026, 3, 3, 9, 1, 0, 0, time.UTC))
+	if len(ch.sent) != 1 || ch.sent[0].Content != "heartbeat" {
+		t.Errorf("expected the restored message to be released, got %+v", ch.sent)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("held messages file still there after release: %v", err)
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync/atomic"
+	"time"
 
 	"github.com/caarlos0/env/v11"
 )
@@ -47,16 +49,236 @@ func (f *FlexibleStringSlice) UnmarshalJSON(data []byte) error {
 }
 
 type Config struct {
-	Agents    AgentsConfig    `json:"agents"`
-	Bindings  []AgentBinding  `json:"bindings,omitempty"`
-	Session   SessionConfig   `json:"session,omitempty"`
-	Channels  ChannelsConfig  `json:"channels"`
-	Providers ProvidersConfig `json:"providers,omitempty"`
-	ModelList []ModelConfig   `json:"model_list"` // New model-centric provider configuration
-	Gateway   GatewayConfig   `json:"gateway"`
-	Tools     ToolsConfig     `json:"tools"`
-	Heartbeat HeartbeatConfig `json:"heartbeat"`
-	Devices   DevicesConfig   `json:"devices"`
+	Agents        AgentsConfig        `json:"agents"`
+	Bindings      []AgentBinding      `json:"bindings,omitempty"`
+	Session       SessionConfig       `json:"session,omitempty"`
+	Channels      ChannelsConfig      `json:"channels"`
+	Providers     ProvidersConfig     `json:"providers,omitempty"`
+	ModelList     []ModelConfig       `json:"model_list"` // New model-centric provider configuration
+	Gateway       GatewayConfig       `json:"gateway"`
+	Tools         ToolsConfig         `json:"tools"`
+	Heartbeat     HeartbeatConfig     `json:"heartbeat"`
+	Devices       DevicesConfig       `json:"devices"`
+	Messages      MessagesConfig      `json:"messages,omitempty"`
+	Observability ObservabilityConfig `json:"observability"`
+	Governor      GovernorConfig      `json:"governor"`
+	Network       NetworkConfig       `json:"network"`
+	FileUploads   FileUploadsConfig   `json:"file_uploads"`
+	Encryption    EncryptionConfig    `json:"encryption"`
+	Storage       StorageConfig       `json:"storage"`
+	RetryQueue    RetryQueueConfig    `json:"retry_queue"`
+	Usage         UsageConfig         `json:"usage"`
+	Memory        MemoryConfig        `json:"memory"`
+	Auth          AuthConfig          `json:"auth"`
+
+	// CustomProviders declares OpenAI-compatible providers that model_list
+	// entries can use by name, like the built-in ones.
+	CustomProviders []CustomProviderConfig `json:"custom_providers,omitempty"`
+	// ProviderPlugins declares providers implemented by external programs.
+	ProviderPlugins []ProviderPluginConfig `json:"provider_plugins,omitempty"`
+}
+
+// UsageConfig records the tokens used per session and per model. Pricing
+// maps a model name, as shown by picoclaw usage, to its price; models
+// without a price are counted at no cost.
+// MemoryConfig configures the upkeep of the agents' memory files, run
+// daily by the gateway when Maintenance is set: repeated entries are
+// removed, daily notes older than NotesRetentionDays are deleted (0 keeps
+// them), and empty notes are cleaned up.
+type MemoryConfig struct {
+	Maintenance        bool `json:"maintenance" env:"PICOCLAW_MEMORY_MAINTENANCE"`
+	NotesRetentionDays int  `json:"notes_retention_days" env:"PICOCLAW_MEMORY_NOTES_RETENTION_DAYS"`
+}
+
+type UsageConfig struct {
+	Enabled bool                    `json:"enabled" env:"PICOCLAW_USAGE_ENABLED"`
+	Pricing map[string]ModelPricing `json:"pricing,omitempty"`
+}
+
+// ModelPricing is a model's price in dollars per 1K tokens. CachedInput
+// applies to prompt tokens read from the provider's cache and defaults to
+// Input.
+type ModelPricing struct {
+	Input       float64 `json:"input"`
+	CachedInput float64 `json:"cached_input,omitempty"`
+	Output      float64 `json:"output"`
+}
+
+// RetryQueueConfig parks turns that fail because the provider is down or
+// overloaded, tells the user a delayed answer is coming, and answers them
+// once a retry succeeds. Turns parked longer than MaxAgeMinutes are given
+// up with an error reply.
+type RetryQueueConfig struct {
+	Enabled              bool `json:"enabled" env:"PICOCLAW_RETRY_QUEUE_ENABLED"`
+	MaxQueued            int  `json:"max_queued" env:"PICOCLAW_RETRY_QUEUE_MAX_QUEUED"`
+	MaxAgeMinutes        int  `json:"max_age_minutes" env:"PICOCLAW_RETRY_QUEUE_MAX_AGE_MINUTES"`
+	ProbeIntervalSeconds int  `json:"probe_interval_seconds" env:"PICOCLAW_RETRY_QUEUE_PROBE_INTERVAL_SECONDS"`
+}
+
+// GovernorConfig configures the memory governor. Above SoftLimitMB of
+// resident memory, background jobs are deferred and caches and idle sessions
+// are released; above HardLimitMB, sessions idle for more than a minute are
+// released as well. Background jobs are also deferred while any thermal
+// zone is at MaxTempC or above, or the device runs on a battery charged to
+// MinBatteryPercent or less. Zero disables a limit.
+type GovernorConfig struct {
+	Enabled              bool `json:"enabled" env:"PICOCLAW_GOVERNOR_ENABLED"`
+	SoftLimitMB          int  `json:"soft_limit_mb" env:"PICOCLAW_GOVERNOR_SOFT_LIMIT_MB"`
+	HardLimitMB          int  `json:"hard_limit_mb" env:"PICOCLAW_GOVERNOR_HARD_LIMIT_MB"`
+	CheckIntervalSeconds int  `json:"check_interval_seconds" env:"PICOCLAW_GOVERNOR_CHECK_INTERVAL_SECONDS"`
+	IdleSessionMinutes   int  `json:"idle_session_minutes" env:"PICOCLAW_GOVERNOR_IDLE_SESSION_MINUTES"`
+	MaxTempC             int  `json:"max_temp_c" env:"PICOCLAW_GOVERNOR_MAX_TEMP_C"`
+	MinBatteryPercent    int  `json:"min_battery_percent" env:"PICOCLAW_GOVERNOR_MIN_BATTERY_PERCENT"`
+}
+
+// FileUploadsConfig uploads documents users send (PDF, plain text) to the
+// provider's Files API, so later turns reference them by ID instead of
+// re-sending their content. Uploads are deleted after RetentionHours.
+type FileUploadsConfig struct {
+	Enabled        bool `json:"enabled" env:"PICOCLAW_FILE_UPLOADS_ENABLED"`
+	MaxSizeMB      int  `json:"max_size_mb" env:"PICOCLAW_FILE_UPLOADS_MAX_SIZE_MB"`
+	RetentionHours int  `json:"retention_hours" env:"PICOCLAW_FILE_UPLOADS_RETENTION_HOURS"`
+}
+
+// EncryptionConfig encrypts session histories and memory files at rest.
+// The key is derived from Passphrase if set, otherwise read from KeyFile
+// (generated on first use). Keep the key file off the device, or use a
+// passphrase, for protection against physical access.
+type EncryptionConfig struct {
+	Enabled    bool   `json:"enabled" env:"PICOCLAW_ENCRYPTION_ENABLED"`
+	Passphrase string `json:"passphrase,omitempty" env:"PICOCLAW_ENCRYPTION_PASSPHRASE"`
+	KeyFile    string `json:"key_file" env:"PICOCLAW_ENCRYPTION_KEY_FILE"`
+}
+
+// AuthConfig selects where the credentials of picoclaw auth login are
+// kept. Store is "file" (~/.picoclaw/auth.json) or "keyring", the OS
+// keyring: the login keychain on macOS, or the Secret Service on Linux.
+type AuthConfig struct {
+	Store string `json:"store" env:"PICOCLAW_AUTH_STORE"`
+}
+
+// StorageConfig selects where memory, sessions and the files written by
+// the file tools are stored. Backend is "local" (the workspace directory)
+// or "webdav", which keeps them under WebDAV.URL.
+type StorageConfig struct {
+	Backend string       `json:"backend" env:"PICOCLAW_STORAGE_BACKEND"`
+	WebDAV  WebDAVConfig `json:"webdav"`
+}
+
+type WebDAVConfig struct {
+	URL      string `json:"url" env:"PICOCLAW_STORAGE_WEBDAV_URL"`
+	Username string `json:"username,omitempty" env:"PICOCLAW_STORAGE_WEBDAV_USERNAME"`
+	Password string `json:"password,omitempty" env:"PICOCLAW_STORAGE_WEBDAV_PASSWORD"`
+}
+
+// NetworkConfig tunes and restricts outbound connections.
+type NetworkConfig struct {
+	DNSCacheTTLSeconds     int  `json:"dns_cache_ttl_seconds" env:"PICOCLAW_NETWORK_DNS_CACHE_TTL_SECONDS"`
+	PrewarmConnections     bool `json:"prewarm_connections" env:"PICOCLAW_NETWORK_PREWARM_CONNECTIONS"`
+	PrewarmIntervalSeconds int  `json:"prewarm_interval_seconds" env:"PICOCLAW_NETWORK_PREWARM_INTERVAL_SECONDS"`
+	// EgressPolicy is the path of a JSON file restricting the hosts each
+	// subsystem may connect to. Empty means unrestricted.
+	EgressPolicy  string              `json:"egress_policy,omitempty" env:"PICOCLAW_NETWORK_EGRESS_POLICY"`
+	ProviderRetry ProviderRetryConfig `json:"provider_retry"`
+}
+
+// ProviderRetryConfig retries provider requests that fail with 429 or a
+// server error, or whose connection is dropped. Retries wait with
+// exponential backoff from InitialDelayMS up to MaxDelaySeconds, or as long
+// as the server's Retry-After asks. A request is attempted at most
+// MaxAttempts times and is not retried past BudgetSeconds after it started.
+type ProviderRetryConfig struct {
+	Enabled         bool `json:"enabled" env:"PICOCLAW_NETWORK_PROVIDER_RETRY_ENABLED"`
+	MaxAttempts     int  `json:"max_attempts" env:"PICOCLAW_NETWORK_PROVIDER_RETRY_MAX_ATTEMPTS"`
+	InitialDelayMS  int  `json:"initial_delay_ms" env:"PICOCLAW_NETWORK_PROVIDER_RETRY_INITIAL_DELAY_MS"`
+	MaxDelaySeconds int  `json:"max_delay_seconds" env:"PICOCLAW_NETWORK_PROVIDER_RETRY_MAX_DELAY_SECONDS"`
+	BudgetSeconds   int  `json:"budget_seconds" env:"PICOCLAW_NETWORK_PROVIDER_RETRY_BUDGET_SECONDS"`
+}
+
+type ObservabilityConfig struct {
+	Alerts         AlertsConfig         `json:"alerts"`
+	ErrorReporting ErrorReportingConfig `json:"error_reporting"`
+	LogEscalation  LogEscalationConfig  `json:"log_escalation"`
+	ShutdownReport ShutdownReportConfig `json:"shutdown_report"`
+	Transcripts    TranscriptsConfig    `json:"transcripts"`
+}
+
+// TranscriptsConfig records each LLM request of the agent and its response
+// as JSON lines under transcripts/ in the workspace, for debugging prompts
+// and replaying conversations in tests. Known kinds of secrets are
+// redacted, as are the matches of the regular expressions in Redact.
+type TranscriptsConfig struct {
+	Enabled bool     `json:"enabled" env:"PICOCLAW_OBSERVABILITY_TRANSCRIPTS_ENABLED"`
+	Redact  []string `json:"redact,omitempty"`
+}
+
+// ShutdownReportConfig logs a summary of the run when the gateway stops
+// gracefully, and also sends it to Channel/ChatID when those are set.
+type ShutdownReportConfig struct {
+	Enabled bool   `json:"enabled" env:"PICOCLAW_OBSERVABILITY_SHUTDOWN_REPORT_ENABLED"`
+	Channel string `json:"channel,omitempty" env:"PICOCLAW_OBSERVABILITY_SHUTDOWN_REPORT_CHANNEL"`
+	ChatID  string `json:"chat_id,omitempty" env:"PICOCLAW_OBSERVABILITY_SHUTDOWN_REPORT_CHAT_ID"`
+}
+
+// LogEscalationConfig raises the log level of a component to Level for
+// DurationMinutes once it logs Threshold errors within WindowSeconds,
+// together with the components that logged for the same agent turns, and
+// then restores it.
+type LogEscalationConfig struct {
+	Enabled         bool   `json:"enabled" env:"PICOCLAW_OBSERVABILITY_LOG_ESCALATION_ENABLED"`
+	Threshold       int    `json:"threshold" env:"PICOCLAW_OBSERVABILITY_LOG_ESCALATION_THRESHOLD"`
+	WindowSeconds   int    `json:"window_seconds" env:"PICOCLAW_OBSERVABILITY_LOG_ESCALATION_WINDOW_SECONDS"`
+	DurationMinutes int    `json:"duration_minutes" env:"PICOCLAW_OBSERVABILITY_LOG_ESCALATION_DURATION_MINUTES"`
+	Level           string `json:"level" env:"PICOCLAW_OBSERVABILITY_LOG_ESCALATION_LEVEL"`
+}
+
+// ErrorReportingConfig forwards recovered panics to a webhook that accepts
+// Sentry event payloads.
+type ErrorReportingConfig struct {
+	Enabled     bool   `json:"enabled" env:"PICOCLAW_OBSERVABILITY_ERROR_REPORTING_ENABLED"`
+	WebhookURL  string `json:"webhook_url" env:"PICOCLAW_OBSERVABILITY_ERROR_REPORTING_WEBHOOK_URL"`
+	Environment string `json:"environment,omitempty" env:"PICOCLAW_OBSERVABILITY_ERROR_REPORTING_ENVIRONMENT"`
+}
+
+// AlertsConfig configures in-process alert rules. Alerts are delivered to
+// Channel/ChatID; when those are empty they are only logged.
+type AlertsConfig struct {
+	Enabled              bool            `json:"enabled" env:"PICOCLAW_OBSERVABILITY_ALERTS_ENABLED"`
+	Channel              string          `json:"channel" env:"PICOCLAW_OBSERVABILITY_ALERTS_CHANNEL"`
+	ChatID               string          `json:"chat_id" env:"PICOCLAW_OBSERVABILITY_ALERTS_CHAT_ID"`
+	CheckIntervalSeconds int             `json:"check_interval_seconds" env:"PICOCLAW_OBSERVABILITY_ALERTS_CHECK_INTERVAL_SECONDS"`
+	CooldownMinutes      int             `json:"cooldown_minutes" env:"PICOCLAW_OBSERVABILITY_ALERTS_COOLDOWN_MINUTES"`
+	ErrorRate            RateAlertConfig `json:"error_rate"`
+	ProviderFailures     RateAlertConfig `json:"provider_failures"`
+	Disk                 DiskAlertConfig `json:"disk"`
+}
+
+// RateAlertConfig fires when at least Threshold events happen within
+// WindowMinutes.
+type RateAlertConfig struct {
+	Enabled       bool `json:"enabled"`
+	Threshold     int  `json:"threshold"`
+	WindowMinutes int  `json:"window_minutes"`
+}
+
+// DiskAlertConfig fires when free space on the filesystem holding Path
+// (default: the workspace) drops below MinFreePercent.
+type DiskAlertConfig struct {
+	Enabled        bool    `json:"enabled"`
+	Path           string  `json:"path,omitempty"`
+	MinFreePercent float64 `json:"min_free_percent"`
+}
+
+// MessagesConfig customizes text that picoclaw itself sends to users.
+// Templates are keyed by language and then message key, e.g.
+// {"en": {"processing_error": "Oops: {{.Error}}"}}. Built-in languages are
+// "en" and "zh".
+type MessagesConfig struct {
+	Language  string                       `json:"language,omitempty" env:"PICOCLAW_MESSAGES_LANGUAGE"`
+	Templates map[string]map[string]string `json:"templates,omitempty"`
+	// UserLanguages overrides the language per user, keyed by
+	// "<channel>:<sender_id>", e.g. {"telegram:123456": "zh"}.
+	UserLanguages map[string]string `json:"user_languages,omitempty"`
 }
 
 // MarshalJSON implements custom JSON marshaling for Config
@@ -136,6 +358,17 @@ type AgentConfig struct {
 	Model     *AgentModelConfig `json:"model,omitempty"`
 	Skills    []string          `json:"skills,omitempty"`
 	Subagents *SubagentsConfig  `json:"subagents,omitempty"`
+	// Account selects the stored provider login the agent's model uses,
+	// overriding the account of its model_list entry, so that agents can
+	// use different accounts of the same provider.
+	Account string `json:"account,omitempty"`
+	// Summarizer overrides the summarizer settings of the defaults that
+	// it sets.
+	Summarizer *SummarizerConfig `json:"summarizer,omitempty"`
+	// ResponseCache replaces the response cache settings of the defaults.
+	ResponseCache *ResponseCacheConfig `json:"response_cache,omitempty"`
+	// Tools names the only tools the agent is offered; empty is all.
+	Tools []string `json:"tools,omitempty"`
 }
 
 type SubagentsConfig struct {
@@ -164,6 +397,16 @@ type AgentBinding struct {
 type SessionConfig struct {
 	DMScope       string              `json:"dm_scope,omitempty"`
 	IdentityLinks map[string][]string `json:"identity_links,omitempty"`
+	Titles        SessionTitlesConfig `json:"titles"`
+}
+
+// SessionTitlesConfig names sessions automatically. Once a session has
+// AfterTurns user messages, Model (the agent's model if empty) is asked
+// for a short title and tags.
+type SessionTitlesConfig struct {
+	Enabled    bool   `json:"enabled" env:"PICOCLAW_SESSION_TITLES_ENABLED"`
+	Model      string `json:"model,omitempty" env:"PICOCLAW_SESSION_TITLES_MODEL"`
+	AfterTurns int    `json:"after_turns" env:"PICOCLAW_SESSION_TITLES_AFTER_TURNS"`
 }
 
 type AgentDefaults struct {
@@ -177,6 +420,95 @@ type AgentDefaults struct {
 	MaxTokens           int      `json:"max_tokens" env:"PICOCLAW_AGENTS_DEFAULTS_MAX_TOKENS"`
 	Temperature         *float64 `json:"temperature,omitempty" env:"PICOCLAW_AGENTS_DEFAULTS_TEMPERATURE"`
 	MaxToolIterations   int      `json:"max_tool_iterations" env:"PICOCLAW_AGENTS_DEFAULTS_MAX_TOOL_ITERATIONS"`
+	StreamToolCalls     bool     `json:"stream_tool_calls,omitempty" env:"PICOCLAW_AGENTS_DEFAULTS_STREAM_TOOL_CALLS"`
+	StreamReplies       bool     `json:"stream_replies,omitempty" env:"PICOCLAW_AGENTS_DEFAULTS_STREAM_REPLIES"`
+	// Seed is sent with every request to providers that take one. Unset,
+	// each turn gets a random seed, which is recorded with the reply.
+	Seed *int `json:"seed,omitempty" env:"PICOCLAW_AGENTS_DEFAULTS_SEED"`
+	// EmbeddingModel turns text into vectors for semantic search: the
+	// model_name of a model_list entry, or "local/hash" to compute them
+	// without a model.
+	EmbeddingModel string `json:"embedding_model,omitempty" env:"PICOCLAW_AGENTS_DEFAULTS_EMBEDDING_MODEL"`
+	// MaxBackgroundTasks is how many spawned tasks run at once per agent;
+	// more are queued.
+	MaxBackgroundTasks int `json:"max_background_tasks,omitempty" env:"PICOCLAW_AGENTS_DEFAULTS_MAX_BACKGROUND_TASKS"`
+	// RateLimit shares the provider quota between interactive turns and
+	// background work.
+	RateLimit RateLimitConfig `json:"rate_limit"`
+	// Summarizer condenses the old messages of long sessions.
+	Summarizer SummarizerConfig `json:"summarizer"`
+	// ResponseCache answers repeated identical requests from disk.
+	ResponseCache ResponseCacheConfig `json:"response_cache"`
+	// LatencyBudget sets how long turns should take, per channel.
+	LatencyBudget LatencyBudgetConfig `json:"latency_budget"`
+	// StrictModels refuses model_list entries whose model the capability
+	// catalog does not know, for providers it has models of. Otherwise
+	// they are used with a warning.
+	StrictModels bool `json:"strict_models,omitempty" env:"PICOCLAW_AGENTS_DEFAULTS_STRICT_MODELS"`
+	// Truncation decides what happens to a request too long for the
+	// model's context window: "drop_oldest" leaves out its oldest
+	// messages, "summarize" summarizes the session first, and "error"
+	// fails the turn. Models of unknown context window are not checked.
+	Truncation string `json:"truncation,omitempty" env:"PICOCLAW_AGENTS_DEFAULTS_TRUNCATION"`
+}
+
+// LatencyBudgetConfig is a soft limit on how long a turn takes, in
+// seconds: the budget of the channel in Channels, or Seconds for other
+// channels, where zero is no budget. A turn that runs past its budget
+// hurries: its remaining model requests go to FastModel, if set, without
+// tools, and tool calls not yet started are skipped.
+type LatencyBudgetConfig struct {
+	Seconds   int            `json:"seconds,omitempty" env:"PICOCLAW_AGENTS_DEFAULTS_LATENCY_BUDGET_SECONDS"`
+	Channels  map[string]int `json:"channels,omitempty"`
+	FastModel string         `json:"fast_model,omitempty" env:"PICOCLAW_AGENTS_DEFAULTS_LATENCY_BUDGET_FAST_MODEL"`
+}
+
+// For returns the budget of turns on channel, zero if there is none.
+func (c LatencyBudgetConfig) For(channel string) time.Duration {
+	seconds, ok := c.Channels[channel]
+	if !ok {
+		seconds = c.Seconds
+	}
+	if seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// ResponseCacheConfig caches model responses in the agent workspace for
+// TTLMinutes, keyed on the model, messages, tools and settings of the
+// request, so that repeating a request costs nothing. Meant for testing
+// and batch work, where the same prompts are sent again.
+type ResponseCacheConfig struct {
+	Enabled    bool `json:"enabled" env:"PICOCLAW_AGENTS_DEFAULTS_RESPONSE_CACHE_ENABLED"`
+	TTLMinutes int  `json:"ttl_minutes,omitempty" env:"PICOCLAW_AGENTS_DEFAULTS_RESPONSE_CACHE_TTL_MINUTES"`
+}
+
+// SummarizerConfig selects how old messages of long sessions are condensed
+// into a summary. Strategy is one of:
+//
+//   - "model": the agent's model, or Model if set, summarizes them
+//   - "map_reduce": Model summarizes chunks of ChunkTokens each and then
+//     merges the summaries, for sessions too long for one request
+//   - "extractive": the first sentences of the messages are kept, without
+//     any model request
+//
+// MaxTokens limits the length of summaries per strategy.
+type SummarizerConfig struct {
+	Strategy    string         `json:"strategy,omitempty" env:"PICOCLAW_AGENTS_DEFAULTS_SUMMARIZER_STRATEGY"`
+	Model       string         `json:"model,omitempty" env:"PICOCLAW_AGENTS_DEFAULTS_SUMMARIZER_MODEL"`
+	MaxTokens   map[string]int `json:"max_tokens,omitempty"`
+	ChunkTokens int            `json:"chunk_tokens,omitempty" env:"PICOCLAW_AGENTS_DEFAULTS_SUMMARIZER_CHUNK_TOKENS"`
+}
+
+// RateLimitConfig is the provider quota all agents share. Requests wait
+// until the quota allows them, interactive turns first; background tasks,
+// cron jobs, heartbeats, summaries and titles may not use the last
+// BackgroundReserve percent of it. Zero limits are not enforced.
+type RateLimitConfig struct {
+	RequestsPerMinute int `json:"requests_per_minute,omitempty" env:"PICOCLAW_AGENTS_DEFAULTS_RATE_LIMIT_REQUESTS_PER_MINUTE"`
+	TokensPerMinute   int `json:"tokens_per_minute,omitempty" env:"PICOCLAW_AGENTS_DEFAULTS_RATE_LIMIT_TOKENS_PER_MINUTE"`
+	BackgroundReserve int `json:"background_reserve,omitempty" env:"PICOCLAW_AGENTS_DEFAULTS_RATE_LIMIT_BACKGROUND_RESERVE"`
 }
 
 type ChannelsConfig struct {
@@ -192,6 +524,23 @@ type ChannelsConfig struct {
 	OneBot   OneBotConfig   `json:"onebot"`
 	WeCom    WeComConfig    `json:"wecom"`
 	WeComApp WeComAppConfig `json:"wecom_app"`
+	MQTT     MQTTConfig     `json:"mqtt"`
+	Serial   SerialConfig   `json:"serial"`
+
+	// WorkingHours limits when proactive messages (heartbeat, cron, device
+	// alerts) may be delivered, keyed by channel name. The "*" key applies
+	// to channels without their own entry. Replies to user messages are
+	// never held back.
+	WorkingHours map[string]WorkingHoursConfig `json:"working_hours,omitempty"`
+}
+
+// WorkingHoursConfig is a daily delivery window. Start and End use 24h
+// "HH:MM" format; a window with End before Start wraps past midnight.
+type WorkingHoursConfig struct {
+	Timezone string   `json:"timezone,omitempty"` // IANA name, e.g. "Europe/Berlin"; empty means local time
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+	Days     []string `json:"days,omitempty"` // "mon".."sun"; empty means every day
 }
 
 type WhatsAppConfig struct {
@@ -223,6 +572,32 @@ type DiscordConfig struct {
 	MentionOnly bool                `json:"mention_only" env:"PICOCLAW_CHANNELS_DISCORD_MENTION_ONLY"`
 }
 
+// MQTTConfig connects the agent to an MQTT broker. Messages published to
+// CommandTopic are handled as user messages and answered on ResponseTopic,
+// or on the reply_to topic a JSON command names.
+type MQTTConfig struct {
+	Enabled       bool                `json:"enabled" env:"PICOCLAW_CHANNELS_MQTT_ENABLED"`
+	Broker        string              `json:"broker" env:"PICOCLAW_CHANNELS_MQTT_BROKER"`
+	ClientID      string              `json:"client_id" env:"PICOCLAW_CHANNELS_MQTT_CLIENT_ID"`
+	Username      string              `json:"username" env:"PICOCLAW_CHANNELS_MQTT_USERNAME"`
+	Password      string              `json:"password" env:"PICOCLAW_CHANNELS_MQTT_PASSWORD"`
+	CommandTopic  string              `json:"command_topic" env:"PICOCLAW_CHANNELS_MQTT_COMMAND_TOPIC"`
+	ResponseTopic string              `json:"response_topic" env:"PICOCLAW_CHANNELS_MQTT_RESPONSE_TOPIC"`
+	QoS           int                 `json:"qos" env:"PICOCLAW_CHANNELS_MQTT_QOS"`
+	AllowFrom     FlexibleStringSlice `json:"allow_from" env:"PICOCLAW_CHANNELS_MQTT_ALLOW_FROM"`
+	// AllowReplyTo lists the topic filters a command's reply_to may name;
+	// replies go to ResponseTopic otherwise.
+	AllowReplyTo FlexibleStringSlice `json:"allow_reply_to,omitempty" env:"PICOCLAW_CHANNELS_MQTT_ALLOW_REPLY_TO"`
+}
+
+// SerialConfig serves the agent on a serial console, so boards without
+// networking can be used over UART.
+type SerialConfig struct {
+	Enabled  bool   `json:"enabled" env:"PICOCLAW_CHANNELS_SERIAL_ENABLED"`
+	Device   string `json:"device" env:"PICOCLAW_CHANNELS_SERIAL_DEVICE"`
+	BaudRate int    `json:"baud_rate" env:"PICOCLAW_CHANNELS_SERIAL_BAUD_RATE"`
+}
+
 type MaixCamConfig struct {
 	Enabled   bool                `json:"enabled" env:"PICOCLAW_CHANNELS_MAIXCAM_ENABLED"`
 	Host      string              `json:"host" env:"PICOCLAW_CHANNELS_MAIXCAM_HOST"`
@@ -324,6 +699,9 @@ type ProvidersConfig struct {
 	GitHubCopilot ProviderConfig       `json:"github_copilot"`
 	Antigravity   ProviderConfig       `json:"antigravity"`
 	Qwen          ProviderConfig       `json:"qwen"`
+	Mistral       ProviderConfig       `json:"mistral"`
+	Cohere        ProviderConfig       `json:"cohere"`
+	XAI           ProviderConfig       `json:"xai"`
 }
 
 // IsEmpty checks if all provider configs are empty (no API keys or API bases set)
@@ -345,7 +723,10 @@ func (p ProvidersConfig) IsEmpty() bool {
 		p.VolcEngine.APIKey == "" && p.VolcEngine.APIBase == "" &&
 		p.GitHubCopilot.APIKey == "" && p.GitHubCopilot.APIBase == "" &&
 		p.Antigravity.APIKey == "" && p.Antigravity.APIBase == "" &&
-		p.Qwen.APIKey == "" && p.Qwen.APIBase == ""
+		p.Qwen.APIKey == "" && p.Qwen.APIBase == "" &&
+		p.Mistral.APIKey == "" && p.Mistral.APIBase == "" &&
+		p.Cohere.APIKey == "" && p.Cohere.APIBase == "" &&
+		p.XAI.APIKey == "" && p.XAI.APIBase == ""
 }
 
 // MarshalJSON implements custom JSON marshaling for ProvidersConfig
@@ -390,10 +771,151 @@ type ModelConfig struct {
 	AuthMethod  string `json:"auth_method,omitempty"`  // Authentication method: oauth, token
 	ConnectMode string `json:"connect_mode,omitempty"` // Connection mode: stdio, grpc
 	Workspace   string `json:"workspace,omitempty"`    // Workspace path for CLI-based providers
+	// Account selects the stored login to use with auth_method oauth or
+	// token, as labelled by "picoclaw auth login --account"; empty is the
+	// unlabelled one.
+	Account string `json:"account,omitempty"`
+
+	// Rate limits of this entry, shared by all agents that use it. Zero
+	// limits are not enforced.
+	RPM           int `json:"rpm,omitempty"`            // Requests per minute
+	TPM           int `json:"tpm,omitempty"`            // Tokens per minute
+	MaxConcurrent int `json:"max_concurrent,omitempty"` // Requests in flight at once
 
 	// Optional optimizations
-	RPM            int    `json:"rpm,omitempty"`              // Requests per minute limit
 	MaxTokensField string `json:"max_tokens_field,omitempty"` // Field name for max tokens (e.g., "max_completion_tokens")
+
+	// Capabilities overrides the built-in capability catalog for this model.
+	Capabilities *ModelCapabilities `json:"capabilities,omitempty"`
+
+	// Middleware names registered provider middleware to send this model's
+	// requests through, outermost first.
+	Middleware []string `json:"middleware,omitempty"`
+
+	// SafetySettings sets the block threshold of Gemini's harm categories,
+	// by category name or "all"; other providers ignore it.
+	SafetySettings map[string]string `json:"safety_settings,omitempty"`
+
+	// Bridge launches the GitHub Copilot bridge when it is not running at
+	// api_base; other providers ignore it.
+	Bridge *CopilotBridgeConfig `json:"bridge,omitempty"`
+
+	// API selects the OpenAI API of an openai/ entry: "chat_completions",
+	// the default, or "responses".
+	API string `json:"api,omitempty"`
+	// BuiltinTools names the OpenAI built-in tools, "web_search" and
+	// "code_interpreter", offered with the Responses API; other providers
+	// ignore it.
+	BuiltinTools []string `json:"builtin_tools,omitempty"`
+
+	// Timeouts bounds the requests of this entry, so that a stuck upstream
+	// cannot hold an agent indefinitely.
+	Timeouts *ProviderTimeouts `json:"timeouts,omitempty"`
+
+	// Fixture is the file of scripted responses of a mock/ entry; other
+	// providers ignore it.
+	Fixture string `json:"fixture,omitempty"`
+}
+
+// ProviderTimeouts bounds requests to a provider, in seconds. A request
+// fails after ConnectSeconds without a connection or ReadSeconds without
+// data, whether waiting for the reply to start or between the parts of a
+// streamed one; TotalSeconds bounds the whole request. Zero keeps the
+// default, 30 for connect, 300 for read and no total limit, and a negative
+// value removes the limit. Providers that run a program only have the
+// total limit.
+type ProviderTimeouts struct {
+	ConnectSeconds int `json:"connect_seconds,omitempty"`
+	ReadSeconds    int `json:"read_seconds,omitempty"`
+	TotalSeconds   int `json:"total_seconds,omitempty"`
+}
+
+// CopilotBridgeConfig is the program that serves the GitHub Copilot SDK
+// protocol, usually "copilot --server --port 4321". With connect_mode
+// grpc it is launched when nothing listens at api_base and kept running;
+// with stdio it is run for the provider and spoken to over its stdin.
+type CopilotBridgeConfig struct {
+	Command string   `json:"command"` // Program to run; empty is "copilot"
+	Args    []string `json:"args,omitempty"`
+	// Env adds variables to the program's environment, on top of
+	// picoclaw's own.
+	Env map[string]string `json:"env,omitempty"`
+	// StartTimeoutSeconds bounds the wait for a launched bridge to
+	// listen; zero is 30.
+	StartTimeoutSeconds int `json:"start_timeout_seconds,omitempty"`
+}
+
+// CustomProviderConfig declares an OpenAI-compatible provider. A
+// model_list entry whose model is "<name>/<model-id>" uses it.
+type CustomProviderConfig struct {
+	Name    string `json:"name"`     // Protocol prefix in model_list, e.g. "acme"
+	APIBase string `json:"api_base"` // Default API endpoint URL; an entry's api_base overrides it
+	// AuthHeader is the header that carries the key, as "Name: value"
+	// with {api_key} standing for the entry's api_key. Empty is
+	// "Authorization: Bearer {api_key}".
+	AuthHeader string `json:"auth_header,omitempty"`
+	// ModelPrefix is put before the model ID sent to the API, for APIs
+	// whose model IDs share a path such as "accounts/acme/models/".
+	ModelPrefix string `json:"model_prefix,omitempty"`
+}
+
+// Validate checks if the CustomProviderConfig has all required fields.
+func (c *CustomProviderConfig) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if strings.Contains(c.Name, "/") {
+		return fmt.Errorf("name %q must not contain '/'", c.Name)
+	}
+	if c.APIBase == "" {
+		return fmt.Errorf("api_base is required")
+	}
+	if c.AuthHeader != "" {
+		if name, _, ok := strings.Cut(c.AuthHeader, ":"); !ok || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("auth_header %q must be \"Name: value\"", c.AuthHeader)
+		}
+	}
+	return nil
+}
+
+// ProviderPluginConfig declares a provider implemented by an external
+// program, which is run for each request with the request as JSON on its
+// stdin and writes the response as JSON to its stdout. A model_list entry
+// whose model is "<name>/<model-id>" uses it.
+type ProviderPluginConfig struct {
+	Name    string   `json:"name"`    // Protocol prefix in model_list, e.g. "acme"
+	Command string   `json:"command"` // Program to run
+	Args    []string `json:"args,omitempty"`
+	// Env adds variables to the program's environment, on top of
+	// picoclaw's own.
+	Env map[string]string `json:"env,omitempty"`
+	// TimeoutSeconds bounds a request; zero is 120.
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+}
+
+// Validate checks if the ProviderPluginConfig has all required fields.
+func (c *ProviderPluginConfig) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if strings.Contains(c.Name, "/") {
+		return fmt.Errorf("name %q must not contain '/'", c.Name)
+	}
+	if c.Command == "" {
+		return fmt.Errorf("command is required")
+	}
+	return nil
+}
+
+// ModelCapabilities describes a model's limits and features, for models
+// the built-in catalog does not know or gets wrong. Unset fields keep the
+// catalog's value.
+type ModelCapabilities struct {
+	ContextWindow   int           `json:"context_window,omitempty"`
+	MaxOutputTokens int           `json:"max_output_tokens,omitempty"`
+	Vision          *bool         `json:"vision,omitempty"`
+	Tools           *bool         `json:"tools,omitempty"`
+	Pricing         *ModelPricing `json:"pricing,omitempty"`
 }
 
 // Validate checks if the ModelConfig has all required fields.
@@ -404,12 +926,26 @@ func (c *ModelConfig) Validate() error {
 	if c.Model == "" {
 		return fmt.Errorf("model is required")
 	}
+	switch c.API {
+	case "", "chat_completions", "responses":
+	default:
+		return fmt.Errorf("api %q is not supported; use chat_completions or responses", c.API)
+	}
+	for _, t := range c.BuiltinTools {
+		if t != "web_search" && t != "code_interpreter" {
+			return fmt.Errorf("builtin tool %q is not supported; use web_search or code_interpreter", t)
+		}
+	}
 	return nil
 }
 
 type GatewayConfig struct {
 	Host string `json:"host" env:"PICOCLAW_GATEWAY_HOST"`
 	Port int    `json:"port" env:"PICOCLAW_GATEWAY_PORT"`
+	// AdminToken is the bearer token of the endpoints that show or change
+	// the agent's state, such as /tasks. Without one they only answer
+	// requests from this machine.
+	AdminToken string `json:"admin_token,omitempty" env:"PICOCLAW_GATEWAY_ADMIN_TOKEN"`
 }
 
 type BraveConfig struct {
@@ -444,11 +980,113 @@ type ExecConfig struct {
 	CustomDenyPatterns []string `json:"custom_deny_patterns" env:"PICOCLAW_TOOLS_EXEC_CUSTOM_DENY_PATTERNS"`
 }
 
+type InjectionGuardConfig struct {
+	Enabled         bool     `json:"enabled" env:"PICOCLAW_TOOLS_INJECTION_GUARD_ENABLED"`
+	Action          string   `json:"action" env:"PICOCLAW_TOOLS_INJECTION_GUARD_ACTION"` // "flag" or "strip"
+	CustomPatterns  []string `json:"custom_patterns" env:"PICOCLAW_TOOLS_INJECTION_GUARD_CUSTOM_PATTERNS"`
+	DisableDefaults bool     `json:"disable_defaults,omitempty" env:"PICOCLAW_TOOLS_INJECTION_GUARD_DISABLE_DEFAULTS"`
+	Tools           []string `json:"tools,omitempty" env:"PICOCLAW_TOOLS_INJECTION_GUARD_TOOLS"` // empty means all tools
+}
+
 type ToolsConfig struct {
-	Web    WebToolsConfig    `json:"web"`
-	Cron   CronToolsConfig   `json:"cron"`
-	Exec   ExecConfig        `json:"exec"`
-	Skills SkillsToolsConfig `json:"skills"`
+	Web            WebToolsConfig       `json:"web"`
+	Cron           CronToolsConfig      `json:"cron"`
+	Exec           ExecConfig           `json:"exec"`
+	Skills         SkillsToolsConfig    `json:"skills"`
+	InjectionGuard InjectionGuardConfig `json:"injection_guard"`
+	Approvals      ApprovalsConfig      `json:"approvals"`
+	MQTT           MQTTToolConfig       `json:"mqtt"`
+	Hardware       HardwareToolConfig   `json:"hardware"`
+	Prefetch       PrefetchConfig       `json:"prefetch"`
+	Notify         NotifyToolConfig     `json:"notify"`
+}
+
+// NotifyToolConfig enables the notify tool, which sends a message to one
+// of the named Targets from any conversation. Each target receives at most
+// MaxPerHour notifications an hour.
+type NotifyToolConfig struct {
+	Enabled    bool                 `json:"enabled" env:"PICOCLAW_TOOLS_NOTIFY_ENABLED"`
+	Targets    []NotifyTargetConfig `json:"targets"`
+	MaxPerHour int                  `json:"max_per_hour" env:"PICOCLAW_TOOLS_NOTIFY_MAX_PER_HOUR"`
+}
+
+// NotifyTargetConfig is a chat notifications can be sent to. If Agents is
+// set, only those agents may notify it.
+type NotifyTargetConfig struct {
+	Name    string   `json:"name"`
+	Channel string   `json:"channel"`
+	ChatID  string   `json:"chat_id"`
+	Agents  []string `json:"agents,omitempty"`
+}
+
+// PrefetchConfig prepares tools that a message suggests will be used, such
+// as by opening the connection to their backend, while the model is still
+// deciding. A tool is prepared when the message contains one of its
+// Keywords, compared case-insensitively.
+type PrefetchConfig struct {
+	Enabled  bool                `json:"enabled" env:"PICOCLAW_TOOLS_PREFETCH_ENABLED"`
+	Keywords map[string][]string `json:"keywords,omitempty"`
+}
+
+// HardwareToolConfig enables the hardware tool, which is only compiled in
+// with the "hardware" build tag. Only the listed GPIO pins and I2C sensors
+// are reachable, and each may be used at most MaxOpsPerMinute times a
+// minute.
+type HardwareToolConfig struct {
+	Enabled         bool              `json:"enabled" env:"PICOCLAW_TOOLS_HARDWARE_ENABLED"`
+	GPIOPins        []GPIOPinConfig   `json:"gpio_pins"`
+	I2CSensors      []I2CSensorConfig `json:"i2c_sensors"`
+	MaxOpsPerMinute int               `json:"max_ops_per_minute" env:"PICOCLAW_TOOLS_HARDWARE_MAX_OPS_PER_MINUTE"`
+}
+
+// GPIOPinConfig allows a GPIO line. Mode is "input" (read only) or
+// "output" (read and write).
+type GPIOPinConfig struct {
+	Name string `json:"name"`
+	Pin  int    `json:"pin"`
+	Mode string `json:"mode"`
+}
+
+// I2CSensorConfig allows reads from a device on an I2C bus.
+type I2CSensorConfig struct {
+	Name    string `json:"name"`
+	Bus     string `json:"bus"`
+	Address int    `json:"address"`
+}
+
+// MQTTToolConfig enables the mqtt tool. The agent may only publish to
+// topics matching AllowPublish and subscribe to filters within
+// AllowSubscribe; both take MQTT filters with + and # wildcards and are
+// empty (nothing allowed) by default.
+type MQTTToolConfig struct {
+	Enabled        bool                `json:"enabled" env:"PICOCLAW_TOOLS_MQTT_ENABLED"`
+	Broker         string              `json:"broker" env:"PICOCLAW_TOOLS_MQTT_BROKER"`
+	ClientID       string              `json:"client_id" env:"PICOCLAW_TOOLS_MQTT_CLIENT_ID"`
+	Username       string              `json:"username" env:"PICOCLAW_TOOLS_MQTT_USERNAME"`
+	Password       string              `json:"password" env:"PICOCLAW_TOOLS_MQTT_PASSWORD"`
+	AllowPublish   FlexibleStringSlice `json:"allow_publish" env:"PICOCLAW_TOOLS_MQTT_ALLOW_PUBLISH"`
+	AllowSubscribe FlexibleStringSlice `json:"allow_subscribe" env:"PICOCLAW_TOOLS_MQTT_ALLOW_SUBSCRIBE"`
+}
+
+// ApprovalsConfig requires a person to approve calls to the listed tools
+// before they run. Requests go to the first matching approver, or to the
+// conversing chat when none matches, and are denied after TimeoutSeconds.
+type ApprovalsConfig struct {
+	Enabled        bool             `json:"enabled" env:"PICOCLAW_TOOLS_APPROVALS_ENABLED"`
+	Tools          []string         `json:"tools" env:"PICOCLAW_TOOLS_APPROVALS_TOOLS"`
+	TimeoutSeconds int              `json:"timeout_seconds" env:"PICOCLAW_TOOLS_APPROVALS_TIMEOUT_SECONDS"`
+	Approvers      []ApproverConfig `json:"approvers,omitempty"`
+}
+
+// ApproverConfig routes approval requests from conversations on Channel
+// (and ChatID, if set) to another chat. "*" matches any channel. If
+// ApproverSenderID is set, only that sender's replies count.
+type ApproverConfig struct {
+	Channel          string `json:"channel"`
+	ChatID           string `json:"chat_id,omitempty"`
+	ApproverChannel  string `json:"approver_channel"`
+	ApproverChatID   string `json:"approver_chat_id"`
+	ApproverSenderID string `json:"approver_sender_id,omitempty"`
 }
 
 type SkillsToolsConfig struct {
@@ -506,6 +1144,12 @@ func LoadConfig(path string) (*Config, error) {
 	if err := cfg.ValidateModelList(); err != nil {
 		return nil, err
 	}
+	if err := cfg.ValidateCustomProviders(); err != nil {
+		return nil, err
+	}
+	if err := cfg.ValidateProviderPlugins(); err != nil {
+		return nil, err
+	}
 
 	return cfg, nil
 }
@@ -528,6 +1172,16 @@ func (c *Config) WorkspacePath() string {
 	return expandHome(c.Agents.Defaults.Workspace)
 }
 
+// EncryptionKeyPath returns the encryption key file path with ~ expanded.
+func (c *Config) EncryptionKeyPath() string {
+	return expandHome(c.Encryption.KeyFile)
+}
+
+// EgressPolicyPath returns the egress policy file path with ~ expanded.
+func (c *Config) EgressPolicyPath() string {
+	return expandHome(c.Network.EgressPolicy)
+}
+
 func (c *Config) GetAPIKey() string {
 	if c.Providers.OpenRouter.APIKey != "" {
 		return c.Providers.OpenRouter.APIKey
@@ -636,7 +1290,10 @@ func (c *Config) HasProvidersConfig() bool {
 		v.VolcEngine.APIKey != "" || v.VolcEngine.APIBase != "" ||
 		v.GitHubCopilot.APIKey != "" || v.GitHubCopilot.APIBase != "" ||
 		v.Antigravity.APIKey != "" || v.Antigravity.APIBase != "" ||
-		v.Qwen.APIKey != "" || v.Qwen.APIBase != ""
+		v.Qwen.APIKey != "" || v.Qwen.APIBase != "" ||
+		v.Mistral.APIKey != "" || v.Mistral.APIBase != "" ||
+		v.Cohere.APIKey != "" || v.Cohere.APIBase != "" ||
+		v.XAI.APIKey != "" || v.XAI.APIBase != ""
 }
 
 // ValidateModelList validates all ModelConfig entries in the model_list.
@@ -650,3 +1307,40 @@ func (c *Config) ValidateModelList() error {
 	}
 	return nil
 }
+
+// ValidateCustomProviders validates the custom_providers entries and
+// checks that their names are unique.
+func (c *Config) ValidateCustomProviders() error {
+	seen := make(map[string]bool, len(c.CustomProviders))
+	for i := range c.CustomProviders {
+		p := &c.CustomProviders[i]
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("custom_providers[%d]: %w", i, err)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("custom_providers[%d]: duplicate name %q", i, p.Name)
+		}
+		seen[p.Name] = true
+	}
+	return nil
+}
+
+// ValidateProviderPlugins validates the provider_plugins entries and
+// checks that their names are unique, also among custom_providers.
+func (c *Config) ValidateProviderPlugins() error {
+	seen := make(map[string]bool, len(c.CustomProviders)+len(c.ProviderPlugins))
+	for _, p := range c.CustomProviders {
+		seen[p.Name] = true
+	}
+	for i := range c.ProviderPlugins {
+		p := &c.ProviderPlugins[i]
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("provider_plugins[%d]: %w", i, err)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("provider_plugins[%d]: duplicate name %q", i, p.Name)
+		}
+		seen[p.Name] = true
+	}
+	return nil
+}
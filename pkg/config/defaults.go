@@ -17,11 +17,30 @@ func DefaultConfig() *Config {
 				MaxTokens:           8192,
 				Temperature:         nil, // nil means use provider default
 				MaxToolIterations:   20,
+				MaxBackgroundTasks:  2,
+				RateLimit: RateLimitConfig{
+					BackgroundReserve: 20,
+				},
+				Summarizer: SummarizerConfig{
+					Strategy: "model",
+					MaxTokens: map[string]int{
+						"model":      1024,
+						"map_reduce": 1024,
+						"extractive": 512,
+					},
+				},
+				ResponseCache: ResponseCacheConfig{
+					TTLMinutes: 1440,
+				},
+				Truncation: "drop_oldest",
 			},
 		},
 		Bindings: []AgentBinding{},
 		Session: SessionConfig{
 			DMScope: "main",
+			Titles: SessionTitlesConfig{
+				AfterTurns: 3,
+			},
 		},
 		Channels: ChannelsConfig{
 			WhatsApp: WhatsAppConfig{
@@ -100,6 +119,19 @@ func DefaultConfig() *Config {
 				AllowFrom:      FlexibleStringSlice{},
 				ReplyTimeout:   5,
 			},
+			MQTT: MQTTConfig{
+				Enabled:       false,
+				Broker:        "tcp://localhost:1883",
+				CommandTopic:  "picoclaw/command",
+				ResponseTopic: "picoclaw/response",
+				QoS:           1,
+				AllowFrom:     FlexibleStringSlice{},
+			},
+			Serial: SerialConfig{
+				Enabled:  false,
+				Device:   "/dev/ttyS0",
+				BaudRate: 115200,
+			},
 			WeComApp: WeComAppConfig{
 				Enabled:        false,
 				CorpID:         "",
@@ -216,6 +248,30 @@ func DefaultConfig() *Config {
 				APIKey:    "",
 			},
 
+			// Mistral - https://console.mistral.ai/api-keys
+			{
+				ModelName: "mistral-large",
+				Model:     "mistral/mistral-large-latest",
+				APIBase:   "https://api.mistral.ai/v1",
+				APIKey:    "",
+			},
+
+			// Cohere - https://dashboard.cohere.com/api-keys
+			{
+				ModelName: "command-a",
+				Model:     "cohere/command-a-03-2025",
+				APIBase:   "https://api.cohere.com/v2",
+				APIKey:    "",
+			},
+
+			// xAI - https://console.x.ai
+			{
+				ModelName: "grok-4",
+				Model:     "xai/grok-4",
+				APIBase:   "https://api.x.ai/v1",
+				APIKey:    "",
+			},
+
 			// Volcengine (火山引擎) - https://console.volcengine.com/ark
 			{
 				ModelName: "doubao-pro",
@@ -255,6 +311,20 @@ func DefaultConfig() *Config {
 				APIKey:    "ollama",
 			},
 
+			// LM Studio (local) - https://lmstudio.ai
+			{
+				ModelName: "lmstudio",
+				Model:     "lmstudio/local-model",
+				APIBase:   "http://localhost:1234/v1",
+			},
+
+			// llama.cpp server (local) - https://github.com/ggml-org/llama.cpp
+			{
+				ModelName: "llamacpp",
+				Model:     "llamacpp/local-model",
+				APIBase:   "http://localhost:8080/v1",
+			},
+
 			// VLLM (local) - http://localhost:8000
 			{
 				ModelName: "local-model",
@@ -290,6 +360,39 @@ func DefaultConfig() *Config {
 			Exec: ExecConfig{
 				EnableDenyPatterns: true,
 			},
+			InjectionGuard: InjectionGuardConfig{
+				Enabled: true,
+				Action:  "flag",
+			},
+			Approvals: ApprovalsConfig{
+				Enabled:        false,
+				Tools:          []string{"exec"},
+				TimeoutSeconds: 300,
+			},
+			MQTT: MQTTToolConfig{
+				Enabled:        false,
+				Broker:         "tcp://localhost:1883",
+				AllowPublish:   FlexibleStringSlice{},
+				AllowSubscribe: FlexibleStringSlice{},
+			},
+			Hardware: HardwareToolConfig{
+				Enabled:         false,
+				MaxOpsPerMinute: 30,
+			},
+			Notify: NotifyToolConfig{
+				Enabled:    false,
+				Targets:    []NotifyTargetConfig{},
+				MaxPerHour: 10,
+			},
+			Prefetch: PrefetchConfig{
+				Enabled: false,
+				Keywords: map[string][]string{
+					"web_search": {
+						"search", "look up", "google", "latest", "news", "today",
+						"current", "weather", "price", "搜索", "最新", "新闻", "天气",
+					},
+				},
+			},
 			Skills: SkillsToolsConfig{
 				Registries: SkillsRegistriesConfig{
 					ClawHub: ClawHubRegistryConfig{
@@ -312,5 +415,85 @@ func DefaultConfig() *Config {
 			Enabled:    false,
 			MonitorUSB: true,
 		},
+		Observability: ObservabilityConfig{
+			Alerts: AlertsConfig{
+				Enabled:              false,
+				CheckIntervalSeconds: 60,
+				CooldownMinutes:      30,
+				ErrorRate: RateAlertConfig{
+					Enabled:       true,
+					Threshold:     10,
+					WindowMinutes: 5,
+				},
+				ProviderFailures: RateAlertConfig{
+					Enabled:       true,
+					Threshold:     3,
+					WindowMinutes: 10,
+				},
+				Disk: DiskAlertConfig{
+					Enabled:        true,
+					MinFreePercent: 10,
+				},
+			},
+			LogEscalation: LogEscalationConfig{
+				Enabled:         false,
+				Threshold:       5,
+				WindowSeconds:   60,
+				DurationMinutes: 10,
+				Level:           "debug",
+			},
+			ShutdownReport: ShutdownReportConfig{
+				Enabled: true,
+			},
+		},
+		Governor: GovernorConfig{
+			Enabled:              false,
+			SoftLimitMB:          64,
+			HardLimitMB:          96,
+			CheckIntervalSeconds: 30,
+			IdleSessionMinutes:   30,
+			MaxTempC:             80,
+			MinBatteryPercent:    20,
+		},
+		Network: NetworkConfig{
+			DNSCacheTTLSeconds:     0,
+			PrewarmConnections:     false,
+			PrewarmIntervalSeconds: 60,
+			ProviderRetry: ProviderRetryConfig{
+				Enabled:         true,
+				MaxAttempts:     3,
+				InitialDelayMS:  1000,
+				MaxDelaySeconds: 20,
+				BudgetSeconds:   60,
+			},
+		},
+		FileUploads: FileUploadsConfig{
+			Enabled:        false,
+			MaxSizeMB:      32,
+			RetentionHours: 72,
+		},
+		Encryption: EncryptionConfig{
+			Enabled: false,
+			KeyFile: "~/.picoclaw/encryption.key",
+		},
+		Storage: StorageConfig{
+			Backend: "local",
+		},
+		Auth: AuthConfig{
+			Store: "file",
+		},
+		RetryQueue: RetryQueueConfig{
+			Enabled:              false,
+			MaxQueued:            20,
+			MaxAgeMinutes:        60,
+			ProbeIntervalSeconds: 30,
+		},
+		Usage: UsageConfig{
+			Enabled: true,
+		},
+		Memory: MemoryConfig{
+			Maintenance:        false,
+			NotesRetentionDays: 0,
+		},
 	}
 }
@@ -324,6 +324,54 @@ func ConvertProvidersToModelList(cfg *Config) []ModelConfig {
 				}, true
 			},
 		},
+		{
+			providerNames: []string{"mistral"},
+			protocol:      "mistral",
+			buildConfig: func(p ProvidersConfig) (ModelConfig, bool) {
+				if p.Mistral.APIKey == "" && p.Mistral.APIBase == "" {
+					return ModelConfig{}, false
+				}
+				return ModelConfig{
+					ModelName: "mistral",
+					Model:     "mistral/mistral-large-latest",
+					APIKey:    p.Mistral.APIKey,
+					APIBase:   p.Mistral.APIBase,
+					Proxy:     p.Mistral.Proxy,
+				}, true
+			},
+		},
+		{
+			providerNames: []string{"cohere"},
+			protocol:      "cohere",
+			buildConfig: func(p ProvidersConfig) (ModelConfig, bool) {
+				if p.Cohere.APIKey == "" && p.Cohere.APIBase == "" {
+					return ModelConfig{}, false
+				}
+				return ModelConfig{
+					ModelName: "cohere",
+					Model:     "cohere/command-a-03-2025",
+					APIKey:    p.Cohere.APIKey,
+					APIBase:   p.Cohere.APIBase,
+					Proxy:     p.Cohere.Proxy,
+				}, true
+			},
+		},
+		{
+			providerNames: []string{"xai", "grok"},
+			protocol:      "xai",
+			buildConfig: func(p ProvidersConfig) (ModelConfig, bool) {
+				if p.XAI.APIKey == "" && p.XAI.APIBase == "" {
+					return ModelConfig{}, false
+				}
+				return ModelConfig{
+					ModelName: "xai",
+					Model:     "xai/grok-4",
+					APIKey:    p.XAI.APIKey,
+					APIBase:   p.XAI.APIBase,
+					Proxy:     p.XAI.Proxy,
+				}, true
+			},
+		},
 	}
 
 	// Process each provider migration
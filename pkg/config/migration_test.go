@@ -131,14 +131,17 @@ func TestConvertProvidersToModelList_AllProviders(t *testing.T) {
 			GitHubCopilot: ProviderConfig{ConnectMode: "grpc"},
 			Antigravity:   ProviderConfig{AuthMethod: "oauth"},
 			Qwen:          ProviderConfig{APIKey: "key17"},
+			Mistral:       ProviderConfig{APIKey: "key18"},
+			Cohere:        ProviderConfig{APIKey: "key19"},
+			XAI:           ProviderConfig{APIKey: "key20"},
 		},
 	}
 
 	result := ConvertProvidersToModelList(cfg)
 
-	// All 17 providers should be converted
-	if len(result) != 17 {
-		t.Errorf("len(result) = %d, want 17", len(result))
+	// All 20 providers should be converted
+	if len(result) != 20 {
+		t.Errorf("len(result) = %d, want 20", len(result))
 	}
 }
 
@@ -147,6 +147,34 @@ func TestModelConfig_Validate(t *testing.T) {
 			config:  ModelConfig{},
 			wantErr: true,
 		},
+		{
+			name: "responses api with builtin tools",
+			config: ModelConfig{
+				ModelName:    "test",
+				Model:        "openai/gpt-5.2",
+				API:          "responses",
+				BuiltinTools: []string{"web_search", "code_interpreter"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown api",
+			config: ModelConfig{
+				ModelName: "test",
+				Model:     "openai/gpt-5.2",
+				API:       "assistants",
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown builtin tool",
+			config: ModelConfig{
+				ModelName:    "test",
+				Model:        "openai/gpt-5.2",
+				BuiltinTools: []string{"file_search"},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -233,3 +261,51 @@ func TestConfig_ValidateModelList(t *testing.T) {
 		})
 	}
 }
+
+func TestConfig_ValidateCustomProviders(t *testing.T) {
+	tests := []struct {
+		name      string
+		providers []CustomProviderConfig
+		errMsg    string
+	}{
+		{"valid", []CustomProviderConfig{
+			{Name: "acme", APIBase: "https://api.acme.ai/v1", AuthHeader: "X-Api-Key: {api_key}"},
+			{Name: "other", APIBase: "http://localhost:9000/v1"},
+		}, ""},
+		{"missing name", []CustomProviderConfig{{APIBase: "https://api.acme.ai/v1"}}, "name is required"},
+		{"slash in name", []CustomProviderConfig{{Name: "a/b", APIBase: "https://x"}}, "must not contain"},
+		{"missing api_base", []CustomProviderConfig{{Name: "acme"}}, "api_base is required"},
+		{"bad auth_header", []CustomProviderConfig{{Name: "acme", APIBase: "https://x", AuthHeader: "{api_key}"}}, "auth_header"},
+		{"duplicate", []CustomProviderConfig{{Name: "acme", APIBase: "https://x"}, {Name: "acme", APIBase: "https://y"}}, "duplicate name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&Config{CustomProviders: tt.providers}).ValidateCustomProviders()
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("ValidateCustomProviders() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("ValidateCustomProviders() error = %v, want it to contain %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestConfig_ValidateProviderPlugins(t *testing.T) {
+	cfg := &Config{
+		CustomProviders: []CustomProviderConfig{{Name: "acme", APIBase: "https://x"}},
+		ProviderPlugins: []ProviderPluginConfig{{Name: "corp", Command: "/opt/corp-llm"}},
+	}
+	if err := cfg.ValidateProviderPlugins(); err != nil {
+		t.Errorf("ValidateProviderPlugins() error = %v", err)
+	}
+	for _, p := range []ProviderPluginConfig{{Command: "x"}, {Name: "corp"}, {Name: "acme", Command: "x"}} {
+		cfg.ProviderPlugins = []ProviderPluginConfig{p}
+		if err := cfg.ValidateProviderPlugins(); err == nil {
+			t.Errorf("ValidateProviderPlugins(%+v) succeeded", p)
+		}
+	}
+}
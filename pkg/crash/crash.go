@@ -0,0 +1,202 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package crash recovers panics in long-running goroutines, logs a
+// structured crash report and optionally forwards it to an error-reporting
+// webhook that accepts Sentry event payloads.
+package crash
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// Report describes a recovered panic.
+type Report struct {
+	Component string
+	Message   string
+	Stack     string
+	Frames    []runtime.Frame
+	Time      time.Time
+	Fields    map[string]interface{}
+}
+
+var (
+	crashes atomic.Uint64
+
+	mu          sync.RWMutex
+	webhookURL  string
+	environment string
+	httpClient  = &http.Client{Timeout: 10 * time.Second}
+)
+
+// SetWebhook configures the error-reporting endpoint. An empty url disables
+// remote reporting; crashes are still logged and counted.
+func SetWebhook(url, env string) {
+	mu.Lock()
+	defer mu.Unlock()
+	webhookURL = url
+	environment = env
+}
+
+// Count returns the number of panics recovered since process start.
+func Count() uint64 {
+	return crashes.Load()
+}
+
+// Recover must be deferred directly at the top of a goroutine. It stops a
+// panic from taking down the process and reports it under component.
+// fields adds context such as the channel or session being handled.
+func Recover(component string, fields map[string]interface{}) {
+	if r := recover(); r != nil {
+		Capture(component, r, fields)
+	}
+}
+
+// Go runs fn in a new goroutine with panic recovery. A crash report names
+// the trace of ctx, if any, so it can be matched with the logs of the work
+// that started fn.
+func Go(ctx context.Context, component string, fn func()) {
+	fields := logger.TraceFields(ctx, nil)
+	go func() {
+		defer Recover(component, fields)
+		fn()
+	}()
+}
+
+// Capture reports a value obtained from recover(). It is exported for
+// callers that need to act on the panic themselves, e.g. to turn it into an
+// error reply. A trace ID in fields, as added by logger.TraceFields, is also
+// sent as a tag.
+func Capture(component string, value interface{}, fields map[string]interface{}) *Report {
+	report := &Report{
+		Component: component,
+		Message:   fmt.Sprint(value),
+		Stack:     string(debug.Stack()),
+		Frames:    callerFrames(),
+		Time:      time.Now().UTC(),
+		Fields:    fields,
+	}
+	count := crashes.Add(1)
+
+	logFields := map[string]interface{}{
+		"panic":       report.Message,
+		"crash_count": count,
+		"stack":       report.Stack,
+	}
+	for k, v := range fields {
+		logFields[k] = v
+	}
+	logger.ErrorCF(component, "Recovered from panic", logFields)
+
+	mu.RLock()
+	url, env := webhookURL, environment
+	mu.RUnlock()
+	if url != "" {
+		go send(url, sentryEvent(report, env))
+	}
+	return report
+}
+
+// callerFrames returns the stack of the panicking goroutine, skipping the
+// runtime and this package.
+func callerFrames() []runtime.Frame {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var out []runtime.Frame
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "runtime.") &&
+			!strings.Contains(frame.Function, "/pkg/crash.") {
+			out = append(out, frame)
+		}
+		if !more {
+			break
+		}
+	}
+	return out
+}
+
+// sentryEvent builds a Sentry-compatible event payload for report.
+func sentryEvent(report *Report, env string) map[string]interface{} {
+	// Sentry expects frames ordered from outermost to innermost call.
+	frames := make([]map[string]interface{}, 0, len(report.Frames))
+	for i := len(report.Frames) - 1; i >= 0; i-- {
+		f := report.Frames[i]
+		frames = append(frames, map[string]interface{}{
+			"function": f.Function,
+			"filename": f.File,
+			"lineno":   f.Line,
+			"in_app":   strings.Contains(f.Function, "picoclaw"),
+		})
+	}
+
+	event := map[string]interface{}{
+		"event_id":  eventID(),
+		"timestamp": report.Time.Format(time.RFC3339),
+		"level":     "fatal",
+		"platform":  "go",
+		"logger":    report.Component,
+		"message":   report.Message,
+		"tags":      map[string]string{"component": report.Component},
+		"exception": map[string]interface{}{
+			"values": []map[string]interface{}{{
+				"type":       "panic",
+				"value":      report.Message,
+				"stacktrace": map[string]interface{}{"frames": frames},
+			}},
+		},
+	}
+	if env != "" {
+		event["environment"] = env
+	}
+	if trace, ok := report.Fields[logger.TraceField].(string); ok {
+		event["tags"].(map[string]string)[logger.TraceField] = trace
+	}
+	if len(report.Fields) > 0 {
+		event["extra"] = report.Fields
+	}
+	return event
+}
+
+func eventID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func send(url string, event map[string]interface{}) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		logger.WarnCF("crash", "Failed to encode crash report", map[string]interface{}{"error": err.Error()})
+		return
+	}
+	resp, err := httpClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		logger.WarnCF("crash", "Failed to send crash report", map[string]interface{}{"error": err.Error()})
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		logger.WarnCF("crash", "Error reporting webhook rejected crash report", map[string]interface{}{
+			"status": resp.StatusCode,
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package crash
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+func TestRecover_CountsAndSurvives(t *testing.T) {
+	before := Count()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer Recover("test", map[string]interface{}{"chat_id": "1"})
+		panic("boom")
+	}()
+	<-done
+
+	if got := Count(); got != before+1 {
+		t.Errorf("Count() = %d, want %d", got, before+1)
+	}
+}
+
+func TestCapture_PostsSentryEvent(t *testing.T) {
+	events := make(chan map[string]interface{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+		events <- event
+	}))
+	defer srv.Close()
+
+	SetWebhook(srv.URL, "test")
+	defer SetWebhook("", "")
+
+	report := Capture("agent", "nil map write", map[string]interface{}{"session_key": "s1"})
+	if report.Message != "nil map write" || report.Stack == "" {
+		t.Errorf("unexpected report: %+v", report)
+	}
+
+	select {
+	case event := <-events:
+		if event["level"] != "fatal" || event["platform"] != "go" || event["environment"] != "test" {
+			t.Errorf("unexpected event header: %v", event)
+		}
+		if len(event["event_id"].(string)) != 32 {
+			t.Errorf("event_id = %v, want 32 hex chars", event["event_id"])
+		}
+		extra, _ := event["extra"].(map[string]interface{})
+		if extra["session_key"] != "s1" {
+			t.Errorf("extra = %v", event["extra"])
+		}
+		exc := event["exception"].(map[string]interface{})["values"].([]interface{})[0].(map[string]interface{})
+		if exc["value"] != "nil map write" {
+			t.Errorf("exception value = %v", exc["value"])
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not called")
+	}
+}
+
+func TestGo_ReportsTrace(t *testing.T) {
+	events := make(chan map[string]interface{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&event)
+		events <- event
+	}))
+	defer srv.Close()
+
+	SetWebhook(srv.URL, "")
+	defer SetWebhook("", "")
+
+	ctx := logger.WithTrace(context.Background())
+	Go(ctx, "agent", func() { panic("boom") })
+
+	select {
+	case event := <-events:
+		tags, _ := event["tags"].(map[string]interface{})
+		extra, _ := event["extra"].(map[string]interface{})
+		if tags[logger.TraceField] != logger.TraceID(ctx) || extra[logger.TraceField] != logger.TraceID(ctx) {
+			t.Errorf("tags = %v, extra = %v, want trace %s", tags, extra, logger.TraceID(ctx))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not called")
+	}
+}
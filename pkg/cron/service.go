@@ -4,14 +4,17 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
-	"os"
 	"path/filepath"
 	"sync"
 	"time"
 
 	"github.com/adhocore/gronx"
+
+	"github.com/sipeed/picoclaw/pkg/vfs"
 )
 
 type CronSchedule struct {
@@ -29,6 +32,10 @@ type CronPayload struct {
 	Deliver bool   `json:"deliver"`
 	Channel string `json:"channel,omitempty"`
 	To      string `json:"to,omitempty"`
+	// Digest, when set to "daily" or "weekly", makes the job produce a
+	// conversation digest instead of running Message through the agent.
+	Digest      string `json:"digest,omitempty"`
+	DigestScope string `json:"digestScope,omitempty"`
 }
 
 type CronJobState struct {
@@ -65,6 +72,7 @@ type CronService struct {
 	running   bool
 	stopChan  chan struct{}
 	gronx     *gronx.Gronx
+	deferFn   func() bool
 }
 
 func NewCronService(storePath string, onJob JobHandler) *CronService {
@@ -139,6 +147,12 @@ func (cs *CronService) checkJobs() {
 		return
 	}
 
+	// Due jobs stay due while deferred and run once the check clears.
+	if cs.deferFn != nil && cs.deferFn() {
+		cs.mu.Unlock()
+		return
+	}
+
 	now := time.Now().UnixMilli()
 	var dueJobIDs []string
 
@@ -306,6 +320,14 @@ func (cs *CronService) Load() error {
 	return cs.loadStore()
 }
 
+// SetDeferCheck sets a function that, when it returns true, postpones due
+// jobs (e.g. under memory pressure).
+func (cs *CronService) SetDeferCheck(fn func() bool) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.deferFn = fn
+}
+
 func (cs *CronService) SetOnJob(handler JobHandler) {
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
@@ -318,9 +340,9 @@ func (cs *CronService) loadStore() error {
 		Jobs:    []CronJob{},
 	}
 
-	data, err := os.ReadFile(cs.storePath)
+	data, err := vfs.ReadFile(cs.storePath)
 	if err != nil {
-		if os.IsNotExist(err) {
+		if errors.Is(err, fs.ErrNotExist) {
 			return nil
 		}
 		return err
@@ -331,7 +353,7 @@ func (cs *CronService) loadStore() error {
 
 func (cs *CronService) saveStoreUnsafe() error {
 	dir := filepath.Dir(cs.storePath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	if err := vfs.MkdirAll(dir, 0755); err != nil {
 		return err
 	}
 
@@ -340,7 +362,7 @@ func (cs *CronService) saveStoreUnsafe() error {
 		return err
 	}
 
-	return os.WriteFile(cs.storePath, data, 0600)
+	return vfs.WriteFile(cs.storePath, data, 0600)
 }
 
 func (cs *CronService) AddJob(name string, schedule CronSchedule, message string, deliver bool, channel, to string) (*CronJob, error) {
@@ -128,9 +128,10 @@ func (s *Service) sendNotification(ev *events.DeviceEvent) {
 
 	msg := ev.FormatMessage()
 	msgBus.PublishOutbound(bus.OutboundMessage{
-		Channel: platform,
-		ChatID:  userID,
-		Content: msg,
+		Channel:   platform,
+		ChatID:    userID,
+		Content:   msg,
+		Proactive: true,
 	})
 
 	logger.InfoCF("devices", "Device notification sent", map[string]interface{}{
@@ -0,0 +1,307 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package egress restricts which hosts the agent's own HTTP clients and
+// the MQTT tool may connect to. A policy file lists, per subsystem
+// ("providers", "tools", "storage"), the domains and IP ranges that are
+// allowed or denied. It is enforced in the dialer, after DNS resolution,
+// so redirects and hostnames that resolve to a denied address are caught
+// too.
+//
+// Programs started by the exec tool and channels are not covered.
+package egress
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Subsystems with their own section in the policy file.
+const (
+	Providers = "providers"
+	Tools     = "tools"
+	Storage   = "storage"
+)
+
+// Rule lists the destinations a subsystem may reach. Entries are domain
+// names ("api.openai.com"), wildcard domains ("*.googleapis.com", which
+// also matches the bare domain), IP addresses, or CIDR ranges. Deny wins
+// over Allow; an empty Allow list allows everything not denied.
+type Rule struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+// Policy maps subsystems to rules. Subsystems without a rule fall back to
+// Default; if that is also absent they are unrestricted.
+type Policy struct {
+	Default   *Rule            `json:"default,omitempty"`
+	Subsystem map[string]*Rule `json:"-"`
+}
+
+// UnmarshalJSON reads the "default" key and treats every other key as a
+// subsystem name.
+func (p *Policy) UnmarshalJSON(data []byte) error {
+	var raw map[string]*Rule
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	p.Default = raw["default"]
+	delete(raw, "default")
+	p.Subsystem = raw
+	return nil
+}
+
+// ErrDenied is returned, wrapped, when a connection is blocked.
+var ErrDenied = errors.New("blocked by egress policy")
+
+var (
+	mu     sync.RWMutex
+	active map[string]*matcher
+	deflt  *matcher
+
+	lookupIP = net.DefaultResolver.LookupIPAddr
+	dialer   = &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+)
+
+// LoadFile reads a JSON policy from path and activates it. It must be
+// called before HTTP clients are created.
+func LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading egress policy: %w", err)
+	}
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return fmt.Errorf("parsing egress policy %s: %w", path, err)
+	}
+	return Set(&p)
+}
+
+// Set activates p. A nil policy removes all restrictions.
+func Set(p *Policy) error {
+	if p == nil {
+		mu.Lock()
+		active, deflt = nil, nil
+		mu.Unlock()
+		return nil
+	}
+
+	matchers := make(map[string]*matcher, len(p.Subsystem))
+	for name, rule := range p.Subsystem {
+		m, err := newMatcher(rule)
+		if err != nil {
+			return fmt.Errorf("egress policy %q: %w", name, err)
+		}
+		matchers[name] = m
+	}
+	def, err := newMatcher(p.Default)
+	if err != nil {
+		return fmt.Errorf("egress policy default: %w", err)
+	}
+
+	mu.Lock()
+	active, deflt = matchers, def
+	mu.Unlock()
+	return nil
+}
+
+func matcherFor(subsystem string) *matcher {
+	mu.RLock()
+	defer mu.RUnlock()
+	if m, ok := active[subsystem]; ok {
+		return m
+	}
+	return deflt
+}
+
+// Transport returns base with the policy for subsystem enforced in its
+// dialer. base may be nil for the default transport. When no policy
+// applies, or base is not an *http.Transport, base is returned unchanged.
+//
+// With a proxy configured the dialer connects to the proxy, so the proxy
+// host has to be allowed.
+func Transport(subsystem string, base http.RoundTripper) http.RoundTripper {
+	m := matcherFor(subsystem)
+	if m == nil {
+		return base
+	}
+
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t, ok := base.(*http.Transport)
+	if !ok {
+		return base
+	}
+	t = t.Clone()
+	next := t.DialContext
+	if next == nil {
+		next = dialer.DialContext
+	}
+	t.DialContext = m.dialContext(subsystem, next)
+	return t
+}
+
+// Dialer returns a dial function with the policy for subsystem enforced,
+// for clients that do not speak HTTP, such as the MQTT tool's.
+func Dialer(subsystem string) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	m := matcherFor(subsystem)
+	if m == nil {
+		return dialer.DialContext
+	}
+	return m.dialContext(subsystem, dialer.DialContext)
+}
+
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+type matcher struct {
+	allowDomains []string
+	allowNets    []*net.IPNet
+	denyDomains  []string
+	denyNets     []*net.IPNet
+	allowAll     bool
+}
+
+func newMatcher(rule *Rule) (*matcher, error) {
+	if rule == nil {
+		return nil, nil
+	}
+	m := &matcher{allowAll: len(rule.Allow) == 0}
+	var err error
+	if m.allowDomains, m.allowNets, err = parseEntries(rule.Allow); err != nil {
+		return nil, err
+	}
+	if m.denyDomains, m.denyNets, err = parseEntries(rule.Deny); err != nil {
+		return nil, err
+	}
+	for _, d := range m.allowDomains {
+		if d == "*" {
+			m.allowAll = true
+		}
+	}
+	return m, nil
+}
+
+func parseEntries(entries []string) ([]string, []*net.IPNet, error) {
+	var domains []string
+	var nets []*net.IPNet
+	for _, e := range entries {
+		e = strings.ToLower(strings.TrimSpace(e))
+		if e == "" {
+			continue
+		}
+		if strings.Contains(e, "/") {
+			_, n, err := net.ParseCIDR(e)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid CIDR %q", e)
+			}
+			nets = append(nets, n)
+			continue
+		}
+		if ip := net.ParseIP(e); ip != nil {
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		domains = append(domains, strings.TrimSuffix(e, "."))
+	}
+	return domains, nets, nil
+}
+
+func matchDomain(patterns []string, host string) bool {
+	for _, p := range patterns {
+		switch {
+		case p == "*":
+			return true
+		case strings.HasPrefix(p, "*."):
+			base := p[2:]
+			if host == base || strings.HasSuffix(host, "."+base) {
+				return true
+			}
+		case host == p:
+			return true
+		}
+	}
+	return false
+}
+
+func matchIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIP reports whether ip may be reached. nameAllowed is set when the
+// hostname that resolved to ip matched an allow entry.
+func (m *matcher) checkIP(ip net.IP, nameAllowed bool) bool {
+	if matchIP(m.denyNets, ip) {
+		return false
+	}
+	return nameAllowed || m.allowAll || matchIP(m.allowNets, ip)
+}
+
+func (m *matcher) dialContext(subsystem string, next dialFunc) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		host = strings.ToLower(strings.TrimSuffix(host, "."))
+		denied := fmt.Errorf("%s may not connect to %s: %w", subsystem, host, ErrDenied)
+
+		if ip := net.ParseIP(host); ip != nil {
+			if !m.checkIP(ip, false) {
+				return nil, denied
+			}
+			return next(ctx, network, addr)
+		}
+
+		if matchDomain(m.denyDomains, host) {
+			return nil, denied
+		}
+		nameAllowed := m.allowAll || matchDomain(m.allowDomains, host)
+		if nameAllowed && len(m.denyNets) == 0 {
+			return next(ctx, network, addr)
+		}
+		if !nameAllowed && len(m.allowNets) == 0 {
+			return nil, denied
+		}
+
+		// The decision depends on the addresses the name resolves to. Dial
+		// the checked addresses directly so a second lookup cannot return
+		// something else.
+		addrs, err := lookupIP(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		var lastErr error = denied
+		for _, a := range addrs {
+			if !m.checkIP(a.IP, nameAllowed) {
+				continue
+			}
+			conn, err := next(ctx, network, net.JoinHostPort(a.IP.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		return nil, lastErr
+	}
+}
@@ -0,0 +1,146 @@
+package egress
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func stubLookup(t *testing.T, ips map[string]string) {
+	t.Helper()
+	orig := lookupIP
+	lookupIP = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		ip, ok := ips[host]
+		if !ok {
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+		return []net.IPAddr{{IP: net.ParseIP(ip)}}, nil
+	}
+	t.Cleanup(func() { lookupIP = orig })
+}
+
+func TestMatcher_Dial(t *testing.T) {
+	stubLookup(t, map[string]string{
+		"api.example.com":  "93.184.216.34",
+		"evil.example.net": "127.0.0.1",
+		"intranet.local":   "10.1.2.3",
+		"other.org":        "203.0.113.7",
+	})
+
+	m, err := newMatcher(&Rule{
+		Allow: []string{"*.example.com", "evil.example.net", "10.0.0.0/8"},
+		Deny:  []string{"127.0.0.0/8", "blocked.example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var dialed string
+	dial := m.dialContext("tools", func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed = addr
+		c1, c2 := net.Pipe()
+		c2.Close()
+		return c1, nil
+	})
+
+	tests := []struct {
+		addr    string
+		allowed bool
+		dialed  string
+	}{
+		{"api.example.com:443", true, "93.184.216.34:443"},
+		{"blocked.example.com:443", false, ""},
+		// Allowed by name, but resolves into a denied range.
+		{"evil.example.net:80", false, ""},
+		// Not allowed by name, but resolves into an allowed range.
+		{"intranet.local:80", true, "10.1.2.3:80"},
+		{"other.org:443", false, ""},
+		{"127.0.0.1:8080", false, ""},
+		{"10.0.0.5:80", true, "10.0.0.5:80"},
+	}
+	for _, tt := range tests {
+		dialed = ""
+		conn, err := dial(context.Background(), "tcp", tt.addr)
+		if tt.allowed {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.addr, err)
+				continue
+			}
+			conn.Close()
+			if dialed != tt.dialed {
+				t.Errorf("%s: dialed %q, want %q", tt.addr, dialed, tt.dialed)
+			}
+		} else if !errors.Is(err, ErrDenied) {
+			t.Errorf("%s: err = %v, want ErrDenied", tt.addr, err)
+		}
+	}
+}
+
+func TestTransport_EnforcesPolicy(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "egress.json")
+	os.WriteFile(path, []byte(`{"tools": {"deny": ["127.0.0.0/8"]}, "default": {"allow": ["127.0.0.1"]}}`), 0644)
+	if err := LoadFile(path); err != nil {
+		t.Fatalf("LoadFile() error: %v", err)
+	}
+	defer Set(nil)
+
+	tools := &http.Client{Transport: Transport(Tools, nil)}
+	if _, err := tools.Get(srv.URL); !errors.Is(err, ErrDenied) {
+		t.Errorf("tools request err = %v, want ErrDenied", err)
+	}
+
+	// Providers have no section and fall back to the default rule.
+	providers := &http.Client{Transport: Transport(Providers, nil)}
+	resp, err := providers.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("providers request failed: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestDialer_EnforcesPolicy(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	if err := Set(&Policy{Subsystem: map[string]*Rule{Tools: {Deny: []string{"127.0.0.0/8"}}}}); err != nil {
+		t.Fatal(err)
+	}
+	defer Set(nil)
+	if _, err := Dialer(Tools)(context.Background(), "tcp", ln.Addr().String()); !errors.Is(err, ErrDenied) {
+		t.Errorf("Dialer(tools) err = %v, want ErrDenied", err)
+	}
+
+	Set(nil)
+	conn, err := Dialer(Tools)(context.Background(), "tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Dialer(tools) without a policy failed: %v", err)
+	}
+	conn.Close()
+}
+
+func TestTransport_NoPolicy(t *testing.T) {
+	Set(nil)
+	if got := Transport(Tools, nil); got != nil {
+		t.Errorf("Transport() = %v, want nil without a policy", got)
+	}
+}
+
+func TestLoadFile_InvalidCIDR(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "egress.json")
+	os.WriteFile(path, []byte(`{"tools": {"allow": ["10.0.0.0/99"]}}`), 0644)
+	if err := LoadFile(path); err == nil {
+		t.Error("expected an error for an invalid CIDR")
+	}
+}
@@ -0,0 +1,65 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package features records which optional components were compiled into
+// the binary. Components register themselves from build-tagged files, so the
+// registry reflects the build profile (see Profile).
+//
+// Profiles are selected with build tags:
+//
+//	go build -tags minimal   # CLI agent and OpenAI-compatible HTTP providers
+//	go build -tags standard  # adds common chat channels and SDK providers
+//	go build                 # full: every channel and provider
+package features
+
+import (
+	"sort"
+	"sync"
+)
+
+// Feature is an optional component included in the build.
+type Feature struct {
+	Kind string // e.g. "channel", "provider"
+	Name string
+}
+
+var (
+	mu       sync.RWMutex
+	features = map[Feature]struct{}{}
+)
+
+// Register records that a component of the given kind is compiled in.
+// It is meant to be called from init functions.
+func Register(kind, name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	features[Feature{Kind: kind, Name: name}] = struct{}{}
+}
+
+// Has reports whether the named component is compiled in.
+func Has(kind, name string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	_, ok := features[Feature{Kind: kind, Name: name}]
+	return ok
+}
+
+// List returns all registered features sorted by kind and name.
+func List() []Feature {
+	mu.RLock()
+	out := make([]Feature, 0, len(features))
+	for f := range features {
+		out = append(out, f)
+	}
+	mu.RUnlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Kind != out[j].Kind {
+			return out[i].Kind < out[j].Kind
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
@@ -0,0 +1,25 @@
+package features
+
+import "testing"
+
+func TestRegisterAndList(t *testing.T) {
+	Register("provider", "zeta")
+	Register("channel", "alpha")
+	Register("provider", "beta")
+	Register("channel", "alpha")
+
+	if !Has("channel", "alpha") || Has("channel", "zeta") {
+		t.Fatal("Has() does not match registrations")
+	}
+
+	got := List()
+	want := []Feature{{"channel", "alpha"}, {"provider", "beta"}, {"provider", "zeta"}}
+	if len(got) != len(want) {
+		t.Fatalf("List() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("List()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
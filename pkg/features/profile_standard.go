@@ -0,0 +1,6 @@
+//go:build standard && !minimal
+
+package features
+
+// Profile is the build profile the binary was compiled with.
+const Profile = "standard"
@@ -0,0 +1,226 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package governor watches the process's resident memory and asks the rest
+// of the system to shed load when it grows past configured limits, so the
+// agent stays alive on boards with very little RAM. On battery powered or
+// passively cooled boards it also defers background work while the device
+// is too hot or low on battery.
+package governor
+
+import (
+	"context"
+	"os"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// Level is the current memory pressure.
+type Level int32
+
+const (
+	LevelNormal Level = iota
+	LevelSoft
+	LevelHard
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelSoft:
+		return "soft"
+	case LevelHard:
+		return "hard"
+	default:
+		return "normal"
+	}
+}
+
+const mb = 1024 * 1024
+
+// Governor periodically samples RSS and notifies handlers while memory is
+// above the soft limit.
+type Governor struct {
+	cfg       config.GovernorConfig
+	readRSS   func() (uint64, error)
+	readPower func() PowerStatus
+
+	level       atomic.Int32
+	constrained atomic.Bool
+	mu          sync.Mutex
+	power       PowerStatus
+	handlers    []func(Level)
+	cancel      context.CancelFunc
+}
+
+// New creates a governor. It does nothing until Start is called.
+func New(cfg config.GovernorConfig) *Governor {
+	return &Governor{cfg: cfg, readRSS: readRSS, readPower: ReadPower}
+}
+
+// OnPressure registers fn to be called on every check while memory is
+// above the soft limit.
+func (g *Governor) OnPressure(fn func(Level)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.handlers = append(g.handlers, fn)
+}
+
+// Level returns the pressure level from the latest check.
+func (g *Governor) Level() Level {
+	if g == nil {
+		return LevelNormal
+	}
+	return Level(g.level.Load())
+}
+
+// PowerConstrained reports whether the latest check found the device too
+// hot or low on battery.
+func (g *Governor) PowerConstrained() bool {
+	return g != nil && g.constrained.Load()
+}
+
+// Power returns the battery and thermal data from the latest check.
+func (g *Governor) Power() PowerStatus {
+	if g == nil {
+		return PowerStatus{}
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.power
+}
+
+// Deferring reports whether background jobs should be postponed.
+func (g *Governor) Deferring() bool {
+	return g.Level() >= LevelSoft || g.PowerConstrained()
+}
+
+// MemoryUsage returns the resident memory of the process in bytes.
+func MemoryUsage() (uint64, error) {
+	return readRSS()
+}
+
+// Start begins periodic checks. Unless GOMEMLIMIT is set, the Go runtime's
+// soft memory limit is also set to the soft limit so the GC works harder
+// before the governor has to step in.
+func (g *Governor) Start(ctx context.Context) {
+	watchPower := g.cfg.MaxTempC > 0 || g.cfg.MinBatteryPercent > 0
+	if !g.cfg.Enabled || (g.cfg.SoftLimitMB <= 0 && !watchPower) {
+		return
+	}
+
+	if g.cfg.SoftLimitMB > 0 && os.Getenv("GOMEMLIMIT") == "" {
+		debug.SetMemoryLimit(int64(g.cfg.SoftLimitMB) * mb)
+	}
+
+	interval := time.Duration(g.cfg.CheckIntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	g.cancel = cancel
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-runCtx.Done():
+				return
+			case <-ticker.C:
+				g.Check()
+			}
+		}
+	}()
+
+	logger.InfoCF("governor", "Memory governor started", map[string]interface{}{
+		"soft_limit_mb":       g.cfg.SoftLimitMB,
+		"hard_limit_mb":       g.cfg.HardLimitMB,
+		"max_temp_c":          g.cfg.MaxTempC,
+		"min_battery_percent": g.cfg.MinBatteryPercent,
+	})
+}
+
+// Stop ends periodic checks.
+func (g *Governor) Stop() {
+	if g.cancel != nil {
+		g.cancel()
+	}
+}
+
+// Check samples memory and power once, updates the level and runs the
+// pressure handlers if needed.
+func (g *Governor) Check() Level {
+	g.checkPower()
+	if g.cfg.SoftLimitMB <= 0 {
+		return LevelNormal
+	}
+
+	rss, err := g.readRSS()
+	if err != nil {
+		logger.DebugCF("governor", "Failed to read memory usage", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return g.Level()
+	}
+
+	level := LevelNormal
+	switch {
+	case g.cfg.HardLimitMB > 0 && rss >= uint64(g.cfg.HardLimitMB)*mb:
+		level = LevelHard
+	case rss >= uint64(g.cfg.SoftLimitMB)*mb:
+		level = LevelSoft
+	}
+
+	prev := Level(g.level.Swap(int32(level)))
+	if level != prev {
+		fields := map[string]interface{}{
+			"rss_mb": rss / mb,
+			"level":  level.String(),
+		}
+		if level > prev {
+			logger.WarnCF("governor", "Memory pressure increased", fields)
+		} else {
+			logger.InfoCF("governor", "Memory pressure decreased", fields)
+		}
+	}
+
+	if level == LevelNormal {
+		return level
+	}
+
+	g.mu.Lock()
+	handlers := append([]func(Level){}, g.handlers...)
+	g.mu.Unlock()
+	for _, fn := range handlers {
+		fn(level)
+	}
+	debug.FreeOSMemory()
+	return level
+}
+
+// checkPower samples the battery and thermal sensors and updates whether
+// background work is deferred because of them.
+func (g *Governor) checkPower() {
+	p := g.readPower()
+	g.mu.Lock()
+	g.power = p
+	g.mu.Unlock()
+
+	constrained := g.powerConstrained(p)
+	if g.constrained.Swap(constrained) == constrained {
+		return
+	}
+	fields := map[string]interface{}{"device": p.String()}
+	if constrained {
+		logger.WarnCF("governor", "Device too hot or low on battery, deferring background jobs", fields)
+	} else {
+		logger.InfoCF("governor", "Device power and temperature back to normal", fields)
+	}
+}
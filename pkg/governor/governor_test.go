@@ -0,0 +1,58 @@
+package governor
+
+import (
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestCheck_LevelsAndHandlers(t *testing.T) {
+	g := New(config.GovernorConfig{Enabled: true, SoftLimitMB: 64, HardLimitMB: 96})
+	var rss uint64
+	g.readRSS = func() (uint64, error) { return rss, nil }
+
+	var seen []Level
+	g.OnPressure(func(l Level) { seen = append(seen, l) })
+
+	rss = 10 * mb
+	if got := g.Check(); got != LevelNormal || g.Deferring() {
+		t.Errorf("Check() at 10MB = %v, deferring=%v", got, g.Deferring())
+	}
+
+	rss = 70 * mb
+	if got := g.Check(); got != LevelSoft || !g.Deferring() {
+		t.Errorf("Check() at 70MB = %v, deferring=%v", got, g.Deferring())
+	}
+
+	rss = 100 * mb
+	if got := g.Check(); got != LevelHard {
+		t.Errorf("Check() at 100MB = %v", got)
+	}
+
+	rss = 10 * mb
+	g.Check()
+
+	if len(seen) != 2 || seen[0] != LevelSoft || seen[1] != LevelHard {
+		t.Errorf("handlers saw %v, want [soft hard]", seen)
+	}
+	if g.Deferring() {
+		t.Error("expected no deferral after memory dropped")
+	}
+}
+
+func TestReadRSS(t *testing.T) {
+	rss, err := readRSS()
+	if err != nil {
+		t.Fatalf("readRSS() error: %v", err)
+	}
+	if rss == 0 {
+		t.Error("readRSS() returned 0")
+	}
+}
+
+func TestNilGovernorIsNormal(t *testing.T) {
+	var g *Governor
+	if g.Level() != LevelNormal || g.Deferring() {
+		t.Error("nil governor should report normal pressure")
+	}
+}
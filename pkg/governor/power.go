@@ -0,0 +1,100 @@
+package governor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// sysfsRoot is where battery and thermal data is read from.
+var sysfsRoot = "/sys"
+
+// PowerStatus is a snapshot of the battery and thermal sensors. The
+// battery fields are only meaningful with HasBattery, TempC only with
+// HasThermal.
+type PowerStatus struct {
+	HasBattery     bool
+	BatteryPercent int  // lowest charge over all batteries
+	Discharging    bool // running on battery
+	HasThermal     bool
+	TempC          float64 // hottest thermal zone
+}
+
+func (p PowerStatus) String() string {
+	var parts []string
+	if p.HasBattery {
+		source := "on external power"
+		if p.Discharging {
+			source = "on battery"
+		}
+		parts = append(parts, fmt.Sprintf("battery %d%% (%s)", p.BatteryPercent, source))
+	}
+	if p.HasThermal {
+		parts = append(parts, fmt.Sprintf("temperature %.1f°C", p.TempC))
+	}
+	if len(parts) == 0 {
+		return "no battery or thermal sensors found"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ReadPower reads battery and thermal data from sysfs. On systems without
+// it the result reports neither.
+func ReadPower() PowerStatus {
+	return readPower(sysfsRoot)
+}
+
+func readPower(root string) PowerStatus {
+	var p PowerStatus
+
+	supplies, _ := filepath.Glob(filepath.Join(root, "class", "power_supply", "*"))
+	for _, dir := range supplies {
+		if readSysfs(filepath.Join(dir, "type")) != "Battery" {
+			continue
+		}
+		capacity, err := strconv.Atoi(readSysfs(filepath.Join(dir, "capacity")))
+		if err != nil {
+			continue
+		}
+		if !p.HasBattery || capacity < p.BatteryPercent {
+			p.BatteryPercent = capacity
+		}
+		p.HasBattery = true
+		if readSysfs(filepath.Join(dir, "status")) == "Discharging" {
+			p.Discharging = true
+		}
+	}
+
+	zones, _ := filepath.Glob(filepath.Join(root, "class", "thermal", "thermal_zone*", "temp"))
+	for _, path := range zones {
+		milli, err := strconv.Atoi(readSysfs(path))
+		if err != nil {
+			continue
+		}
+		if temp := float64(milli) / 1000; !p.HasThermal || temp > p.TempC {
+			p.TempC = temp
+		}
+		p.HasThermal = true
+	}
+	return p
+}
+
+func readSysfs(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// powerConstrained reports whether the device is too hot or low on battery
+// for background work under cfg.
+func (g *Governor) powerConstrained(p PowerStatus) bool {
+	if g.cfg.MaxTempC > 0 && p.HasThermal && p.TempC >= float64(g.cfg.MaxTempC) {
+		return true
+	}
+	return g.cfg.MinBatteryPercent > 0 && p.HasBattery && p.Discharging &&
+		p.BatteryPercent <= g.cfg.MinBatteryPercent
+}
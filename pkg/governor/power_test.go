@@ -0,0 +1,80 @@
+package governor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func writeSysfs(t *testing.T, root, path, content string) {
+	t.Helper()
+	full := filepath.Join(root, path)
+	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte(content+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadPower(t *testing.T) {
+	root := t.TempDir()
+	if p := readPower(root); p.HasBattery || p.HasThermal {
+		t.Fatalf("empty sysfs reported %+v", p)
+	}
+
+	writeSysfs(t, root, "class/power_supply/AC/type", "Mains")
+	writeSysfs(t, root, "class/power_supply/BAT0/type", "Battery")
+	writeSysfs(t, root, "class/power_supply/BAT0/capacity", "42")
+	writeSysfs(t, root, "class/power_supply/BAT0/status", "Discharging")
+	writeSysfs(t, root, "class/thermal/thermal_zone0/temp", "48500")
+	writeSysfs(t, root, "class/thermal/thermal_zone1/temp", "61250")
+
+	p := readPower(root)
+	if !p.HasBattery || p.BatteryPercent != 42 || !p.Discharging {
+		t.Errorf("battery = %+v", p)
+	}
+	if !p.HasThermal || p.TempC != 61.25 {
+		t.Errorf("temperature = %v", p.TempC)
+	}
+	if got := p.String(); got != "battery 42% (on battery), temperature 61.2°C" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestCheck_DefersWhenHotOrLowBattery(t *testing.T) {
+	g := New(config.GovernorConfig{Enabled: true, MaxTempC: 80, MinBatteryPercent: 20})
+	var power PowerStatus
+	g.readPower = func() PowerStatus { return power }
+	released := false
+	g.OnPressure(func(Level) { released = true })
+
+	power = PowerStatus{HasThermal: true, TempC: 65, HasBattery: true, BatteryPercent: 15}
+	g.Check()
+	if g.Deferring() {
+		t.Error("deferring at 65°C with a low battery on external power")
+	}
+
+	power.Discharging = true
+	g.Check()
+	if !g.Deferring() {
+		t.Error("not deferring on a low battery")
+	}
+
+	power = PowerStatus{HasThermal: true, TempC: 85}
+	g.Check()
+	if !g.Deferring() || g.Power().TempC != 85 {
+		t.Errorf("not deferring at 85°C (power %+v)", g.Power())
+	}
+
+	power.TempC = 70
+	g.Check()
+	if g.Deferring() {
+		t.Error("still deferring after cooling down")
+	}
+	if released {
+		t.Error("power limits should not release memory")
+	}
+}
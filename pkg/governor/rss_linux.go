@@ -0,0 +1,27 @@
+//go:build linux
+
+package governor
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// readRSS returns the resident set size from /proc/self/statm.
+func readRSS() (uint64, error) {
+	data, err := os.ReadFile("/proc/self/statm")
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		return 0, fmt.Errorf("unexpected statm format: %q", data)
+	}
+	pages, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return pages * uint64(os.Getpagesize()), nil
+}
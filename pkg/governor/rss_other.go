@@ -0,0 +1,13 @@
+//go:build !linux
+
+package governor
+
+import "runtime"
+
+// readRSS approximates resident memory with the memory the Go runtime holds
+// from the OS, since RSS is not portably available.
+func readRSS() (uint64, error) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return ms.Sys - ms.HeapReleased, nil
+}
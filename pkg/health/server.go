@@ -2,15 +2,19 @@ package health
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 )
 
 type Server struct {
 	server    *http.Server
+	mux       *http.ServeMux
 	mu        sync.RWMutex
 	ready     bool
 	checks    map[string]Check
@@ -33,6 +37,7 @@ type StatusResponse struct {
 func NewServer(host string, port int) *Server {
 	mux := http.NewServeMux()
 	s := &Server{
+		mux:       mux,
 		ready:     false,
 		checks:    make(map[string]Check),
 		startTime: time.Now(),
@@ -84,6 +89,49 @@ func (s *Server) Stop(ctx context.Context) error {
 	return s.server.Shutdown(ctx)
 }
 
+// Handle serves another endpoint next to the health endpoints. It must be
+// called before the server is started.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// HandleAdmin serves an endpoint that shows or changes the agent's state,
+// which the health server's usual address, on all interfaces, must not
+// expose: requests need the bearer token, if one is set, and otherwise
+// have to come from this machine.
+func (s *Server) HandleAdmin(pattern string, handler http.Handler, token string) {
+	s.mux.Handle(pattern, RequireAdmin(handler, token))
+}
+
+// RequireAdmin wraps handler so that it only serves requests that carry
+// "Authorization: Bearer <token>", or, with an empty token, requests from
+// a loopback address.
+func RequireAdmin(handler http.Handler, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token != "" {
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+		} else if !fromLoopback(r) {
+			http.Error(w, "forbidden: set gateway.admin_token to allow remote access", http.StatusForbidden)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func fromLoopback(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
 func (s *Server) SetReady(ready bool) {
 	s.mu.Lock()
 	s.ready = ready
@@ -0,0 +1,40 @@
+package health
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireAdmin(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name   string
+		token  string
+		remote string
+		auth   string
+		want   int
+	}{
+		{"loopback without token", "", "127.0.0.1:5000", "", http.StatusOK},
+		{"ipv6 loopback without token", "", "[::1]:5000", "", http.StatusOK},
+		{"remote without token", "", "192.168.1.20:5000", "", http.StatusForbidden},
+		{"remote with token", "s3cret", "192.168.1.20:5000", "Bearer s3cret", http.StatusOK},
+		{"wrong token", "s3cret", "192.168.1.20:5000", "Bearer guess", http.StatusUnauthorized},
+		{"loopback needs the token once set", "s3cret", "127.0.0.1:5000", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/tasks?id=1", nil)
+			req.RemoteAddr = tt.remote
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			RequireAdmin(ok, tt.token).ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
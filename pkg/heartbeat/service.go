@@ -7,7 +7,9 @@
 package heartbeat
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -19,6 +21,7 @@ import (
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/state"
 	"github.com/sipeed/picoclaw/pkg/tools"
+	"github.com/sipeed/picoclaw/pkg/vfs"
 )
 
 const (
@@ -39,6 +42,7 @@ type HeartbeatService struct {
 	handler   HeartbeatHandler
 	interval  time.Duration
 	enabled   bool
+	deferFn   func() bool
 	mu        sync.RWMutex
 	stopChan  chan struct{}
 }
@@ -76,6 +80,14 @@ func (hs *HeartbeatService) SetHandler(handler HeartbeatHandler) {
 	hs.handler = handler
 }
 
+// SetDeferCheck sets a function that, when it returns true, makes the
+// service skip heartbeats (e.g. under memory pressure).
+func (hs *HeartbeatService) SetDeferCheck(fn func() bool) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.deferFn = fn
+}
+
 // Start begins the heartbeat service
 func (hs *HeartbeatService) Start() error {
 	hs.mu.Lock()
@@ -147,6 +159,7 @@ func (hs *HeartbeatService) executeHeartbeat() {
 	hs.mu.RLock()
 	enabled := hs.enabled
 	handler := hs.handler
+	deferFn := hs.deferFn
 	if !hs.enabled || hs.stopChan == nil {
 		hs.mu.RUnlock()
 		return
@@ -157,6 +170,11 @@ func (hs *HeartbeatService) executeHeartbeat() {
 		return
 	}
 
+	if deferFn != nil && deferFn() {
+		logger.InfoC("heartbeat", "Heartbeat deferred due to memory pressure")
+		return
+	}
+
 	logger.DebugC("heartbeat", "Executing heartbeat")
 
 	prompt := hs.buildPrompt()
@@ -219,9 +237,9 @@ func (hs *HeartbeatService) executeHeartbeat() {
 func (hs *HeartbeatService) buildPrompt() string {
 	heartbeatPath := filepath.Join(hs.workspace, "HEARTBEAT.md")
 
-	data, err := os.ReadFile(heartbeatPath)
+	data, err := vfs.ReadFile(heartbeatPath)
 	if err != nil {
-		if os.IsNotExist(err) {
+		if errors.Is(err, fs.ErrNotExist) {
 			hs.createDefaultHeartbeatTemplate()
 			return ""
 		}
@@ -275,7 +293,7 @@ This file contains tasks for the heartbeat service to check periodically.
 Add your heartbeat tasks below this line:
 `
 
-	if err := os.WriteFile(heartbeatPath, []byte(defaultContent), 0644); err != nil {
+	if err := vfs.WriteFile(heartbeatPath, []byte(defaultContent), 0644); err != nil {
 		hs.logError("Failed to create default HEARTBEAT.md: %v", err)
 	} else {
 		hs.logInfo("Created default HEARTBEAT.md template")
@@ -308,9 +326,10 @@ func (hs *HeartbeatService) sendResponse(response string) {
 	}
 
 	msgBus.PublishOutbound(bus.OutboundMessage{
-		Channel: platform,
-		ChatID:  userID,
-		Content: response,
+		Channel:   platform,
+		ChatID:    userID,
+		Content:   response,
+		Proactive: true,
 	})
 
 	hs.logInfo("Heartbeat result sent to %s", platform)
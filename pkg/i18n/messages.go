@@ -0,0 +1,259 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package i18n holds the templates for text that picoclaw itself sends to
+// users (as opposed to model output), so deployments can localize and brand
+// them from config.
+package i18n
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// Key identifies a system message.
+type Key string
+
+// Template data fields available for each key are listed next to it.
+const (
+	ProcessingError    Key = "processing_error" // .Error
+	NoResponse         Key = "no_response"
+	BackgroundDone     Key = "background_done"
+	ContextCompressing Key = "context_compressing"
+	MemoryCompressing  Key = "memory_compressing"
+	ToolDenied         Key = "tool_denied" // .Tool, .Reason
+	Offline            Key = "offline"
+	ApprovalRequest    Key = "approval_request" // .Tool, .Summary
+
+	CmdShowUsage        Key = "cmd_show_usage"
+	CmdListUsage        Key = "cmd_list_usage"
+	CmdSwitchUsage      Key = "cmd_switch_usage"
+	CmdNoDefaultAgent   Key = "cmd_no_default_agent"
+	CmdCurrentModel     Key = "cmd_current_model"     // .Model
+	CmdCurrentChannel   Key = "cmd_current_channel"   // .Channel
+	CmdRegisteredAgents Key = "cmd_registered_agents" // .Agents
+	CmdAvailableModels  Key = "cmd_available_models"
+	CmdNoChannelManager Key = "cmd_no_channel_manager"
+	CmdNoChannels       Key = "cmd_no_channels"
+	CmdEnabledChannels  Key = "cmd_enabled_channels"  // .Channels
+	CmdSwitchedModel    Key = "cmd_switched_model"    // .From, .To
+	CmdChannelNotFound  Key = "cmd_channel_not_found" // .Channel
+	CmdSwitchedChannel  Key = "cmd_switched_channel"  // .Channel
+	CmdUnknownTarget    Key = "cmd_unknown_target"    // .Command, .Target
+	CmdExported         Key = "cmd_exported"          // .Path
+	CmdExportEmpty      Key = "cmd_export_empty"
+	CmdExportFailed     Key = "cmd_export_failed" // .Error
+	CmdWhyEmpty         Key = "cmd_why_empty"
+	CmdTasksUsage       Key = "cmd_tasks_usage"
+	CmdTasksEmpty       Key = "cmd_tasks_empty"
+	CmdTaskCancelled    Key = "cmd_task_cancelled"     // .ID
+	CmdTaskCancelFailed Key = "cmd_task_cancel_failed" // .Error
+	CmdForgetUsage      Key = "cmd_forget_usage"
+	CmdForgotten        Key = "cmd_forgotten" // .Messages, .Matches
+	CmdForgetNothing    Key = "cmd_forget_nothing"
+	CmdForgetFailed     Key = "cmd_forget_failed" // .Error
+)
+
+// DefaultLanguage is used when no language is configured and as the
+// fallback when a language has no template for a key.
+const DefaultLanguage = "en"
+
+var defaults = map[string]map[Key]string{
+	"en": {
+		ProcessingError:    "Error processing message: {{.Error}}",
+		NoResponse:         "I've completed processing but have no response to give.",
+		BackgroundDone:     "Background task completed.",
+		ContextCompressing: "Context window exceeded. Compressing history and retrying...",
+		MemoryCompressing:  "Memory threshold reached. Optimizing conversation history...",
+		ToolDenied:         "The {{.Tool}} tool was blocked: {{.Reason}}",
+		Offline:            "I'm offline right now and can't reach the model. Your message will be answered once I'm back.",
+		ApprovalRequest:    "The agent wants to run {{.Tool}}: {{.Summary}}\nReply \"approve\" or \"deny\".",
+
+		CmdShowUsage:        "Usage: /show [model|channel|agents]",
+		CmdListUsage:        "Usage: /list [models|channels|agents]",
+		CmdSwitchUsage:      "Usage: /switch [model|channel] to <name>",
+		CmdNoDefaultAgent:   "No default agent configured",
+		CmdCurrentModel:     "Current model: {{.Model}}",
+		CmdCurrentChannel:   "Current channel: {{.Channel}}",
+		CmdRegisteredAgents: "Registered agents: {{.Agents}}",
+		CmdAvailableModels:  "Available models: configured in config.json per agent",
+		CmdNoChannelManager: "Channel manager not initialized",
+		CmdNoChannels:       "No channels enabled",
+		CmdEnabledChannels:  "Enabled channels: {{.Channels}}",
+		CmdSwitchedModel:    "Switched model from {{.From}} to {{.To}}",
+		CmdChannelNotFound:  "Channel '{{.Channel}}' not found or not enabled",
+		CmdSwitchedChannel:  "Switched target channel to {{.Channel}}",
+		CmdUnknownTarget:    "Unknown {{.Command}} target: {{.Target}}",
+		CmdExported:         "Conversation exported to {{.Path}}",
+		CmdExportEmpty:      "There are no messages in this conversation to export",
+		CmdExportFailed:     "Failed to export conversation: {{.Error}}",
+		CmdWhyEmpty:         "Nothing has been sent to the model in this conversation yet",
+		CmdTasksUsage:       "Usage: /tasks [cancel <id>]",
+		CmdTasksEmpty:       "No background tasks were started in this chat",
+		CmdTaskCancelled:    "Cancelled task {{.ID}}",
+		CmdTaskCancelFailed: "Failed to cancel task: {{.Error}}",
+		CmdForgetUsage:      "Usage: /forget last [n] | /forget <text of at least 4 characters>",
+		CmdForgotten:        "Forgotten: {{.Messages}} message(s) removed, {{.Matches}} match(es) redacted",
+		CmdForgetNothing:    "Nothing matched, so nothing was forgotten",
+		CmdForgetFailed:     "Failed to forget: {{.Error}}",
+	},
+	"zh": {
+		ProcessingError:    "处理消息时出错：{{.Error}}",
+		NoResponse:         "处理已完成，但没有可回复的内容。",
+		BackgroundDone:     "后台任务已完成。",
+		ContextCompressing: "上下文窗口已满，正在压缩历史记录并重试……",
+		MemoryCompressing:  "已达到记忆阈值，正在优化对话历史……",
+		ToolDenied:         "工具 {{.Tool}} 已被拦截：{{.Reason}}",
+		Offline:            "我现在处于离线状态，无法连接模型。恢复后会回复你的消息。",
+		ApprovalRequest:    "智能体请求运行 {{.Tool}}：{{.Summary}}\n请回复 \"approve\"（批准）或 \"deny\"（拒绝）。",
+
+		CmdShowUsage:        "用法：/show [model|channel|agents]",
+		CmdListUsage:        "用法：/list [models|channels|agents]",
+		CmdSwitchUsage:      "用法：/switch [model|channel] to <名称>",
+		CmdNoDefaultAgent:   "未配置默认智能体",
+		CmdCurrentModel:     "当前模型：{{.Model}}",
+		CmdCurrentChannel:   "当前渠道：{{.Channel}}",
+		CmdRegisteredAgents: "已注册的智能体：{{.Agents}}",
+		CmdAvailableModels:  "可用模型：在 config.json 中按智能体配置",
+		CmdNoChannelManager: "渠道管理器未初始化",
+		CmdNoChannels:       "没有启用的渠道",
+		CmdEnabledChannels:  "已启用的渠道：{{.Channels}}",
+		CmdSwitchedModel:    "模型已从 {{.From}} 切换为 {{.To}}",
+		CmdChannelNotFound:  "渠道 '{{.Channel}}' 不存在或未启用",
+		CmdSwitchedChannel:  "目标渠道已切换为 {{.Channel}}",
+		CmdUnknownTarget:    "未知的 {{.Command}} 目标：{{.Target}}",
+		CmdExported:         "对话已导出到 {{.Path}}",
+		CmdExportEmpty:      "当前对话还没有可导出的消息",
+		CmdExportFailed:     "导出对话失败：{{.Error}}",
+		CmdWhyEmpty:         "当前对话还没有可解释的请求",
+		CmdTasksUsage:       "用法：/tasks [cancel <id>]",
+		CmdTasksEmpty:       "当前聊天还没有启动后台任务",
+		CmdTaskCancelled:    "已取消任务 {{.ID}}",
+		CmdTaskCancelFailed: "取消任务失败：{{.Error}}",
+		CmdForgetUsage:      "用法：/forget last [n] | /forget <至少 4 个字符的文本>",
+		CmdForgotten:        "已遗忘：删除 {{.Messages}} 条消息，涂抹 {{.Matches}} 处匹配",
+		CmdForgetNothing:    "没有匹配的内容，未遗忘任何内容",
+		CmdForgetFailed:     "遗忘失败：{{.Error}}",
+	},
+}
+
+// Catalog renders system messages, preferring configured overrides over
+// the built-in defaults.
+type Catalog struct {
+	language  string
+	templates map[string]map[Key]*template.Template
+}
+
+// NewCatalog builds a catalog for language with optional overrides keyed by
+// language and then message key. Overrides that fail to parse are logged
+// and the default is kept.
+func NewCatalog(language string, overrides map[string]map[string]string) *Catalog {
+	language = Normalize(language)
+	if language == "" {
+		language = DefaultLanguage
+	}
+	c := &Catalog{
+		language:  language,
+		templates: make(map[string]map[Key]*template.Template),
+	}
+
+	for lang, msgs := range defaults {
+		for key, text := range msgs {
+			c.set(lang, key, template.Must(template.New(string(key)).Parse(text)))
+		}
+	}
+	for lang, msgs := range overrides {
+		lang = Normalize(lang)
+		for key, text := range msgs {
+			tmpl, err := template.New(key).Option("missingkey=zero").Parse(text)
+			if err != nil {
+				logger.WarnCF("i18n", "Invalid message template, using default",
+					map[string]interface{}{
+						"language": lang,
+						"key":      key,
+						"error":    err.Error(),
+					})
+				continue
+			}
+			c.set(lang, Key(key), tmpl)
+		}
+	}
+
+	return c
+}
+
+func (c *Catalog) set(lang string, key Key, tmpl *template.Template) {
+	if c.templates[lang] == nil {
+		c.templates[lang] = make(map[Key]*template.Template)
+	}
+	c.templates[lang][key] = tmpl
+}
+
+// Normalize reduces a language tag such as "zh-Hans" or "en_US" to its
+// lowercase primary subtag, which is how catalogs are keyed.
+func Normalize(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	return tag
+}
+
+// Resolve returns the first candidate language the catalog has templates
+// for, or the catalog's configured language if none match. Empty
+// candidates are skipped.
+func (c *Catalog) Resolve(candidates ...string) string {
+	if c == nil {
+		return DefaultLanguage
+	}
+	for _, cand := range candidates {
+		if lang := Normalize(cand); lang != "" && c.templates[lang] != nil {
+			return lang
+		}
+	}
+	return c.language
+}
+
+// Language returns the catalog's configured language.
+func (c *Catalog) Language() string {
+	return c.language
+}
+
+// Get renders key in the catalog's language.
+func (c *Catalog) Get(key Key, data map[string]interface{}) string {
+	if c == nil {
+		return c.Render(DefaultLanguage, key, data)
+	}
+	return c.Render(c.language, key, data)
+}
+
+// Render renders key in lang, falling back to DefaultLanguage. A nil
+// catalog renders the built-in English text.
+func (c *Catalog) Render(lang string, key Key, data map[string]interface{}) string {
+	if c == nil {
+		c = NewCatalog(DefaultLanguage, nil)
+	}
+	for _, l := range []string{Normalize(lang), c.language, DefaultLanguage} {
+		tmpl, ok := c.templates[l][key]
+		if !ok {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			logger.WarnCF("i18n", "Failed to render message template",
+				map[string]interface{}{
+					"language": l,
+					"key":      string(key),
+					"error":    err.Error(),
+				})
+			continue
+		}
+		return buf.String()
+	}
+	return string(key)
+}
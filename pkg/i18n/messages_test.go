@@ -0,0 +1,79 @@
+package i18n
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCatalog_DefaultsRender(t *testing.T) {
+	c := NewCatalog("", nil)
+	got := c.Get(ProcessingError, map[string]interface{}{"Error": errors.New("boom")})
+	if got != "Error processing message: boom" {
+		t.Errorf("Get(ProcessingError) = %q", got)
+	}
+	if c.Language() != DefaultLanguage {
+		t.Errorf("Language() = %q, want %q", c.Language(), DefaultLanguage)
+	}
+}
+
+func TestCatalog_OverridesAndFallback(t *testing.T) {
+	c := NewCatalog("de", map[string]map[string]string{
+		"de": {"processing_error": "Fehler: {{.Error}}"},
+		"en": {"no_response": "Done."},
+	})
+
+	if got := c.Get(ProcessingError, map[string]interface{}{"Error": "x"}); got != "Fehler: x" {
+		t.Errorf("override not used, got %q", got)
+	}
+	// "de" has no no_response entry, so the English override applies.
+	if got := c.Get(NoResponse, nil); got != "Done." {
+		t.Errorf("fallback to English override failed, got %q", got)
+	}
+	// Render can pick a language other than the catalog default.
+	if got := c.Render("en", ProcessingError, map[string]interface{}{"Error": "x"}); got != "Error processing message: x" {
+		t.Errorf("Render(en) = %q", got)
+	}
+}
+
+func TestCatalog_InvalidOverrideKeepsDefault(t *testing.T) {
+	c := NewCatalog("en", map[string]map[string]string{
+		"en": {"offline": "{{.Broken"},
+	})
+	if got := c.Get(Offline, nil); got != defaults["en"][Offline] {
+		t.Errorf("expected default offline text, got %q", got)
+	}
+}
+
+func TestCatalog_NilUsesDefaults(t *testing.T) {
+	var c *Catalog
+	if got := c.Get(BackgroundDone, nil); got != "Background task completed." {
+		t.Errorf("nil catalog Get() = %q", got)
+	}
+}
+
+func TestCatalog_ResolveAndNormalize(t *testing.T) {
+	c := NewCatalog("en", nil)
+	tests := []struct {
+		candidates []string
+		want       string
+	}{
+		{[]string{"zh-CN"}, "zh"},
+		{[]string{"", "zh_TW"}, "zh"},
+		{[]string{"fr", "EN-us"}, "en"},
+		{[]string{"fr"}, "en"},
+		{nil, "en"},
+	}
+	for _, tt := range tests {
+		if got := c.Resolve(tt.candidates...); got != tt.want {
+			t.Errorf("Resolve(%v) = %q, want %q", tt.candidates, got, tt.want)
+		}
+	}
+}
+
+func TestCatalog_ChineseCoversAllEnglishKeys(t *testing.T) {
+	for key := range defaults["en"] {
+		if _, ok := defaults["zh"][key]; !ok {
+			t.Errorf("zh catalog is missing %q", key)
+		}
+	}
+}
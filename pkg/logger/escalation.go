@@ -0,0 +1,264 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TraceField is the field that ties together the entries logged for one
+// piece of work, such as an agent turn, across components.
+const TraceField = "trace_id"
+
+// maxTraces bounds how many recent traces escalation remembers the
+// components of.
+const maxTraces = 256
+
+type traceKey struct{}
+
+// WithTrace returns ctx with a new trace ID, or ctx itself if it already
+// carries one.
+func WithTrace(ctx context.Context) context.Context {
+	if TraceID(ctx) != "" {
+		return ctx
+	}
+	b := make([]byte, 8)
+	rand.Read(b)
+	return context.WithValue(ctx, traceKey{}, hex.EncodeToString(b))
+}
+
+// TraceID returns the trace ID ctx carries, or "".
+func TraceID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(traceKey{}).(string)
+	return id
+}
+
+// TraceFields adds the trace ID of ctx to fields, creating them if needed,
+// and returns them.
+func TraceFields(ctx context.Context, fields map[string]interface{}) map[string]interface{} {
+	id := TraceID(ctx)
+	if id == "" {
+		return fields
+	}
+	if fields == nil {
+		fields = map[string]interface{}{}
+	}
+	fields[TraceField] = id
+	return fields
+}
+
+// ParseLevel returns the level named s, such as "debug" or "WARN".
+func ParseLevel(s string) (LogLevel, bool) {
+	for level, name := range logLevelNames {
+		if strings.EqualFold(s, name) {
+			return level, true
+		}
+	}
+	return INFO, false
+}
+
+// EscalationPolicy raises the log level of a component that logs Threshold
+// errors within Window to Level for Duration, so that the detail of a
+// problem is captured while it happens. Components that logged under the
+// same trace as those errors are raised with it.
+type EscalationPolicy struct {
+	Threshold int
+	Window    time.Duration
+	Duration  time.Duration
+	Level     LogLevel
+}
+
+var (
+	escalation atomic.Pointer[escalator]
+	// raisedLevels maps escalated components to the level they log at.
+	raisedLevels atomic.Pointer[map[string]LogLevel]
+)
+
+// ConfigureEscalation sets the escalation policy. A policy without a
+// Threshold turns escalation off and restores all components' levels.
+func ConfigureEscalation(p EscalationPolicy) {
+	if p.Threshold <= 0 || p.Duration <= 0 {
+		escalation.Store(nil)
+		raisedLevels.Store(nil)
+		return
+	}
+	if p.Window <= 0 {
+		p.Window = time.Minute
+	}
+	escalation.Store(&escalator{
+		policy: p,
+		errors: make(map[string][]errorMark),
+		traces: make(map[string]map[string]bool),
+		raised: make(map[string]time.Time),
+	})
+	raisedLevels.Store(nil)
+}
+
+// RaisedLevels returns the components whose level is raised, with the
+// level they log at.
+func RaisedLevels() map[string]LogLevel {
+	out := map[string]LogLevel{}
+	if p := raisedLevels.Load(); p != nil {
+		for k, v := range *p {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// EnabledFor reports whether entries of level logged by component are
+// logged, counting a level raised by escalation.
+func EnabledFor(component string, level LogLevel) bool {
+	return Enabled(level) || raisedFor(component, level)
+}
+
+// raisedFor reports whether component logs entries of level while raised.
+func raisedFor(component string, level LogLevel) bool {
+	p := raisedLevels.Load()
+	if p == nil {
+		return false
+	}
+	raised, ok := (*p)[component]
+	return ok && level >= raised
+}
+
+type errorMark struct {
+	at    time.Time
+	trace string
+}
+
+type escalator struct {
+	policy EscalationPolicy
+
+	mu     sync.Mutex
+	errors map[string][]errorMark
+	// traces holds the components seen under each recent trace, oldest
+	// first in traceOrder.
+	traces     map[string]map[string]bool
+	traceOrder []string
+	raised     map[string]time.Time
+}
+
+// observe notes an entry that was logged and raises levels when its
+// component crosses the error threshold.
+func (e *escalator) observe(level LogLevel, component string, fields map[string]interface{}) {
+	if component == "" || component == "logger" {
+		return
+	}
+	trace, _ := fields[TraceField].(string)
+	now := time.Now()
+
+	e.mu.Lock()
+	if trace != "" {
+		e.noteTrace(trace, component)
+	}
+	if level < ERROR {
+		e.mu.Unlock()
+		return
+	}
+	marks := e.errors[component][:0]
+	for _, m := range e.errors[component] {
+		if now.Sub(m.at) <= e.policy.Window {
+			marks = append(marks, m)
+		}
+	}
+	marks = append(marks, errorMark{at: now, trace: trace})
+	e.errors[component] = marks
+	if len(marks) < e.policy.Threshold {
+		e.mu.Unlock()
+		return
+	}
+	delete(e.errors, component)
+
+	until := now.Add(e.policy.Duration)
+	var raised, related []string
+	raise := func(c string) {
+		if _, ok := e.raised[c]; ok {
+			return
+		}
+		e.raised[c] = until
+		raised = append(raised, c)
+		if c != component {
+			related = append(related, c)
+		}
+	}
+	raise(component)
+	for _, m := range marks {
+		for c := range e.traces[m.trace] {
+			raise(c)
+		}
+	}
+	e.publish()
+	e.mu.Unlock()
+
+	if len(raised) == 0 {
+		return
+	}
+	WarnCF("logger", "Log level raised after repeated errors", map[string]interface{}{
+		"component": component,
+		"errors":    len(marks),
+		"related":   strings.Join(related, ","),
+		"level":     logLevelNames[e.policy.Level],
+		"until":     until.UTC().Format(time.RFC3339),
+	})
+	time.AfterFunc(e.policy.Duration, func() { e.restore(raised, until) })
+}
+
+// noteTrace records that component logged under trace. e.mu must be held.
+func (e *escalator) noteTrace(trace, component string) {
+	components, ok := e.traces[trace]
+	if !ok {
+		if len(e.traceOrder) >= maxTraces {
+			delete(e.traces, e.traceOrder[0])
+			e.traceOrder = e.traceOrder[1:]
+		}
+		components = map[string]bool{}
+		e.traces[trace] = components
+		e.traceOrder = append(e.traceOrder, trace)
+	}
+	components[component] = true
+}
+
+// restore returns components raised until until to the global level.
+func (e *escalator) restore(components []string, until time.Time) {
+	e.mu.Lock()
+	var restored []string
+	for _, c := range components {
+		if e.raised[c].Equal(until) {
+			delete(e.raised, c)
+			restored = append(restored, c)
+		}
+	}
+	e.publish()
+	e.mu.Unlock()
+
+	if len(restored) > 0 && escalation.Load() == e {
+		InfoCF("logger", "Log level restored", map[string]interface{}{
+			"components": strings.Join(restored, ","),
+		})
+	}
+}
+
+// publish replaces raisedLevels with the raised components, unless the
+// policy has been replaced since. e.mu must be held.
+func (e *escalator) publish() {
+	if escalation.Load() != e {
+		return
+	}
+	if len(e.raised) == 0 {
+		raisedLevels.Store(nil)
+		return
+	}
+	levels := make(map[string]LogLevel, len(e.raised))
+	for c := range e.raised {
+		levels[c] = e.policy.Level
+	}
+	raisedLevels.Store(&levels)
+}
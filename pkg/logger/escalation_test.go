@@ -0,0 +1,83 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEscalation(t *testing.T) {
+	initialLevel := GetLevel()
+	defer SetLevel(initialLevel)
+	SetLevel(INFO)
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	ConfigureEscalation(EscalationPolicy{Threshold: 3, Window: time.Minute, Duration: 100 * time.Millisecond, Level: DEBUG})
+	defer ConfigureEscalation(EscalationPolicy{})
+
+	var mu sync.Mutex
+	var debug []string
+	defer AddHook(func(level LogLevel, entry LogEntry) {
+		if level == DEBUG {
+			mu.Lock()
+			debug = append(debug, entry.Component)
+			mu.Unlock()
+		}
+	})()
+	debugged := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		out := debug
+		debug = nil
+		return out
+	}
+
+	ctx := WithTrace(context.Background())
+	InfoCF("caller", "calling", TraceFields(ctx, nil))
+	for i := 0; i < 2; i++ {
+		ErrorCF("failing", "boom", TraceFields(ctx, nil))
+	}
+	DebugC("failing", "detail")
+	if got := debugged(); len(got) != 0 {
+		t.Fatalf("debug logged below the threshold: %v", got)
+	}
+
+	ErrorCF("failing", "boom", TraceFields(ctx, nil))
+	DebugC("failing", "detail")
+	DebugC("caller", "detail")
+	DebugC("bystander", "detail")
+	if got := debugged(); len(got) != 2 || got[0] != "failing" || got[1] != "caller" {
+		t.Fatalf("debug entries while raised = %v, want [failing caller]", got)
+	}
+	if !EnabledFor("failing", DEBUG) || EnabledFor("bystander", DEBUG) {
+		t.Error("EnabledFor does not follow the raised levels")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(RaisedLevels()) > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	DebugC("failing", "detail")
+	if got := debugged(); len(got) != 0 {
+		t.Errorf("debug logged after the level was restored: %v", got)
+	}
+}
+
+func TestWithTrace(t *testing.T) {
+	if TraceID(context.Background()) != "" {
+		t.Fatal("background context has a trace")
+	}
+	ctx := WithTrace(context.Background())
+	id := TraceID(ctx)
+	if id == "" || TraceID(WithTrace(ctx)) != id {
+		t.Errorf("WithTrace did not keep trace %q", id)
+	}
+	if fields := TraceFields(ctx, nil); fields[TraceField] != id {
+		t.Errorf("TraceFields() = %v", fields)
+	}
+}
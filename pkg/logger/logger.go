@@ -1,13 +1,14 @@
 package logger
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"runtime"
-	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -30,12 +31,25 @@ var (
 		FATAL: "FATAL",
 	}
 
-	currentLevel = INFO
+	currentLevel atomic.Int32
 	logger       *Logger
 	once         sync.Once
 	mu           sync.RWMutex
+
+	hooks      = map[int]Hook{}
+	nextHookID int
+	// activeHooks is a copy of hooks that entries are passed to without
+	// taking mu, replaced whenever a hook is added or removed.
+	activeHooks atomic.Pointer[[]Hook]
+
+	// bufPool holds the buffers log lines are formatted in.
+	bufPool = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
 )
 
+// Hook receives every log entry that passes the level filter. Hooks run
+// synchronously on the logging goroutine and must not block.
+type Hook func(level LogLevel, entry LogEntry)
+
 type Logger struct {
 	file *os.File
 }
@@ -52,19 +66,48 @@ type LogEntry struct {
 func init() {
 	once.Do(func() {
 		logger = &Logger{}
+		currentLevel.Store(int32(INFO))
 	})
 }
 
 func SetLevel(level LogLevel) {
+	currentLevel.Store(int32(level))
+}
+
+func GetLevel() LogLevel {
+	return LogLevel(currentLevel.Load())
+}
+
+// Enabled reports whether entries of level are logged. Callers in hot
+// loops check it before building fields that would only be dropped.
+func Enabled(level LogLevel) bool {
+	return level >= LogLevel(currentLevel.Load())
+}
+
+// AddHook registers h and returns a function that removes it.
+func AddHook(h Hook) (remove func()) {
 	mu.Lock()
 	defer mu.Unlock()
-	currentLevel = level
+	id := nextHookID
+	nextHookID++
+	hooks[id] = h
+	updateActiveHooks()
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		delete(hooks, id)
+		updateActiveHooks()
+	}
 }
 
-func GetLevel() LogLevel {
-	mu.RLock()
-	defer mu.RUnlock()
-	return currentLevel
+// updateActiveHooks replaces activeHooks with the current hooks. mu must
+// be held.
+func updateActiveHooks() {
+	active := make([]Hook, 0, len(hooks))
+	for _, h := range hooks {
+		active = append(active, h)
+	}
+	activeHooks.Store(&active)
 }
 
 func EnableFileLogging(filePath string) error {
@@ -97,7 +140,7 @@ func DisableFileLogging() {
 }
 
 func logMessage(level LogLevel, component string, message string, fields map[string]interface{}) {
-	if level < currentLevel {
+	if !Enabled(level) && !raisedFor(component, level) {
 		return
 	}
 
@@ -109,53 +152,81 @@ func logMessage(level LogLevel, component string, message string, fields map[str
 		Fields:    fields,
 	}
 
-	if pc, file, line, ok := runtime.Caller(2); ok {
-		fn := runtime.FuncForPC(pc)
-		if fn != nil {
-			entry.Caller = fmt.Sprintf("%s:%d (%s)", file, line, fn.Name())
+	var active []Hook
+	if p := activeHooks.Load(); p != nil {
+		active = *p
+	}
+	file := logger.file
+
+	// Only the log file and hooks see the caller, which is costly to find.
+	if file != nil || len(active) > 0 {
+		if pc, path, line, ok := runtime.Caller(2); ok {
+			if fn := runtime.FuncForPC(pc); fn != nil {
+				entry.Caller = fmt.Sprintf("%s:%d (%s)", path, line, fn.Name())
+			}
 		}
 	}
 
-	if logger.file != nil {
+	if file != nil {
 		jsonData, err := json.Marshal(entry)
 		if err == nil {
-			logger.file.WriteString(string(jsonData) + "\n")
+			file.Write(append(jsonData, '\n'))
 		}
 	}
 
-	var fieldStr string
+	buf := bufPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	buf.WriteByte('[')
+	buf.WriteString(entry.Timestamp)
+	buf.WriteString("] [")
+	buf.WriteString(entry.Level)
+	buf.WriteByte(']')
+	if component != "" {
+		buf.WriteByte(' ')
+		buf.WriteString(component)
+		buf.WriteByte(':')
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(message)
 	if len(fields) > 0 {
-		fieldStr = " " + formatFields(fields)
+		buf.WriteByte(' ')
+		writeFields(buf, fields)
 	}
+	buf.WriteByte('\n')
+	log.Print(buf.String())
+	bufPool.Put(buf)
 
-	logLine := fmt.Sprintf("[%s] [%s]%s %s%s",
-		entry.Timestamp,
-		logLevelNames[level],
-		formatComponent(component),
-		message,
-		fieldStr,
-	)
+	for _, h := range active {
+		h(level, entry)
+	}
 
-	log.Println(logLine)
+	if e := escalation.Load(); e != nil {
+		e.observe(level, component, fields)
+	}
 
 	if level == FATAL {
 		os.Exit(1)
 	}
 }
 
-func formatComponent(component string) string {
-	if component == "" {
-		return ""
-	}
-	return fmt.Sprintf(" %s:", component)
-}
-
-func formatFields(fields map[string]interface{}) string {
-	var parts []string
+// writeFields writes fields to buf as {key=value, ...}.
+func writeFields(buf *bytes.Buffer, fields map[string]interface{}) {
+	buf.WriteByte('{')
+	first := true
 	for k, v := range fields {
-		parts = append(parts, fmt.Sprintf("%s=%v", k, v))
+		if !first {
+			buf.WriteString(", ")
+		}
+		first = false
+		buf.WriteString(k)
+		buf.WriteByte('=')
+		if s, ok := v.(string); ok {
+			buf.WriteString(s)
+		} else {
+			fmt.Fprint(buf, v)
+		}
 	}
-	return fmt.Sprintf("{%s}", strings.Join(parts, ", "))
+	buf.WriteByte('}')
 }
 
 func Debug(message string) {
@@ -1,6 +1,11 @@
 package logger
 
 import (
+	"bytes"
+	"io"
+	"log"
+	"os"
+	"strings"
 	"testing"
 )
 
@@ -137,3 +142,96 @@ func TestLoggerHelperFunctions(t *testing.T) {
 	DebugC("test", "Debug with component")
 	WarnF("Warning with fields", map[string]interface{}{"key": "value"})
 }
+
+func TestAddHook(t *testing.T) {
+	initialLevel := GetLevel()
+	defer SetLevel(initialLevel)
+	SetLevel(WARN)
+
+	var got []LogEntry
+	remove := AddHook(func(level LogLevel, entry LogEntry) {
+		got = append(got, entry)
+	})
+
+	InfoC("hooktest", "filtered out by level")
+	ErrorCF("hooktest", "boom", map[string]interface{}{"k": "v"})
+
+	if len(got) != 1 {
+		t.Fatalf("hook received %d entries, want 1", len(got))
+	}
+	if got[0].Component != "hooktest" || got[0].Message != "boom" || got[0].Level != "ERROR" {
+		t.Errorf("unexpected entry: %+v", got[0])
+	}
+
+	remove()
+	ErrorC("hooktest", "after remove")
+	if len(got) != 1 {
+		t.Errorf("hook still called after remove")
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	initialLevel := GetLevel()
+	defer SetLevel(initialLevel)
+	SetLevel(WARN)
+
+	if Enabled(INFO) || !Enabled(WARN) || !Enabled(ERROR) {
+		t.Errorf("Enabled at WARN: INFO=%v WARN=%v ERROR=%v", Enabled(INFO), Enabled(WARN), Enabled(ERROR))
+	}
+}
+
+func TestLogLineFormat(t *testing.T) {
+	initialLevel := GetLevel()
+	defer SetLevel(initialLevel)
+	SetLevel(INFO)
+
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	flags := log.Flags()
+	log.SetFlags(0)
+	defer func() {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(flags)
+	}()
+
+	InfoCF("agent", "Reply sent", map[string]interface{}{"chars": 42})
+	Warn("plain")
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines: %q", len(lines), out.String())
+	}
+	if !strings.HasSuffix(lines[0], "] [INFO] agent: Reply sent {chars=42}") {
+		t.Errorf("line = %q", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], "] [WARN] plain") {
+		t.Errorf("line = %q", lines[1])
+	}
+}
+
+func TestDisabledLevelDoesNotAllocate(t *testing.T) {
+	initialLevel := GetLevel()
+	defer SetLevel(initialLevel)
+	SetLevel(INFO)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		DebugC("test", "dropped")
+	})
+	if allocs != 0 {
+		t.Errorf("DebugC at INFO allocated %v times", allocs)
+	}
+}
+
+func BenchmarkInfoCF(b *testing.B) {
+	initialLevel := GetLevel()
+	defer SetLevel(initialLevel)
+	SetLevel(INFO)
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	fields := map[string]interface{}{"agent_id": "main", "iteration": 3}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		InfoCF("agent", "LLM iteration", fields)
+	}
+}
@@ -0,0 +1,616 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package mqtt is a small MQTT 3.1.1 client covering what the MQTT channel
+// and tool need: connect with optional credentials and TLS, subscribe,
+// publish at QoS 0 or 1, and keepalive. Sessions are always clean and
+// QoS 2 is not supported.
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	packetConnect     = 1
+	packetConnack     = 2
+	packetPublish     = 3
+	packetPuback      = 4
+	packetSubscribe   = 8
+	packetSuback      = 9
+	packetUnsubscribe = 10
+	packetUnsuback    = 11
+	packetPingreq     = 12
+	packetPingresp    = 13
+	packetDisconnect  = 14
+)
+
+// maxPacketSize bounds incoming packets so a broker cannot make the client
+// allocate arbitrary amounts of memory.
+const maxPacketSize = 1 << 20
+
+// ErrClosed is returned for operations on a closed client.
+var ErrClosed = errors.New("mqtt: client closed")
+
+// Message is a received PUBLISH.
+type Message struct {
+	Topic    string
+	Payload  []byte
+	Retained bool
+}
+
+// Handler receives messages for a subscription. It runs on the client's
+// read loop and must not block.
+type Handler func(Message)
+
+// Options configures a connection.
+type Options struct {
+	// Broker is the broker URL: tcp://host:1883, or tls://host:8883 (also
+	// mqtt:// and mqtts://, ssl://). The port defaults by scheme.
+	Broker string
+	// ClientID identifies the session; a random one is generated if empty.
+	ClientID  string
+	Username  string
+	Password  string
+	KeepAlive time.Duration // default 60s
+	TLSConfig *tls.Config
+	// Dial opens the TCP connection to the broker; a net.Dialer is used
+	// if nil.
+	Dial func(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+type packet struct {
+	kind  byte
+	flags byte
+	body  []byte
+}
+
+// Subscription is one subscriber's interest in a topic filter, returned
+// by Subscribe. Several subscriptions may share a filter; the broker is
+// only unsubscribed from it once all of them are removed.
+type Subscription struct {
+	filter  string
+	handler Handler
+}
+
+// Filter returns the topic filter of s.
+func (s *Subscription) Filter() string {
+	return s.filter
+}
+
+// Client is a connection to an MQTT broker. It is safe for concurrent use.
+type Client struct {
+	conn      net.Conn
+	keepAlive time.Duration
+
+	writeMu sync.Mutex
+
+	// subMu keeps SUBSCRIBE and UNSUBSCRIBE packets in the order the
+	// subscriptions they are for were added and removed.
+	subMu sync.Mutex
+
+	mu       sync.Mutex
+	nextID   uint16
+	pending  map[uint16]chan packet
+	subs     []*Subscription
+	lastRecv time.Time
+	err      error
+	done     chan struct{}
+}
+
+// Dial connects to the broker and completes the MQTT handshake.
+func Dial(ctx context.Context, opts Options) (*Client, error) {
+	addr, useTLS, err := brokerAddr(opts.Broker)
+	if err != nil {
+		return nil, err
+	}
+
+	dial := opts.Dial
+	if dial == nil {
+		var d net.Dialer
+		dial = d.DialContext
+	}
+	conn, err := dial(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("mqtt: connect to %s: %w", addr, err)
+	}
+	if useTLS {
+		cfg := opts.TLSConfig
+		if cfg == nil {
+			cfg = &tls.Config{}
+		}
+		if cfg.ServerName == "" {
+			cfg = cfg.Clone()
+			cfg.ServerName, _, _ = net.SplitHostPort(addr)
+		}
+		tlsConn := tls.Client(conn, cfg)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("mqtt: TLS handshake with %s: %w", addr, err)
+		}
+		conn = tlsConn
+	}
+	return connect(ctx, conn, opts)
+}
+
+// connect performs the handshake on an established connection.
+func connect(ctx context.Context, conn net.Conn, opts Options) (*Client, error) {
+	keepAlive := opts.KeepAlive
+	if keepAlive <= 0 {
+		keepAlive = 60 * time.Second
+	}
+
+	clientID := opts.ClientID
+	if clientID == "" {
+		var b [4]byte
+		rand.Read(b[:])
+		clientID = "picoclaw-" + hex.EncodeToString(b[:])
+	}
+
+	var flags byte = 0x02 // clean session
+	var payload []byte
+	payload = appendString(payload, clientID)
+	if opts.Username != "" {
+		flags |= 0x80
+		payload = appendString(payload, opts.Username)
+		if opts.Password != "" {
+			flags |= 0x40
+			payload = appendString(payload, opts.Password)
+		}
+	}
+	body := appendString(nil, "MQTT")
+	body = append(body, 4, flags)
+	body = binary.BigEndian.AppendUint16(body, uint16(keepAlive/time.Second))
+	body = append(body, payload...)
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(30 * time.Second))
+	}
+	if err := writePacket(conn, packetConnect<<4, body); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("mqtt: send CONNECT: %w", err)
+	}
+	r := bufio.NewReader(conn)
+	ack, err := readPacket(r)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("mqtt: read CONNACK: %w", err)
+	}
+	if ack.kind != packetConnack || len(ack.body) < 2 {
+		conn.Close()
+		return nil, fmt.Errorf("mqtt: expected CONNACK, got packet type %d", ack.kind)
+	}
+	if code := ack.body[1]; code != 0 {
+		conn.Close()
+		return nil, fmt.Errorf("mqtt: connection refused: %s", connackReason(code))
+	}
+	conn.SetDeadline(time.Time{})
+
+	c := &Client{
+		conn:      conn,
+		keepAlive: keepAlive,
+		pending:   make(map[uint16]chan packet),
+		lastRecv:  time.Now(),
+		done:      make(chan struct{}),
+	}
+	go c.readLoop(r)
+	go c.pingLoop()
+	return c, nil
+}
+
+func connackReason(code byte) string {
+	switch code {
+	case 1:
+		return "unacceptable protocol version"
+	case 2:
+		return "client identifier rejected"
+	case 3:
+		return "server unavailable"
+	case 4:
+		return "bad user name or password"
+	case 5:
+		return "not authorized"
+	}
+	return fmt.Sprintf("code %d", code)
+}
+
+// Done is closed when the connection ends.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns why the connection ended, or nil while it is open.
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Close disconnects from the broker.
+func (c *Client) Close() error {
+	c.writeMu.Lock()
+	writePacket(c.conn, packetDisconnect<<4, nil)
+	c.writeMu.Unlock()
+	c.shutdown(ErrClosed)
+	return nil
+}
+
+func (c *Client) shutdown(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	c.conn.Close()
+	close(c.done)
+}
+
+// Subscribe subscribes to filter, which may contain + and # wildcards, and
+// routes matching messages to handler until the returned subscription is
+// removed with Unsubscribe.
+func (c *Client) Subscribe(ctx context.Context, filter string, qos byte, handler Handler) (*Subscription, error) {
+	if qos > 1 {
+		qos = 1
+	}
+	sub := &Subscription{filter: filter, handler: handler}
+	body := appendString(nil, filter)
+	body = append(body, qos)
+
+	// The broker is sent SUBSCRIBE even if the filter is subscribed to
+	// already, so that this subscriber gets its retained messages too.
+	c.subMu.Lock()
+	c.mu.Lock()
+	c.subs = append(c.subs, sub)
+	c.mu.Unlock()
+	id, ch := c.register()
+	err := c.write(packetSubscribe<<4|0x02, append(binary.BigEndian.AppendUint16(nil, id), body...))
+	c.subMu.Unlock()
+
+	var ack packet
+	if err == nil {
+		ack, err = c.await(ctx, id, ch)
+	} else {
+		c.release(id)
+	}
+	if err == nil && (len(ack.body) < 3 || ack.body[2] == 0x80) {
+		err = fmt.Errorf("mqtt: broker rejected subscription to %q", filter)
+	}
+	if err != nil {
+		c.removeSub(sub)
+		return nil, err
+	}
+	return sub, nil
+}
+
+// Unsubscribe removes sub. The broker is unsubscribed from its filter
+// once no other subscription has the same filter.
+func (c *Client) Unsubscribe(ctx context.Context, sub *Subscription) error {
+	c.subMu.Lock()
+	if !c.removeSub(sub) {
+		c.subMu.Unlock()
+		return nil
+	}
+	id, ch := c.register()
+	body := binary.BigEndian.AppendUint16(nil, id)
+	body = appendString(body, sub.filter)
+	err := c.write(packetUnsubscribe<<4|0x02, body)
+	c.subMu.Unlock()
+	if err != nil {
+		c.release(id)
+		return err
+	}
+	_, err = c.await(ctx, id, ch)
+	return err
+}
+
+// removeSub removes sub and reports whether it was the last subscription
+// to its filter.
+func (c *Client) removeSub(sub *Subscription) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	found, last := false, true
+	for i := 0; i < len(c.subs); i++ {
+		switch {
+		case c.subs[i] == sub:
+			c.subs = append(c.subs[:i], c.subs[i+1:]...)
+			found = true
+			i--
+		case c.subs[i].filter == sub.filter:
+			last = false
+		}
+	}
+	return found && last
+}
+
+// Publish sends payload to topic. At QoS 1 it waits for the broker's
+// acknowledgement.
+func (c *Client) Publish(ctx context.Context, topic string, payload []byte, qos byte, retain bool) error {
+	if strings.ContainsAny(topic, "+#") {
+		return fmt.Errorf("mqtt: cannot publish to wildcard topic %q", topic)
+	}
+	header := byte(packetPublish << 4)
+	if retain {
+		header |= 0x01
+	}
+	body := appendString(nil, topic)
+	if qos == 0 {
+		body = append(body, payload...)
+		return c.write(header, body)
+	}
+
+	header |= 1 << 1
+	id, ch := c.register()
+	body = binary.BigEndian.AppendUint16(body, id)
+	body = append(body, payload...)
+	_, err := c.request(ctx, header, body, id, ch)
+	return err
+}
+
+// register allocates a packet ID and a channel for its acknowledgement.
+func (c *Client) register() (uint16, chan packet) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for {
+		c.nextID++
+		if c.nextID == 0 {
+			c.nextID = 1
+		}
+		if _, used := c.pending[c.nextID]; !used {
+			break
+		}
+	}
+	ch := make(chan packet, 1)
+	c.pending[c.nextID] = ch
+	return c.nextID, ch
+}
+
+func (c *Client) request(ctx context.Context, header byte, body []byte, id uint16, ch chan packet) (packet, error) {
+	if err := c.write(header, body); err != nil {
+		c.release(id)
+		return packet{}, err
+	}
+	return c.await(ctx, id, ch)
+}
+
+// await waits for the acknowledgement of packet id and releases the ID.
+func (c *Client) await(ctx context.Context, id uint16, ch chan packet) (packet, error) {
+	defer c.release(id)
+	select {
+	case p := <-ch:
+		return p, nil
+	case <-c.done:
+		return packet{}, c.Err()
+	case <-ctx.Done():
+		return packet{}, ctx.Err()
+	}
+}
+
+// release frees packet ID id for reuse.
+func (c *Client) release(id uint16) {
+	c.mu.Lock()
+	delete(c.pending, id)
+	c.mu.Unlock()
+}
+
+func (c *Client) write(header byte, body []byte) error {
+	select {
+	case <-c.done:
+		return c.Err()
+	default:
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(c.keepAlive))
+	if err := writePacket(c.conn, header, body); err != nil {
+		c.shutdown(fmt.Errorf("mqtt: write: %w", err))
+		return c.Err()
+	}
+	return nil
+}
+
+func (c *Client) readLoop(r *bufio.Reader) {
+	for {
+		p, err := readPacket(r)
+		if err != nil {
+			c.shutdown(fmt.Errorf("mqtt: connection lost: %w", err))
+			return
+		}
+		c.mu.Lock()
+		c.lastRecv = time.Now()
+		c.mu.Unlock()
+
+		switch p.kind {
+		case packetPublish:
+			c.handlePublish(p)
+		case packetPuback, packetSuback, packetUnsuback:
+			if len(p.body) < 2 {
+				continue
+			}
+			id := binary.BigEndian.Uint16(p.body)
+			c.mu.Lock()
+			ch := c.pending[id]
+			c.mu.Unlock()
+			if ch != nil {
+				ch <- p
+			}
+		}
+	}
+}
+
+func (c *Client) handlePublish(p packet) {
+	topic, rest, ok := readString(p.body)
+	if !ok {
+		return
+	}
+	qos := (p.flags >> 1) & 0x03
+	if qos > 0 {
+		if len(rest) < 2 {
+			return
+		}
+		id := rest[:2]
+		rest = rest[2:]
+		c.write(packetPuback<<4, append([]byte(nil), id...))
+	}
+	msg := Message{Topic: topic, Payload: rest, Retained: p.flags&0x01 != 0}
+
+	c.mu.Lock()
+	var handlers []Handler
+	for _, s := range c.subs {
+		if Match(s.filter, topic) {
+			handlers = append(handlers, s.handler)
+		}
+	}
+	c.mu.Unlock()
+	for _, h := range handlers {
+		h(msg)
+	}
+}
+
+func (c *Client) pingLoop() {
+	ticker := time.NewTicker(c.keepAlive * 3 / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			c.mu.Lock()
+			idle := time.Since(c.lastRecv)
+			c.mu.Unlock()
+			if idle > c.keepAlive*3/2 {
+				c.shutdown(errors.New("mqtt: broker stopped responding"))
+				return
+			}
+			c.write(packetPingreq<<4, nil)
+		}
+	}
+}
+
+// Match reports whether topic matches filter, which may contain the +
+// (one level) and # (remaining levels) wildcards.
+func Match(filter, topic string) bool {
+	fl := strings.Split(filter, "/")
+	tl := strings.Split(topic, "/")
+	for i, f := range fl {
+		if f == "#" {
+			return true
+		}
+		if i >= len(tl) {
+			return false
+		}
+		if f != "+" && f != tl[i] {
+			return false
+		}
+	}
+	return len(fl) == len(tl)
+}
+
+func brokerAddr(broker string) (addr string, useTLS bool, err error) {
+	if broker == "" {
+		return "", false, errors.New("mqtt: broker not configured")
+	}
+	if !strings.Contains(broker, "://") {
+		broker = "tcp://" + broker
+	}
+	u, err := url.Parse(broker)
+	if err != nil {
+		return "", false, fmt.Errorf("mqtt: invalid broker URL: %w", err)
+	}
+	port := "1883"
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "tls", "ssl", "mqtts":
+		useTLS = true
+		port = "8883"
+	default:
+		return "", false, fmt.Errorf("mqtt: unsupported broker scheme %q", u.Scheme)
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	return net.JoinHostPort(u.Hostname(), port), useTLS, nil
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+func readString(b []byte) (string, []byte, bool) {
+	if len(b) < 2 {
+		return "", nil, false
+	}
+	n := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+n {
+		return "", nil, false
+	}
+	return string(b[2 : 2+n]), b[2+n:], true
+}
+
+func writePacket(w io.Writer, header byte, body []byte) error {
+	buf := make([]byte, 0, 5+len(body))
+	buf = append(buf, header)
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		buf = append(buf, b)
+		if n == 0 {
+			break
+		}
+	}
+	buf = append(buf, body...)
+	_, err := w.Write(buf)
+	return err
+}
+
+func readPacket(r *bufio.Reader) (packet, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return packet{}, err
+	}
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return packet{}, errors.New("malformed remaining length")
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return packet{}, err
+		}
+		length += int(b&0x7f) * multiplier
+		if b&0x80 == 0 {
+			break
+		}
+		multiplier *= 128
+	}
+	if length > maxPacketSize {
+		return packet{}, fmt.Errorf("packet of %d bytes exceeds limit", length)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return packet{}, err
+	}
+	return packet{kind: header >> 4, flags: header & 0x0f, body: body}, nil
+}
@@ -0,0 +1,273 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testBroker is a minimal in-process broker: it accepts CONNECT with an
+// optional password check, tracks subscriptions and forwards PUBLISH
+// packets to matching subscribers at QoS 0.
+type testBroker struct {
+	ln       net.Listener
+	password string
+
+	mu   sync.Mutex
+	subs map[net.Conn][]string
+}
+
+func newTestBroker(t *testing.T, password string) *testBroker {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &testBroker{ln: ln, password: password, subs: make(map[net.Conn][]string)}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+	return b
+}
+
+func (b *testBroker) url() string {
+	return "tcp://" + b.ln.Addr().String()
+}
+
+func (b *testBroker) serve(conn net.Conn) {
+	defer func() {
+		b.mu.Lock()
+		delete(b.subs, conn)
+		b.mu.Unlock()
+		conn.Close()
+	}()
+	r := bufio.NewReader(conn)
+	for {
+		p, err := readPacket(r)
+		if err != nil {
+			return
+		}
+		switch p.kind {
+		case packetConnect:
+			code := byte(0)
+			if b.password != "" && !strings.HasSuffix(string(p.body), b.password) {
+				code = 4
+			}
+			writePacket(conn, packetConnack<<4, []byte{0, code})
+			if code != 0 {
+				return
+			}
+		case packetSubscribe:
+			filter, _, _ := readString(p.body[2:])
+			b.mu.Lock()
+			if !slices.Contains(b.subs[conn], filter) {
+				b.subs[conn] = append(b.subs[conn], filter)
+			}
+			b.mu.Unlock()
+			writePacket(conn, packetSuback<<4, append(p.body[:2:2], 1))
+		case packetUnsubscribe:
+			filter, _, _ := readString(p.body[2:])
+			b.mu.Lock()
+			b.subs[conn] = slices.DeleteFunc(b.subs[conn], func(f string) bool { return f == filter })
+			b.mu.Unlock()
+			writePacket(conn, packetUnsuback<<4, p.body[:2])
+		case packetPublish:
+			topic, rest, _ := readString(p.body)
+			if (p.flags>>1)&0x03 > 0 {
+				writePacket(conn, packetPuback<<4, rest[:2])
+				rest = rest[2:]
+			}
+			b.forward(topic, rest)
+		case packetPingreq:
+			writePacket(conn, packetPingresp<<4, nil)
+		case packetDisconnect:
+			return
+		}
+	}
+}
+
+// filters returns the filters subscribed to on all connections.
+func (b *testBroker) filters() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var all []string
+	for _, filters := range b.subs {
+		all = append(all, filters...)
+	}
+	return all
+}
+
+func (b *testBroker) forward(topic string, payload []byte) {
+	body := appendString(nil, topic)
+	body = append(body, payload...)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for conn, filters := range b.subs {
+		for _, f := range filters {
+			if Match(f, topic) {
+				writePacket(conn, packetPublish<<4, body)
+				break
+			}
+		}
+	}
+}
+
+func TestClient_PublishSubscribe(t *testing.T) {
+	broker := newTestBroker(t, "")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sub, err := Dial(ctx, Options{Broker: broker.url(), ClientID: "sub"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	pub, err := Dial(ctx, Options{Broker: broker.url(), ClientID: "pub"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pub.Close()
+
+	received := make(chan Message, 1)
+	if _, err := sub.Subscribe(ctx, "sensors/+/temp", 1, func(m Message) { received <- m }); err != nil {
+		t.Fatal(err)
+	}
+	if err := pub.Publish(ctx, "sensors/kitchen/temp", []byte("21.5"), 1, false); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case m := <-received:
+		if m.Topic != "sensors/kitchen/temp" || string(m.Payload) != "21.5" {
+			t.Errorf("received %s = %q", m.Topic, m.Payload)
+		}
+	case <-ctx.Done():
+		t.Fatal("message not delivered")
+	}
+
+	if err := pub.Publish(ctx, "sensors/#", nil, 0, false); err == nil {
+		t.Error("expected publishing to a wildcard topic to fail")
+	}
+}
+
+func TestClient_SubscriptionsShareFilter(t *testing.T) {
+	broker := newTestBroker(t, "")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c, err := Dial(ctx, Options{Broker: broker.url()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	first, second := make(chan Message, 1), make(chan Message, 1)
+	a, err := c.Subscribe(ctx, "lights/#", 1, func(m Message) { first <- m })
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := c.Subscribe(ctx, "lights/#", 1, func(m Message) { second <- m })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Removing one subscription leaves the other, and the broker's.
+	if err := c.Unsubscribe(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	if got := broker.filters(); len(got) != 1 {
+		t.Fatalf("broker filters = %v, want lights/# kept", got)
+	}
+	if err := c.Publish(ctx, "lights/hall", []byte("on"), 1, false); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-second:
+	case <-ctx.Done():
+		t.Fatal("message not delivered to the remaining subscription")
+	}
+	select {
+	case m := <-first:
+		t.Errorf("removed subscription got %s", m.Topic)
+	default:
+	}
+
+	if err := c.Unsubscribe(ctx, b); err != nil {
+		t.Fatal(err)
+	}
+	if got := broker.filters(); len(got) != 0 {
+		t.Errorf("broker filters = %v, want none after the last subscription", got)
+	}
+}
+
+func TestClient_ConnectRefused(t *testing.T) {
+	broker := newTestBroker(t, "secret")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := Dial(ctx, Options{Broker: broker.url(), Username: "u", Password: "wrong"})
+	if err == nil || !strings.Contains(err.Error(), "bad user name or password") {
+		t.Fatalf("err = %v", err)
+	}
+	c, err := Dial(ctx, Options{Broker: broker.url(), Username: "u", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	select {
+	case <-c.Done():
+	default:
+		t.Error("Done not closed after Close")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		filter, topic string
+		want          bool
+	}{
+		{"a/b", "a/b", true},
+		{"a/b", "a/c", false},
+		{"a/+", "a/b", true},
+		{"a/+", "a/b/c", false},
+		{"a/#", "a", true},
+		{"a/#", "a/b/c", true},
+		{"#", "anything/at/all", true},
+		{"+/b", "a/b", true},
+		{"a/b/c", "a/b", false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.filter, tt.topic); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.filter, tt.topic, got, tt.want)
+		}
+	}
+}
+
+func TestPacketRoundTrip(t *testing.T) {
+	body := make([]byte, 300) // needs a two-byte remaining length
+	binary.BigEndian.PutUint16(body, 7)
+	var sb strings.Builder
+	if err := writePacket(&sb, packetPublish<<4|0x03, body); err != nil {
+		t.Fatal(err)
+	}
+	p, err := readPacket(bufio.NewReader(strings.NewReader(sb.String())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.kind != packetPublish || p.flags != 0x03 || len(p.body) != 300 || binary.BigEndian.Uint16(p.body) != 7 {
+		t.Errorf("round trip = kind %d flags %d len %d", p.kind, p.flags, len(p.body))
+	}
+}
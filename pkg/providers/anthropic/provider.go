@@ -1,14 +1,23 @@
 package anthropicprovider
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"strings"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/anthropics/anthropic-sdk-go/packages/param"
+	"github.com/sipeed/picoclaw/pkg/egress"
+	"github.com/sipeed/picoclaw/pkg/providers/httpcapture"
+	"github.com/sipeed/picoclaw/pkg/providers/httpretry"
+	"github.com/sipeed/picoclaw/pkg/providers/httptimeout"
+	"github.com/sipeed/picoclaw/pkg/providers/httpwarm"
 	"github.com/sipeed/picoclaw/pkg/providers/protocoltypes"
 )
 
@@ -19,6 +28,9 @@ type UsageInfo = protocoltypes.UsageInfo
 type Message = protocoltypes.Message
 type ToolDefinition = protocoltypes.ToolDefinition
 type ToolFunctionDefinition = protocoltypes.ToolFunctionDefinition
+type FileRef = protocoltypes.FileRef
+type Image = protocoltypes.Image
+type StreamEvent = protocoltypes.StreamEvent
 
 const defaultBaseURL = "https://api.anthropic.com"
 
@@ -26,6 +38,7 @@ type Provider struct {
 	client      *anthropic.Client
 	tokenSource func() (string, error)
 	baseURL     string
+	httpClient  *http.Client
 }
 
 func NewProvider(token string) *Provider {
@@ -34,13 +47,20 @@ func NewProvider(token string) *Provider {
 
 func NewProviderWithBaseURL(token, apiBase string) *Provider {
 	baseURL := normalizeBaseURL(apiBase)
-	client := anthropic.NewClient(
+	httpClient := &http.Client{Transport: httpretry.Transport(httpcapture.Wrap(httptimeout.Transport(egress.Transport(egress.Providers, httpwarm.Transport(nil)))))}
+	opts := []option.RequestOption{
 		option.WithAuthToken(token),
 		option.WithBaseURL(baseURL),
-	)
+		option.WithHTTPClient(httpClient),
+	}
+	if httpretry.Enabled() {
+		opts = append(opts, option.WithMaxRetries(0))
+	}
+	client := anthropic.NewClient(opts...)
 	return &Provider{
-		client:  &client,
-		baseURL: baseURL,
+		client:     &client,
+		baseURL:    baseURL,
+		httpClient: httpClient,
 	}
 }
 
@@ -61,7 +81,87 @@ func NewProviderWithTokenSourceAndBaseURL(token string, tokenSource func() (stri
 	return p
 }
 
+// Warm opens a connection to the API host ahead of the first request.
+func (p *Provider) Warm(ctx context.Context) error {
+	if p.httpClient == nil {
+		return fmt.Errorf("provider has no HTTP client to warm")
+	}
+	return httpwarm.Warm(ctx, p.httpClient, p.baseURL)
+}
+
 func (p *Provider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	opts, err := p.requestOptions()
+	if err != nil {
+		return nil, err
+	}
+	if hasFiles(messages) {
+		opts = append(opts, option.WithHeaderAdd("anthropic-beta", string(anthropic.AnthropicBetaFilesAPI2025_04_14)))
+	}
+
+	params, err := buildParams(messages, tools, model, options)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Messages.New(ctx, params, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("claude API call: %w", err)
+	}
+
+	return parseResponse(resp), nil
+}
+
+// ChatStream is Chat with a streamed response. Text is passed to onEvent as
+// it arrives, and each tool call when its content block ends.
+func (p *Provider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onEvent func(StreamEvent)) (*LLMResponse, error) {
+	opts, err := p.requestOptions()
+	if err != nil {
+		return nil, err
+	}
+	if hasFiles(messages) {
+		opts = append(opts, option.WithHeaderAdd("anthropic-beta", string(anthropic.AnthropicBetaFilesAPI2025_04_14)))
+	}
+
+	params, err := buildParams(messages, tools, model, options)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := p.client.Messages.NewStreaming(ctx, params, opts...)
+	defer stream.Close()
+
+	var message anthropic.Message
+	for stream.Next() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		event := stream.Current()
+		if err := message.Accumulate(event); err != nil {
+			return nil, fmt.Errorf("claude API stream: %w", err)
+		}
+		if onEvent == nil {
+			continue
+		}
+		switch event.Type {
+		case "content_block_delta":
+			if event.Delta.Type == "text_delta" && event.Delta.Text != "" {
+				onEvent(StreamEvent{Text: event.Delta.Text})
+			}
+		case "content_block_stop":
+			if block := message.Content[len(message.Content)-1]; block.Type == "tool_use" {
+				tc := toolCallFromBlock(block.AsToolUse())
+				onEvent(StreamEvent{ToolCall: &tc})
+			}
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, fmt.Errorf("claude API call: %w", err)
+	}
+
+	return parseResponse(&message), nil
+}
+
+func (p *Provider) requestOptions() ([]option.RequestOption, error) {
 	var opts []option.RequestOption
 	if p.tokenSource != nil {
 		tok, err := p.tokenSource()
@@ -70,18 +170,91 @@ func (p *Provider) Chat(ctx context.Context, messages []Message, tools []ToolDef
 		}
 		opts = append(opts, option.WithAuthToken(tok))
 	}
+	return opts, nil
+}
 
-	params, err := buildParams(messages, tools, model, options)
+// ListModels lists the models available to the account.
+func (p *Provider) ListModels(ctx context.Context) ([]string, error) {
+	opts, err := p.requestOptions()
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := p.client.Messages.New(ctx, params, opts...)
+	var models []string
+	iter := p.client.Models.ListAutoPaging(ctx, anthropic.ModelListParams{}, opts...)
+	for iter.Next() {
+		models = append(models, iter.Current().ID)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("claude API list models: %w", err)
+	}
+	return models, nil
+}
+
+// UploadFile stores a document with the Files API and returns its ID.
+func (p *Provider) UploadFile(ctx context.Context, name, mimeType string, data []byte) (string, error) {
+	if mimeType != "application/pdf" && mimeType != "text/plain" {
+		return "", fmt.Errorf("unsupported file type %s", mimeType)
+	}
+	opts, err := p.requestOptions()
 	if err != nil {
-		return nil, fmt.Errorf("claude API call: %w", err)
+		return "", err
 	}
+	file, err := p.client.Beta.Files.Upload(ctx, anthropic.BetaFileUploadParams{
+		File:  anthropic.File(bytes.NewReader(data), name, mimeType),
+		Betas: []anthropic.AnthropicBeta{anthropic.AnthropicBetaFilesAPI2025_04_14},
+	}, opts...)
+	if err != nil {
+		return "", fmt.Errorf("claude file upload: %w", err)
+	}
+	return file.ID, nil
+}
 
-	return parseResponse(resp), nil
+// DeleteFile removes an uploaded document.
+func (p *Provider) DeleteFile(ctx context.Context, id string) error {
+	opts, err := p.requestOptions()
+	if err != nil {
+		return err
+	}
+	_, err = p.client.Beta.Files.Delete(ctx, id, anthropic.BetaFileDeleteParams{
+		Betas: []anthropic.AnthropicBeta{anthropic.AnthropicBetaFilesAPI2025_04_14},
+	}, opts...)
+	var apiErr *anthropic.Error
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("claude file delete: %w: %w", protocoltypes.ErrFileNotFound, err)
+	}
+	if err != nil {
+		return fmt.Errorf("claude file delete: %w", err)
+	}
+	return nil
+}
+
+func hasFiles(messages []Message) bool {
+	for _, m := range messages {
+		if len(m.Files) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// fileDocumentBlock references an uploaded file. The stable Messages types
+// have no file source yet, so the block is given as raw JSON.
+func fileDocumentBlock(f FileRef) anthropic.ContentBlockParamUnion {
+	raw, _ := json.Marshal(map[string]interface{}{
+		"type":   "document",
+		"source": map[string]string{"type": "file", "file_id": f.ID},
+	})
+	doc := param.Override[anthropic.DocumentBlockParam](json.RawMessage(raw))
+	return anthropic.ContentBlockParamUnion{OfDocument: &doc}
+}
+
+// imageBlock sends an image by URL or inline.
+func imageBlock(img Image) anthropic.ContentBlockParamUnion {
+	if img.URL != "" {
+		return anthropic.NewImageBlock(anthropic.URLImageSourceParam{URL: img.URL})
+	}
+	return anthropic.NewImageBlockBase64(img.MimeType, img.Data)
 }
 
 func (p *Provider) GetDefaultModel() string {
@@ -105,6 +278,18 @@ func buildParams(messages []Message, tools []ToolDefinition, model string, optio
 				anthropicMessages = append(anthropicMessages,
 					anthropic.NewUserMessage(anthropic.NewToolResultBlock(msg.ToolCallID, msg.Content, false)),
 				)
+			} else if len(msg.Files) > 0 || len(msg.Images) > 0 {
+				var blocks []anthropic.ContentBlockParamUnion
+				for _, f := range msg.Files {
+					blocks = append(blocks, fileDocumentBlock(f))
+				}
+				for _, img := range msg.Images {
+					blocks = append(blocks, imageBlock(img))
+				}
+				if msg.Content != "" {
+					blocks = append(blocks, anthropic.NewTextBlock(msg.Content))
+				}
+				anthropicMessages = append(anthropicMessages, anthropic.NewUserMessage(blocks...))
 			} else {
 				anthropicMessages = append(anthropicMessages,
 					anthropic.NewUserMessage(anthropic.NewTextBlock(msg.Content)),
@@ -153,6 +338,11 @@ func buildParams(messages []Message, tools []ToolDefinition, model string, optio
 
 	if len(tools) > 0 {
 		params.Tools = translateTools(tools)
+		// tool_choice names a tool the model must call, for structured
+		// output.
+		if name, ok := options["tool_choice"].(string); ok && name != "" {
+			params.ToolChoice = anthropic.ToolChoiceParamOfTool(name)
+		}
 	}
 
 	return params, nil
@@ -194,17 +384,7 @@ func parseResponse(resp *anthropic.Message) *LLMResponse {
 			tb := block.AsText()
 			content += tb.Text
 		case "tool_use":
-			tu := block.AsToolUse()
-			var args map[string]interface{}
-			if err := json.Unmarshal(tu.Input, &args); err != nil {
-				log.Printf("anthropic: failed to decode tool call input for %q: %v", tu.Name, err)
-				args = map[string]interface{}{"raw": string(tu.Input)}
-			}
-			toolCalls = append(toolCalls, ToolCall{
-				ID:        tu.ID,
-				Name:      tu.Name,
-				Arguments: args,
-			})
+			toolCalls = append(toolCalls, toolCallFromBlock(block.AsToolUse()))
 		}
 	}
 
@@ -222,11 +402,33 @@ func parseResponse(resp *anthropic.Message) *LLMResponse {
 		Content:      content,
 		ToolCalls:    toolCalls,
 		FinishReason: finishReason,
-		Usage: &UsageInfo{
-			PromptTokens:     int(resp.Usage.InputTokens),
-			CompletionTokens: int(resp.Usage.OutputTokens),
-			TotalTokens:      int(resp.Usage.InputTokens + resp.Usage.OutputTokens),
-		},
+		Usage:        usageFromMessage(resp.Usage),
+		Model:        string(resp.Model),
+	}
+}
+
+// usageFromMessage converts Anthropic's usage, whose input token count
+// leaves out tokens written to or read from the prompt cache.
+func usageFromMessage(u anthropic.Usage) *UsageInfo {
+	prompt := int(u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens)
+	return &UsageInfo{
+		PromptTokens:     prompt,
+		CompletionTokens: int(u.OutputTokens),
+		TotalTokens:      prompt + int(u.OutputTokens),
+		CachedTokens:     int(u.CacheReadInputTokens),
+	}
+}
+
+func toolCallFromBlock(tu anthropic.ToolUseBlock) ToolCall {
+	var args map[string]interface{}
+	if err := json.Unmarshal(tu.Input, &args); err != nil {
+		log.Printf("anthropic: failed to decode tool call input for %q: %v", tu.Name, err)
+		args = map[string]interface{}{"raw": string(tu.Input)}
+	}
+	return ToolCall{
+		ID:        tu.ID,
+		Name:      tu.Name,
+		Arguments: args,
 	}
 }
 
@@ -2,6 +2,8 @@ package anthropicprovider
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"sync/atomic"
@@ -9,6 +11,8 @@ import (
 
 	"github.com/anthropics/anthropic-sdk-go"
 	anthropicoption "github.com/anthropics/anthropic-sdk-go/option"
+
+	"github.com/sipeed/picoclaw/pkg/providers/protocoltypes"
 )
 
 func TestBuildParams_BasicMessage(t *testing.T) {
@@ -32,6 +36,28 @@ func TestBuildParams_BasicMessage(t *testing.T) {
 	}
 }
 
+func TestBuildParams_ForcesToolChoice(t *testing.T) {
+	tools := []ToolDefinition{{
+		Type: "function",
+		Function: ToolFunctionDefinition{
+			Name:       "result",
+			Parameters: map[string]interface{}{"type": "object"},
+		},
+	}}
+	params, err := buildParams([]Message{{Role: "user", Content: "Hi"}}, tools, "claude-sonnet-4.6", map[string]interface{}{"tool_choice": "result"})
+	if err != nil {
+		t.Fatalf("buildParams() error: %v", err)
+	}
+	if params.ToolChoice.OfTool == nil || params.ToolChoice.OfTool.Name != "result" {
+		t.Errorf("ToolChoice = %+v, want tool result", params.ToolChoice)
+	}
+
+	params, _ = buildParams([]Message{{Role: "user", Content: "Hi"}}, tools, "claude-sonnet-4.6", nil)
+	if params.ToolChoice.OfTool != nil {
+		t.Errorf("ToolChoice = %+v, want none", params.ToolChoice)
+	}
+}
+
 func TestBuildParams_SystemMessage(t *testing.T) {
 	messages := []Message{
 		{Role: "system", Content: "You are helpful"},
@@ -123,6 +149,22 @@ func TestParseResponse_TextOnly(t *testing.T) {
 	}
 }
 
+func TestParseResponse_CachedUsage(t *testing.T) {
+	resp := &anthropic.Message{
+		Content: []anthropic.ContentBlockUnion{},
+		Usage: anthropic.Usage{
+			InputTokens:              10,
+			CacheCreationInputTokens: 100,
+			CacheReadInputTokens:     1000,
+			OutputTokens:             20,
+		},
+	}
+	u := parseResponse(resp).Usage
+	if u.PromptTokens != 1110 || u.CachedTokens != 1000 || u.TotalTokens != 1130 {
+		t.Errorf("Usage = %+v, want 1110 prompt tokens of which 1000 cached, 1130 total", u)
+	}
+}
+
 func TestParseResponse_StopReasons(t *testing.T) {
 	tests := []struct {
 		stopReason anthropic.StopReason
@@ -263,3 +305,157 @@ func createAnthropicTestClient(baseURL, token string) *anthropic.Client {
 	)
 	return &c
 }
+
+func TestProvider_ChatSendsFileDocuments(t *testing.T) {
+	var reqBody struct {
+		Messages []struct {
+			Content []map[string]interface{} `json:"content"`
+		} `json:"messages"`
+	}
+	var beta string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		beta = r.Header.Get("anthropic-beta")
+		json.NewDecoder(r.Body).Decode(&reqBody)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id": "msg_test", "type": "message", "role": "assistant", "model": "claude-sonnet-4.6",
+			"stop_reason": "end_turn",
+			"content":     []map[string]interface{}{{"type": "text", "text": "ok"}},
+			"usage":       map[string]interface{}{"input_tokens": 1, "output_tokens": 1},
+		})
+	}))
+	defer server.Close()
+
+	provider := NewProviderWithClient(createAnthropicTestClient(server.URL, "test-token"))
+	messages := []Message{{Role: "user", Content: "Summarize", Files: []FileRef{{ID: "file_011", Name: "a.pdf"}}}}
+	if _, err := provider.Chat(t.Context(), messages, nil, "claude-sonnet-4.6", nil); err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+
+	if beta != "files-api-2025-04-14" {
+		t.Errorf("anthropic-beta = %q", beta)
+	}
+	if len(reqBody.Messages) != 1 || len(reqBody.Messages[0].Content) != 2 {
+		t.Fatalf("unexpected request: %+v", reqBody)
+	}
+	doc := reqBody.Messages[0].Content[0]
+	source, _ := doc["source"].(map[string]interface{})
+	if doc["type"] != "document" || source["type"] != "file" || source["file_id"] != "file_011" {
+		t.Errorf("unexpected document block: %v", doc)
+	}
+	if reqBody.Messages[0].Content[1]["text"] != "Summarize" {
+		t.Errorf("unexpected text block: %v", reqBody.Messages[0].Content[1])
+	}
+}
+
+func TestProvider_DeleteFileNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/v1/files/file_gone" {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"type":  "error",
+				"error": map[string]interface{}{"type": "not_found_error", "message": "File not found"},
+			})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": "file_011", "type": "file_deleted"})
+	}))
+	defer server.Close()
+
+	provider := NewProviderWithClient(createAnthropicTestClient(server.URL, "test-token"))
+	if err := provider.DeleteFile(t.Context(), "file_011"); err != nil {
+		t.Fatalf("DeleteFile() error: %v", err)
+	}
+	if err := provider.DeleteFile(t.Context(), "file_gone"); !errors.Is(err, protocoltypes.ErrFileNotFound) {
+		t.Errorf("DeleteFile() error = %v, want ErrFileNotFound", err)
+	}
+}
+
+func TestProvider_ChatStream(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4.6","content":[],"usage":{"input_tokens":20,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Checking."}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"read_file","input":{}}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"path\":"}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"a.txt\"}"}}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":12}}`,
+		`{"type":"message_stop"}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&reqBody)
+		if reqBody["stream"] != true {
+			http.Error(w, "expected stream", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, e := range events {
+			var typed struct{ Type string }
+			json.Unmarshal([]byte(e), &typed)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typed.Type, e)
+		}
+	}))
+	defer server.Close()
+
+	provider := NewProviderWithClient(createAnthropicTestClient(server.URL, "test-token"))
+	var streamed []ToolCall
+	var text string
+	resp, err := provider.ChatStream(t.Context(), []Message{{Role: "user", Content: "Read a.txt"}}, nil, "claude-sonnet-4.6", nil,
+		func(ev StreamEvent) {
+			text += ev.Text
+			if ev.ToolCall != nil {
+				streamed = append(streamed, *ev.ToolCall)
+			}
+		})
+	if err != nil {
+		t.Fatalf("ChatStream() error: %v", err)
+	}
+
+	if text != "Checking." {
+		t.Errorf("streamed text = %q, want %q", text, "Checking.")
+	}
+	if len(streamed) != 1 || streamed[0].ID != "toolu_1" || streamed[0].Arguments["path"] != "a.txt" {
+		t.Fatalf("streamed tool calls = %+v", streamed)
+	}
+	if resp.Content != "Checking." || resp.FinishReason != "tool_calls" {
+		t.Errorf("response = %+v", resp)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Arguments["path"] != "a.txt" {
+		t.Errorf("ToolCalls = %+v", resp.ToolCalls)
+	}
+	if resp.Usage.PromptTokens != 20 || resp.Usage.CompletionTokens != 12 {
+		t.Errorf("Usage = %+v", resp.Usage)
+	}
+}
+
+func TestBuildParams_ImageBlocks(t *testing.T) {
+	messages := []Message{{
+		Role:    "user",
+		Content: "Describe these",
+		Images: []Image{
+			{Data: "aGVsbG8=", MimeType: "image/png"},
+			{URL: "https://example.com/cat.jpg"},
+		},
+	}}
+	params, err := buildParams(messages, nil, "claude-sonnet-4.6", nil)
+	if err != nil {
+		t.Fatalf("buildParams() error: %v", err)
+	}
+	blocks := params.Messages[0].Content
+	if len(blocks) != 3 {
+		t.Fatalf("got %d blocks, want 3", len(blocks))
+	}
+	if img := blocks[0].OfImage; img == nil || img.Source.OfBase64 == nil || img.Source.OfBase64.Data != "aGVsbG8=" {
+		t.Errorf("first block is not the inline image: %+v", blocks[0])
+	}
+	if img := blocks[1].OfImage; img == nil || img.Source.OfURL == nil || img.Source.OfURL.URL != "https://example.com/cat.jpg" {
+		t.Errorf("second block is not the URL image: %+v", blocks[1])
+	}
+	if blocks[2].OfText == nil || blocks[2].OfText.Text != "Describe these" {
+		t.Errorf("last block is not the text: %+v", blocks[2])
+	}
+}
@@ -1,7 +1,6 @@
 package providers
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -13,7 +12,14 @@ import (
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/auth"
+	"github.com/sipeed/picoclaw/pkg/egress"
 	"github.com/sipeed/picoclaw/pkg/logger"
+	geminiprovider "github.com/sipeed/picoclaw/pkg/providers/gemini"
+	"github.com/sipeed/picoclaw/pkg/providers/httpcapture"
+	"github.com/sipeed/picoclaw/pkg/providers/httpretry"
+	"github.com/sipeed/picoclaw/pkg/providers/httptimeout"
+	"github.com/sipeed/picoclaw/pkg/providers/httpwarm"
+	"github.com/sipeed/picoclaw/pkg/providers/sse"
 )
 
 const (
@@ -34,18 +40,35 @@ type AntigravityProvider struct {
 
 // NewAntigravityProvider creates a new Antigravity provider using stored auth credentials.
 func NewAntigravityProvider() *AntigravityProvider {
+	return newAntigravityProvider("")
+}
+
+// newAntigravityProvider uses the credential of the labelled account, or
+// the default one if account is empty.
+func newAntigravityProvider(account string) *AntigravityProvider {
 	return &AntigravityProvider{
-		tokenSource: createAntigravityTokenSource(),
+		tokenSource: createAntigravityTokenSource(account),
 		httpClient: &http.Client{
-			Timeout: 120 * time.Second,
+			Transport: httpretry.Transport(httpcapture.Wrap(httptimeout.Transport(egress.Transport(egress.Providers, httpwarm.Transport(nil))))),
 		},
 	}
 }
 
+// Warm implements Warmer.
+func (p *AntigravityProvider) Warm(ctx context.Context) error {
+	return httpwarm.Warm(ctx, p.httpClient, antigravityBaseURL)
+}
+
 // Chat implements LLMProvider.Chat using the Cloud Code Assist v1internal API.
 // The v1internal endpoint wraps the standard Gemini request in an envelope with
 // project, model, request, requestType, userAgent, and requestId fields.
 func (p *AntigravityProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	return p.ChatStream(ctx, messages, tools, model, options, nil)
+}
+
+// ChatStream is Chat with text and tool calls passed to onEvent as they
+// arrive. The API always streams; Chat just does not listen.
+func (p *AntigravityProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onEvent func(StreamEvent)) (*LLMResponse, error) {
 	accessToken, projectID, err := p.tokenSource()
 	if err != nil {
 		return nil, fmt.Errorf("antigravity auth: %w", err)
@@ -109,12 +132,11 @@ func (p *AntigravityProvider) Chat(ctx context.Context, messages []Message, tool
 	}
 	defer resp.Body.Close()
 
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("reading response: %w", err)
-	}
-
 	if resp.StatusCode != http.StatusOK {
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("reading response: %w", err)
+		}
 		logger.ErrorCF("provider.antigravity", "API call failed", map[string]interface{}{
 			"status_code": resp.StatusCode,
 			"response":    string(respBody),
@@ -124,11 +146,11 @@ func (p *AntigravityProvider) Chat(ctx context.Context, messages []Message, tool
 		return nil, p.parseAntigravityError(resp.StatusCode, respBody)
 	}
 
-	// Response is always SSE from streamGenerateContent — each line is "data: {...}"
+	// Response is always SSE from streamGenerateContent — each event is "data: {...}"
 	// with a "response" wrapper containing the standard Gemini response
-	llmResp, err := p.parseSSEResponse(string(respBody))
+	llmResp, err := p.parseSSEStream(ctx, resp.Body, onEvent)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("reading response: %w", err)
 	}
 
 	// Check for empty response (some models might return valid success but empty text)
@@ -164,6 +186,20 @@ type antigravityPart struct {
 	ThoughtSignatureSnake string                       `json:"thought_signature,omitempty"`
 	FunctionCall          *antigravityFunctionCall     `json:"functionCall,omitempty"`
 	FunctionResponse      *antigravityFunctionResponse `json:"functionResponse,omitempty"`
+	InlineData            *antigravityBlob             `json:"inlineData,omitempty"`
+	FileData              *antigravityFileData         `json:"fileData,omitempty"`
+}
+
+// antigravityBlob is inline base64 data, such as an image.
+type antigravityBlob struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+// antigravityFileData references data by URI.
+type antigravityFileData struct {
+	MimeType string `json:"mimeType,omitempty"`
+	FileURI  string `json:"fileUri"`
 }
 
 type antigravityFunctionCall struct {
@@ -222,9 +258,17 @@ func (p *AntigravityProvider) buildRequest(messages []Message, tools []ToolDefin
 					}},
 				})
 			} else {
+				parts := []antigravityPart{{Text: msg.Content}}
+				for _, img := range msg.Images {
+					if img.URL != "" {
+						parts = append(parts, antigravityPart{FileData: &antigravityFileData{MimeType: img.MimeType, FileURI: img.URL}})
+					} else {
+						parts = append(parts, antigravityPart{InlineData: &antigravityBlob{MimeType: img.MimeType, Data: img.Data}})
+					}
+				}
 				req.Contents = append(req.Contents, antigravityContent{
 					Role:  "user",
-					Parts: []antigravityPart{{Text: msg.Content}},
+					Parts: parts,
 				})
 			}
 		case "assistant":
@@ -280,7 +324,7 @@ func (p *AntigravityProvider) buildRequest(messages []Message, tools []ToolDefin
 			if t.Type != "function" {
 				continue
 			}
-			params := sanitizeSchemaForGemini(t.Function.Parameters)
+			params := geminiprovider.SanitizeSchema(t.Function.Parameters)
 			funcDecls = append(funcDecls, antigravityFuncDecl{
 				Name:        t.Function.Name,
 				Description: t.Function.Description,
@@ -381,9 +425,10 @@ type antigravityJSONResponse struct {
 		FinishReason string `json:"finishReason"`
 	} `json:"candidates"`
 	UsageMetadata struct {
-		PromptTokenCount     int `json:"promptTokenCount"`
-		CandidatesTokenCount int `json:"candidatesTokenCount"`
-		TotalTokenCount      int `json:"totalTokenCount"`
+		PromptTokenCount        int `json:"promptTokenCount"`
+		CandidatesTokenCount    int `json:"candidatesTokenCount"`
+		TotalTokenCount         int `json:"totalTokenCount"`
+		CachedContentTokenCount int `json:"cachedContentTokenCount"`
 	} `json:"usageMetadata"`
 }
 
@@ -408,7 +453,8 @@ func (p *AntigravityProvider) parseJSONResponse(body []byte) (*LLMResponse, erro
 		if part.FunctionCall != nil {
 			argumentsJSON, _ := json.Marshal(part.FunctionCall.Args)
 			toolCalls = append(toolCalls, ToolCall{
-				ID:        fmt.Sprintf("call_%s_%d", part.FunctionCall.Name, time.Now().UnixNano()),
+				// Offset by the index so parallel calls get distinct IDs.
+				ID:        fmt.Sprintf("call_%s_%d", part.FunctionCall.Name, time.Now().UnixNano()+int64(len(toolCalls))),
 				Name:      part.FunctionCall.Name,
 				Arguments: part.FunctionCall.Args,
 				Function: &FunctionCall{
@@ -434,6 +480,7 @@ func (p *AntigravityProvider) parseJSONResponse(body []byte) (*LLMResponse, erro
 			PromptTokens:     resp.UsageMetadata.PromptTokenCount,
 			CompletionTokens: resp.UsageMetadata.CandidatesTokenCount,
 			TotalTokens:      resp.UsageMetadata.TotalTokenCount,
+			CachedTokens:     resp.UsageMetadata.CachedContentTokenCount,
 		}
 	}
 
@@ -445,20 +492,29 @@ func (p *AntigravityProvider) parseJSONResponse(body []byte) (*LLMResponse, erro
 	}, nil
 }
 
-func (p *AntigravityProvider) parseSSEResponse(body string) (*LLMResponse, error) {
+// parseSSEStream decodes SSE events as they arrive, so events split across
+// network reads and large chunks are handled without buffering the body.
+// onEvent, if set, receives each text part and tool call. It stops when
+// ctx is done.
+func (p *AntigravityProvider) parseSSEStream(ctx context.Context, r io.Reader, onEvent func(StreamEvent)) (*LLMResponse, error) {
 	var contentParts []string
 	var toolCalls []ToolCall
 	var usage *UsageInfo
 	var finishReason string
 
-	scanner := bufio.NewScanner(strings.NewReader(body))
-	for scanner.Scan() {
-		line := scanner.Text()
-		if !strings.HasPrefix(line, "data: ") {
-			continue
+	events := sse.NewReader(r)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		data, err := events.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
 		}
-		data := strings.TrimPrefix(line, "data: ")
-		if data == "[DONE]" {
+		if bytes.Equal(data, []byte("[DONE]")) {
 			break
 		}
 
@@ -466,7 +522,7 @@ func (p *AntigravityProvider) parseSSEResponse(body string) (*LLMResponse, error
 		var sseChunk struct {
 			Response antigravityJSONResponse `json:"response"`
 		}
-		if err := json.Unmarshal([]byte(data), &sseChunk); err != nil {
+		if err := json.Unmarshal(data, &sseChunk); err != nil {
 			continue
 		}
 		resp := sseChunk.Response
@@ -475,11 +531,14 @@ func (p *AntigravityProvider) parseSSEResponse(body string) (*LLMResponse, error
 			for _, part := range candidate.Content.Parts {
 				if part.Text != "" {
 					contentParts = append(contentParts, part.Text)
+					if onEvent != nil {
+						onEvent(StreamEvent{Text: part.Text})
+					}
 				}
 				if part.FunctionCall != nil {
 					argumentsJSON, _ := json.Marshal(part.FunctionCall.Args)
-					toolCalls = append(toolCalls, ToolCall{
-						ID:        fmt.Sprintf("call_%s_%d", part.FunctionCall.Name, time.Now().UnixNano()),
+					tc := ToolCall{
+						ID:        fmt.Sprintf("call_%s_%d", part.FunctionCall.Name, time.Now().UnixNano()+int64(len(toolCalls))),
 						Name:      part.FunctionCall.Name,
 						Arguments: part.FunctionCall.Args,
 						Function: &FunctionCall{
@@ -487,7 +546,11 @@ func (p *AntigravityProvider) parseSSEResponse(body string) (*LLMResponse, error
 							Arguments:        string(argumentsJSON),
 							ThoughtSignature: extractPartThoughtSignature(part.ThoughtSignature, part.ThoughtSignatureSnake),
 						},
-					})
+					}
+					toolCalls = append(toolCalls, tc)
+					if onEvent != nil {
+						onEvent(StreamEvent{ToolCall: &tc})
+					}
 				}
 			}
 			if candidate.FinishReason != "" {
@@ -500,6 +563,7 @@ func (p *AntigravityProvider) parseSSEResponse(body string) (*LLMResponse, error
 				PromptTokens:     resp.UsageMetadata.PromptTokenCount,
 				CompletionTokens: resp.UsageMetadata.CandidatesTokenCount,
 				TotalTokens:      resp.UsageMetadata.TotalTokenCount,
+				CachedTokens:     resp.UsageMetadata.CachedContentTokenCount,
 			}
 		}
 	}
@@ -530,102 +594,24 @@ func extractPartThoughtSignature(thoughtSignature string, thoughtSignatureSnake
 	return ""
 }
 
-// --- Schema sanitization ---
-
-// Google/Gemini doesn't support many JSON Schema keywords that other providers accept.
-var geminiUnsupportedKeywords = map[string]bool{
-	"patternProperties":    true,
-	"additionalProperties": true,
-	"$schema":              true,
-	"$id":                  true,
-	"$ref":                 true,
-	"$defs":                true,
-	"definitions":          true,
-	"examples":             true,
-	"minLength":            true,
-	"maxLength":            true,
-	"minimum":              true,
-	"maximum":              true,
-	"multipleOf":           true,
-	"pattern":              true,
-	"format":               true,
-	"minItems":             true,
-	"maxItems":             true,
-	"uniqueItems":          true,
-	"minProperties":        true,
-	"maxProperties":        true,
-}
-
-func sanitizeSchemaForGemini(schema map[string]interface{}) map[string]interface{} {
-	if schema == nil {
-		return nil
-	}
-
-	result := make(map[string]interface{})
-	for k, v := range schema {
-		if geminiUnsupportedKeywords[k] {
-			continue
-		}
-		// Recursively sanitize nested objects
-		switch val := v.(type) {
-		case map[string]interface{}:
-			result[k] = sanitizeSchemaForGemini(val)
-		case []interface{}:
-			sanitized := make([]interface{}, len(val))
-			for i, item := range val {
-				if m, ok := item.(map[string]interface{}); ok {
-					sanitized[i] = sanitizeSchemaForGemini(m)
-				} else {
-					sanitized[i] = item
-				}
-			}
-			result[k] = sanitized
-		default:
-			result[k] = v
-		}
-	}
-
-	// Ensure top-level has type: "object" if properties are present
-	if _, hasProps := result["properties"]; hasProps {
-		if _, hasType := result["type"]; !hasType {
-			result["type"] = "object"
-		}
-	}
-
-	return result
-}
-
 // --- Token source ---
 
-func createAntigravityTokenSource() func() (string, string, error) {
+func createAntigravityTokenSource(account string) func() (string, string, error) {
+	key := auth.AccountKey("google-antigravity", account)
+	source := auth.NewTokenSource(key, func(cred *auth.AuthCredential) (*auth.AuthCredential, error) {
+		return auth.RefreshAccessToken(cred, auth.GoogleAntigravityOAuthConfig())
+	})
 	return func() (string, string, error) {
-		cred, err := auth.GetCredential("google-antigravity")
+		cred, err := source.Credential()
 		if err != nil {
 			return "", "", fmt.Errorf("loading auth credentials: %w", err)
 		}
 		if cred == nil {
-			return "", "", fmt.Errorf("no credentials for google-antigravity. Run: picoclaw auth login --provider google-antigravity")
-		}
-
-		// Refresh if needed
-		if cred.NeedsRefresh() && cred.RefreshToken != "" {
-			oauthCfg := auth.GoogleAntigravityOAuthConfig()
-			refreshed, err := auth.RefreshAccessToken(cred, oauthCfg)
-			if err != nil {
-				return "", "", fmt.Errorf("refreshing token: %w", err)
-			}
-			refreshed.Email = cred.Email
-			if refreshed.ProjectID == "" {
-				refreshed.ProjectID = cred.ProjectID
-			}
-			if err := auth.SetCredential("google-antigravity", refreshed); err != nil {
-				return "", "", fmt.Errorf("saving refreshed token: %w", err)
-			}
-			cred = refreshed
+			return "", "", fmt.Errorf("no credentials for google-antigravity. Run: %s", loginHint("google-antigravity", account))
 		}
 
 		if cred.IsExpired() {
-			return "", "", fmt.Errorf("antigravity credentials expired. Run: picoclaw auth login --provider google-antigravity")
+			return "", "", fmt.Errorf("antigravity credentials expired. Run: %s", loginHint("google-antigravity", account))
 		}
 
 		projectID := cred.ProjectID
@@ -640,7 +626,7 @@ func createAntigravityTokenSource() func() (string, string, error) {
 			} else {
 				projectID = fetchedID
 				cred.ProjectID = projectID
-				_ = auth.SetCredential("google-antigravity", cred)
+				_ = auth.SetCredential(key, cred)
 			}
 		}
 
@@ -667,7 +653,7 @@ func FetchAntigravityProjectID(accessToken string) (string, error) {
 	req.Header.Set("User-Agent", antigravityUserAgent)
 	req.Header.Set("X-Goog-Api-Client", antigravityXGoogClient)
 
-	client := &http.Client{Timeout: 15 * time.Second}
+	client := &http.Client{Timeout: 15 * time.Second, Transport: egress.Transport(egress.Providers, nil)}
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", err
@@ -708,7 +694,7 @@ func FetchAntigravityModels(accessToken, projectID string) ([]AntigravityModelIn
 	req.Header.Set("User-Agent", antigravityUserAgent)
 	req.Header.Set("X-Goog-Api-Client", antigravityXGoogClient)
 
-	client := &http.Client{Timeout: 15 * time.Second}
+	client := &http.Client{Timeout: 15 * time.Second, Transport: egress.Transport(egress.Providers, nil)}
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
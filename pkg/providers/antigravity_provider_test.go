@@ -1,6 +1,10 @@
 package providers
 
-import "testing"
+import (
+	"strings"
+	"testing"
+	"testing/iotest"
+)
 
 func TestBuildRequestUsesFunctionFieldsWhenToolCallNameMissing(t *testing.T) {
 	p := &AntigravityProvider{}
@@ -54,3 +58,51 @@ func TestResolveToolResponseNameInfersNameFromGeneratedCallID(t *testing.T) {
 		t.Fatalf("expected inferred tool name search_docs, got %q", got)
 	}
 }
+
+func TestAntigravityParseSSEStream_LargeChunk(t *testing.T) {
+	text := strings.Repeat("a", 100*1024)
+	stream := `data: {"response":{"candidates":[{"content":{"parts":[{"text":"` + text + `"}]},"finishReason":"STOP"}]}}` + "\n\n" +
+		": ping\n\n" +
+		`data: {"response":{"candidates":[{"content":{"parts":[{"text":"!"}]}}],"usageMetadata":{"totalTokenCount":3}}}` + "\n\n"
+
+	p := &AntigravityProvider{}
+	var streamed strings.Builder
+	resp, err := p.parseSSEStream(t.Context(), iotest.HalfReader(strings.NewReader(stream)), func(ev StreamEvent) {
+		streamed.WriteString(ev.Text)
+	})
+	if err != nil {
+		t.Fatalf("parseSSEStream() error: %v", err)
+	}
+	if resp.Content != text+"!" {
+		t.Errorf("content length = %d, want %d", len(resp.Content), len(text)+1)
+	}
+	if streamed.String() != resp.Content {
+		t.Errorf("streamed text length = %d, want %d", streamed.Len(), len(resp.Content))
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 3 {
+		t.Errorf("usage = %+v", resp.Usage)
+	}
+}
+
+func TestBuildRequestSendsImages(t *testing.T) {
+	p := &AntigravityProvider{}
+	req := p.buildRequest([]Message{{
+		Role:    "user",
+		Content: "What is this?",
+		Images: []Image{
+			{Data: "aGVsbG8=", MimeType: "image/png"},
+			{URL: "gs://bucket/cat.jpg", MimeType: "image/jpeg"},
+		},
+	}}, nil, "", nil)
+
+	parts := req.Contents[0].Parts
+	if len(parts) != 3 {
+		t.Fatalf("expected text and 2 image parts, got %d", len(parts))
+	}
+	if parts[1].InlineData == nil || parts[1].InlineData.Data != "aGVsbG8=" || parts[1].InlineData.MimeType != "image/png" {
+		t.Errorf("inline image part = %+v", parts[1])
+	}
+	if parts[2].FileData == nil || parts[2].FileData.FileURI != "gs://bucket/cat.jpg" {
+		t.Errorf("URL image part = %+v", parts[2])
+	}
+}
@@ -0,0 +1,186 @@
+package providers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/atrest"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/vfs"
+)
+
+// cachePruneInterval is how often a ResponseCache removes expired entries.
+const cachePruneInterval = time.Hour
+
+// ResponseCache keeps model responses on disk for a while, keyed on the
+// model, messages, tools and options of the request, so that repeating a
+// request returns the same response without asking the model again. The
+// seed option is left out of the key, as agents without a fixed seed send
+// a new one every turn.
+//
+// A nil *ResponseCache caches nothing.
+type ResponseCache struct {
+	dir string
+	ttl time.Duration
+
+	mu        sync.Mutex
+	lastPrune time.Time
+	nowFunc   func() time.Time
+}
+
+type cacheEntry struct {
+	Created  time.Time    `json:"created"`
+	Model    string       `json:"model"`
+	Response *LLMResponse `json:"response"`
+}
+
+// NewResponseCache returns a cache that stores responses in dir for ttl.
+// The files are encrypted when encryption at rest is on.
+func NewResponseCache(dir string, ttl time.Duration) *ResponseCache {
+	vfs.MkdirAll(dir, 0755)
+	atrest.Protect(dir)
+	return &ResponseCache{dir: dir, ttl: ttl, nowFunc: time.Now}
+}
+
+// Get returns the cached response to the request, or nil.
+func (c *ResponseCache) Get(model string, messages []Message, tools []ToolDefinition, options map[string]interface{}) *LLMResponse {
+	if c == nil {
+		return nil
+	}
+	path := c.path(model, messages, tools, options)
+	data, err := vfs.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Response == nil {
+		return nil
+	}
+	if c.nowFunc().Sub(entry.Created) > c.ttl {
+		vfs.Remove(path)
+		return nil
+	}
+	logger.DebugCF("cache", "Response served from cache", map[string]interface{}{
+		"model": model,
+		"age":   c.nowFunc().Sub(entry.Created).Round(time.Second).String(),
+	})
+	return entry.Response
+}
+
+// Put caches resp as the response to the request.
+func (c *ResponseCache) Put(model string, messages []Message, tools []ToolDefinition, options map[string]interface{}, resp *LLMResponse) {
+	if c == nil || resp == nil {
+		return
+	}
+	data, err := json.Marshal(cacheEntry{Created: c.nowFunc(), Model: model, Response: resp})
+	if err != nil {
+		return
+	}
+	if err := vfs.WriteFile(c.path(model, messages, tools, options), data, 0600); err != nil {
+		logger.WarnCF("cache", "Failed to cache response", map[string]interface{}{"error": err.Error()})
+	}
+	c.prune()
+}
+
+// Clear removes all entries and returns how many there were.
+func (c *ResponseCache) Clear() int {
+	if c == nil {
+		return 0
+	}
+	entries, err := vfs.ReadDir(c.dir)
+	if err != nil {
+		return 0
+	}
+	removed := 0
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		if vfs.Remove(filepath.Join(c.dir, e.Name())) == nil {
+			removed++
+		}
+	}
+	return removed
+}
+
+// Provider returns p with its responses cached, or p itself if c is nil.
+func (c *ResponseCache) Provider(p LLMProvider) LLMProvider {
+	if c == nil {
+		return p
+	}
+	return &cachedProvider{LLMProvider: p, cache: c}
+}
+
+// path returns the file of the request's entry, named after a hash of it.
+func (c *ResponseCache) path(model string, messages []Message, tools []ToolDefinition, options map[string]interface{}) string {
+	keyOptions := make(map[string]interface{}, len(options))
+	for k, v := range options {
+		if k != "seed" {
+			keyOptions[k] = v
+		}
+	}
+	data, _ := json.Marshal(struct {
+		Model    string                 `json:"model"`
+		Messages []Message              `json:"messages"`
+		Tools    []ToolDefinition       `json:"tools"`
+		Options  map[string]interface{} `json:"options"`
+	}{model, messages, tools, keyOptions})
+	sum := sha256.Sum256(data)
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// prune removes expired entries, at most once per cachePruneInterval.
+func (c *ResponseCache) prune() {
+	c.mu.Lock()
+	now := c.nowFunc()
+	if now.Sub(c.lastPrune) < cachePruneInterval {
+		c.mu.Unlock()
+		return
+	}
+	c.lastPrune = now
+	c.mu.Unlock()
+
+	entries, err := vfs.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		if info, err := e.Info(); err == nil && now.Sub(info.ModTime()) > c.ttl {
+			vfs.Remove(filepath.Join(c.dir, e.Name()))
+		}
+	}
+}
+
+// cachedProvider answers repeated requests from a ResponseCache.
+type cachedProvider struct {
+	LLMProvider
+	cache *ResponseCache
+}
+
+func (p *cachedProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	if resp := p.cache.Get(model, messages, tools, options); resp != nil {
+		return resp, nil
+	}
+	resp, err := p.LLMProvider.Chat(ctx, messages, tools, model, options)
+	if err == nil {
+		p.cache.Put(model, messages, tools, options, resp)
+	}
+	return resp, err
+}
+
+// StructuredOutput keeps the wrapped provider's structured output mode.
+func (p *cachedProvider) StructuredOutput() StructuredMode {
+	if s, ok := p.LLMProvider.(StructuredOutputter); ok {
+		return s.StructuredOutput()
+	}
+	return StructuredPrompt
+}
@@ -0,0 +1,62 @@
+package providers
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestResponseCache_Provider(t *testing.T) {
+	cache := NewResponseCache(t.TempDir(), time.Hour)
+	inner := &structuredMock{responses: []*LLMResponse{
+		{Content: "first"},
+		{Content: "second"},
+	}}
+	p := cache.Provider(inner)
+	ctx := context.Background()
+	msgs := []Message{{Role: "user", Content: "hello"}}
+
+	for _, seed := range []int{1, 2} {
+		resp, err := p.Chat(ctx, msgs, nil, "m", map[string]interface{}{"temperature": 0.0, "seed": seed})
+		if err != nil || resp.Content != "first" {
+			t.Fatalf("Chat() = %+v, %v", resp, err)
+		}
+	}
+	if len(inner.requests) != 1 {
+		t.Fatalf("provider asked %d times, want 1", len(inner.requests))
+	}
+
+	// Any other setting, model or message is another request.
+	resp, _ := p.Chat(ctx, msgs, nil, "m", map[string]interface{}{"temperature": 0.5})
+	if resp.Content != "second" || len(inner.requests) != 2 {
+		t.Errorf("different options served %q after %d requests", resp.Content, len(inner.requests))
+	}
+}
+
+func TestResponseCache_Expires(t *testing.T) {
+	cache := NewResponseCache(t.TempDir(), time.Hour)
+	now := time.Now()
+	cache.nowFunc = func() time.Time { return now }
+	msgs := []Message{{Role: "user", Content: "hello"}}
+
+	cache.Put("m", msgs, nil, nil, &LLMResponse{Content: "cached"})
+	if resp := cache.Get("m", msgs, nil, nil); resp == nil || resp.Content != "cached" {
+		t.Fatalf("Get() = %+v", resp)
+	}
+	now = now.Add(2 * time.Hour)
+	if resp := cache.Get("m", msgs, nil, nil); resp != nil {
+		t.Errorf("expired entry served: %+v", resp)
+	}
+}
+
+func TestResponseCache_NilCachesNothing(t *testing.T) {
+	var cache *ResponseCache
+	cache.Put("m", nil, nil, nil, &LLMResponse{Content: "x"})
+	if cache.Get("m", nil, nil, nil) != nil {
+		t.Error("nil cache returned a response")
+	}
+	p := &structuredMock{}
+	if cache.Provider(p) != LLMProvider(p) {
+		t.Error("nil cache should not wrap the provider")
+	}
+}
@@ -0,0 +1,190 @@
+package providers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// Capabilities describes what a model supports. ContextWindow and
+// MaxOutputTokens are 0 when unknown; models that are not known are
+// assumed to support vision and tools.
+type Capabilities struct {
+	ContextWindow   int
+	MaxOutputTokens int
+	Vision          bool
+	Tools           bool
+	// Pricing is nil when unknown.
+	Pricing *config.ModelPricing
+	// Known reports whether anything was known about the model.
+	Known bool
+}
+
+// capabilityCatalog lists the capabilities of well-known models, keyed by
+// "provider/model". Keys match model IDs they are a prefix of, so dated
+// and point releases are covered; the longest key wins. Prices are in
+// dollars per 1K tokens.
+var capabilityCatalog = map[string]Capabilities{
+	"anthropic/claude-opus-4":    {ContextWindow: 200000, MaxOutputTokens: 32000, Vision: true, Tools: true, Pricing: &config.ModelPricing{Input: 0.015, CachedInput: 0.0015, Output: 0.075}},
+	"anthropic/claude-sonnet-4":  {ContextWindow: 200000, MaxOutputTokens: 64000, Vision: true, Tools: true, Pricing: &config.ModelPricing{Input: 0.003, CachedInput: 0.0003, Output: 0.015}},
+	"anthropic/claude-haiku-4":   {ContextWindow: 200000, MaxOutputTokens: 64000, Vision: true, Tools: true, Pricing: &config.ModelPricing{Input: 0.001, CachedInput: 0.0001, Output: 0.005}},
+	"anthropic/claude-3-5-haiku": {ContextWindow: 200000, MaxOutputTokens: 8192, Vision: true, Tools: true, Pricing: &config.ModelPricing{Input: 0.0008, CachedInput: 0.00008, Output: 0.004}},
+
+	"openai/gpt-5":        {ContextWindow: 400000, MaxOutputTokens: 128000, Vision: true, Tools: true, Pricing: &config.ModelPricing{Input: 0.00125, CachedInput: 0.000125, Output: 0.01}},
+	"openai/gpt-5-mini":   {ContextWindow: 400000, MaxOutputTokens: 128000, Vision: true, Tools: true, Pricing: &config.ModelPricing{Input: 0.00025, CachedInput: 0.000025, Output: 0.002}},
+	"openai/gpt-5-nano":   {ContextWindow: 400000, MaxOutputTokens: 128000, Vision: true, Tools: true, Pricing: &config.ModelPricing{Input: 0.00005, CachedInput: 0.000005, Output: 0.0004}},
+	"openai/gpt-4.1":      {ContextWindow: 1047576, MaxOutputTokens: 32768, Vision: true, Tools: true, Pricing: &config.ModelPricing{Input: 0.002, CachedInput: 0.0005, Output: 0.008}},
+	"openai/gpt-4.1-mini": {ContextWindow: 1047576, MaxOutputTokens: 32768, Vision: true, Tools: true, Pricing: &config.ModelPricing{Input: 0.0004, CachedInput: 0.0001, Output: 0.0016}},
+	"openai/gpt-4.1-nano": {ContextWindow: 1047576, MaxOutputTokens: 32768, Vision: true, Tools: true, Pricing: &config.ModelPricing{Input: 0.0001, CachedInput: 0.000025, Output: 0.0004}},
+	"openai/gpt-4o":       {ContextWindow: 128000, MaxOutputTokens: 16384, Vision: true, Tools: true, Pricing: &config.ModelPricing{Input: 0.0025, CachedInput: 0.00125, Output: 0.01}},
+	"openai/gpt-4o-mini":  {ContextWindow: 128000, MaxOutputTokens: 16384, Vision: true, Tools: true, Pricing: &config.ModelPricing{Input: 0.00015, CachedInput: 0.000075, Output: 0.0006}},
+	"openai/o3":           {ContextWindow: 200000, MaxOutputTokens: 100000, Vision: true, Tools: true, Pricing: &config.ModelPricing{Input: 0.002, CachedInput: 0.0005, Output: 0.008}},
+	"openai/o3-mini":      {ContextWindow: 200000, MaxOutputTokens: 100000, Vision: false, Tools: true, Pricing: &config.ModelPricing{Input: 0.0011, CachedInput: 0.00055, Output: 0.0044}},
+	"openai/o4-mini":      {ContextWindow: 200000, MaxOutputTokens: 100000, Vision: true, Tools: true, Pricing: &config.ModelPricing{Input: 0.0011, CachedInput: 0.000275, Output: 0.0044}},
+
+	"gemini/gemini-2.5-pro":        {ContextWindow: 1048576, MaxOutputTokens: 65536, Vision: true, Tools: true, Pricing: &config.ModelPricing{Input: 0.00125, Output: 0.01}},
+	"gemini/gemini-2.5-flash":      {ContextWindow: 1048576, MaxOutputTokens: 65536, Vision: true, Tools: true, Pricing: &config.ModelPricing{Input: 0.0003, Output: 0.0025}},
+	"gemini/gemini-2.5-flash-lite": {ContextWindow: 1048576, MaxOutputTokens: 65536, Vision: true, Tools: true, Pricing: &config.ModelPricing{Input: 0.0001, Output: 0.0004}},
+
+	"deepseek/deepseek-chat":     {ContextWindow: 128000, MaxOutputTokens: 8192, Vision: false, Tools: true, Pricing: &config.ModelPricing{Input: 0.00056, CachedInput: 0.00007, Output: 0.00168}},
+	"deepseek/deepseek-reasoner": {ContextWindow: 128000, MaxOutputTokens: 65536, Vision: false, Tools: true, Pricing: &config.ModelPricing{Input: 0.00056, CachedInput: 0.00007, Output: 0.00168}},
+
+	"mistral/mistral-large": {ContextWindow: 128000, Vision: false, Tools: true, Pricing: &config.ModelPricing{Input: 0.002, Output: 0.006}},
+	"cohere/command-a":      {ContextWindow: 256000, MaxOutputTokens: 8000, Vision: false, Tools: true, Pricing: &config.ModelPricing{Input: 0.0025, Output: 0.01}},
+	"xai/grok-4":            {ContextWindow: 256000, Vision: true, Tools: true, Pricing: &config.ModelPricing{Input: 0.003, Output: 0.015}},
+
+	"groq/llama-3.3-70b": {ContextWindow: 131072, MaxOutputTokens: 32768, Vision: false, Tools: true},
+}
+
+// LookupCapabilities returns the catalog's capabilities for model, given as
+// "provider/model" like model_list entries. Models behind aggregators
+// ("openrouter/anthropic/claude-sonnet-4") and CLI or OAuth providers
+// ("claude-cli/claude-sonnet-4") are matched by their own name.
+func LookupCapabilities(model string) (Capabilities, bool) {
+	protocol, modelID := ExtractProtocol(model)
+	if caps, ok := lookupCatalog(func(key string) bool { return matchesModel(protocol+"/"+modelID, key) }); ok {
+		return caps, true
+	}
+	if strings.Contains(modelID, "/") {
+		if caps, ok := lookupCatalog(func(key string) bool { return matchesModel(modelID, key) }); ok {
+			return caps, true
+		}
+	}
+	bare := modelID[strings.LastIndex(modelID, "/")+1:]
+	return lookupCatalog(func(key string) bool {
+		_, name, _ := strings.Cut(key, "/")
+		return matchesModel(bare, name)
+	})
+}
+
+func lookupCatalog(match func(key string) bool) (Capabilities, bool) {
+	best := ""
+	for key := range capabilityCatalog {
+		if match(key) && len(key) > len(best) {
+			best = key
+		}
+	}
+	if best == "" {
+		return Capabilities{}, false
+	}
+	caps := capabilityCatalog[best]
+	caps.Known = true
+	return caps, true
+}
+
+// matchesModel reports whether the catalog key covers model: equal to it,
+// or a prefix ending where a version or date suffix starts.
+func matchesModel(model, key string) bool {
+	model = strings.ToLower(model)
+	if !strings.HasPrefix(model, key) {
+		return false
+	}
+	rest := model[len(key):]
+	return rest == "" || rest[0] == '-' || rest[0] == '.' || rest[0] == '@' || rest[0] == ':'
+}
+
+// ValidateModel checks the model of a model_list entry against the
+// catalog. Entries that set capabilities, and models of providers the
+// catalog has none of, such as local servers and custom providers, pass:
+// there is nothing to check them against.
+func ValidateModel(mc *config.ModelConfig) error {
+	if mc.Capabilities != nil {
+		return nil
+	}
+	if _, ok := LookupCapabilities(mc.Model); ok {
+		return nil
+	}
+	protocol, modelID := ExtractProtocol(mc.Model)
+	for key := range capabilityCatalog {
+		if strings.HasPrefix(key, protocol+"/") {
+			return fmt.Errorf("model %q of %s is not a known %s model; check its name, or set its capabilities", modelID, mc.ModelName, protocol)
+		}
+	}
+	return nil
+}
+
+// ResolveCapabilities returns the capabilities of the model named
+// modelName in cfg's model_list: the catalog's, with the entry's
+// capabilities settings applied on top.
+func ResolveCapabilities(cfg *config.Config, modelName string) Capabilities {
+	var mc *config.ModelConfig
+	if cfg != nil {
+		for i := range cfg.ModelList {
+			if cfg.ModelList[i].ModelName == modelName {
+				mc = &cfg.ModelList[i]
+				break
+			}
+		}
+	}
+
+	model := modelName
+	if mc != nil {
+		model = mc.Model
+	}
+	caps, ok := LookupCapabilities(model)
+	if !ok {
+		caps = Capabilities{Vision: true, Tools: true}
+	}
+	if mc == nil || mc.Capabilities == nil {
+		return caps
+	}
+
+	o := mc.Capabilities
+	caps.Known = true
+	if o.ContextWindow > 0 {
+		caps.ContextWindow = o.ContextWindow
+	}
+	if o.MaxOutputTokens > 0 {
+		caps.MaxOutputTokens = o.MaxOutputTokens
+	}
+	if o.Vision != nil {
+		caps.Vision = *o.Vision
+	}
+	if o.Tools != nil {
+		caps.Tools = *o.Tools
+	}
+	if o.Pricing != nil {
+		caps.Pricing = o.Pricing
+	}
+	return caps
+}
+
+// ModelPrices returns the price of each model in cfg's model_list whose
+// price is known, by model name, for usage tracking. Prices set in
+// usage.pricing take precedence.
+func ModelPrices(cfg *config.Config) map[string]config.ModelPricing {
+	prices := make(map[string]config.ModelPricing)
+	for _, mc := range cfg.ModelList {
+		if _, ok := prices[mc.ModelName]; ok {
+			continue
+		}
+		if caps := ResolveCapabilities(cfg, mc.ModelName); caps.Pricing != nil {
+			prices[mc.ModelName] = *caps.Pricing
+		}
+	}
+	for model, price := range cfg.Usage.Pricing {
+		prices[model] = price
+	}
+	return prices
+}
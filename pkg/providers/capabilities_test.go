@@ -0,0 +1,96 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestLookupCapabilities(t *testing.T) {
+	tests := []struct {
+		model         string
+		contextWindow int
+		input         float64
+	}{
+		{"anthropic/claude-sonnet-4.6", 200000, 0.003},
+		{"anthropic/claude-sonnet-4-5-20250929", 200000, 0.003},
+		{"openai/gpt-4o", 128000, 0.0025},
+		{"openai/gpt-4o-mini", 128000, 0.00015},
+		{"gpt-5.2", 400000, 0.00125},
+		{"openrouter/anthropic/claude-opus-4.1", 200000, 0.015},
+		{"claude-cli/claude-haiku-4.5", 200000, 0.001},
+	}
+	for _, tt := range tests {
+		caps, ok := LookupCapabilities(tt.model)
+		if !ok || !caps.Known {
+			t.Errorf("LookupCapabilities(%q) not found", tt.model)
+			continue
+		}
+		if caps.ContextWindow != tt.contextWindow || caps.Pricing == nil || caps.Pricing.Input != tt.input {
+			t.Errorf("LookupCapabilities(%q) = window %d, pricing %+v; want %d, input %v", tt.model, caps.ContextWindow, caps.Pricing, tt.contextWindow, tt.input)
+		}
+	}
+
+	for _, model := range []string{"ollama/llama3.3", "openai/gpt-50", "openai/o3x"} {
+		if caps, ok := LookupCapabilities(model); ok {
+			t.Errorf("LookupCapabilities(%q) = %+v, want not found", model, caps)
+		}
+	}
+}
+
+func TestResolveCapabilities(t *testing.T) {
+	noVision := false
+	cfg := &config.Config{
+		ModelList: []config.ModelConfig{
+			{ModelName: "sonnet", Model: "anthropic/claude-sonnet-4.6"},
+			{ModelName: "local", Model: "ollama/qwen3:8b"},
+			{ModelName: "tuned", Model: "openai/gpt-4o", Capabilities: &config.ModelCapabilities{
+				ContextWindow: 64000,
+				Vision:        &noVision,
+			}},
+		},
+		Usage: config.UsageConfig{Pricing: map[string]config.ModelPricing{"sonnet": {Input: 1, Output: 2}}},
+	}
+
+	if caps := ResolveCapabilities(cfg, "sonnet"); caps.ContextWindow != 200000 || !caps.Tools {
+		t.Errorf("sonnet = %+v", caps)
+	}
+	if caps := ResolveCapabilities(cfg, "local"); caps.Known || !caps.Tools || !caps.Vision || caps.ContextWindow != 0 {
+		t.Errorf("unknown model = %+v, want unknown with tools and vision assumed", caps)
+	}
+	caps := ResolveCapabilities(cfg, "tuned")
+	if caps.ContextWindow != 64000 || caps.Vision || caps.MaxOutputTokens != 16384 || !caps.Tools {
+		t.Errorf("overridden = %+v, want window 64000, no vision, catalog output limit and tools", caps)
+	}
+
+	prices := ModelPrices(cfg)
+	if prices["sonnet"].Input != 1 {
+		t.Errorf("configured price not preferred: %+v", prices["sonnet"])
+	}
+	if prices["tuned"].Input != 0.0025 {
+		t.Errorf("catalog price = %+v", prices["tuned"])
+	}
+	if _, ok := prices["local"]; ok {
+		t.Error("unknown model was priced")
+	}
+}
+
+func TestValidateModel(t *testing.T) {
+	tests := []struct {
+		mc   config.ModelConfig
+		want bool
+	}{
+		{config.ModelConfig{ModelName: "a", Model: "openai/gpt-5.2"}, true},
+		{config.ModelConfig{ModelName: "a", Model: "deepseek/deepseek-reasoner"}, true},
+		{config.ModelConfig{ModelName: "a", Model: "deepseek/deepseek-v9"}, false},
+		{config.ModelConfig{ModelName: "a", Model: "anthropic/claude-sonet-4"}, false},
+		{config.ModelConfig{ModelName: "a", Model: "anthropic/claude-next", Capabilities: &config.ModelCapabilities{ContextWindow: 1000}}, true},
+		{config.ModelConfig{ModelName: "a", Model: "vllm/my-finetune"}, true},
+		{config.ModelConfig{ModelName: "a", Model: "acme/anything"}, true},
+	}
+	for _, tt := range tests {
+		if err := ValidateModel(&tt.mc); (err == nil) != tt.want {
+			t.Errorf("ValidateModel(%s) = %v, want valid %v", tt.mc.Model, err, tt.want)
+		}
+	}
+}
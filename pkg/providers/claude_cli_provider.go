@@ -62,6 +62,21 @@ func (p *ClaudeCliProvider) GetDefaultModel() string {
 	return "claude-code"
 }
 
+// CheckHealth implements HealthChecker: it checks that the claude CLI is
+// installed.
+func (p *ClaudeCliProvider) CheckHealth(ctx context.Context) error {
+	if _, err := exec.LookPath(p.command); err != nil {
+		return fmt.Errorf("%s CLI not found: %w", p.command, err)
+	}
+	return nil
+}
+
+// ListModels returns the model aliases the claude CLI accepts, which
+// always resolve to the latest model of each family.
+func (p *ClaudeCliProvider) ListModels(ctx context.Context) ([]string, error) {
+	return []string{"sonnet", "opus", "haiku"}, nil
+}
+
 // messagesToPrompt converts messages to a CLI-compatible prompt string.
 func (p *ClaudeCliProvider) messagesToPrompt(messages []Message) string {
 	var parts []string
@@ -160,6 +175,7 @@ func (p *ClaudeCliProvider) parseClaudeCliResponse(output string) (*LLMResponse,
 			PromptTokens:     resp.Usage.InputTokens + resp.Usage.CacheCreationInputTokens + resp.Usage.CacheReadInputTokens,
 			CompletionTokens: resp.Usage.OutputTokens,
 			TotalTokens:      resp.Usage.InputTokens + resp.Usage.CacheCreationInputTokens + resp.Usage.CacheReadInputTokens + resp.Usage.OutputTokens,
+			CachedTokens:     resp.Usage.CacheReadInputTokens,
 		}
 	}
 
@@ -1,9 +1,12 @@
+//go:build !minimal
+
 package providers
 
 import (
 	"context"
 	"fmt"
 
+	"github.com/sipeed/picoclaw/pkg/auth"
 	anthropicprovider "github.com/sipeed/picoclaw/pkg/providers/anthropic"
 )
 
@@ -47,18 +50,43 @@ func (p *ClaudeProvider) Chat(ctx context.Context, messages []Message, tools []T
 	return resp, nil
 }
 
+func (p *ClaudeProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onEvent func(StreamEvent)) (*LLMResponse, error) {
+	return p.delegate.ChatStream(ctx, messages, tools, model, options, onEvent)
+}
+
+func (p *ClaudeProvider) UploadFile(ctx context.Context, name, mimeType string, data []byte) (string, error) {
+	return p.delegate.UploadFile(ctx, name, mimeType, data)
+}
+
+func (p *ClaudeProvider) DeleteFile(ctx context.Context, id string) error {
+	return p.delegate.DeleteFile(ctx, id)
+}
+
+func (p *ClaudeProvider) ListModels(ctx context.Context) ([]string, error) {
+	return p.delegate.ListModels(ctx)
+}
+
+func (p *ClaudeProvider) Warm(ctx context.Context) error {
+	return p.delegate.Warm(ctx)
+}
+
+func (p *ClaudeProvider) StructuredOutput() StructuredMode {
+	return StructuredToolChoice
+}
+
 func (p *ClaudeProvider) GetDefaultModel() string {
 	return p.delegate.GetDefaultModel()
 }
 
-func createClaudeTokenSource() func() (string, error) {
+func createClaudeTokenSource(account string) func() (string, error) {
+	source := auth.NewTokenSource(auth.AccountKey("anthropic", account), nil)
 	return func() (string, error) {
-		cred, err := getCredential("anthropic")
+		cred, err := source.Credential()
 		if err != nil {
 			return "", fmt.Errorf("loading auth credentials: %w", err)
 		}
 		if cred == nil {
-			return "", fmt.Errorf("no credentials for anthropic. Run: picoclaw auth login --provider anthropic")
+			return "", fmt.Errorf("no credentials for anthropic. Run: %s", loginHint("anthropic", account))
 		}
 		return cred.AccessToken, nil
 	}
@@ -1,13 +1,18 @@
+//go:build !minimal
+
 package providers
 
 import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	anthropicoption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/sipeed/picoclaw/pkg/auth"
+	"github.com/sipeed/picoclaw/pkg/config"
 	anthropicprovider "github.com/sipeed/picoclaw/pkg/providers/anthropic"
 )
 
@@ -77,3 +82,76 @@ func createAnthropicTestClient(baseURL, token string) *anthropic.Client {
 	)
 	return &c
 }
+
+func TestCreateProviderReturnsClaudeProviderForAnthropicOAuth(t *testing.T) {
+	originalGetCredential := getCredential
+	t.Cleanup(func() { getCredential = originalGetCredential })
+
+	getCredential = func(provider string) (*auth.AuthCredential, error) {
+		if provider != "anthropic" {
+			t.Fatalf("provider = %q, want anthropic", provider)
+		}
+		return &auth.AuthCredential{
+			AccessToken: "anthropic-token",
+		}, nil
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Model = "test-claude-oauth"
+	cfg.ModelList = []config.ModelConfig{
+		{
+			ModelName:  "test-claude-oauth",
+			Model:      "anthropic/claude-sonnet-4.6",
+			AuthMethod: "oauth",
+		},
+	}
+
+	provider, _, err := CreateProvider(cfg)
+	if err != nil {
+		t.Fatalf("CreateProvider() error = %v", err)
+	}
+
+	if _, ok := provider.(*ClaudeProvider); !ok {
+		t.Fatalf("provider type = %T, want *ClaudeProvider", provider)
+	}
+	// TODO: Test custom APIBase when createClaudeAuthProvider supports it
+}
+
+func TestCreateAccountProviderUsesAccountCredential(t *testing.T) {
+	originalGetCredential := getCredential
+	t.Cleanup(func() { getCredential = originalGetCredential })
+
+	var keys []string
+	getCredential = func(provider string) (*auth.AuthCredential, error) {
+		keys = append(keys, provider)
+		return &auth.AuthCredential{AccessToken: "token-" + provider}, nil
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Model = "claude"
+	cfg.ModelList = []config.ModelConfig{
+		{ModelName: "claude", Model: "anthropic/claude-sonnet-4.6", AuthMethod: "token", Account: "personal"},
+	}
+
+	if _, _, err := CreateProvider(cfg); err != nil {
+		t.Fatalf("CreateProvider() error = %v", err)
+	}
+	provider, _, err := CreateAccountProvider(cfg, "claude", "work")
+	if err != nil {
+		t.Fatalf("CreateAccountProvider() error = %v", err)
+	}
+	if _, ok := provider.(*ClaudeProvider); !ok {
+		t.Fatalf("provider type = %T, want *ClaudeProvider", provider)
+	}
+	if len(keys) != 2 || keys[0] != "anthropic:personal" || keys[1] != "anthropic:work" {
+		t.Errorf("credentials looked up = %v, want [anthropic:personal anthropic:work]", keys)
+	}
+	if cfg.ModelList[0].Account != "personal" {
+		t.Errorf("model_list entry account changed to %q", cfg.ModelList[0].Account)
+	}
+
+	getCredential = func(provider string) (*auth.AuthCredential, error) { return nil, nil }
+	if _, _, err := CreateAccountProvider(cfg, "claude", "missing"); err == nil || !strings.Contains(err.Error(), "--account missing") {
+		t.Errorf("error = %v, want login hint for the account", err)
+	}
+}
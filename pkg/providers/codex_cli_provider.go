@@ -84,6 +84,21 @@ func (p *CodexCliProvider) GetDefaultModel() string {
 	return "codex-cli"
 }
 
+// CheckHealth implements HealthChecker: it checks that the codex CLI is
+// installed.
+func (p *CodexCliProvider) CheckHealth(ctx context.Context) error {
+	if _, err := exec.LookPath(p.command); err != nil {
+		return fmt.Errorf("%s CLI not found: %w", p.command, err)
+	}
+	return nil
+}
+
+// ListModels returns the models the codex CLI offers; it has no command
+// to list them.
+func (p *CodexCliProvider) ListModels(ctx context.Context) ([]string, error) {
+	return []string{"gpt-5-codex", "gpt-5"}, nil
+}
+
 // buildPrompt converts messages to a prompt string for the Codex CLI.
 // System messages are prepended as instructions since Codex CLI has no --system-prompt flag.
 func (p *CodexCliProvider) buildPrompt(messages []Message, tools []ToolDefinition) string {
@@ -216,6 +231,7 @@ func (p *CodexCliProvider) parseJSONLEvents(output string) (*LLMResponse, error)
 					PromptTokens:     promptTokens,
 					CompletionTokens: event.Usage.OutputTokens,
 					TotalTokens:      promptTokens + event.Usage.OutputTokens,
+					CachedTokens:     event.Usage.CachedInputTokens,
 				}
 			}
 		case "error":
@@ -1,3 +1,5 @@
+//go:build !minimal
+
 package providers
 
 import (
@@ -5,13 +7,19 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 
 	"github.com/openai/openai-go/v3"
 	"github.com/openai/openai-go/v3/option"
 	"github.com/openai/openai-go/v3/responses"
 	"github.com/sipeed/picoclaw/pkg/auth"
+	"github.com/sipeed/picoclaw/pkg/egress"
 	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers/httpcapture"
+	"github.com/sipeed/picoclaw/pkg/providers/httpretry"
+	"github.com/sipeed/picoclaw/pkg/providers/httptimeout"
+	"github.com/sipeed/picoclaw/pkg/providers/httpwarm"
 )
 
 const codexDefaultModel = "gpt-5.2"
@@ -22,6 +30,9 @@ type CodexProvider struct {
 	accountID       string
 	tokenSource     func() (string, string, error)
 	enableWebSearch bool
+	// builtinTools overrides enableWebSearch with the built-in tools to
+	// offer when set.
+	builtinTools []string
 }
 
 const defaultCodexInstructions = "You are Codex, a coding assistant."
@@ -32,10 +43,14 @@ func NewCodexProvider(token, accountID string) *CodexProvider {
 		option.WithAPIKey(token),
 		option.WithHeader("originator", "codex_cli_rs"),
 		option.WithHeader("OpenAI-Beta", "responses=experimental"),
+		option.WithHTTPClient(&http.Client{Transport: httpretry.Transport(httpcapture.Wrap(httptimeout.Transport(egress.Transport(egress.Providers, httpwarm.Transport(nil)))))}),
 	}
 	if accountID != "" {
 		opts = append(opts, option.WithHeader("Chatgpt-Account-Id", accountID))
 	}
+	if httpretry.Enabled() {
+		opts = append(opts, option.WithMaxRetries(0))
+	}
 	client := openai.NewClient(opts...)
 	return &CodexProvider{
 		client:          &client,
@@ -80,13 +95,20 @@ func (p *CodexProvider) Chat(ctx context.Context, messages []Message, tools []To
 		})
 	}
 
-	params := buildCodexParams(messages, tools, resolvedModel, options, p.enableWebSearch)
+	builtins := p.builtinTools
+	if builtins == nil && p.enableWebSearch {
+		builtins = []string{"web_search"}
+	}
+	params := buildCodexParamsWithBuiltins(messages, tools, resolvedModel, builtins)
 
 	stream := p.client.Responses.NewStreaming(ctx, params, opts...)
 	defer stream.Close()
 
 	var resp *responses.Response
 	for stream.Next() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		evt := stream.Current()
 		if evt.Type == "response.completed" || evt.Type == "response.failed" || evt.Type == "response.incomplete" {
 			evtResp := evt.Response
@@ -185,6 +207,28 @@ func resolveCodexModel(model string) (string, string) {
 }
 
 func buildCodexParams(messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, enableWebSearch bool) responses.ResponseNewParams {
+	var builtins []string
+	if enableWebSearch {
+		builtins = []string{"web_search"}
+	}
+	return buildCodexParamsWithBuiltins(messages, tools, model, builtins)
+}
+
+// buildCodexParamsWithBuiltins builds a request for the Codex backend,
+// which takes no output limit or temperature and requires instructions.
+func buildCodexParamsWithBuiltins(messages []Message, tools []ToolDefinition, model string, builtins []string) responses.ResponseNewParams {
+	params := buildResponsesParams(messages, tools, model, builtins)
+	if !params.Instructions.Valid() {
+		// ChatGPT Codex backend requires instructions to be present.
+		params.Instructions = openai.Opt(defaultCodexInstructions)
+	}
+	return params
+}
+
+// buildResponsesParams builds a Responses API request. System messages
+// become its instructions, and builtins, "web_search" and
+// "code_interpreter", are added as OpenAI's built-in tools.
+func buildResponsesParams(messages []Message, tools []ToolDefinition, model string, builtins []string) responses.ResponseNewParams {
 	var inputItems responses.ResponseInputParam
 	var instructions string
 
@@ -257,19 +301,15 @@ func buildCodexParams(messages []Message, tools []ToolDefinition, model string,
 		Input: responses.ResponseNewParamsInputUnion{
 			OfInputItemList: inputItems,
 		},
-		Instructions: openai.Opt(instructions),
-		Store:        openai.Opt(false),
+		Store: openai.Opt(false),
 	}
 
 	if instructions != "" {
 		params.Instructions = openai.Opt(instructions)
-	} else {
-		// ChatGPT Codex backend requires instructions to be present.
-		params.Instructions = openai.Opt(defaultCodexInstructions)
 	}
 
-	if len(tools) > 0 || enableWebSearch {
-		params.Tools = translateToolsForCodex(tools, enableWebSearch)
+	if len(tools) > 0 || len(builtins) > 0 {
+		params.Tools = translateResponsesTools(tools, builtins)
 	}
 
 	return params
@@ -300,16 +340,23 @@ func resolveCodexToolCall(tc ToolCall) (name string, arguments string, ok bool)
 }
 
 func translateToolsForCodex(tools []ToolDefinition, enableWebSearch bool) []responses.ToolUnionParam {
-	capHint := len(tools)
+	var builtins []string
 	if enableWebSearch {
-		capHint++
+		builtins = []string{"web_search"}
 	}
-	result := make([]responses.ToolUnionParam, 0, capHint)
+	return translateResponsesTools(tools, builtins)
+}
+
+// translateResponsesTools converts tools to Responses API function tools
+// and adds the built-in tools named in builtins. A built-in replaces the
+// function tool of the same name, as OpenAI runs it itself.
+func translateResponsesTools(tools []ToolDefinition, builtins []string) []responses.ToolUnionParam {
+	result := make([]responses.ToolUnionParam, 0, len(tools)+len(builtins))
 	for _, t := range tools {
 		if t.Type != "function" {
 			continue
 		}
-		if enableWebSearch && strings.EqualFold(t.Function.Name, "web_search") {
+		if hasBuiltinTool(builtins, t.Function.Name) {
 			continue
 		}
 		ft := responses.FunctionToolParam{
@@ -322,12 +369,26 @@ func translateToolsForCodex(tools []ToolDefinition, enableWebSearch bool) []resp
 		}
 		result = append(result, responses.ToolUnionParam{OfFunction: &ft})
 	}
-	if enableWebSearch {
-		result = append(result, responses.ToolParamOfWebSearch(responses.WebSearchToolTypeWebSearch))
+	for _, b := range builtins {
+		switch b {
+		case "web_search":
+			result = append(result, responses.ToolParamOfWebSearch(responses.WebSearchToolTypeWebSearch))
+		case "code_interpreter":
+			result = append(result, responses.ToolParamOfCodeInterpreter(responses.ToolCodeInterpreterContainerCodeInterpreterContainerAutoParam{}))
+		}
 	}
 	return result
 }
 
+func hasBuiltinTool(builtins []string, name string) bool {
+	for _, b := range builtins {
+		if strings.EqualFold(b, name) {
+			return true
+		}
+	}
+	return false
+}
+
 func parseCodexResponse(resp *responses.Response) *LLMResponse {
 	var content strings.Builder
 	var toolCalls []ToolCall
@@ -367,6 +428,7 @@ func parseCodexResponse(resp *responses.Response) *LLMResponse {
 			PromptTokens:     int(resp.Usage.InputTokens),
 			CompletionTokens: int(resp.Usage.OutputTokens),
 			TotalTokens:      int(resp.Usage.TotalTokens),
+			CachedTokens:     int(resp.Usage.InputTokensDetails.CachedTokens),
 		}
 	}
 
@@ -378,31 +440,21 @@ func parseCodexResponse(resp *responses.Response) *LLMResponse {
 	}
 }
 
-func createCodexTokenSource() func() (string, string, error) {
+func createCodexTokenSource(account string) func() (string, string, error) {
+	source := auth.NewTokenSource(auth.AccountKey("openai", account), func(cred *auth.AuthCredential) (*auth.AuthCredential, error) {
+		if cred.AuthMethod != "oauth" {
+			return cred, nil
+		}
+		return auth.RefreshAccessToken(cred, auth.OpenAIOAuthConfig())
+	})
 	return func() (string, string, error) {
-		cred, err := auth.GetCredential("openai")
+		cred, err := source.Credential()
 		if err != nil {
 			return "", "", fmt.Errorf("loading auth credentials: %w", err)
 		}
 		if cred == nil {
-			return "", "", fmt.Errorf("no credentials for openai. Run: picoclaw auth login --provider openai")
-		}
-
-		if cred.AuthMethod == "oauth" && cred.NeedsRefresh() && cred.RefreshToken != "" {
-			oauthCfg := auth.OpenAIOAuthConfig()
-			refreshed, err := auth.RefreshAccessToken(cred, oauthCfg)
-			if err != nil {
-				return "", "", fmt.Errorf("refreshing token: %w", err)
-			}
-			if refreshed.AccountID == "" {
-				refreshed.AccountID = cred.AccountID
-			}
-			if err := auth.SetCredential("openai", refreshed); err != nil {
-				return "", "", fmt.Errorf("saving refreshed token: %w", err)
-			}
-			return refreshed.AccessToken, refreshed.AccountID, nil
+			return "", "", fmt.Errorf("no credentials for openai. Run: %s", loginHint("openai", account))
 		}
-
 		return cred.AccessToken, cred.AccountID, nil
 	}
 }
@@ -1,3 +1,5 @@
+//go:build !minimal
+
 package providers
 
 import (
@@ -191,6 +193,62 @@ func TestBuildCodexParams_WebSearchFunctionReplacedWithBuiltin(t *testing.T) {
 	}
 }
 
+func TestBuildResponsesParams_BuiltinTools(t *testing.T) {
+	tools := []ToolDefinition{
+		{
+			Type: "function",
+			Function: ToolFunctionDefinition{
+				Name:       "read_file",
+				Parameters: map[string]interface{}{"type": "object"},
+			},
+		},
+	}
+
+	params := buildResponsesParams([]Message{{Role: "user", Content: "Hi"}}, tools, "gpt-5.2", []string{"web_search", "code_interpreter"})
+	if len(params.Tools) != 3 {
+		t.Fatalf("len(Tools) = %d, want 3", len(params.Tools))
+	}
+	if params.Tools[0].OfFunction == nil || params.Tools[1].OfWebSearch == nil || params.Tools[2].OfCodeInterpreter == nil {
+		t.Fatalf("Tools = %#v, want read_file, web_search and code_interpreter", params.Tools)
+	}
+	if params.Instructions.Valid() {
+		t.Errorf("Instructions = %q, want none without a system message", params.Instructions.Value)
+	}
+}
+
+func TestCodexProvider_BuiltinToolsOverrideWebSearch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody struct {
+			Tools []struct {
+				Type string `json:"type"`
+			} `json:"tools"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		if len(reqBody.Tools) != 1 || reqBody.Tools[0].Type != "code_interpreter" {
+			http.Error(w, fmt.Sprintf("tools = %+v, want only code_interpreter", reqBody.Tools), http.StatusBadRequest)
+			return
+		}
+		writeCompletedSSE(w, map[string]interface{}{
+			"id":     "resp_test",
+			"object": "response",
+			"status": "completed",
+			"output": []map[string]interface{}{},
+		})
+	}))
+	defer server.Close()
+
+	provider := NewCodexProvider("test-token", "acc-123")
+	provider.builtinTools = []string{"code_interpreter"}
+	provider.client = createOpenAITestClient(server.URL, "test-token", "acc-123")
+
+	if _, err := provider.Chat(t.Context(), []Message{{Role: "user", Content: "Hello"}}, nil, "gpt-4o", nil); err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+}
+
 func TestParseCodexResponse_TextOutput(t *testing.T) {
 	respJSON := `{
 		"id": "resp_test",
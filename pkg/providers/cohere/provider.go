@@ -0,0 +1,242 @@
+// Package cohereprovider implements Cohere's chat API (v2), which takes
+// OpenAI-style messages and tools but answers in a format of its own.
+package cohereprovider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/egress"
+	"github.com/sipeed/picoclaw/pkg/providers/httpcapture"
+	"github.com/sipeed/picoclaw/pkg/providers/httpretry"
+	"github.com/sipeed/picoclaw/pkg/providers/httptimeout"
+	"github.com/sipeed/picoclaw/pkg/providers/httpwarm"
+	"github.com/sipeed/picoclaw/pkg/providers/protocoltypes"
+)
+
+type ToolCall = protocoltypes.ToolCall
+type LLMResponse = protocoltypes.LLMResponse
+type UsageInfo = protocoltypes.UsageInfo
+type Message = protocoltypes.Message
+type ToolDefinition = protocoltypes.ToolDefinition
+
+const DefaultAPIBase = "https://api.cohere.com/v2"
+
+type Provider struct {
+	apiKey     string
+	apiBase    string
+	httpClient *http.Client
+}
+
+func NewProvider(apiKey, apiBase, proxy string) *Provider {
+	if apiBase == "" {
+		apiBase = DefaultAPIBase
+	}
+
+	client := &http.Client{}
+	if proxy != "" {
+		parsed, err := url.Parse(proxy)
+		if err == nil {
+			client.Transport = &http.Transport{
+				Proxy: http.ProxyURL(parsed),
+			}
+		} else {
+			log.Printf("cohere: invalid proxy URL %q: %v", proxy, err)
+		}
+	}
+	client.Transport = httpretry.Transport(httpcapture.Wrap(httptimeout.Transport(egress.Transport(egress.Providers, httpwarm.Transport(client.Transport)))))
+
+	return &Provider{
+		apiKey:     apiKey,
+		apiBase:    strings.TrimRight(apiBase, "/"),
+		httpClient: client,
+	}
+}
+
+// Warm opens a connection to the API host ahead of the first request.
+func (p *Provider) Warm(ctx context.Context) error {
+	return httpwarm.Warm(ctx, p.httpClient, p.apiBase)
+}
+
+func (p *Provider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	requestBody := map[string]interface{}{
+		"model":    model,
+		"messages": wireMessages(messages),
+	}
+	if len(tools) > 0 {
+		requestBody["tools"] = tools
+	}
+	if maxTokens, ok := options["max_tokens"].(int); ok {
+		requestBody["max_tokens"] = maxTokens
+	}
+	if temperature, ok := options["temperature"].(float64); ok {
+		requestBody["temperature"] = temperature
+	}
+	if seed, ok := options["seed"].(int); ok {
+		requestBody["seed"] = seed
+	}
+
+	jsonData, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.apiBase+"/chat", bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API request failed:\n  Status: %d\n  Body:   %s", resp.StatusCode, string(body))
+	}
+
+	return parseResponse(body)
+}
+
+// wireMessages converts messages to the chat v2 format. The text of an
+// assistant message that calls tools is sent as its tool plan, tool call
+// arguments as JSON strings, and images as content parts.
+func wireMessages(messages []Message) []map[string]interface{} {
+	out := make([]map[string]interface{}, 0, len(messages))
+	for _, m := range messages {
+		msg := map[string]interface{}{"role": m.Role}
+		switch {
+		case m.Role == "tool":
+			msg["tool_call_id"] = m.ToolCallID
+			msg["content"] = m.Content
+		case m.Role == "assistant" && len(m.ToolCalls) > 0:
+			calls := make([]map[string]interface{}, 0, len(m.ToolCalls))
+			for _, tc := range m.ToolCalls {
+				name, arguments := tc.Name, ""
+				if tc.Function != nil {
+					if name == "" {
+						name = tc.Function.Name
+					}
+					arguments = tc.Function.Arguments
+				}
+				if arguments == "" {
+					data, _ := json.Marshal(tc.Arguments)
+					arguments = string(data)
+				}
+				calls = append(calls, map[string]interface{}{
+					"id":       tc.ID,
+					"type":     "function",
+					"function": map[string]string{"name": name, "arguments": arguments},
+				})
+			}
+			msg["tool_calls"] = calls
+			if m.Content != "" {
+				msg["tool_plan"] = m.Content
+			}
+		case len(m.Images) > 0:
+			parts := []map[string]interface{}{{"type": "text", "text": m.Content}}
+			for _, img := range m.Images {
+				parts = append(parts, map[string]interface{}{
+					"type":      "image_url",
+					"image_url": map[string]string{"url": img.DataURL()},
+				})
+			}
+			msg["content"] = parts
+		default:
+			msg["content"] = m.Content
+		}
+		out = append(out, msg)
+	}
+	return out
+}
+
+func parseResponse(body []byte) (*LLMResponse, error) {
+	var apiResponse struct {
+		FinishReason string `json:"finish_reason"`
+		Message      struct {
+			Content []struct {
+				Type string `json:"type"`
+				Text string `json:"text"`
+			} `json:"content"`
+			ToolCalls []struct {
+				ID       string `json:"id"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"message"`
+		Usage *struct {
+			Tokens struct {
+				InputTokens  float64 `json:"input_tokens"`
+				OutputTokens float64 `json:"output_tokens"`
+			} `json:"tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &apiResponse); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	var content strings.Builder
+	for _, c := range apiResponse.Message.Content {
+		if c.Type == "text" {
+			content.WriteString(c.Text)
+		}
+	}
+
+	toolCalls := make([]ToolCall, 0, len(apiResponse.Message.ToolCalls))
+	for _, tc := range apiResponse.Message.ToolCalls {
+		arguments := make(map[string]interface{})
+		if tc.Function.Arguments != "" {
+			if err := json.Unmarshal([]byte(tc.Function.Arguments), &arguments); err != nil {
+				log.Printf("cohere: failed to decode tool call arguments for %q: %v", tc.Function.Name, err)
+				arguments["raw"] = tc.Function.Arguments
+			}
+		}
+		toolCalls = append(toolCalls, ToolCall{ID: tc.ID, Name: tc.Function.Name, Arguments: arguments})
+	}
+
+	var usage *UsageInfo
+	if u := apiResponse.Usage; u != nil {
+		usage = &UsageInfo{
+			PromptTokens:     int(u.Tokens.InputTokens),
+			CompletionTokens: int(u.Tokens.OutputTokens),
+			TotalTokens:      int(u.Tokens.InputTokens + u.Tokens.OutputTokens),
+		}
+	}
+
+	return &LLMResponse{
+		Content:      content.String(),
+		ToolCalls:    toolCalls,
+		FinishReason: finishReason(apiResponse.FinishReason),
+		Usage:        usage,
+	}, nil
+}
+
+// finishReason maps Cohere's finish reasons to the OpenAI ones the agent
+// checks for.
+func finishReason(reason string) string {
+	switch reason {
+	case "COMPLETE", "STOP_SEQUENCE":
+		return "stop"
+	case "TOOL_CALL":
+		return "tool_calls"
+	case "MAX_TOKENS":
+		return "length"
+	}
+	return strings.ToLower(reason)
+}
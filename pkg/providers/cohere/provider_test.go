@@ -0,0 +1,95 @@
+package cohereprovider
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/providers/protocoltypes"
+)
+
+func TestProviderChat_SendsV2Request(t *testing.T) {
+	var requestBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat" || r.Header.Get("Authorization") != "Bearer key" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&requestBody)
+		w.Write([]byte(`{"finish_reason":"COMPLETE","message":{"role":"assistant","content":[{"type":"text","text":"done"}]},` +
+			`"usage":{"tokens":{"input_tokens":12,"output_tokens":3}}}`))
+	}))
+	defer server.Close()
+
+	messages := []Message{
+		{Role: "system", Content: "be brief"},
+		{Role: "user", Content: "what time is it?"},
+		{Role: "assistant", Content: "I'll check.", ToolCalls: []ToolCall{{
+			ID: "call_1", Name: "time", Arguments: map[string]interface{}{"tz": "UTC"},
+		}}},
+		{Role: "tool", ToolCallID: "call_1", Content: "12:00"},
+	}
+	tools := []ToolDefinition{{Type: "function", Function: protocoltypes.ToolFunctionDefinition{Name: "time"}}}
+
+	p := NewProvider("key", server.URL, "")
+	resp, err := p.Chat(t.Context(), messages, tools, "command-a-03-2025", map[string]interface{}{"max_tokens": 100})
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if resp.Content != "done" || resp.FinishReason != "stop" {
+		t.Errorf("response = %+v", resp)
+	}
+	if resp.Usage == nil || resp.Usage.PromptTokens != 12 || resp.Usage.TotalTokens != 15 {
+		t.Errorf("usage = %+v", resp.Usage)
+	}
+
+	if requestBody["model"] != "command-a-03-2025" || requestBody["max_tokens"] != float64(100) {
+		t.Errorf("request = %v", requestBody)
+	}
+	sent := requestBody["messages"].([]interface{})
+	assistant := sent[2].(map[string]interface{})
+	if assistant["tool_plan"] != "I'll check." || assistant["content"] != nil {
+		t.Errorf("assistant message = %v", assistant)
+	}
+	call := assistant["tool_calls"].([]interface{})[0].(map[string]interface{})
+	function := call["function"].(map[string]interface{})
+	if function["name"] != "time" || function["arguments"] != `{"tz":"UTC"}` {
+		t.Errorf("tool call = %v", call)
+	}
+	if tool := sent[3].(map[string]interface{}); tool["tool_call_id"] != "call_1" || tool["content"] != "12:00" {
+		t.Errorf("tool message = %v", tool)
+	}
+}
+
+func TestProviderChat_ParsesToolCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"finish_reason":"TOOL_CALL","message":{"role":"assistant","tool_plan":"Look it up.",` +
+			`"tool_calls":[{"id":"t1","type":"function","function":{"name":"web_search","arguments":"{\"query\":\"picoclaw\"}"}}]}}`))
+	}))
+	defer server.Close()
+
+	resp, err := NewProvider("key", server.URL, "").Chat(t.Context(), []Message{{Role: "user", Content: "search"}}, nil, "command-a", nil)
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if resp.FinishReason != "tool_calls" || len(resp.ToolCalls) != 1 {
+		t.Fatalf("response = %+v", resp)
+	}
+	if tc := resp.ToolCalls[0]; tc.ID != "t1" || tc.Name != "web_search" || tc.Arguments["query"] != "picoclaw" {
+		t.Errorf("tool call = %+v", tc)
+	}
+}
+
+func TestProviderChat_ReportsAPIErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"invalid api token"}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	_, err := NewProvider("bad", server.URL, "").Chat(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, "command-a", nil)
+	if err == nil || !strings.Contains(err.Error(), "Status: 401") {
+		t.Errorf("err = %v, want the status", err)
+	}
+}
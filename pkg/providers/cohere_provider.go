@@ -0,0 +1,41 @@
+package providers
+
+import (
+	"context"
+
+	cohereprovider "github.com/sipeed/picoclaw/pkg/providers/cohere"
+)
+
+// CohereProvider talks to Cohere's native chat API, whose responses are not
+// OpenAI-compatible.
+type CohereProvider struct {
+	delegate *cohereprovider.Provider
+}
+
+func NewCohereProvider(apiKey, apiBase, proxy string) *CohereProvider {
+	return &CohereProvider{
+		delegate: cohereprovider.NewProvider(apiKey, apiBase, proxy),
+	}
+}
+
+func (p *CohereProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	return p.delegate.Chat(ctx, messages, tools, model, options)
+}
+
+func (p *CohereProvider) Warm(ctx context.Context) error {
+	return p.delegate.Warm(ctx)
+}
+
+func (p *CohereProvider) SupportsSeed() bool {
+	return true
+}
+
+// ListModels returns the current chat models. The models endpoint is not
+// part of the chat v2 API, so they are listed here.
+func (p *CohereProvider) ListModels(ctx context.Context) ([]string, error) {
+	return []string{"command-a-03-2025", "command-r-plus-08-2024", "command-r-08-2024", "command-r7b-12-2024"}, nil
+}
+
+func (p *CohereProvider) GetDefaultModel() string {
+	return ""
+}
@@ -0,0 +1,248 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+package providers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// CopilotBridgeError reports that the GitHub Copilot bridge, the Copilot
+// CLI server the provider talks to, is not running or cannot be reached.
+type CopilotBridgeError struct {
+	Addr string
+	Err  error
+}
+
+func (e *CopilotBridgeError) Error() string {
+	return fmt.Sprintf("GitHub Copilot bridge is not reachable at %s: %v; run `copilot --server --port <port>` "+
+		"or set bridge in the model_list entry to launch it", e.Addr, e.Err)
+}
+
+func (e *CopilotBridgeError) Unwrap() error {
+	return e.Err
+}
+
+// CopilotAuthError reports that the GitHub Copilot bridge is running but
+// not signed in to GitHub, or that GitHub refused its login.
+type CopilotAuthError struct {
+	Message string
+}
+
+func (e *CopilotAuthError) Error() string {
+	msg := "GitHub Copilot is not signed in"
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg + "; run `copilot` and use /login, or set GH_TOKEN in the bridge's environment"
+}
+
+// classifyCopilotError turns a failed request's error into a
+// *CopilotAuthError when GitHub refused the login.
+func classifyCopilotError(err error) error {
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{"unauthorized", "not authenticated", "not logged in", "401", "403"} {
+		if strings.Contains(msg, s) {
+			return &CopilotAuthError{Message: err.Error()}
+		}
+	}
+	return err
+}
+
+// defaultBridgeStartTimeout bounds the wait for a launched bridge.
+const defaultBridgeStartTimeout = 30 * time.Second
+
+// copilotBridge is a bridge process launched by PicoClaw.
+type copilotBridge struct {
+	addr   string
+	cmd    *exec.Cmd
+	stderr *bytes.Buffer
+	// done is closed when the process exits.
+	done    chan struct{}
+	stopped atomic.Bool
+}
+
+var (
+	bridgesMu sync.Mutex
+	// bridges holds the launched bridges by address, so that the entries
+	// and agents that share a bridge share its process.
+	bridges = map[string]*copilotBridge{}
+)
+
+// bridgeAddr returns the host:port of a bridge URI, which is "host:port",
+// "http://host:port" or just a port, as the Copilot SDK takes them.
+func bridgeAddr(uri string) string {
+	addr := strings.TrimSpace(uri)
+	if _, rest, ok := strings.Cut(addr, "://"); ok {
+		addr = rest
+	}
+	addr = strings.TrimRight(addr, "/")
+	if strings.Trim(addr, "0123456789") == "" {
+		return "localhost:" + addr
+	}
+	return addr
+}
+
+// probeCopilotBridge returns a *CopilotBridgeError if nothing accepts
+// connections at the bridge URI.
+func probeCopilotBridge(ctx context.Context, uri string) error {
+	addr := bridgeAddr(uri)
+	dialer := net.Dialer{Timeout: 2 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return &CopilotBridgeError{Addr: addr, Err: err}
+	}
+	conn.Close()
+	return nil
+}
+
+// ensureCopilotBridge makes sure the bridge at uri is reachable. If it is
+// not and bridge is set, the bridge is launched, or launched again if it
+// has exited, and waited for.
+func ensureCopilotBridge(ctx context.Context, uri string, bridge *config.CopilotBridgeConfig) error {
+	err := probeCopilotBridge(ctx, uri)
+	if err == nil || bridge == nil {
+		return err
+	}
+	addr := bridgeAddr(uri)
+
+	bridgesMu.Lock()
+	b, ok := bridges[addr]
+	if ok {
+		select {
+		case <-b.done:
+			ok = false
+		default:
+		}
+	}
+	if !ok {
+		b, err = launchCopilotBridge(addr, bridge)
+		if err != nil {
+			bridgesMu.Unlock()
+			return &CopilotBridgeError{Addr: addr, Err: err}
+		}
+		bridges[addr] = b
+	}
+	bridgesMu.Unlock()
+
+	timeout := defaultBridgeStartTimeout
+	if bridge.StartTimeoutSeconds > 0 {
+		timeout = time.Duration(bridge.StartTimeoutSeconds) * time.Second
+	}
+	return b.wait(ctx, timeout)
+}
+
+func launchCopilotBridge(addr string, bridge *config.CopilotBridgeConfig) (*copilotBridge, error) {
+	command := bridge.Command
+	if command == "" {
+		command = "copilot"
+	}
+	// The process outlives the request that launched it, so it is not
+	// bound to its context.
+	cmd := exec.Command(command, bridge.Args...)
+	cmd.Env = os.Environ()
+	for k, v := range bridge.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	b := &copilotBridge{addr: addr, cmd: cmd, stderr: &bytes.Buffer{}, done: make(chan struct{})}
+	cmd.Stderr = &limitedWriter{buf: b.stderr, max: 4096}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("launching %s: %w", command, err)
+	}
+	logger.InfoCF("provider", "Launched GitHub Copilot bridge", map[string]interface{}{
+		"addr":    addr,
+		"command": command,
+		"pid":     cmd.Process.Pid,
+	})
+	go func() {
+		err := cmd.Wait()
+		close(b.done)
+		if b.stopped.Load() {
+			return
+		}
+		fields := map[string]interface{}{"addr": addr}
+		if err != nil {
+			fields["error"] = err.Error()
+		}
+		logger.WarnCF("provider", "GitHub Copilot bridge exited", fields)
+	}()
+	return b, nil
+}
+
+// wait waits until the bridge accepts connections, exits or timeout
+// passes.
+func (b *copilotBridge) wait(ctx context.Context, timeout time.Duration) error {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	tick := time.NewTicker(200 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		if err := probeCopilotBridge(ctx, b.addr); err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-b.done:
+			msg := strings.TrimSpace(b.stderr.String())
+			if msg == "" {
+				msg = b.cmd.ProcessState.String()
+			}
+			return &CopilotBridgeError{Addr: b.addr, Err: errors.New("launched bridge exited: " + msg)}
+		case <-deadline.C:
+			return &CopilotBridgeError{Addr: b.addr, Err: fmt.Errorf("launched bridge did not listen within %s", timeout)}
+		case <-tick.C:
+		}
+	}
+}
+
+// StopCopilotBridges stops the GitHub Copilot bridges PicoClaw launched.
+func StopCopilotBridges() {
+	bridgesMu.Lock()
+	defer bridgesMu.Unlock()
+	for addr, b := range bridges {
+		select {
+		case <-b.done:
+		default:
+			b.stopped.Store(true)
+			b.cmd.Process.Kill()
+			<-b.done
+		}
+		delete(bridges, addr)
+	}
+}
+
+// limitedWriter keeps the first max bytes written to it.
+type limitedWriter struct {
+	mu  sync.Mutex
+	buf *bytes.Buffer
+	max int
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if room := w.max - w.buf.Len(); room > 0 {
+		if len(p) > room {
+			w.buf.Write(p[:room])
+		} else {
+			w.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,113 @@
+package providers
+
+import (
+	"errors"
+	"net"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// TestCopilotBridgeHelper is the bridge launched by the tests below: it
+// listens at PICOCLAW_TEST_BRIDGE until it is killed.
+func TestCopilotBridgeHelper(t *testing.T) {
+	addr := os.Getenv("PICOCLAW_TEST_BRIDGE")
+	if addr == "" {
+		t.Skip("only run as a launched bridge")
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		os.Exit(2)
+	}
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			os.Exit(0)
+		}
+		conn.Close()
+	}
+}
+
+// freeAddr returns a local address nothing listens at.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return addr
+}
+
+func TestBridgeAddr(t *testing.T) {
+	for uri, want := range map[string]string{
+		"localhost:4321":        "localhost:4321",
+		"http://127.0.0.1:9000": "127.0.0.1:9000",
+		"4321":                  "localhost:4321",
+	} {
+		if got := bridgeAddr(uri); got != want {
+			t.Errorf("bridgeAddr(%q) = %q, want %q", uri, got, want)
+		}
+	}
+}
+
+func TestEnsureCopilotBridge(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	if err := ensureCopilotBridge(t.Context(), ln.Addr().String(), nil); err != nil {
+		t.Errorf("running bridge: %v", err)
+	}
+
+	down := freeAddr(t)
+	var bridgeErr *CopilotBridgeError
+	if err := ensureCopilotBridge(t.Context(), down, nil); !errors.As(err, &bridgeErr) || bridgeErr.Addr != down {
+		t.Errorf("stopped bridge: %v, want a CopilotBridgeError", err)
+	}
+
+	defer StopCopilotBridges()
+	bridge := &config.CopilotBridgeConfig{
+		Command: os.Args[0],
+		Args:    []string{"-test.run=^TestCopilotBridgeHelper$"},
+		Env:     map[string]string{"PICOCLAW_TEST_BRIDGE": down},
+	}
+	if err := ensureCopilotBridge(t.Context(), "http://"+down, bridge); err != nil {
+		t.Fatalf("launching bridge: %v", err)
+	}
+	if err := probeCopilotBridge(t.Context(), down); err != nil {
+		t.Errorf("launched bridge not reachable: %v", err)
+	}
+	StopCopilotBridges()
+	if err := probeCopilotBridge(t.Context(), down); err == nil {
+		t.Error("bridge still reachable after StopCopilotBridges")
+	}
+}
+
+func TestEnsureCopilotBridge_LaunchFails(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("bridge script needs sh")
+	}
+	defer StopCopilotBridges()
+	bridge := &config.CopilotBridgeConfig{Command: "sh", Args: []string{"-c", "echo 'copilot: port in use' >&2; exit 1"}}
+	err := ensureCopilotBridge(t.Context(), freeAddr(t), bridge)
+	var bridgeErr *CopilotBridgeError
+	if !errors.As(err, &bridgeErr) || !strings.Contains(err.Error(), "port in use") {
+		t.Errorf("ensureCopilotBridge() = %v, want a CopilotBridgeError with the bridge's output", err)
+	}
+}
+
+func TestClassifyCopilotError(t *testing.T) {
+	var authErr *CopilotAuthError
+	if err := classifyCopilotError(errors.New("request failed: 401 Unauthorized")); !errors.As(err, &authErr) {
+		t.Errorf("401 = %v, want a CopilotAuthError", err)
+	}
+	if err := classifyCopilotError(errors.New("model overloaded")); errors.As(err, &authErr) {
+		t.Errorf("other failure = %v, want it unchanged", err)
+	}
+}
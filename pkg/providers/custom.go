@@ -0,0 +1,81 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+package providers
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// builtinProtocols are the protocols createProviderFromConfig handles
+// itself. Custom providers cannot take their names.
+var builtinProtocols = []string{
+	"openai", "openrouter", "groq", "zhipu", "gemini", "nvidia",
+	"ollama", "moonshot", "shengsuanyun", "deepseek", "cerebras",
+	"volcengine", "vllm", "qwen", "mistral", "xai",
+	"anthropic", "lmstudio", "llamacpp", "cohere", "antigravity",
+	"claude-cli", "claudecli", "codex-cli", "codexcli", "github-copilot", "copilot",
+}
+
+var (
+	customMu sync.RWMutex
+	// customProviders holds the providers declared in custom_providers,
+	// by name.
+	customProviders = map[string]config.CustomProviderConfig{}
+)
+
+// RegisterCustomProviders makes the providers declared in custom_providers
+// available to model_list entries, replacing those registered before.
+func RegisterCustomProviders(cfgs []config.CustomProviderConfig) error {
+	registered := make(map[string]config.CustomProviderConfig, len(cfgs))
+	for _, c := range cfgs {
+		if err := c.Validate(); err != nil {
+			return fmt.Errorf("custom provider %q: %w", c.Name, err)
+		}
+		for _, builtin := range builtinProtocols {
+			if c.Name == builtin {
+				return fmt.Errorf("custom provider %q: name is taken by a built-in provider", c.Name)
+			}
+		}
+		if _, ok := findRegisteredProvider(c.Name); ok {
+			return fmt.Errorf("custom provider %q: name is taken by a registered provider", c.Name)
+		}
+		registered[c.Name] = c
+	}
+	customMu.Lock()
+	customProviders = registered
+	customMu.Unlock()
+	return nil
+}
+
+func findCustomProvider(name string) (config.CustomProviderConfig, bool) {
+	customMu.RLock()
+	defer customMu.RUnlock()
+	c, ok := customProviders[name]
+	return c, ok
+}
+
+// newCustomProvider creates the provider of the model_list entry cfg,
+// which uses the custom provider custom.
+func newCustomProvider(custom config.CustomProviderConfig, cfg *config.ModelConfig) (LLMProvider, error) {
+	apiBase := cfg.APIBase
+	if apiBase == "" {
+		apiBase = custom.APIBase
+	}
+	provider := NewHTTPProviderWithMaxTokensField(cfg.APIKey, apiBase, cfg.Proxy, cfg.MaxTokensField)
+	if custom.AuthHeader != "" {
+		if strings.Contains(custom.AuthHeader, "{api_key}") && cfg.APIKey == "" {
+			return nil, fmt.Errorf("api_key is required for custom provider %q (model: %s)", custom.Name, cfg.Model)
+		}
+		name, value, _ := strings.Cut(custom.AuthHeader, ":")
+		value = strings.ReplaceAll(strings.TrimSpace(value), "{api_key}", cfg.APIKey)
+		provider.delegate.WithAuthHeader(strings.TrimSpace(name), value)
+	}
+	return provider, nil
+}
@@ -0,0 +1,61 @@
+package providers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestCustomProvider(t *testing.T) {
+	var gotKey, gotAuth, gotModel string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotKey = r.Header.Get("X-Api-Key")
+		gotAuth = r.Header.Get("Authorization")
+		var body struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		gotModel = body.Model
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`))
+	}))
+	defer srv.Close()
+	defer RegisterCustomProviders(nil)
+
+	if err := RegisterCustomProviders([]config.CustomProviderConfig{{
+		Name:        "acme",
+		APIBase:     srv.URL + "/v1",
+		AuthHeader:  "X-Api-Key: {api_key}",
+		ModelPrefix: "models/",
+	}}); err != nil {
+		t.Fatalf("RegisterCustomProviders() error = %v", err)
+	}
+
+	p, modelID, err := CreateProviderFromConfig(&config.ModelConfig{ModelName: "acme", Model: "acme/large", APIKey: "secret"})
+	if err != nil {
+		t.Fatalf("CreateProviderFromConfig() error = %v", err)
+	}
+	if modelID != "models/large" {
+		t.Errorf("model ID = %q", modelID)
+	}
+	resp, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "hello"}}, nil, modelID, nil)
+	if err != nil || resp.Content != "hi" {
+		t.Fatalf("Chat() = %+v, %v", resp, err)
+	}
+	if gotKey != "secret" || gotAuth != "" || gotModel != "models/large" {
+		t.Errorf("server got key %q, authorization %q, model %q", gotKey, gotAuth, gotModel)
+	}
+
+	if _, _, err := CreateProviderFromConfig(&config.ModelConfig{ModelName: "acme", Model: "acme/large"}); err == nil {
+		t.Error("expected an error without the api_key the auth header needs")
+	}
+	if err := RegisterCustomProviders([]config.CustomProviderConfig{{Name: "openai", APIBase: "https://x"}}); err == nil {
+		t.Error("expected an error for a custom provider named after a built-in one")
+	}
+	RegisterCustomProviders(nil)
+	if _, _, err := CreateProviderFromConfig(&config.ModelConfig{ModelName: "acme", Model: "acme/large", APIKey: "secret"}); err == nil {
+		t.Error("expected unregistered providers to be unknown")
+	}
+}
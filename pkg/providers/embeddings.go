@@ -0,0 +1,260 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/egress"
+)
+
+// EmbeddingsProvider turns texts into vectors whose cosine similarity
+// reflects how alike the texts are in meaning, for memory search and
+// finding the tools relevant to a request.
+type EmbeddingsProvider interface {
+	// Embed returns one vector per text, in the order of texts. Vectors
+	// from the same provider all have the same length.
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// CreateEmbeddingsProvider returns the embeddings provider for model: the
+// model_name of a model_list entry, or "local/hash" (optionally
+// "local/hash-<dimensions>") for embeddings computed locally from the
+// words of the texts, which need no model but only match shared words.
+//
+// OpenAI-compatible APIs, including Ollama, vLLM, LM Studio and the
+// llama.cpp server, are asked at their embeddings endpoint, and Gemini at
+// its native one.
+func CreateEmbeddingsProvider(cfg *config.Config, model string) (EmbeddingsProvider, error) {
+	if protocol, id := ExtractProtocol(model); protocol == "local" {
+		return newHashEmbeddings(id)
+	}
+	mc, err := cfg.GetModelConfig(model)
+	if err != nil {
+		return nil, err
+	}
+	return CreateEmbeddingsProviderFromConfig(mc)
+}
+
+// CreateEmbeddingsProviderFromConfig returns the embeddings provider for
+// a model_list entry; see CreateEmbeddingsProvider.
+func CreateEmbeddingsProviderFromConfig(mc *config.ModelConfig) (EmbeddingsProvider, error) {
+	protocol, modelID := ExtractProtocol(mc.Model)
+	switch protocol {
+	case "local":
+		return newHashEmbeddings(modelID)
+	case "gemini":
+		if mc.APIKey == "" {
+			return nil, fmt.Errorf("api_key is required for gemini embeddings (model: %s)", mc.Model)
+		}
+		apiBase := mc.APIBase
+		if apiBase == "" {
+			apiBase = getDefaultAPIBase(protocol)
+		}
+		return newGeminiEmbeddings(mc.APIKey, apiBase, modelID), nil
+	case "anthropic":
+		return nil, fmt.Errorf("anthropic has no embeddings API (model: %s)", mc.Model)
+	}
+
+	provider, modelID, err := CreateProviderFromConfig(mc)
+	if err != nil {
+		return nil, err
+	}
+	embedder, ok := provider.(Embedder)
+	if !ok {
+		return nil, fmt.Errorf("protocol %q does not support embeddings (model: %s)", protocol, mc.Model)
+	}
+	return &modelEmbeddings{embedder: embedder, model: modelID}, nil
+}
+
+// Cosine returns the cosine similarity of a and b, from -1 to 1, or 0 if
+// either is empty or they differ in length.
+func Cosine(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+// modelEmbeddings embeds with a provider's embeddings endpoint.
+type modelEmbeddings struct {
+	embedder Embedder
+	model    string
+}
+
+func (e *modelEmbeddings) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	return e.embedder.Embed(ctx, texts, e.model)
+}
+
+// geminiEmbeddings embeds with the Gemini API's batchEmbedContents.
+type geminiEmbeddings struct {
+	apiKey     string
+	apiBase    string
+	model      string
+	httpClient *http.Client
+}
+
+func newGeminiEmbeddings(apiKey, apiBase, model string) *geminiEmbeddings {
+	return &geminiEmbeddings{
+		apiKey: apiKey,
+		// The OpenAI-compatible endpoint has no embeddings; use the native one
+		// next to it.
+		apiBase: strings.TrimSuffix(strings.TrimRight(apiBase, "/"), "/openai"),
+		model:   strings.TrimPrefix(model, "models/"),
+		httpClient: &http.Client{
+			Timeout:   60 * time.Second,
+			Transport: egress.Transport(egress.Providers, nil),
+		},
+	}
+}
+
+func (e *geminiEmbeddings) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	type part struct {
+		Text string `json:"text"`
+	}
+	type content struct {
+		Parts []part `json:"parts"`
+	}
+	type request struct {
+		Model   string  `json:"model"`
+		Content content `json:"content"`
+	}
+	requests := make([]request, len(texts))
+	for i, text := range texts {
+		requests[i] = request{Model: "models/" + e.model, Content: content{Parts: []part{{Text: text}}}}
+	}
+	body, err := json.Marshal(map[string]interface{}{"requests": requests})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/models/%s:batchEmbedContents", e.apiBase, e.model)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", e.apiKey)
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API request failed:\n  Status: %d\n  Body:   %s", resp.StatusCode, string(respBody))
+	}
+
+	var result struct {
+		Embeddings []struct {
+			Values []float32 `json:"values"`
+		} `json:"embeddings"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(result.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("got %d embeddings for %d texts", len(result.Embeddings), len(texts))
+	}
+	vectors := make([][]float32, len(texts))
+	for i, emb := range result.Embeddings {
+		vectors[i] = emb.Values
+	}
+	return vectors, nil
+}
+
+// defaultHashDimensions is the length of local/hash vectors.
+const defaultHashDimensions = 256
+
+// hashEmbeddings computes embeddings without a model by hashing the words
+// of a text, and the letter trigrams of each word so that forms of a word
+// still match, into a fixed number of dimensions.
+type hashEmbeddings struct {
+	dims int
+}
+
+func newHashEmbeddings(id string) (*hashEmbeddings, error) {
+	if id == "hash" {
+		return &hashEmbeddings{dims: defaultHashDimensions}, nil
+	}
+	if size, ok := strings.CutPrefix(id, "hash-"); ok {
+		if dims, err := strconv.Atoi(size); err == nil && dims > 0 {
+			return &hashEmbeddings{dims: dims}, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown local embeddings model %q; use local/hash or local/hash-<dimensions>", id)
+}
+
+func (e *hashEmbeddings) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = e.embed(text)
+	}
+	return vectors, nil
+}
+
+func (e *hashEmbeddings) embed(text string) []float32 {
+	v := make([]float32, e.dims)
+	add := func(feature string, weight float32) {
+		h := fnv.New32a()
+		h.Write([]byte(feature))
+		sum := h.Sum32()
+		// The top bit gives the sign, so that collisions cancel out rather
+		// than add up.
+		if sum&(1<<31) != 0 {
+			weight = -weight
+		}
+		v[int(sum%uint32(e.dims))] += weight
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for _, word := range words {
+		add("w:"+word, 1)
+		runes := []rune("^" + word + "$")
+		for j := 0; j+3 <= len(runes); j++ {
+			add("t:"+string(runes[j:j+3]), 0.5)
+		}
+	}
+
+	var norm float64
+	for _, x := range v {
+		norm += float64(x) * float64(x)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for j := range v {
+			v[j] *= scale
+		}
+	}
+	return v
+}
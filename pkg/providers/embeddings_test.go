@@ -0,0 +1,99 @@
+package providers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestCreateEmbeddingsProvider_Local(t *testing.T) {
+	e, err := CreateEmbeddingsProvider(&config.Config{}, "local/hash")
+	if err != nil {
+		t.Fatalf("CreateEmbeddingsProvider() error = %v", err)
+	}
+	vectors, err := e.Embed(t.Context(), []string{
+		"restart the web server",
+		"the webserver needs restarting",
+		"what should I cook for dinner",
+	})
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	if len(vectors[0]) != defaultHashDimensions {
+		t.Fatalf("got %d dimensions, want %d", len(vectors[0]), defaultHashDimensions)
+	}
+	if near, far := Cosine(vectors[0], vectors[1]), Cosine(vectors[0], vectors[2]); near <= far {
+		t.Errorf("similar texts score %v, unrelated ones %v", near, far)
+	}
+
+	if e, err := CreateEmbeddingsProvider(&config.Config{}, "local/hash-64"); err != nil || e.(*hashEmbeddings).dims != 64 {
+		t.Errorf("local/hash-64 = %v, %v", e, err)
+	}
+	if _, err := CreateEmbeddingsProvider(&config.Config{}, "local/bert"); err == nil {
+		t.Error("expected an error for an unknown local model")
+	}
+}
+
+func TestCreateEmbeddingsProvider_Gemini(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta/models/text-embedding-004:batchEmbedContents" || r.Header.Get("x-goog-api-key") != "key" {
+			t.Errorf("request %s with key %q", r.URL.Path, r.Header.Get("x-goog-api-key"))
+		}
+		var req struct {
+			Requests []struct {
+				Model string `json:"model"`
+			} `json:"requests"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if len(req.Requests) != 2 || req.Requests[0].Model != "models/text-embedding-004" {
+			t.Errorf("requests = %+v", req.Requests)
+		}
+		w.Write([]byte(`{"embeddings":[{"values":[1,0]},{"values":[0,1]}]}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{ModelList: []config.ModelConfig{{
+		ModelName: "embed",
+		Model:     "gemini/text-embedding-004",
+		APIBase:   server.URL + "/v1beta/openai",
+		APIKey:    "key",
+	}}}
+	e, err := CreateEmbeddingsProvider(cfg, "embed")
+	if err != nil {
+		t.Fatalf("CreateEmbeddingsProvider() error = %v", err)
+	}
+	vectors, err := e.Embed(t.Context(), []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	if len(vectors) != 2 || vectors[1][1] != 1 {
+		t.Errorf("vectors = %v", vectors)
+	}
+}
+
+func TestCreateEmbeddingsProvider_Unsupported(t *testing.T) {
+	cfg := &config.Config{ModelList: []config.ModelConfig{
+		{ModelName: "claude", Model: "anthropic/claude-sonnet-4.6", APIKey: "key"},
+		{ModelName: "cli", Model: "claude-cli/sonnet"},
+	}}
+	for _, model := range []string{"claude", "cli", "missing"} {
+		if _, err := CreateEmbeddingsProvider(cfg, model); err == nil {
+			t.Errorf("%s: expected an error", model)
+		}
+	}
+}
+
+func TestCosine(t *testing.T) {
+	if got := Cosine([]float32{1, 0}, []float32{1, 0}); got != 1 {
+		t.Errorf("identical = %v", got)
+	}
+	if got := Cosine([]float32{1, 0}, []float32{0, 1}); got != 0 {
+		t.Errorf("orthogonal = %v", got)
+	}
+	if got := Cosine([]float32{1}, []float32{1, 0}); got != 0 {
+		t.Errorf("different lengths = %v", got)
+	}
+}
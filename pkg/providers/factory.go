@@ -12,6 +12,15 @@ const defaultAnthropicAPIBase = "https://api.anthropic.com/v1"
 
 var getCredential = auth.GetCredential
 
+// loginHint returns the command that stores the credential of a provider
+// account.
+func loginHint(provider, account string) string {
+	if account == "" {
+		return "picoclaw auth login --provider " + provider
+	}
+	return "picoclaw auth login --provider " + provider + " --account " + account
+}
+
 type providerType int
 
 const (
@@ -35,6 +44,10 @@ type providerSelection struct {
 	enableWebSearch bool
 }
 
+// resolveProviderSelection picks a provider from the legacy providers
+// section. Only tests call it: CreateProvider goes through model_list, to
+// which config.ConvertProvidersToModelList moves the legacy providers,
+// keeping the configured model.
 func resolveProviderSelection(cfg *config.Config) (providerSelection, error) {
 	model := cfg.Agents.Defaults.Model
 	providerName := strings.ToLower(cfg.Agents.Defaults.Provider)
@@ -168,9 +181,6 @@ func resolveProviderSelection(cfg *config.Config) (providerSelection, error) {
 				if sel.apiBase == "" {
 					sel.apiBase = "https://api.deepseek.com/v1"
 				}
-				if model != "deepseek-chat" && model != "deepseek-reasoner" {
-					sel.model = "deepseek-chat"
-				}
 			}
 		case "github_copilot", "copilot":
 			sel.providerType = providerTypeGitHubCopilot
@@ -0,0 +1,24 @@
+//go:build !minimal && !standard
+
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+package providers
+
+import "github.com/sipeed/picoclaw/pkg/config"
+
+func init() {
+	registerOptionalProvider("github-copilot", func(cfg *config.ModelConfig, modelID string) (LLMProvider, error) {
+		apiBase := cfg.APIBase
+		if apiBase == "" {
+			apiBase = "localhost:4321"
+		}
+		connectMode := cfg.ConnectMode
+		if connectMode == "" {
+			connectMode = "grpc"
+		}
+		return NewGitHubCopilotProviderWithBridge(apiBase, connectMode, modelID, cfg.Bridge)
+	})
+}
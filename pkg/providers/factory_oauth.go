@@ -0,0 +1,57 @@
+//go:build !minimal
+
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+package providers
+
+import (
+	"fmt"
+
+	"github.com/sipeed/picoclaw/pkg/auth"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func init() {
+	registerOptionalProvider("anthropic-oauth", func(cfg *config.ModelConfig, modelID string) (LLMProvider, error) {
+		return createClaudeAuthProvider(cfg.Account)
+	})
+	registerOptionalProvider("openai-oauth", func(cfg *config.ModelConfig, modelID string) (LLMProvider, error) {
+		provider, err := createCodexAuthProvider(cfg.Account)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.BuiltinTools != nil {
+			provider.(*CodexProvider).builtinTools = cfg.BuiltinTools
+		}
+		return provider, nil
+	})
+}
+
+// createClaudeAuthProvider creates a Claude provider using OAuth credentials from auth store.
+// account selects a labelled account; empty means the default one.
+func createClaudeAuthProvider(account string) (LLMProvider, error) {
+	cred, err := getCredential(auth.AccountKey("anthropic", account))
+	if err != nil {
+		return nil, fmt.Errorf("loading auth credentials: %w", err)
+	}
+	if cred == nil {
+		return nil, fmt.Errorf("no credentials for anthropic. Run: %s", loginHint("anthropic", account))
+	}
+	return NewClaudeProviderWithTokenSource(cred.AccessToken, createClaudeTokenSource(account)), nil
+}
+
+// createCodexAuthProvider creates a Codex provider using OAuth credentials from auth store.
+// account selects a labelled account; empty means the default one.
+func createCodexAuthProvider(account string) (LLMProvider, error) {
+	cred, err := getCredential(auth.AccountKey("openai", account))
+	if err != nil {
+		return nil, fmt.Errorf("loading auth credentials: %w", err)
+	}
+	if cred == nil {
+		return nil, fmt.Errorf("no credentials for openai. Run: %s", loginHint("openai", account))
+	}
+	return NewCodexProviderWithTokenSource(cred.AccessToken, cred.AccountID, createCodexTokenSource(account)), nil
+}
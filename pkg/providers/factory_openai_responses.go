@@ -0,0 +1,16 @@
+//go:build !minimal
+
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+package providers
+
+import "github.com/sipeed/picoclaw/pkg/config"
+
+func init() {
+	registerOptionalProvider("openai-responses", func(cfg *config.ModelConfig, modelID string) (LLMProvider, error) {
+		return NewOpenAIResponsesProvider(cfg.APIKey, cfg.APIBase, cfg.Proxy, cfg.BuiltinTools), nil
+	})
+}
@@ -12,30 +12,6 @@ import (
 	"github.com/sipeed/picoclaw/pkg/config"
 )
 
-// createClaudeAuthProvider creates a Claude provider using OAuth credentials from auth store.
-func createClaudeAuthProvider() (LLMProvider, error) {
-	cred, err := getCredential("anthropic")
-	if err != nil {
-		return nil, fmt.Errorf("loading auth credentials: %w", err)
-	}
-	if cred == nil {
-		return nil, fmt.Errorf("no credentials for anthropic. Run: picoclaw auth login --provider anthropic")
-	}
-	return NewClaudeProviderWithTokenSource(cred.AccessToken, createClaudeTokenSource()), nil
-}
-
-// createCodexAuthProvider creates a Codex provider using OAuth credentials from auth store.
-func createCodexAuthProvider() (LLMProvider, error) {
-	cred, err := getCredential("openai")
-	if err != nil {
-		return nil, fmt.Errorf("loading auth credentials: %w", err)
-	}
-	if cred == nil {
-		return nil, fmt.Errorf("no credentials for openai. Run: picoclaw auth login --provider openai")
-	}
-	return NewCodexProviderWithTokenSource(cred.AccessToken, cred.AccountID, createCodexTokenSource()), nil
-}
-
 // ExtractProtocol extracts the protocol prefix and model identifier from a model string.
 // If no prefix is specified, it defaults to "openai".
 // Examples:
@@ -53,9 +29,45 @@ func ExtractProtocol(model string) (protocol, modelID string) {
 
 // CreateProviderFromConfig creates a provider based on the ModelConfig.
 // It uses the protocol prefix in the Model field to determine which provider to create.
-// Supported protocols: openai, anthropic, antigravity, claude-cli, codex-cli, github-copilot
+// Supported protocols: openai, anthropic, cohere, antigravity, claude-cli, codex-cli, github-copilot
+// and the OpenAI-compatible ones listed below, as well as the custom providers
+// provider plugins and providers registered by RegisterCustomProviders,
+// RegisterProviderPlugins and RegisterProvider. The entry is first passed
+// through the resolvers added by RegisterConfigResolver.
+// The provider is wrapped in the middleware the entry lists, in the
+// entry's rate limits, which all providers created for it share, and in its
+// timeouts.
 // Returns the provider, the model ID (without protocol prefix), and any error.
 func CreateProviderFromConfig(cfg *config.ModelConfig) (LLMProvider, string, error) {
+	cfg, err := resolveProviderConfig(cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	provider, modelID, err := createProviderFromConfig(cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	chain, err := resolveMiddleware(cfg.Middleware)
+	if err != nil {
+		return nil, "", err
+	}
+	var builtin []Middleware
+	if limiter := limiterFor(cfg); limiter != nil {
+		builtin = append(builtin, limiter.middleware)
+	}
+	if timeouts := timeoutsMiddleware(cfg); timeouts != nil {
+		builtin = append(builtin, timeouts)
+	}
+	wrapped := chainMiddleware(provider, append(chain, builtin...))
+	if _, ok := provider.(FileStore); ok && len(chain) == 0 && len(builtin) > 0 {
+		// Rate limits and timeouts only pace and bound chat requests, so
+		// uploads need not go through them.
+		wrapped = &fileStoreMiddlewareProvider{wrapped.(*middlewareProvider)}
+	}
+	return wrapped, modelID, nil
+}
+
+func createProviderFromConfig(cfg *config.ModelConfig) (LLMProvider, string, error) {
 	if cfg == nil {
 		return nil, "", fmt.Errorf("config is nil")
 	}
@@ -70,7 +82,7 @@ func CreateProviderFromConfig(cfg *config.ModelConfig) (LLMProvider, string, err
 	case "openai":
 		// OpenAI with OAuth/token auth (Codex-style)
 		if cfg.AuthMethod == "oauth" || cfg.AuthMethod == "token" {
-			provider, err := createCodexAuthProvider()
+			provider, err := createOptionalProvider("openai-oauth", cfg, modelID)
 			if err != nil {
 				return nil, "", err
 			}
@@ -80,15 +92,37 @@ func CreateProviderFromConfig(cfg *config.ModelConfig) (LLMProvider, string, err
 		if cfg.APIKey == "" && cfg.APIBase == "" {
 			return nil, "", fmt.Errorf("api_key or api_base is required for HTTP-based protocol %q", protocol)
 		}
+		if cfg.API == "responses" {
+			provider, err := createOptionalProvider("openai-responses", cfg, modelID)
+			if err != nil {
+				return nil, "", err
+			}
+			return provider, modelID, nil
+		}
 		apiBase := cfg.APIBase
 		if apiBase == "" {
 			apiBase = getDefaultAPIBase(protocol)
 		}
 		return NewHTTPProviderWithMaxTokensField(cfg.APIKey, apiBase, cfg.Proxy, cfg.MaxTokensField), modelID, nil
 
-	case "openrouter", "groq", "zhipu", "gemini", "nvidia",
+	case "gemini":
+		// The OpenAI-compatible endpoint stays available for entries
+		// that point at it.
+		if strings.HasSuffix(strings.TrimRight(cfg.APIBase, "/"), "/openai") {
+			return NewHTTPProviderWithMaxTokensField(cfg.APIKey, cfg.APIBase, cfg.Proxy, cfg.MaxTokensField), modelID, nil
+		}
+		if cfg.APIKey == "" {
+			return nil, "", fmt.Errorf("api_key is required for gemini protocol (model: %s)", cfg.Model)
+		}
+		provider, err := NewGeminiProvider(cfg.APIKey, cfg.APIBase, cfg.Proxy, cfg.SafetySettings)
+		if err != nil {
+			return nil, "", err
+		}
+		return provider, modelID, nil
+
+	case "openrouter", "groq", "zhipu", "nvidia",
 		"ollama", "moonshot", "shengsuanyun", "deepseek", "cerebras",
-		"volcengine", "vllm", "qwen":
+		"volcengine", "vllm", "qwen", "mistral", "xai":
 		// All other OpenAI-compatible HTTP providers
 		if cfg.APIKey == "" && cfg.APIBase == "" {
 			return nil, "", fmt.Errorf("api_key or api_base is required for HTTP-based protocol %q", protocol)
@@ -102,7 +136,7 @@ func CreateProviderFromConfig(cfg *config.ModelConfig) (LLMProvider, string, err
 	case "anthropic":
 		if cfg.AuthMethod == "oauth" || cfg.AuthMethod == "token" {
 			// Use OAuth credentials from auth store
-			provider, err := createClaudeAuthProvider()
+			provider, err := createOptionalProvider("anthropic-oauth", cfg, modelID)
 			if err != nil {
 				return nil, "", err
 			}
@@ -118,8 +152,23 @@ func CreateProviderFromConfig(cfg *config.ModelConfig) (LLMProvider, string, err
 		}
 		return NewHTTPProviderWithMaxTokensField(cfg.APIKey, apiBase, cfg.Proxy, cfg.MaxTokensField), modelID, nil
 
+	case "lmstudio", "llamacpp":
+		// Local servers need no API key.
+		server, _ := findLocalServer(protocol)
+		apiBase := cfg.APIBase
+		if apiBase == "" {
+			apiBase = server.apiBase
+		}
+		return newLocalServerProvider(server, cfg.APIKey, apiBase, cfg.Proxy, cfg.MaxTokensField), modelID, nil
+
+	case "cohere":
+		if cfg.APIKey == "" {
+			return nil, "", fmt.Errorf("api_key is required for cohere protocol (model: %s)", cfg.Model)
+		}
+		return NewCohereProvider(cfg.APIKey, cfg.APIBase, cfg.Proxy), modelID, nil
+
 	case "antigravity":
-		return NewAntigravityProvider(), modelID, nil
+		return newAntigravityProvider(cfg.Account), modelID, nil
 
 	case "claude-cli", "claudecli":
 		workspace := cfg.Workspace
@@ -136,21 +185,30 @@ func CreateProviderFromConfig(cfg *config.ModelConfig) (LLMProvider, string, err
 		return NewCodexCliProvider(workspace), modelID, nil
 
 	case "github-copilot", "copilot":
-		apiBase := cfg.APIBase
-		if apiBase == "" {
-			apiBase = "localhost:4321"
-		}
-		connectMode := cfg.ConnectMode
-		if connectMode == "" {
-			connectMode = "grpc"
-		}
-		provider, err := NewGitHubCopilotProvider(apiBase, connectMode, modelID)
+		provider, err := createOptionalProvider("github-copilot", cfg, modelID)
 		if err != nil {
 			return nil, "", err
 		}
 		return provider, modelID, nil
 
 	default:
+		if factory, ok := findRegisteredProvider(protocol); ok {
+			provider, err := factory(cfg, modelID)
+			if err != nil {
+				return nil, "", err
+			}
+			return provider, modelID, nil
+		}
+		if custom, ok := findCustomProvider(protocol); ok {
+			provider, err := newCustomProvider(custom, cfg)
+			if err != nil {
+				return nil, "", err
+			}
+			return provider, custom.ModelPrefix + modelID, nil
+		}
+		if plugin, ok := findProviderPlugin(protocol); ok {
+			return newPluginProvider(plugin, cfg), modelID, nil
+		}
 		return nil, "", fmt.Errorf("unknown protocol %q in model %q", protocol, cfg.Model)
 	}
 }
@@ -186,6 +244,10 @@ func getDefaultAPIBase(protocol string) string {
 		return "https://dashscope.aliyuncs.com/compatible-mode/v1"
 	case "vllm":
 		return "http://localhost:8000/v1"
+	case "mistral":
+		return "https://api.mistral.ai/v1"
+	case "xai":
+		return "https://api.x.ai/v1"
 	default:
 		return ""
 	}
@@ -108,6 +108,8 @@ func TestCreateProviderFromConfig_DefaultAPIBase(t *testing.T) {
 		{"vllm", "vllm"},
 		{"deepseek", "deepseek"},
 		{"ollama", "ollama"},
+		{"mistral", "mistral"},
+		{"xai", "xai"},
 	}
 
 	for _, tt := range tests {
@@ -150,6 +152,25 @@ func TestCreateProviderFromConfig_Anthropic(t *testing.T) {
 	}
 }
 
+func TestCreateProviderFromConfig_Cohere(t *testing.T) {
+	cfg := &config.ModelConfig{
+		ModelName: "test-cohere",
+		Model:     "cohere/command-a-03-2025",
+		APIKey:    "test-key",
+	}
+
+	provider, modelID, err := CreateProviderFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("CreateProviderFromConfig() error = %v", err)
+	}
+	if _, ok := provider.(*CohereProvider); !ok {
+		t.Fatalf("expected *CohereProvider, got %T", provider)
+	}
+	if modelID != "command-a-03-2025" {
+		t.Errorf("modelID = %q, want %q", modelID, "command-a-03-2025")
+	}
+}
+
 func TestCreateProviderFromConfig_Antigravity(t *testing.T) {
 	cfg := &config.ModelConfig{
 		ModelName: "test-antigravity",
@@ -247,3 +268,55 @@ func TestCreateProviderFromConfig_EmptyModel(t *testing.T) {
 		t.Fatal("CreateProviderFromConfig() expected error for empty model")
 	}
 }
+
+func TestCreateProviderFromConfig_LocalServerNeedsNoKey(t *testing.T) {
+	for _, protocol := range []string{"lmstudio", "llamacpp"} {
+		cfg := &config.ModelConfig{ModelName: "local", Model: protocol + "/qwen3-8b"}
+		provider, modelID, err := CreateProviderFromConfig(cfg)
+		if err != nil {
+			t.Fatalf("%s: CreateProviderFromConfig() error = %v", protocol, err)
+		}
+		if _, ok := provider.(*LocalServerProvider); !ok {
+			t.Errorf("%s: expected *LocalServerProvider, got %T", protocol, provider)
+		}
+		if modelID != "qwen3-8b" {
+			t.Errorf("%s: modelID = %q", protocol, modelID)
+		}
+	}
+}
+
+func TestCreateProviderFromConfig_Gemini(t *testing.T) {
+	provider, modelID, err := CreateProviderFromConfig(&config.ModelConfig{
+		ModelName: "flash",
+		Model:     "gemini/gemini-2.5-flash",
+		APIKey:    "key",
+	})
+	if err != nil {
+		t.Fatalf("CreateProviderFromConfig() error = %v", err)
+	}
+	if _, ok := provider.(*GeminiProvider); !ok || modelID != "gemini-2.5-flash" {
+		t.Errorf("provider = %T, model = %q; want the native Gemini provider", provider, modelID)
+	}
+
+	provider, _, err = CreateProviderFromConfig(&config.ModelConfig{
+		ModelName: "flash-compat",
+		Model:     "gemini/gemini-2.5-flash",
+		APIKey:    "key",
+		APIBase:   "https://generativelanguage.googleapis.com/v1beta/openai/",
+	})
+	if err != nil {
+		t.Fatalf("CreateProviderFromConfig() error = %v", err)
+	}
+	if _, ok := provider.(*HTTPProvider); !ok {
+		t.Errorf("provider = %T; want the OpenAI-compatible provider for the openai endpoint", provider)
+	}
+
+	if _, _, err := CreateProviderFromConfig(&config.ModelConfig{
+		ModelName:      "flash",
+		Model:          "gemini/gemini-2.5-flash",
+		APIKey:         "key",
+		SafetySettings: map[string]string{"violence": "none"},
+	}); err == nil {
+		t.Error("unknown safety category accepted")
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"strings"
 	"testing"
 
-	"github.com/sipeed/picoclaw/pkg/auth"
 	"github.com/sipeed/picoclaw/pkg/config"
 )
 
@@ -15,6 +14,7 @@ func TestResolveProviderSelection(t *testing.T) {
 		wantType      providerType
 		wantAPIBase   string
 		wantProxy     string
+		wantModel     string
 		wantErrSubstr string
 	}{
 		{
@@ -45,6 +45,16 @@ func TestResolveProviderSelection(t *testing.T) {
 			wantAPIBase: "https://api.deepseek.com/v1",
 			wantProxy:   "http://127.0.0.1:7890",
 		},
+		{
+			name: "explicit deepseek provider keeps the configured model",
+			setup: func(cfg *config.Config) {
+				cfg.Agents.Defaults.Provider = "deepseek"
+				cfg.Agents.Defaults.Model = "deepseek-v4"
+				cfg.Providers.DeepSeek.APIKey = "deepseek-key"
+			},
+			wantType:  providerTypeHTTPCompat,
+			wantModel: "deepseek-v4",
+		},
 		{
 			name: "explicit shengsuanyun provider uses defaults",
 			setup: func(cfg *config.Config) {
@@ -190,6 +200,9 @@ func TestResolveProviderSelection(t *testing.T) {
 			if tt.wantProxy != "" && got.proxy != tt.wantProxy {
 				t.Fatalf("proxy = %q, want %q", got.proxy, tt.wantProxy)
 			}
+			if tt.wantModel != "" && got.model != tt.wantModel {
+				t.Fatalf("model = %q, want %q", got.model, tt.wantModel)
+			}
 		})
 	}
 }
@@ -258,42 +271,30 @@ func TestCreateProviderReturnsClaudeCliProviderForClaudeCli(t *testing.T) {
 	}
 }
 
-func TestCreateProviderReturnsClaudeProviderForAnthropicOAuth(t *testing.T) {
-	originalGetCredential := getCredential
-	t.Cleanup(func() { getCredential = originalGetCredential })
-
-	getCredential = func(provider string) (*auth.AuthCredential, error) {
-		if provider != "anthropic" {
-			t.Fatalf("provider = %q, want anthropic", provider)
-		}
-		return &auth.AuthCredential{
-			AccessToken: "anthropic-token",
-		}, nil
-	}
+func TestCreateProviderReturnsCodexProviderForOpenAIOAuth(t *testing.T) {
+	// TODO: This test requires openai protocol to support auth_method: "oauth"
+	// which is not yet implemented in the new factory_provider.go
+	t.Skip("OpenAI OAuth via model_list not yet implemented")
+}
 
+func TestCreateProviderValidatesModels(t *testing.T) {
 	cfg := config.DefaultConfig()
-	cfg.Agents.Defaults.Model = "test-claude-oauth"
+	cfg.Agents.Defaults.Model = "typo"
 	cfg.ModelList = []config.ModelConfig{
-		{
-			ModelName:  "test-claude-oauth",
-			Model:      "anthropic/claude-sonnet-4.6",
-			AuthMethod: "oauth",
-		},
+		{ModelName: "typo", Model: "deepseek/deepseek-chta", APIKey: "sk-test"},
+		{ModelName: "local", Model: "ollama/qwen3", APIBase: "http://localhost:11434/v1"},
 	}
 
-	provider, _, err := CreateProvider(cfg)
-	if err != nil {
-		t.Fatalf("CreateProvider() error = %v", err)
+	if _, modelID, err := CreateProvider(cfg); err != nil || modelID != "deepseek-chta" {
+		t.Fatalf("CreateProvider() = %q, %v; want the unknown model used as is", modelID, err)
 	}
 
-	if _, ok := provider.(*ClaudeProvider); !ok {
-		t.Fatalf("provider type = %T, want *ClaudeProvider", provider)
+	cfg.Agents.Defaults.StrictModels = true
+	if _, _, err := CreateProvider(cfg); err == nil || !strings.Contains(err.Error(), "not a known deepseek model") {
+		t.Fatalf("CreateProvider() error = %v, want an unknown model error", err)
+	}
+	cfg.Agents.Defaults.Model = "local"
+	if _, _, err := CreateProvider(cfg); err != nil {
+		t.Fatalf("CreateProvider() error = %v; providers without catalog models should pass", err)
 	}
-	// TODO: Test custom APIBase when createClaudeAuthProvider supports it
-}
-
-func TestCreateProviderReturnsCodexProviderForOpenAIOAuth(t *testing.T) {
-	// TODO: This test requires openai protocol to support auth_method: "oauth"
-	// which is not yet implemented in the new factory_provider.go
-	t.Skip("OpenAI OAuth via model_list not yet implemented")
 }
@@ -0,0 +1,657 @@
+// Package geminiprovider implements the Gemini API's generateContent and
+// streamGenerateContent, which keep features the OpenAI-compatible
+// endpoint drops: system instructions, thought signatures of tool calls,
+// inline media and safety settings.
+package geminiprovider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/egress"
+	"github.com/sipeed/picoclaw/pkg/providers/httpcapture"
+	"github.com/sipeed/picoclaw/pkg/providers/httpretry"
+	"github.com/sipeed/picoclaw/pkg/providers/httptimeout"
+	"github.com/sipeed/picoclaw/pkg/providers/httpwarm"
+	"github.com/sipeed/picoclaw/pkg/providers/protocoltypes"
+	"github.com/sipeed/picoclaw/pkg/providers/sse"
+)
+
+type ToolCall = protocoltypes.ToolCall
+type FunctionCall = protocoltypes.FunctionCall
+type LLMResponse = protocoltypes.LLMResponse
+type UsageInfo = protocoltypes.UsageInfo
+type Message = protocoltypes.Message
+type ToolDefinition = protocoltypes.ToolDefinition
+type StreamEvent = protocoltypes.StreamEvent
+
+const DefaultAPIBase = "https://generativelanguage.googleapis.com/v1beta"
+
+// harmCategories maps the names of safety_settings keys to the API's harm
+// categories.
+var harmCategories = map[string]string{
+	"harassment":        "HARM_CATEGORY_HARASSMENT",
+	"hate_speech":       "HARM_CATEGORY_HATE_SPEECH",
+	"sexually_explicit": "HARM_CATEGORY_SEXUALLY_EXPLICIT",
+	"dangerous_content": "HARM_CATEGORY_DANGEROUS_CONTENT",
+	"civic_integrity":   "HARM_CATEGORY_CIVIC_INTEGRITY",
+}
+
+// blockThresholds maps the values of safety_settings to the API's block
+// thresholds.
+var blockThresholds = map[string]string{
+	"off":              "OFF",
+	"none":             "BLOCK_NONE",
+	"only_high":        "BLOCK_ONLY_HIGH",
+	"medium_and_above": "BLOCK_MEDIUM_AND_ABOVE",
+	"low_and_above":    "BLOCK_LOW_AND_ABOVE",
+}
+
+type Provider struct {
+	apiKey     string
+	apiBase    string
+	safety     []safetySetting
+	httpClient *http.Client
+}
+
+// NewProvider returns a provider for the API at apiBase. safety maps harm
+// categories ("harassment", "hate_speech", "sexually_explicit",
+// "dangerous_content", "civic_integrity", or "all") to the threshold at
+// which replies are blocked ("off", "none", "only_high",
+// "medium_and_above", "low_and_above"); unset categories keep the API's
+// default.
+func NewProvider(apiKey, apiBase, proxy string, safety map[string]string) (*Provider, error) {
+	settings, err := safetySettings(safety)
+	if err != nil {
+		return nil, err
+	}
+	if apiBase == "" {
+		apiBase = DefaultAPIBase
+	}
+
+	client := &http.Client{}
+	if proxy != "" {
+		parsed, err := url.Parse(proxy)
+		if err == nil {
+			client.Transport = &http.Transport{
+				Proxy: http.ProxyURL(parsed),
+			}
+		} else {
+			log.Printf("gemini: invalid proxy URL %q: %v", proxy, err)
+		}
+	}
+	client.Transport = httpretry.Transport(httpcapture.Wrap(httptimeout.Transport(egress.Transport(egress.Providers, httpwarm.Transport(client.Transport)))))
+
+	return &Provider{
+		apiKey:     apiKey,
+		apiBase:    strings.TrimRight(apiBase, "/"),
+		safety:     settings,
+		httpClient: client,
+	}, nil
+}
+
+// safetySettings converts the safety_settings of a model entry.
+func safetySettings(safety map[string]string) ([]safetySetting, error) {
+	thresholds := map[string]string{}
+	if level, ok := safety["all"]; ok {
+		threshold, ok := blockThresholds[strings.ToLower(level)]
+		if !ok {
+			return nil, fmt.Errorf("gemini: unknown safety threshold %q", level)
+		}
+		for _, category := range harmCategories {
+			thresholds[category] = threshold
+		}
+	}
+	for name, level := range safety {
+		if name == "all" {
+			continue
+		}
+		category, ok := harmCategories[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("gemini: unknown safety category %q", name)
+		}
+		threshold, ok := blockThresholds[strings.ToLower(level)]
+		if !ok {
+			return nil, fmt.Errorf("gemini: unknown safety threshold %q", level)
+		}
+		thresholds[category] = threshold
+	}
+	settings := make([]safetySetting, 0, len(thresholds))
+	for category, threshold := range thresholds {
+		settings = append(settings, safetySetting{Category: category, Threshold: threshold})
+	}
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Category < settings[j].Category })
+	return settings, nil
+}
+
+// Warm opens a connection to the API host ahead of the first request.
+func (p *Provider) Warm(ctx context.Context) error {
+	return httpwarm.Warm(ctx, p.httpClient, p.apiBase)
+}
+
+func (p *Provider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	resp, err := p.post(ctx, model, "generateContent", messages, tools, options)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	var apiResponse response
+	if err := json.Unmarshal(body, &apiResponse); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	var acc accumulator
+	if err := acc.add(apiResponse, nil); err != nil {
+		return nil, err
+	}
+	return acc.response(), nil
+}
+
+// ChatStream is Chat with the reply streamed through onEvent.
+func (p *Provider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onEvent func(StreamEvent)) (*LLMResponse, error) {
+	resp, err := p.post(ctx, model, "streamGenerateContent?alt=sse", messages, tools, options)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var acc accumulator
+	events := sse.NewReader(resp.Body)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		data, err := events.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stream: %w", err)
+		}
+		var chunk response
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal stream event: %w", err)
+		}
+		if err := acc.add(chunk, onEvent); err != nil {
+			return nil, err
+		}
+	}
+	return acc.response(), nil
+}
+
+// post sends the request to the model's method and returns the response,
+// or an error if it failed.
+func (p *Provider) post(ctx context.Context, model, method string, messages []Message, tools []ToolDefinition, options map[string]interface{}) (*http.Response, error) {
+	jsonData, err := json.Marshal(p.buildRequest(messages, tools, options))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	endpoint := fmt.Sprintf("%s/models/%s:%s", p.apiBase, strings.TrimPrefix(model, "models/"), method)
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", p.apiKey)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("API request failed:\n  Status: %d\n  Body:   %s", resp.StatusCode, string(body))
+	}
+	return resp, nil
+}
+
+// ListModels returns the models that can generate content.
+func (p *Provider) ListModels(ctx context.Context) ([]string, error) {
+	var models []string
+	pageToken := ""
+	for {
+		endpoint := p.apiBase + "/models?pageSize=1000"
+		if pageToken != "" {
+			endpoint += "&pageToken=" + url.QueryEscape(pageToken)
+		}
+		req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("x-goog-api-key", p.apiKey)
+		resp, err := p.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("API request failed:\n  Status: %d\n  Body:   %s", resp.StatusCode, string(body))
+		}
+		var page struct {
+			Models []struct {
+				Name    string   `json:"name"`
+				Methods []string `json:"supportedGenerationMethods"`
+			} `json:"models"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, err
+		}
+		for _, m := range page.Models {
+			for _, method := range m.Methods {
+				if method == "generateContent" {
+					models = append(models, strings.TrimPrefix(m.Name, "models/"))
+					break
+				}
+			}
+		}
+		if page.NextPageToken == "" {
+			return models, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// --- Request building ---
+
+type request struct {
+	Contents          []content        `json:"contents"`
+	SystemInstruction *content         `json:"systemInstruction,omitempty"`
+	Tools             []tool           `json:"tools,omitempty"`
+	SafetySettings    []safetySetting  `json:"safetySettings,omitempty"`
+	GenerationConfig  generationConfig `json:"generationConfig"`
+}
+
+type content struct {
+	Role  string `json:"role,omitempty"`
+	Parts []part `json:"parts"`
+}
+
+type part struct {
+	Text             string            `json:"text,omitempty"`
+	Thought          bool              `json:"thought,omitempty"`
+	ThoughtSignature string            `json:"thoughtSignature,omitempty"`
+	InlineData       *blob             `json:"inlineData,omitempty"`
+	FileData         *fileData         `json:"fileData,omitempty"`
+	FunctionCall     *functionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *functionResponse `json:"functionResponse,omitempty"`
+}
+
+type blob struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type fileData struct {
+	MimeType string `json:"mimeType,omitempty"`
+	FileURI  string `json:"fileUri"`
+}
+
+type functionCall struct {
+	ID   string                 `json:"id,omitempty"`
+	Name string                 `json:"name"`
+	Args map[string]interface{} `json:"args"`
+}
+
+type functionResponse struct {
+	ID       string                 `json:"id,omitempty"`
+	Name     string                 `json:"name"`
+	Response map[string]interface{} `json:"response"`
+}
+
+type tool struct {
+	FunctionDeclarations []functionDeclaration `json:"functionDeclarations"`
+}
+
+type functionDeclaration struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+type safetySetting struct {
+	Category  string `json:"category"`
+	Threshold string `json:"threshold"`
+}
+
+type generationConfig struct {
+	MaxOutputTokens int      `json:"maxOutputTokens,omitempty"`
+	Temperature     *float64 `json:"temperature,omitempty"`
+	Seed            *int     `json:"seed,omitempty"`
+}
+
+// buildRequest converts messages to contents. System messages become the
+// system instruction, assistant messages model turns with their tool
+// calls, and tool results user turns of function responses, one for each
+// run of results.
+func (p *Provider) buildRequest(messages []Message, tools []ToolDefinition, options map[string]interface{}) request {
+	req := request{SafetySettings: p.safety}
+	callNames := map[string]string{}
+	var system []part
+
+	for _, m := range messages {
+		switch m.Role {
+		case "system":
+			system = append(system, part{Text: m.Content})
+		case "assistant":
+			c := content{Role: "model"}
+			if m.Content != "" {
+				c.Parts = append(c.Parts, part{Text: m.Content})
+			}
+			for _, tc := range m.ToolCalls {
+				name, args, signature := storedCall(tc)
+				if name == "" {
+					continue
+				}
+				callNames[tc.ID] = name
+				c.Parts = append(c.Parts, part{
+					ThoughtSignature: signature,
+					FunctionCall:     &functionCall{Name: name, Args: args},
+				})
+			}
+			if len(c.Parts) > 0 {
+				req.Contents = append(req.Contents, c)
+			}
+		case "tool":
+			name := callNames[m.ToolCallID]
+			if name == "" {
+				name = nameFromCallID(m.ToolCallID)
+			}
+			response := part{FunctionResponse: &functionResponse{
+				Name:     name,
+				Response: map[string]interface{}{"result": m.Content},
+			}}
+			if n := len(req.Contents); n > 0 && isFunctionResponses(req.Contents[n-1]) {
+				req.Contents[n-1].Parts = append(req.Contents[n-1].Parts, response)
+			} else {
+				req.Contents = append(req.Contents, content{Role: "user", Parts: []part{response}})
+			}
+		default:
+			var parts []part
+			if m.Content != "" {
+				parts = append(parts, part{Text: m.Content})
+			}
+			for _, img := range m.Images {
+				if img.URL != "" {
+					parts = append(parts, part{FileData: &fileData{MimeType: img.MimeType, FileURI: img.URL}})
+				} else {
+					parts = append(parts, part{InlineData: &blob{MimeType: img.MimeType, Data: img.Data}})
+				}
+			}
+			if len(parts) == 0 {
+				parts = []part{{Text: " "}}
+			}
+			req.Contents = append(req.Contents, content{Role: "user", Parts: parts})
+		}
+	}
+	if len(system) > 0 {
+		req.SystemInstruction = &content{Parts: system}
+	}
+
+	var decls []functionDeclaration
+	for _, t := range tools {
+		if t.Type != "function" {
+			continue
+		}
+		decls = append(decls, functionDeclaration{
+			Name:        t.Function.Name,
+			Description: t.Function.Description,
+			Parameters:  SanitizeSchema(t.Function.Parameters),
+		})
+	}
+	if len(decls) > 0 {
+		req.Tools = []tool{{FunctionDeclarations: decls}}
+	}
+
+	switch maxTokens := options["max_tokens"].(type) {
+	case int:
+		req.GenerationConfig.MaxOutputTokens = maxTokens
+	case float64:
+		req.GenerationConfig.MaxOutputTokens = int(maxTokens)
+	}
+	if temperature, ok := options["temperature"].(float64); ok {
+		req.GenerationConfig.Temperature = &temperature
+	}
+	if seed, ok := options["seed"].(int); ok {
+		req.GenerationConfig.Seed = &seed
+	}
+	return req
+}
+
+// nameFromCallID returns the function name in a tool call ID made by
+// add, "call_<name>_<n>", for results whose call is no longer in the
+// history.
+func nameFromCallID(id string) string {
+	rest, ok := strings.CutPrefix(id, "call_")
+	if i := strings.LastIndex(rest, "_"); ok && i > 0 {
+		return rest[:i]
+	}
+	return id
+}
+
+func isFunctionResponses(c content) bool {
+	return c.Role == "user" && len(c.Parts) > 0 && c.Parts[0].FunctionResponse != nil
+}
+
+// storedCall returns the name, arguments and thought signature of a tool
+// call from the history.
+func storedCall(tc ToolCall) (string, map[string]interface{}, string) {
+	name, args, signature := tc.Name, tc.Arguments, tc.ThoughtSignature
+	if tc.Function != nil {
+		if name == "" {
+			name = tc.Function.Name
+		}
+		if signature == "" {
+			signature = tc.Function.ThoughtSignature
+		}
+		if len(args) == 0 && tc.Function.Arguments != "" {
+			json.Unmarshal([]byte(tc.Function.Arguments), &args)
+		}
+	}
+	if signature == "" && tc.ExtraContent != nil && tc.ExtraContent.Google != nil {
+		signature = tc.ExtraContent.Google.ThoughtSignature
+	}
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	return name, args, signature
+}
+
+// unsupportedKeywords are the JSON Schema keywords the API rejects in
+// function parameters.
+var unsupportedKeywords = map[string]bool{
+	"patternProperties":    true,
+	"additionalProperties": true,
+	"$schema":              true,
+	"$id":                  true,
+	"$ref":                 true,
+	"$defs":                true,
+	"definitions":          true,
+	"examples":             true,
+	"minLength":            true,
+	"maxLength":            true,
+	"minimum":              true,
+	"maximum":              true,
+	"multipleOf":           true,
+	"pattern":              true,
+	"format":               true,
+	"minItems":             true,
+	"maxItems":             true,
+	"uniqueItems":          true,
+	"minProperties":        true,
+	"maxProperties":        true,
+}
+
+// SanitizeSchema returns schema without the keywords Gemini does not
+// accept, with type "object" added where properties lack a type.
+func SanitizeSchema(schema map[string]interface{}) map[string]interface{} {
+	if schema == nil {
+		return nil
+	}
+
+	result := make(map[string]interface{})
+	for k, v := range schema {
+		if unsupportedKeywords[k] {
+			continue
+		}
+		switch val := v.(type) {
+		case map[string]interface{}:
+			result[k] = SanitizeSchema(val)
+		case []interface{}:
+			sanitized := make([]interface{}, len(val))
+			for i, item := range val {
+				if m, ok := item.(map[string]interface{}); ok {
+					sanitized[i] = SanitizeSchema(m)
+				} else {
+					sanitized[i] = item
+				}
+			}
+			result[k] = sanitized
+		default:
+			result[k] = v
+		}
+	}
+
+	if _, hasProps := result["properties"]; hasProps {
+		if _, hasType := result["type"]; !hasType {
+			result["type"] = "object"
+		}
+	}
+	return result
+}
+
+// --- Response parsing ---
+
+type response struct {
+	Candidates []struct {
+		Content      content `json:"content"`
+		FinishReason string  `json:"finishReason"`
+	} `json:"candidates"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
+	UsageMetadata *struct {
+		PromptTokenCount        int `json:"promptTokenCount"`
+		CandidatesTokenCount    int `json:"candidatesTokenCount"`
+		ThoughtsTokenCount      int `json:"thoughtsTokenCount"`
+		TotalTokenCount         int `json:"totalTokenCount"`
+		CachedContentTokenCount int `json:"cachedContentTokenCount"`
+	} `json:"usageMetadata"`
+	ModelVersion string `json:"modelVersion"`
+}
+
+// accumulator builds a response from one or more responses, the chunks of
+// a stream.
+type accumulator struct {
+	content      strings.Builder
+	toolCalls    []ToolCall
+	finishReason string
+	usage        *UsageInfo
+	model        string
+}
+
+// add adds a response, passing its text and tool calls to onEvent if it
+// is set. Thoughts are left out. A blocked prompt is an error.
+func (a *accumulator) add(r response, onEvent func(StreamEvent)) error {
+	if r.PromptFeedback != nil && r.PromptFeedback.BlockReason != "" {
+		return fmt.Errorf("gemini: prompt blocked: %s", r.PromptFeedback.BlockReason)
+	}
+	if r.ModelVersion != "" {
+		a.model = r.ModelVersion
+	}
+	if u := r.UsageMetadata; u != nil {
+		a.usage = &UsageInfo{
+			PromptTokens:     u.PromptTokenCount,
+			CompletionTokens: u.CandidatesTokenCount + u.ThoughtsTokenCount,
+			TotalTokens:      u.TotalTokenCount,
+			CachedTokens:     u.CachedContentTokenCount,
+		}
+	}
+	if len(r.Candidates) == 0 {
+		return nil
+	}
+	candidate := r.Candidates[0]
+	for _, p := range candidate.Content.Parts {
+		switch {
+		case p.Thought:
+		case p.FunctionCall != nil:
+			args := p.FunctionCall.Args
+			if args == nil {
+				args = map[string]interface{}{}
+			}
+			id := p.FunctionCall.ID
+			if id == "" {
+				id = fmt.Sprintf("call_%s_%d", p.FunctionCall.Name, len(a.toolCalls))
+			}
+			arguments, _ := json.Marshal(args)
+			tc := ToolCall{
+				ID:               id,
+				Type:             "function",
+				Name:             p.FunctionCall.Name,
+				Arguments:        args,
+				ThoughtSignature: p.ThoughtSignature,
+				Function: &FunctionCall{
+					Name:             p.FunctionCall.Name,
+					Arguments:        string(arguments),
+					ThoughtSignature: p.ThoughtSignature,
+				},
+			}
+			a.toolCalls = append(a.toolCalls, tc)
+			if onEvent != nil {
+				onEvent(StreamEvent{ToolCall: &tc})
+			}
+		case p.Text != "":
+			a.content.WriteString(p.Text)
+			if onEvent != nil {
+				onEvent(StreamEvent{Text: p.Text})
+			}
+		}
+	}
+	if candidate.FinishReason != "" {
+		a.finishReason = candidate.FinishReason
+	}
+	return nil
+}
+
+func (a *accumulator) response() *LLMResponse {
+	return &LLMResponse{
+		Content:      a.content.String(),
+		ToolCalls:    a.toolCalls,
+		FinishReason: finishReason(a.finishReason, len(a.toolCalls) > 0),
+		Usage:        a.usage,
+		Model:        a.model,
+	}
+}
+
+// finishReason maps Gemini's finish reasons to the OpenAI ones the agent
+// checks for.
+func finishReason(reason string, toolCalls bool) string {
+	switch reason {
+	case "MAX_TOKENS":
+		return "length"
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII", "IMAGE_SAFETY":
+		return "content_filter"
+	}
+	if toolCalls {
+		return "tool_calls"
+	}
+	return "stop"
+}
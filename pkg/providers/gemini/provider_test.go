@@ -0,0 +1,145 @@
+package geminiprovider
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/providers/protocoltypes"
+)
+
+func TestProviderChat_SendsNativeRequest(t *testing.T) {
+	var requestBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/gemini-2.5-flash:generateContent" || r.Header.Get("x-goog-api-key") != "key" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&requestBody)
+		w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"thinking...","thought":true},{"text":"It is noon."}]},` +
+			`"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":20,"candidatesTokenCount":4,"thoughtsTokenCount":6,"totalTokenCount":30},` +
+			`"modelVersion":"gemini-2.5-flash-001"}`))
+	}))
+	defer server.Close()
+
+	messages := []Message{
+		{Role: "system", Content: "be brief"},
+		{Role: "user", Content: "what time is it here and in Tokyo?", Images: []protocoltypes.Image{{Data: "aGk=", MimeType: "image/png"}}},
+		{Role: "assistant", ToolCalls: []ToolCall{
+			{ID: "call_time_0", Name: "time", Arguments: map[string]interface{}{"tz": "UTC"}, Function: &FunctionCall{Name: "time", ThoughtSignature: "sig"}},
+			{ID: "call_time_1", Name: "time", Arguments: map[string]interface{}{"tz": "Asia/Tokyo"}},
+		}},
+		{Role: "tool", ToolCallID: "call_time_0", Content: "12:00"},
+		{Role: "tool", ToolCallID: "call_time_1", Content: "21:00"},
+	}
+	tools := []ToolDefinition{{Type: "function", Function: protocoltypes.ToolFunctionDefinition{
+		Name:       "time",
+		Parameters: map[string]interface{}{"properties": map[string]interface{}{"tz": map[string]interface{}{"type": "string", "format": "tz"}}},
+	}}}
+
+	p, err := NewProvider("key", server.URL, "", map[string]string{"all": "only_high", "harassment": "none"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := p.Chat(t.Context(), messages, tools, "gemini-2.5-flash", map[string]interface{}{"max_tokens": 100, "temperature": 0.0, "seed": 7})
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if resp.Content != "It is noon." || resp.FinishReason != "stop" || resp.Model != "gemini-2.5-flash-001" {
+		t.Errorf("response = %+v", resp)
+	}
+	if resp.Usage == nil || resp.Usage.CompletionTokens != 10 || resp.Usage.TotalTokens != 30 {
+		t.Errorf("usage = %+v", resp.Usage)
+	}
+
+	data, _ := json.Marshal(requestBody)
+	sent := string(data)
+	for _, want := range []string{
+		`"systemInstruction":{"parts":[{"text":"be brief"}]}`,
+		`"inlineData":{"data":"aGk=","mimeType":"image/png"}`,
+		`"thoughtSignature":"sig"`,
+		`"generationConfig":{"maxOutputTokens":100,"seed":7,"temperature":0}`,
+		`{"category":"HARM_CATEGORY_HARASSMENT","threshold":"BLOCK_NONE"}`,
+		`{"category":"HARM_CATEGORY_HATE_SPEECH","threshold":"BLOCK_ONLY_HIGH"}`,
+		`"parameters":{"properties":{"tz":{"type":"string"}},"type":"object"}`,
+	} {
+		if !strings.Contains(sent, want) {
+			t.Errorf("request lacks %s:\n%s", want, sent)
+		}
+	}
+	contents := requestBody["contents"].([]interface{})
+	if len(contents) != 3 {
+		t.Fatalf("contents = %d, want user, model and one turn of both function responses", len(contents))
+	}
+	responses := contents[2].(map[string]interface{})["parts"].([]interface{})
+	second := responses[1].(map[string]interface{})["functionResponse"].(map[string]interface{})
+	if len(responses) != 2 || second["name"] != "time" {
+		t.Errorf("function responses = %v", responses)
+	}
+}
+
+func TestProviderChatStream_ParsesToolCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/gemini-2.5-pro:streamGenerateContent" || r.URL.Query().Get("alt") != "sse" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"Looking \"}]}}]}\n\n"))
+		w.Write([]byte("data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"it up.\"},{\"functionCall\":{\"name\":\"web_search\",\"args\":{\"query\":\"picoclaw\"}},\"thoughtSignature\":\"abc\"}]},\"finishReason\":\"STOP\"}]," +
+			"\"usageMetadata\":{\"promptTokenCount\":5,\"candidatesTokenCount\":3,\"totalTokenCount\":8}}\n\n"))
+	}))
+	defer server.Close()
+
+	p, _ := NewProvider("key", server.URL, "", nil)
+	var texts []string
+	var calls int
+	resp, err := p.ChatStream(t.Context(), []Message{{Role: "user", Content: "search"}}, nil, "gemini-2.5-pro", nil, func(e StreamEvent) {
+		if e.ToolCall != nil {
+			calls++
+		} else {
+			texts = append(texts, e.Text)
+		}
+	})
+	if err != nil {
+		t.Fatalf("ChatStream() error = %v", err)
+	}
+	if resp.Content != "Looking it up." || len(texts) != 2 || calls != 1 {
+		t.Errorf("response = %+v, streamed %q and %d calls", resp, texts, calls)
+	}
+	if resp.FinishReason != "tool_calls" || len(resp.ToolCalls) != 1 {
+		t.Fatalf("response = %+v", resp)
+	}
+	if tc := resp.ToolCalls[0]; tc.Name != "web_search" || tc.Arguments["query"] != "picoclaw" || tc.Function.ThoughtSignature != "abc" {
+		t.Errorf("tool call = %+v", tc)
+	}
+}
+
+func TestProviderChat_BlockedAndFiltered(t *testing.T) {
+	body := `{"promptFeedback":{"blockReason":"SAFETY"}}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	p, _ := NewProvider("key", server.URL, "", nil)
+	if _, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, "gemini-2.5-flash", nil); err == nil || !strings.Contains(err.Error(), "SAFETY") {
+		t.Errorf("blocked prompt error = %v", err)
+	}
+
+	body = `{"candidates":[{"content":{"parts":[]},"finishReason":"SAFETY"}]}`
+	resp, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, "gemini-2.5-flash", nil)
+	if err != nil || resp.FinishReason != "content_filter" {
+		t.Errorf("filtered reply = %+v, %v", resp, err)
+	}
+}
+
+func TestNewProvider_RejectsUnknownSafetySettings(t *testing.T) {
+	for _, safety := range []map[string]string{{"violence": "none"}, {"harassment": "some"}, {"all": "never"}} {
+		if _, err := NewProvider("key", "", "", safety); err == nil {
+			t.Errorf("NewProvider(%v) succeeded", safety)
+		}
+	}
+}
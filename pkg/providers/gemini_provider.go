@@ -0,0 +1,46 @@
+package providers
+
+import (
+	"context"
+
+	geminiprovider "github.com/sipeed/picoclaw/pkg/providers/gemini"
+)
+
+// GeminiProvider talks to the Gemini API natively, keeping the system
+// instruction, thought signatures, inline media and safety settings that
+// its OpenAI-compatible endpoint drops.
+type GeminiProvider struct {
+	delegate *geminiprovider.Provider
+}
+
+func NewGeminiProvider(apiKey, apiBase, proxy string, safety map[string]string) (*GeminiProvider, error) {
+	delegate, err := geminiprovider.NewProvider(apiKey, apiBase, proxy, safety)
+	if err != nil {
+		return nil, err
+	}
+	return &GeminiProvider{delegate: delegate}, nil
+}
+
+func (p *GeminiProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	return p.delegate.Chat(ctx, messages, tools, model, options)
+}
+
+func (p *GeminiProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onEvent func(StreamEvent)) (*LLMResponse, error) {
+	return p.delegate.ChatStream(ctx, messages, tools, model, options, onEvent)
+}
+
+func (p *GeminiProvider) Warm(ctx context.Context) error {
+	return p.delegate.Warm(ctx)
+}
+
+func (p *GeminiProvider) SupportsSeed() bool {
+	return true
+}
+
+func (p *GeminiProvider) ListModels(ctx context.Context) ([]string, error) {
+	return p.delegate.ListModels(ctx)
+}
+
+func (p *GeminiProvider) GetDefaultModel() string {
+	return ""
+}
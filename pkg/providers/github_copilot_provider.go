@@ -1,53 +1,119 @@
+//go:build !minimal && !standard
+
 package providers
 
 import (
 	"context"
 	"fmt"
+	"os"
 
 	json "encoding/json"
 
 	copilot "github.com/github/copilot-sdk/go"
+
+	"github.com/sipeed/picoclaw/pkg/config"
 )
 
 type GitHubCopilotProvider struct {
 	uri         string
 	connectMode string // `stdio` or `grpc``
+	bridge      *config.CopilotBridgeConfig
 
+	client  *copilot.Client
 	session *copilot.Session
 }
 
 func NewGitHubCopilotProvider(uri string, connectMode string, model string) (*GitHubCopilotProvider, error) {
+	return NewGitHubCopilotProviderWithBridge(uri, connectMode, model, nil)
+}
 
-	var session *copilot.Session
+// NewGitHubCopilotProviderWithBridge connects to the Copilot bridge at uri,
+// launching it from bridge when it is not running. It fails with a
+// *CopilotBridgeError if the bridge cannot be reached and with a
+// *CopilotAuthError if it is not signed in.
+func NewGitHubCopilotProviderWithBridge(uri string, connectMode string, model string, bridge *config.CopilotBridgeConfig) (*GitHubCopilotProvider, error) {
+	ctx := context.Background()
 	if connectMode == "" {
 		connectMode = "grpc"
 	}
+	var client *copilot.Client
 	switch connectMode {
-
 	case "stdio":
-		//todo
+		opts := &copilot.ClientOptions{UseStdio: copilot.Bool(true), CLIPath: "copilot"}
+		if bridge != nil {
+			if bridge.Command != "" {
+				opts.CLIPath = bridge.Command
+			}
+			opts.Env = os.Environ()
+			for k, v := range bridge.Env {
+				opts.Env = append(opts.Env, k+"="+v)
+			}
+		}
+		client = copilot.NewClient(opts)
+		if err := client.Start(ctx); err != nil {
+			return nil, &CopilotBridgeError{Addr: opts.CLIPath + " (stdio)", Err: err}
+		}
 	case "grpc":
-		client := copilot.NewClient(&copilot.ClientOptions{
+		if err := ensureCopilotBridge(ctx, uri, bridge); err != nil {
+			return nil, err
+		}
+		client = copilot.NewClient(&copilot.ClientOptions{
 			CLIUrl: uri,
 		})
-		if err := client.Start(context.Background()); err != nil {
-			return nil, fmt.Errorf("Can't connect to Github Copilot, https://github.com/github/copilot-sdk/blob/main/docs/getting-started.md#connecting-to-an-external-cli-server for details")
+		if err := client.Start(ctx); err != nil {
+			return nil, &CopilotBridgeError{Addr: bridgeAddr(uri), Err: err}
 		}
-		defer client.Stop()
-		session, _ = client.CreateSession(context.Background(), &copilot.SessionConfig{
-			Model: model,
-			Hooks: &copilot.SessionHooks{},
-		})
+	default:
+		return nil, fmt.Errorf("unknown connect_mode %q for GitHub Copilot; use grpc or stdio", connectMode)
+	}
 
+	if err := checkCopilotAuth(ctx, client); err != nil {
+		client.Stop()
+		return nil, err
+	}
+	session, err := client.CreateSession(ctx, &copilot.SessionConfig{
+		Model: model,
+		Hooks: &copilot.SessionHooks{},
+	})
+	if err != nil {
+		client.Stop()
+		return nil, fmt.Errorf("creating GitHub Copilot session: %w", classifyCopilotError(err))
 	}
 
 	return &GitHubCopilotProvider{
 		uri:         uri,
 		connectMode: connectMode,
+		bridge:      bridge,
+		client:      client,
 		session:     session,
 	}, nil
 }
 
+// checkCopilotAuth returns a *CopilotAuthError if the bridge is not signed
+// in. Bridges too old to report it are let through.
+func checkCopilotAuth(ctx context.Context, client *copilot.Client) error {
+	status, err := client.GetAuthStatus(ctx)
+	if err != nil || status.IsAuthenticated {
+		return nil
+	}
+	authErr := &CopilotAuthError{}
+	if status.StatusMessage != nil {
+		authErr.Message = *status.StatusMessage
+	}
+	return authErr
+}
+
+// Warm implements Warmer: it checks that the bridge is reachable, or
+// launches it again, and still signed in.
+func (p *GitHubCopilotProvider) Warm(ctx context.Context) error {
+	if p.connectMode == "grpc" {
+		if err := ensureCopilotBridge(ctx, p.uri, p.bridge); err != nil {
+			return err
+		}
+	}
+	return checkCopilotAuth(ctx, p.client)
+}
+
 // Chat sends a chat request to GitHub Copilot
 func (p *GitHubCopilotProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
 	type tempMessage struct {
@@ -65,9 +131,17 @@ func (p *GitHubCopilotProvider) Chat(ctx context.Context, messages []Message, to
 
 	fullcontent, _ := json.Marshal(out)
 
-	content, _ := p.session.Send(ctx, copilot.MessageOptions{
+	content, err := p.session.Send(ctx, copilot.MessageOptions{
 		Prompt: string(fullcontent),
 	})
+	if err != nil {
+		if p.connectMode == "grpc" {
+			if probeErr := probeCopilotBridge(ctx, p.uri); probeErr != nil {
+				return nil, probeErr
+			}
+		}
+		return nil, classifyCopilotError(err)
+	}
 
 	return &LLMResponse{
 		FinishReason: "stop",
@@ -80,3 +154,8 @@ func (p *GitHubCopilotProvider) GetDefaultModel() string {
 
 	return "gpt-4.1"
 }
+
+// CheckHealth implements HealthChecker, as Warm does.
+func (p *GitHubCopilotProvider) CheckHealth(ctx context.Context) error {
+	return p.Warm(ctx)
+}
@@ -0,0 +1,253 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+package providers
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	cohereprovider "github.com/sipeed/picoclaw/pkg/providers/cohere"
+)
+
+// doctorTimeout bounds the checks of one model_list entry.
+const doctorTimeout = 20 * time.Second
+
+// CheckHealth checks that p can serve requests for model, as cheaply as p
+// allows: with its own check if it is a HealthChecker, by listing its
+// models if it is a ModelLister, and otherwise with a one-token completion.
+func CheckHealth(ctx context.Context, p LLMProvider, model string) error {
+	base := p
+	if m, ok := p.(*middlewareProvider); ok {
+		base = m.base
+	}
+	if h, ok := base.(HealthChecker); ok {
+		return h.CheckHealth(ctx)
+	}
+	if l, ok := base.(ModelLister); ok {
+		_, err := l.ListModels(ctx)
+		return err
+	}
+	_, err := p.Chat(ctx, []Message{{Role: "user", Content: "ping"}}, nil, model, map[string]interface{}{"max_tokens": 1})
+	return err
+}
+
+// DiagnosticStatus is the outcome of a check.
+type DiagnosticStatus string
+
+const (
+	DiagnosticOK      DiagnosticStatus = "ok"
+	DiagnosticWarning DiagnosticStatus = "warning"
+	DiagnosticError   DiagnosticStatus = "error"
+)
+
+// DiagnosticCheck is one check of a model_list entry. Name is config,
+// model, proxy, endpoint, credentials or health.
+type DiagnosticCheck struct {
+	Name    string           `json:"name"`
+	Status  DiagnosticStatus `json:"status"`
+	Message string           `json:"message,omitempty"`
+}
+
+// Diagnostic is the result of checking a model_list entry. Status is the
+// worst status of its checks.
+type Diagnostic struct {
+	ModelName string            `json:"model_name"`
+	Model     string            `json:"model"`
+	Endpoint  string            `json:"endpoint,omitempty"`
+	Proxy     string            `json:"proxy,omitempty"`
+	Status    DiagnosticStatus  `json:"status"`
+	Checks    []DiagnosticCheck `json:"checks"`
+	// LatencyMS is how long the health request took, if it was made.
+	LatencyMS int64 `json:"latency_ms,omitempty"`
+}
+
+func (d *Diagnostic) add(name string, status DiagnosticStatus, message string) {
+	d.Checks = append(d.Checks, DiagnosticCheck{Name: name, Status: status, Message: message})
+	if status == DiagnosticError || (status == DiagnosticWarning && d.Status == DiagnosticOK) {
+		d.Status = status
+	}
+}
+
+// Doctor checks the model_list entries of cfg named in names, or all of
+// them if names is empty: that the entry creates a provider, that its
+// proxy and endpoint can be reached, and that the provider accepts its
+// credentials, with CheckHealth. Entries are checked concurrently; the
+// diagnostics are in model_list order.
+func Doctor(ctx context.Context, cfg *config.Config, names ...string) []Diagnostic {
+	list := cfg.ModelList
+	if len(list) == 0 && cfg.HasProvidersConfig() {
+		list = config.ConvertProvidersToModelList(cfg)
+	}
+	wanted := map[string]bool{}
+	for _, n := range names {
+		wanted[n] = true
+	}
+
+	var entries []config.ModelConfig
+	for _, mc := range list {
+		if len(wanted) == 0 || wanted[mc.ModelName] {
+			entries = append(entries, mc)
+		}
+	}
+	out := make([]Diagnostic, len(entries))
+	var wg sync.WaitGroup
+	for i, mc := range entries {
+		if mc.Workspace == "" {
+			mc.Workspace = cfg.WorkspacePath()
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			out[i] = diagnose(ctx, &mc)
+		}()
+	}
+	wg.Wait()
+	return out
+}
+
+// diagnose runs the checks of one entry. Network checks stop at the first
+// that fails, as the later ones would fail the same way.
+func diagnose(ctx context.Context, mc *config.ModelConfig) Diagnostic {
+	ctx, cancel := context.WithTimeout(ctx, doctorTimeout)
+	defer cancel()
+
+	d := Diagnostic{
+		ModelName: mc.ModelName,
+		Model:     mc.Model,
+		Endpoint:  entryEndpoint(mc),
+		Status:    DiagnosticOK,
+	}
+	if mc.Proxy != "" {
+		d.Proxy = mc.Proxy
+		if u, err := url.Parse(mc.Proxy); err == nil {
+			d.Proxy = u.Redacted()
+		}
+	}
+
+	provider, modelID, err := CreateProviderFromConfig(mc)
+	if err != nil {
+		name, status, message := diagnoseError(err, mc)
+		if name == "health" {
+			name = "config"
+		}
+		d.add(name, status, message)
+		return d
+	}
+	d.add("config", DiagnosticOK, "")
+	if err := ValidateModel(mc); err != nil {
+		d.add("model", DiagnosticWarning, err.Error())
+	}
+
+	if mc.Proxy != "" {
+		u, err := url.Parse(mc.Proxy)
+		if err != nil || u.Host == "" {
+			d.add("proxy", DiagnosticError, "invalid proxy URL")
+			return d
+		}
+		if err := dialHost(ctx, u); err != nil {
+			d.add("proxy", DiagnosticError, err.Error())
+			return d
+		}
+		d.add("proxy", DiagnosticOK, "")
+	} else if u, err := url.Parse(d.Endpoint); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		// Through a proxy, the endpoint may only be reachable from the
+		// proxy, so it is only dialled directly.
+		if err := dialHost(ctx, u); err != nil {
+			d.add("endpoint", DiagnosticError, err.Error())
+			return d
+		}
+		d.add("endpoint", DiagnosticOK, "")
+	}
+
+	start := time.Now()
+	err = CheckHealth(ctx, provider, modelID)
+	d.LatencyMS = time.Since(start).Milliseconds()
+	if err != nil {
+		d.add(diagnoseError(err, mc))
+		return d
+	}
+	d.add("credentials", DiagnosticOK, "")
+	return d
+}
+
+// diagnoseError names the check a provider error fails, and whether it
+// is an error or a warning.
+func diagnoseError(err error, mc *config.ModelConfig) (string, DiagnosticStatus, string) {
+	var bridgeErr *CopilotBridgeError
+	var authErr *CopilotAuthError
+	var opErr *net.OpError
+	switch {
+	case errors.As(err, &authErr):
+		return "credentials", DiagnosticError, err.Error()
+	case errors.As(err, &bridgeErr), errors.As(err, &opErr):
+		return "endpoint", DiagnosticError, err.Error()
+	case errors.Is(err, context.DeadlineExceeded):
+		return "endpoint", DiagnosticError, "no response within " + doctorTimeout.String()
+	}
+	protocol, modelID := ExtractProtocol(mc.Model)
+	if fe := ClassifyError(err, protocol, modelID); fe != nil {
+		switch fe.Reason {
+		case FailoverAuth:
+			return "credentials", DiagnosticError, "credentials rejected: " + err.Error()
+		case FailoverRateLimit, FailoverOverloaded:
+			// The provider answered and accepted the credentials.
+			return "health", DiagnosticWarning, err.Error()
+		}
+		return "health", DiagnosticError, err.Error()
+	}
+	return "health", DiagnosticError, err.Error()
+}
+
+// entryEndpoint returns the API base a model_list entry uses, or "" if it
+// has none, as for CLI providers.
+func entryEndpoint(mc *config.ModelConfig) string {
+	if mc.APIBase != "" {
+		return mc.APIBase
+	}
+	protocol, _ := ExtractProtocol(mc.Model)
+	if c, ok := findCustomProvider(protocol); ok {
+		return c.APIBase
+	}
+	if s, ok := findLocalServer(protocol); ok {
+		return s.apiBase
+	}
+	switch protocol {
+	case "anthropic":
+		return defaultAnthropicAPIBase
+	case "cohere":
+		return cohereprovider.DefaultAPIBase
+	case "github-copilot", "copilot":
+		return "localhost:4321"
+	}
+	return getDefaultAPIBase(protocol)
+}
+
+// dialHost opens and closes a TCP connection to the host of u.
+func dialHost(ctx context.Context, u *url.URL) error {
+	port := u.Port()
+	if port == "" {
+		switch u.Scheme {
+		case "https":
+			port = "443"
+		case "socks5", "socks5h":
+			port = "1080"
+		default:
+			port = "80"
+		}
+	}
+	dialer := net.Dialer{Timeout: 5 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(u.Hostname(), port))
+	if err != nil {
+		return err
+	}
+	conn.Close()
+	return nil
+}
@@ -0,0 +1,74 @@
+package providers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestDoctor(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer good" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"message":"invalid api key"}}`))
+			return
+		}
+		w.Write([]byte(`{"data":[{"id":"gpt-5.2"}]}`))
+	}))
+	defer srv.Close()
+	down := freeAddr(t)
+
+	cfg := &config.Config{ModelList: []config.ModelConfig{
+		{ModelName: "good", Model: "openai/gpt-5.2", APIKey: "good", APIBase: srv.URL + "/v1"},
+		{ModelName: "bad-key", Model: "openai/gpt-5.2", APIKey: "wrong", APIBase: srv.URL + "/v1"},
+		{ModelName: "down", Model: "openai/gpt-5.2", APIKey: "good", APIBase: "http://" + down + "/v1"},
+		{ModelName: "bad-proxy", Model: "openai/gpt-5.2", APIKey: "good", APIBase: srv.URL + "/v1", Proxy: "http://user:secret@" + down},
+		{ModelName: "no-key", Model: "anthropic/claude-sonnet-4.6"},
+		{ModelName: "unknown", Model: "openai/gpt-99", APIKey: "good", APIBase: srv.URL + "/v1"},
+	}}
+
+	want := map[string]struct {
+		status DiagnosticStatus
+		check  string
+	}{
+		"good":      {DiagnosticOK, "credentials"},
+		"bad-key":   {DiagnosticError, "credentials"},
+		"down":      {DiagnosticError, "endpoint"},
+		"bad-proxy": {DiagnosticError, "proxy"},
+		"no-key":    {DiagnosticError, "config"},
+		"unknown":   {DiagnosticWarning, "credentials"},
+	}
+	diags := Doctor(t.Context(), cfg)
+	if len(diags) != len(cfg.ModelList) {
+		t.Fatalf("Doctor() returned %d diagnostics, want %d", len(diags), len(cfg.ModelList))
+	}
+	for i, d := range diags {
+		if d.ModelName != cfg.ModelList[i].ModelName {
+			t.Errorf("diagnostic %d is for %q, want model_list order", i, d.ModelName)
+		}
+		w := want[d.ModelName]
+		last := d.Checks[len(d.Checks)-1]
+		if d.Status != w.status || last.Name != w.check {
+			t.Errorf("%s: status %s, last check %+v; want %s after %s", d.ModelName, d.Status, last, w.status, w.check)
+		}
+	}
+	if diags[3].Proxy == "" || diags[3].Proxy == cfg.ModelList[3].Proxy {
+		t.Errorf("proxy = %q, want it shown without its password", diags[3].Proxy)
+	}
+
+	if diags := Doctor(t.Context(), cfg, "good"); len(diags) != 1 || diags[0].ModelName != "good" {
+		t.Errorf("Doctor(good) = %+v", diags)
+	}
+}
+
+func TestCheckHealth_FallsBackToCompletion(t *testing.T) {
+	mock := &structuredMock{responses: []*LLMResponse{{Content: "pong"}}}
+	if err := CheckHealth(t.Context(), mock, "m"); err != nil {
+		t.Fatalf("CheckHealth() error = %v", err)
+	}
+	if len(mock.requests) != 1 || mock.requests[0].options["max_tokens"] != 1 {
+		t.Errorf("requests = %+v, want one one-token completion", mock.requests)
+	}
+}
@@ -32,6 +32,41 @@ func (p *HTTPProvider) Chat(ctx context.Context, messages []Message, tools []Too
 	return p.delegate.Chat(ctx, messages, tools, model, options)
 }
 
+func (p *HTTPProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onEvent func(StreamEvent)) (*LLMResponse, error) {
+	return p.delegate.ChatStream(ctx, messages, tools, model, options, onEvent)
+}
+
+func (p *HTTPProvider) UploadFile(ctx context.Context, name, mimeType string, data []byte) (string, error) {
+	return p.delegate.UploadFile(ctx, name, mimeType, data)
+}
+
+func (p *HTTPProvider) DeleteFile(ctx context.Context, id string) error {
+	return p.delegate.DeleteFile(ctx, id)
+}
+
+func (p *HTTPProvider) Warm(ctx context.Context) error {
+	return p.delegate.Warm(ctx)
+}
+
+func (p *HTTPProvider) SupportsSeed() bool {
+	return true
+}
+
+func (p *HTTPProvider) StructuredOutput() StructuredMode {
+	if p.delegate.SupportsResponseFormat() {
+		return StructuredResponseFormat
+	}
+	return StructuredPrompt
+}
+
+func (p *HTTPProvider) ListModels(ctx context.Context) ([]string, error) {
+	return p.delegate.ListModels(ctx)
+}
+
+func (p *HTTPProvider) Embed(ctx context.Context, texts []string, model string) ([][]float32, error) {
+	return p.delegate.Embed(ctx, texts, model)
+}
+
 func (p *HTTPProvider) GetDefaultModel() string {
 	return ""
 }
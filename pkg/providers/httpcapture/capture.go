@@ -0,0 +1,277 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package httpcapture records raw provider HTTP exchanges to disk so that
+// integration bugs can be reported with the exact payloads that were sent
+// and received. Capture is off by default and is switched on by the
+// --trace flag of the agent and gateway commands.
+package httpcapture
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const redacted = "[REDACTED]"
+
+var (
+	mu  sync.RWMutex
+	dir string
+	seq uint64
+)
+
+// sensitiveHeaders are replaced with a placeholder in captured files.
+var sensitiveHeaders = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"x-api-key":           true,
+	"api-key":             true,
+	"x-goog-api-key":      true,
+	"cookie":              true,
+	"set-cookie":          true,
+}
+
+// sensitiveParams are replaced with a placeholder in captured URLs.
+var sensitiveParams = map[string]bool{
+	"key":          true,
+	"api_key":      true,
+	"apikey":       true,
+	"access_token": true,
+	"token":        true,
+}
+
+// Exchange is the on-disk format of a single captured request/response pair.
+type Exchange struct {
+	ID         string    `json:"id"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMS int64     `json:"duration_ms"`
+	Request    Request   `json:"request"`
+	Response   *Response `json:"response,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Request is the sanitized request half of an Exchange.
+type Request struct {
+	Method  string              `json:"method"`
+	URL     string              `json:"url"`
+	Headers map[string][]string `json:"headers,omitempty"`
+	Body    json.RawMessage     `json:"body,omitempty"`
+	RawBody string              `json:"raw_body,omitempty"`
+}
+
+// Response is the sanitized response half of an Exchange.
+type Response struct {
+	Status  int                 `json:"status"`
+	Headers map[string][]string `json:"headers,omitempty"`
+	Body    json.RawMessage     `json:"body,omitempty"`
+	RawBody string              `json:"raw_body,omitempty"`
+}
+
+// Enable turns capture on and writes one file per exchange into captureDir.
+func Enable(captureDir string) error {
+	if err := os.MkdirAll(captureDir, 0700); err != nil {
+		return fmt.Errorf("failed to create capture directory: %w", err)
+	}
+	mu.Lock()
+	dir = captureDir
+	mu.Unlock()
+	return nil
+}
+
+// Disable turns capture off.
+func Disable() {
+	mu.Lock()
+	dir = ""
+	mu.Unlock()
+}
+
+// Dir returns the active capture directory, or "" when capture is off.
+func Dir() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return dir
+}
+
+// Transport is an http.RoundTripper that records every exchange while
+// capture is enabled and is a plain pass-through otherwise.
+type Transport struct {
+	Base http.RoundTripper
+}
+
+// Wrap returns base wrapped in a capturing Transport when capture is
+// enabled, and base unchanged otherwise, so Enable must be called before
+// providers are constructed. A nil base means http.DefaultTransport.
+func Wrap(base http.RoundTripper) http.RoundTripper {
+	if Dir() == "" {
+		return base
+	}
+	if _, ok := base.(*Transport); ok {
+		return base
+	}
+	return &Transport{Base: base}
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	captureDir := Dir()
+	if captureDir == "" {
+		return t.base().RoundTrip(req)
+	}
+
+	ex := Exchange{
+		ID:        fmt.Sprintf("%s-%04d", time.Now().Format("20060102-150405.000"), atomic.AddUint64(&seq, 1)),
+		StartedAt: time.Now(),
+		Request: Request{
+			Method:  req.Method,
+			URL:     sanitizeURL(req.URL),
+			Headers: sanitizeHeaders(req.Header),
+		},
+	}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		ex.Request.Body, ex.Request.RawBody = encodeBody(body)
+	}
+
+	resp, err := t.base().RoundTrip(req)
+	ex.DurationMS = time.Since(ex.StartedAt).Milliseconds()
+	if err != nil {
+		ex.Error = err.Error()
+		write(captureDir, ex)
+		return resp, err
+	}
+
+	ex.Response = &Response{
+		Status:  resp.StatusCode,
+		Headers: sanitizeHeaders(resp.Header),
+	}
+	// The body is recorded as the caller reads it, so that streamed
+	// responses still reach the caller as they arrive.
+	resp.Body = &captureBody{body: resp.Body, dir: captureDir, ex: ex}
+	return resp, nil
+}
+
+// captureBody passes a response body through to the caller, keeping a copy,
+// and writes the exchange once the caller has read all of it or closed it.
+// A body closed early is captured as far as it was read.
+type captureBody struct {
+	body io.ReadCloser
+	dir  string
+
+	mu   sync.Mutex
+	ex   Exchange
+	buf  bytes.Buffer
+	done bool
+}
+
+func (b *captureBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Write(p[:n])
+	if err != nil {
+		if err != io.EOF {
+			b.ex.Error = err.Error()
+		}
+		b.finish()
+	}
+	return n, err
+}
+
+func (b *captureBody) Close() error {
+	err := b.body.Close()
+	b.mu.Lock()
+	b.finish()
+	b.mu.Unlock()
+	return err
+}
+
+// finish writes the exchange the first time it is called. b.mu is held.
+func (b *captureBody) finish() {
+	if b.done {
+		return
+	}
+	b.done = true
+	b.ex.Response.Body, b.ex.Response.RawBody = encodeBody(b.buf.Bytes())
+	write(b.dir, b.ex)
+}
+
+// encodeBody keeps JSON bodies structured and falls back to a string for
+// anything else (e.g. SSE streams or HTML error pages).
+func encodeBody(body []byte) (json.RawMessage, string) {
+	if len(body) == 0 {
+		return nil, ""
+	}
+	if json.Valid(body) {
+		return json.RawMessage(body), ""
+	}
+	return nil, string(body)
+}
+
+func sanitizeHeaders(h http.Header) map[string][]string {
+	if len(h) == 0 {
+		return nil
+	}
+	out := make(map[string][]string, len(h))
+	for k, v := range h {
+		if sensitiveHeaders[strings.ToLower(k)] {
+			out[k] = []string{redacted}
+			continue
+		}
+		out[k] = append([]string(nil), v...)
+	}
+	return out
+}
+
+func sanitizeURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	clean := *u
+	clean.User = nil
+	q := clean.Query()
+	changed := false
+	for k := range q {
+		if sensitiveParams[strings.ToLower(k)] {
+			q.Set(k, redacted)
+			changed = true
+		}
+	}
+	if changed {
+		clean.RawQuery = q.Encode()
+	}
+	return clean.String()
+}
+
+func write(captureDir string, ex Exchange) {
+	data, err := json.MarshalIndent(ex, "", "  ")
+	if err != nil {
+		return
+	}
+	// Capture is best effort; a failed write must never break the request.
+	_ = os.WriteFile(filepath.Join(captureDir, ex.ID+".json"), data, 0600)
+}
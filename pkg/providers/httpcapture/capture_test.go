@@ -0,0 +1,152 @@
+package httpcapture
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTransport_PassThroughWhenDisabled(t *testing.T) {
+	dir := t.TempDir()
+	if err := Enable(dir); err != nil {
+		t.Fatalf("Enable() error: %v", err)
+	}
+	client := &http.Client{Transport: Wrap(nil)}
+	Disable()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if files, _ := filepath.Glob(filepath.Join(dir, "*.json")); len(files) != 0 {
+		t.Errorf("expected no capture files after Disable, got %d", len(files))
+	}
+	if Wrap(nil) != nil {
+		t.Error("Wrap should return the base transport unchanged when capture is disabled")
+	}
+}
+
+func TestTransport_CapturesSanitizedExchange(t *testing.T) {
+	dir := t.TempDir()
+	if err := Enable(dir); err != nil {
+		t.Fatalf("Enable() error: %v", err)
+	}
+	defer Disable()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"model":"test"}` {
+			t.Errorf("server got body %q, request body was not restored", body)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[]}`))
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest("POST", server.URL+"/chat?key=secret-key&alt=sse", strings.NewReader(`{"model":"test"}`))
+	req.Header.Set("Authorization", "Bearer sk-secret")
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Transport: Wrap(nil)}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	respBody, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(respBody) != `{"choices":[]}` {
+		t.Errorf("caller got body %q, response body was not restored", respBody)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("expected 1 capture file, got %d", len(files))
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("read capture: %v", err)
+	}
+	if strings.Contains(string(data), "sk-secret") || strings.Contains(string(data), "secret-key") {
+		t.Errorf("capture file leaks credentials:\n%s", data)
+	}
+
+	var ex Exchange
+	if err := json.Unmarshal(data, &ex); err != nil {
+		t.Fatalf("unmarshal capture: %v", err)
+	}
+	if ex.Request.Method != "POST" {
+		t.Errorf("Request.Method = %q, want POST", ex.Request.Method)
+	}
+	var reqBody map[string]string
+	if err := json.Unmarshal(ex.Request.Body, &reqBody); err != nil || reqBody["model"] != "test" {
+		t.Errorf("Request.Body = %s", ex.Request.Body)
+	}
+	if ex.Response == nil || ex.Response.Status != 200 {
+		t.Fatalf("Response = %+v, want status 200", ex.Response)
+	}
+	if got := ex.Request.Headers["Authorization"]; len(got) != 1 || got[0] != redacted {
+		t.Errorf("Authorization header = %v, want redacted", got)
+	}
+}
+
+func TestTransport_StreamsResponseWhileCapturing(t *testing.T) {
+	dir := t.TempDir()
+	if err := Enable(dir); err != nil {
+		t.Fatalf("Enable() error: %v", err)
+	}
+	defer Disable()
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: one\n\n"))
+		w.(http.Flusher).Flush()
+		<-release
+		w.Write([]byte("data: two\n\n"))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: Wrap(nil)}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	first := make([]byte, len("data: one\n\n"))
+	if _, err := io.ReadFull(resp.Body, first); err != nil || string(first) != "data: one\n\n" {
+		t.Fatalf("first event = %q, %v; the response was not streamed", first, err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*.json")); len(files) != 0 {
+		t.Errorf("exchange captured before the body was read")
+	}
+	close(release)
+	rest, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(rest) != "data: two\n\n" {
+		t.Errorf("rest of the body = %q", rest)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("expected 1 capture file, got %d", len(files))
+	}
+	data, _ := os.ReadFile(files[0])
+	var ex Exchange
+	if err := json.Unmarshal(data, &ex); err != nil {
+		t.Fatalf("unmarshal capture: %v", err)
+	}
+	if ex.Response == nil || ex.Response.RawBody != "data: one\n\ndata: two\n\n" {
+		t.Errorf("Response = %+v, want the whole stream", ex.Response)
+	}
+}
@@ -0,0 +1,206 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package httpretry retries provider HTTP requests that fail for reasons
+// that usually pass: rate limits (429), server errors and overload (5xx),
+// and connections dropped by the server. Retries wait with exponential
+// backoff and jitter, or as long as the server asks in Retry-After, and
+// stop after a number of attempts or once a time budget is used up.
+//
+// Only the request is retried, never a response that has started. A stream
+// that breaks halfway is returned as an error like before.
+package httpretry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// Policy limits the retries of a request. A request is attempted at most
+// MaxAttempts times, and is not retried if the wait would end more than
+// Budget after the first attempt started.
+type Policy struct {
+	MaxAttempts  int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	Budget       time.Duration
+}
+
+var (
+	mu     sync.RWMutex
+	policy Policy
+
+	// sleep waits for d or until ctx is done. Tests replace it.
+	sleep = func(ctx context.Context, d time.Duration) error {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		select {
+		case <-t.C:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+)
+
+// retryStatusCodes are the statuses worth retrying: rate limits, server
+// errors, and the gateway errors of proxies and CDNs in front of APIs.
+var retryStatusCodes = map[int]bool{
+	429: true,
+	500: true, 502: true, 503: true, 504: true,
+	521: true, 522: true, 523: true, 524: true,
+	529: true,
+}
+
+// Configure sets the policy of transports created afterwards. A policy with
+// MaxAttempts below two disables retries. It must be called before
+// providers are created.
+func Configure(p Policy) {
+	mu.Lock()
+	defer mu.Unlock()
+	policy = p
+}
+
+// Enabled reports whether requests are retried. Providers built on SDKs
+// with their own retries turn those off when it is.
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return policy.MaxAttempts > 1
+}
+
+// Transport returns base with retries. base may be nil for the default
+// transport. When retries are disabled, base is returned unchanged.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	mu.RLock()
+	p := policy
+	mu.RUnlock()
+	if p.MaxAttempts <= 1 {
+		return base
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base, policy: p}
+}
+
+type transport struct {
+	base   http.RoundTripper
+	policy Policy
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A body that cannot be read again cannot be sent again.
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		r := req
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r = req.Clone(req.Context())
+			r.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(r)
+		if !retryable(resp, err) || attempt >= t.policy.MaxAttempts {
+			return resp, err
+		}
+
+		delay := t.policy.backoff(attempt)
+		fields := logger.TraceFields(req.Context(), map[string]interface{}{
+			"host":    req.URL.Host,
+			"attempt": attempt,
+		})
+		if resp != nil {
+			if after, ok := retryAfter(resp.Header, time.Now()); ok {
+				delay = after
+			}
+			fields["status"] = resp.StatusCode
+		} else {
+			fields["error"] = err.Error()
+		}
+		if time.Since(start)+delay > t.policy.Budget {
+			fields["delay_ms"] = delay.Milliseconds()
+			logger.WarnCF("providers", "Provider request failed, retry budget exhausted", fields)
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+
+		fields["delay_ms"] = delay.Milliseconds()
+		logger.WarnCF("providers", "Provider request failed, retrying", fields)
+		if err := sleep(req.Context(), delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryable reports whether a request that ended with resp or err is worth
+// sending again.
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return errors.Is(err, syscall.ECONNRESET) ||
+			errors.Is(err, syscall.ECONNREFUSED) ||
+			errors.Is(err, io.EOF) ||
+			errors.Is(err, io.ErrUnexpectedEOF)
+	}
+	return retryStatusCodes[resp.StatusCode]
+}
+
+// backoff returns how long to wait after the given failed attempt: the
+// initial delay doubled per attempt, capped at the maximum, of which a
+// random half is waited.
+func (p Policy) backoff(attempt int) time.Duration {
+	d := p.InitialDelay
+	for i := 1; i < attempt && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// retryAfter parses the wait the server asked for, given in milliseconds
+// by retry-after-ms (OpenAI, Anthropic) or in seconds or as a date by
+// Retry-After.
+func retryAfter(h http.Header, now time.Time) (time.Duration, bool) {
+	if v := h.Get("Retry-After-Ms"); v != "" {
+		if ms, err := strconv.ParseFloat(v, 64); err == nil && ms >= 0 {
+			return time.Duration(ms * float64(time.Millisecond)), true
+		}
+	}
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(v); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+	return 0, false
+}
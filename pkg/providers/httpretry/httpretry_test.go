@@ -0,0 +1,179 @@
+package httpretry
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// useSleep configures retries for the test and records the waits instead
+// of sleeping.
+func useSleep(t *testing.T, p Policy) *[]time.Duration {
+	t.Helper()
+	var waits []time.Duration
+	origSleep := sleep
+	sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	Configure(p)
+	t.Cleanup(func() {
+		sleep = origSleep
+		Configure(Policy{})
+	})
+	return &waits
+}
+
+var testPolicy = Policy{
+	MaxAttempts:  3,
+	InitialDelay: 100 * time.Millisecond,
+	MaxDelay:     time.Second,
+	Budget:       time.Minute,
+}
+
+func post(t *testing.T, url string) *http.Response {
+	t.Helper()
+	client := &http.Client{Transport: Transport(nil)}
+	resp, err := client.Post(url, "application/json", strings.NewReader(`{"q":1}`))
+	if err != nil {
+		t.Fatalf("Post() error: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestTransport_RetriesWithRetryAfter(t *testing.T) {
+	waits := useSleep(t, testPolicy)
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"q":1}` {
+			t.Errorf("attempt %d body = %q", calls.Load()+1, body)
+		}
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	resp := post(t, server.URL)
+	if resp.StatusCode != http.StatusOK || calls.Load() != 2 {
+		t.Fatalf("status = %d after %d calls, want 200 after 2", resp.StatusCode, calls.Load())
+	}
+	if len(*waits) != 1 || (*waits)[0] != 7*time.Second {
+		t.Errorf("waits = %v, want [7s]", *waits)
+	}
+}
+
+func TestTransport_GivesUpAfterMaxAttempts(t *testing.T) {
+	waits := useSleep(t, testPolicy)
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	resp := post(t, server.URL)
+	if resp.StatusCode != http.StatusServiceUnavailable || calls.Load() != 3 {
+		t.Fatalf("status = %d after %d calls, want 503 after 3", resp.StatusCode, calls.Load())
+	}
+	// Backoff doubles, and jitter waits between half and all of it.
+	if len(*waits) != 2 ||
+		(*waits)[0] < 50*time.Millisecond || (*waits)[0] > 100*time.Millisecond ||
+		(*waits)[1] < 100*time.Millisecond || (*waits)[1] > 200*time.Millisecond {
+		t.Errorf("waits = %v", *waits)
+	}
+}
+
+func TestTransport_DoesNotRetry(t *testing.T) {
+	useSleep(t, testPolicy)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		status  int
+	}{
+		{"bad request", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		}, http.StatusBadRequest},
+		{"retry after beyond budget", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}, http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				tt.handler(w, r)
+			}))
+			defer server.Close()
+
+			resp := post(t, server.URL)
+			if resp.StatusCode != tt.status || calls.Load() != 1 {
+				t.Errorf("status = %d after %d calls, want %d after 1", resp.StatusCode, calls.Load(), tt.status)
+			}
+		})
+	}
+}
+
+func TestTransport_RetriesDroppedConnection(t *testing.T) {
+	useSleep(t, testPolicy)
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	resp := post(t, server.URL)
+	if resp.StatusCode != http.StatusOK || calls.Load() != 2 {
+		t.Fatalf("status = %d after %d calls, want 200 after 2", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestTransport_Disabled(t *testing.T) {
+	Configure(Policy{MaxAttempts: 1})
+	defer Configure(Policy{})
+	if Enabled() || Transport(nil) != nil {
+		t.Error("retries should be disabled with a single attempt")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		header http.Header
+		want   time.Duration
+		ok     bool
+	}{
+		{http.Header{"Retry-After": {"3"}}, 3 * time.Second, true},
+		{http.Header{"Retry-After": {now.Add(90 * time.Second).Format(http.TimeFormat)}}, 90 * time.Second, true},
+		{http.Header{"Retry-After-Ms": {"250"}, "Retry-After": {"1"}}, 250 * time.Millisecond, true},
+		{http.Header{"Retry-After": {"soon"}}, 0, false},
+		{http.Header{}, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := retryAfter(tt.header, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("retryAfter(%v) = %v, %v; want %v, %v", tt.header, got, ok, tt.want, tt.ok)
+		}
+	}
+}
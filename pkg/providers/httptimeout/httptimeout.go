@@ -0,0 +1,205 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package httptimeout bounds the phases of provider HTTP requests, so that
+// an upstream that stops answering fails the request instead of holding it
+// forever: connecting, and waiting for data, which is the response headers
+// and then each read of the body. A stream that keeps sending is never cut
+// off; bounding a whole request is left to its context.
+//
+// The limits come from the request's context, as set by WithTimeouts, and
+// otherwise the defaults.
+package httptimeout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Default limits of requests whose context sets none.
+const (
+	DefaultConnect = 30 * time.Second
+	DefaultRead    = 5 * time.Minute
+)
+
+// Timeouts limits a request. Connect bounds opening a connection to the
+// server, and Read the wait for the response headers and then for each
+// piece of the body. Zero is the default; a negative value is no limit.
+type Timeouts struct {
+	Connect time.Duration
+	Read    time.Duration
+}
+
+type contextKey struct{}
+
+// WithTimeouts returns ctx with the timeouts of the requests made with it.
+func WithTimeouts(ctx context.Context, t Timeouts) context.Context {
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// timeoutsFor returns the timeouts of ctx with the defaults applied.
+func timeoutsFor(ctx context.Context) Timeouts {
+	t, _ := ctx.Value(contextKey{}).(Timeouts)
+	if t.Connect == 0 {
+		t.Connect = DefaultConnect
+	}
+	if t.Read == 0 {
+		t.Read = DefaultRead
+	}
+	return t
+}
+
+// Error reports a request that ran into one of its timeouts.
+type Error struct {
+	Phase string // "connect", "read" or "total"
+	Limit time.Duration
+}
+
+func (e *Error) Error() string {
+	switch e.Phase {
+	case "connect":
+		return fmt.Sprintf("provider connect timeout: no connection within %s", e.Limit)
+	case "total":
+		return fmt.Sprintf("provider total timeout: no complete reply within %s", e.Limit)
+	}
+	return fmt.Sprintf("provider read timeout: no data for %s", e.Limit)
+}
+
+// Timeout reports true, like the Timeout method of net.Error.
+func (e *Error) Timeout() bool { return true }
+
+var dialer = &net.Dialer{Timeout: DefaultConnect, KeepAlive: 30 * time.Second}
+
+// Transport returns base with timeouts. base may be nil for the default
+// transport. The connect timeout needs an *http.Transport to apply to;
+// the read timeout applies to any base.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if t, ok := base.(*http.Transport); ok {
+		t = t.Clone()
+		next := t.DialContext
+		if next == nil {
+			next = dialer.DialContext
+		}
+		t.DialContext = dialContext(next)
+		base = t
+	}
+	return &transport{base: base}
+}
+
+func dialContext(next func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		d := timeoutsFor(ctx).Connect
+		if d < 0 {
+			return next(ctx, network, addr)
+		}
+		dctx, cancel := context.WithTimeout(ctx, d)
+		defer cancel()
+		conn, err := next(dctx, network, addr)
+		if err != nil && ctx.Err() == nil && errors.Is(dctx.Err(), context.DeadlineExceeded) {
+			return nil, &Error{Phase: "connect", Limit: d}
+		}
+		return conn, err
+	}
+}
+
+type transport struct {
+	base http.RoundTripper
+}
+
+// Unwrap returns the transport the requests are sent with.
+func (t *transport) Unwrap() http.RoundTripper {
+	return t.base
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	d := timeoutsFor(req.Context()).Read
+	if d < 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithCancel(req.Context())
+	w := &watchdog{timeout: d, cancel: cancel}
+	w.timer = time.AfterFunc(d, w.fire)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		w.stop()
+		if w.expired() {
+			return nil, &Error{Phase: "read", Limit: d}
+		}
+		return nil, err
+	}
+	w.timer.Reset(d)
+	resp.Body = &body{ReadCloser: resp.Body, w: w}
+	return resp, nil
+}
+
+// watchdog cancels a request that has waited for data for too long.
+type watchdog struct {
+	timeout time.Duration
+	timer   *time.Timer
+	cancel  context.CancelFunc
+
+	mu      sync.Mutex
+	fired   bool
+	stopped bool
+}
+
+func (w *watchdog) fire() {
+	w.mu.Lock()
+	if w.stopped {
+		w.mu.Unlock()
+		return
+	}
+	w.fired = true
+	w.mu.Unlock()
+	w.cancel()
+}
+
+func (w *watchdog) expired() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.fired
+}
+
+// stop ends the watch and releases the request's context.
+func (w *watchdog) stop() {
+	w.mu.Lock()
+	w.stopped = true
+	w.mu.Unlock()
+	w.timer.Stop()
+	w.cancel()
+}
+
+// body restarts the watchdog on each read, and reports the reads it cut
+// short as timeouts.
+type body struct {
+	io.ReadCloser
+	w *watchdog
+}
+
+func (b *body) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF && b.w.expired() {
+		return n, &Error{Phase: "read", Limit: b.w.timeout}
+	}
+	if n > 0 {
+		b.w.timer.Reset(b.w.timeout)
+	}
+	return n, err
+}
+
+func (b *body) Close() error {
+	b.w.stop()
+	return b.ReadCloser.Close()
+}
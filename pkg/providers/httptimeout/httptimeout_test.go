@@ -0,0 +1,99 @@
+package httptimeout
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func get(t *testing.T, base http.RoundTripper, url string, timeouts Timeouts) (*http.Response, error) {
+	t.Helper()
+	ctx := WithTimeouts(t.Context(), timeouts)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: Transport(base)}
+	return client.Do(req)
+}
+
+func TestTransport_ReadTimeoutBeforeHeaders(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	_, err := get(t, nil, server.URL, Timeouts{Read: 50 * time.Millisecond})
+	var te *Error
+	if !errors.As(err, &te) || te.Phase != "read" {
+		t.Fatalf("error = %v, want a read timeout", err)
+	}
+}
+
+func TestTransport_ReadTimeoutMidStream(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("data: first\n\n"))
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	resp, err := get(t, nil, server.URL, Timeouts{Read: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("get() error: %v", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	var te *Error
+	if !errors.As(err, &te) || te.Phase != "read" {
+		t.Fatalf("error = %v, want a read timeout", err)
+	}
+	if string(data) != "data: first\n\n" {
+		t.Errorf("data = %q, want what arrived before the stall", data)
+	}
+}
+
+func TestTransport_SteadyStreamOutlastsReadTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 6; i++ {
+			w.Write([]byte("x"))
+			w.(http.Flusher).Flush()
+			time.Sleep(40 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	resp, err := get(t, nil, server.URL, Timeouts{Read: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("get() error: %v", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil || string(data) != "xxxxxx" {
+		t.Fatalf("ReadAll() = %q, %v; want the whole stream", data, err)
+	}
+}
+
+func TestTransport_ConnectTimeout(t *testing.T) {
+	base := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+
+	_, err := get(t, base, "http://example.invalid", Timeouts{Connect: 50 * time.Millisecond})
+	var te *Error
+	if !errors.As(err, &te) || te.Phase != "connect" {
+		t.Fatalf("error = %v, want a connect timeout", err)
+	}
+}
@@ -0,0 +1,182 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package httpwarm cuts first-token latency to LLM providers on high-RTT
+// networks. It offers an in-process DNS cache with a configurable TTL for
+// provider transports, and helpers that open (and keep open) a TLS
+// connection to a provider endpoint before the first request needs it.
+package httpwarm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+type dnsEntry struct {
+	addrs   []string
+	expires time.Time
+}
+
+var (
+	mu       sync.RWMutex
+	dnsTTL   time.Duration
+	dnsCache = map[string]dnsEntry{}
+
+	lookupHost = net.DefaultResolver.LookupHost
+	dialer     = &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+)
+
+// EnableDNSCache caches provider host lookups for ttl, overriding the
+// resolver's own TTL. A ttl of zero disables the cache. It must be called
+// before providers are created.
+func EnableDNSCache(ttl time.Duration) {
+	mu.Lock()
+	defer mu.Unlock()
+	dnsTTL = ttl
+	dnsCache = map[string]dnsEntry{}
+}
+
+// Transport returns base with DNS caching applied to its dialer. base may
+// be nil for the default transport. When the cache is disabled, or base is
+// not an *http.Transport, base is returned unchanged.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	mu.RLock()
+	enabled := dnsTTL > 0
+	mu.RUnlock()
+	if !enabled {
+		return base
+	}
+
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t, ok := base.(*http.Transport)
+	if !ok {
+		return base
+	}
+	t = t.Clone()
+	t.DialContext = dialContext
+	return t
+}
+
+// dialContext dials addr using cached addresses for its host, trying each
+// address in turn.
+func dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || net.ParseIP(host) != nil {
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	addrs, err := resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for _, ip := range addrs {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+
+	// The cached addresses may be stale; look the host up again next time.
+	mu.Lock()
+	delete(dnsCache, host)
+	mu.Unlock()
+	return nil, lastErr
+}
+
+func resolve(ctx context.Context, host string) ([]string, error) {
+	now := time.Now()
+	mu.RLock()
+	entry, ok := dnsCache[host]
+	ttl := dnsTTL
+	mu.RUnlock()
+	if ok && now.Before(entry.expires) {
+		return entry.addrs, nil
+	}
+
+	addrs, err := lookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no addresses found for %s", host)
+	}
+
+	mu.Lock()
+	dnsCache[host] = dnsEntry{addrs: addrs, expires: now.Add(ttl)}
+	mu.Unlock()
+	return addrs, nil
+}
+
+// Warm opens a connection to the host of endpoint with a HEAD request, so
+// the DNS lookup and TLS handshake are done and the connection sits in
+// client's pool. The response status is irrelevant.
+func Warm(ctx context.Context, client *http.Client, endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid endpoint %q", endpoint)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, u.Scheme+"://"+u.Host+"/", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return nil
+}
+
+// KeepWarm calls warm immediately and then every interval until ctx is
+// done, so pooled connections are refreshed before the server closes them
+// as idle. An interval of zero warms only once.
+func KeepWarm(ctx context.Context, interval time.Duration, warm func(ctx context.Context) error) {
+	run := func() {
+		start := time.Now()
+		warmCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+		defer cancel()
+		if err := warm(warmCtx); err != nil {
+			logger.DebugCF("provider", "Connection pre-warm failed", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return
+		}
+		logger.DebugCF("provider", "Provider connection warmed", map[string]interface{}{
+			"duration_ms": time.Since(start).Milliseconds(),
+		})
+	}
+
+	go func() {
+		run()
+		if interval <= 0 {
+			return
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				run()
+			}
+		}
+	}()
+}
@@ -0,0 +1,98 @@
+package httpwarm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTransport_DisabledReturnsBase(t *testing.T) {
+	EnableDNSCache(0)
+	base := &http.Transport{}
+	if got := Transport(base); got != base {
+		t.Errorf("Transport() = %v, want base unchanged", got)
+	}
+	if got := Transport(nil); got != nil {
+		t.Errorf("Transport(nil) = %v, want nil", got)
+	}
+}
+
+func TestTransport_CachesLookups(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+
+	var lookups atomic.Int32
+	orig := lookupHost
+	lookupHost = func(ctx context.Context, host string) ([]string, error) {
+		lookups.Add(1)
+		return []string{"127.0.0.1"}, nil
+	}
+	defer func() { lookupHost = orig }()
+
+	EnableDNSCache(time.Minute)
+	defer EnableDNSCache(0)
+
+	transport := Transport(nil).(*http.Transport)
+	transport.DisableKeepAlives = true
+	client := &http.Client{Transport: transport}
+
+	endpoint := "http://provider.test:" + u.Port() + "/v1"
+	for i := 0; i < 3; i++ {
+		if err := Warm(context.Background(), client, endpoint); err != nil {
+			t.Fatalf("Warm() error: %v", err)
+		}
+	}
+	if got := lookups.Load(); got != 1 {
+		t.Errorf("lookups = %d, want 1", got)
+	}
+}
+
+func TestTransport_StaleEntryInvalidated(t *testing.T) {
+	orig := lookupHost
+	lookupHost = func(ctx context.Context, host string) ([]string, error) {
+		return []string{"127.0.0.1"}, nil
+	}
+	defer func() { lookupHost = orig }()
+
+	EnableDNSCache(time.Minute)
+	defer EnableDNSCache(0)
+
+	// Grab a port with nothing listening on it.
+	ln := httptest.NewServer(http.NotFoundHandler())
+	u, _ := url.Parse(ln.URL)
+	ln.Close()
+
+	if _, err := dialContext(context.Background(), "tcp", "provider.test:"+u.Port()); err == nil {
+		t.Fatal("expected dial error")
+	}
+	mu.RLock()
+	_, cached := dnsCache["provider.test"]
+	mu.RUnlock()
+	if cached {
+		t.Error("expected failed entry to be evicted from the cache")
+	}
+}
+
+func TestKeepWarm_Repeats(t *testing.T) {
+	var calls atomic.Int32
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	KeepWarm(ctx, 10*time.Millisecond, func(context.Context) error {
+		calls.Add(1)
+		return nil
+	})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for calls.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := calls.Load(); got < 3 {
+		t.Errorf("warm called %d times, want at least 3", got)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
 )
 
 // CreateProvider creates a provider based on the configuration.
@@ -16,7 +17,23 @@ import (
 // The old providers config is automatically converted to model_list during config loading.
 // Returns the provider, the model ID to use, and any error.
 func CreateProvider(cfg *config.Config) (LLMProvider, string, error) {
-	model := cfg.Agents.Defaults.Model
+	return createModelProvider(cfg, cfg.Agents.Defaults.Model, "")
+}
+
+// CreateAccountProvider creates a provider for the model_list entry named
+// model that logs in with the stored credential of account instead of
+// the entry's own, for agents that use another account of the provider.
+func CreateAccountProvider(cfg *config.Config, model, account string) (LLMProvider, string, error) {
+	return createModelProvider(cfg, model, account)
+}
+
+// CreateModelProvider creates a provider for the model_list entry named
+// model, for requests that go to another model than the agent's.
+func CreateModelProvider(cfg *config.Config, model string) (LLMProvider, string, error) {
+	return createModelProvider(cfg, model, "")
+}
+
+func createModelProvider(cfg *config.Config, model, account string) (LLMProvider, string, error) {
 
 	// Ensure model_list is populated (should be done by LoadConfig, but handle edge cases)
 	if len(cfg.ModelList) == 0 && cfg.HasProvidersConfig() {
@@ -38,6 +55,18 @@ func CreateProvider(cfg *config.Config) (LLMProvider, string, error) {
 	if modelCfg.Workspace == "" {
 		modelCfg.Workspace = cfg.WorkspacePath()
 	}
+	if account != "" {
+		modelCfg.Account = account
+	}
+	if err := ValidateModel(modelCfg); err != nil {
+		if cfg.Agents.Defaults.StrictModels {
+			return nil, "", err
+		}
+		logger.WarnCF("provider", "Unknown model", map[string]interface{}{
+			"model": model,
+			"error": err.Error(),
+		})
+	}
 
 	// Use factory to create provider
 	provider, modelID, err := CreateProviderFromConfig(modelCfg)
@@ -0,0 +1,121 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+package providers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// providerLimiter enforces the rate limits of a model_list entry: its
+// requests and tokens per minute, with a Scheduler, and its requests in
+// flight at once.
+type providerLimiter struct {
+	name  string
+	quota *Scheduler    // nil without rpm and tpm
+	slots chan struct{} // nil without max_concurrent
+}
+
+var (
+	limitersMu sync.Mutex
+	// limiters holds the limiter of each model_list entry, so that every
+	// agent creating a provider for the entry shares it.
+	limiters = map[string]*providerLimiter{}
+)
+
+// limiterFor returns the shared limiter of the entry cfg, or nil if the
+// entry has no rate limits.
+func limiterFor(cfg *config.ModelConfig) *providerLimiter {
+	if cfg.RPM <= 0 && cfg.TPM <= 0 && cfg.MaxConcurrent <= 0 {
+		return nil
+	}
+	// Entries that only differ in their key or account have separate
+	// quotas; the key is hashed so it is not kept in memory twice.
+	sum := sha256.Sum256([]byte(cfg.ModelName + "\x00" + cfg.Model + "\x00" + cfg.APIBase + "\x00" + cfg.APIKey + "\x00" + cfg.Account))
+	key := hex.EncodeToString(sum[:])
+
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	if l, ok := limiters[key]; ok {
+		return l
+	}
+	l := &providerLimiter{name: cfg.ModelName}
+	if cfg.RPM > 0 || cfg.TPM > 0 {
+		l.quota = NewScheduler(cfg.RPM, cfg.TPM, 0)
+	}
+	if cfg.MaxConcurrent > 0 {
+		l.slots = make(chan struct{}, cfg.MaxConcurrent)
+	}
+	limiters[key] = l
+	return l
+}
+
+// middleware puts the limiter in front of a provider.
+func (l *providerLimiter) middleware(next LLMProvider) LLMProvider {
+	return &limitedProvider{LLMProvider: next, limiter: l}
+}
+
+// acquire waits until a request may be sent, or ctx is done. The returned
+// function must be called when the request is over.
+func (l *providerLimiter) acquire(ctx context.Context) (release func(), err error) {
+	start := time.Now()
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release = func() {
+		if l.slots != nil {
+			<-l.slots
+		}
+	}
+	if err := l.quota.Wait(ctx); err != nil {
+		release()
+		return nil, err
+	}
+	if waited := time.Since(start); waited >= time.Second {
+		logger.DebugCF("provider", "Request waited for the model's rate limits", logger.TraceFields(ctx, map[string]interface{}{
+			"model":  l.name,
+			"waited": waited.Round(time.Millisecond).String(),
+		}))
+	}
+	return release, nil
+}
+
+type limitedProvider struct {
+	LLMProvider
+	limiter *providerLimiter
+}
+
+func (p *limitedProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	release, err := p.limiter.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	resp, err := p.LLMProvider.Chat(ctx, messages, tools, model, options)
+	p.limiter.quota.Record(resp)
+	return resp, err
+}
+
+func (p *limitedProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onEvent func(StreamEvent)) (*LLMResponse, error) {
+	release, err := p.limiter.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	resp, err := StreamChat(ctx, p.LLMProvider, messages, tools, model, options, onEvent)
+	p.limiter.quota.Record(resp)
+	return resp, err
+}
@@ -0,0 +1,77 @@
+package providers
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// slowProvider records how many of its requests ran at once.
+type slowProvider struct {
+	running, most atomic.Int32
+}
+
+func (p *slowProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	n := p.running.Add(1)
+	defer p.running.Add(-1)
+	for {
+		most := p.most.Load()
+		if n <= most || p.most.CompareAndSwap(most, n) {
+			break
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	return &LLMResponse{Content: "ok"}, nil
+}
+
+func (p *slowProvider) GetDefaultModel() string { return "slow" }
+
+func TestLimiterFor(t *testing.T) {
+	cfg := &config.ModelConfig{ModelName: "limited", Model: "openai/gpt-4o", APIKey: "k1", MaxConcurrent: 2}
+	l := limiterFor(cfg)
+	if l == nil || limiterFor(&config.ModelConfig{ModelName: "limited", Model: "openai/gpt-4o", APIKey: "k1", MaxConcurrent: 2}) != l {
+		t.Fatal("providers for the same entry should share a limiter")
+	}
+	if limiterFor(&config.ModelConfig{ModelName: "limited", Model: "openai/gpt-4o", APIKey: "k2", MaxConcurrent: 2}) == l {
+		t.Error("entries with another key should have their own limiter")
+	}
+	if limiterFor(&config.ModelConfig{ModelName: "free", Model: "openai/gpt-4o"}) != nil {
+		t.Error("an entry without limits should not be limited")
+	}
+
+	// Two agents' providers for the entry share its slots.
+	inner := &slowProvider{}
+	a := chainMiddleware(inner, []Middleware{l.middleware})
+	b := chainMiddleware(inner, []Middleware{l.middleware})
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		p := a
+		if i%2 == 1 {
+			p = b
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.Chat(context.Background(), nil, nil, "m", nil)
+		}()
+	}
+	wg.Wait()
+	if most := inner.most.Load(); most != 2 {
+		t.Errorf("%d requests ran at once, want 2", most)
+	}
+
+	// A request that cannot get a slot gives up with its context.
+	for i := 0; i < 2; i++ {
+		l.slots <- struct{}{}
+		defer func() { <-l.slots }()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := a.Chat(ctx, nil, nil, "m", nil); err == nil {
+		t.Error("expected the request to time out waiting for a slot")
+	}
+}
@@ -0,0 +1,179 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// localServer describes an OpenAI-compatible server that runs on the
+// user's machine and needs no API key.
+type localServer struct {
+	protocol string
+	name     string
+	apiBase  string
+	// start tells the user how to start the server.
+	start string
+}
+
+var localServers = []localServer{
+	{
+		protocol: "lmstudio",
+		name:     "LM Studio",
+		apiBase:  "http://localhost:1234/v1",
+		start:    "start the server in LM Studio's Developer tab, or run `lms server start`",
+	},
+	{
+		protocol: "llamacpp",
+		name:     "llama.cpp server",
+		apiBase:  "http://localhost:8080/v1",
+		start:    "run `llama-server -m <model.gguf> --port 8080`",
+	},
+}
+
+func findLocalServer(protocol string) (localServer, bool) {
+	for _, s := range localServers {
+		if s.protocol == protocol {
+			return s, true
+		}
+	}
+	return localServer{}, false
+}
+
+// LocalServerProvider is the HTTP provider for a local server. It reports
+// a server that cannot be reached with how to start it, rather than with
+// the bare connection error.
+type LocalServerProvider struct {
+	delegate *HTTPProvider
+	server   localServer
+}
+
+func newLocalServerProvider(server localServer, apiKey, apiBase, proxy, maxTokensField string) *LocalServerProvider {
+	server.apiBase = apiBase
+	return &LocalServerProvider{
+		delegate: NewHTTPProviderWithMaxTokensField(apiKey, apiBase, proxy, maxTokensField),
+		server:   server,
+	}
+}
+
+func (p *LocalServerProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	resp, err := p.delegate.Chat(ctx, messages, tools, model, options)
+	return resp, p.explain(err)
+}
+
+func (p *LocalServerProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onEvent func(StreamEvent)) (*LLMResponse, error) {
+	resp, err := p.delegate.ChatStream(ctx, messages, tools, model, options, onEvent)
+	return resp, p.explain(err)
+}
+
+func (p *LocalServerProvider) Warm(ctx context.Context) error {
+	return p.explain(p.delegate.Warm(ctx))
+}
+
+func (p *LocalServerProvider) SupportsSeed() bool {
+	return true
+}
+
+// StructuredOutput implements StructuredOutputter: LM Studio and the
+// llama.cpp server both take a JSON schema as response_format.
+func (p *LocalServerProvider) StructuredOutput() StructuredMode {
+	return StructuredResponseFormat
+}
+
+func (p *LocalServerProvider) ListModels(ctx context.Context) ([]string, error) {
+	models, err := p.delegate.ListModels(ctx)
+	return models, p.explain(err)
+}
+
+func (p *LocalServerProvider) Embed(ctx context.Context, texts []string, model string) ([][]float32, error) {
+	vectors, err := p.delegate.Embed(ctx, texts, model)
+	return vectors, p.explain(err)
+}
+
+func (p *LocalServerProvider) GetDefaultModel() string {
+	return ""
+}
+
+// explain adds how to start the server to an error connecting to it.
+func (p *LocalServerProvider) explain(err error) error {
+	var opErr *net.OpError
+	if err == nil || !errors.As(err, &opErr) || opErr.Op != "dial" {
+		return err
+	}
+	return fmt.Errorf("%s is not reachable at %s; %s, or set api_base to where it listens: %w",
+		p.server.name, p.server.apiBase, p.server.start, err)
+}
+
+// LocalServer is a local server found running by DetectLocalServers.
+type LocalServer struct {
+	Name     string
+	Protocol string
+	APIBase  string
+	// Models are the IDs of the models the server offers.
+	Models []string
+}
+
+// DetectLocalServers returns the local servers, such as LM Studio, that
+// are running on their default ports.
+func DetectLocalServers(ctx context.Context) []LocalServer {
+	client := &http.Client{Timeout: time.Second}
+
+	found := make([]*LocalServer, len(localServers))
+	var wg sync.WaitGroup
+	for i, s := range localServers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			models, err := listLocalModels(ctx, client, s.apiBase)
+			if err != nil {
+				return
+			}
+			found[i] = &LocalServer{Name: s.name, Protocol: s.protocol, APIBase: s.apiBase, Models: models}
+		}()
+	}
+	wg.Wait()
+
+	var servers []LocalServer
+	for _, s := range found {
+		if s != nil {
+			servers = append(servers, *s)
+		}
+	}
+	return servers
+}
+
+// listLocalModels lists the models of the OpenAI-compatible server at
+// apiBase.
+func listLocalModels(ctx context.Context, client *http.Client, apiBase string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", apiBase+"/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, err
+	}
+	models := make([]string, 0, len(list.Data))
+	for _, m := range list.Data {
+		models = append(models, m.ID)
+	}
+	return models, nil
+}
@@ -0,0 +1,48 @@
+package providers
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLocalServerProvider_ExplainsUnreachableServer(t *testing.T) {
+	// Take a free port and close it, so nothing listens there.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiBase := "http://" + ln.Addr().String() + "/v1"
+	ln.Close()
+
+	server, _ := findLocalServer("lmstudio")
+	p := newLocalServerProvider(server, "", apiBase, "", "")
+	_, err = p.Chat(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, "local-model", nil)
+	if err == nil {
+		t.Fatal("Chat() succeeded without a server")
+	}
+	if msg := err.Error(); !strings.Contains(msg, "LM Studio is not reachable at "+apiBase) || !strings.Contains(msg, "lms server start") {
+		t.Errorf("error = %q, want how to start LM Studio", msg)
+	}
+}
+
+func TestListLocalModels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"object":"list","data":[{"id":"qwen3-8b"},{"id":"gemma-3-4b"}]}`))
+	}))
+	defer srv.Close()
+
+	models, err := listLocalModels(t.Context(), srv.Client(), srv.URL+"/v1")
+	if err != nil {
+		t.Fatalf("listLocalModels() error = %v", err)
+	}
+	if len(models) != 2 || models[0] != "qwen3-8b" || models[1] != "gemma-3-4b" {
+		t.Errorf("models = %v", models)
+	}
+}
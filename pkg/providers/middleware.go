@@ -0,0 +1,202 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+package providers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/features"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// Middleware wraps a provider to see or change its requests and responses,
+// for logging, redaction, rewriting or guardrails. The returned provider's
+// Chat usually calls next.Chat; returning an error instead blocks the
+// request. A middleware that also implements StreamingProvider, usually
+// with StreamChat on next, keeps replies streaming; otherwise the agent
+// gets each response whole.
+type Middleware func(next LLMProvider) LLMProvider
+
+var (
+	middlewareMu sync.RWMutex
+	middlewares  = map[string]Middleware{}
+)
+
+// RegisterMiddleware makes m available under name to the "middleware" list
+// of model_list entries. It is meant to be called from init functions, so
+// that a build can add middleware without changing the providers.
+func RegisterMiddleware(name string, m Middleware) {
+	middlewareMu.Lock()
+	defer middlewareMu.Unlock()
+	middlewares[name] = m
+	features.Register("middleware", name)
+}
+
+// WithMiddleware wraps p in the registered middleware named in names, the
+// first outermost, so that it sees requests first and responses last.
+func WithMiddleware(p LLMProvider, names []string) (LLMProvider, error) {
+	chain, err := resolveMiddleware(names)
+	if err != nil {
+		return nil, err
+	}
+	return chainMiddleware(p, chain), nil
+}
+
+// resolveMiddleware returns the registered middleware named in names.
+func resolveMiddleware(names []string) ([]Middleware, error) {
+	middlewareMu.RLock()
+	defer middlewareMu.RUnlock()
+	chain := make([]Middleware, len(names))
+	for i, name := range names {
+		m, ok := middlewares[name]
+		if !ok {
+			return nil, fmt.Errorf("provider middleware %q is not registered", name)
+		}
+		chain[i] = m
+	}
+	return chain, nil
+}
+
+// chainMiddleware wraps p in chain, the first outermost.
+func chainMiddleware(p LLMProvider, chain []Middleware) LLMProvider {
+	if len(chain) == 0 {
+		return p
+	}
+	out := p
+	for i := len(chain) - 1; i >= 0; i-- {
+		out = chain[i](out)
+	}
+	return &middlewareProvider{LLMProvider: out, base: p}
+}
+
+// middlewareProvider sends chat requests through a middleware chain and
+// everything else to the provider the chain ends in. It does not offer
+// the provider's file API, as uploaded documents would bypass the chain.
+type middlewareProvider struct {
+	LLMProvider
+	base LLMProvider
+}
+
+// fileStoreMiddlewareProvider is a middlewareProvider whose chain holds
+// only built-in links, such as rate limits and timeouts, and that offers
+// the file API of the provider the chain ends in.
+type fileStoreMiddlewareProvider struct {
+	*middlewareProvider
+}
+
+func (p *fileStoreMiddlewareProvider) UploadFile(ctx context.Context, name, mimeType string, data []byte) (string, error) {
+	return p.base.(FileStore).UploadFile(ctx, name, mimeType, data)
+}
+
+func (p *fileStoreMiddlewareProvider) DeleteFile(ctx context.Context, id string) error {
+	return p.base.(FileStore).DeleteFile(ctx, id)
+}
+
+// ChatStream streams through the chain as far as its middleware can.
+func (p *middlewareProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onEvent func(StreamEvent)) (*LLMResponse, error) {
+	return StreamChat(ctx, p.LLMProvider, messages, tools, model, options, onEvent)
+}
+
+func (p *middlewareProvider) SupportsSeed() bool {
+	s, ok := p.base.(Seeder)
+	return ok && s.SupportsSeed()
+}
+
+func (p *middlewareProvider) StructuredOutput() StructuredMode {
+	if s, ok := p.base.(StructuredOutputter); ok {
+		return s.StructuredOutput()
+	}
+	return StructuredPrompt
+}
+
+func (p *middlewareProvider) Warm(ctx context.Context) error {
+	if w, ok := p.base.(Warmer); ok {
+		return w.Warm(ctx)
+	}
+	return nil
+}
+
+func (p *middlewareProvider) ListModels(ctx context.Context) ([]string, error) {
+	if l, ok := p.base.(ModelLister); ok {
+		return l.ListModels(ctx)
+	}
+	return nil, nil
+}
+
+func (p *middlewareProvider) Embed(ctx context.Context, texts []string, model string) ([][]float32, error) {
+	if e, ok := p.base.(Embedder); ok {
+		return e.Embed(ctx, texts, model)
+	}
+	return nil, fmt.Errorf("provider has no embeddings API")
+}
+
+// StreamChat streams the response from p if p can stream, and otherwise
+// asks p with Chat and reports the whole response as one event per part.
+func StreamChat(ctx context.Context, p LLMProvider, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onEvent func(StreamEvent)) (*LLMResponse, error) {
+	if s, ok := p.(StreamingProvider); ok {
+		return s.ChatStream(ctx, messages, tools, model, options, onEvent)
+	}
+	resp, err := p.Chat(ctx, messages, tools, model, options)
+	if err != nil {
+		return resp, err
+	}
+	if resp.Content != "" {
+		onEvent(StreamEvent{Text: resp.Content})
+	}
+	for i := range resp.ToolCalls {
+		onEvent(StreamEvent{ToolCall: &resp.ToolCalls[i]})
+	}
+	return resp, nil
+}
+
+// The built-in "log" middleware logs each chat request and its outcome.
+func init() {
+	RegisterMiddleware("log", func(next LLMProvider) LLMProvider {
+		return &loggingProvider{LLMProvider: next}
+	})
+}
+
+type loggingProvider struct {
+	LLMProvider
+}
+
+func (p *loggingProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	start := time.Now()
+	resp, err := p.LLMProvider.Chat(ctx, messages, tools, model, options)
+	p.log(ctx, messages, tools, model, start, resp, err)
+	return resp, err
+}
+
+func (p *loggingProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onEvent func(StreamEvent)) (*LLMResponse, error) {
+	start := time.Now()
+	resp, err := StreamChat(ctx, p.LLMProvider, messages, tools, model, options, onEvent)
+	p.log(ctx, messages, tools, model, start, resp, err)
+	return resp, err
+}
+
+func (p *loggingProvider) log(ctx context.Context, messages []Message, tools []ToolDefinition, model string, start time.Time, resp *LLMResponse, err error) {
+	fields := logger.TraceFields(ctx, map[string]interface{}{
+		"model":       model,
+		"messages":    len(messages),
+		"tools":       len(tools),
+		"duration_ms": time.Since(start).Milliseconds(),
+	})
+	if err != nil {
+		fields["error"] = err.Error()
+		logger.WarnCF("provider", "Chat request failed", fields)
+		return
+	}
+	fields["finish_reason"] = resp.FinishReason
+	fields["tool_calls"] = len(resp.ToolCalls)
+	if resp.Usage != nil {
+		fields["prompt_tokens"] = resp.Usage.PromptTokens
+		fields["completion_tokens"] = resp.Usage.CompletionTokens
+	}
+	logger.InfoCF("provider", "Chat request", fields)
+}
@@ -0,0 +1,122 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// rewriteProvider is a test middleware that replaces text in requests and
+// notes the order it ran in.
+type rewriteProvider struct {
+	LLMProvider
+	from, to string
+	order    *[]string
+}
+
+func (p *rewriteProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	*p.order = append(*p.order, p.from)
+	if strings.Contains(messages[0].Content, "forbidden") {
+		return nil, errors.New("blocked")
+	}
+	out := append([]Message(nil), messages...)
+	out[0].Content = strings.ReplaceAll(out[0].Content, p.from, p.to)
+	return p.LLMProvider.Chat(ctx, out, tools, model, options)
+}
+
+func TestWithMiddleware(t *testing.T) {
+	var order []string
+	RegisterMiddleware("test-a", func(next LLMProvider) LLMProvider {
+		return &rewriteProvider{LLMProvider: next, from: "a", to: "b", order: &order}
+	})
+	RegisterMiddleware("test-b", func(next LLMProvider) LLMProvider {
+		return &rewriteProvider{LLMProvider: next, from: "b", to: "c", order: &order}
+	})
+
+	inner := &structuredMock{mode: StructuredResponseFormat, responses: []*LLMResponse{
+		{Content: "done", ToolCalls: []ToolCall{{ID: "1", Name: "t"}}},
+	}}
+	p, err := WithMiddleware(inner, []string{"test-a", "test-b"})
+	if err != nil {
+		t.Fatalf("WithMiddleware() error = %v", err)
+	}
+
+	var events []StreamEvent
+	resp, err := p.(StreamingProvider).ChatStream(context.Background(), []Message{{Role: "user", Content: "a"}}, nil, "m", nil, func(ev StreamEvent) {
+		events = append(events, ev)
+	})
+	if err != nil || resp.Content != "done" {
+		t.Fatalf("ChatStream() = %+v, %v", resp, err)
+	}
+	if got := inner.requests[0].messages[0].Content; got != "c" {
+		t.Errorf("provider got %q, want the request rewritten by both middleware", got)
+	}
+	if strings.Join(order, ",") != "a,b" {
+		t.Errorf("middleware ran in order %v", order)
+	}
+	if len(events) != 2 || events[0].Text != "done" || events[1].ToolCall == nil {
+		t.Errorf("events = %+v", events)
+	}
+	if p.(StructuredOutputter).StructuredOutput() != StructuredResponseFormat {
+		t.Error("structured output mode of the provider was not kept")
+	}
+	if _, ok := p.(FileStore); ok {
+		t.Error("middleware should not offer the file API")
+	}
+
+	if _, err := p.Chat(context.Background(), []Message{{Role: "user", Content: "forbidden"}}, nil, "m", nil); err == nil {
+		t.Error("expected middleware to block the request")
+	}
+	if _, err := WithMiddleware(inner, []string{"missing"}); err == nil {
+		t.Error("expected an error for unregistered middleware")
+	}
+}
+
+func TestCreateProviderFromConfig_Middleware(t *testing.T) {
+	p, _, err := CreateProviderFromConfig(&config.ModelConfig{
+		Model:      "openai/gpt-4o",
+		APIKey:     "key",
+		Middleware: []string{"log"},
+	})
+	if err != nil {
+		t.Fatalf("CreateProviderFromConfig() error = %v", err)
+	}
+	mp, ok := p.(*middlewareProvider)
+	if !ok {
+		t.Fatalf("provider = %T, want it wrapped in middleware", p)
+	}
+	if _, ok := mp.base.(*HTTPProvider); !ok {
+		t.Errorf("chain ends in %T", mp.base)
+	}
+}
+
+func TestCreateProviderFromConfig_LimitsKeepFileStore(t *testing.T) {
+	for _, cfg := range []*config.ModelConfig{
+		{Model: "openai/gpt-4o", APIKey: "key", RPM: 60},
+		{Model: "openai/gpt-4o", APIKey: "key", MaxConcurrent: 2},
+		{Model: "openai/gpt-4o", APIKey: "key", Timeouts: &config.ProviderTimeouts{ReadSeconds: 60}},
+	} {
+		p, _, err := CreateProviderFromConfig(cfg)
+		if err != nil {
+			t.Fatalf("CreateProviderFromConfig() error = %v", err)
+		}
+		if _, ok := p.(FileStore); !ok {
+			t.Errorf("provider %T for %+v has no file API", p, cfg)
+		}
+		if _, ok := p.(StreamingProvider); !ok {
+			t.Errorf("provider %T does not stream", p)
+		}
+	}
+
+	// Uploads would bypass other middleware, so it hides the file API.
+	p, _, err := CreateProviderFromConfig(&config.ModelConfig{Model: "openai/gpt-4o", APIKey: "key", RPM: 60, Middleware: []string{"log"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.(FileStore); ok {
+		t.Error("provider with middleware offers the file API")
+	}
+}
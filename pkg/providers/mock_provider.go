@@ -0,0 +1,157 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sync"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// MockFixture scripts the replies of a MockProvider.
+type MockFixture struct {
+	Responses []MockResponse `json:"responses"`
+}
+
+// MockResponse is one scripted reply. Match, a regular expression, limits
+// it to requests whose last message it matches; Error makes the request
+// fail with that message instead.
+type MockResponse struct {
+	Match        string         `json:"match,omitempty"`
+	Content      string         `json:"content,omitempty"`
+	ToolCalls    []MockToolCall `json:"tool_calls,omitempty"`
+	FinishReason string         `json:"finish_reason,omitempty"`
+	Usage        *UsageInfo     `json:"usage,omitempty"`
+	Error        string         `json:"error,omitempty"`
+
+	match *regexp.Regexp
+}
+
+// MockToolCall is a scripted tool call. ID defaults to call_<n>, numbered
+// across the provider's replies.
+type MockToolCall struct {
+	ID        string                 `json:"id,omitempty"`
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+}
+
+// MockProvider answers with scripted replies, for testing agents without a
+// network or API keys. Each request gets the first unused reply that
+// matches it, so the same fixture gives the same conversation every time;
+// a request no reply is left for fails.
+type MockProvider struct {
+	model string
+
+	mu        sync.Mutex
+	responses []MockResponse
+	calls     int
+}
+
+// LoadMockFixture reads a fixture from a JSON file.
+func LoadMockFixture(path string) (MockFixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return MockFixture{}, fmt.Errorf("reading mock fixture: %w", err)
+	}
+	var f MockFixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return MockFixture{}, fmt.Errorf("parsing mock fixture %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// NewMockProvider returns a provider of the replies in f, reporting model
+// as its default.
+func NewMockProvider(f MockFixture, model string) (*MockProvider, error) {
+	responses := make([]MockResponse, len(f.Responses))
+	for i, r := range f.Responses {
+		if r.Match != "" {
+			re, err := regexp.Compile(r.Match)
+			if err != nil {
+				return nil, fmt.Errorf("mock response %d: invalid match: %w", i+1, err)
+			}
+			r.match = re
+		}
+		for _, tc := range r.ToolCalls {
+			if tc.Name == "" {
+				return nil, fmt.Errorf("mock response %d: tool call without a name", i+1)
+			}
+		}
+		responses[i] = r
+	}
+	return &MockProvider{model: model, responses: responses}, nil
+}
+
+func (p *MockProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	last := ""
+	if len(messages) > 0 {
+		last = messages[len(messages)-1].Content
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, r := range p.responses {
+		if r.match != nil && !r.match.MatchString(last) {
+			continue
+		}
+		p.responses = append(p.responses[:i:i], p.responses[i+1:]...)
+		if r.Error != "" {
+			return nil, errors.New(r.Error)
+		}
+		return p.reply(r), nil
+	}
+	return nil, fmt.Errorf("mock provider: no scripted response left for %q", last)
+}
+
+func (p *MockProvider) reply(r MockResponse) *LLMResponse {
+	resp := &LLMResponse{Content: r.Content, FinishReason: r.FinishReason, Usage: r.Usage, Model: p.model}
+	for _, tc := range r.ToolCalls {
+		p.calls++
+		id := tc.ID
+		if id == "" {
+			id = fmt.Sprintf("call_%d", p.calls)
+		}
+		resp.ToolCalls = append(resp.ToolCalls, NormalizeToolCall(ToolCall{ID: id, Type: "function", Name: tc.Name, Arguments: tc.Arguments}))
+	}
+	if resp.FinishReason == "" {
+		resp.FinishReason = "stop"
+		if len(resp.ToolCalls) > 0 {
+			resp.FinishReason = "tool_calls"
+		}
+	}
+	return resp
+}
+
+func (p *MockProvider) GetDefaultModel() string {
+	return p.model
+}
+
+// Remaining returns the number of scripted replies not used yet, for tests
+// to check that a conversation went as scripted.
+func (p *MockProvider) Remaining() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.responses)
+}
+
+func init() {
+	RegisterProvider("mock", func(cfg *config.ModelConfig, modelID string) (LLMProvider, error) {
+		if cfg.Fixture == "" {
+			return nil, fmt.Errorf("fixture is required for mock protocol (model: %s)", cfg.Model)
+		}
+		f, err := LoadMockFixture(cfg.Fixture)
+		if err != nil {
+			return nil, err
+		}
+		return NewMockProvider(f, modelID)
+	})
+}
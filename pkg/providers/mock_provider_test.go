@@ -0,0 +1,79 @@
+package providers
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestMockProvider_Script(t *testing.T) {
+	p, err := NewMockProvider(MockFixture{Responses: []MockResponse{
+		{Match: "(?i)weather", ToolCalls: []MockToolCall{{Name: "web_fetch", Arguments: map[string]interface{}{"url": "https://wttr.in"}}}},
+		{Match: "^Sunny", Content: "It is sunny."},
+		{Error: "rate limited"},
+		{Content: "Hello!"},
+	}}, "scripted")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "What's the weather?"}}, nil, "scripted", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].ID != "call_1" || resp.ToolCalls[0].Name != "web_fetch" || resp.FinishReason != "tool_calls" {
+		t.Fatalf("response = %+v, want the web_fetch call", resp)
+	}
+	if resp.ToolCalls[0].Function == nil || resp.ToolCalls[0].Function.Arguments != `{"url":"https://wttr.in"}` {
+		t.Errorf("tool call function = %+v", resp.ToolCalls[0].Function)
+	}
+
+	resp, err = p.Chat(t.Context(), []Message{{Role: "tool", Content: "Sunny, 21C"}}, nil, "scripted", nil)
+	if err != nil || resp.Content != "It is sunny." || resp.FinishReason != "stop" {
+		t.Fatalf("Chat() = %+v, %v; want the matching reply", resp, err)
+	}
+
+	if _, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "Hi"}}, nil, "scripted", nil); err == nil || err.Error() != "rate limited" {
+		t.Errorf("Chat() error = %v, want the scripted error", err)
+	}
+	resp, err = p.Chat(t.Context(), []Message{{Role: "user", Content: "Hi"}}, nil, "scripted", nil)
+	if err != nil || resp.Content != "Hello!" {
+		t.Errorf("Chat() = %+v, %v", resp, err)
+	}
+
+	if p.Remaining() != 0 {
+		t.Errorf("Remaining() = %d, want 0", p.Remaining())
+	}
+	if _, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "Hi"}}, nil, "scripted", nil); err == nil || !strings.Contains(err.Error(), "no scripted response") {
+		t.Errorf("Chat() error = %v once the script is used up", err)
+	}
+}
+
+func TestMockProvider_FromConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixture.json")
+	if err := os.WriteFile(path, []byte(`{"responses": [{"content": "pong"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, modelID, err := CreateProviderFromConfig(&config.ModelConfig{ModelName: "test", Model: "mock/scripted", Fixture: path})
+	if err != nil {
+		t.Fatalf("CreateProviderFromConfig() error = %v", err)
+	}
+	if _, ok := p.(*MockProvider); !ok || modelID != "scripted" {
+		t.Fatalf("provider = %T, model = %q", p, modelID)
+	}
+	resp, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "ping"}}, nil, modelID, nil)
+	if err != nil || resp.Content != "pong" {
+		t.Errorf("Chat() = %+v, %v", resp, err)
+	}
+
+	if _, _, err := CreateProviderFromConfig(&config.ModelConfig{ModelName: "test", Model: "mock/scripted"}); err == nil {
+		t.Error("CreateProviderFromConfig() error = nil without a fixture")
+	}
+	if _, err := NewMockProvider(MockFixture{Responses: []MockResponse{{Match: "("}}}, "m"); err == nil {
+		t.Error("NewMockProvider() error = nil for an invalid match")
+	}
+}
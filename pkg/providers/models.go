@@ -0,0 +1,95 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// listModelsTimeout bounds how long ListAvailableModels waits for one
+// provider.
+const listModelsTimeout = 15 * time.Second
+
+// AvailableModel is a model one of the configured providers serves.
+type AvailableModel struct {
+	// Model is the model with its protocol prefix, as model_list takes it.
+	Model string `json:"model"`
+	// ModelName is the model_name of the model's model_list entry, if it
+	// has one.
+	ModelName string `json:"model_name,omitempty"`
+}
+
+// ListAvailableModels lists, sorted, the models of each provider in the
+// model_list of cfg, and the models configured there. Providers that
+// cannot list their models, or fail to, only contribute their configured
+// models; their errors are returned alongside.
+func ListAvailableModels(ctx context.Context, cfg *config.Config) ([]AvailableModel, []error) {
+	// Entries that share a provider and credentials list the same models.
+	type providerKey struct{ protocol, apiBase, apiKey string }
+	groups := make(map[providerKey]config.ModelConfig)
+	byModel := make(map[string]*AvailableModel)
+	for _, mc := range cfg.ModelList {
+		protocol, _ := ExtractProtocol(mc.Model)
+		key := providerKey{protocol, mc.APIBase, mc.APIKey}
+		if _, ok := groups[key]; !ok {
+			groups[key] = mc
+		}
+		if _, ok := byModel[mc.Model]; !ok {
+			byModel[mc.Model] = &AvailableModel{Model: mc.Model, ModelName: mc.ModelName}
+		}
+	}
+
+	var (
+		mu   sync.Mutex
+		errs []error
+		wg   sync.WaitGroup
+	)
+	for key, mc := range groups {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			models, err := listProviderModels(ctx, &mc)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s (%s): %w", key.protocol, mc.ModelName, err))
+				return
+			}
+			for _, id := range models {
+				model := key.protocol + "/" + id
+				if _, ok := byModel[model]; !ok {
+					byModel[model] = &AvailableModel{Model: model}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	models := make([]AvailableModel, 0, len(byModel))
+	for _, m := range byModel {
+		models = append(models, *m)
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].Model < models[j].Model })
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return models, errs
+}
+
+// listProviderModels lists the models of the provider of mc, or none if
+// it cannot list them.
+func listProviderModels(ctx context.Context, mc *config.ModelConfig) ([]string, error) {
+	provider, _, err := CreateProviderFromConfig(mc)
+	if err != nil {
+		return nil, err
+	}
+	lister, ok := provider.(ModelLister)
+	if !ok {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, listModelsTimeout)
+	defer cancel()
+	return lister.ListModels(ctx)
+}
@@ -0,0 +1,54 @@
+package providers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestListAvailableModels(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/v1/models" || r.Header.Get("Authorization") != "Bearer key" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"data":[{"id":"llama-3.3-70b"},{"id":"qwen3-32b"}]}`))
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{ModelList: []config.ModelConfig{
+		{ModelName: "fast", Model: "groq/llama-3.3-70b", APIBase: srv.URL + "/v1", APIKey: "key"},
+		{ModelName: "fast-too", Model: "groq/qwen3-32b", APIBase: srv.URL + "/v1", APIKey: "key"},
+		{ModelName: "code", Model: "claude-cli/sonnet"},
+		{ModelName: "broken", Model: "deepseek/deepseek-chat"},
+	}}
+
+	models, errs := ListAvailableModels(t.Context(), cfg)
+	if requests != 1 {
+		t.Errorf("models listed %d times for one provider, want once", requests)
+	}
+	if len(errs) != 1 {
+		t.Errorf("errs = %v, want one for the provider without credentials", errs)
+	}
+
+	want := []AvailableModel{
+		{Model: "claude-cli/haiku"},
+		{Model: "claude-cli/opus"},
+		{Model: "claude-cli/sonnet", ModelName: "code"},
+		{Model: "deepseek/deepseek-chat", ModelName: "broken"},
+		{Model: "groq/llama-3.3-70b", ModelName: "fast"},
+		{Model: "groq/qwen3-32b", ModelName: "fast-too"},
+	}
+	if len(models) != len(want) {
+		t.Fatalf("models = %+v, want %+v", models, want)
+	}
+	for i := range want {
+		if models[i] != want[i] {
+			t.Errorf("models[%d] = %+v, want %+v", i, models[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package openai_compat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Embed returns the embeddings of texts from the API's embeddings
+// endpoint, in the order of texts.
+func (p *Provider) Embed(ctx context.Context, texts []string, model string) ([][]float32, error) {
+	if p.apiBase == "" {
+		return nil, fmt.Errorf("API base not configured")
+	}
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"model": model,
+		"input": texts,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", p.apiBase+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	p.setAuth(req)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API request failed:\n  Status: %d\n  Body:   %s", resp.StatusCode, string(respBody))
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("got %d embeddings for %d texts", len(result.Data), len(texts))
+	}
+	vectors := make([][]float32, len(texts))
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= len(texts) || vectors[d.Index] != nil {
+			return nil, fmt.Errorf("embedding with invalid index %d", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
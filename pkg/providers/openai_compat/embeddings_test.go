@@ -0,0 +1,37 @@
+package openai_compat
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProvider_Embed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "text-embedding-3-small" || len(req.Input) != 2 {
+			t.Errorf("request = %+v", req)
+		}
+		// Out of order, as the API does not promise any.
+		w.Write([]byte(`{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`))
+	}))
+	defer server.Close()
+
+	p := NewProvider("key", server.URL, "")
+	vectors, err := p.Embed(t.Context(), []string{"a", "b"}, "text-embedding-3-small")
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	if len(vectors) != 2 || vectors[0][0] != 1 || vectors[1][1] != 1 {
+		t.Errorf("vectors = %v", vectors)
+	}
+}
@@ -0,0 +1,125 @@
+package openai_compat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+
+	"github.com/sipeed/picoclaw/pkg/providers/protocoltypes"
+)
+
+// UploadFile stores a document with the provider's Files API and returns
+// its ID. Chat completions only accept PDF file inputs, so other types are
+// rejected before uploading.
+func (p *Provider) UploadFile(ctx context.Context, name, mimeType string, data []byte) (string, error) {
+	if p.apiBase == "" {
+		return "", fmt.Errorf("API base not configured")
+	}
+	if mimeType != "application/pdf" {
+		return "", fmt.Errorf("unsupported file type %s", mimeType)
+	}
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	w.WriteField("purpose", "user_data")
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, name))
+	header.Set("Content-Type", mimeType)
+	part, err := w.CreatePart(header)
+	if err != nil {
+		return "", fmt.Errorf("failed to build upload: %w", err)
+	}
+	part.Write(data)
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("failed to build upload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.apiBase+"/files", &body)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	respBody, err := p.doFilesRequest(req)
+	if err != nil {
+		return "", err
+	}
+	var file struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(respBody, &file); err != nil || file.ID == "" {
+		return "", fmt.Errorf("unexpected upload response: %s", string(respBody))
+	}
+	return file.ID, nil
+}
+
+// DeleteFile removes an uploaded document.
+func (p *Provider) DeleteFile(ctx context.Context, id string) error {
+	req, err := http.NewRequestWithContext(ctx, "DELETE", p.apiBase+"/files/"+id, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	_, err = p.doFilesRequest(req)
+	return err
+}
+
+func (p *Provider) doFilesRequest(req *http.Request) ([]byte, error) {
+	p.setAuth(req)
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("files API request failed: %w: %s", protocoltypes.ErrFileNotFound, string(body))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("files API request failed:\n  Status: %d\n  Body:   %s", resp.StatusCode, string(body))
+	}
+	return body, nil
+}
+
+// wireMessages converts messages to the chat completions format. Messages
+// with attached files or images use the content-parts form; all others are
+// sent as they are.
+func wireMessages(messages []Message) []interface{} {
+	out := make([]interface{}, 0, len(messages))
+	for _, m := range messages {
+		if len(m.Files) == 0 && len(m.Images) == 0 {
+			out = append(out, m)
+			continue
+		}
+
+		parts := make([]map[string]interface{}, 0, len(m.Files)+len(m.Images)+1)
+		if m.Content != "" {
+			parts = append(parts, map[string]interface{}{"type": "text", "text": m.Content})
+		}
+		for _, f := range m.Files {
+			parts = append(parts, map[string]interface{}{
+				"type": "file",
+				"file": map[string]string{"file_id": f.ID},
+			})
+		}
+		for _, img := range m.Images {
+			parts = append(parts, map[string]interface{}{
+				"type":      "image_url",
+				"image_url": map[string]string{"url": img.DataURL()},
+			})
+		}
+		out = append(out, map[string]interface{}{
+			"role":    m.Role,
+			"content": parts,
+		})
+	}
+	return out
+}
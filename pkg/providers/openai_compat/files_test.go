@@ -0,0 +1,131 @@
+package openai_compat
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/providers/protocoltypes"
+)
+
+func TestProvider_UploadAndDeleteFile(t *testing.T) {
+	var deleted string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/files":
+			if r.FormValue("purpose") != "user_data" {
+				t.Errorf("purpose = %q", r.FormValue("purpose"))
+			}
+			f, hdr, err := r.FormFile("file")
+			if err != nil {
+				t.Fatalf("missing file part: %v", err)
+			}
+			data, _ := io.ReadAll(f)
+			if hdr.Filename != "report.pdf" || string(data) != "%PDF-1.4" {
+				t.Errorf("unexpected upload %q: %q", hdr.Filename, data)
+			}
+			json.NewEncoder(w).Encode(map[string]string{"id": "file-abc"})
+		case r.Method == http.MethodDelete && r.URL.Path == "/files/file-gone":
+			http.Error(w, `{"error":{"message":"No such File object"}}`, http.StatusNotFound)
+		case r.Method == http.MethodDelete:
+			deleted = r.URL.Path
+			json.NewEncoder(w).Encode(map[string]interface{}{"deleted": true})
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	p := NewProvider("key", server.URL, "")
+	id, err := p.UploadFile(t.Context(), "report.pdf", "application/pdf", []byte("%PDF-1.4"))
+	if err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	if id != "file-abc" {
+		t.Errorf("id = %q, want file-abc", id)
+	}
+
+	if err := p.DeleteFile(t.Context(), id); err != nil {
+		t.Fatalf("DeleteFile() error = %v", err)
+	}
+	if deleted != "/files/file-abc" {
+		t.Errorf("deleted path = %q", deleted)
+	}
+	if err := p.DeleteFile(t.Context(), "file-gone"); !errors.Is(err, protocoltypes.ErrFileNotFound) {
+		t.Errorf("DeleteFile() error = %v, want ErrFileNotFound", err)
+	}
+
+	if _, err := p.UploadFile(t.Context(), "notes.txt", "text/plain", []byte("x")); err == nil {
+		t.Error("expected an error for a non-PDF upload")
+	}
+}
+
+func TestProviderChat_SendsFileParts(t *testing.T) {
+	var requestBody struct {
+		Messages []map[string]interface{} `json:"messages"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&requestBody)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]interface{}{"content": "ok"}, "finish_reason": "stop"},
+			},
+		})
+	}))
+	defer server.Close()
+
+	p := NewProvider("key", server.URL, "")
+	messages := []Message{
+		{Role: "system", Content: "sys"},
+		{Role: "user", Content: "summarize", Files: []FileRef{{ID: "file-abc", Name: "report.pdf"}}},
+	}
+	if _, err := p.Chat(t.Context(), messages, nil, "gpt-4o", nil); err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+
+	if got := requestBody.Messages[0]["content"]; got != "sys" {
+		t.Errorf("plain message content = %v", got)
+	}
+	if _, ok := requestBody.Messages[0]["files"]; ok {
+		t.Error("files field leaked into the request")
+	}
+	parts, ok := requestBody.Messages[1]["content"].([]interface{})
+	if !ok || len(parts) != 2 {
+		t.Fatalf("expected text and file parts, got %v", requestBody.Messages[1]["content"])
+	}
+	file := parts[1].(map[string]interface{})
+	if file["type"] != "file" || file["file"].(map[string]interface{})["file_id"] != "file-abc" {
+		t.Errorf("unexpected file part: %v", file)
+	}
+}
+
+func TestWireMessages_ImageParts(t *testing.T) {
+	out := wireMessages([]Message{{
+		Role:    "user",
+		Content: "what is this?",
+		Images: []Image{
+			{URL: "https://example.com/cat.png"},
+			{Data: "aGVsbG8=", MimeType: "image/jpeg"},
+		},
+	}})
+
+	data, _ := json.Marshal(out[0])
+	var msg struct {
+		Content []struct {
+			Type     string            `json:"type"`
+			ImageURL map[string]string `json:"image_url"`
+		} `json:"content"`
+	}
+	if err := json.Unmarshal(data, &msg); err != nil || len(msg.Content) != 3 {
+		t.Fatalf("content = %s", data)
+	}
+	if msg.Content[1].Type != "image_url" || msg.Content[1].ImageURL["url"] != "https://example.com/cat.png" {
+		t.Errorf("URL image part = %+v", msg.Content[1])
+	}
+	if msg.Content[2].ImageURL["url"] != "data:image/jpeg;base64,aGVsbG8=" {
+		t.Errorf("inline image part = %+v", msg.Content[2])
+	}
+}
@@ -10,8 +10,12 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
-	"time"
 
+	"github.com/sipeed/picoclaw/pkg/egress"
+	"github.com/sipeed/picoclaw/pkg/providers/httpcapture"
+	"github.com/sipeed/picoclaw/pkg/providers/httpretry"
+	"github.com/sipeed/picoclaw/pkg/providers/httptimeout"
+	"github.com/sipeed/picoclaw/pkg/providers/httpwarm"
 	"github.com/sipeed/picoclaw/pkg/providers/protocoltypes"
 )
 
@@ -24,12 +28,17 @@ type ToolDefinition = protocoltypes.ToolDefinition
 type ToolFunctionDefinition = protocoltypes.ToolFunctionDefinition
 type ExtraContent = protocoltypes.ExtraContent
 type GoogleExtra = protocoltypes.GoogleExtra
+type FileRef = protocoltypes.FileRef
+type Image = protocoltypes.Image
 
 type Provider struct {
 	apiKey         string
 	apiBase        string
 	maxTokensField string // Field name for max tokens (e.g., "max_completion_tokens" for o1/glm models)
 	httpClient     *http.Client
+	// authHeader and authValue replace the bearer token when set.
+	authHeader string
+	authValue  string
 }
 
 func NewProvider(apiKey, apiBase, proxy string) *Provider {
@@ -37,9 +46,7 @@ func NewProvider(apiKey, apiBase, proxy string) *Provider {
 }
 
 func NewProviderWithMaxTokensField(apiKey, apiBase, proxy, maxTokensField string) *Provider {
-	client := &http.Client{
-		Timeout: 120 * time.Second,
-	}
+	client := &http.Client{}
 
 	if proxy != "" {
 		parsed, err := url.Parse(proxy)
@@ -51,6 +58,7 @@ func NewProviderWithMaxTokensField(apiKey, apiBase, proxy, maxTokensField string
 			log.Printf("openai_compat: invalid proxy URL %q: %v", proxy, err)
 		}
 	}
+	client.Transport = httpretry.Transport(httpcapture.Wrap(httptimeout.Transport(egress.Transport(egress.Providers, httpwarm.Transport(client.Transport)))))
 
 	return &Provider{
 		apiKey:         apiKey,
@@ -60,16 +68,79 @@ func NewProviderWithMaxTokensField(apiKey, apiBase, proxy, maxTokensField string
 	}
 }
 
-func (p *Provider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+// WithAuthHeader makes the provider authenticate with the header name set
+// to value instead of a bearer token, for APIs that take the key
+// elsewhere. It returns p.
+func (p *Provider) WithAuthHeader(name, value string) *Provider {
+	p.authHeader = name
+	p.authValue = value
+	return p
+}
+
+// setAuth adds the provider's credentials to req.
+func (p *Provider) setAuth(req *http.Request) {
+	if p.authHeader != "" {
+		req.Header.Set(p.authHeader, p.authValue)
+		return
+	}
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+}
+
+// Warm opens a connection to the API host ahead of the first request.
+func (p *Provider) Warm(ctx context.Context) error {
 	if p.apiBase == "" {
-		return nil, fmt.Errorf("API base not configured")
+		return fmt.Errorf("API base not configured")
 	}
+	return httpwarm.Warm(ctx, p.httpClient, p.apiBase)
+}
 
+// responseFormatHosts are the API hosts known to accept a JSON schema as
+// response_format. Others may reject it or only take json_object.
+var responseFormatHosts = []string{
+	"api.openai.com",
+	"openrouter.ai",
+	"generativelanguage.googleapis.com",
+	"api.groq.com",
+	"api.mistral.ai",
+	"api.x.ai",
+	"api.cerebras.ai",
+}
+
+// SupportsResponseFormat reports whether the API takes a JSON schema as
+// the response_format option.
+func (p *Provider) SupportsResponseFormat() bool {
+	base := strings.ToLower(p.apiBase)
+	for _, host := range responseFormatHosts {
+		if strings.Contains(base, "//"+host) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Provider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	resp, err := p.post(ctx, p.requestBody(messages, tools, model, options))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return parseResponse(body)
+}
+
+func (p *Provider) requestBody(messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) map[string]interface{} {
 	model = normalizeModel(model, p.apiBase)
 
 	requestBody := map[string]interface{}{
 		"model":    model,
-		"messages": messages,
+		"messages": wireMessages(messages),
 	}
 
 	if len(tools) > 0 {
@@ -102,6 +173,24 @@ func (p *Provider) Chat(ctx context.Context, messages []Message, tools []ToolDef
 		}
 	}
 
+	if seed, ok := asInt(options["seed"]); ok {
+		requestBody["seed"] = seed
+	}
+
+	if format, ok := options["response_format"].(map[string]interface{}); ok {
+		requestBody["response_format"] = format
+	}
+
+	return requestBody
+}
+
+// post sends a chat completion request. The caller must close the body of
+// the returned response, which always has status 200.
+func (p *Provider) post(ctx context.Context, requestBody map[string]interface{}) (*http.Response, error) {
+	if p.apiBase == "" {
+		return nil, fmt.Errorf("API base not configured")
+	}
+
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
@@ -113,26 +202,23 @@ func (p *Provider) Chat(ctx context.Context, messages []Message, tools []ToolDef
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	if p.apiKey != "" {
-		req.Header.Set("Authorization", "Bearer "+p.apiKey)
-	}
+	p.setAuth(req)
 
 	resp, err := p.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
 
 	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", err)
+		}
 		return nil, fmt.Errorf("API request failed:\n  Status: %d\n  Body:   %s", resp.StatusCode, string(body))
 	}
 
-	return parseResponse(body)
+	return resp, nil
 }
 
 func parseResponse(body []byte) (*LLMResponse, error) {
@@ -156,7 +242,9 @@ func parseResponse(body []byte) (*LLMResponse, error) {
 			} `json:"message"`
 			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
-		Usage *UsageInfo `json:"usage"`
+		Usage             *apiUsage `json:"usage"`
+		Model             string    `json:"model"`
+		SystemFingerprint string    `json:"system_fingerprint"`
 	}
 
 	if err := json.Unmarshal(body, &apiResponse); err != nil {
@@ -165,58 +253,93 @@ func parseResponse(body []byte) (*LLMResponse, error) {
 
 	if len(apiResponse.Choices) == 0 {
 		return &LLMResponse{
-			Content:      "",
-			FinishReason: "stop",
+			Content:           "",
+			FinishReason:      "stop",
+			Model:             apiResponse.Model,
+			SystemFingerprint: apiResponse.SystemFingerprint,
 		}, nil
 	}
 
 	choice := apiResponse.Choices[0]
 	toolCalls := make([]ToolCall, 0, len(choice.Message.ToolCalls))
 	for _, tc := range choice.Message.ToolCalls {
-		arguments := make(map[string]interface{})
-		name := ""
-
 		// Extract thought_signature from Gemini/Google-specific extra content
 		thoughtSignature := ""
 		if tc.ExtraContent != nil && tc.ExtraContent.Google != nil {
 			thoughtSignature = tc.ExtraContent.Google.ThoughtSignature
 		}
 
+		var name, rawArguments string
 		if tc.Function != nil {
 			name = tc.Function.Name
-			if tc.Function.Arguments != "" {
-				if err := json.Unmarshal([]byte(tc.Function.Arguments), &arguments); err != nil {
-					log.Printf("openai_compat: failed to decode tool call arguments for %q: %v", name, err)
-					arguments["raw"] = tc.Function.Arguments
-				}
-			}
+			rawArguments = tc.Function.Arguments
 		}
 
-		// Build ToolCall with ExtraContent for Gemini 3 thought_signature persistence
-		toolCall := ToolCall{
-			ID:               tc.ID,
-			Name:             name,
-			Arguments:        arguments,
-			ThoughtSignature: thoughtSignature,
-		}
+		toolCalls = append(toolCalls, newToolCall(tc.ID, name, rawArguments, thoughtSignature))
+	}
 
-		if thoughtSignature != "" {
-			toolCall.ExtraContent = &ExtraContent{
-				Google: &GoogleExtra{
-					ThoughtSignature: thoughtSignature,
-				},
-			}
+	return &LLMResponse{
+		Content:           choice.Message.Content,
+		ToolCalls:         toolCalls,
+		FinishReason:      choice.FinishReason,
+		Usage:             apiResponse.Usage.info(),
+		Model:             apiResponse.Model,
+		SystemFingerprint: apiResponse.SystemFingerprint,
+	}, nil
+}
+
+// apiUsage is the usage object of a response, which reports cached prompt
+// tokens in a nested object.
+type apiUsage struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	TotalTokens         int `json:"total_tokens"`
+	PromptTokensDetails *struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+}
+
+func (u *apiUsage) info() *UsageInfo {
+	if u == nil {
+		return nil
+	}
+	info := &UsageInfo{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.TotalTokens,
+	}
+	if u.PromptTokensDetails != nil {
+		info.CachedTokens = u.PromptTokensDetails.CachedTokens
+	}
+	return info
+}
+
+func newToolCall(id, name, rawArguments, thoughtSignature string) ToolCall {
+	arguments := make(map[string]interface{})
+	if rawArguments != "" {
+		if err := json.Unmarshal([]byte(rawArguments), &arguments); err != nil {
+			log.Printf("openai_compat: failed to decode tool call arguments for %q: %v", name, err)
+			arguments["raw"] = rawArguments
 		}
+	}
 
-		toolCalls = append(toolCalls, toolCall)
+	// Build ToolCall with ExtraContent for Gemini 3 thought_signature persistence
+	toolCall := ToolCall{
+		ID:               id,
+		Name:             name,
+		Arguments:        arguments,
+		ThoughtSignature: thoughtSignature,
 	}
 
-	return &LLMResponse{
-		Content:      choice.Message.Content,
-		ToolCalls:    toolCalls,
-		FinishReason: choice.FinishReason,
-		Usage:        apiResponse.Usage,
-	}, nil
+	if thoughtSignature != "" {
+		toolCall.ExtraContent = &ExtraContent{
+			Google: &GoogleExtra{
+				ThoughtSignature: thoughtSignature,
+			},
+		}
+	}
+
+	return toolCall
 }
 
 func normalizeModel(model, apiBase string) string {
@@ -231,7 +354,7 @@ func normalizeModel(model, apiBase string) string {
 
 	prefix := strings.ToLower(model[:idx])
 	switch prefix {
-	case "moonshot", "nvidia", "groq", "ollama", "deepseek", "google", "openrouter", "zhipu":
+	case "moonshot", "nvidia", "groq", "ollama", "deepseek", "google", "openrouter", "zhipu", "mistral", "xai":
 		return model[idx+1:]
 	default:
 		return model
@@ -69,9 +69,10 @@ func TestProviderChat_ParsesToolCalls(t *testing.T) {
 				},
 			},
 			"usage": map[string]interface{}{
-				"prompt_tokens":     10,
-				"completion_tokens": 5,
-				"total_tokens":      15,
+				"prompt_tokens":         10,
+				"completion_tokens":     5,
+				"total_tokens":          15,
+				"prompt_tokens_details": map[string]interface{}{"cached_tokens": 4},
 			},
 		}
 		w.Header().Set("Content-Type", "application/json")
@@ -93,6 +94,9 @@ func TestProviderChat_ParsesToolCalls(t *testing.T) {
 	if out.ToolCalls[0].Arguments["city"] != "SF" {
 		t.Fatalf("ToolCalls[0].Arguments[city] = %v, want SF", out.ToolCalls[0].Arguments["city"])
 	}
+	if out.Usage == nil || out.Usage.TotalTokens != 15 || out.Usage.CachedTokens != 4 {
+		t.Fatalf("Usage = %+v, want 15 tokens with 4 cached", out.Usage)
+	}
 }
 
 func TestProviderChat_HTTPError(t *testing.T) {
@@ -211,9 +215,13 @@ func TestProvider_ProxyConfigured(t *testing.T) {
 	proxyURL := "http://127.0.0.1:8080"
 	p := NewProvider("key", "https://example.com", proxyURL)
 
-	transport, ok := p.httpClient.Transport.(*http.Transport)
+	rt := p.httpClient.Transport
+	if u, ok := rt.(interface{ Unwrap() http.RoundTripper }); ok {
+		rt = u.Unwrap()
+	}
+	transport, ok := rt.(*http.Transport)
 	if !ok || transport == nil {
-		t.Fatalf("expected http transport with proxy, got %T", p.httpClient.Transport)
+		t.Fatalf("expected http transport with proxy, got %T", rt)
 	}
 
 	req := &http.Request{URL: &url.URL{Scheme: "https", Host: "api.example.com"}}
@@ -271,7 +279,77 @@ func TestNormalizeModel_UsesAPIBase(t *testing.T) {
 	if got := normalizeModel("deepseek/deepseek-chat", "https://api.deepseek.com/v1"); got != "deepseek-chat" {
 		t.Fatalf("normalizeModel(deepseek) = %q, want %q", got, "deepseek-chat")
 	}
+	if got := normalizeModel("xai/grok-4", "https://api.x.ai/v1"); got != "grok-4" {
+		t.Fatalf("normalizeModel(xai) = %q, want %q", got, "grok-4")
+	}
 	if got := normalizeModel("openrouter/auto", "https://openrouter.ai/api/v1"); got != "openrouter/auto" {
 		t.Fatalf("normalizeModel(openrouter) = %q, want %q", got, "openrouter/auto")
 	}
 }
+
+func TestProviderListModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models" || r.Header.Get("Authorization") != "Bearer key" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"object":"list","data":[{"id":"gpt-5.2","object":"model"},{"id":"gpt-5-mini","object":"model"}]}`))
+	}))
+	defer server.Close()
+
+	models, err := NewProvider("key", server.URL, "").ListModels(t.Context())
+	if err != nil {
+		t.Fatalf("ListModels() error = %v", err)
+	}
+	if len(models) != 2 || models[0] != "gpt-5.2" || models[1] != "gpt-5-mini" {
+		t.Errorf("models = %v", models)
+	}
+}
+
+func TestProviderChat_SendsSeedAndReportsModelVersion(t *testing.T) {
+	var requestBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&requestBody)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"model":"gpt-4o-2024-08-06","system_fingerprint":"fp_abc","choices":[{"message":{"content":"ok"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	p := NewProvider("key", server.URL, "")
+	resp, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, "gpt-4o", map[string]interface{}{"seed": 42})
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if requestBody["seed"] != float64(42) {
+		t.Errorf("seed sent = %v, want 42", requestBody["seed"])
+	}
+	if resp.Model != "gpt-4o-2024-08-06" || resp.SystemFingerprint != "fp_abc" {
+		t.Errorf("model = %q, fingerprint = %q", resp.Model, resp.SystemFingerprint)
+	}
+}
+
+func TestProviderChat_SendsResponseFormat(t *testing.T) {
+	var requestBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&requestBody)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"content":"{}"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	format := map[string]interface{}{"type": "json_schema", "json_schema": map[string]interface{}{"name": "result"}}
+	p := NewProvider("key", server.URL, "")
+	if _, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, "gpt-4o", map[string]interface{}{"response_format": format}); err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if got, _ := requestBody["response_format"].(map[string]interface{}); got["type"] != "json_schema" {
+		t.Errorf("response_format sent = %v", requestBody["response_format"])
+	}
+
+	if p.SupportsResponseFormat() {
+		t.Error("test server should not be known to take response_format")
+	}
+	if !NewProvider("key", "https://api.openai.com/v1", "").SupportsResponseFormat() {
+		t.Error("OpenAI should take response_format")
+	}
+}
@@ -0,0 +1,184 @@
+package openai_compat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/providers/protocoltypes"
+	"github.com/sipeed/picoclaw/pkg/providers/sse"
+)
+
+type StreamEvent = protocoltypes.StreamEvent
+
+type streamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content   string                `json:"content"`
+			ToolCalls []streamToolCallDelta `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage             *apiUsage `json:"usage"`
+	Model             string    `json:"model"`
+	SystemFingerprint string    `json:"system_fingerprint"`
+	Error             *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type streamToolCallDelta struct {
+	Index    int    `json:"index"`
+	ID       string `json:"id"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+	ExtraContent *struct {
+		Google *struct {
+			ThoughtSignature string `json:"thought_signature"`
+		} `json:"google"`
+	} `json:"extra_content"`
+}
+
+// ChatStream is Chat with a streamed response. Text is passed to onEvent as
+// it arrives, and tool calls as soon as their arguments are complete.
+func (p *Provider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onEvent func(StreamEvent)) (*LLMResponse, error) {
+	requestBody := p.requestBody(messages, tools, model, options)
+	requestBody["stream"] = true
+	requestBody["stream_options"] = map[string]interface{}{"include_usage": true}
+
+	resp, err := p.post(ctx, requestBody)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var content strings.Builder
+	var finishReason string
+	var usage *apiUsage
+	var modelVersion, fingerprint string
+	calls := &toolCallAssembler{onEvent: onEvent}
+
+	events := sse.NewReader(resp.Body)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		data, err := events.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", err)
+		}
+		if bytes.Equal(data, []byte("[DONE]")) {
+			break
+		}
+
+		var chunk streamChunk
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal stream chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return nil, fmt.Errorf("API stream failed: %s", chunk.Error.Message)
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		if chunk.Model != "" {
+			modelVersion = chunk.Model
+		}
+		if chunk.SystemFingerprint != "" {
+			fingerprint = chunk.SystemFingerprint
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+
+		choice := chunk.Choices[0]
+		if choice.Delta.Content != "" {
+			content.WriteString(choice.Delta.Content)
+			if onEvent != nil {
+				onEvent(StreamEvent{Text: choice.Delta.Content})
+			}
+		}
+		for _, delta := range choice.Delta.ToolCalls {
+			calls.add(delta)
+		}
+		if choice.FinishReason != "" {
+			finishReason = choice.FinishReason
+			calls.flush()
+		}
+	}
+	calls.flush()
+
+	if finishReason == "" {
+		finishReason = "stop"
+	}
+	return &LLMResponse{
+		Content:           content.String(),
+		ToolCalls:         calls.calls,
+		FinishReason:      finishReason,
+		Usage:             usage.info(),
+		Model:             modelVersion,
+		SystemFingerprint: fingerprint,
+	}, nil
+}
+
+// toolCallAssembler joins streamed tool call fragments. Calls are streamed
+// one after another: a fragment with the call's ID and name, then pieces of
+// its arguments. A call is therefore complete as soon as the next one
+// starts, without waiting for the end of the response.
+type toolCallAssembler struct {
+	onEvent func(StreamEvent)
+	calls   []ToolCall
+
+	open      bool
+	index     int
+	id        string
+	name      string
+	signature string
+	arguments strings.Builder
+}
+
+func (a *toolCallAssembler) add(delta streamToolCallDelta) {
+	// Some servers leave the index at zero and only change the ID.
+	if a.open && (delta.Index != a.index || (delta.ID != "" && a.id != "" && delta.ID != a.id)) {
+		a.flush()
+	}
+	if !a.open {
+		a.open = true
+		a.index = delta.Index
+		a.id, a.name, a.signature = "", "", ""
+		a.arguments.Reset()
+	}
+
+	if delta.ID != "" {
+		a.id = delta.ID
+	}
+	if delta.Function.Name != "" {
+		a.name = delta.Function.Name
+	}
+	if delta.ExtraContent != nil && delta.ExtraContent.Google != nil && delta.ExtraContent.Google.ThoughtSignature != "" {
+		a.signature = delta.ExtraContent.Google.ThoughtSignature
+	}
+	a.arguments.WriteString(delta.Function.Arguments)
+}
+
+// flush completes the open call, if any.
+func (a *toolCallAssembler) flush() {
+	if !a.open {
+		return
+	}
+	a.open = false
+
+	tc := newToolCall(a.id, a.name, a.arguments.String(), a.signature)
+	a.calls = append(a.calls, tc)
+	if a.onEvent != nil {
+		a.onEvent(StreamEvent{ToolCall: &tc})
+	}
+}
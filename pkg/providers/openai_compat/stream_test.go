@@ -0,0 +1,100 @@
+package openai_compat
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestProviderChatStream_ReportsToolCallsBeforeResponseEnds(t *testing.T) {
+	firstSeen := make(chan struct{})
+	var requestBody map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&requestBody)
+		w.Header().Set("Content-Type", "text/event-stream")
+		send := func(data string) {
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			w.(http.Flusher).Flush()
+		}
+
+		send(`{"choices":[{"delta":{"content":"On it."}}]}`)
+		send(`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"read_file","arguments":""}}]}}]}`)
+		send(`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"path\":"}}]}}]}`)
+		send(`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"a.txt\"}"}}]}}]}`)
+		send(`{"choices":[{"delta":{"tool_calls":[{"index":1,"id":"call_2","type":"function","function":{"name":"list_dir","arguments":"{\"path\""}}]}}]}`)
+
+		// The first call is complete once the second starts; the rest of
+		// the response is held back until the client has seen it.
+		select {
+		case <-firstSeen:
+		case <-time.After(2 * time.Second):
+			return
+		}
+
+		send(`{"choices":[{"delta":{"tool_calls":[{"index":1,"function":{"arguments":":\".\"}"}}]}}]}`)
+		send(`{"choices":[{"delta":{},"finish_reason":"tool_calls"}]}`)
+		send(`{"choices":[],"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`)
+		send(`[DONE]`)
+	}))
+	defer server.Close()
+
+	var streamed []ToolCall
+	var text string
+	p := NewProvider("key", server.URL, "")
+	resp, err := p.ChatStream(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, "gpt-4o", nil, func(ev StreamEvent) {
+		text += ev.Text
+		if ev.ToolCall == nil {
+			return
+		}
+		streamed = append(streamed, *ev.ToolCall)
+		if len(streamed) == 1 {
+			close(firstSeen)
+		}
+	})
+	if err != nil {
+		t.Fatalf("ChatStream() error = %v", err)
+	}
+
+	if requestBody["stream"] != true {
+		t.Errorf("stream = %v, want true", requestBody["stream"])
+	}
+	if text != "On it." {
+		t.Errorf("streamed text = %q, want %q", text, "On it.")
+	}
+	if len(streamed) != 2 || streamed[0].Name != "read_file" || streamed[1].Name != "list_dir" {
+		t.Fatalf("streamed tool calls = %+v", streamed)
+	}
+	if resp.Content != "On it." || resp.FinishReason != "tool_calls" {
+		t.Errorf("response = %+v", resp)
+	}
+	if len(resp.ToolCalls) != 2 {
+		t.Fatalf("len(ToolCalls) = %d, want 2", len(resp.ToolCalls))
+	}
+	if resp.ToolCalls[0].ID != "call_1" || resp.ToolCalls[0].Arguments["path"] != "a.txt" {
+		t.Errorf("ToolCalls[0] = %+v", resp.ToolCalls[0])
+	}
+	if resp.ToolCalls[1].ID != "call_2" || resp.ToolCalls[1].Arguments["path"] != "." {
+		t.Errorf("ToolCalls[1] = %+v", resp.ToolCalls[1])
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 15 {
+		t.Errorf("Usage = %+v", resp.Usage)
+	}
+}
+
+func TestProviderChatStream_StreamError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"error\":{\"message\":\"overloaded\"}}\n\n")
+	}))
+	defer server.Close()
+
+	p := NewProvider("key", server.URL, "")
+	_, err := p.ChatStream(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, "gpt-4o", nil, nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,91 @@
+//go:build !minimal
+
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+package providers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"github.com/sipeed/picoclaw/pkg/egress"
+	"github.com/sipeed/picoclaw/pkg/providers/httpcapture"
+	"github.com/sipeed/picoclaw/pkg/providers/httpretry"
+	"github.com/sipeed/picoclaw/pkg/providers/httptimeout"
+	"github.com/sipeed/picoclaw/pkg/providers/httpwarm"
+)
+
+const openAIDefaultAPIBase = "https://api.openai.com/v1"
+
+// OpenAIResponsesProvider talks to the OpenAI Responses API with an API
+// key, for models and built-in tools that chat/completions does not offer.
+type OpenAIResponsesProvider struct {
+	client       *openai.Client
+	builtinTools []string
+}
+
+// NewOpenAIResponsesProvider creates a Responses API provider. builtinTools
+// names the OpenAI built-in tools, "web_search" and "code_interpreter",
+// offered with every request.
+func NewOpenAIResponsesProvider(apiKey, apiBase, proxy string, builtinTools []string) *OpenAIResponsesProvider {
+	if apiBase == "" {
+		apiBase = openAIDefaultAPIBase
+	}
+	client := &http.Client{}
+	if proxy != "" {
+		parsed, err := url.Parse(proxy)
+		if err == nil {
+			client.Transport = &http.Transport{Proxy: http.ProxyURL(parsed)}
+		} else {
+			log.Printf("openai responses: invalid proxy URL %q: %v", proxy, err)
+		}
+	}
+	client.Transport = httpretry.Transport(httpcapture.Wrap(httptimeout.Transport(egress.Transport(egress.Providers, httpwarm.Transport(client.Transport)))))
+
+	opts := []option.RequestOption{
+		option.WithBaseURL(strings.TrimRight(apiBase, "/") + "/"),
+		option.WithAPIKey(apiKey),
+		option.WithHTTPClient(client),
+	}
+	if httpretry.Enabled() {
+		opts = append(opts, option.WithMaxRetries(0))
+	}
+	c := openai.NewClient(opts...)
+	return &OpenAIResponsesProvider{client: &c, builtinTools: builtinTools}
+}
+
+func (p *OpenAIResponsesProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	params := buildResponsesParams(messages, tools, model, p.builtinTools)
+	switch v := options["max_tokens"].(type) {
+	case int:
+		if v > 0 {
+			params.MaxOutputTokens = openai.Opt(int64(v))
+		}
+	case float64:
+		if v > 0 {
+			params.MaxOutputTokens = openai.Opt(int64(v))
+		}
+	}
+	if temp, ok := options["temperature"].(float64); ok {
+		params.Temperature = openai.Opt(temp)
+	}
+
+	resp, err := p.client.Responses.New(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("openai responses API call: %w", err)
+	}
+	return parseCodexResponse(resp), nil
+}
+
+func (p *OpenAIResponsesProvider) GetDefaultModel() string {
+	return ""
+}
@@ -0,0 +1,117 @@
+//go:build !minimal
+
+package providers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestOpenAIResponsesProvider_Chat(t *testing.T) {
+	var reqBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/responses" {
+			http.Error(w, "not found: "+r.URL.Path, http.StatusNotFound)
+			return
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer sk-test" {
+			http.Error(w, "bad auth: "+got, http.StatusUnauthorized)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":     "resp_test",
+			"object": "response",
+			"status": "completed",
+			"output": []map[string]interface{}{
+				{
+					"id":     "msg_1",
+					"type":   "message",
+					"role":   "assistant",
+					"status": "completed",
+					"content": []map[string]interface{}{
+						{"type": "output_text", "text": "42"},
+					},
+				},
+			},
+			"usage": map[string]interface{}{
+				"input_tokens":          10,
+				"output_tokens":         2,
+				"total_tokens":          12,
+				"input_tokens_details":  map[string]interface{}{"cached_tokens": 0},
+				"output_tokens_details": map[string]interface{}{"reasoning_tokens": 0},
+			},
+		})
+	}))
+	defer server.Close()
+
+	provider := NewOpenAIResponsesProvider("sk-test", server.URL+"/v1", "", []string{"web_search"})
+	messages := []Message{
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", Content: "What is six times seven?"},
+	}
+	resp, err := provider.Chat(t.Context(), messages, nil, "gpt-5.2", map[string]interface{}{"max_tokens": 256, "temperature": 0.2})
+	if err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	if resp.Content != "42" {
+		t.Errorf("Content = %q, want %q", resp.Content, "42")
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 12 {
+		t.Errorf("Usage = %+v, want 12 total tokens", resp.Usage)
+	}
+
+	if reqBody["instructions"] != "Be brief." {
+		t.Errorf("instructions = %v, want the system message", reqBody["instructions"])
+	}
+	if reqBody["max_output_tokens"] != float64(256) {
+		t.Errorf("max_output_tokens = %v, want 256", reqBody["max_output_tokens"])
+	}
+	if reqBody["temperature"] != 0.2 {
+		t.Errorf("temperature = %v, want 0.2", reqBody["temperature"])
+	}
+	tools, _ := reqBody["tools"].([]interface{})
+	if len(tools) != 1 || tools[0].(map[string]interface{})["type"] != "web_search" {
+		t.Errorf("tools = %v, want the built-in web_search", reqBody["tools"])
+	}
+}
+
+func TestCreateProviderFromConfig_OpenAIResponses(t *testing.T) {
+	provider, modelID, err := CreateProviderFromConfig(&config.ModelConfig{
+		ModelName:    "gpt",
+		Model:        "openai/gpt-5.2",
+		APIKey:       "sk-test",
+		API:          "responses",
+		BuiltinTools: []string{"code_interpreter"},
+	})
+	if err != nil {
+		t.Fatalf("CreateProviderFromConfig() error = %v", err)
+	}
+	p, ok := provider.(*OpenAIResponsesProvider)
+	if !ok || modelID != "gpt-5.2" {
+		t.Fatalf("provider = %T, model = %q; want the Responses API provider", provider, modelID)
+	}
+	if len(p.builtinTools) != 1 || p.builtinTools[0] != "code_interpreter" {
+		t.Errorf("builtinTools = %v, want [code_interpreter]", p.builtinTools)
+	}
+
+	provider, _, err = CreateProviderFromConfig(&config.ModelConfig{
+		ModelName: "gpt",
+		Model:     "openai/gpt-5.2",
+		APIKey:    "sk-test",
+	})
+	if err != nil {
+		t.Fatalf("CreateProviderFromConfig() error = %v", err)
+	}
+	if _, ok := provider.(*HTTPProvider); !ok {
+		t.Errorf("provider = %T; want chat/completions by default", provider)
+	}
+}
@@ -0,0 +1,38 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+package providers
+
+import (
+	"fmt"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/features"
+)
+
+// optionalProviders holds providers that depend on large SDKs. They register
+// themselves from build-tagged files so smaller build profiles can leave
+// them out.
+var optionalProviders = map[string]ProviderFactory{}
+
+func registerOptionalProvider(name string, factory ProviderFactory) {
+	optionalProviders[name] = factory
+	features.Register("provider", name)
+}
+
+func createOptionalProvider(name string, cfg *config.ModelConfig, modelID string) (LLMProvider, error) {
+	factory, ok := optionalProviders[name]
+	if !ok {
+		return nil, fmt.Errorf("%s provider is not included in this build (profile %q)", name, features.Profile)
+	}
+	return factory(cfg, modelID)
+}
+
+// Providers without extra dependencies, available in every build profile.
+func init() {
+	for _, name := range []string{"openai-compatible", "antigravity", "claude-cli", "codex-cli", "plugin"} {
+		features.Register("provider", name)
+	}
+}
@@ -0,0 +1,169 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// PluginProtocolVersion is the version of the requests provider plugins
+// are sent. It changes only when a plugin written for an older version
+// could misread a request.
+const PluginProtocolVersion = 1
+
+// defaultPluginTimeout bounds a plugin request without timeout_seconds.
+const defaultPluginTimeout = 120 * time.Second
+
+// PluginRequest is what a provider plugin reads from its stdin.
+type PluginRequest struct {
+	Version  int                    `json:"version"`
+	Method   string                 `json:"method"` // "chat"
+	Model    string                 `json:"model"`
+	Messages []Message              `json:"messages"`
+	Tools    []ToolDefinition       `json:"tools,omitempty"`
+	Options  map[string]interface{} `json:"options,omitempty"`
+}
+
+// PluginResponse is what a provider plugin writes to its stdout: the
+// response, or an error.
+type PluginResponse struct {
+	LLMResponse
+	Error string `json:"error,omitempty"`
+}
+
+// pluginProviders holds the providers declared in provider_plugins, by
+// name. It is guarded by customMu.
+var pluginProviders = map[string]config.ProviderPluginConfig{}
+
+// RegisterProviderPlugins makes the providers declared in provider_plugins
+// available to model_list entries, replacing those registered before.
+// Register custom providers first, as plugins cannot take their names.
+func RegisterProviderPlugins(cfgs []config.ProviderPluginConfig) error {
+	registered := make(map[string]config.ProviderPluginConfig, len(cfgs))
+	for _, c := range cfgs {
+		if err := c.Validate(); err != nil {
+			return fmt.Errorf("provider plugin %q: %w", c.Name, err)
+		}
+		for _, builtin := range builtinProtocols {
+			if c.Name == builtin {
+				return fmt.Errorf("provider plugin %q: name is taken by a built-in provider", c.Name)
+			}
+		}
+		if _, ok := findCustomProvider(c.Name); ok {
+			return fmt.Errorf("provider plugin %q: name is taken by a custom provider", c.Name)
+		}
+		if _, ok := findRegisteredProvider(c.Name); ok {
+			return fmt.Errorf("provider plugin %q: name is taken by a registered provider", c.Name)
+		}
+		registered[c.Name] = c
+	}
+	customMu.Lock()
+	pluginProviders = registered
+	customMu.Unlock()
+	return nil
+}
+
+func findProviderPlugin(name string) (config.ProviderPluginConfig, bool) {
+	customMu.RLock()
+	defer customMu.RUnlock()
+	c, ok := pluginProviders[name]
+	return c, ok
+}
+
+// PluginProvider is a provider implemented by an external program, which
+// is run for each request. The model_list entry's api_key and api_base
+// are passed to it as PICOCLAW_API_KEY and PICOCLAW_API_BASE.
+type PluginProvider struct {
+	name    string
+	command string
+	args    []string
+	env     []string
+	timeout time.Duration
+}
+
+func newPluginProvider(plugin config.ProviderPluginConfig, cfg *config.ModelConfig) *PluginProvider {
+	env := os.Environ()
+	for k, v := range plugin.Env {
+		env = append(env, k+"="+v)
+	}
+	env = append(env, "PICOCLAW_API_KEY="+cfg.APIKey, "PICOCLAW_API_BASE="+cfg.APIBase)
+	timeout := defaultPluginTimeout
+	if plugin.TimeoutSeconds > 0 {
+		timeout = time.Duration(plugin.TimeoutSeconds) * time.Second
+	}
+	return &PluginProvider{
+		name:    plugin.Name,
+		command: plugin.Command,
+		args:    plugin.Args,
+		env:     env,
+		timeout: timeout,
+	}
+}
+
+// Chat runs the plugin with the request and returns its response.
+func (p *PluginProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	req, err := json.Marshal(PluginRequest{
+		Version:  PluginProtocolVersion,
+		Method:   "chat",
+		Model:    model,
+		Messages: messages,
+		Tools:    tools,
+		Options:  options,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: encoding request: %w", p.name, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, p.command, p.args...)
+	cmd.Env = p.env
+	cmd.Stdin = bytes.NewReader(req)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	runErr := cmd.Run()
+	var resp PluginResponse
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		if runErr != nil {
+			return nil, p.failure(runErr, stderr.String())
+		}
+		return nil, fmt.Errorf("plugin %s: invalid response: %w", p.name, err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("plugin %s: %s", p.name, resp.Error)
+	}
+	if runErr != nil {
+		return nil, p.failure(runErr, stderr.String())
+	}
+	return &resp.LLMResponse, nil
+}
+
+// failure describes a plugin run that failed, with the end of what it
+// wrote to stderr.
+func (p *PluginProvider) failure(err error, stderr string) error {
+	if stderr = strings.TrimSpace(stderr); stderr != "" {
+		if len(stderr) > 500 {
+			stderr = "..." + stderr[len(stderr)-500:]
+		}
+		return fmt.Errorf("plugin %s: %w: %s", p.name, err, stderr)
+	}
+	return fmt.Errorf("plugin %s: %w", p.name, err)
+}
+
+func (p *PluginProvider) GetDefaultModel() string {
+	return ""
+}
@@ -0,0 +1,65 @@
+package providers
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestProviderPlugin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin script needs sh")
+	}
+	reqFile := filepath.Join(t.TempDir(), "request.json")
+	defer RegisterProviderPlugins(nil)
+	if err := RegisterProviderPlugins([]config.ProviderPluginConfig{
+		{
+			Name:    "acme",
+			Command: "sh",
+			Args:    []string{"-c", `cat > "$REQ"; echo '{"content":"hi '"$PICOCLAW_API_KEY"'","tool_calls":[{"id":"1","name":"t","arguments":{"a":1}}]}'`},
+			Env:     map[string]string{"REQ": reqFile},
+		},
+		{Name: "broken", Command: "sh", Args: []string{"-c", `echo 'backend down' >&2; exit 3`}},
+		{Name: "refusing", Command: "sh", Args: []string{"-c", `echo '{"error":"quota exceeded"}'`}},
+	}); err != nil {
+		t.Fatalf("RegisterProviderPlugins() error = %v", err)
+	}
+
+	p, modelID, err := CreateProviderFromConfig(&config.ModelConfig{ModelName: "acme", Model: "acme/large", APIKey: "secret"})
+	if err != nil {
+		t.Fatalf("CreateProviderFromConfig() error = %v", err)
+	}
+	resp, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "hello"}}, nil, modelID, map[string]interface{}{"max_tokens": 10})
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if resp.Content != "hi secret" || len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Arguments["a"] != float64(1) {
+		t.Errorf("Chat() = %+v", resp)
+	}
+	data, _ := os.ReadFile(reqFile)
+	var req PluginRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		t.Fatalf("plugin got %q: %v", data, err)
+	}
+	if req.Version != PluginProtocolVersion || req.Method != "chat" || req.Model != "large" || req.Messages[0].Content != "hello" || req.Options["max_tokens"] != float64(10) {
+		t.Errorf("plugin got %+v", req)
+	}
+
+	for model, want := range map[string]string{"broken/m": "backend down", "refusing/m": "quota exceeded"} {
+		p, _, _ := CreateProviderFromConfig(&config.ModelConfig{ModelName: "x", Model: model})
+		if _, err := p.Chat(t.Context(), nil, nil, "m", nil); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: Chat() error = %v, want it to contain %q", model, err, want)
+		}
+	}
+
+	RegisterCustomProviders([]config.CustomProviderConfig{{Name: "taken", APIBase: "https://x"}})
+	defer RegisterCustomProviders(nil)
+	if err := RegisterProviderPlugins([]config.ProviderPluginConfig{{Name: "taken", Command: "x"}}); err == nil {
+		t.Error("expected an error for a plugin named after a custom provider")
+	}
+}
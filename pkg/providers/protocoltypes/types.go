@@ -1,5 +1,7 @@
 package protocoltypes
 
+import "errors"
+
 type ToolCall struct {
 	ID               string                 `json:"id"`
 	Type             string                 `json:"type,omitempty"`
@@ -29,12 +31,29 @@ type LLMResponse struct {
 	ToolCalls    []ToolCall `json:"tool_calls,omitempty"`
 	FinishReason string     `json:"finish_reason"`
 	Usage        *UsageInfo `json:"usage,omitempty"`
+	// Model is the exact model version that generated the response and
+	// SystemFingerprint the backend configuration, where the provider
+	// reports them.
+	Model             string `json:"model,omitempty"`
+	SystemFingerprint string `json:"system_fingerprint,omitempty"`
+}
+
+// StreamEvent reports progress while a response is streamed. Text is the
+// next piece of the reply. ToolCall is set once a tool call's arguments are
+// complete, which can be well before the rest of the response has been
+// generated.
+type StreamEvent struct {
+	Text     string
+	ToolCall *ToolCall
 }
 
 type UsageInfo struct {
 	PromptTokens     int `json:"prompt_tokens"`
 	CompletionTokens int `json:"completion_tokens"`
 	TotalTokens      int `json:"total_tokens"`
+	// CachedTokens is the part of PromptTokens read from the provider's
+	// prompt cache, which is usually billed at a lower rate.
+	CachedTokens int `json:"cached_tokens,omitempty"`
 }
 
 type Message struct {
@@ -42,6 +61,37 @@ type Message struct {
 	Content    string     `json:"content"`
 	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
 	ToolCallID string     `json:"tool_call_id,omitempty"`
+	Files      []FileRef  `json:"files,omitempty"`
+	Images     []Image    `json:"images,omitempty"`
+}
+
+// Image is a picture sent with a message, either by URL or inline as
+// base64 Data of type MimeType.
+type Image struct {
+	URL      string `json:"url,omitempty"`
+	Data     string `json:"data,omitempty"`
+	MimeType string `json:"mime_type,omitempty"`
+}
+
+// ErrFileNotFound is matched by errors of file stores that no longer know
+// the file asked for.
+var ErrFileNotFound = errors.New("file not found")
+
+// DataURL returns the image as a URL: its own, or a data: URL for inline
+// images.
+func (i Image) DataURL() string {
+	if i.URL != "" {
+		return i.URL
+	}
+	return "data:" + i.MimeType + ";base64," + i.Data
+}
+
+// FileRef references a document uploaded to the provider's file store, so
+// it can be sent by ID instead of inline on every turn.
+type FileRef struct {
+	ID       string `json:"id"`
+	Name     string `json:"name,omitempty"`
+	MimeType string `json:"mime_type,omitempty"`
 }
 
 type ToolDefinition struct {
@@ -0,0 +1,171 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PromptTools returns p with tool calling done in the prompt, ReAct style,
+// for models without native tool calling. The tools passed to Chat are
+// described in a system message, earlier tool calls and results are
+// written into the conversation as text, and the actions in the reply are
+// returned as ToolCalls, so callers use it like any other provider.
+func PromptTools(p LLMProvider) LLMProvider {
+	return &promptToolsProvider{LLMProvider: p}
+}
+
+type promptToolsProvider struct {
+	LLMProvider
+}
+
+func (p *promptToolsProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	if len(tools) == 0 {
+		return p.LLMProvider.Chat(ctx, messages, nil, model, options)
+	}
+	resp, err := p.LLMProvider.Chat(ctx, ReActMessages(messages, tools), nil, model, options)
+	if err != nil || resp == nil {
+		return resp, err
+	}
+	if calls, rest := ParseReAct(resp.Content); len(calls) > 0 {
+		out := *resp
+		out.Content = rest
+		out.ToolCalls = calls
+		out.FinishReason = "tool_calls"
+		return &out, nil
+	}
+	return resp, nil
+}
+
+// ReActMessages rewrites a conversation for a model that calls tools in
+// text: the tools are described after the system messages, tool calls
+// become Action lines and tool results Observations.
+func ReActMessages(messages []Message, tools []ToolDefinition) []Message {
+	out := make([]Message, 0, len(messages)+1)
+	names := make(map[string]string) // tool call ID -> tool name
+	added := false
+	for _, m := range messages {
+		if m.Role != "system" && !added {
+			out = append(out, Message{Role: "system", Content: ReActPrompt(tools)})
+			added = true
+		}
+		switch {
+		case m.Role == "assistant" && len(m.ToolCalls) > 0:
+			var sb strings.Builder
+			if text := strings.TrimSpace(m.Content); text != "" {
+				fmt.Fprintf(&sb, "Thought: %s\n", text)
+			}
+			for _, tc := range m.ToolCalls {
+				tc = NormalizeToolCall(tc)
+				names[tc.ID] = tc.Name
+				fmt.Fprintf(&sb, "Action: %s\nAction Input: %s\n", tc.Name, tc.Function.Arguments)
+			}
+			out = append(out, Message{Role: "assistant", Content: strings.TrimSpace(sb.String())})
+		case m.Role == "tool":
+			name := names[m.ToolCallID]
+			if name == "" {
+				name = m.ToolCallID
+			}
+			out = append(out, Message{Role: "user", Content: fmt.Sprintf("Observation from %s: %s", name, m.Content)})
+		default:
+			m.ToolCalls = nil
+			out = append(out, m)
+		}
+	}
+	if !added {
+		out = append(out, Message{Role: "system", Content: ReActPrompt(tools)})
+	}
+	return out
+}
+
+// ReActPrompt describes tools and how to call them in a reply.
+func ReActPrompt(tools []ToolDefinition) string {
+	var sb strings.Builder
+	sb.WriteString("## Tools\n\n")
+	sb.WriteString("You can use the tools below. To use them, reply in exactly this format and stop after the last Action Input:\n\n")
+	sb.WriteString("Thought: what you are going to do and why\n")
+	sb.WriteString("Action: the tool name\n")
+	sb.WriteString("Action Input: the arguments as a JSON object\n\n")
+	sb.WriteString("To use several tools at once, give several Action and Action Input pairs. ")
+	sb.WriteString("Their results come back as Observations. When you can answer without a tool, reply normally without any Action.\n\n")
+	for _, tool := range tools {
+		if tool.Type != "" && tool.Type != "function" {
+			continue
+		}
+		fmt.Fprintf(&sb, "### %s\n", tool.Function.Name)
+		if tool.Function.Description != "" {
+			sb.WriteString(tool.Function.Description + "\n")
+		}
+		if len(tool.Function.Parameters) > 0 {
+			params, _ := json.Marshal(tool.Function.Parameters)
+			fmt.Fprintf(&sb, "Parameters: %s\n", params)
+		}
+		sb.WriteString("\n")
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// ParseReAct returns the tool calls in a reply, as Action and Action Input
+// lines or a {"tool_calls": [...]} object, and the reply without them.
+func ParseReAct(reply string) ([]ToolCall, string) {
+	if calls := extractToolCallsFromText(reply); len(calls) > 0 {
+		return calls, stripToolCallsFromText(reply)
+	}
+
+	var calls []ToolCall
+	var rest []string
+	lines := strings.Split(reply, "\n")
+	stamp := time.Now().UnixNano()
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if _, ok := cutLabel(line, "Observation:"); ok && len(calls) > 0 {
+			break // the model made up the results
+		}
+		name, ok := cutLabel(line, "Action:")
+		if !ok {
+			if text, ok := cutLabel(line, "Thought:"); ok {
+				line = text
+			}
+			rest = append(rest, line)
+			continue
+		}
+
+		args := map[string]interface{}{}
+		if i+1 < len(lines) {
+			if input, ok := cutLabel(strings.TrimSpace(lines[i+1]), "Action Input:"); ok {
+				// The JSON may span lines; decode it from the rest of the reply.
+				remaining := input + "\n" + strings.Join(lines[i+2:], "\n")
+				dec := json.NewDecoder(strings.NewReader(remaining))
+				if err := dec.Decode(&args); err != nil {
+					args = map[string]interface{}{}
+					i++
+				} else {
+					i += 1 + strings.Count(remaining[:dec.InputOffset()], "\n")
+				}
+			}
+		}
+		calls = append(calls, NormalizeToolCall(ToolCall{
+			ID:        fmt.Sprintf("call_%d_%d", stamp, len(calls)),
+			Type:      "function",
+			Name:      strings.Trim(name, "`\" "),
+			Arguments: args,
+		}))
+	}
+	if len(calls) == 0 {
+		if i := strings.Index(reply, "Final Answer:"); i >= 0 {
+			return nil, strings.TrimSpace(reply[i+len("Final Answer:"):])
+		}
+		return nil, reply
+	}
+	return calls, strings.TrimSpace(strings.Join(rest, "\n"))
+}
+
+// cutLabel returns what follows label at the start of line, ignoring case.
+func cutLabel(line, label string) (string, bool) {
+	if len(line) < len(label) || !strings.EqualFold(line[:len(label)], label) {
+		return "", false
+	}
+	return strings.TrimSpace(line[len(label):]), true
+}
@@ -0,0 +1,98 @@
+package providers
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+var weatherTool = ToolDefinition{
+	Type: "function",
+	Function: ToolFunctionDefinition{
+		Name:        "get_weather",
+		Description: "Get the weather for a city.",
+		Parameters: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"city": map[string]interface{}{"type": "string"}},
+		},
+	},
+}
+
+func TestParseReAct_ParallelActions(t *testing.T) {
+	reply := "Thought: I need the weather in both cities.\n" +
+		"Action: get_weather\n" +
+		"Action Input: {\"city\": \"Paris\"}\n" +
+		"Action: `get_weather`\n" +
+		"Action Input: {\n  \"city\": \"Oslo\"\n}\n" +
+		"Observation: sunny"
+
+	calls, rest := ParseReAct(reply)
+	if len(calls) != 2 {
+		t.Fatalf("got %d calls, want 2", len(calls))
+	}
+	for i, city := range []string{"Paris", "Oslo"} {
+		if calls[i].Name != "get_weather" || calls[i].Arguments["city"] != city {
+			t.Errorf("call %d = %s %v", i, calls[i].Name, calls[i].Arguments)
+		}
+	}
+	if calls[0].ID == calls[1].ID {
+		t.Error("parallel calls share an ID")
+	}
+	if rest != "I need the weather in both cities." {
+		t.Errorf("rest = %q", rest)
+	}
+}
+
+func TestParseReAct_NoAction(t *testing.T) {
+	if calls, rest := ParseReAct("Thought: done\nFinal Answer: It is sunny."); calls != nil || rest != "It is sunny." {
+		t.Errorf("ParseReAct() = %v, %q", calls, rest)
+	}
+	if calls, rest := ParseReAct("Just text."); calls != nil || rest != "Just text." {
+		t.Errorf("ParseReAct() = %v, %q", calls, rest)
+	}
+}
+
+func TestParseReAct_ToolCallsObject(t *testing.T) {
+	calls, _ := ParseReAct(`{"tool_calls":[{"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"Rome\"}"}}]}`)
+	if len(calls) != 1 || calls[0].Arguments["city"] != "Rome" {
+		t.Errorf("calls = %+v", calls)
+	}
+}
+
+func TestReActMessages(t *testing.T) {
+	msgs := ReActMessages([]Message{
+		{Role: "system", Content: "You are helpful."},
+		{Role: "user", Content: "Weather in Paris?"},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "c1", Name: "get_weather", Arguments: map[string]interface{}{"city": "Paris"}}}},
+		{Role: "tool", ToolCallID: "c1", Content: "sunny"},
+	}, []ToolDefinition{weatherTool})
+
+	if len(msgs) != 5 {
+		t.Fatalf("got %d messages, want 5", len(msgs))
+	}
+	if msgs[1].Role != "system" || !strings.Contains(msgs[1].Content, "### get_weather") {
+		t.Errorf("tools prompt = %+v", msgs[1])
+	}
+	if msgs[3].Content != "Action: get_weather\nAction Input: {\"city\":\"Paris\"}" || msgs[3].ToolCalls != nil {
+		t.Errorf("assistant message = %+v", msgs[3])
+	}
+	if msgs[4].Role != "user" || msgs[4].Content != "Observation from get_weather: sunny" {
+		t.Errorf("tool result = %+v", msgs[4])
+	}
+}
+
+func TestPromptTools_Chat(t *testing.T) {
+	inner := &structuredMock{responses: []*LLMResponse{
+		{Content: "Action: get_weather\nAction Input: {\"city\": \"Paris\"}", FinishReason: "stop"},
+	}}
+	resp, err := PromptTools(inner).Chat(context.Background(), []Message{{Role: "user", Content: "Weather?"}}, []ToolDefinition{weatherTool}, "m", nil)
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if len(inner.requests[0].tools) != 0 {
+		t.Error("tools were sent natively")
+	}
+	if resp.FinishReason != "tool_calls" || len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Function.Arguments != `{"city":"Paris"}` {
+		t.Errorf("response = %+v", resp)
+	}
+}
@@ -0,0 +1,82 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+package providers
+
+import (
+	"fmt"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/features"
+)
+
+// ProviderFactory creates the provider of a model_list entry. modelID is
+// the entry's model without its protocol prefix.
+type ProviderFactory func(cfg *config.ModelConfig, modelID string) (LLMProvider, error)
+
+// ConfigResolver adjusts a model_list entry before its provider is
+// created, for example to fill in an api_base or a key kept elsewhere. It
+// gets a copy of the entry, so its changes are not saved.
+type ConfigResolver func(cfg *config.ModelConfig) error
+
+var (
+	// registeredProviders holds the providers added by RegisterProvider,
+	// by protocol. It is guarded by customMu.
+	registeredProviders = map[string]ProviderFactory{}
+	configResolvers     []ConfigResolver
+)
+
+// RegisterProvider makes factory create the providers of the model_list
+// entries whose model starts with name + "/". It lets a program that
+// embeds PicoClaw add providers; registering a name again replaces its
+// factory. Built-in protocols cannot be replaced.
+func RegisterProvider(name string, factory ProviderFactory) error {
+	if name == "" || factory == nil {
+		return fmt.Errorf("provider name and factory are required")
+	}
+	for _, builtin := range builtinProtocols {
+		if name == builtin {
+			return fmt.Errorf("provider %q: name is taken by a built-in provider", name)
+		}
+	}
+	customMu.Lock()
+	registeredProviders[name] = factory
+	customMu.Unlock()
+	features.Register("provider", name)
+	return nil
+}
+
+// RegisterConfigResolver adds r to the resolvers CreateProviderFromConfig
+// runs, in the order they were added, on each model_list entry.
+func RegisterConfigResolver(r ConfigResolver) {
+	customMu.Lock()
+	defer customMu.Unlock()
+	configResolvers = append(configResolvers, r)
+}
+
+func findRegisteredProvider(name string) (ProviderFactory, bool) {
+	customMu.RLock()
+	defer customMu.RUnlock()
+	f, ok := registeredProviders[name]
+	return f, ok
+}
+
+// resolveProviderConfig returns cfg as the registered config resolvers
+// adjust it, or cfg itself if there are none.
+func resolveProviderConfig(cfg *config.ModelConfig) (*config.ModelConfig, error) {
+	customMu.RLock()
+	resolvers := configResolvers
+	customMu.RUnlock()
+	if cfg == nil || len(resolvers) == 0 {
+		return cfg, nil
+	}
+	resolved := *cfg
+	for _, r := range resolvers {
+		if err := r(&resolved); err != nil {
+			return nil, fmt.Errorf("resolving model %q: %w", cfg.ModelName, err)
+		}
+	}
+	return &resolved, nil
+}
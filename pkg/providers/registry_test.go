@@ -0,0 +1,56 @@
+package providers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestRegisterProvider(t *testing.T) {
+	var gotKey, gotModel string
+	err := RegisterProvider("test-embedded", func(cfg *config.ModelConfig, modelID string) (LLMProvider, error) {
+		gotKey, gotModel = cfg.APIKey, modelID
+		return &structuredMock{}, nil
+	})
+	if err != nil {
+		t.Fatalf("RegisterProvider() error = %v", err)
+	}
+	RegisterConfigResolver(func(cfg *config.ModelConfig) error {
+		if cfg.APIKey == "" {
+			cfg.APIKey = "from-vault"
+		}
+		if cfg.ModelName == "broken" {
+			return errors.New("no secret")
+		}
+		return nil
+	})
+	defer func() {
+		customMu.Lock()
+		delete(registeredProviders, "test-embedded")
+		configResolvers = nil
+		customMu.Unlock()
+	}()
+
+	entry := &config.ModelConfig{ModelName: "embedded", Model: "test-embedded/small"}
+	p, modelID, err := CreateProviderFromConfig(entry)
+	if err != nil {
+		t.Fatalf("CreateProviderFromConfig() error = %v", err)
+	}
+	if _, ok := p.(*structuredMock); !ok || modelID != "small" || gotModel != "small" {
+		t.Errorf("provider = %T, model = %q, factory got %q", p, modelID, gotModel)
+	}
+	if gotKey != "from-vault" || entry.APIKey != "" {
+		t.Errorf("factory got key %q, entry key %q; want the resolved key on a copy", gotKey, entry.APIKey)
+	}
+
+	if _, _, err := CreateProviderFromConfig(&config.ModelConfig{ModelName: "broken", Model: "test-embedded/small"}); err == nil {
+		t.Error("resolver error ignored")
+	}
+	if err := RegisterProvider("openai", func(*config.ModelConfig, string) (LLMProvider, error) { return nil, nil }); err == nil {
+		t.Error("RegisterProvider() replaced a built-in provider")
+	}
+	if err := RegisterCustomProviders([]config.CustomProviderConfig{{Name: "test-embedded", APIBase: "http://localhost/v1"}}); err == nil {
+		t.Error("custom provider took a registered provider's name")
+	}
+}
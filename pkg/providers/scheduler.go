@@ -0,0 +1,249 @@
+package providers
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// Priority orders requests that wait for the provider quota.
+type Priority int
+
+const (
+	// PriorityInteractive is for turns a user is waiting on.
+	PriorityInteractive Priority = iota
+	// PriorityBackground is for background tasks, cron jobs, heartbeats
+	// and housekeeping such as summaries and titles.
+	PriorityBackground
+)
+
+func (p Priority) String() string {
+	if p == PriorityBackground {
+		return "background"
+	}
+	return "interactive"
+}
+
+type priorityKey struct{}
+
+// WithPriority marks the requests made with ctx as having priority p.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityOf returns the priority ctx was marked with, interactive by
+// default.
+func PriorityOf(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+	return PriorityInteractive
+}
+
+// minSchedulerDelay keeps waiting requests from spinning on tiny refills.
+const minSchedulerDelay = 10 * time.Millisecond
+
+// Scheduler shares a provider quota of requests and tokens per minute
+// between all requests, using two token buckets that refill continuously.
+// Interactive requests go first: background requests wait while any
+// interactive request is waiting and may not use the last reserve part of
+// either bucket. Requests of the same priority are served in order.
+//
+// Tokens are only known once a response arrives, so they are charged
+// afterwards with Record and the token bucket may go below zero.
+//
+// A nil *Scheduler does not limit anything.
+type Scheduler struct {
+	mu       sync.Mutex
+	rpm      float64 // 0 for no request limit
+	tpm      float64 // 0 for no token limit
+	reserve  float64 // part of each bucket kept for interactive requests
+	requests float64
+	tokens   float64
+	last     time.Time
+	queues   [2][]*schedWaiter
+	changed  chan struct{} // closed and replaced when waiters may proceed
+	nowFunc  func() time.Time
+}
+
+// NewScheduler returns a scheduler for a quota of requestsPerMinute and
+// tokensPerMinute, either of which may be 0 for no limit, that keeps
+// reservePercent of the quota for interactive requests.
+func NewScheduler(requestsPerMinute, tokensPerMinute, reservePercent int) *Scheduler {
+	s := &Scheduler{
+		rpm:     float64(max(requestsPerMinute, 0)),
+		tpm:     float64(max(tokensPerMinute, 0)),
+		reserve: float64(min(max(reservePercent, 0), 100)) / 100,
+		changed: make(chan struct{}),
+		nowFunc: time.Now,
+	}
+	s.requests = s.rpm
+	s.tokens = s.tpm
+	s.last = s.nowFunc()
+	return s
+}
+
+// Wait blocks until a request with ctx's priority may be sent, or ctx is
+// done.
+func (s *Scheduler) Wait(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	p := PriorityOf(ctx)
+	w := &schedWaiter{since: time.Now()}
+
+	s.mu.Lock()
+	s.queues[p] = append(s.queues[p], w)
+	for {
+		s.refillLocked()
+		if s.queues[p][0] == w && s.admitLocked(p) {
+			if s.rpm > 0 {
+				s.requests--
+			}
+			s.removeLocked(p, w)
+			s.mu.Unlock()
+			if waited := time.Since(w.since); waited >= time.Second {
+				logger.DebugCF("scheduler", "Request waited for quota", map[string]interface{}{
+					"priority": p.String(),
+					"waited":   waited.Round(time.Millisecond).String(),
+				})
+			}
+			return nil
+		}
+		delay := s.delayLocked(p)
+		changed := s.changed
+		s.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			s.mu.Lock()
+			s.removeLocked(p, w)
+			s.mu.Unlock()
+			return ctx.Err()
+		case <-changed:
+		case <-timer.C:
+		}
+		timer.Stop()
+		s.mu.Lock()
+	}
+}
+
+// Record charges the tokens resp used to the quota.
+func (s *Scheduler) Record(resp *LLMResponse) {
+	if s == nil || s.tpm == 0 || resp == nil || resp.Usage == nil {
+		return
+	}
+	used := resp.Usage.TotalTokens
+	if used == 0 {
+		used = resp.Usage.PromptTokens + resp.Usage.CompletionTokens
+	}
+	s.mu.Lock()
+	s.refillLocked()
+	s.tokens -= float64(used)
+	s.mu.Unlock()
+}
+
+// Provider returns p with its Chat requests scheduled by s, for callers
+// that only chat, such as background tasks and summaries.
+func (s *Scheduler) Provider(p LLMProvider) LLMProvider {
+	if s == nil {
+		return p
+	}
+	return &scheduledProvider{LLMProvider: p, scheduler: s}
+}
+
+// schedWaiter is a request waiting in a queue of the scheduler.
+type schedWaiter struct {
+	since time.Time
+}
+
+type scheduledProvider struct {
+	LLMProvider
+	scheduler *Scheduler
+}
+
+func (p *scheduledProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	if err := p.scheduler.Wait(ctx); err != nil {
+		return nil, err
+	}
+	resp, err := p.LLMProvider.Chat(ctx, messages, tools, model, options)
+	p.scheduler.Record(resp)
+	return resp, err
+}
+
+// StructuredOutput keeps the wrapped provider's structured output mode.
+func (p *scheduledProvider) StructuredOutput() StructuredMode {
+	if s, ok := p.LLMProvider.(StructuredOutputter); ok {
+		return s.StructuredOutput()
+	}
+	return StructuredPrompt
+}
+
+func (s *Scheduler) refillLocked() {
+	now := s.nowFunc()
+	elapsed := now.Sub(s.last).Minutes()
+	s.last = now
+	if elapsed <= 0 {
+		return
+	}
+	s.requests = math.Min(s.rpm, s.requests+elapsed*s.rpm)
+	s.tokens = math.Min(s.tpm, s.tokens+elapsed*s.tpm)
+}
+
+// thresholdsLocked returns how many requests and tokens must be left for
+// a request with priority p to be sent.
+func (s *Scheduler) thresholdsLocked(p Priority) (requests, tokens float64) {
+	requests = 1
+	if p == PriorityBackground {
+		requests += s.reserve * s.rpm
+		tokens = s.reserve * s.tpm
+	}
+	return requests, tokens
+}
+
+func (s *Scheduler) admitLocked(p Priority) bool {
+	if p == PriorityBackground && len(s.queues[PriorityInteractive]) > 0 {
+		return false
+	}
+	needRequests, needTokens := s.thresholdsLocked(p)
+	if s.rpm > 0 && s.requests < math.Min(needRequests, s.rpm) {
+		return false
+	}
+	if s.tpm > 0 && s.tokens <= math.Min(needTokens, s.tpm-1) {
+		return false
+	}
+	return true
+}
+
+// delayLocked returns how long until the buckets have refilled enough for
+// a request with priority p.
+func (s *Scheduler) delayLocked(p Priority) time.Duration {
+	if p == PriorityBackground && len(s.queues[PriorityInteractive]) > 0 {
+		return time.Minute // woken when the interactive requests are sent
+	}
+	needRequests, needTokens := s.thresholdsLocked(p)
+	var minutes float64
+	if s.rpm > 0 && s.requests < needRequests {
+		minutes = (math.Min(needRequests, s.rpm) - s.requests) / s.rpm
+	}
+	if s.tpm > 0 && s.tokens <= needTokens {
+		minutes = math.Max(minutes, (math.Min(needTokens, s.tpm-1)+1-s.tokens)/s.tpm)
+	}
+	return max(time.Duration(minutes*float64(time.Minute)), minSchedulerDelay)
+}
+
+func (s *Scheduler) removeLocked(p Priority, w *schedWaiter) {
+	for i, q := range s.queues[p] {
+		if q == w {
+			s.queues[p] = append(s.queues[p][:i], s.queues[p][i+1:]...)
+			break
+		}
+	}
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
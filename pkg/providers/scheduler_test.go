@@ -0,0 +1,147 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeClock lets tests move the scheduler's time forward.
+type fakeClock struct {
+	s   *Scheduler
+	now time.Time
+}
+
+func newTestScheduler(rpm, tpm, reserve int) (*Scheduler, *fakeClock) {
+	s := NewScheduler(rpm, tpm, reserve)
+	c := &fakeClock{s: s, now: time.Unix(1700000000, 0)}
+	s.nowFunc = func() time.Time { return c.now }
+	s.last = c.now
+	return s, c
+}
+
+// advance moves time forward and wakes the waiting requests.
+func (c *fakeClock) advance(d time.Duration) {
+	c.s.mu.Lock()
+	c.now = c.now.Add(d)
+	close(c.s.changed)
+	c.s.changed = make(chan struct{})
+	c.s.mu.Unlock()
+}
+
+func waitFor(t *testing.T, s *Scheduler, p Priority, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		s.mu.Lock()
+		got := len(s.queues[p])
+		s.mu.Unlock()
+		if got == n {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("%d %s requests never waited", n, p)
+}
+
+func TestScheduler_NilDoesNotLimit(t *testing.T) {
+	var s *Scheduler
+	if err := s.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	s.Record(&LLMResponse{Usage: &UsageInfo{TotalTokens: 100}})
+	p := &structuredMock{}
+	if s.Provider(p) != LLMProvider(p) {
+		t.Error("nil scheduler should not wrap the provider")
+	}
+}
+
+func TestScheduler_ReserveIsKeptForInteractive(t *testing.T) {
+	s, _ := newTestScheduler(10, 0, 20)
+	s.requests = 2.5 // below the background threshold of 1 + 2
+
+	bg := WithPriority(context.Background(), PriorityBackground)
+	ctx, cancel := context.WithTimeout(bg, 50*time.Millisecond)
+	defer cancel()
+	if err := s.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("background Wait() = %v, want deadline exceeded", err)
+	}
+	if len(s.queues[PriorityBackground]) != 0 {
+		t.Error("cancelled request left in the queue")
+	}
+
+	if err := s.Wait(context.Background()); err != nil {
+		t.Fatalf("interactive Wait() error = %v", err)
+	}
+	if s.requests != 1.5 {
+		t.Errorf("requests = %v, want 1.5", s.requests)
+	}
+}
+
+func TestScheduler_InteractiveGoesFirst(t *testing.T) {
+	s, clock := newTestScheduler(60, 0, 0)
+	s.requests = 0
+
+	order := make(chan Priority, 2)
+	wait := func(p Priority) {
+		if err := s.Wait(WithPriority(context.Background(), p)); err == nil {
+			order <- p
+		}
+	}
+	go wait(PriorityBackground)
+	waitFor(t, s, PriorityBackground, 1)
+	go wait(PriorityInteractive)
+	waitFor(t, s, PriorityInteractive, 1)
+
+	clock.advance(time.Second) // one request
+	if got := <-order; got != PriorityInteractive {
+		t.Fatalf("first request was %s", got)
+	}
+	select {
+	case p := <-order:
+		t.Fatalf("%s request sent without quota", p)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	clock.advance(time.Second)
+	if got := <-order; got != PriorityBackground {
+		t.Fatalf("second request was %s", got)
+	}
+}
+
+func TestScheduler_TokensAreChargedAfterwards(t *testing.T) {
+	s, clock := newTestScheduler(0, 6000, 0)
+	p := s.Provider(&structuredMock{responses: []*LLMResponse{
+		{Usage: &UsageInfo{PromptTokens: 6000, CompletionTokens: 1000}},
+	}})
+	if _, err := p.Chat(context.Background(), nil, nil, "m", nil); err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if s.tokens != -1000 {
+		t.Fatalf("tokens = %v, want -1000", s.tokens)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- s.Wait(context.Background()) }()
+	waitFor(t, s, PriorityInteractive, 1)
+	select {
+	case <-done:
+		t.Fatal("request sent while the token quota was used up")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	clock.advance(11 * time.Second) // 1100 tokens
+	if err := <-done; err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+}
+
+func TestScheduledProvider_KeepsStructuredOutput(t *testing.T) {
+	s := NewScheduler(60, 0, 0)
+	p := s.Provider(&structuredMock{mode: StructuredToolChoice})
+	so, ok := p.(StructuredOutputter)
+	if !ok || so.StructuredOutput() != StructuredToolChoice {
+		t.Error("scheduled provider lost the structured output mode")
+	}
+}
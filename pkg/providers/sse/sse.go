@@ -0,0 +1,97 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package sse reads server-sent event streams from LLM APIs.
+package sse
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+)
+
+// Reader incrementally reads server-sent events from a stream. Unlike
+// scanning whole lines of a fully buffered body, it copes with events split
+// across reads, multi-line data fields, CRLF line endings and keep-alive
+// comments, and has no per-line size limit.
+type Reader struct {
+	r    *bufio.Reader
+	line []byte
+	data bytes.Buffer
+}
+
+// NewReader returns a Reader for the event stream r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Next returns the data payload of the next event that carries data. The
+// returned slice is only valid until the next call. It returns io.EOF when
+// the stream ends.
+func (s *Reader) Next() ([]byte, error) {
+	s.data.Reset()
+	hasData := false
+
+	for {
+		line, err := s.readLine()
+		if err != nil {
+			// A final event without the trailing blank line still counts.
+			if errors.Is(err, io.EOF) && hasData {
+				return s.data.Bytes(), nil
+			}
+			return nil, err
+		}
+
+		if len(line) == 0 {
+			if hasData {
+				return s.data.Bytes(), nil
+			}
+			continue
+		}
+		if line[0] == ':' {
+			continue // comment / keep-alive
+		}
+
+		field, value := line, []byte(nil)
+		if i := bytes.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], line[i+1:]
+			if len(value) > 0 && value[0] == ' ' {
+				value = value[1:]
+			}
+		}
+		if string(field) != "data" {
+			continue // event, id and retry are not needed
+		}
+
+		if hasData {
+			s.data.WriteByte('\n')
+		}
+		s.data.Write(value)
+		hasData = true
+	}
+}
+
+// readLine returns the next line without its terminator. Lines longer than
+// the buffer are assembled across reads.
+func (s *Reader) readLine() ([]byte, error) {
+	s.line = s.line[:0]
+	for {
+		chunk, err := s.r.ReadSlice('\n')
+		s.line = append(s.line, chunk...)
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil && (err != io.EOF || len(s.line) == 0) {
+			return nil, err
+		}
+		line := bytes.TrimSuffix(s.line, []byte("\n"))
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		if err == io.EOF && len(line) == 0 {
+			return nil, io.EOF
+		}
+		return line, nil
+	}
+}
@@ -0,0 +1,58 @@
+package sse
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func readAllEvents(t *testing.T, r io.Reader) []string {
+	t.Helper()
+	var out []string
+	events := NewReader(r)
+	for {
+		data, err := events.Next()
+		if err == io.EOF {
+			return out
+		}
+		if err != nil {
+			t.Fatalf("Next() error: %v", err)
+		}
+		out = append(out, string(data))
+	}
+}
+
+func TestSSEReader_EventFraming(t *testing.T) {
+	stream := ": keep-alive\r\n" +
+		"event: message\r\n" +
+		"data: {\"a\":1}\r\n\r\n" +
+		"data:{\"b\":2}\n\n" +
+		"data: line1\n" +
+		"data: line2\n\n" +
+		"id: 7\n\n" +
+		"data: [DONE]"
+
+	got := readAllEvents(t, strings.NewReader(stream))
+	want := []string{`{"a":1}`, `{"b":2}`, "line1\nline2", "[DONE]"}
+	if len(got) != len(want) {
+		t.Fatalf("events = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSSEReader_SplitReadsAndLongLines(t *testing.T) {
+	long := strings.Repeat("x", 200*1024)
+	stream := "data: " + long + "\n\ndata: next\n\n"
+
+	// OneByteReader delivers the stream one byte per Read, like an event
+	// split across many TCP packets.
+	got := readAllEvents(t, iotest.OneByteReader(strings.NewReader(stream)))
+	if len(got) != 2 || got[0] != long || got[1] != "next" {
+		t.Fatalf("unexpected events: %d events", len(got))
+	}
+}
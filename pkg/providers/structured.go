@@ -0,0 +1,303 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// StructuredMode is how a provider constrains a reply to a JSON schema.
+type StructuredMode string
+
+const (
+	// StructuredPrompt puts the schema in the prompt; the reply is only
+	// validated afterwards. Used for providers without a native mode.
+	StructuredPrompt StructuredMode = ""
+	// StructuredResponseFormat sends the schema as the OpenAI
+	// response_format option.
+	StructuredResponseFormat StructuredMode = "response_format"
+	// StructuredToolChoice offers the schema as the only tool and forces
+	// the model to call it.
+	StructuredToolChoice StructuredMode = "tool_choice"
+)
+
+// structuredRepairs is how many times a reply that does not match the
+// schema is sent back to the model to be corrected.
+const structuredRepairs = 2
+
+// Schema describes the JSON value ChatStructured asks for.
+type Schema struct {
+	// Name identifies the schema to the API, e.g. "task_result".
+	Name        string
+	Description string
+	// Schema is a JSON schema, as decoded from JSON. Replies are checked
+	// against its type, properties, required, items, enum and
+	// additionalProperties keywords.
+	Schema map[string]interface{}
+}
+
+// ChatStructured asks model for a reply that is a JSON value matching
+// schema, using the provider's native structured output if it has one
+// and instructions in the prompt otherwise. Replies that are not valid
+// JSON or do not match the schema are sent back to be corrected a few
+// times. It returns the JSON value and the last response.
+func ChatStructured(ctx context.Context, provider LLMProvider, messages []Message, model string, options map[string]interface{}, schema Schema) (json.RawMessage, *LLMResponse, error) {
+	if schema.Name == "" {
+		schema.Name = "result"
+	}
+	mode := StructuredPrompt
+	if s, ok := provider.(StructuredOutputter); ok {
+		mode = s.StructuredOutput()
+	}
+
+	opts := make(map[string]interface{}, len(options)+1)
+	for k, v := range options {
+		opts[k] = v
+	}
+	msgs := append([]Message(nil), messages...)
+	var tools []ToolDefinition
+
+	switch mode {
+	case StructuredResponseFormat:
+		opts["response_format"] = map[string]interface{}{
+			"type": "json_schema",
+			"json_schema": map[string]interface{}{
+				"name":   schema.Name,
+				"schema": schema.Schema,
+			},
+		}
+	case StructuredToolChoice:
+		tools = []ToolDefinition{{
+			Type: "function",
+			Function: ToolFunctionDefinition{
+				Name:        schema.Name,
+				Description: schemaDescription(schema),
+				Parameters:  schema.Schema,
+			},
+		}}
+		opts["tool_choice"] = schema.Name
+	default:
+		msgs = append(msgs, Message{Role: "system", Content: StructuredInstructions(schema)})
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= structuredRepairs; attempt++ {
+		resp, err := provider.Chat(ctx, msgs, tools, model, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		reply := resp.Content
+		if mode == StructuredToolChoice {
+			reply = ""
+			for _, tc := range resp.ToolCalls {
+				if tc := NormalizeToolCall(tc); tc.Name == schema.Name {
+					args, _ := json.Marshal(tc.Arguments)
+					reply = string(args)
+					break
+				}
+			}
+		}
+
+		value, err := ParseStructured(reply, schema.Schema)
+		if err == nil {
+			return value, resp, nil
+		}
+		lastErr = err
+		msgs = append(msgs,
+			Message{Role: "assistant", Content: reply},
+			Message{Role: "user", Content: fmt.Sprintf("That reply is not valid: %v. Reply again with only the corrected JSON.", err)},
+		)
+	}
+	return nil, nil, fmt.Errorf("reply does not match schema %s: %w", schema.Name, lastErr)
+}
+
+func schemaDescription(schema Schema) string {
+	if schema.Description != "" {
+		return schema.Description
+	}
+	return "Give the result."
+}
+
+// StructuredInstructions asks for a reply matching schema in a prompt.
+func StructuredInstructions(schema Schema) string {
+	data, _ := json.MarshalIndent(schema.Schema, "", "  ")
+	var sb strings.Builder
+	sb.WriteString("Reply with only a JSON value, without any other text or code fences, that matches this JSON schema")
+	if schema.Description != "" {
+		sb.WriteString(" (" + schema.Description + ")")
+	}
+	sb.WriteString(":\n")
+	sb.Write(data)
+	return sb.String()
+}
+
+// ParseStructured extracts the JSON value from a reply, which may wrap it
+// in code fences or text, and checks it against schema.
+func ParseStructured(reply string, schema map[string]interface{}) (json.RawMessage, error) {
+	text := extractJSON(reply)
+	if text == "" {
+		return nil, fmt.Errorf("no JSON in reply")
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if err := ValidateSchema(schema, value); err != nil {
+		return nil, err
+	}
+	return json.RawMessage(text), nil
+}
+
+// extractJSON returns the JSON in a reply, without code fences or text
+// around it.
+func extractJSON(reply string) string {
+	text := strings.TrimSpace(reply)
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text, "```")
+		text = strings.TrimPrefix(text, "json")
+		text = strings.TrimSuffix(strings.TrimSpace(text), "```")
+		text = strings.TrimSpace(text)
+	}
+	if json.Valid([]byte(text)) {
+		return text
+	}
+	start := strings.IndexAny(text, "{[")
+	if start < 0 {
+		return text
+	}
+	closing := "}"
+	if text[start] == '[' {
+		closing = "]"
+	}
+	end := strings.LastIndex(text, closing)
+	if end < start {
+		return text
+	}
+	return text[start : end+1]
+}
+
+// ValidateSchema checks value, as decoded by encoding/json, against the
+// type, properties, required, items, enum and additionalProperties
+// keywords of a JSON schema.
+func ValidateSchema(schema map[string]interface{}, value interface{}) error {
+	return validateSchema(schema, value, "$")
+}
+
+func validateSchema(schema map[string]interface{}, value interface{}, path string) error {
+	if schema == nil {
+		return nil
+	}
+	if err := checkType(schema["type"], value, path); err != nil {
+		return err
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if fmt.Sprint(e) == fmt.Sprint(value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: %v is not one of %v", path, value, enum)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		props, _ := schema["properties"].(map[string]interface{})
+		for _, name := range stringList(schema["required"]) {
+			if _, ok := v[name]; !ok {
+				return fmt.Errorf("%s: missing required property %q", path, name)
+			}
+		}
+		for name, field := range v {
+			sub, ok := props[name].(map[string]interface{})
+			if !ok {
+				if allowed, ok := schema["additionalProperties"].(bool); ok && !allowed {
+					return fmt.Errorf("%s: unexpected property %q", path, name)
+				}
+				continue
+			}
+			if err := validateSchema(sub, field, path+"."+name); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		items, _ := schema["items"].(map[string]interface{})
+		for i, item := range v {
+			if err := validateSchema(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkType checks the type keyword, a type name or a list of them.
+func checkType(want interface{}, value interface{}, path string) error {
+	types := stringList(want)
+	if s, ok := want.(string); ok {
+		types = []string{s}
+	}
+	if len(types) == 0 {
+		return nil
+	}
+	for _, t := range types {
+		if hasType(t, value) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: expected %s, got %s", path, strings.Join(types, " or "), jsonType(value))
+}
+
+func hasType(t string, value interface{}) bool {
+	switch t {
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	default:
+		return jsonType(value) == t
+	}
+}
+
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func stringList(v interface{}) []string {
+	switch list := v.(type) {
+	case []string:
+		return list
+	case []interface{}:
+		out := make([]string, 0, len(list))
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
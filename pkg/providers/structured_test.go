@@ -0,0 +1,139 @@
+package providers
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// structuredMock replies with the given responses in turn and records
+// the requests.
+type structuredMock struct {
+	mode      StructuredMode
+	responses []*LLMResponse
+	requests  []structuredRequest
+}
+
+type structuredRequest struct {
+	messages []Message
+	tools    []ToolDefinition
+	options  map[string]interface{}
+}
+
+func (m *structuredMock) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	m.requests = append(m.requests, structuredRequest{messages, tools, options})
+	resp := m.responses[0]
+	m.responses = m.responses[1:]
+	return resp, nil
+}
+
+func (m *structuredMock) GetDefaultModel() string          { return "test-model" }
+func (m *structuredMock) StructuredOutput() StructuredMode { return m.mode }
+
+var personSchema = Schema{
+	Name: "person",
+	Schema: map[string]interface{}{
+		"type":     "object",
+		"required": []interface{}{"name", "age"},
+		"properties": map[string]interface{}{
+			"name": map[string]interface{}{"type": "string"},
+			"age":  map[string]interface{}{"type": "integer"},
+		},
+	},
+}
+
+func TestChatStructured_PromptRepairsInvalidReply(t *testing.T) {
+	m := &structuredMock{responses: []*LLMResponse{
+		{Content: "Sure! {\"name\": \"Ada\"}"},
+		{Content: "```json\n{\"name\": \"Ada\", \"age\": 36}\n```"},
+	}}
+	value, _, err := ChatStructured(context.Background(), m, []Message{{Role: "user", Content: "Who?"}}, "test-model", nil, personSchema)
+	if err != nil {
+		t.Fatalf("ChatStructured() error: %v", err)
+	}
+	if string(value) != `{"name": "Ada", "age": 36}` {
+		t.Errorf("value = %s", value)
+	}
+	if len(m.requests) != 2 {
+		t.Fatalf("requests = %d, want 2", len(m.requests))
+	}
+	first := m.requests[0].messages
+	if last := first[len(first)-1]; last.Role != "system" || !strings.Contains(last.Content, `"age"`) {
+		t.Errorf("schema not in prompt: %+v", last)
+	}
+	retry := m.requests[1].messages
+	if last := retry[len(retry)-1]; !strings.Contains(last.Content, `missing required property "age"`) {
+		t.Errorf("repair request = %q", last.Content)
+	}
+}
+
+func TestChatStructured_GivesUpAfterRepairs(t *testing.T) {
+	m := &structuredMock{responses: []*LLMResponse{{Content: "no"}, {Content: "still no"}, {Content: "never"}}}
+	if _, _, err := ChatStructured(context.Background(), m, nil, "test-model", nil, personSchema); err == nil {
+		t.Fatal("expected an error")
+	}
+	if len(m.requests) != structuredRepairs+1 {
+		t.Errorf("requests = %d, want %d", len(m.requests), structuredRepairs+1)
+	}
+}
+
+func TestChatStructured_NativeModes(t *testing.T) {
+	m := &structuredMock{mode: StructuredResponseFormat, responses: []*LLMResponse{{Content: `{"name":"Ada","age":36}`}}}
+	if _, _, err := ChatStructured(context.Background(), m, nil, "test-model", map[string]interface{}{"max_tokens": 10}, personSchema); err != nil {
+		t.Fatalf("response_format: %v", err)
+	}
+	opts := m.requests[0].options
+	if format, _ := opts["response_format"].(map[string]interface{}); format["type"] != "json_schema" || opts["max_tokens"] != 10 {
+		t.Errorf("options = %v", opts)
+	}
+
+	m = &structuredMock{mode: StructuredToolChoice, responses: []*LLMResponse{{ToolCalls: []ToolCall{{
+		ID: "call_1", Name: "person", Arguments: map[string]interface{}{"name": "Ada", "age": 36},
+	}}}}}
+	value, _, err := ChatStructured(context.Background(), m, nil, "test-model", nil, personSchema)
+	if err != nil {
+		t.Fatalf("tool_choice: %v", err)
+	}
+	if string(value) != `{"age":36,"name":"Ada"}` {
+		t.Errorf("value = %s", value)
+	}
+	req := m.requests[0]
+	if len(req.tools) != 1 || req.tools[0].Function.Name != "person" || req.options["tool_choice"] != "person" {
+		t.Errorf("request = %+v", req)
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties": map[string]interface{}{
+			"tags":   map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+			"level":  map[string]interface{}{"enum": []interface{}{"low", "high"}},
+			"score":  map[string]interface{}{"type": []interface{}{"number", "null"}},
+			"nested": map[string]interface{}{"type": "object", "required": []interface{}{"id"}},
+		},
+	}
+	tests := []struct {
+		value   string
+		wantErr string
+	}{
+		{`{"tags":["a"],"level":"low","score":null,"nested":{"id":1}}`, ""},
+		{`{"score":1.5}`, ""},
+		{`[]`, "expected object"},
+		{`{"tags":["a",1]}`, "$.tags[1]: expected string"},
+		{`{"level":"mid"}`, "not one of"},
+		{`{"score":"1"}`, "expected number or null"},
+		{`{"nested":{}}`, `$.nested: missing required property "id"`},
+		{`{"other":1}`, `unexpected property "other"`},
+	}
+	for _, tt := range tests {
+		_, err := ParseStructured(tt.value, schema)
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.value, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: error = %v, want %q", tt.value, err, tt.wantErr)
+		}
+	}
+}
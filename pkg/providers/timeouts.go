@@ -0,0 +1,81 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+package providers
+
+import (
+	"context"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers/httptimeout"
+)
+
+// timeoutsMiddleware returns the middleware that applies the timeouts of
+// the entry cfg, or nil if it sets none. The connect and read timeouts
+// reach the HTTP transports of the providers through the request context;
+// the total timeout is the context's deadline, so it also bounds the
+// providers that run a program. Like rate limits, it leaves the
+// provider's file API in place.
+func timeoutsMiddleware(cfg *config.ModelConfig) Middleware {
+	t := cfg.Timeouts
+	if t == nil || (t.ConnectSeconds == 0 && t.ReadSeconds == 0 && t.TotalSeconds == 0) {
+		return nil
+	}
+	limits := httptimeout.Timeouts{Connect: seconds(t.ConnectSeconds), Read: seconds(t.ReadSeconds)}
+	total := seconds(t.TotalSeconds)
+	return func(next LLMProvider) LLMProvider {
+		return &timeoutProvider{LLMProvider: next, limits: limits, total: total}
+	}
+}
+
+// seconds converts a timeout in seconds, keeping negative ones, which
+// remove the limit, negative.
+func seconds(n int) time.Duration {
+	if n < 0 {
+		return -1
+	}
+	return time.Duration(n) * time.Second
+}
+
+type timeoutProvider struct {
+	LLMProvider
+	limits httptimeout.Timeouts
+	total  time.Duration
+}
+
+func (p *timeoutProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	ctx, cancel := p.context(ctx)
+	defer cancel()
+	resp, err := p.LLMProvider.Chat(ctx, messages, tools, model, options)
+	return resp, p.error(ctx, err)
+}
+
+func (p *timeoutProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onEvent func(StreamEvent)) (*LLMResponse, error) {
+	ctx, cancel := p.context(ctx)
+	defer cancel()
+	resp, err := StreamChat(ctx, p.LLMProvider, messages, tools, model, options, onEvent)
+	return resp, p.error(ctx, err)
+}
+
+func (p *timeoutProvider) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx = httptimeout.WithTimeouts(ctx, p.limits)
+	if p.total <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeoutCause(ctx, p.total, &httptimeout.Error{Phase: "total", Limit: p.total})
+}
+
+// error reports a request cut short by the total timeout as such, rather
+// than as whatever the provider made of its cancelled context.
+func (p *timeoutProvider) error(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if cause, ok := context.Cause(ctx).(*httptimeout.Error); ok {
+		return cause
+	}
+	return err
+}
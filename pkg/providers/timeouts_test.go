@@ -0,0 +1,126 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers/httptimeout"
+)
+
+// stuckProvider never replies; it returns when its context is done.
+type stuckProvider struct{}
+
+func (stuckProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (stuckProvider) GetDefaultModel() string { return "stuck" }
+
+func TestTimeoutsMiddleware_None(t *testing.T) {
+	if m := timeoutsMiddleware(&config.ModelConfig{}); m != nil {
+		t.Error("middleware for an entry without timeouts, want none")
+	}
+	if m := timeoutsMiddleware(&config.ModelConfig{Timeouts: &config.ProviderTimeouts{}}); m != nil {
+		t.Error("middleware for an entry with zero timeouts, want none")
+	}
+}
+
+func TestTimeoutProvider_Total(t *testing.T) {
+	p := &timeoutProvider{LLMProvider: stuckProvider{}, total: 50 * time.Millisecond}
+
+	start := time.Now()
+	_, err := p.Chat(t.Context(), nil, nil, "stuck", nil)
+	var te *httptimeout.Error
+	if !errors.As(err, &te) || te.Phase != "total" {
+		t.Fatalf("Chat() error = %v, want the total timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Chat() took %s", elapsed)
+	}
+	if fe := ClassifyError(err, "openai", "gpt-4o"); fe == nil || fe.Reason != FailoverTimeout {
+		t.Errorf("ClassifyError() = %v, want a timeout", fe)
+	}
+}
+
+func TestTimeoutProvider_CallerCancelIsNotATimeout(t *testing.T) {
+	p := &timeoutProvider{LLMProvider: stuckProvider{}, total: time.Minute}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	_, err := p.Chat(ctx, nil, nil, "stuck", nil)
+	if err != context.Canceled {
+		t.Fatalf("Chat() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestCreateProviderFromConfig_ReadTimeoutMidStream(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n"))
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	p, _, err := CreateProviderFromConfig(&config.ModelConfig{
+		ModelName: "stalls",
+		Model:     "openai/gpt-4o",
+		APIBase:   server.URL,
+		APIKey:    "key",
+		Timeouts:  &config.ProviderTimeouts{ReadSeconds: 1},
+	})
+	if err != nil {
+		t.Fatalf("CreateProviderFromConfig() error = %v", err)
+	}
+
+	var text string
+	_, err = StreamChat(t.Context(), p, []Message{{Role: "user", Content: "Hi"}}, nil, "gpt-4o", nil, func(ev StreamEvent) {
+		text += ev.Text
+	})
+	var te *httptimeout.Error
+	if !errors.As(err, &te) || te.Phase != "read" {
+		t.Fatalf("StreamChat() error = %v, want the read timeout", err)
+	}
+	if text != "Hel" {
+		t.Errorf("streamed %q before the stall, want %q", text, "Hel")
+	}
+}
+
+func TestHTTPProvider_ChatStreamStopsWhenCancelled(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n"))
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	p := NewHTTPProvider("key", server.URL, "")
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.ChatStream(ctx, []Message{{Role: "user", Content: "Hi"}}, nil, "gpt-4o", nil, func(ev StreamEvent) {
+			cancel()
+		})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("ChatStream() error = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ChatStream() did not return after its context was cancelled")
+	}
+}
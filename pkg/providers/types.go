@@ -16,12 +16,72 @@ type ToolDefinition = protocoltypes.ToolDefinition
 type ToolFunctionDefinition = protocoltypes.ToolFunctionDefinition
 type ExtraContent = protocoltypes.ExtraContent
 type GoogleExtra = protocoltypes.GoogleExtra
+type FileRef = protocoltypes.FileRef
+type Image = protocoltypes.Image
+type StreamEvent = protocoltypes.StreamEvent
+
+var ErrFileNotFound = protocoltypes.ErrFileNotFound
 
 type LLMProvider interface {
 	Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error)
 	GetDefaultModel() string
 }
 
+// FileStore is implemented by providers with a native file API. Uploaded
+// documents are attached to messages as FileRefs and sent by ID. DeleteFile
+// returns an error matching ErrFileNotFound for a file the provider no
+// longer knows.
+type FileStore interface {
+	UploadFile(ctx context.Context, name, mimeType string, data []byte) (string, error)
+	DeleteFile(ctx context.Context, id string) error
+}
+
+// StreamingProvider is implemented by providers that can stream responses.
+// ChatStream returns the same response as Chat, and calls onEvent from the
+// goroutine reading the stream as parts of it complete.
+type StreamingProvider interface {
+	ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onEvent func(StreamEvent)) (*LLMResponse, error)
+}
+
+// Warmer is implemented by providers that can open a connection to their
+// API ahead of the first request.
+type Warmer interface {
+	Warm(ctx context.Context) error
+}
+
+// HealthChecker is implemented by providers that can check, without a
+// completion, that they can serve requests. Use CheckHealth to check any
+// provider.
+type HealthChecker interface {
+	CheckHealth(ctx context.Context) error
+}
+
+// ModelLister is implemented by providers that can list the models they
+// serve: from the API where it has a models endpoint, and otherwise from
+// a list of known models.
+type ModelLister interface {
+	ListModels(ctx context.Context) ([]string, error)
+}
+
+// Embedder is implemented by providers with an embeddings endpoint. Use
+// CreateEmbeddingsProvider to get embeddings for a configured model.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string, model string) ([][]float32, error)
+}
+
+// Seeder is implemented by providers that pass options["seed"] on to the
+// API, so that a response can be reproduced, as far as the API allows,
+// with the same seed, model version and settings.
+type Seeder interface {
+	SupportsSeed() bool
+}
+
+// StructuredOutputter is implemented by providers that can constrain a
+// reply to a JSON schema natively; see ChatStructured.
+type StructuredOutputter interface {
+	StructuredOutput() StructuredMode
+}
+
 // FailoverReason classifies why an LLM request failed for fallback decisions.
 type FailoverReason string
 
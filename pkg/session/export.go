@@ -0,0 +1,162 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// exportTemplate renders a session as a single self-contained HTML page:
+// styles are inline and nothing is loaded from the network, so the file
+// can be shared or archived as is.
+var exportTemplate = template.Must(template.New("export").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{if .Title}}{{.Title}}{{else}}{{.Key}}{{end}} - PicoClaw conversation</title>
+<style>
+body{font-family:-apple-system,"Segoe UI",Roboto,sans-serif;max-width:860px;margin:2em auto;padding:0 1em;color:#222;background:#fafafa}
+header{border-bottom:1px solid #ddd;margin-bottom:1.5em}
+header p{color:#666;font-size:.9em}
+.msg{border-radius:8px;padding:.75em 1em;margin:.75em 0;white-space:pre-wrap;word-wrap:break-word}
+.role{font-size:.75em;font-weight:bold;text-transform:uppercase;color:#888;margin-bottom:.3em}
+.user{background:#e3f0ff}
+.assistant{background:#fff;border:1px solid #e5e5e5}
+.system,.summary{background:#f3f3f3;color:#555}
+details{margin:.4em 0;font-size:.9em}
+summary{cursor:pointer;color:#555}
+pre{background:#f5f5f5;padding:.6em;overflow-x:auto;white-space:pre-wrap;font-size:.85em}
+</style>
+</head>
+<body>
+<header>
+<h1>{{if .Title}}{{.Title}}{{else}}{{.Key}}{{end}}</h1>
+{{if .Title}}<p>{{.Key}}{{if .Tags}} &middot; {{range $i, $t := .Tags}}{{if $i}}, {{end}}#{{$t}}{{end}}{{end}}</p>
+{{end}}<p>{{.Created}} &ndash; {{.Updated}} &middot; {{.MessageCount}} messages &middot; {{.ToolCallCount}} tool calls</p>
+</header>
+{{if .Summary}}<div class="msg summary"><div class="role">Earlier conversation (summary)</div>{{.Summary}}</div>
+{{end}}{{range .Entries}}<div class="msg {{.Role}}"><div class="role">{{.Role}}</div>{{.Content}}{{range .ToolCalls}}
+<details><summary>Tool call: {{.Name}}</summary><pre>{{.Arguments}}</pre>{{if .Result}}<div class="role">Result</div><pre>{{.Result}}</pre>{{end}}</details>{{end}}</div>
+{{end}}<footer><p><small>Exported by PicoClaw on {{.Exported}}</small></p></footer>
+</body>
+</html>
+`))
+
+type exportToolCall struct {
+	Name      string
+	Arguments string
+	Result    string
+}
+
+type exportEntry struct {
+	Role      string
+	Content   string
+	ToolCalls []exportToolCall
+}
+
+type exportPage struct {
+	Key           string
+	Title         string
+	Tags          []string
+	Summary       string
+	Created       string
+	Updated       string
+	Exported      string
+	MessageCount  int
+	ToolCallCount int
+	Entries       []exportEntry
+}
+
+// ExportHTML writes s to w as a standalone HTML document. Tool calls are
+// collapsed under the assistant message that made them, with their results
+// folded in instead of shown as separate messages.
+func ExportHTML(w io.Writer, s Session) error {
+	page := exportPage{
+		Key:      s.Key,
+		Title:    s.Title,
+		Tags:     s.Tags,
+		Summary:  s.Summary,
+		Created:  formatExportTime(s.Created),
+		Updated:  formatExportTime(s.Updated),
+		Exported: formatExportTime(time.Now()),
+	}
+
+	// Tool results follow the assistant message that requested them and
+	// are folded into the matching call.
+	calls := make(map[string]*exportToolCall)
+	for _, msg := range s.Messages {
+		if msg.Role == "tool" {
+			if call, ok := calls[msg.ToolCallID]; ok {
+				call.Result = msg.Content
+				continue
+			}
+			// Orphaned result, e.g. its call was truncated away.
+			page.Entries = append(page.Entries, exportEntry{Role: "tool", Content: msg.Content})
+			continue
+		}
+
+		entry := exportEntry{Role: msg.Role, Content: strings.TrimSpace(msg.Content)}
+		for _, tc := range msg.ToolCalls {
+			entry.ToolCalls = append(entry.ToolCalls, exportToolCall{
+				Name:      toolCallName(tc),
+				Arguments: toolCallArguments(tc),
+			})
+		}
+		for i, tc := range msg.ToolCalls {
+			calls[tc.ID] = &entry.ToolCalls[i]
+		}
+		page.Entries = append(page.Entries, entry)
+		page.MessageCount++
+		page.ToolCallCount += len(msg.ToolCalls)
+	}
+
+	if err := exportTemplate.Execute(w, page); err != nil {
+		return fmt.Errorf("rendering session %s: %w", s.Key, err)
+	}
+	return nil
+}
+
+func formatExportTime(t time.Time) string {
+	if t.IsZero() {
+		return "unknown"
+	}
+	return t.Format("2006-01-02 15:04 MST")
+}
+
+func toolCallName(tc providers.ToolCall) string {
+	if tc.Function != nil && tc.Function.Name != "" {
+		return tc.Function.Name
+	}
+	return tc.Name
+}
+
+func toolCallArguments(tc providers.ToolCall) string {
+	raw := ""
+	if tc.Function != nil {
+		raw = tc.Function.Arguments
+	}
+	if raw == "" && len(tc.Arguments) > 0 {
+		b, _ := json.Marshal(tc.Arguments)
+		raw = string(b)
+	}
+
+	var v interface{}
+	if json.Unmarshal([]byte(raw), &v) == nil {
+		if pretty, err := json.MarshalIndent(v, "", "  "); err == nil {
+			return string(pretty)
+		}
+	}
+	return raw
+}
+
+// ExportFilename returns the file name used for the HTML export of the
+// session stored under key.
+func ExportFilename(key string) string {
+	return sanitizeFilename(key) + ".html"
+}
@@ -0,0 +1,81 @@
+package session
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+func TestExportHTML(t *testing.T) {
+	sess := Session{
+		Key:     "telegram:42",
+		Summary: "Earlier they talked about the weather.",
+		Created: time.Date(2026, 1, 2, 3, 4, 0, 0, time.UTC),
+		Updated: time.Date(2026, 1, 2, 4, 0, 0, 0, time.UTC),
+		Messages: []providers.Message{
+			{Role: "user", Content: "list files <please>"},
+			{Role: "assistant", ToolCalls: []providers.ToolCall{{
+				ID:       "call_1",
+				Type:     "function",
+				Function: &providers.FunctionCall{Name: "list_dir", Arguments: `{"path":"."}`},
+			}}},
+			{Role: "tool", ToolCallID: "call_1", Content: "README.md\nmain.go"},
+			{Role: "assistant", Content: "There are two files."},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := ExportHTML(&buf, sess); err != nil {
+		t.Fatalf("ExportHTML() error: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"<title>telegram:42 - PicoClaw conversation</title>",
+		"Earlier they talked about the weather.",
+		"list files &lt;please&gt;",
+		"<details><summary>Tool call: list_dir</summary>",
+		"&#34;path&#34;: &#34;.&#34;",
+		"README.md\nmain.go",
+		"There are two files.",
+		"3 messages &middot; 1 tool calls",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("export missing %q", want)
+		}
+	}
+	// The tool result is folded into its call, not rendered on its own.
+	if strings.Contains(out, `class="msg tool"`) {
+		t.Error("tool result rendered as a separate message")
+	}
+	if strings.Contains(out, "<script") || strings.Contains(out, "http://") || strings.Contains(out, "https://") {
+		t.Error("export should not reference scripts or external resources")
+	}
+}
+
+func TestExportHTML_Title(t *testing.T) {
+	sess := Session{Key: "telegram:42", Title: "Heater repair", Tags: []string{"home", "diy"}}
+
+	var buf bytes.Buffer
+	if err := ExportHTML(&buf, sess); err != nil {
+		t.Fatalf("ExportHTML() error: %v", err)
+	}
+	for _, want := range []string{
+		"<title>Heater repair - PicoClaw conversation</title>",
+		"<h1>Heater repair</h1>",
+		"<p>telegram:42 &middot; #home, #diy</p>",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("export missing %q", want)
+		}
+	}
+}
+
+func TestExportFilename(t *testing.T) {
+	if got := ExportFilename("agent:main:telegram:42"); got != "agent_main_telegram_42.html" {
+		t.Errorf("ExportFilename() = %q", got)
+	}
+}
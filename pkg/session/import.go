@@ -0,0 +1,289 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// maxImportFileBytes is the largest file FileMessage takes; larger ones
+// would fill most context windows on their own.
+const maxImportFileBytes = 512 << 10
+
+// carryoverChars bounds how much of another session's latest messages
+// Carryover includes.
+const carryoverChars = 4000
+
+// Conversation is a conversation read from an export, with its messages in
+// PicoClaw's message model.
+type Conversation struct {
+	Title    string
+	Created  time.Time
+	Messages []providers.Message
+}
+
+// ParseConversations reads the conversations in an export: ChatGPT's or
+// Claude's conversations.json (or one conversation of either), a PicoClaw
+// session file, or a JSON array of messages with role and content.
+//
+// Only the text of user and assistant messages is kept from ChatGPT and
+// Claude exports; attachments, tool use and hidden system messages are
+// dropped, and consecutive messages of the same role are joined.
+func ParseConversations(data []byte) ([]Conversation, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty export")
+	}
+
+	var items []json.RawMessage
+	if data[0] == '[' {
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, fmt.Errorf("parsing export: %w", err)
+		}
+	} else {
+		items = []json.RawMessage{data}
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("export has no conversations")
+	}
+
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(items[0], &probe); err != nil {
+		return nil, fmt.Errorf("parsing export: %w", err)
+	}
+	switch {
+	case probe["mapping"] != nil:
+		return parseChatGPT(items)
+	case probe["chat_messages"] != nil:
+		return parseClaude(items)
+	case probe["role"] != nil:
+		// A bare list of messages is one conversation.
+		var msgs []providers.Message
+		if err := json.Unmarshal(data, &msgs); err != nil {
+			return nil, fmt.Errorf("parsing messages: %w", err)
+		}
+		return []Conversation{{Messages: withoutSystem(msgs)}}, nil
+	case probe["messages"] != nil:
+		var convs []Conversation
+		for _, item := range items {
+			var s Session
+			if err := json.Unmarshal(item, &s); err != nil {
+				return nil, fmt.Errorf("parsing session: %w", err)
+			}
+			convs = append(convs, Conversation{Title: s.Title, Created: s.Created, Messages: withoutSystem(s.Messages)})
+		}
+		return convs, nil
+	}
+	return nil, fmt.Errorf("unrecognized export format")
+}
+
+// parseChatGPT reads ChatGPT conversations. Their messages form a tree, as
+// edited prompts and regenerated replies branch off; the branch that ends
+// at current_node is the one that was shown last.
+func parseChatGPT(items []json.RawMessage) ([]Conversation, error) {
+	type node struct {
+		Parent  string `json:"parent"`
+		Message *struct {
+			Author struct {
+				Role string `json:"role"`
+			} `json:"author"`
+			Content struct {
+				Parts []json.RawMessage `json:"parts"`
+			} `json:"content"`
+		} `json:"message"`
+	}
+	var convs []Conversation
+	for _, item := range items {
+		var c struct {
+			Title       string          `json:"title"`
+			CreateTime  float64         `json:"create_time"`
+			CurrentNode string          `json:"current_node"`
+			Mapping     map[string]node `json:"mapping"`
+		}
+		if err := json.Unmarshal(item, &c); err != nil {
+			return nil, fmt.Errorf("parsing ChatGPT conversation: %w", err)
+		}
+
+		var branch []providers.Message
+		seen := make(map[string]bool)
+		for id := c.CurrentNode; id != "" && !seen[id]; id = c.Mapping[id].Parent {
+			seen[id] = true
+			m := c.Mapping[id].Message
+			if m == nil || (m.Author.Role != "user" && m.Author.Role != "assistant") {
+				continue
+			}
+			var text []string
+			for _, part := range m.Content.Parts {
+				var s string
+				if json.Unmarshal(part, &s) == nil && strings.TrimSpace(s) != "" {
+					text = append(text, s)
+				}
+			}
+			if len(text) > 0 {
+				branch = append(branch, providers.Message{Role: m.Author.Role, Content: strings.Join(text, "\n")})
+			}
+		}
+		for i, j := 0, len(branch)-1; i < j; i, j = i+1, j-1 {
+			branch[i], branch[j] = branch[j], branch[i]
+		}
+
+		conv := Conversation{Title: c.Title, Messages: joinRoles(branch)}
+		if c.CreateTime > 0 {
+			conv.Created = time.Unix(int64(c.CreateTime), 0)
+		}
+		convs = append(convs, conv)
+	}
+	return convs, nil
+}
+
+// parseClaude reads Claude conversations, whose messages are from the
+// "human" or the "assistant".
+func parseClaude(items []json.RawMessage) ([]Conversation, error) {
+	var convs []Conversation
+	for _, item := range items {
+		var c struct {
+			Name         string    `json:"name"`
+			CreatedAt    time.Time `json:"created_at"`
+			ChatMessages []struct {
+				Sender  string `json:"sender"`
+				Text    string `json:"text"`
+				Content []struct {
+					Type string `json:"type"`
+					Text string `json:"text"`
+				} `json:"content"`
+			} `json:"chat_messages"`
+		}
+		if err := json.Unmarshal(item, &c); err != nil {
+			return nil, fmt.Errorf("parsing Claude conversation: %w", err)
+		}
+
+		var msgs []providers.Message
+		for _, m := range c.ChatMessages {
+			role := "assistant"
+			if m.Sender == "human" {
+				role = "user"
+			}
+			text := m.Text
+			if len(m.Content) > 0 {
+				var parts []string
+				for _, block := range m.Content {
+					if block.Type == "text" && strings.TrimSpace(block.Text) != "" {
+						parts = append(parts, block.Text)
+					}
+				}
+				text = strings.Join(parts, "\n")
+			}
+			if strings.TrimSpace(text) != "" {
+				msgs = append(msgs, providers.Message{Role: role, Content: text})
+			}
+		}
+		convs = append(convs, Conversation{Title: c.Name, Created: c.CreatedAt, Messages: joinRoles(msgs)})
+	}
+	return convs, nil
+}
+
+// joinRoles joins consecutive text messages of the same role, which
+// providers may reject, into one.
+func joinRoles(msgs []providers.Message) []providers.Message {
+	plain := func(m providers.Message) bool {
+		return len(m.ToolCalls) == 0 && m.ToolCallID == "" && len(m.Images) == 0 && len(m.Files) == 0
+	}
+	var out []providers.Message
+	for _, m := range msgs {
+		if n := len(out); n > 0 && out[n-1].Role == m.Role && plain(out[n-1]) && plain(m) {
+			out[n-1].Content += "\n\n" + m.Content
+			continue
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+// withoutSystem drops system messages; the agent builds its own.
+func withoutSystem(msgs []providers.Message) []providers.Message {
+	out := make([]providers.Message, 0, len(msgs))
+	for _, m := range msgs {
+		if m.Role != "system" {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// FileMessage returns a user message that shares the text file name,
+// with contents data, with the model.
+func FileMessage(name string, data []byte) (providers.Message, error) {
+	if len(data) > maxImportFileBytes {
+		return providers.Message{}, fmt.Errorf("%s is larger than %d KB", name, maxImportFileBytes>>10)
+	}
+	if !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+		return providers.Message{}, fmt.Errorf("%s is not a text file", name)
+	}
+	return providers.Message{
+		Role:    "user",
+		Content: fmt.Sprintf("Contents of %s:\n\n%s", name, strings.TrimSpace(string(data))),
+	}, nil
+}
+
+// Carryover returns what a session seeded from s needs to know of it: its
+// summary, or without one its latest user and assistant messages.
+func Carryover(s Session) string {
+	name := s.Key
+	if s.Title != "" {
+		name = s.Title
+	}
+	if s.Summary != "" {
+		return fmt.Sprintf("From the earlier session %q: %s", name, s.Summary)
+	}
+
+	var lines []string
+	size := 0
+	for i := len(s.Messages) - 1; i >= 0 && size < carryoverChars; i-- {
+		m := s.Messages[i]
+		if (m.Role != "user" && m.Role != "assistant") || strings.TrimSpace(m.Content) == "" {
+			continue
+		}
+		line := m.Role + ": " + strings.TrimSpace(m.Content)
+		lines = append(lines, line)
+		size += len(line)
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i] // oldest first
+	}
+	return fmt.Sprintf("From the earlier session %q:\n%s", name, strings.Join(lines, "\n"))
+}
+
+// Import adds summary and messages to the session stored under key,
+// creating it if needed: messages after its own, summary after its
+// summary. Consecutive imported messages of the same role, such as
+// several files, are joined. The title is set if the session has none.
+func (sm *SessionManager) Import(key, title, summary string, messages []providers.Message) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.restoreLocked(key)
+
+	session, ok := sm.sessions[key]
+	if !ok {
+		session = &Session{Key: key, Messages: []providers.Message{}, Created: time.Now()}
+		sm.sessions[key] = session
+	}
+	session.Messages = append(session.Messages, joinRoles(messages)...)
+	if summary != "" {
+		if session.Summary != "" {
+			session.Summary += "\n\n"
+		}
+		session.Summary += summary
+	}
+	if session.Title == "" {
+		session.Title = title
+	}
+	session.Updated = time.Now()
+}
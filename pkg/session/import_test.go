@@ -0,0 +1,125 @@
+package session
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+func TestParseConversations_ChatGPT(t *testing.T) {
+	// The first reply was regenerated; current_node ends the shown branch.
+	export := `[{
+		"title": "Trip plan",
+		"create_time": 1700000000.5,
+		"current_node": "a2",
+		"mapping": {
+			"root": {"parent": null, "message": null},
+			"sys": {"parent": "root", "message": {"author": {"role": "system"}, "content": {"content_type": "text", "parts": [""]}}},
+			"u1": {"parent": "sys", "message": {"author": {"role": "user"}, "content": {"content_type": "text", "parts": ["Plan a trip to Oslo"]}}},
+			"a1": {"parent": "u1", "message": {"author": {"role": "assistant"}, "content": {"content_type": "text", "parts": ["Old reply"]}}},
+			"t1": {"parent": "u1", "message": {"author": {"role": "tool"}, "content": {"content_type": "text", "parts": ["search results"]}}},
+			"a2": {"parent": "t1", "message": {"author": {"role": "assistant"}, "content": {"content_type": "multimodal_text", "parts": [{"asset_pointer": "file-1"}, "Day 1: museums"]}}}
+		}
+	}, {"title": "Other", "mapping": {}, "current_node": ""}]`
+
+	convs, err := ParseConversations([]byte(export))
+	if err != nil {
+		t.Fatalf("ParseConversations() error = %v", err)
+	}
+	if len(convs) != 2 || convs[0].Title != "Trip plan" || convs[0].Created.Unix() != 1700000000 {
+		t.Fatalf("conversations = %+v", convs)
+	}
+	got := convs[0].Messages
+	if len(got) != 2 || got[0].Role != "user" || got[0].Content != "Plan a trip to Oslo" ||
+		got[1].Role != "assistant" || got[1].Content != "Day 1: museums" {
+		t.Errorf("messages = %+v", got)
+	}
+}
+
+func TestParseConversations_Claude(t *testing.T) {
+	export := `[{
+		"name": "Recipes",
+		"created_at": "2026-03-01T10:00:00Z",
+		"chat_messages": [
+			{"sender": "human", "text": "A soup recipe?", "content": [{"type": "text", "text": "A soup recipe?"}]},
+			{"sender": "human", "text": "Vegetarian, please."},
+			{"sender": "assistant", "text": "", "content": [{"type": "tool_use"}, {"type": "text", "text": "Try minestrone."}]}
+		]
+	}]`
+
+	convs, err := ParseConversations([]byte(export))
+	if err != nil {
+		t.Fatalf("ParseConversations() error = %v", err)
+	}
+	msgs := convs[0].Messages
+	if convs[0].Title != "Recipes" || len(msgs) != 2 {
+		t.Fatalf("conversations = %+v", convs)
+	}
+	if msgs[0].Content != "A soup recipe?\n\nVegetarian, please." || msgs[1].Content != "Try minestrone." {
+		t.Errorf("messages = %+v", msgs)
+	}
+}
+
+func TestParseConversations_SessionAndMessages(t *testing.T) {
+	convs, err := ParseConversations([]byte(`{"key": "agent:main:main", "title": "Notes", "messages": [{"role": "user", "content": "hi"}]}`))
+	if err != nil || len(convs) != 1 || convs[0].Title != "Notes" || convs[0].Messages[0].Content != "hi" {
+		t.Errorf("session = %+v, %v", convs, err)
+	}
+
+	convs, err = ParseConversations([]byte(`[{"role": "system", "content": "be brief"}, {"role": "user", "content": "hi"}]`))
+	if err != nil || len(convs) != 1 || len(convs[0].Messages) != 1 {
+		t.Errorf("messages = %+v, %v", convs, err)
+	}
+
+	if _, err := ParseConversations([]byte(`{"foo": 1}`)); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestFileMessage(t *testing.T) {
+	msg, err := FileMessage("notes.md", []byte("# Notes\n"))
+	if err != nil || msg.Role != "user" || msg.Content != "Contents of notes.md:\n\n# Notes" {
+		t.Errorf("FileMessage() = %+v, %v", msg, err)
+	}
+	if _, err := FileMessage("photo.jpg", []byte{0xff, 0xd8, 0x00}); err == nil {
+		t.Error("expected an error for a binary file")
+	}
+}
+
+func TestCarryover(t *testing.T) {
+	if got := Carryover(Session{Key: "k", Title: "Taxes", Summary: "Filed in March."}); got != `From the earlier session "Taxes": Filed in March.` {
+		t.Errorf("with summary = %q", got)
+	}
+
+	got := Carryover(Session{Key: "k", Messages: []providers.Message{
+		{Role: "user", Content: "first"},
+		{Role: "tool", Content: "ignored"},
+		{Role: "assistant", Content: "second"},
+	}})
+	if got != "From the earlier session \"k\":\nuser: first\nassistant: second" {
+		t.Errorf("without summary = %q", got)
+	}
+}
+
+func TestSessionManager_Import(t *testing.T) {
+	sm := NewSessionManager(t.TempDir())
+	sm.AddMessage("k", "user", "hello")
+	sm.SetSummary("k", "Earlier.")
+
+	sm.Import("k", "Imported", "From elsewhere.", []providers.Message{
+		{Role: "user", Content: "Contents of a.txt:\n\na"},
+		{Role: "user", Content: "Contents of b.txt:\n\nb"},
+	})
+	if err := sm.Save("k"); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	s, _ := NewSessionManager(sm.storage).Get("k")
+	if len(s.Messages) != 2 || !strings.Contains(s.Messages[1].Content, "b.txt") {
+		t.Errorf("messages = %+v", s.Messages)
+	}
+	if s.Summary != "Earlier.\n\nFrom elsewhere." || s.Title != "Imported" {
+		t.Errorf("summary = %q, title = %q", s.Summary, s.Title)
+	}
+}
@@ -2,37 +2,94 @@ package session
 
 import (
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/sipeed/picoclaw/pkg/atrest"
+	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/vfs"
 )
 
 type Session struct {
 	Key      string              `json:"key"`
 	Messages []providers.Message `json:"messages"`
 	Summary  string              `json:"summary,omitempty"`
+	Title    string              `json:"title,omitempty"`
+	Tags     []string            `json:"tags,omitempty"`
+	Feedback []Feedback          `json:"feedback,omitempty"`
 	Created  time.Time           `json:"created"`
 	Updated  time.Time           `json:"updated"`
+	// Generations records how the most recent replies were generated.
+	Generations []Generation `json:"generations,omitempty"`
+}
+
+// Feedback is a reaction of a user to one of the agent's replies. The
+// reply is identified by when it was sent and its beginning, as summaries
+// may have removed it from the history since.
+type Feedback struct {
+	RepliedAt time.Time `json:"replied_at"`
+	Reply     string    `json:"reply"`
+	Channel   string    `json:"channel"`
+	SenderID  string    `json:"sender_id"`
+	Reaction  string    `json:"reaction"`
+	Score     int       `json:"score"` // 1 positive, -1 negative, 0 neither
+	At        time.Time `json:"at"`
+}
+
+// maxGenerations is how many replies Generation records are kept for per
+// session.
+const maxGenerations = 100
+
+// Generation records the settings a reply was generated with, so that it
+// can be reproduced or reported. Seed is nil when the provider takes no
+// seed; ModelVersion and Fingerprint are empty when it does not report
+// them.
+type Generation struct {
+	RepliedAt    time.Time `json:"replied_at"`
+	Reply        string    `json:"reply"`
+	Model        string    `json:"model"`
+	ModelVersion string    `json:"model_version,omitempty"`
+	Fingerprint  string    `json:"system_fingerprint,omitempty"`
+	Seed         *int      `json:"seed,omitempty"`
+	Temperature  float64   `json:"temperature"`
+	MaxTokens    int       `json:"max_tokens"`
+	// Calls is the number of model requests made for the reply, one more
+	// than the rounds of tool calls.
+	Calls int `json:"calls"`
+	// For turns with a latency budget, ElapsedMs is how long the turn
+	// took, LatencyBudgetMs the budget and OverBudget whether the turn
+	// ran past it.
+	ElapsedMs       int64 `json:"elapsed_ms,omitempty"`
+	LatencyBudgetMs int64 `json:"latency_budget_ms,omitempty"`
+	OverBudget      bool  `json:"over_budget,omitempty"`
 }
 
 type SessionManager struct {
 	sessions map[string]*Session
 	mu       sync.RWMutex
 	storage  string
+
+	// evicted tracks sessions dropped from memory by Evict, with their last
+	// update time. They are reloaded from storage on next access.
+	evicted map[string]time.Time
 }
 
 func NewSessionManager(storage string) *SessionManager {
 	sm := &SessionManager{
 		sessions: make(map[string]*Session),
 		storage:  storage,
+		evicted:  make(map[string]time.Time),
 	}
 
 	if storage != "" {
-		os.MkdirAll(storage, 0755)
+		vfs.MkdirAll(storage, 0755)
+		atrest.Protect(storage)
 		sm.loadSessions()
 	}
 
@@ -42,6 +99,7 @@ func NewSessionManager(storage string) *SessionManager {
 func (sm *SessionManager) GetOrCreate(key string) *Session {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
+	sm.restoreLocked(key)
 
 	session, ok := sm.sessions[key]
 	if ok {
@@ -71,6 +129,7 @@ func (sm *SessionManager) AddMessage(sessionKey, role, content string) {
 func (sm *SessionManager) AddFullMessage(sessionKey string, msg providers.Message) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
+	sm.restoreLocked(sessionKey)
 
 	session, ok := sm.sessions[sessionKey]
 	if !ok {
@@ -87,6 +146,8 @@ func (sm *SessionManager) AddFullMessage(sessionKey string, msg providers.Messag
 }
 
 func (sm *SessionManager) GetHistory(key string) []providers.Message {
+	sm.restore(key)
+
 	sm.mu.RLock()
 	defer sm.mu.RUnlock()
 
@@ -100,7 +161,26 @@ func (sm *SessionManager) GetHistory(key string) []providers.Message {
 	return history
 }
 
+// Get returns a copy of the session stored under key.
+func (sm *SessionManager) Get(key string) (Session, bool) {
+	sm.restore(key)
+
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	session, ok := sm.sessions[key]
+	if !ok {
+		return Session{}, false
+	}
+	snapshot := *session
+	snapshot.Messages = make([]providers.Message, len(session.Messages))
+	copy(snapshot.Messages, session.Messages)
+	return snapshot, true
+}
+
 func (sm *SessionManager) GetSummary(key string) string {
+	sm.restore(key)
+
 	sm.mu.RLock()
 	defer sm.mu.RUnlock()
 
@@ -114,6 +194,7 @@ func (sm *SessionManager) GetSummary(key string) string {
 func (sm *SessionManager) SetSummary(key string, summary string) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
+	sm.restoreLocked(key)
 
 	session, ok := sm.sessions[key]
 	if ok {
@@ -122,9 +203,76 @@ func (sm *SessionManager) SetSummary(key string, summary string) {
 	}
 }
 
+// SetTitle sets the title and tags of a session.
+func (sm *SessionManager) SetTitle(key, title string, tags []string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.restoreLocked(key)
+
+	session, ok := sm.sessions[key]
+	if ok {
+		session.Title = title
+		session.Tags = append([]string(nil), tags...)
+		session.Updated = time.Now()
+	}
+}
+
+// AddFeedback records a reaction to a reply of the session.
+func (sm *SessionManager) AddFeedback(key string, fb Feedback) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.restoreLocked(key)
+
+	session, ok := sm.sessions[key]
+	if ok {
+		session.Feedback = append(session.Feedback, fb)
+	}
+}
+
+// AddGeneration records how a reply of the session was generated, keeping
+// the records of the last maxGenerations replies.
+func (sm *SessionManager) AddGeneration(key string, g Generation) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.restoreLocked(key)
+
+	session, ok := sm.sessions[key]
+	if !ok {
+		return
+	}
+	gens := session.Generations
+	if len(gens) >= maxGenerations {
+		// Copy rather than shift in place, as Save may hold the old slice.
+		gens = append([]Generation(nil), gens[len(gens)-maxGenerations+1:]...)
+	}
+	session.Generations = append(gens, g)
+}
+
+// RemoveFeedback removes a reaction recorded by AddFeedback, matched by
+// reply, sender and reaction. It reports whether one was found.
+func (sm *SessionManager) RemoveFeedback(key string, fb Feedback) bool {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.restoreLocked(key)
+
+	session, ok := sm.sessions[key]
+	if !ok {
+		return false
+	}
+	for i, f := range session.Feedback {
+		if f.RepliedAt.Equal(fb.RepliedAt) && f.SenderID == fb.SenderID && f.Reaction == fb.Reaction {
+			// Copy rather than shift in place, as Save may hold the old slice.
+			session.Feedback = append(append([]Feedback(nil), session.Feedback[:i]...), session.Feedback[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
 func (sm *SessionManager) TruncateHistory(key string, keepLast int) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
+	sm.restoreLocked(key)
 
 	session, ok := sm.sessions[key]
 	if !ok {
@@ -178,11 +326,15 @@ func (sm *SessionManager) Save(key string) error {
 	}
 
 	snapshot := Session{
-		Key:     stored.Key,
-		Summary: stored.Summary,
-		Created: stored.Created,
-		Updated: stored.Updated,
+		Key:      stored.Key,
+		Summary:  stored.Summary,
+		Title:    stored.Title,
+		Tags:     stored.Tags,
+		Feedback: stored.Feedback,
+		Created:  stored.Created,
+		Updated:  stored.Updated,
 	}
+	snapshot.Generations = stored.Generations
 	if len(stored.Messages) > 0 {
 		snapshot.Messages = make([]providers.Message, len(stored.Messages))
 		copy(snapshot.Messages, stored.Messages)
@@ -197,44 +349,11 @@ func (sm *SessionManager) Save(key string) error {
 	}
 
 	sessionPath := filepath.Join(sm.storage, filename+".json")
-	tmpFile, err := os.CreateTemp(sm.storage, "session-*.tmp")
-	if err != nil {
-		return err
-	}
-
-	tmpPath := tmpFile.Name()
-	cleanup := true
-	defer func() {
-		if cleanup {
-			_ = os.Remove(tmpPath)
-		}
-	}()
-
-	if _, err := tmpFile.Write(data); err != nil {
-		_ = tmpFile.Close()
-		return err
-	}
-	if err := tmpFile.Chmod(0644); err != nil {
-		_ = tmpFile.Close()
-		return err
-	}
-	if err := tmpFile.Sync(); err != nil {
-		_ = tmpFile.Close()
-		return err
-	}
-	if err := tmpFile.Close(); err != nil {
-		return err
-	}
-
-	if err := os.Rename(tmpPath, sessionPath); err != nil {
-		return err
-	}
-	cleanup = false
-	return nil
+	return vfs.WriteFile(sessionPath, data, 0644)
 }
 
 func (sm *SessionManager) loadSessions() error {
-	files, err := os.ReadDir(sm.storage)
+	files, err := vfs.ReadDir(sm.storage)
 	if err != nil {
 		return err
 	}
@@ -249,8 +368,13 @@ func (sm *SessionManager) loadSessions() error {
 		}
 
 		sessionPath := filepath.Join(sm.storage, file.Name())
-		data, err := os.ReadFile(sessionPath)
+		data, err := vfs.ReadFile(sessionPath)
 		if err != nil {
+			if errors.Is(err, atrest.ErrLocked) {
+				logger.WarnCF("session", "Skipping encrypted session, no key configured", map[string]interface{}{
+					"file": file.Name(),
+				})
+			}
 			continue
 		}
 
@@ -269,6 +393,7 @@ func (sm *SessionManager) loadSessions() error {
 func (sm *SessionManager) SetHistory(key string, history []providers.Message) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
+	sm.restoreLocked(key)
 
 	session, ok := sm.sessions[key]
 	if ok {
@@ -280,3 +405,145 @@ func (sm *SessionManager) SetHistory(key string, history []providers.Message) {
 		session.Updated = time.Now()
 	}
 }
+
+// UpdatedSince returns copies of all sessions updated at or after since,
+// most recently updated first.
+func (sm *SessionManager) UpdatedSince(since time.Time) []Session {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	var result []Session
+	for _, session := range sm.sessions {
+		if session.Updated.Before(since) {
+			continue
+		}
+		snapshot := *session
+		snapshot.Messages = make([]providers.Message, len(session.Messages))
+		copy(snapshot.Messages, session.Messages)
+		result = append(result, snapshot)
+	}
+
+	for key, updated := range sm.evicted {
+		if updated.Before(since) {
+			continue
+		}
+		if session, err := sm.readSession(key); err == nil {
+			result = append(result, *session)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Updated.After(result[j].Updated)
+	})
+	return result
+}
+
+// Search returns the sessions whose key, title or tags contain query,
+// ignoring case, most recently updated first.
+func (sm *SessionManager) Search(query string) []Session {
+	query = strings.ToLower(strings.TrimSpace(query))
+	var result []Session
+	for _, s := range sm.UpdatedSince(time.Time{}) {
+		if s.matches(query) {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// matches reports whether the session's key, title or one of its tags
+// contains query, which must be lower case.
+func (s Session) matches(query string) bool {
+	if strings.Contains(strings.ToLower(s.Key), query) || strings.Contains(strings.ToLower(s.Title), query) {
+		return true
+	}
+	for _, tag := range s.Tags {
+		if strings.Contains(strings.ToLower(tag), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// Evict saves and drops sessions idle for longer than idle from memory, to
+// relieve memory pressure. Evicted sessions stay on disk and are reloaded
+// transparently on next access. Nothing is evicted without persistent
+// storage. It returns the number of sessions evicted.
+func (sm *SessionManager) Evict(idle time.Duration) int {
+	if sm.storage == "" {
+		return 0
+	}
+
+	cutoff := time.Now().Add(-idle)
+	sm.mu.RLock()
+	var candidates []string
+	for key, session := range sm.sessions {
+		if session.Updated.Before(cutoff) {
+			candidates = append(candidates, key)
+		}
+	}
+	sm.mu.RUnlock()
+
+	evicted := 0
+	for _, key := range candidates {
+		if err := sm.Save(key); err != nil {
+			continue
+		}
+		sm.mu.Lock()
+		// Skip sessions touched while saving.
+		if session, ok := sm.sessions[key]; ok && session.Updated.Before(cutoff) {
+			sm.evicted[key] = session.Updated
+			delete(sm.sessions, key)
+			evicted++
+		}
+		sm.mu.Unlock()
+	}
+	return evicted
+}
+
+// Loaded reports whether key is held in memory, without reloading it if
+// it was evicted.
+func (sm *SessionManager) Loaded(key string) bool {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	_, ok := sm.sessions[key]
+	return ok
+}
+
+// restore reloads key from storage if it was evicted.
+func (sm *SessionManager) restore(key string) {
+	sm.mu.RLock()
+	_, evicted := sm.evicted[key]
+	sm.mu.RUnlock()
+	if !evicted {
+		return
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.restoreLocked(key)
+}
+
+// restoreLocked reloads key from storage if it was evicted. sm.mu must be
+// held for writing.
+func (sm *SessionManager) restoreLocked(key string) {
+	if _, evicted := sm.evicted[key]; !evicted {
+		return
+	}
+	delete(sm.evicted, key)
+	if session, err := sm.readSession(key); err == nil {
+		sm.sessions[key] = session
+	}
+}
+
+func (sm *SessionManager) readSession(key string) (*Session, error) {
+	data, err := vfs.ReadFile(filepath.Join(sm.storage, sanitizeFilename(key)+".json"))
+	if err != nil {
+		return nil, err
+	}
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
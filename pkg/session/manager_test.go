@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestSanitizeFilename(t *testing.T) {
@@ -72,3 +73,115 @@ func TestSave_RejectsPathTraversal(t *testing.T) {
 		}
 	}
 }
+
+func TestEvict_ReloadsOnAccess(t *testing.T) {
+	sm := NewSessionManager(t.TempDir())
+
+	key := "telegram:1"
+	sm.AddMessage(key, "user", "hello")
+	sm.SetSummary(key, "greeting")
+
+	if n := sm.Evict(0); n != 1 {
+		t.Fatalf("Evict() = %d, want 1", n)
+	}
+	if len(sm.sessions) != 0 {
+		t.Fatalf("expected no sessions in memory after eviction, got %d", len(sm.sessions))
+	}
+
+	if got := sm.UpdatedSince(time.Time{}); len(got) != 1 || got[0].Key != key {
+		t.Errorf("UpdatedSince() should include evicted sessions, got %+v", got)
+	}
+
+	if got := sm.GetSummary(key); got != "greeting" {
+		t.Errorf("GetSummary() after eviction = %q", got)
+	}
+	sm.AddMessage(key, "assistant", "hi")
+	if history := sm.GetHistory(key); len(history) != 2 {
+		t.Errorf("expected 2 messages after reload, got %d", len(history))
+	}
+}
+
+func TestEvict_KeepsActiveAndNonPersistentSessions(t *testing.T) {
+	sm := NewSessionManager(t.TempDir())
+	sm.AddMessage("active", "user", "hello")
+	if n := sm.Evict(time.Hour); n != 0 {
+		t.Errorf("Evict() evicted %d recently active sessions", n)
+	}
+
+	mem := NewSessionManager("")
+	mem.AddMessage("k", "user", "hello")
+	if n := mem.Evict(0); n != 0 {
+		t.Errorf("Evict() without storage = %d, want 0", n)
+	}
+}
+
+func TestSetTitle_PersistsAndIsSearchable(t *testing.T) {
+	dir := t.TempDir()
+	sm := NewSessionManager(dir)
+	sm.AddMessage("telegram:1", "user", "plan a trip")
+	sm.AddMessage("telegram:2", "user", "fix the heater")
+	sm.SetTitle("telegram:1", "Weekend in Lisbon", []string{"travel", "portugal"})
+	if err := sm.Save("telegram:1"); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := NewSessionManager(dir)
+	s, ok := reloaded.Get("telegram:1")
+	if !ok || s.Title != "Weekend in Lisbon" || len(s.Tags) != 2 {
+		t.Fatalf("reloaded session = %+v", s)
+	}
+
+	for query, want := range map[string]int{"lisbon": 1, "TRAVEL": 1, "telegram": 2, "cooking": 0} {
+		if got := sm.Search(query); len(got) != want {
+			t.Errorf("Search(%q) returned %d sessions, want %d", query, len(got), want)
+		}
+	}
+}
+
+func TestFeedback_AddRemovePersists(t *testing.T) {
+	dir := t.TempDir()
+	sm := NewSessionManager(dir)
+	sm.AddMessage("k", "user", "hello")
+
+	repliedAt := time.Now()
+	up := Feedback{RepliedAt: repliedAt, Reply: "hi", SenderID: "alice", Reaction: "👍", Score: 1}
+	down := Feedback{RepliedAt: repliedAt, Reply: "hi", SenderID: "bob", Reaction: "👎", Score: -1}
+	sm.AddFeedback("k", up)
+	sm.AddFeedback("k", down)
+	if sm.RemoveFeedback("k", Feedback{RepliedAt: repliedAt, SenderID: "bob", Reaction: "👍"}) {
+		t.Error("removed feedback that was never given")
+	}
+	if !sm.RemoveFeedback("k", down) {
+		t.Error("RemoveFeedback() = false for recorded feedback")
+	}
+	if err := sm.Save("k"); err != nil {
+		t.Fatal(err)
+	}
+
+	s, ok := NewSessionManager(dir).Get("k")
+	if !ok || len(s.Feedback) != 1 || s.Feedback[0].SenderID != "alice" || s.Feedback[0].Score != 1 {
+		t.Fatalf("reloaded feedback = %+v", s.Feedback)
+	}
+}
+
+func TestAddGeneration_KeepsRecentAndPersists(t *testing.T) {
+	dir := t.TempDir()
+	sm := NewSessionManager(dir)
+	sm.AddMessage("k", "user", "hello")
+
+	seed := 7
+	for i := 0; i < maxGenerations+5; i++ {
+		sm.AddGeneration("k", Generation{Model: "m", Seed: &seed, Calls: i})
+	}
+	if err := sm.Save("k"); err != nil {
+		t.Fatal(err)
+	}
+
+	s, _ := NewSessionManager(dir).Get("k")
+	if len(s.Generations) != maxGenerations {
+		t.Fatalf("kept %d generations, want %d", len(s.Generations), maxGenerations)
+	}
+	if first := s.Generations[0]; first.Calls != 5 || first.Seed == nil || *first.Seed != 7 {
+		t.Errorf("oldest kept generation = %+v, want the 6th recorded", first)
+	}
+}
@@ -0,0 +1,153 @@
+package session
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// Redacted replaces the text removed by Redact.
+const Redacted = "[redacted]"
+
+// Redaction reports what Redact changed in a session.
+type Redaction struct {
+	// Removed is the number of messages removed.
+	Removed int
+	// Replaced is the number of matches of the pattern replaced.
+	Replaced int
+}
+
+// Changed reports whether anything was redacted.
+func (r Redaction) Changed() bool {
+	return r.Removed > 0 || r.Replaced > 0
+}
+
+// Redact removes the last turns of the session stored under key, each
+// starting at a user message, along with the feedback and generation
+// records of their replies. If pattern is not nil, its matches are then
+// replaced with Redacted in the rest of the session: messages, tool calls,
+// summary, title, tags, feedback and generation records. The session is
+// saved if anything changed. ok is false if there is no such session.
+func (sm *SessionManager) Redact(key string, turns int, pattern *regexp.Regexp) (r Redaction, ok bool, err error) {
+	sm.mu.Lock()
+	sm.restoreLocked(key)
+	session, ok := sm.sessions[key]
+	if !ok {
+		sm.mu.Unlock()
+		return r, false, nil
+	}
+
+	if turns > 0 {
+		cut := len(session.Messages)
+		for cut > 0 && turns > 0 {
+			cut--
+			if session.Messages[cut].Role == "user" {
+				turns--
+			}
+		}
+		removed := session.Messages[cut:]
+		r.Removed = len(removed)
+		// Copy rather than truncate in place, as Save may hold the old slice.
+		session.Messages = append([]providers.Message{}, session.Messages[:cut]...)
+		session.Feedback = dropReplies(session.Feedback, removed, func(f Feedback) string { return f.Reply })
+		session.Generations = dropReplies(session.Generations, removed, func(g Generation) string { return g.Reply })
+	}
+
+	if pattern != nil {
+		replace := func(s string) string {
+			n := len(pattern.FindAllStringIndex(s, -1))
+			if n == 0 {
+				return s
+			}
+			r.Replaced += n
+			return pattern.ReplaceAllLiteralString(s, Redacted)
+		}
+		messages := make([]providers.Message, len(session.Messages))
+		for i, m := range session.Messages {
+			m.Content = replace(m.Content)
+			m.ToolCalls = redactToolCalls(m.ToolCalls, replace)
+			messages[i] = m
+		}
+		session.Messages = messages
+		session.Summary = replace(session.Summary)
+		session.Title = replace(session.Title)
+		var tags []string
+		for _, tag := range session.Tags {
+			// A tag is a single word, so a tag with a match goes entirely.
+			if replace(tag) == tag {
+				tags = append(tags, tag)
+			}
+		}
+		session.Tags = tags
+		feedback := make([]Feedback, len(session.Feedback))
+		for i, f := range session.Feedback {
+			f.Reply = replace(f.Reply)
+			feedback[i] = f
+		}
+		session.Feedback = feedback
+		gens := make([]Generation, len(session.Generations))
+		for i, g := range session.Generations {
+			g.Reply = replace(g.Reply)
+			gens[i] = g
+		}
+		session.Generations = gens
+	}
+
+	if !r.Changed() {
+		sm.mu.Unlock()
+		return r, true, nil
+	}
+	session.Updated = time.Now()
+	sm.mu.Unlock()
+	return r, true, sm.Save(key)
+}
+
+// dropReplies returns the records of records whose reply, the beginning
+// of a reply as returned by reply, is not one of the assistant messages in
+// removed.
+func dropReplies[T any](records []T, removed []providers.Message, reply func(T) string) []T {
+	var kept []T
+	for _, rec := range records {
+		text := reply(rec)
+		gone := false
+		for _, m := range removed {
+			if m.Role == "assistant" && text != "" && strings.HasPrefix(m.Content, text) {
+				gone = true
+				break
+			}
+		}
+		if !gone {
+			kept = append(kept, rec)
+		}
+	}
+	return kept
+}
+
+// redactToolCalls returns calls with replace applied to their arguments.
+func redactToolCalls(calls []providers.ToolCall, replace func(string) string) []providers.ToolCall {
+	if len(calls) == 0 {
+		return calls
+	}
+	out := make([]providers.ToolCall, len(calls))
+	for i, tc := range calls {
+		if tc.Function != nil {
+			fn := *tc.Function
+			fn.Arguments = replace(fn.Arguments)
+			tc.Function = &fn
+		}
+		if len(tc.Arguments) > 0 {
+			args := make(map[string]interface{}, len(tc.Arguments))
+			for k, v := range tc.Arguments {
+				if s, ok := v.(string); ok {
+					v = replace(s)
+				}
+				args[k] = v
+			}
+			tc.Arguments = args
+		}
+		out[i] = tc
+	}
+	return out
+}
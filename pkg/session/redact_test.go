@@ -0,0 +1,58 @@
+package session
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+func TestRedact(t *testing.T) {
+	sm := NewSessionManager(t.TempDir())
+	key := "telegram:1"
+	sm.GetOrCreate(key)
+	sm.AddMessage(key, "user", "my card is 4111-1111")
+	sm.AddFullMessage(key, providers.Message{Role: "assistant", ToolCalls: []providers.ToolCall{{
+		ID:       "1",
+		Function: &providers.FunctionCall{Name: "note", Arguments: `{"text":"card 4111-1111"}`},
+	}}})
+	sm.AddMessage(key, "assistant", "Noted.")
+	sm.AddMessage(key, "user", "and my address is Main St 1")
+	sm.AddMessage(key, "assistant", "Got it, Main St 1.")
+	sm.SetSummary(key, "The user shared card 4111-1111.")
+	sm.SetTitle(key, "Card 4111-1111", []string{"4111-1111", "payments"})
+	sm.AddFeedback(key, Feedback{Reply: "Got it", Score: 1})
+	sm.AddGeneration(key, Generation{Reply: "Noted."})
+	sm.AddGeneration(key, Generation{Reply: "Got it, Main"})
+
+	r, ok, err := sm.Redact(key, 1, regexp.MustCompile(`4111-\d+`))
+	if !ok || err != nil {
+		t.Fatalf("Redact() ok = %v, err = %v", ok, err)
+	}
+	if r.Removed != 2 || r.Replaced != 5 {
+		t.Errorf("Redact() = %+v, want 2 messages removed and 5 matches replaced", r)
+	}
+
+	reloaded := NewSessionManager(sm.storage)
+	s, _ := reloaded.Get(key)
+	if len(s.Messages) != 3 {
+		t.Fatalf("%d messages left, want the last turn removed", len(s.Messages))
+	}
+	if s.Messages[0].Content != "my card is "+Redacted || !strings.Contains(s.Messages[1].ToolCalls[0].Function.Arguments, Redacted) {
+		t.Errorf("messages not redacted: %+v", s.Messages[:2])
+	}
+	if strings.Contains(s.Summary+s.Title, "4111") || len(s.Tags) != 1 || s.Tags[0] != "payments" {
+		t.Errorf("summary %q, title %q, tags %v still hold the match", s.Summary, s.Title, s.Tags)
+	}
+	if len(s.Feedback) != 0 || len(s.Generations) != 1 || s.Generations[0].Reply != "Noted." {
+		t.Errorf("feedback %+v, generations %+v; want the removed reply's records dropped", s.Feedback, s.Generations)
+	}
+	if got := reloaded.Search("4111"); len(got) != 0 {
+		t.Errorf("search still finds the redacted title: %+v", got)
+	}
+
+	if r, ok, _ := sm.Redact("missing", 1, nil); ok || r.Changed() {
+		t.Errorf("Redact(missing) = %+v, %v", r, ok)
+	}
+}
@@ -0,0 +1,147 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package shutdown reports what a run of the gateway did when it stops,
+// so that restarts of unattended devices leave a record.
+package shutdown
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/usage"
+)
+
+// Report summarizes a run from Started to Stopped.
+type Report struct {
+	Started time.Time
+	Stopped time.Time
+	Turns   int64
+	// Usage is the token usage and cost of the run's requests.
+	Usage usage.Counts
+	// Errors counts the errors logged, by component.
+	Errors map[string]int
+	// Pending counts the work left unfinished, by queue.
+	Pending map[string]int
+}
+
+// Uptime returns how long the run lasted.
+func (r Report) Uptime() time.Duration {
+	return r.Stopped.Sub(r.Started)
+}
+
+// Fields returns the report as log fields.
+func (r Report) Fields() map[string]interface{} {
+	return map[string]interface{}{
+		"started":  r.Started.UTC().Format(time.RFC3339),
+		"uptime":   r.Uptime().Round(time.Second).String(),
+		"turns":    r.Turns,
+		"requests": r.Usage.Requests,
+		"tokens":   r.Usage.TotalTokens,
+		"cost":     fmt.Sprintf("%.4f", r.Usage.Cost),
+		"errors":   formatCounts(r.Errors),
+		"pending":  formatCounts(r.Pending),
+	}
+}
+
+// String returns the report as a message.
+func (r Report) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "PicoClaw is shutting down after %s.\n", r.Uptime().Round(time.Second))
+	fmt.Fprintf(&sb, "Turns: %d\n", r.Turns)
+	fmt.Fprintf(&sb, "Requests: %d, tokens: %d, cost: $%.4f\n", r.Usage.Requests, r.Usage.TotalTokens, r.Usage.Cost)
+	fmt.Fprintf(&sb, "Errors: %s\n", formatCounts(r.Errors))
+	fmt.Fprintf(&sb, "Unfinished: %s", formatCounts(r.Pending))
+	return sb.String()
+}
+
+// Recorder collects what the report needs while the gateway runs.
+type Recorder struct {
+	started time.Time
+	tracker *usage.Tracker
+	base    usage.Counts
+
+	mu         sync.Mutex
+	errors     map[string]int
+	removeHook func()
+}
+
+// NewRecorder starts counting logged errors. tracker, which may be nil,
+// is the usage tracker whose growth from now on is the run's usage.
+func NewRecorder(tracker *usage.Tracker) *Recorder {
+	r := &Recorder{started: time.Now(), tracker: tracker, errors: map[string]int{}}
+	if tracker != nil {
+		r.base = tracker.Report().Total
+	}
+	r.removeHook = logger.AddHook(func(level logger.LogLevel, entry logger.LogEntry) {
+		if level < logger.ERROR {
+			return
+		}
+		comp := entry.Component
+		if comp == "" {
+			comp = "general"
+		}
+		r.mu.Lock()
+		r.errors[comp]++
+		r.mu.Unlock()
+	})
+	return r
+}
+
+// Report stops counting errors and returns the report of the run, with
+// turns answered and the work left in pending.
+func (r *Recorder) Report(turns int64, pending map[string]int) Report {
+	r.removeHook()
+	report := Report{
+		Started: r.started,
+		Stopped: time.Now(),
+		Turns:   turns,
+		Errors:  map[string]int{},
+		Pending: map[string]int{},
+	}
+	if r.tracker != nil {
+		now := r.tracker.Report().Total
+		report.Usage = usage.Counts{
+			Requests:         now.Requests - r.base.Requests,
+			PromptTokens:     now.PromptTokens - r.base.PromptTokens,
+			CompletionTokens: now.CompletionTokens - r.base.CompletionTokens,
+			CachedTokens:     now.CachedTokens - r.base.CachedTokens,
+			TotalTokens:      now.TotalTokens - r.base.TotalTokens,
+			Cost:             now.Cost - r.base.Cost,
+		}
+	}
+	r.mu.Lock()
+	for k, v := range r.errors {
+		report.Errors[k] = v
+	}
+	r.mu.Unlock()
+	for k, v := range pending {
+		if v > 0 {
+			report.Pending[k] = v
+		}
+	}
+	return report
+}
+
+// formatCounts lists counts as "name=n" by name, or "none".
+func formatCounts(counts map[string]int) string {
+	if len(counts) == 0 {
+		return "none"
+	}
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s=%d", name, counts[name])
+	}
+	return strings.Join(parts, ", ")
+}
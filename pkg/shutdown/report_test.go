@@ -0,0 +1,49 @@
+package shutdown
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers/protocoltypes"
+	"github.com/sipeed/picoclaw/pkg/usage"
+)
+
+func TestRecorder(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	tracker := usage.NewTracker(filepath.Join(t.TempDir(), "usage.json"), nil)
+	tracker.Record("s", "m", protocoltypes.UsageInfo{PromptTokens: 100, CompletionTokens: 50})
+
+	r := NewRecorder(tracker)
+	tracker.Record("s", "m", protocoltypes.UsageInfo{PromptTokens: 10, CompletionTokens: 5})
+	logger.ErrorCF("tool", "failed", nil)
+	logger.ErrorCF("tool", "failed", nil)
+	logger.ErrorCF("agent", "failed", nil)
+	logger.WarnCF("agent", "only a warning", nil)
+
+	report := r.Report(3, map[string]int{"outbound_messages": 2, "background_tasks": 0})
+	logger.ErrorCF("tool", "after the report", nil)
+
+	if report.Turns != 3 || report.Usage.Requests != 1 || report.Usage.TotalTokens != 15 {
+		t.Errorf("turns = %d, usage = %+v; want only the usage since the recorder started", report.Turns, report.Usage)
+	}
+	if report.Errors["tool"] != 2 || report.Errors["agent"] != 1 || len(report.Errors) != 2 {
+		t.Errorf("errors = %v", report.Errors)
+	}
+	if len(report.Pending) != 1 {
+		t.Errorf("pending = %v, want only non-empty queues", report.Pending)
+	}
+
+	text := report.String()
+	for _, want := range []string{"Turns: 3", "tokens: 15", "Errors: agent=1, tool=2", "Unfinished: outbound_messages=2"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("report lacks %q:\n%s", want, text)
+		}
+	}
+}
@@ -5,12 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
-	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
 
 	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/vfs"
 )
 
 var namePattern = regexp.MustCompile(`^[a-zA-Z0-9]+(-[a-zA-Z0-9]+)*$`)
@@ -73,11 +73,11 @@ func (sl *SkillsLoader) ListSkills() []SkillInfo {
 	skills := make([]SkillInfo, 0)
 
 	if sl.workspaceSkills != "" {
-		if dirs, err := os.ReadDir(sl.workspaceSkills); err == nil {
+		if dirs, err := vfs.ReadDir(sl.workspaceSkills); err == nil {
 			for _, dir := range dirs {
 				if dir.IsDir() {
 					skillFile := filepath.Join(sl.workspaceSkills, dir.Name(), "SKILL.md")
-					if _, err := os.Stat(skillFile); err == nil {
+					if _, err := vfs.Stat(skillFile); err == nil {
 						info := SkillInfo{
 							Name:   dir.Name(),
 							Path:   skillFile,
@@ -101,11 +101,11 @@ func (sl *SkillsLoader) ListSkills() []SkillInfo {
 
 	// 全局 skills (~/.picoclaw/skills) - 被 workspace skills 覆盖
 	if sl.globalSkills != "" {
-		if dirs, err := os.ReadDir(sl.globalSkills); err == nil {
+		if dirs, err := vfs.ReadDir(sl.globalSkills); err == nil {
 			for _, dir := range dirs {
 				if dir.IsDir() {
 					skillFile := filepath.Join(sl.globalSkills, dir.Name(), "SKILL.md")
-					if _, err := os.Stat(skillFile); err == nil {
+					if _, err := vfs.Stat(skillFile); err == nil {
 						// 检查是否已被 workspace skills 覆盖
 						exists := false
 						for _, s := range skills {
@@ -140,11 +140,11 @@ func (sl *SkillsLoader) ListSkills() []SkillInfo {
 	}
 
 	if sl.builtinSkills != "" {
-		if dirs, err := os.ReadDir(sl.builtinSkills); err == nil {
+		if dirs, err := vfs.ReadDir(sl.builtinSkills); err == nil {
 			for _, dir := range dirs {
 				if dir.IsDir() {
 					skillFile := filepath.Join(sl.builtinSkills, dir.Name(), "SKILL.md")
-					if _, err := os.Stat(skillFile); err == nil {
+					if _, err := vfs.Stat(skillFile); err == nil {
 						// 检查是否已被 workspace 或 global skills 覆盖
 						exists := false
 						for _, s := range skills {
@@ -185,7 +185,7 @@ func (sl *SkillsLoader) LoadSkill(name string) (string, bool) {
 	// 1. 优先从 workspace skills 加载（项目级别）
 	if sl.workspaceSkills != "" {
 		skillFile := filepath.Join(sl.workspaceSkills, name, "SKILL.md")
-		if content, err := os.ReadFile(skillFile); err == nil {
+		if content, err := vfs.ReadFile(skillFile); err == nil {
 			return sl.stripFrontmatter(string(content)), true
 		}
 	}
@@ -193,7 +193,7 @@ func (sl *SkillsLoader) LoadSkill(name string) (string, bool) {
 	// 2. 其次从全局 skills 加载 (~/.picoclaw/skills)
 	if sl.globalSkills != "" {
 		skillFile := filepath.Join(sl.globalSkills, name, "SKILL.md")
-		if content, err := os.ReadFile(skillFile); err == nil {
+		if content, err := vfs.ReadFile(skillFile); err == nil {
 			return sl.stripFrontmatter(string(content)), true
 		}
 	}
@@ -201,7 +201,7 @@ func (sl *SkillsLoader) LoadSkill(name string) (string, bool) {
 	// 3. 最后从内置 skills 加载
 	if sl.builtinSkills != "" {
 		skillFile := filepath.Join(sl.builtinSkills, name, "SKILL.md")
-		if content, err := os.ReadFile(skillFile); err == nil {
+		if content, err := vfs.ReadFile(skillFile); err == nil {
 			return sl.stripFrontmatter(string(content)), true
 		}
 	}
@@ -251,7 +251,7 @@ func (sl *SkillsLoader) BuildSkillsSummary() string {
 }
 
 func (sl *SkillsLoader) getSkillMetadata(skillPath string) *SkillMetadata {
-	content, err := os.ReadFile(skillPath)
+	content, err := vfs.ReadFile(skillPath)
 	if err != nil {
 		logger.WarnCF("skills", "Failed to read skill metadata",
 			map[string]interface{}{
@@ -139,6 +139,14 @@ func (sc *SearchCache) Len() int {
 	return len(sc.entries)
 }
 
+// Clear drops all cached entries.
+func (sc *SearchCache) Clear() {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.entries = make(map[string]*cacheEntry)
+	sc.order = sc.order[:0]
+}
+
 // --- internal ---
 
 func (sc *SearchCache) evictExpiredLocked() {
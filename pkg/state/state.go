@@ -2,12 +2,15 @@ package state
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
-	"os"
 	"path/filepath"
 	"sync"
 	"time"
+
+	"github.com/sipeed/picoclaw/pkg/vfs"
 )
 
 // State represents the persistent state for a workspace.
@@ -38,7 +41,7 @@ func NewManager(workspace string) *Manager {
 	oldStateFile := filepath.Join(workspace, "state.json")
 
 	// Create state directory if it doesn't exist
-	os.MkdirAll(stateDir, 0755)
+	vfs.MkdirAll(stateDir, 0755)
 
 	sm := &Manager{
 		workspace: workspace,
@@ -47,9 +50,9 @@ func NewManager(workspace string) *Manager {
 	}
 
 	// Try to load from new location first
-	if _, err := os.Stat(stateFile); os.IsNotExist(err) {
+	if _, err := vfs.Stat(stateFile); errors.Is(err, fs.ErrNotExist) {
 		// New file doesn't exist, try migrating from old location
-		if data, err := os.ReadFile(oldStateFile); err == nil {
+		if data, err := vfs.ReadFile(oldStateFile); err == nil {
 			if err := json.Unmarshal(data, sm.state); err == nil {
 				// Migrate to new location
 				sm.saveAtomic()
@@ -121,33 +124,20 @@ func (sm *Manager) GetTimestamp() time.Time {
 	return sm.state.Timestamp
 }
 
-// saveAtomic performs an atomic save using temp file + rename.
-// This ensures that the state file is never corrupted:
-// 1. Write to a temp file
-// 2. Rename temp file to target (atomic on POSIX systems)
-// 3. If rename fails, cleanup the temp file
+// saveAtomic saves the state with vfs.WriteFile, which on the local disk
+// writes a temp file and renames it over the target, so the state file is
+// never corrupted. On a mounted backend the file is replaced as a whole.
 //
 // Must be called with the lock held.
 func (sm *Manager) saveAtomic() error {
-	// Create temp file in the same directory as the target
-	tempFile := sm.stateFile + ".tmp"
-
 	// Marshal state to JSON
 	data, err := json.MarshalIndent(sm.state, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal state: %w", err)
 	}
 
-	// Write to temp file
-	if err := os.WriteFile(tempFile, data, 0644); err != nil {
-		return fmt.Errorf("failed to write temp file: %w", err)
-	}
-
-	// Atomic rename from temp to target
-	if err := os.Rename(tempFile, sm.stateFile); err != nil {
-		// Cleanup temp file if rename fails
-		os.Remove(tempFile)
-		return fmt.Errorf("failed to rename temp file: %w", err)
+	if err := vfs.WriteFile(sm.stateFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
 	}
 
 	return nil
@@ -155,10 +145,10 @@ func (sm *Manager) saveAtomic() error {
 
 // load loads the state from disk.
 func (sm *Manager) load() error {
-	data, err := os.ReadFile(sm.stateFile)
+	data, err := vfs.ReadFile(sm.stateFile)
 	if err != nil {
 		// File doesn't exist yet, that's OK
-		if os.IsNotExist(err) {
+		if errors.Is(err, fs.ErrNotExist) {
 			return nil
 		}
 		return fmt.Errorf("failed to read state file: %w", err)
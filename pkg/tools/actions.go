@@ -0,0 +1,127 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+// ActionSchemaFunc returns the schema of the actions a channel can show,
+// and false if it cannot show any.
+type ActionSchemaFunc func(channel string) (bus.ActionSchema, bool)
+
+// ActionsTool lets the agent offer choices with its reply, which channels
+// that support it show as buttons. The choices are taken by the agent with
+// TakeActions when it sends the reply.
+type ActionsTool struct {
+	schemaFor ActionSchemaFunc
+
+	mu             sync.Mutex
+	defaultChannel string
+	defaultChatID  string
+	pending        []bus.Action
+}
+
+func NewActionsTool(schemaFor ActionSchemaFunc) *ActionsTool {
+	return &ActionsTool{schemaFor: schemaFor}
+}
+
+func (t *ActionsTool) Name() string {
+	return "offer_actions"
+}
+
+func (t *ActionsTool) Description() string {
+	return "Offer the user a few choices shown as buttons below your reply, such as answers to a question you ask. " +
+		"Choosing one sends its value to you as the user's message. Call it once, before your final reply; a later call replaces the choices."
+}
+
+func (t *ActionsTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"actions": map[string]interface{}{
+				"type":        "array",
+				"description": "The choices, in the order to show them",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"label": map[string]interface{}{
+							"type":        "string",
+							"description": "Short button text",
+						},
+						"value": map[string]interface{}{
+							"type":        "string",
+							"description": "Optional: the message sent when chosen, if it should differ from the label",
+						},
+					},
+					"required": []string{"label"},
+				},
+			},
+		},
+		"required": []string{"actions"},
+	}
+}
+
+func (t *ActionsTool) SetContext(channel, chatID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.defaultChannel = channel
+	t.defaultChatID = chatID
+	t.pending = nil
+}
+
+// TakeActions returns the choices offered in the current round and clears
+// them.
+func (t *ActionsTool) TakeActions() []bus.Action {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	actions := t.pending
+	t.pending = nil
+	return actions
+}
+
+func (t *ActionsTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	t.mu.Lock()
+	channel := t.defaultChannel
+	t.mu.Unlock()
+
+	schema, ok := bus.ActionSchema{}, false
+	if t.schemaFor != nil {
+		schema, ok = t.schemaFor(channel)
+	}
+	if !ok {
+		return ErrorResult(fmt.Sprintf("the %s channel cannot show choices; list them in your reply instead", channel))
+	}
+
+	items, _ := args["actions"].([]interface{})
+	if len(items) == 0 {
+		return ErrorResult("actions is required")
+	}
+	if schema.MaxActions > 0 && len(items) > schema.MaxActions {
+		return ErrorResult(fmt.Sprintf("at most %d choices can be shown on %s", schema.MaxActions, channel))
+	}
+
+	actions := make([]bus.Action, 0, len(items))
+	for i, item := range items {
+		m, _ := item.(map[string]interface{})
+		label, _ := m["label"].(string)
+		value, _ := m["value"].(string)
+		label = strings.TrimSpace(label)
+		if label == "" {
+			return ErrorResult(fmt.Sprintf("choice %d has no label", i+1))
+		}
+		if schema.MaxLabelRunes > 0 && utf8.RuneCountInString(label) > schema.MaxLabelRunes {
+			return ErrorResult(fmt.Sprintf("label %q is longer than %d characters; shorten it and put details in value", label, schema.MaxLabelRunes))
+		}
+		actions = append(actions, bus.Action{Label: label, Value: strings.TrimSpace(value)})
+	}
+
+	t.mu.Lock()
+	t.pending = actions
+	t.mu.Unlock()
+	return SilentResult(fmt.Sprintf("%d choices will be shown below your reply.", len(actions)))
+}
@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+func buttonSchemas(channel string) (bus.ActionSchema, bool) {
+	if channel == "telegram" {
+		return bus.ActionSchema{MaxActions: 2, MaxLabelRunes: 10}, true
+	}
+	return bus.ActionSchema{}, false
+}
+
+func actionArgs(labels ...string) map[string]interface{} {
+	items := make([]interface{}, len(labels))
+	for i, l := range labels {
+		items[i] = map[string]interface{}{"label": l}
+	}
+	return map[string]interface{}{"actions": items}
+}
+
+func TestActionsTool_OffersChoices(t *testing.T) {
+	tool := NewActionsTool(buttonSchemas)
+	tool.SetContext("telegram", "1")
+
+	args := map[string]interface{}{"actions": []interface{}{
+		map[string]interface{}{"label": " Yes "},
+		map[string]interface{}{"label": "Later", "value": "Remind me tomorrow"},
+	}}
+	if result := tool.Execute(context.Background(), args); result.IsError {
+		t.Fatalf("Execute() error: %s", result.ForLLM)
+	}
+
+	got := tool.TakeActions()
+	if len(got) != 2 || got[0].Label != "Yes" || got[1].Value != "Remind me tomorrow" {
+		t.Fatalf("actions = %+v", got)
+	}
+	if again := tool.TakeActions(); len(again) != 0 {
+		t.Errorf("actions taken twice: %+v", again)
+	}
+}
+
+func TestActionsTool_RejectsWhatTheChannelCannotShow(t *testing.T) {
+	tool := NewActionsTool(buttonSchemas)
+	tests := []struct {
+		name    string
+		channel string
+		args    map[string]interface{}
+		want    string
+	}{
+		{"channel without buttons", "cli", actionArgs("Yes"), "cannot show choices"},
+		{"too many", "telegram", actionArgs("A", "B", "C"), "at most 2"},
+		{"label too long", "telegram", actionArgs("A very long label"), "longer than 10"},
+		{"empty label", "telegram", actionArgs(" "), "no label"},
+		{"no actions", "telegram", map[string]interface{}{}, "required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tool.SetContext(tt.channel, "1")
+			result := tool.Execute(context.Background(), tt.args)
+			if !result.IsError || !strings.Contains(result.ForLLM, tt.want) {
+				t.Errorf("Execute() = %+v, want error containing %q", result, tt.want)
+			}
+			if got := tool.TakeActions(); len(got) != 0 {
+				t.Errorf("rejected actions were kept: %+v", got)
+			}
+		})
+	}
+}
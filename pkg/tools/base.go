@@ -17,6 +17,24 @@ type ContextualTool interface {
 	SetContext(channel, chatID string)
 }
 
+// Prewarmer is an optional interface for tools whose first call can be
+// made faster by preparing for it, such as by opening a connection to the
+// tool's backend. The agent calls Prewarm when a message suggests the tool
+// is about to be used, so it must be cheap: the tool may not be called.
+type Prewarmer interface {
+	Tool
+	Prewarm(ctx context.Context) error
+}
+
+// ReadOnlyTool is an optional interface for tools that only read, so that
+// running a call again does no harm. Only their calls are started while a
+// streamed response is still generating: that response can still fail and
+// be requested again, and a turn that fails is retried later as a whole.
+type ReadOnlyTool interface {
+	Tool
+	ReadOnly() bool
+}
+
 // AsyncCallback is a function type that async tools use to notify completion.
 // When an async tool finishes its work, it calls this callback with the result.
 //
@@ -9,6 +9,7 @@ import (
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/cron"
+	"github.com/sipeed/picoclaw/pkg/providers"
 	"github.com/sipeed/picoclaw/pkg/utils"
 )
 
@@ -17,6 +18,12 @@ type JobExecutor interface {
 	ProcessDirectWithChannel(ctx context.Context, content, sessionKey, channel, chatID string) (string, error)
 }
 
+// DigestGenerator is implemented by executors that can produce scheduled
+// conversation digests.
+type DigestGenerator interface {
+	GenerateDigest(ctx context.Context, period, scope string) (string, error)
+}
+
 // CronTool provides scheduling capabilities for the agent
 type CronTool struct {
 	cronService *cron.CronService
@@ -48,7 +55,7 @@ func (t *CronTool) Name() string {
 
 // Description returns the tool description
 func (t *CronTool) Description() string {
-	return "Schedule reminders, tasks, or system commands. IMPORTANT: When user asks to be reminded or scheduled, you MUST call this tool. Use 'at_seconds' for one-time reminders (e.g., 'remind me in 10 minutes' → at_seconds=600). Use 'every_seconds' ONLY for recurring tasks (e.g., 'every 2 hours' → every_seconds=7200). Use 'cron_expr' for complex recurring schedules. Use 'command' to execute shell commands directly."
+	return "Schedule reminders, tasks, or system commands. IMPORTANT: When user asks to be reminded or scheduled, you MUST call this tool. Use 'at_seconds' for one-time reminders (e.g., 'remind me in 10 minutes' → at_seconds=600). Use 'every_seconds' ONLY for recurring tasks (e.g., 'every 2 hours' → every_seconds=7200). Use 'cron_expr' for complex recurring schedules. Use 'command' to execute shell commands directly. Use 'digest' to schedule daily or weekly conversation digests."
 }
 
 // Parameters returns the tool parameters schema
@@ -89,6 +96,15 @@ func (t *CronTool) Parameters() map[string]interface{} {
 				"type":        "boolean",
 				"description": "If true, send message directly to channel. If false, let agent process message (for complex tasks). Default: true",
 			},
+			"digest": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"daily", "weekly"},
+				"description": "Optional: schedule a conversation digest instead of a reminder. The digest is saved to memory/digests/ and sent to this chat when 'deliver' is true.",
+			},
+			"digest_scope": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional: limit the digest to sessions whose key contains this text (e.g. a channel name). Default: all sessions",
+			},
 		},
 		"required": []string{"action"},
 	}
@@ -135,8 +151,16 @@ func (t *CronTool) addJob(args map[string]interface{}) *ToolResult {
 		return ErrorResult("no session context (channel/chat_id not set). Use this tool in an active conversation.")
 	}
 
+	digest, _ := args["digest"].(string)
+	digestScope, _ := args["digest_scope"].(string)
+	if digest != "" && digest != "daily" && digest != "weekly" {
+		return ErrorResult("digest must be 'daily' or 'weekly'")
+	}
+
 	message, ok := args["message"].(string)
-	if !ok || message == "" {
+	if (!ok || message == "") && digest != "" {
+		message = digest + " digest"
+	} else if !ok || message == "" {
 		return ErrorResult("message is required for add")
 	}
 
@@ -199,8 +223,10 @@ func (t *CronTool) addJob(args map[string]interface{}) *ToolResult {
 		return ErrorResult(fmt.Sprintf("Error adding job: %v", err))
 	}
 
-	if command != "" {
+	if command != "" || digest != "" {
 		job.Payload.Command = command
+		job.Payload.Digest = digest
+		job.Payload.DigestScope = digestScope
 		// Need to save the updated payload
 		t.cronService.UpdateJob(job)
 	}
@@ -265,6 +291,9 @@ func (t *CronTool) enableJob(args map[string]interface{}, enable bool) *ToolResu
 
 // ExecuteJob executes a cron job through the agent
 func (t *CronTool) ExecuteJob(ctx context.Context, job *cron.CronJob) string {
+	// Jobs run in the background and must not hold up interactive turns.
+	ctx = providers.WithPriority(ctx, providers.PriorityBackground)
+
 	// Get channel/chatID from job payload
 	channel := job.Payload.Channel
 	chatID := job.Payload.To
@@ -277,6 +306,10 @@ func (t *CronTool) ExecuteJob(ctx context.Context, job *cron.CronJob) string {
 		chatID = "direct"
 	}
 
+	if job.Payload.Digest != "" {
+		return t.executeDigest(ctx, job, channel, chatID)
+	}
+
 	// Execute command if present
 	if job.Payload.Command != "" {
 		args := map[string]interface{}{
@@ -292,9 +325,10 @@ func (t *CronTool) ExecuteJob(ctx context.Context, job *cron.CronJob) string {
 		}
 
 		t.msgBus.PublishOutbound(bus.OutboundMessage{
-			Channel: channel,
-			ChatID:  chatID,
-			Content: output,
+			Channel:   channel,
+			ChatID:    chatID,
+			Content:   output,
+			Proactive: true,
 		})
 		return "ok"
 	}
@@ -302,9 +336,10 @@ func (t *CronTool) ExecuteJob(ctx context.Context, job *cron.CronJob) string {
 	// If deliver=true, send message directly without agent processing
 	if job.Payload.Deliver {
 		t.msgBus.PublishOutbound(bus.OutboundMessage{
-			Channel: channel,
-			ChatID:  chatID,
-			Content: job.Payload.Message,
+			Channel:   channel,
+			ChatID:    chatID,
+			Content:   job.Payload.Message,
+			Proactive: true,
 		})
 		return "ok"
 	}
@@ -329,3 +364,27 @@ func (t *CronTool) ExecuteJob(ctx context.Context, job *cron.CronJob) string {
 	_ = response // Will be sent by AgentLoop
 	return "ok"
 }
+
+// executeDigest generates a scheduled digest and optionally delivers it.
+func (t *CronTool) executeDigest(ctx context.Context, job *cron.CronJob, channel, chatID string) string {
+	generator, ok := t.executor.(DigestGenerator)
+	if !ok {
+		return "Error: digests are not supported by this executor"
+	}
+
+	digest, err := generator.GenerateDigest(ctx, job.Payload.Digest, job.Payload.DigestScope)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if digest == "" || !job.Payload.Deliver {
+		return "ok"
+	}
+
+	t.msgBus.PublishOutbound(bus.OutboundMessage{
+		Channel:   channel,
+		ChatID:    chatID,
+		Content:   digest,
+		Proactive: true,
+	})
+	return "ok"
+}
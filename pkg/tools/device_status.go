@@ -0,0 +1,44 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sipeed/picoclaw/pkg/governor"
+)
+
+// DeviceStatusTool reports battery, temperature and memory use, so the
+// agent can tell how the board it runs on is doing.
+type DeviceStatusTool struct{}
+
+func NewDeviceStatusTool() *DeviceStatusTool {
+	return &DeviceStatusTool{}
+}
+
+func (t *DeviceStatusTool) Name() string {
+	return "device_status"
+}
+
+// ReadOnly implements ReadOnlyTool.
+func (t *DeviceStatusTool) ReadOnly() bool {
+	return true
+}
+
+func (t *DeviceStatusTool) Description() string {
+	return "Report the health of the device the agent runs on: battery level and power source, temperature, and memory used by the agent."
+}
+
+func (t *DeviceStatusTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{},
+	}
+}
+
+func (t *DeviceStatusTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	report := "Device: " + governor.ReadPower().String()
+	if rss, err := governor.MemoryUsage(); err == nil {
+		report += fmt.Sprintf("\nAgent memory: %d MB", rss/(1024*1024))
+	}
+	return SilentResult(report)
+}
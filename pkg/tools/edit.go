@@ -2,9 +2,12 @@ package tools
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
+	"io/fs"
 	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/vfs"
 )
 
 // EditFileTool edits a file by replacing old_text with new_text.
@@ -72,11 +75,11 @@ func (t *EditFileTool) Execute(ctx context.Context, args map[string]interface{})
 		return ErrorResult(err.Error())
 	}
 
-	if _, err := os.Stat(resolvedPath); os.IsNotExist(err) {
+	if _, err := vfs.Stat(resolvedPath); errors.Is(err, fs.ErrNotExist) {
 		return ErrorResult(fmt.Sprintf("file not found: %s", path))
 	}
 
-	content, err := os.ReadFile(resolvedPath)
+	content, err := vfs.ReadFile(resolvedPath)
 	if err != nil {
 		return ErrorResult(fmt.Sprintf("failed to read file: %v", err))
 	}
@@ -94,7 +97,7 @@ func (t *EditFileTool) Execute(ctx context.Context, args map[string]interface{})
 
 	newContent := strings.Replace(contentStr, oldText, newText, 1)
 
-	if err := os.WriteFile(resolvedPath, []byte(newContent), 0644); err != nil {
+	if err := vfs.WriteFile(resolvedPath, []byte(newContent), 0644); err != nil {
 		return ErrorResult(fmt.Sprintf("failed to write file: %v", err))
 	}
 
@@ -151,13 +154,7 @@ func (t *AppendFileTool) Execute(ctx context.Context, args map[string]interface{
 		return ErrorResult(err.Error())
 	}
 
-	f, err := os.OpenFile(resolvedPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return ErrorResult(fmt.Sprintf("failed to open file: %v", err))
-	}
-	defer f.Close()
-
-	if _, err := f.WriteString(content); err != nil {
+	if err := vfs.AppendFile(resolvedPath, []byte(content), 0644); err != nil {
 		return ErrorResult(fmt.Sprintf("failed to append to file: %v", err))
 	}
 
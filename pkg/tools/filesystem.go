@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/vfs"
 )
 
 // validatePath ensures the given path is within the workspace if restrict is true.
@@ -90,6 +92,11 @@ func (t *ReadFileTool) Name() string {
 	return "read_file"
 }
 
+// ReadOnly implements ReadOnlyTool.
+func (t *ReadFileTool) ReadOnly() bool {
+	return true
+}
+
 func (t *ReadFileTool) Description() string {
 	return "Read the contents of a file"
 }
@@ -118,7 +125,7 @@ func (t *ReadFileTool) Execute(ctx context.Context, args map[string]interface{})
 		return ErrorResult(err.Error())
 	}
 
-	content, err := os.ReadFile(resolvedPath)
+	content, err := vfs.ReadFile(resolvedPath)
 	if err != nil {
 		return ErrorResult(fmt.Sprintf("failed to read file: %v", err))
 	}
@@ -177,11 +184,11 @@ func (t *WriteFileTool) Execute(ctx context.Context, args map[string]interface{}
 	}
 
 	dir := filepath.Dir(resolvedPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	if err := vfs.MkdirAll(dir, 0755); err != nil {
 		return ErrorResult(fmt.Sprintf("failed to create directory: %v", err))
 	}
 
-	if err := os.WriteFile(resolvedPath, []byte(content), 0644); err != nil {
+	if err := vfs.WriteFile(resolvedPath, []byte(content), 0644); err != nil {
 		return ErrorResult(fmt.Sprintf("failed to write file: %v", err))
 	}
 
@@ -201,6 +208,11 @@ func (t *ListDirTool) Name() string {
 	return "list_dir"
 }
 
+// ReadOnly implements ReadOnlyTool.
+func (t *ListDirTool) ReadOnly() bool {
+	return true
+}
+
 func (t *ListDirTool) Description() string {
 	return "List files and directories in a path"
 }
@@ -229,7 +241,7 @@ func (t *ListDirTool) Execute(ctx context.Context, args map[string]interface{})
 		return ErrorResult(err.Error())
 	}
 
-	entries, err := os.ReadDir(resolvedPath)
+	entries, err := vfs.ReadDir(resolvedPath)
 	if err != nil {
 		return ErrorResult(fmt.Sprintf("failed to read directory: %v", err))
 	}
@@ -0,0 +1,239 @@
+//go:build hardware
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/features"
+)
+
+func init() {
+	newHardwareTool = func(opts HardwareToolOptions) Tool {
+		return newHardwareToolAt(opts, "/sys/class/gpio")
+	}
+	features.Register("tool", "hardware")
+}
+
+// maxSensorRead caps the bytes read from a sensor in one call.
+const maxSensorRead = 32
+
+// HardwareTool reads and drives GPIO pins through the sysfs interface and
+// reads I2C sensors, limited to an allowlist of named pins and devices.
+type HardwareTool struct {
+	pins     map[string]GPIOPin
+	sensors  map[string]I2CSensor
+	limiter  *rateLimiter
+	gpioRoot string
+	i2c      *I2CTool
+}
+
+func newHardwareToolAt(opts HardwareToolOptions, gpioRoot string) *HardwareTool {
+	t := &HardwareTool{
+		pins:     make(map[string]GPIOPin),
+		sensors:  make(map[string]I2CSensor),
+		limiter:  newRateLimiter(opts.MaxOpsPerMinute),
+		gpioRoot: gpioRoot,
+		i2c:      NewI2CTool(),
+	}
+	for _, p := range opts.GPIOPins {
+		t.pins[p.Name] = p
+	}
+	for _, s := range opts.I2CSensors {
+		t.sensors[s.Name] = s
+	}
+	return t
+}
+
+func (t *HardwareTool) Name() string {
+	return "hardware"
+}
+
+func (t *HardwareTool) Description() string {
+	return "Use the GPIO pins and I2C sensors attached to this board. Actions: list (show available pins and sensors), gpio_read (read a pin), gpio_write (set an output pin to 0 or 1), sensor_read (read bytes from a sensor). Pins and sensors are referred to by name; operations are rate limited."
+}
+
+func (t *HardwareTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"list", "gpio_read", "gpio_write", "sensor_read"},
+				"description": "Action to perform",
+			},
+			"name": map[string]interface{}{
+				"type":        "string",
+				"description": "Pin or sensor name, as shown by list. Required except for list.",
+			},
+			"value": map[string]interface{}{
+				"type":        "integer",
+				"enum":        []int{0, 1},
+				"description": "Level to set. Required for gpio_write.",
+			},
+			"register": map[string]interface{}{
+				"type":        "integer",
+				"description": "Register to read from. Used with sensor_read.",
+			},
+			"length": map[string]interface{}{
+				"type":        "integer",
+				"description": "Number of bytes to read (1-32). Default: 1. Used with sensor_read.",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *HardwareTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	action, _ := args["action"].(string)
+	name, _ := args["name"].(string)
+
+	switch action {
+	case "list":
+		return t.list()
+	case "gpio_read", "gpio_write":
+		pin, ok := t.pins[name]
+		if !ok {
+			return ErrorResult(fmt.Sprintf("unknown pin %q; use action list to see available pins", name))
+		}
+		if action == "gpio_write" && !pin.Output {
+			return ErrorResult(fmt.Sprintf("pin %q is an input and cannot be written", name))
+		}
+		if !t.limiter.allow("gpio:" + name) {
+			return ErrorResult(fmt.Sprintf("rate limit reached for pin %q; try again later", name))
+		}
+		if action == "gpio_read" {
+			return t.gpioRead(pin)
+		}
+		return t.gpioWrite(pin, args)
+	case "sensor_read":
+		sensor, ok := t.sensors[name]
+		if !ok {
+			return ErrorResult(fmt.Sprintf("unknown sensor %q; use action list to see available sensors", name))
+		}
+		if !t.limiter.allow("i2c:" + name) {
+			return ErrorResult(fmt.Sprintf("rate limit reached for sensor %q; try again later", name))
+		}
+		return t.sensorRead(sensor, args)
+	default:
+		return ErrorResult(fmt.Sprintf("unknown action: %s (valid: list, gpio_read, gpio_write, sensor_read)", action))
+	}
+}
+
+func (t *HardwareTool) list() *ToolResult {
+	var lines []string
+	for _, p := range t.pins {
+		mode := "input"
+		if p.Output {
+			mode = "output"
+		}
+		lines = append(lines, fmt.Sprintf("pin %s: GPIO %d, %s", p.Name, p.Pin, mode))
+	}
+	for _, s := range t.sensors {
+		lines = append(lines, fmt.Sprintf("sensor %s: I2C bus %s, address 0x%02x", s.Name, s.Bus, s.Address))
+	}
+	if len(lines) == 0 {
+		return SilentResult("No pins or sensors are configured")
+	}
+	sort.Strings(lines)
+	return SilentResult(strings.Join(lines, "\n"))
+}
+
+func (t *HardwareTool) gpioRead(pin GPIOPin) *ToolResult {
+	if err := t.exportPin(pin); err != nil {
+		return ErrorResult(err.Error()).WithError(err)
+	}
+	data, err := os.ReadFile(t.pinFile(pin, "value"))
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to read pin %q: %v", pin.Name, err)).WithError(err)
+	}
+	return SilentResult(fmt.Sprintf("%s = %s", pin.Name, strings.TrimSpace(string(data))))
+}
+
+func (t *HardwareTool) gpioWrite(pin GPIOPin, args map[string]interface{}) *ToolResult {
+	var value string
+	switch v := args["value"].(type) {
+	case float64:
+		if v == 0 || v == 1 {
+			value = strconv.Itoa(int(v))
+		}
+	case bool:
+		value = "0"
+		if v {
+			value = "1"
+		}
+	}
+	if value == "" {
+		return ErrorResult("value must be 0 or 1")
+	}
+
+	if err := t.exportPin(pin); err != nil {
+		return ErrorResult(err.Error()).WithError(err)
+	}
+	if err := os.WriteFile(t.pinFile(pin, "value"), []byte(value), 0o644); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to write pin %q: %v", pin.Name, err)).WithError(err)
+	}
+	return SilentResult(fmt.Sprintf("%s set to %s", pin.Name, value))
+}
+
+func (t *HardwareTool) sensorRead(sensor I2CSensor, args map[string]interface{}) *ToolResult {
+	readArgs := map[string]interface{}{
+		"bus":     sensor.Bus,
+		"address": float64(sensor.Address),
+	}
+	if reg, ok := args["register"].(float64); ok {
+		readArgs["register"] = reg
+	}
+	if l, ok := args["length"].(float64); ok {
+		if l < 1 || l > maxSensorRead {
+			return ErrorResult(fmt.Sprintf("length must be between 1 and %d", maxSensorRead))
+		}
+		readArgs["length"] = l
+	}
+	return t.i2c.readDevice(readArgs)
+}
+
+func (t *HardwareTool) pinFile(pin GPIOPin, name string) string {
+	return filepath.Join(t.gpioRoot, fmt.Sprintf("gpio%d", pin.Pin), name)
+}
+
+// exportPin makes the pin available through sysfs and sets its direction.
+func (t *HardwareTool) exportPin(pin GPIOPin) error {
+	direction := t.pinFile(pin, "direction")
+	if _, err := os.Stat(direction); os.IsNotExist(err) {
+		export := filepath.Join(t.gpioRoot, "export")
+		if err := os.WriteFile(export, []byte(strconv.Itoa(pin.Pin)), 0o200); err != nil {
+			return fmt.Errorf("failed to export GPIO %d: %w", pin.Pin, err)
+		}
+		// udev may take a moment to create the pin's files.
+		for i := 0; i < 50; i++ {
+			if _, err := os.Stat(direction); err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	want := "in"
+	if pin.Output {
+		want = "out"
+	}
+	current, err := os.ReadFile(direction)
+	if err != nil {
+		return fmt.Errorf("failed to read direction of GPIO %d: %w", pin.Pin, err)
+	}
+	if strings.TrimSpace(string(current)) == want {
+		return nil
+	}
+	if err := os.WriteFile(direction, []byte(want), 0o644); err != nil {
+		return fmt.Errorf("failed to set direction of GPIO %d: %w", pin.Pin, err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"sync"
+	"time"
+)
+
+// HardwareToolOptions configures the hardware tool. Only the listed pins
+// and sensors can be used, each at most MaxOpsPerMinute times a minute.
+type HardwareToolOptions struct {
+	GPIOPins        []GPIOPin
+	I2CSensors      []I2CSensor
+	MaxOpsPerMinute int
+}
+
+// GPIOPin is a GPIO line the agent may use. Input pins can only be read.
+type GPIOPin struct {
+	Name   string
+	Pin    int
+	Output bool
+}
+
+// I2CSensor is an I2C device the agent may read from.
+type I2CSensor struct {
+	Name    string
+	Bus     string
+	Address int
+}
+
+// newHardwareTool is set by hardware.go, which is only compiled with the
+// "hardware" build tag.
+var newHardwareTool func(HardwareToolOptions) Tool
+
+// NewHardwareTool returns the GPIO and sensor tool, or nil if the binary
+// was built without the "hardware" tag.
+func NewHardwareTool(opts HardwareToolOptions) Tool {
+	if newHardwareTool == nil {
+		return nil
+	}
+	return newHardwareTool(opts)
+}
+
+// rateLimiter allows at most limit operations per key in any one window,
+// a minute unless set otherwise. A limit of zero or less disables it.
+type rateLimiter struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu  sync.Mutex
+	ops map[string][]time.Time
+}
+
+func newRateLimiter(limit int) *rateLimiter {
+	return &rateLimiter{limit: limit, window: time.Minute, now: time.Now, ops: make(map[string][]time.Time)}
+}
+
+// allow records an operation on key and reports whether it is within the
+// limit. Rejected operations are not recorded.
+func (l *rateLimiter) allow(key string) bool {
+	if l.limit <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	recent := l.ops[key][:0]
+	for _, t := range l.ops[key] {
+		if now.Sub(t) < l.window {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= l.limit {
+		l.ops[key] = recent
+		return false
+	}
+	l.ops[key] = append(recent, now)
+	return true
+}
@@ -0,0 +1,31 @@
+package tools
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Unix(1000, 0)
+	l := newRateLimiter(2)
+	l.now = func() time.Time { return now }
+
+	if !l.allow("a") || !l.allow("a") {
+		t.Fatal("first two operations should be allowed")
+	}
+	if l.allow("a") {
+		t.Error("third operation within a minute should be rejected")
+	}
+	if !l.allow("b") {
+		t.Error("keys should be limited independently")
+	}
+
+	now = now.Add(61 * time.Second)
+	if !l.allow("a") {
+		t.Error("operations should be allowed again after a minute")
+	}
+
+	if unlimited := newRateLimiter(0); !unlimited.allow("a") || !unlimited.allow("a") {
+		t.Error("a zero limit should disable limiting")
+	}
+}
@@ -0,0 +1,61 @@
+//go:build hardware
+
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHardwareTool_GPIO(t *testing.T) {
+	root := t.TempDir()
+	// Simulate sysfs: one pin already exported, the other not yet.
+	os.MkdirAll(filepath.Join(root, "gpio14"), 0o755)
+	os.WriteFile(filepath.Join(root, "gpio14", "direction"), []byte("in\n"), 0o644)
+	os.WriteFile(filepath.Join(root, "gpio14", "value"), []byte("0\n"), 0o644)
+
+	tool := newHardwareToolAt(HardwareToolOptions{
+		GPIOPins: []GPIOPin{
+			{Name: "led", Pin: 14, Output: true},
+			{Name: "button", Pin: 15},
+		},
+		MaxOpsPerMinute: 3,
+	}, root)
+	ctx := context.Background()
+
+	result := tool.Execute(ctx, map[string]interface{}{"action": "gpio_write", "name": "led", "value": float64(1)})
+	if result.IsError {
+		t.Fatal(result.ForLLM)
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "gpio14", "direction")); string(data) != "out" {
+		t.Errorf("direction = %q, want out", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "gpio14", "value")); string(data) != "1" {
+		t.Errorf("value = %q, want 1", data)
+	}
+
+	result = tool.Execute(ctx, map[string]interface{}{"action": "gpio_write", "name": "button", "value": float64(1)})
+	if !result.IsError || !strings.Contains(result.ForLLM, "input") {
+		t.Errorf("writing an input pin: %s", result.ForLLM)
+	}
+	result = tool.Execute(ctx, map[string]interface{}{"action": "gpio_read", "name": "relay"})
+	if !result.IsError {
+		t.Errorf("reading an unlisted pin succeeded: %s", result.ForLLM)
+	}
+
+	// Exporting pin 15 writes to the export file.
+	result = tool.Execute(ctx, map[string]interface{}{"action": "gpio_read", "name": "button"})
+	if data, _ := os.ReadFile(filepath.Join(root, "export")); string(data) != "15" {
+		t.Errorf("export = %q, want 15 (%s)", data, result.ForLLM)
+	}
+
+	tool.Execute(ctx, map[string]interface{}{"action": "gpio_read", "name": "led"})
+	tool.Execute(ctx, map[string]interface{}{"action": "gpio_read", "name": "led"})
+	result = tool.Execute(ctx, map[string]interface{}{"action": "gpio_read", "name": "led"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "rate limit") {
+		t.Errorf("fourth operation on led: %s", result.ForLLM)
+	}
+}
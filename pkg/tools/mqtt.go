@@ -0,0 +1,247 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/egress"
+	"github.com/sipeed/picoclaw/pkg/mqtt"
+)
+
+const (
+	mqttDefaultWait = 10 * time.Second
+	mqttMaxWait     = 120 * time.Second
+	mqttMaxMessages = 20
+)
+
+// MQTTToolOptions configures the mqtt tool. Publishing is limited to topics
+// matching AllowPublish and subscribing to filters within AllowSubscribe.
+type MQTTToolOptions struct {
+	Broker         string
+	ClientID       string
+	Username       string
+	Password       string
+	AllowPublish   []string
+	AllowSubscribe []string
+}
+
+// MQTTTool publishes to and reads from MQTT topics, for talking to sensors
+// and actuators on the local network.
+type MQTTTool struct {
+	opts MQTTToolOptions
+
+	mu     sync.Mutex
+	client *mqtt.Client
+}
+
+func NewMQTTTool(opts MQTTToolOptions) *MQTTTool {
+	return &MQTTTool{opts: opts}
+}
+
+func (t *MQTTTool) Name() string {
+	return "mqtt"
+}
+
+func (t *MQTTTool) Description() string {
+	desc := "Publish to or read from MQTT topics on the configured broker. Actions: publish (send a payload to a topic), subscribe (wait for messages on a topic filter and return them; retained values arrive immediately)."
+	if len(t.opts.AllowPublish) > 0 {
+		desc += " Publishable topics: " + strings.Join(t.opts.AllowPublish, ", ") + "."
+	}
+	if len(t.opts.AllowSubscribe) > 0 {
+		desc += " Readable topics: " + strings.Join(t.opts.AllowSubscribe, ", ") + "."
+	}
+	return desc
+}
+
+func (t *MQTTTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"publish", "subscribe"},
+				"description": "Action to perform",
+			},
+			"topic": map[string]interface{}{
+				"type":        "string",
+				"description": "Topic to publish to, or topic filter to subscribe to (+ and # wildcards allowed for subscribe)",
+			},
+			"payload": map[string]interface{}{
+				"type":        "string",
+				"description": "Message payload. Required for publish.",
+			},
+			"retain": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Ask the broker to retain the published message. Default: false.",
+			},
+			"timeout_seconds": map[string]interface{}{
+				"type":        "integer",
+				"description": "How long subscribe waits for messages (1-120). Default: 10.",
+			},
+			"max_messages": map[string]interface{}{
+				"type":        "integer",
+				"description": "Subscribe returns after this many messages (1-20). Default: 1.",
+			},
+		},
+		"required": []string{"action", "topic"},
+	}
+}
+
+func (t *MQTTTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	action, _ := args["action"].(string)
+	topic, _ := args["topic"].(string)
+	if topic == "" {
+		return ErrorResult("topic is required")
+	}
+
+	switch action {
+	case "publish":
+		return t.publish(ctx, topic, args)
+	case "subscribe":
+		return t.subscribe(ctx, topic, args)
+	default:
+		return ErrorResult(fmt.Sprintf("unknown action: %s (valid: publish, subscribe)", action))
+	}
+}
+
+func (t *MQTTTool) publish(ctx context.Context, topic string, args map[string]interface{}) *ToolResult {
+	if !topicAllowed(t.opts.AllowPublish, topic) {
+		return ErrorResult(fmt.Sprintf("publishing to %q is not allowed", topic))
+	}
+	payload, ok := args["payload"].(string)
+	if !ok {
+		return ErrorResult("payload is required for publish")
+	}
+	retain, _ := args["retain"].(bool)
+
+	client, err := t.connect(ctx)
+	if err != nil {
+		return ErrorResult(err.Error()).WithError(err)
+	}
+	if err := client.Publish(ctx, topic, []byte(payload), 1, retain); err != nil {
+		return ErrorResult(fmt.Sprintf("publish failed: %v", err)).WithError(err)
+	}
+	return SilentResult(fmt.Sprintf("Published %d bytes to %s", len(payload), topic))
+}
+
+func (t *MQTTTool) subscribe(ctx context.Context, filter string, args map[string]interface{}) *ToolResult {
+	if !topicAllowed(t.opts.AllowSubscribe, filter) {
+		return ErrorResult(fmt.Sprintf("subscribing to %q is not allowed", filter))
+	}
+	wait := mqttDefaultWait
+	if v, ok := args["timeout_seconds"].(float64); ok && v >= 1 {
+		wait = min(time.Duration(v)*time.Second, mqttMaxWait)
+	}
+	limit := 1
+	if v, ok := args["max_messages"].(float64); ok && v >= 1 {
+		limit = min(int(v), mqttMaxMessages)
+	}
+
+	client, err := t.connect(ctx)
+	if err != nil {
+		return ErrorResult(err.Error()).WithError(err)
+	}
+	received := make(chan mqtt.Message, limit)
+	handler := func(msg mqtt.Message) {
+		select {
+		case received <- msg:
+		default:
+		}
+	}
+	sub, err := client.Subscribe(ctx, filter, 1, handler)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("subscribe failed: %v", err)).WithError(err)
+	}
+	defer client.Unsubscribe(context.Background(), sub)
+
+	var msgs []mqtt.Message
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+collect:
+	for len(msgs) < limit {
+		select {
+		case msg := <-received:
+			msgs = append(msgs, msg)
+		case <-timer.C:
+			break collect
+		case <-client.Done():
+			break collect
+		case <-ctx.Done():
+			return ErrorResult("subscribe cancelled").WithError(ctx.Err())
+		}
+	}
+
+	if len(msgs) == 0 {
+		return SilentResult(fmt.Sprintf("No messages on %s within %s", filter, wait))
+	}
+	var sb strings.Builder
+	for _, msg := range msgs {
+		fmt.Fprintf(&sb, "%s: %s", msg.Topic, msg.Payload)
+		if msg.Retained {
+			sb.WriteString(" (retained)")
+		}
+		sb.WriteString("\n")
+	}
+	return SilentResult(sb.String())
+}
+
+// connect returns the shared connection, dialing again if it dropped.
+func (t *MQTTTool) connect(ctx context.Context) (*mqtt.Client, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.client != nil {
+		select {
+		case <-t.client.Done():
+		default:
+			return t.client, nil
+		}
+	}
+	dialCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	client, err := mqtt.Dial(dialCtx, mqtt.Options{
+		Broker:   t.opts.Broker,
+		ClientID: t.opts.ClientID,
+		Username: t.opts.Username,
+		Password: t.opts.Password,
+		Dial:     egress.Dialer(egress.Tools),
+	})
+	if err != nil {
+		return nil, err
+	}
+	t.client = client
+	return client, nil
+}
+
+// topicAllowed reports whether topic (which may itself be a filter) lies
+// within one of the allowed filters. A wildcard in topic is only covered
+// by a wildcard in the same position, so "home/+/set" allows
+// "home/kitchen/set" but not "home/#".
+func topicAllowed(allowed []string, topic string) bool {
+	for _, filter := range allowed {
+		if filterCovers(filter, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+func filterCovers(filter, topic string) bool {
+	fl := strings.Split(filter, "/")
+	tl := strings.Split(topic, "/")
+	for i, f := range fl {
+		if f == "#" {
+			return true
+		}
+		if i >= len(tl) || tl[i] == "#" {
+			return false
+		}
+		if f != "+" && f != tl[i] {
+			return false
+		}
+	}
+	return len(fl) == len(tl)
+}
@@ -0,0 +1,46 @@
+package tools
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTopicAllowed(t *testing.T) {
+	allowed := []string{"home/+/set", "sensors/#"}
+	tests := []struct {
+		topic string
+		want  bool
+	}{
+		{"home/kitchen/set", true},
+		{"home/+/set", true},
+		{"home/kitchen/get", false},
+		{"home/#", false},
+		{"sensors/kitchen/temp", true},
+		{"sensors/#", true},
+		{"#", false},
+		{"other", false},
+	}
+	for _, tt := range tests {
+		if got := topicAllowed(allowed, tt.topic); got != tt.want {
+			t.Errorf("topicAllowed(%q) = %v, want %v", tt.topic, got, tt.want)
+		}
+	}
+}
+
+func TestMQTTTool_RejectsTopicsOutsideAllowlist(t *testing.T) {
+	// No broker is needed: the allowlist is checked before connecting.
+	tool := NewMQTTTool(MQTTToolOptions{Broker: "tcp://127.0.0.1:1", AllowPublish: []string{"home/+/set"}})
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"action": "publish", "topic": "home/door/unlock", "payload": "1",
+	})
+	if !result.IsError {
+		t.Errorf("publish outside allowlist succeeded: %s", result.ForLLM)
+	}
+	result = tool.Execute(context.Background(), map[string]interface{}{
+		"action": "subscribe", "topic": "home/#",
+	})
+	if !result.IsError {
+		t.Errorf("subscribe with empty allowlist succeeded: %s", result.ForLLM)
+	}
+}
@@ -0,0 +1,138 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+// NotifyTarget is a chat the notify tool can send to. If Agents is set,
+// only those agents may notify it.
+type NotifyTarget struct {
+	Name    string
+	Channel string
+	ChatID  string
+	Agents  []string
+}
+
+// NotifyToolOptions configures the notify tool. Each target receives at
+// most MaxPerHour notifications an hour, from all agents together.
+type NotifyToolOptions struct {
+	Targets    []NotifyTarget
+	MaxPerHour int
+}
+
+// NotifyTool sends a message to a named chat outside the conversation, such
+// as the user's phone when a long task finishes.
+type NotifyTool struct {
+	targets []NotifyTarget
+	agentID string
+	publish func(bus.OutboundMessage)
+	limiter *rateLimiter
+}
+
+// NewNotifyTool returns a notify tool that sends through publish. Use
+// ForAgent to get the tool for each agent.
+func NewNotifyTool(opts NotifyToolOptions, publish func(bus.OutboundMessage)) *NotifyTool {
+	limiter := newRateLimiter(opts.MaxPerHour)
+	limiter.window = time.Hour
+	return &NotifyTool{targets: opts.Targets, publish: publish, limiter: limiter}
+}
+
+// ForAgent returns the tool for agentID, which can only notify the targets
+// open to it, or nil if there are none. All agents share the rate limits.
+func (t *NotifyTool) ForAgent(agentID string) *NotifyTool {
+	tool := *t
+	tool.agentID = agentID
+	for _, target := range t.targets {
+		if tool.allowed(target) {
+			return &tool
+		}
+	}
+	return nil
+}
+
+func (t *NotifyTool) Name() string {
+	return "notify"
+}
+
+func (t *NotifyTool) Description() string {
+	var names []string
+	for _, target := range t.targets {
+		if t.allowed(target) {
+			names = append(names, target.Name)
+		}
+	}
+	return "Send a notification to one of the user's chats outside this conversation, e.g. to alert them on their phone when something they asked to be told about happens. " +
+		"Targets: " + strings.Join(names, ", ") + "."
+}
+
+func (t *NotifyTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"target": map[string]interface{}{
+				"type":        "string",
+				"description": "Name of the target to notify",
+			},
+			"message": map[string]interface{}{
+				"type":        "string",
+				"description": "The notification text; make it understandable without this conversation",
+			},
+			"urgent": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Deliver even outside the target's working hours. Default: false.",
+			},
+		},
+		"required": []string{"target", "message"},
+	}
+}
+
+func (t *NotifyTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	name, _ := args["target"].(string)
+	message, _ := args["message"].(string)
+	urgent, _ := args["urgent"].(bool)
+	if strings.TrimSpace(message) == "" {
+		return ErrorResult("message is required")
+	}
+
+	var target *NotifyTarget
+	for i := range t.targets {
+		if strings.EqualFold(t.targets[i].Name, name) {
+			target = &t.targets[i]
+			break
+		}
+	}
+	if target == nil {
+		return ErrorResult(fmt.Sprintf("unknown target %q", name))
+	}
+	if !t.allowed(*target) {
+		return ErrorResult(fmt.Sprintf("this agent may not notify %s", target.Name))
+	}
+	if !t.limiter.allow(target.Name) {
+		return ErrorResult(fmt.Sprintf("too many notifications to %s this hour; try again later", target.Name))
+	}
+
+	t.publish(bus.OutboundMessage{
+		Channel:   target.Channel,
+		ChatID:    target.ChatID,
+		Content:   message,
+		Proactive: !urgent,
+	})
+	return SilentResult(fmt.Sprintf("Notification sent to %s", target.Name))
+}
+
+func (t *NotifyTool) allowed(target NotifyTarget) bool {
+	if len(target.Agents) == 0 {
+		return true
+	}
+	for _, id := range target.Agents {
+		if id == t.agentID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+func TestNotifyTool_SendsToTarget(t *testing.T) {
+	var sent []bus.OutboundMessage
+	tool := NewNotifyTool(NotifyToolOptions{
+		Targets: []NotifyTarget{{Name: "phone", Channel: "telegram", ChatID: "42"}},
+	}, func(msg bus.OutboundMessage) { sent = append(sent, msg) }).ForAgent("main")
+
+	result := tool.Execute(context.Background(), map[string]interface{}{"target": "Phone", "message": "Backup done"})
+	if result.IsError {
+		t.Fatalf("Execute() error: %s", result.ForLLM)
+	}
+	if len(sent) != 1 || sent[0].Channel != "telegram" || sent[0].ChatID != "42" || sent[0].Content != "Backup done" || !sent[0].Proactive {
+		t.Fatalf("sent = %+v", sent)
+	}
+
+	tool.Execute(context.Background(), map[string]interface{}{"target": "phone", "message": "Disk full", "urgent": true})
+	if len(sent) != 2 || sent[1].Proactive {
+		t.Errorf("urgent notification sent as proactive: %+v", sent)
+	}
+
+	if result := tool.Execute(context.Background(), map[string]interface{}{"target": "pager", "message": "x"}); !result.IsError {
+		t.Error("notified an unknown target")
+	}
+}
+
+func TestNotifyTool_PermissionsAndRateLimit(t *testing.T) {
+	var sent int
+	shared := NewNotifyTool(NotifyToolOptions{
+		Targets:    []NotifyTarget{{Name: "ops", Channel: "slack", ChatID: "C1", Agents: []string{"monitor"}}},
+		MaxPerHour: 2,
+	}, func(bus.OutboundMessage) { sent++ })
+
+	if shared.ForAgent("main") != nil {
+		t.Error("agent without targets got the tool")
+	}
+	monitor := shared.ForAgent("monitor")
+	args := map[string]interface{}{"target": "ops", "message": "Service down"}
+	for i := 0; i < 2; i++ {
+		if result := monitor.Execute(context.Background(), args); result.IsError {
+			t.Fatalf("notification %d failed: %s", i, result.ForLLM)
+		}
+	}
+	if result := monitor.ForAgent("monitor").Execute(context.Background(), args); !result.IsError {
+		t.Error("notification over the hourly limit was sent")
+	}
+	if sent != 2 {
+		t.Errorf("sent %d notifications, want 2", sent)
+	}
+}
@@ -0,0 +1,60 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+)
+
+type taskIDKey struct{}
+
+// withTaskID marks ctx as belonging to the background task taskID, for
+// report_progress.
+func withTaskID(ctx context.Context, taskID string) context.Context {
+	return context.WithValue(ctx, taskIDKey{}, taskID)
+}
+
+// ProgressTool lets a background task report how far it has got. The
+// progress is shown by /tasks and sent to the chat that started the task.
+type ProgressTool struct {
+	manager *SubagentManager
+}
+
+func NewProgressTool(manager *SubagentManager) *ProgressTool {
+	return &ProgressTool{manager: manager}
+}
+
+func (t *ProgressTool) Name() string {
+	return "report_progress"
+}
+
+func (t *ProgressTool) Description() string {
+	return "Report the progress of this background task to the user, e.g. \"fetched 40 of 200 pages\". Use it at meaningful milestones of long tasks, not after every step."
+}
+
+func (t *ProgressTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"progress": map[string]interface{}{
+				"type":        "string",
+				"description": "Short description of the progress so far",
+			},
+		},
+		"required": []string{"progress"},
+	}
+}
+
+func (t *ProgressTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	progress, _ := args["progress"].(string)
+	if progress == "" {
+		return ErrorResult("progress is required")
+	}
+	taskID, _ := ctx.Value(taskIDKey{}).(string)
+	if taskID == "" {
+		return ErrorResult("report_progress is only available in background tasks")
+	}
+	if err := t.manager.reportProgress(taskID, progress); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to report progress: %v", err)).WithError(err)
+	}
+	return SilentResult("Progress reported")
+}
@@ -0,0 +1,123 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// blockingProvider answers only once its context is done or release is
+// closed. The first call of a task may report progress first.
+type blockingProvider struct {
+	release  chan struct{}
+	progress string
+}
+
+func (p *blockingProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, options map[string]interface{}) (*providers.LLMResponse, error) {
+	if p.progress != "" && messages[len(messages)-1].Role == "user" {
+		return &providers.LLMResponse{ToolCalls: []providers.ToolCall{{
+			ID:        "call_1",
+			Name:      "report_progress",
+			Arguments: map[string]interface{}{"progress": p.progress},
+		}}}, nil
+	}
+	select {
+	case <-p.release:
+		return &providers.LLMResponse{Content: "done"}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (p *blockingProvider) GetDefaultModel() string { return "test-model" }
+
+func waitForStatus(t *testing.T, sm *SubagentManager, taskID, status string) *SubagentTask {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if task, ok := sm.GetTask(taskID); ok && task.Status == status {
+			return task
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	task, _ := sm.GetTask(taskID)
+	t.Fatalf("task %s status = %+v, want %s", taskID, task, status)
+	return nil
+}
+
+func TestSubagentManager_QueuesAndCancels(t *testing.T) {
+	provider := &blockingProvider{release: make(chan struct{})}
+	sm := NewSubagentManager(provider, "test-model", t.TempDir(), nil)
+	sm.SetMaxRunning(1)
+
+	if _, err := sm.Spawn(context.Background(), "first", "", "", "cli", "direct", nil); err != nil {
+		t.Fatal(err)
+	}
+	first := sm.ListTasks()[0].ID
+	waitForStatus(t, sm, first, "running")
+	if _, err := sm.Spawn(context.Background(), "second", "", "", "cli", "direct", nil); err != nil {
+		t.Fatal(err)
+	}
+	tasks := sm.ListTasks()
+	if len(tasks) != 2 || tasks[0].Task != "first" || tasks[1].Task != "second" {
+		t.Fatalf("tasks = %+v", tasks)
+	}
+	second := tasks[1].ID
+
+	// The second task waits for the first to leave its slot.
+	time.Sleep(20 * time.Millisecond)
+	if task, _ := sm.GetTask(second); task.Status != "queued" {
+		t.Fatalf("second status = %s, want queued", task.Status)
+	}
+
+	if err := sm.Cancel(first); err != nil {
+		t.Fatalf("Cancel() error: %v", err)
+	}
+	waitForStatus(t, sm, first, "cancelled")
+	waitForStatus(t, sm, second, "running")
+	if err := sm.Cancel(first); err == nil {
+		t.Error("expected cancelling a finished task to fail")
+	}
+	if err := sm.Cancel("subagent-0"); err == nil {
+		t.Error("expected cancelling an unknown task to fail")
+	}
+
+	close(provider.release)
+	if task := waitForStatus(t, sm, second, "completed"); task.Result != "done" || task.Iterations != 1 {
+		t.Errorf("second = %+v", task)
+	}
+}
+
+func TestProgressTool_ReportsToOriginChat(t *testing.T) {
+	release := make(chan struct{})
+	close(release)
+	provider := &blockingProvider{release: release, progress: "fetched 40 of 200 pages"}
+	msgBus := bus.NewMessageBus()
+	sm := NewSubagentManager(provider, "test-model", t.TempDir(), msgBus)
+
+	if _, err := sm.Spawn(context.Background(), "crawl", "crawl", "", "telegram", "c1", nil); err != nil {
+		t.Fatal(err)
+	}
+	taskID := sm.ListTasks()[0].ID
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, ok := msgBus.SubscribeOutbound(ctx)
+	if !ok {
+		t.Fatal("no progress message")
+	}
+	if out.Channel != "telegram" || out.ChatID != "c1" || !strings.Contains(out.Content, "crawl: fetched 40 of 200 pages") {
+		t.Errorf("progress message = %+v", out)
+	}
+	if task := waitForStatus(t, sm, taskID, "completed"); task.Progress != "fetched 40 of 200 pages" || task.Iterations != 2 {
+		t.Errorf("task = %+v", task)
+	}
+
+	if result := NewProgressTool(sm).Execute(context.Background(), map[string]interface{}{"progress": "x"}); !result.IsError {
+		t.Error("expected report_progress outside a task to fail")
+	}
+}
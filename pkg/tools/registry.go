@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -11,8 +12,17 @@ import (
 )
 
 type ToolRegistry struct {
-	tools map[string]Tool
-	mu    sync.RWMutex
+	tools    map[string]Tool
+	guard    *InjectionGuard
+	approver Approver
+	allowed  map[string]bool // if not nil, the only tools kept
+	mu       sync.RWMutex
+}
+
+// Approver decides whether a tool call may run. Approve blocks until the
+// call is approved (nil) or denied (an error giving the reason).
+type Approver interface {
+	Approve(ctx context.Context, tool string, args map[string]interface{}, channel, chatID string) error
 }
 
 func NewToolRegistry() *ToolRegistry {
@@ -24,9 +34,48 @@ func NewToolRegistry() *ToolRegistry {
 func (r *ToolRegistry) Register(tool Tool) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	if r.allowed != nil && !r.allowed[tool.Name()] {
+		return
+	}
 	r.tools[tool.Name()] = tool
 }
 
+// Allow restricts the registry to the named tools: those registered under
+// other names are removed, and registering them later does nothing. An
+// empty list allows every tool.
+func (r *ToolRegistry) Allow(names []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(names) == 0 {
+		r.allowed = nil
+		return
+	}
+	r.allowed = make(map[string]bool, len(names))
+	for _, name := range names {
+		r.allowed[name] = true
+	}
+	for name := range r.tools {
+		if !r.allowed[name] {
+			delete(r.tools, name)
+		}
+	}
+}
+
+// SetInjectionGuard installs a guard that scans every tool result for
+// prompt injection patterns before it is returned to the agent loop.
+func (r *ToolRegistry) SetInjectionGuard(guard *InjectionGuard) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.guard = guard
+}
+
+// SetApprover installs an approver consulted before every tool call.
+func (r *ToolRegistry) SetApprover(approver Approver) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.approver = approver
+}
+
 func (r *ToolRegistry) Get(name string) (Tool, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -43,20 +92,34 @@ func (r *ToolRegistry) Execute(ctx context.Context, name string, args map[string
 // the callback will be set on the tool before execution.
 func (r *ToolRegistry) ExecuteWithContext(ctx context.Context, name string, args map[string]interface{}, channel, chatID string, asyncCallback AsyncCallback) *ToolResult {
 	logger.InfoCF("tool", "Tool execution started",
-		map[string]interface{}{
+		logger.TraceFields(ctx, map[string]interface{}{
 			"tool": name,
 			"args": args,
-		})
+		}))
 
 	tool, ok := r.Get(name)
 	if !ok {
 		logger.ErrorCF("tool", "Tool not found",
-			map[string]interface{}{
+			logger.TraceFields(ctx, map[string]interface{}{
 				"tool": name,
-			})
+			}))
 		return ErrorResult(fmt.Sprintf("tool %q not found", name)).WithError(fmt.Errorf("tool not found"))
 	}
 
+	r.mu.RLock()
+	approver := r.approver
+	r.mu.RUnlock()
+	if approver != nil {
+		if err := approver.Approve(ctx, name, args, channel, chatID); err != nil {
+			logger.WarnCF("tool", "Tool execution not approved",
+				logger.TraceFields(ctx, map[string]interface{}{
+					"tool":   name,
+					"reason": err.Error(),
+				}))
+			return ErrorResult(fmt.Sprintf("tool %q was not approved: %v", name, err)).WithError(err)
+		}
+	}
+
 	// If tool implements ContextualTool, set context
 	if contextualTool, ok := tool.(ContextualTool); ok && channel != "" && chatID != "" {
 		contextualTool.SetContext(channel, chatID)
@@ -66,36 +129,41 @@ func (r *ToolRegistry) ExecuteWithContext(ctx context.Context, name string, args
 	if asyncTool, ok := tool.(AsyncTool); ok && asyncCallback != nil {
 		asyncTool.SetCallback(asyncCallback)
 		logger.DebugCF("tool", "Async callback injected",
-			map[string]interface{}{
+			logger.TraceFields(ctx, map[string]interface{}{
 				"tool": name,
-			})
+			}))
 	}
 
 	start := time.Now()
 	result := tool.Execute(ctx, args)
 	duration := time.Since(start)
 
+	r.mu.RLock()
+	guard := r.guard
+	r.mu.RUnlock()
+	result = guard.Apply(name, result)
+
 	// Log based on result type
 	if result.IsError {
 		logger.ErrorCF("tool", "Tool execution failed",
-			map[string]interface{}{
+			logger.TraceFields(ctx, map[string]interface{}{
 				"tool":     name,
 				"duration": duration.Milliseconds(),
 				"error":    result.ForLLM,
-			})
+			}))
 	} else if result.Async {
 		logger.InfoCF("tool", "Tool started (async)",
-			map[string]interface{}{
+			logger.TraceFields(ctx, map[string]interface{}{
 				"tool":     name,
 				"duration": duration.Milliseconds(),
-			})
+			}))
 	} else {
 		logger.InfoCF("tool", "Tool execution completed",
-			map[string]interface{}{
+			logger.TraceFields(ctx, map[string]interface{}{
 				"tool":          name,
 				"duration_ms":   duration.Milliseconds(),
 				"result_length": len(result.ForLLM),
-			})
+			}))
 	}
 
 	return result
@@ -106,7 +174,7 @@ func (r *ToolRegistry) GetDefinitions() []map[string]interface{} {
 	defer r.mu.RUnlock()
 
 	definitions := make([]map[string]interface{}, 0, len(r.tools))
-	for _, tool := range r.tools {
+	for _, tool := range r.sorted() {
 		definitions = append(definitions, ToolToSchema(tool))
 	}
 	return definitions
@@ -119,7 +187,7 @@ func (r *ToolRegistry) ToProviderDefs() []providers.ToolDefinition {
 	defer r.mu.RUnlock()
 
 	definitions := make([]providers.ToolDefinition, 0, len(r.tools))
-	for _, tool := range r.tools {
+	for _, tool := range r.sorted() {
 		schema := ToolToSchema(tool)
 
 		// Safely extract nested values with type checks
@@ -144,7 +212,19 @@ func (r *ToolRegistry) ToProviderDefs() []providers.ToolDefinition {
 	return definitions
 }
 
-// List returns a list of all registered tool names.
+// sorted returns the registered tools ordered by name, so that the tools
+// are described to the model the same way on every request. The caller
+// holds r.mu.
+func (r *ToolRegistry) sorted() []Tool {
+	list := make([]Tool, 0, len(r.tools))
+	for _, tool := range r.tools {
+		list = append(list, tool)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+	return list
+}
+
+// List returns the names of all registered tools, sorted.
 func (r *ToolRegistry) List() []string {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -153,6 +233,7 @@ func (r *ToolRegistry) List() []string {
 	for name := range r.tools {
 		names = append(names, name)
 	}
+	sort.Strings(names)
 	return names
 }
 
@@ -170,7 +251,7 @@ func (r *ToolRegistry) GetSummaries() []string {
 	defer r.mu.RUnlock()
 
 	summaries := make([]string, 0, len(r.tools))
-	for _, tool := range r.tools {
+	for _, tool := range r.sorted() {
 		summaries = append(summaries, fmt.Sprintf("- `%s` - %s", tool.Name(), tool.Description()))
 	}
 	return summaries
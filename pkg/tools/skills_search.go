@@ -28,6 +28,11 @@ func (t *FindSkillsTool) Name() string {
 	return "find_skills"
 }
 
+// ReadOnly implements ReadOnlyTool.
+func (t *FindSkillsTool) ReadOnly() bool {
+	return true
+}
+
 func (t *FindSkillsTool) Description() string {
 	return "Search for installable skills from skill registries. Returns skill slugs, descriptions, versions, and relevance scores. Use this to discover skills before installing them with install_skill."
 }
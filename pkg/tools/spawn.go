@@ -50,6 +50,7 @@ func (t *SpawnTool) Parameters() map[string]interface{} {
 				"type":        "string",
 				"description": "Optional target agent ID to delegate the task to",
 			},
+			"result_schema": resultSchemaParameter,
 		},
 		"required": []string{"task"},
 	}
@@ -72,6 +73,7 @@ func (t *SpawnTool) Execute(ctx context.Context, args map[string]interface{}) *T
 
 	label, _ := args["label"].(string)
 	agentID, _ := args["agent_id"].(string)
+	schema, _ := args["result_schema"].(map[string]interface{})
 
 	// Check allowlist if targeting a specific agent
 	if agentID != "" && t.allowlistCheck != nil {
@@ -85,7 +87,7 @@ func (t *SpawnTool) Execute(ctx context.Context, args map[string]interface{}) *T
 	}
 
 	// Pass callback to manager for async completion notification
-	result, err := t.manager.Spawn(ctx, task, label, agentID, t.originChannel, t.originChatID, t.callback)
+	result, err := t.manager.SpawnStructured(ctx, task, label, agentID, t.originChannel, t.originChatID, schema, t.callback)
 	if err != nil {
 		return ErrorResult(fmt.Sprintf("failed to spawn subagent: %v", err))
 	}
@@ -3,10 +3,13 @@ package tools
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/crash"
 	"github.com/sipeed/picoclaw/pkg/providers"
 )
 
@@ -17,11 +20,29 @@ type SubagentTask struct {
 	AgentID       string
 	OriginChannel string
 	OriginChatID  string
-	Status        string
+	Status        string // queued, running, completed, failed or cancelled
 	Result        string
 	Created       int64
+	// Progress is the last progress the task reported with report_progress,
+	// and Iterations the number of model calls it has made so far.
+	Progress   string
+	Iterations int
+	Updated    int64
+	// ResultSchema, if set, is the JSON schema the result matches.
+	ResultSchema map[string]interface{}
+
+	seq    int
+	cancel context.CancelFunc
 }
 
+// taskSeq numbers tasks across all managers, so task IDs are unique
+// among agents.
+var taskSeq atomic.Int64
+
+// defaultMaxRunning is how many background tasks run at once unless
+// SetMaxRunning says otherwise; later tasks queue.
+const defaultMaxRunning = 2
+
 type SubagentManager struct {
 	tasks          map[string]*SubagentTask
 	mu             sync.RWMutex
@@ -35,11 +56,11 @@ type SubagentManager struct {
 	temperature    float64
 	hasMaxTokens   bool
 	hasTemperature bool
-	nextID         int
+	slots          chan struct{}
 }
 
 func NewSubagentManager(provider providers.LLMProvider, defaultModel, workspace string, bus *bus.MessageBus) *SubagentManager {
-	return &SubagentManager{
+	sm := &SubagentManager{
 		tasks:         make(map[string]*SubagentTask),
 		provider:      provider,
 		defaultModel:  defaultModel,
@@ -47,8 +68,21 @@ func NewSubagentManager(provider providers.LLMProvider, defaultModel, workspace
 		workspace:     workspace,
 		tools:         NewToolRegistry(),
 		maxIterations: 10,
-		nextID:        1,
+		slots:         make(chan struct{}, defaultMaxRunning),
+	}
+	sm.tools.Register(NewProgressTool(sm))
+	return sm
+}
+
+// SetMaxRunning sets how many tasks run at once; tasks spawned beyond that
+// are queued until one finishes. It must be called before the first Spawn.
+func (sm *SubagentManager) SetMaxRunning(n int) {
+	if n <= 0 {
+		n = defaultMaxRunning
 	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.slots = make(chan struct{}, n)
 }
 
 // SetLLMOptions sets max tokens and temperature for subagent LLM calls.
@@ -63,9 +97,11 @@ func (sm *SubagentManager) SetLLMOptions(maxTokens int, temperature float64) {
 
 // SetTools sets the tool registry for subagent execution.
 // If not set, subagent will have access to the provided tools.
+// report_progress is added to it.
 func (sm *SubagentManager) SetTools(tools *ToolRegistry) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
+	tools.Register(NewProgressTool(sm))
 	sm.tools = tools
 }
 
@@ -77,12 +113,22 @@ func (sm *SubagentManager) RegisterTool(tool Tool) {
 }
 
 func (sm *SubagentManager) Spawn(ctx context.Context, task, label, agentID, originChannel, originChatID string, callback AsyncCallback) (string, error) {
+	return sm.SpawnStructured(ctx, task, label, agentID, originChannel, originChatID, nil, callback)
+}
+
+// SpawnStructured spawns a task whose result is JSON matching
+// resultSchema, or free text if it is nil.
+func (sm *SubagentManager) SpawnStructured(ctx context.Context, task, label, agentID, originChannel, originChatID string, resultSchema map[string]interface{}, callback AsyncCallback) (string, error) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
-	taskID := fmt.Sprintf("subagent-%d", sm.nextID)
-	sm.nextID++
+	seq := int(taskSeq.Add(1))
+	taskID := fmt.Sprintf("subagent-%d", seq)
 
+	// The task outlives the turn that spawned it; it stops when the agent
+	// shuts down or the task is cancelled. Its requests yield to
+	// interactive turns.
+	taskCtx, cancel := context.WithCancel(providers.WithPriority(ctx, providers.PriorityBackground))
 	subagentTask := &SubagentTask{
 		ID:            taskID,
 		Task:          task,
@@ -90,13 +136,17 @@ func (sm *SubagentManager) Spawn(ctx context.Context, task, label, agentID, orig
 		AgentID:       agentID,
 		OriginChannel: originChannel,
 		OriginChatID:  originChatID,
-		Status:        "running",
+		Status:        "queued",
 		Created:       time.Now().UnixMilli(),
+		ResultSchema:  resultSchema,
+		seq:           seq,
+		cancel:        cancel,
 	}
+	subagentTask.Updated = subagentTask.Created
 	sm.tasks[taskID] = subagentTask
 
 	// Start task in background with context cancellation support
-	go sm.runTask(ctx, subagentTask, callback)
+	go sm.runTask(taskCtx, subagentTask, sm.slots, callback)
 
 	if label != "" {
 		return fmt.Sprintf("Spawned subagent '%s' for task: %s", label, task), nil
@@ -104,9 +154,26 @@ func (sm *SubagentManager) Spawn(ctx context.Context, task, label, agentID, orig
 	return fmt.Sprintf("Spawned subagent for task: %s", task), nil
 }
 
-func (sm *SubagentManager) runTask(ctx context.Context, task *SubagentTask, callback AsyncCallback) {
+func (sm *SubagentManager) runTask(ctx context.Context, task *SubagentTask, slots chan struct{}, callback AsyncCallback) {
+	defer crash.Recover("subagent", map[string]interface{}{"task_id": task.ID})
+	defer task.cancel()
+
+	// Wait for a free slot
+	select {
+	case slots <- struct{}{}:
+		defer func() { <-slots }()
+	case <-ctx.Done():
+		sm.mu.Lock()
+		task.Status = "cancelled"
+		task.Result = "Task cancelled before execution"
+		task.Updated = time.Now().UnixMilli()
+		sm.mu.Unlock()
+		return
+	}
+	sm.mu.Lock()
 	task.Status = "running"
-	task.Created = time.Now().UnixMilli()
+	task.Updated = time.Now().UnixMilli()
+	sm.mu.Unlock()
 
 	// Build system prompt for subagent
 	systemPrompt := `You are a subagent. Complete the given task independently and report the result.
@@ -130,6 +197,7 @@ After completing the task, provide a clear summary of what was done.`
 		sm.mu.Lock()
 		task.Status = "cancelled"
 		task.Result = "Task cancelled before execution"
+		task.Updated = time.Now().UnixMilli()
 		sm.mu.Unlock()
 		return
 	default:
@@ -156,15 +224,23 @@ After completing the task, provide a clear summary of what was done.`
 		}
 	}
 
-	loopResult, err := RunToolLoop(ctx, ToolLoopConfig{
+	loopResult, err := RunToolLoop(withTaskID(ctx, task.ID), ToolLoopConfig{
 		Provider:      sm.provider,
 		Model:         sm.defaultModel,
 		Tools:         tools,
 		MaxIterations: maxIter,
 		LLMOptions:    llmOptions,
+		OnIteration: func(iteration int) {
+			sm.mu.Lock()
+			task.Iterations = iteration
+			task.Updated = time.Now().UnixMilli()
+			sm.mu.Unlock()
+		},
+		ResultSchema: resultSchema(task.ResultSchema),
 	}, messages, task.OriginChannel, task.OriginChatID)
 
 	sm.mu.Lock()
+	task.Updated = time.Now().UnixMilli()
 	var result *ToolResult
 	defer func() {
 		sm.mu.Unlock()
@@ -202,9 +278,10 @@ After completing the task, provide a clear summary of what was done.`
 		}
 	}
 
-	// Send announce message back to main agent
-	if sm.bus != nil {
-		announceContent := fmt.Sprintf("Task '%s' completed.\n\nResult:\n%s", task.Label, task.Result)
+	// Send announce message back to main agent. Whoever cancelled the task
+	// already knows.
+	if sm.bus != nil && task.Status != "cancelled" {
+		announceContent := fmt.Sprintf("Task '%s' %s.\n\nResult:\n%s", task.Label, task.Status, task.Result)
 		sm.bus.PublishInbound(bus.InboundMessage{
 			Channel:  "system",
 			SenderID: fmt.Sprintf("subagent:%s", task.ID),
@@ -215,24 +292,75 @@ After completing the task, provide a clear summary of what was done.`
 	}
 }
 
+// GetTask returns a snapshot of the task.
 func (sm *SubagentManager) GetTask(taskID string) (*SubagentTask, bool) {
 	sm.mu.RLock()
 	defer sm.mu.RUnlock()
 	task, ok := sm.tasks[taskID]
-	return task, ok
+	if !ok {
+		return nil, false
+	}
+	snapshot := *task
+	return &snapshot, true
 }
 
+// ListTasks returns snapshots of all tasks, oldest first.
 func (sm *SubagentManager) ListTasks() []*SubagentTask {
 	sm.mu.RLock()
 	defer sm.mu.RUnlock()
 
 	tasks := make([]*SubagentTask, 0, len(sm.tasks))
 	for _, task := range sm.tasks {
-		tasks = append(tasks, task)
+		snapshot := *task
+		tasks = append(tasks, &snapshot)
 	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].seq < tasks[j].seq })
 	return tasks
 }
 
+// Cancel stops a queued or running task.
+func (sm *SubagentManager) Cancel(taskID string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	task, ok := sm.tasks[taskID]
+	if !ok {
+		return fmt.Errorf("no task %s", taskID)
+	}
+	if task.Status != "queued" && task.Status != "running" {
+		return fmt.Errorf("task %s already %s", taskID, task.Status)
+	}
+	task.cancel()
+	return nil
+}
+
+// reportProgress records progress of a task and sends it to the chat the
+// task was spawned from.
+func (sm *SubagentManager) reportProgress(taskID, progress string) error {
+	sm.mu.Lock()
+	task, ok := sm.tasks[taskID]
+	if !ok {
+		sm.mu.Unlock()
+		return fmt.Errorf("no task %s", taskID)
+	}
+	task.Progress = progress
+	task.Updated = time.Now().UnixMilli()
+	name := task.Label
+	if name == "" {
+		name = task.ID
+	}
+	channel, chatID := task.OriginChannel, task.OriginChatID
+	sm.mu.Unlock()
+
+	if sm.bus != nil && channel != "" && chatID != "" {
+		sm.bus.PublishOutbound(bus.OutboundMessage{
+			Channel: channel,
+			ChatID:  chatID,
+			Content: fmt.Sprintf("⏳ %s: %s", name, progress),
+		})
+	}
+	return nil
+}
+
 // SubagentTool executes a subagent task synchronously and returns the result.
 // Unlike SpawnTool which runs tasks asynchronously, SubagentTool waits for completion
 // and returns the result directly in the ToolResult.
@@ -270,6 +398,7 @@ func (t *SubagentTool) Parameters() map[string]interface{} {
 				"type":        "string",
 				"description": "Optional short label for the task (for display)",
 			},
+			"result_schema": resultSchemaParameter,
 		},
 		"required": []string{"task"},
 	}
@@ -287,6 +416,7 @@ func (t *SubagentTool) Execute(ctx context.Context, args map[string]interface{})
 	}
 
 	label, _ := args["label"].(string)
+	schema, _ := args["result_schema"].(map[string]interface{})
 
 	if t.manager == nil {
 		return ErrorResult("Subagent manager not configured").WithError(fmt.Errorf("manager is nil"))
@@ -332,6 +462,7 @@ func (t *SubagentTool) Execute(ctx context.Context, args map[string]interface{})
 		Tools:         tools,
 		MaxIterations: maxIter,
 		LLMOptions:    llmOptions,
+		ResultSchema:  resultSchema(schema),
 	}, messages, t.originChannel, t.originChatID)
 	if err != nil {
 		return ErrorResult(fmt.Sprintf("Subagent execution failed: %v", err)).WithError(err)
@@ -360,3 +491,16 @@ func (t *SubagentTool) Execute(ctx context.Context, args map[string]interface{})
 		Async:   false,
 	}
 }
+
+// resultSchemaParameter lets the model ask a subagent for a typed result.
+var resultSchemaParameter = map[string]interface{}{
+	"type":        "object",
+	"description": "Optional JSON schema of the result; the subagent then returns JSON matching it",
+}
+
+func resultSchema(schema map[string]interface{}) *providers.Schema {
+	if len(schema) == 0 {
+		return nil
+	}
+	return &providers.Schema{Name: "task_result", Description: "The result of the task", Schema: schema}
+}
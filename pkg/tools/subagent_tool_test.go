@@ -342,3 +342,38 @@ func TestSubagentTool_ForUserTruncation(t *testing.T) {
 		t.Error("ForLLM should contain reference to original task")
 	}
 }
+
+// scriptedProvider replies with the given contents in turn.
+type scriptedProvider struct {
+	replies []string
+	calls   int
+}
+
+func (p *scriptedProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, options map[string]interface{}) (*providers.LLMResponse, error) {
+	reply := p.replies[p.calls]
+	p.calls++
+	return &providers.LLMResponse{Content: reply}, nil
+}
+
+func (p *scriptedProvider) GetDefaultModel() string { return "test-model" }
+
+func TestSubagentTool_ResultSchema(t *testing.T) {
+	provider := &scriptedProvider{replies: []string{"I found 3 broken links.", `{"broken": 3}`}}
+	manager := NewSubagentManager(provider, "test-model", "/tmp/test", nil)
+	tool := NewSubagentTool(manager)
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"task": "Check the links",
+		"result_schema": map[string]interface{}{
+			"type":       "object",
+			"required":   []interface{}{"broken"},
+			"properties": map[string]interface{}{"broken": map[string]interface{}{"type": "integer"}},
+		},
+	})
+	if result.IsError {
+		t.Fatalf("Execute() error: %s", result.ForLLM)
+	}
+	if result.ForUser != `{"broken": 3}` || provider.calls != 2 {
+		t.Errorf("result = %q after %d calls", result.ForUser, provider.calls)
+	}
+}
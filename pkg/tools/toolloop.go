@@ -23,6 +23,10 @@ type ToolLoopConfig struct {
 	Tools         *ToolRegistry
 	MaxIterations int
 	LLMOptions    map[string]any
+	// OnIteration, if set, is called before each model call.
+	OnIteration func(iteration int)
+	// ResultSchema, if set, makes the result a JSON value matching it.
+	ResultSchema *providers.Schema
 }
 
 // ToolLoopResult contains the result of running the tool loop.
@@ -37,8 +41,18 @@ func RunToolLoop(ctx context.Context, config ToolLoopConfig, messages []provider
 	iteration := 0
 	var finalContent string
 
+	if config.ResultSchema != nil {
+		messages = append(messages, providers.Message{
+			Role:    "system",
+			Content: "When the task is done, give the result as JSON matching the schema below.\n\n" + providers.StructuredInstructions(*config.ResultSchema),
+		})
+	}
+
 	for iteration < config.MaxIterations {
 		iteration++
+		if config.OnIteration != nil {
+			config.OnIteration(iteration)
+		}
 
 		logger.DebugCF("toolloop", "LLM iteration",
 			map[string]any{
@@ -71,6 +85,13 @@ func RunToolLoop(ctx context.Context, config ToolLoopConfig, messages []provider
 		// 4. If no tool calls, we're done
 		if len(response.ToolCalls) == 0 {
 			finalContent = response.Content
+			if config.ResultSchema != nil {
+				result, err := structuredResult(ctx, config, messages, response.Content, llmOpts)
+				if err != nil {
+					return nil, err
+				}
+				finalContent = result
+			}
 			logger.InfoCF("toolloop", "LLM response without tool calls (direct answer)",
 				map[string]any{
 					"iteration":     iteration,
@@ -155,3 +176,20 @@ func RunToolLoop(ctx context.Context, config ToolLoopConfig, messages []provider
 		Iterations: iteration,
 	}, nil
 }
+
+// structuredResult returns the final answer as JSON matching the result
+// schema, asking the model again if it does not match.
+func structuredResult(ctx context.Context, config ToolLoopConfig, messages []providers.Message, answer string, llmOpts map[string]any) (string, error) {
+	if result, err := providers.ParseStructured(answer, config.ResultSchema.Schema); err == nil {
+		return string(result), nil
+	}
+	messages = append(messages,
+		providers.Message{Role: "assistant", Content: answer},
+		providers.Message{Role: "user", Content: "Give the result of the task as JSON."},
+	)
+	result, _, err := providers.ChatStructured(ctx, config.Provider, messages, config.Model, llmOpts, *config.ResultSchema)
+	if err != nil {
+		return "", fmt.Errorf("structured result: %w", err)
+	}
+	return string(result), nil
+}
@@ -10,6 +10,9 @@ import (
 	"regexp"
 	"strings"
 	"time"
+
+	"github.com/sipeed/picoclaw/pkg/egress"
+	"github.com/sipeed/picoclaw/pkg/providers/httpwarm"
 )
 
 const (
@@ -20,8 +23,26 @@ type SearchProvider interface {
 	Search(ctx context.Context, query string, count int) (string, error)
 }
 
+// newSearchClient returns the HTTP client of a search provider. Providers
+// keep their client, so that a connection opened by a prewarm is reused.
+func newSearchClient(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: egress.Transport(egress.Tools, nil)}
+}
+
 type BraveSearchProvider struct {
 	apiKey string
+	client *http.Client
+}
+
+func (p *BraveSearchProvider) warm(ctx context.Context) error {
+	return httpwarm.Warm(ctx, p.httpClient(), "https://api.search.brave.com")
+}
+
+func (p *BraveSearchProvider) httpClient() *http.Client {
+	if p.client != nil {
+		return p.client
+	}
+	return newSearchClient(10 * time.Second)
 }
 
 func (p *BraveSearchProvider) Search(ctx context.Context, query string, count int) (string, error) {
@@ -36,8 +57,7 @@ func (p *BraveSearchProvider) Search(ctx context.Context, query string, count in
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("X-Subscription-Token", p.apiKey)
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := p.httpClient().Do(req)
 	if err != nil {
 		return "", fmt.Errorf("request failed: %w", err)
 	}
@@ -84,7 +104,20 @@ func (p *BraveSearchProvider) Search(ctx context.Context, query string, count in
 	return strings.Join(lines, "\n"), nil
 }
 
-type DuckDuckGoSearchProvider struct{}
+type DuckDuckGoSearchProvider struct {
+	client *http.Client
+}
+
+func (p *DuckDuckGoSearchProvider) warm(ctx context.Context) error {
+	return httpwarm.Warm(ctx, p.httpClient(), "https://html.duckduckgo.com")
+}
+
+func (p *DuckDuckGoSearchProvider) httpClient() *http.Client {
+	if p.client != nil {
+		return p.client
+	}
+	return newSearchClient(10 * time.Second)
+}
 
 func (p *DuckDuckGoSearchProvider) Search(ctx context.Context, query string, count int) (string, error) {
 	searchURL := fmt.Sprintf("https://html.duckduckgo.com/html/?q=%s", url.QueryEscape(query))
@@ -96,8 +129,7 @@ func (p *DuckDuckGoSearchProvider) Search(ctx context.Context, query string, cou
 
 	req.Header.Set("User-Agent", userAgent)
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := p.httpClient().Do(req)
 	if err != nil {
 		return "", fmt.Errorf("request failed: %w", err)
 	}
@@ -178,6 +210,18 @@ func stripTags(content string) string {
 
 type PerplexitySearchProvider struct {
 	apiKey string
+	client *http.Client
+}
+
+func (p *PerplexitySearchProvider) warm(ctx context.Context) error {
+	return httpwarm.Warm(ctx, p.httpClient(), "https://api.perplexity.ai")
+}
+
+func (p *PerplexitySearchProvider) httpClient() *http.Client {
+	if p.client != nil {
+		return p.client
+	}
+	return newSearchClient(30 * time.Second)
 }
 
 func (p *PerplexitySearchProvider) Search(ctx context.Context, query string, count int) (string, error) {
@@ -206,8 +250,7 @@ func (p *PerplexitySearchProvider) Search(ctx context.Context, query string, cou
 	req.Header.Set("Authorization", "Bearer "+p.apiKey)
 	req.Header.Set("User-Agent", userAgent)
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := p.httpClient().Do(req)
 	if err != nil {
 		return "", fmt.Errorf("request failed: %w", err)
 	}
@@ -263,17 +306,17 @@ func NewWebSearchTool(opts WebSearchToolOptions) *WebSearchTool {
 
 	// Priority: Perplexity > Brave > DuckDuckGo
 	if opts.PerplexityEnabled && opts.PerplexityAPIKey != "" {
-		provider = &PerplexitySearchProvider{apiKey: opts.PerplexityAPIKey}
+		provider = &PerplexitySearchProvider{apiKey: opts.PerplexityAPIKey, client: newSearchClient(30 * time.Second)}
 		if opts.PerplexityMaxResults > 0 {
 			maxResults = opts.PerplexityMaxResults
 		}
 	} else if opts.BraveEnabled && opts.BraveAPIKey != "" {
-		provider = &BraveSearchProvider{apiKey: opts.BraveAPIKey}
+		provider = &BraveSearchProvider{apiKey: opts.BraveAPIKey, client: newSearchClient(10 * time.Second)}
 		if opts.BraveMaxResults > 0 {
 			maxResults = opts.BraveMaxResults
 		}
 	} else if opts.DuckDuckGoEnabled {
-		provider = &DuckDuckGoSearchProvider{}
+		provider = &DuckDuckGoSearchProvider{client: newSearchClient(10 * time.Second)}
 		if opts.DuckDuckGoMaxResults > 0 {
 			maxResults = opts.DuckDuckGoMaxResults
 		}
@@ -291,6 +334,11 @@ func (t *WebSearchTool) Name() string {
 	return "web_search"
 }
 
+// ReadOnly implements ReadOnlyTool.
+func (t *WebSearchTool) ReadOnly() bool {
+	return true
+}
+
 func (t *WebSearchTool) Description() string {
 	return "Search the web for current information. Returns titles, URLs, and snippets from search results."
 }
@@ -314,6 +362,14 @@ func (t *WebSearchTool) Parameters() map[string]interface{} {
 	}
 }
 
+// Prewarm opens a connection to the search backend.
+func (t *WebSearchTool) Prewarm(ctx context.Context) error {
+	if w, ok := t.provider.(interface{ warm(context.Context) error }); ok {
+		return w.warm(ctx)
+	}
+	return nil
+}
+
 func (t *WebSearchTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
 	query, ok := args["query"].(string)
 	if !ok {
@@ -355,6 +411,11 @@ func (t *WebFetchTool) Name() string {
 	return "web_fetch"
 }
 
+// ReadOnly implements ReadOnlyTool.
+func (t *WebFetchTool) ReadOnly() bool {
+	return true
+}
+
 func (t *WebFetchTool) Description() string {
 	return "Fetch a URL and extract readable content (HTML to text). Use this to get weather info, news, articles, or any web content."
 }
@@ -412,12 +473,12 @@ func (t *WebFetchTool) Execute(ctx context.Context, args map[string]interface{})
 
 	client := &http.Client{
 		Timeout: 60 * time.Second,
-		Transport: &http.Transport{
+		Transport: egress.Transport(egress.Tools, &http.Transport{
 			MaxIdleConns:        10,
 			IdleConnTimeout:     30 * time.Second,
 			DisableCompression:  false,
 			TLSHandshakeTimeout: 15 * time.Second,
-		},
+		}),
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			if len(via) >= 5 {
 				return fmt.Errorf("stopped after 5 redirects")
@@ -0,0 +1,122 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+package transcript
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// Load reads the entries of a transcript file, in the order they were
+// recorded.
+func Load(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// Replay is a provider that answers each request with what was recorded
+// for the same request: the model, messages and tools, compared after
+// redaction, since transcripts keep only the redacted form. A request made
+// several times gets the recorded answers in order, and the last one after
+// that. Requests that were not recorded fail, which is how a replayed test
+// notices that the prompts changed.
+type Replay struct {
+	redact *Redactor
+	model  string
+
+	mu      sync.Mutex
+	answers map[string][]*Entry
+}
+
+// NewReplay returns a provider replaying entries, redacting requests with
+// the same extra patterns they were recorded with.
+func NewReplay(entries []Entry, redact []string) (*Replay, error) {
+	r, err := NewRedactor(redact)
+	if err != nil {
+		return nil, err
+	}
+	p := &Replay{redact: r, answers: make(map[string][]*Entry)}
+	for i := range entries {
+		e := &entries[i]
+		if e.Response == nil && e.Error == "" {
+			continue
+		}
+		key, err := p.key(e.Model, e.Messages, e.Tools)
+		if err != nil {
+			return nil, err
+		}
+		p.answers[key] = append(p.answers[key], e)
+		if p.model == "" {
+			p.model = e.Model
+		}
+	}
+	return p, nil
+}
+
+func (p *Replay) key(model string, messages []providers.Message, tools []providers.ToolDefinition) (string, error) {
+	data, err := p.redact.redactEntry(&Entry{Model: model, Messages: messages, Tools: tools})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return string(sum[:]), nil
+}
+
+func (p *Replay) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, options map[string]interface{}) (*providers.LLMResponse, error) {
+	key, err := p.key(model, messages, tools)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	answers := p.answers[key]
+	if len(answers) == 0 {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("transcript replay: no recorded response for this request to %s (%d messages)", model, len(messages))
+	}
+	e := answers[0]
+	if len(answers) > 1 {
+		p.answers[key] = answers[1:]
+	}
+	p.mu.Unlock()
+
+	if e.Error != "" {
+		return nil, errors.New(e.Error)
+	}
+	resp := *e.Response
+	return &resp, nil
+}
+
+// GetDefaultModel returns the model of the first recorded request.
+func (p *Replay) GetDefaultModel() string {
+	return p.model
+}
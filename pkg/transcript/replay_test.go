@@ -0,0 +1,69 @@
+package transcript
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+func TestReplay_AnswersRecordedRequests(t *testing.T) {
+	dir := t.TempDir()
+	rec, err := NewRecorder(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	hello := []providers.Message{{Role: "user", Content: "Hello, my key is sk-abcdefghijklmnopqrstuvwx"}}
+	tools := []providers.ToolDefinition{{Type: "function", Function: providers.ToolFunctionDefinition{
+		Name:       "read_file",
+		Parameters: map[string]interface{}{"type": "object"},
+	}}}
+	weather := []providers.Message{{Role: "user", Content: "Weather?"}}
+	toolCall := []providers.ToolCall{{ID: "call_1", Name: "read_file", Arguments: map[string]interface{}{"path": "a.txt"}}}
+
+	for _, e := range []Entry{
+		{Time: at, Model: "gpt-4o", Messages: hello, Tools: tools, Response: &providers.LLMResponse{Content: "Hi", ToolCalls: toolCall}},
+		{Time: at, Model: "gpt-4o", Messages: hello, Tools: tools, Response: &providers.LLMResponse{Content: "Hi again"}},
+		{Time: at, Model: "gpt-4o", Messages: weather, Error: "rate limited"},
+	} {
+		if err := rec.Record(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := Load(filepath.Join(dir, "2026-03-01.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay, err := NewReplay(entries, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := replay.GetDefaultModel(); got != "gpt-4o" {
+		t.Errorf("GetDefaultModel() = %q", got)
+	}
+
+	// The request is sent unredacted, as the agent would.
+	for _, want := range []string{"Hi", "Hi again", "Hi again"} {
+		resp, err := replay.Chat(t.Context(), hello, tools, "gpt-4o", nil)
+		if err != nil {
+			t.Fatalf("Chat() error: %v", err)
+		}
+		if resp.Content != want {
+			t.Errorf("Chat() = %q, want %q", resp.Content, want)
+		}
+	}
+
+	if _, err := replay.Chat(t.Context(), weather, nil, "gpt-4o", nil); err == nil || err.Error() != "rate limited" {
+		t.Errorf("Chat() error = %v, want the recorded error", err)
+	}
+
+	changed := []providers.Message{{Role: "user", Content: "Hello there"}}
+	if _, err := replay.Chat(t.Context(), changed, tools, "gpt-4o", nil); err == nil || !strings.Contains(err.Error(), "no recorded response") {
+		t.Errorf("Chat() error = %v for a request that was not recorded", err)
+	}
+}
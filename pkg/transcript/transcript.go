@@ -0,0 +1,267 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package transcript records the requests the agent makes to its LLM
+// providers, with the responses, as JSON lines in the workspace: one file
+// per day, each line tied to its agent turn by trace ID and to its
+// session. Transcripts show exactly what a model was asked when a reply
+// goes wrong, and Replay answers requests from them, so that a recorded
+// conversation can be run again in a test without the provider.
+//
+// Secrets are redacted before anything is written, and Forget removes or
+// redacts what a session sent. Recording is off by default.
+package transcript
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// Redacted replaces the secrets removed from transcripts.
+const Redacted = "[REDACTED]"
+
+// fileMu serializes changes to transcript files, so that Forget does not
+// lose entries a Recorder appends while it rewrites a file.
+var fileMu sync.Mutex
+
+// secretPatterns match the credentials most likely to end up in a
+// conversation: provider API keys, access tokens and private keys.
+var secretPatterns = []string{
+	`sk-[A-Za-z0-9_-]{16,}`,                                      // OpenAI, Anthropic, DeepSeek, OpenRouter
+	`AIza[0-9A-Za-z_-]{35}`,                                      // Google
+	`gh[pousr]_[A-Za-z0-9]{36,}`,                                 // GitHub
+	`xox[abposr]-[A-Za-z0-9-]{10,}`,                              // Slack
+	`[0-9]{8,10}:[A-Za-z0-9_-]{35}`,                              // Telegram bot tokens
+	`AKIA[0-9A-Z]{16}`,                                           // AWS access key IDs
+	`(?i)bearer\s+[A-Za-z0-9._~+/-]{16,}=*`,                      // Authorization headers
+	`eyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]+`, // JWTs
+	`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`,
+}
+
+// Entry is one request and its response or error.
+type Entry struct {
+	Time       time.Time                  `json:"time"`
+	TraceID    string                     `json:"trace_id,omitempty"`
+	Session    string                     `json:"session,omitempty"`
+	Model      string                     `json:"model"`
+	Messages   []providers.Message        `json:"messages"`
+	Tools      []providers.ToolDefinition `json:"tools,omitempty"`
+	Options    map[string]interface{}     `json:"options,omitempty"`
+	Response   *providers.LLMResponse     `json:"response,omitempty"`
+	Error      string                     `json:"error,omitempty"`
+	DurationMS int64                      `json:"duration_ms"`
+}
+
+// Dir returns where the transcripts of the workspace are stored.
+func Dir(workspace string) string {
+	return filepath.Join(workspace, "transcripts")
+}
+
+// Redactor removes secrets from text.
+type Redactor struct {
+	patterns []*regexp.Regexp
+}
+
+// NewRedactor returns a redactor for the built-in secret patterns and the
+// regular expressions in extra.
+func NewRedactor(extra []string) (*Redactor, error) {
+	r := &Redactor{}
+	for _, p := range append(append([]string{}, secretPatterns...), extra...) {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", p, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// Redact returns s with the matches of the redactor's patterns replaced.
+func (r *Redactor) Redact(s string) string {
+	for _, re := range r.patterns {
+		s = re.ReplaceAllString(s, Redacted)
+	}
+	return s
+}
+
+// redactEntry returns e encoded as a JSON line with the secrets redacted
+// from all its strings.
+func (r *Redactor) redactEntry(e *Entry) ([]byte, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	data, err = json.Marshal(r.redactValue(v))
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func (r *Redactor) redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return r.Redact(v)
+	case []interface{}:
+		for i := range v {
+			v[i] = r.redactValue(v[i])
+		}
+	case map[string]interface{}:
+		for k := range v {
+			v[k] = r.redactValue(v[k])
+		}
+	}
+	return v
+}
+
+// Recorder appends entries to the transcripts in a directory.
+type Recorder struct {
+	dir    string
+	redact *Redactor
+}
+
+// NewRecorder returns a recorder writing to dir that also redacts the
+// matches of the regular expressions in redact.
+func NewRecorder(dir string, redact []string) (*Recorder, error) {
+	r, err := NewRedactor(redact)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{dir: dir, redact: r}, nil
+}
+
+// Record appends e to the transcript of its day. Transcripts are written
+// in plaintext, so no recorder should be made for an encrypted workspace.
+func (r *Recorder) Record(e Entry) error {
+	line, err := r.redact.redactEntry(&e)
+	if err != nil {
+		return err
+	}
+
+	fileMu.Lock()
+	defer fileMu.Unlock()
+	if err := os.MkdirAll(r.dir, 0700); err != nil {
+		return err
+	}
+	path := filepath.Join(r.dir, e.Time.Format("2006-01-02")+".jsonl")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Forget removes the entries of session from the transcripts in dir if
+// remove is set, as entries are not tied to the turns of a session, and
+// replaces the matches of pattern, which may be nil, in the strings of the
+// other entries of session, or of all entries if everywhere is set. It
+// returns the number of entries removed or changed.
+func Forget(dir, session string, remove bool, pattern *regexp.Regexp, everywhere bool) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	var r *Redactor
+	if pattern != nil {
+		r = &Redactor{patterns: []*regexp.Regexp{pattern}}
+	}
+
+	fileMu.Lock()
+	defer fileMu.Unlock()
+	total := 0
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".jsonl") {
+			continue
+		}
+		n, err := forgetFile(filepath.Join(dir, e.Name()), session, remove, r, everywhere)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// forgetFile does what Forget does for one transcript file, and rewrites
+// it only if an entry was removed or changed.
+func forgetFile(path, session string, remove bool, r *Redactor, everywhere bool) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	var out bytes.Buffer
+	changed := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		data := scanner.Bytes()
+		if len(data) == 0 {
+			continue
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		var v map[string]interface{}
+		if err := dec.Decode(&v); err != nil {
+			f.Close()
+			return 0, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		ours := v["session"] == session
+		if ours && remove {
+			changed++
+			continue
+		}
+		if r != nil && (ours || everywhere) {
+			redacted, err := json.Marshal(r.redactValue(v))
+			if err != nil {
+				f.Close()
+				return 0, err
+			}
+			if !bytes.Equal(redacted, data) {
+				data = redacted
+				changed++
+			}
+		}
+		out.Write(data)
+		out.WriteByte('\n')
+	}
+	err = scanner.Err()
+	f.Close()
+	if err != nil || changed == 0 {
+		return 0, err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, out.Bytes(), 0600); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return 0, err
+	}
+	return changed, nil
+}
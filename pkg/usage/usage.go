@@ -0,0 +1,231 @@
+// Package usage accumulates the tokens used by LLM requests per session and
+// per model, and what they cost.
+package usage
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers/protocoltypes"
+	"github.com/sipeed/picoclaw/pkg/vfs"
+)
+
+// Counts is the usage of a number of requests. Cost is in dollars and is
+// computed with the prices in effect when each request was made.
+// PositiveFeedback and NegativeFeedback count the reactions of users to
+// the replies.
+type Counts struct {
+	Requests         int     `json:"requests"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	CachedTokens     int     `json:"cached_tokens,omitempty"`
+	TotalTokens      int     `json:"total_tokens"`
+	Cost             float64 `json:"cost"`
+	PositiveFeedback int     `json:"positive_feedback,omitempty"`
+	NegativeFeedback int     `json:"negative_feedback,omitempty"`
+}
+
+func (c *Counts) add(u protocoltypes.UsageInfo, cost float64) {
+	c.Requests++
+	c.PromptTokens += u.PromptTokens
+	c.CompletionTokens += u.CompletionTokens
+	c.CachedTokens += u.CachedTokens
+	c.TotalTokens += u.TotalTokens
+	c.Cost += cost
+}
+
+func (c *Counts) addFeedback(score, delta int) {
+	if score > 0 {
+		c.PositiveFeedback = max(c.PositiveFeedback+delta, 0)
+	} else if score < 0 {
+		c.NegativeFeedback = max(c.NegativeFeedback+delta, 0)
+	}
+}
+
+// Report is the usage recorded since Since.
+type Report struct {
+	Since    time.Time         `json:"since"`
+	Updated  time.Time         `json:"updated"`
+	Total    Counts            `json:"total"`
+	Models   map[string]Counts `json:"models"`
+	Sessions map[string]Counts `json:"sessions"`
+}
+
+func newReport() Report {
+	return Report{
+		Since:    time.Now(),
+		Models:   make(map[string]Counts),
+		Sessions: make(map[string]Counts),
+	}
+}
+
+// Tracker records usage in memory and persists it with Save.
+type Tracker struct {
+	path    string
+	pricing map[string]config.ModelPricing
+
+	mu     sync.Mutex
+	report Report
+	dirty  bool
+}
+
+// Path returns where the usage of the workspace is stored.
+func Path(workspace string) string {
+	return filepath.Join(workspace, "state", "usage.json")
+}
+
+// NewTracker returns a tracker that continues the usage stored at path.
+func NewTracker(path string, pricing map[string]config.ModelPricing) *Tracker {
+	report, err := Load(path)
+	if err != nil {
+		report = newReport()
+	}
+	return &Tracker{path: path, pricing: pricing, report: report}
+}
+
+// Load reads the usage stored at path.
+func Load(path string) (Report, error) {
+	data, err := vfs.ReadFile(path)
+	if err != nil {
+		return Report{}, err
+	}
+	report := newReport()
+	if err := json.Unmarshal(data, &report); err != nil {
+		return Report{}, fmt.Errorf("invalid usage file %s: %w", path, err)
+	}
+	if report.Models == nil {
+		report.Models = make(map[string]Counts)
+	}
+	if report.Sessions == nil {
+		report.Sessions = make(map[string]Counts)
+	}
+	return report, nil
+}
+
+// Record adds the usage of one request made for sessionKey with model.
+func (t *Tracker) Record(sessionKey, model string, u protocoltypes.UsageInfo) {
+	if u.TotalTokens == 0 {
+		u.TotalTokens = u.PromptTokens + u.CompletionTokens
+	}
+	cost := t.cost(model, u)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.report.Total.add(u, cost)
+	m := t.report.Models[model]
+	m.add(u, cost)
+	t.report.Models[model] = m
+	if sessionKey != "" {
+		s := t.report.Sessions[sessionKey]
+		s.add(u, cost)
+		t.report.Sessions[sessionKey] = s
+	}
+	t.report.Updated = time.Now()
+	t.dirty = true
+}
+
+// RecordFeedback counts a positive (score > 0) or negative (score < 0)
+// reaction to a reply made for sessionKey with model, or uncounts it when
+// the reaction was removed.
+func (t *Tracker) RecordFeedback(sessionKey, model string, score int, added bool) {
+	delta := 1
+	if !added {
+		delta = -1
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.report.Total.addFeedback(score, delta)
+	m := t.report.Models[model]
+	m.addFeedback(score, delta)
+	t.report.Models[model] = m
+	if sessionKey != "" {
+		s := t.report.Sessions[sessionKey]
+		s.addFeedback(score, delta)
+		t.report.Sessions[sessionKey] = s
+	}
+	t.report.Updated = time.Now()
+	t.dirty = true
+}
+
+// cost prices u by the pricing of model, looked up by its full name and
+// then without a provider prefix.
+func (t *Tracker) cost(model string, u protocoltypes.UsageInfo) float64 {
+	price, ok := t.pricing[model]
+	if !ok {
+		if i := strings.LastIndex(model, "/"); i >= 0 {
+			price, ok = t.pricing[model[i+1:]]
+		}
+	}
+	if !ok {
+		return 0
+	}
+	cachedPrice := price.CachedInput
+	if cachedPrice == 0 {
+		cachedPrice = price.Input
+	}
+	cached := min(u.CachedTokens, u.PromptTokens)
+	return (float64(u.PromptTokens-cached)*price.Input +
+		float64(cached)*cachedPrice +
+		float64(u.CompletionTokens)*price.Output) / 1000
+}
+
+// Report returns a copy of the usage recorded so far.
+func (t *Tracker) Report() Report {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r := t.report
+	r.Models = make(map[string]Counts, len(t.report.Models))
+	for k, v := range t.report.Models {
+		r.Models[k] = v
+	}
+	r.Sessions = make(map[string]Counts, len(t.report.Sessions))
+	for k, v := range t.report.Sessions {
+		r.Sessions[k] = v
+	}
+	return r
+}
+
+// Save writes the usage to disk if it changed since the last save.
+func (t *Tracker) Save() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.dirty {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(t.report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := vfs.MkdirAll(filepath.Dir(t.path), 0o755); err != nil {
+		return err
+	}
+	if err := vfs.WriteFile(t.path, data, 0o644); err != nil {
+		return err
+	}
+	t.dirty = false
+	return nil
+}
+
+// ServeHTTP serves the usage report as JSON. With a session query
+// parameter, only that session's usage is included.
+func (t *Tracker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	report := t.Report()
+	if key := r.URL.Query().Get("session"); key != "" {
+		counts, ok := report.Sessions[key]
+		if !ok {
+			http.Error(w, "session not found", http.StatusNotFound)
+			return
+		}
+		report.Sessions = map[string]Counts{key: counts}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,109 @@
+package usage
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers/protocoltypes"
+)
+
+func TestTracker_RecordAndCost(t *testing.T) {
+	tr := NewTracker(filepath.Join(t.TempDir(), "usage.json"), map[string]config.ModelPricing{
+		"gpt-5.2": {Input: 2, CachedInput: 0.5, Output: 10},
+		"cheap":   {Input: 1, Output: 1},
+	})
+
+	tr.Record("s1", "gpt-5.2", protocoltypes.UsageInfo{PromptTokens: 1000, CachedTokens: 400, CompletionTokens: 100, TotalTokens: 1100})
+	tr.Record("s1", "openai/cheap", protocoltypes.UsageInfo{PromptTokens: 500, CompletionTokens: 500})
+	tr.Record("s2", "unpriced", protocoltypes.UsageInfo{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15})
+
+	r := tr.Report()
+	// 600 uncached at $2/1K, 400 cached at $0.5/1K, 100 out at $10/1K.
+	if got := r.Models["gpt-5.2"].Cost; math.Abs(got-2.4) > 1e-9 {
+		t.Errorf("gpt-5.2 cost = %v, want 2.4", got)
+	}
+	if got := r.Models["openai/cheap"]; math.Abs(got.Cost-1) > 1e-9 || got.TotalTokens != 1000 {
+		t.Errorf("openai/cheap = %+v, want cost 1 and 1000 tokens", got)
+	}
+	if got := r.Models["unpriced"].Cost; got != 0 {
+		t.Errorf("unpriced cost = %v, want 0", got)
+	}
+	if got := r.Sessions["s1"]; got.Requests != 2 || got.TotalTokens != 2100 || got.CachedTokens != 400 {
+		t.Errorf("session s1 = %+v", got)
+	}
+	if r.Total.Requests != 3 || r.Total.TotalTokens != 2115 || math.Abs(r.Total.Cost-3.4) > 1e-9 {
+		t.Errorf("total = %+v", r.Total)
+	}
+}
+
+func TestTracker_SaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "usage.json")
+	tr := NewTracker(path, nil)
+	tr.Record("s1", "m", protocoltypes.UsageInfo{PromptTokens: 3, CompletionTokens: 2, TotalTokens: 5})
+	if err := tr.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	// A new tracker continues from the saved usage.
+	tr = NewTracker(path, nil)
+	tr.Record("s1", "m", protocoltypes.UsageInfo{PromptTokens: 3, CompletionTokens: 2, TotalTokens: 5})
+	if err := tr.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	r, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if r.Sessions["s1"].TotalTokens != 10 || r.Models["m"].Requests != 2 {
+		t.Errorf("loaded report = %+v", r)
+	}
+}
+
+func TestTracker_ServeHTTP(t *testing.T) {
+	tr := NewTracker(filepath.Join(t.TempDir(), "usage.json"), nil)
+	tr.Record("s1", "m", protocoltypes.UsageInfo{TotalTokens: 5})
+	tr.Record("s2", "m", protocoltypes.UsageInfo{TotalTokens: 7})
+
+	rec := httptest.NewRecorder()
+	tr.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/usage?session=s2", nil))
+	var r Report
+	if err := json.NewDecoder(rec.Body).Decode(&r); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(r.Sessions) != 1 || r.Sessions["s2"].TotalTokens != 7 || r.Total.TotalTokens != 12 {
+		t.Errorf("report = %+v", r)
+	}
+
+	rec = httptest.NewRecorder()
+	tr.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/usage?session=missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", rec.Code)
+	}
+}
+
+func TestTracker_RecordFeedback(t *testing.T) {
+	tr := NewTracker(filepath.Join(t.TempDir(), "usage.json"), nil)
+	tr.RecordFeedback("s1", "m", 1, true)
+	tr.RecordFeedback("s1", "m", 1, true)
+	tr.RecordFeedback("s2", "m", -1, true)
+	tr.RecordFeedback("s1", "m", 1, false)
+	tr.RecordFeedback("s2", "m", -1, false)
+	tr.RecordFeedback("s2", "m", -1, false) // never below zero
+
+	r := tr.Report()
+	if got := r.Sessions["s1"]; got.PositiveFeedback != 1 || got.NegativeFeedback != 0 {
+		t.Errorf("session s1 = %+v", got)
+	}
+	if got := r.Models["m"]; got.PositiveFeedback != 1 || got.NegativeFeedback != 0 {
+		t.Errorf("model m = %+v", got)
+	}
+	if r.Total.PositiveFeedback != 1 || r.Total.NegativeFeedback != 0 {
+		t.Errorf("total = %+v", r.Total)
+	}
+}
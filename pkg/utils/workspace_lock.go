@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WorkspaceLockFile is the name of the lock file created in the workspace.
+const WorkspaceLockFile = ".picoclaw.lock"
+
+// ErrWorkspaceLocked is returned by LockWorkspace when another process
+// already holds the workspace lock.
+var ErrWorkspaceLocked = errors.New("workspace is locked by another picoclaw instance")
+
+// errLockHeld is returned by the platform lockFile implementations when the
+// lock is held elsewhere.
+var errLockHeld = errors.New("lock held")
+
+// WorkspaceLock is an exclusive advisory lock on a workspace directory that
+// keeps two long-running picoclaw processes from writing the same sessions
+// and memory files. The operating system releases it if the process dies.
+type WorkspaceLock struct {
+	file *os.File
+	path string
+}
+
+// LockWorkspace acquires the workspace lock without blocking. If another
+// process holds it, the returned error wraps ErrWorkspaceLocked and names the
+// holder's PID when known.
+func LockWorkspace(workspace string) (*WorkspaceLock, error) {
+	if err := os.MkdirAll(workspace, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+
+	path := filepath.Join(workspace, WorkspaceLockFile)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	if err := lockFile(f); err != nil {
+		f.Close()
+		if errors.Is(err, errLockHeld) {
+			if pid := ReadWorkspaceLockPID(workspace); pid > 0 {
+				return nil, fmt.Errorf("%w (pid %d, lock file %s)", ErrWorkspaceLocked, pid, path)
+			}
+			return nil, fmt.Errorf("%w (lock file %s)", ErrWorkspaceLocked, path)
+		}
+		return nil, fmt.Errorf("failed to lock workspace: %w", err)
+	}
+
+	// Record the holder for diagnostics. The lock itself is the flock, not
+	// the file contents, so a stale file left by a crash is harmless.
+	f.Truncate(0)
+	f.Seek(0, 0)
+	fmt.Fprintf(f, "%d\n%s\n", os.Getpid(), time.Now().Format(time.RFC3339))
+	f.Sync()
+
+	return &WorkspaceLock{file: f, path: path}, nil
+}
+
+// Unlock releases the lock. The lock file is left in place on purpose:
+// removing it could let two processes lock different inodes.
+func (l *WorkspaceLock) Unlock() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	l.file.Truncate(0)
+	err := unlockFile(l.file)
+	l.file.Close()
+	l.file = nil
+	return err
+}
+
+// Path returns the lock file path.
+func (l *WorkspaceLock) Path() string {
+	return l.path
+}
+
+// ReadWorkspaceLockPID returns the PID recorded in the workspace lock file,
+// or 0 if there is none. It does not tell whether the lock is still held.
+func ReadWorkspaceLockPID(workspace string) int {
+	data, err := os.ReadFile(filepath.Join(workspace, WorkspaceLockFile))
+	if err != nil {
+		return 0
+	}
+	line, _, _ := strings.Cut(string(data), "\n")
+	pid, _ := strconv.Atoi(strings.TrimSpace(line))
+	return pid
+}
@@ -0,0 +1,15 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly && !windows
+
+package utils
+
+import "os"
+
+// File locking is not available on this platform; the workspace lock is a
+// no-op and concurrent instances are not detected.
+func lockFile(f *os.File) error {
+	return nil
+}
+
+func unlockFile(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,36 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly || windows
+
+package utils
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestLockWorkspace_SecondLockFails(t *testing.T) {
+	dir := t.TempDir()
+
+	first, err := LockWorkspace(dir)
+	if err != nil {
+		t.Fatalf("first LockWorkspace() error: %v", err)
+	}
+
+	if pid := ReadWorkspaceLockPID(dir); pid != os.Getpid() {
+		t.Errorf("ReadWorkspaceLockPID() = %d, want %d", pid, os.Getpid())
+	}
+
+	if _, err := LockWorkspace(dir); !errors.Is(err, ErrWorkspaceLocked) {
+		t.Fatalf("second LockWorkspace() error = %v, want ErrWorkspaceLocked", err)
+	}
+
+	if err := first.Unlock(); err != nil {
+		t.Fatalf("Unlock() error: %v", err)
+	}
+
+	again, err := LockWorkspace(dir)
+	if err != nil {
+		t.Fatalf("LockWorkspace() after Unlock error: %v", err)
+	}
+	again.Unlock()
+}
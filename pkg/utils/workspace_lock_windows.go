@@ -0,0 +1,46 @@
+//go:build windows
+
+package utils
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	modkernel32      = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx   = modkernel32.NewProc("LockFileEx")
+	procUnlockFileEx = modkernel32.NewProc("UnlockFileEx")
+)
+
+const (
+	lockfileFailImmediately = 0x1
+	lockfileExclusiveLock   = 0x2
+	errorLockViolation      = syscall.Errno(33)
+)
+
+// The lock covers a single byte far past the end of the file so the PID
+// written at the start stays readable by other processes.
+const lockOffsetHigh = 0x7fffffff
+
+func lockFile(f *os.File) error {
+	ol := syscall.Overlapped{OffsetHigh: lockOffsetHigh}
+	r1, _, err := procLockFileEx.Call(f.Fd(), lockfileExclusiveLock|lockfileFailImmediately, 0, 1, 0, uintptr(unsafe.Pointer(&ol)))
+	if r1 != 0 {
+		return nil
+	}
+	if err == errorLockViolation {
+		return errLockHeld
+	}
+	return err
+}
+
+func unlockFile(f *os.File) error {
+	ol := syscall.Overlapped{OffsetHigh: lockOffsetHigh}
+	r1, _, err := procUnlockFileEx.Call(f.Fd(), 0, 1, 0, uintptr(unsafe.Pointer(&ol)))
+	if r1 != 0 {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,310 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package vfs routes workspace file access to a storage backend, so that
+// persistent state can live somewhere other than the local disk. A
+// backend is mounted at a directory with Mount; ReadFile, WriteFile and
+// the other functions then send paths inside it to the backend, and all
+// other paths to the local filesystem.
+//
+// Files are sealed and opened with atrest on the way, so encryption at
+// rest works the same on every backend.
+package vfs
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sipeed/picoclaw/pkg/atrest"
+)
+
+// FS is a storage backend. Names are slash-separated paths relative to
+// where it is mounted, "." being the mount point itself. Missing files
+// are reported with errors matching fs.ErrNotExist.
+type FS interface {
+	ReadFile(name string) ([]byte, error)
+	// WriteFile replaces the file as a whole: readers see either its old
+	// or its new content.
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+	ReadDir(name string) ([]fs.DirEntry, error)
+	Stat(name string) (fs.FileInfo, error)
+	MkdirAll(name string, perm fs.FileMode) error
+	Remove(name string) error
+}
+
+// appender is implemented by backends that can append to a file without
+// rewriting it.
+type appender interface {
+	AppendFile(name string, data []byte, perm fs.FileMode) error
+}
+
+type mount struct {
+	root string
+	fsys FS
+}
+
+var (
+	mu     sync.RWMutex
+	mounts []mount
+	// local serves paths outside any mount, by their absolute path.
+	local FS = Dir("")
+)
+
+// Mount sends access to paths inside dir to fsys. Mounting the same dir
+// again replaces its backend; nested mounts take precedence over the
+// ones they are in.
+func Mount(dir string, fsys FS) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for i, m := range mounts {
+		if m.root == abs {
+			mounts[i].fsys = fsys
+			return nil
+		}
+	}
+	mounts = append(mounts, mount{root: abs, fsys: fsys})
+	// Longest first, so the innermost mount wins.
+	sort.Slice(mounts, func(i, j int) bool { return len(mounts[i].root) > len(mounts[j].root) })
+	return nil
+}
+
+// Unmount removes the backend mounted at dir.
+func Unmount(dir string) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for i, m := range mounts {
+		if m.root == abs {
+			mounts = append(mounts[:i], mounts[i+1:]...)
+			return
+		}
+	}
+}
+
+// Mounted reports whether path is on a mounted backend rather than the
+// local filesystem.
+func Mounted(path string) bool {
+	_, _, ok := lookup(path)
+	return ok
+}
+
+// lookup returns the backend of path and the name of path on it.
+func lookup(path string) (FS, string, bool) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return local, filepath.ToSlash(path), false
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, m := range mounts {
+		rel, err := filepath.Rel(m.root, abs)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return m.fsys, filepath.ToSlash(rel), true
+		}
+	}
+	return local, filepath.ToSlash(abs), false
+}
+
+// ReadFile reads path, opening it if sealed.
+func ReadFile(path string) ([]byte, error) {
+	fsys, name, _ := lookup(path)
+	data, err := fsys.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := atrest.Decode(data)
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: path, Err: err}
+	}
+	return plain, nil
+}
+
+// WriteFile replaces path with data, sealed if path is protected.
+func WriteFile(path string, data []byte, perm fs.FileMode) error {
+	out, err := atrest.Encode(path, data)
+	if err != nil {
+		return err
+	}
+	fsys, name, _ := lookup(path)
+	return fsys.WriteFile(name, out, perm)
+}
+
+// AppendFile appends data to path, creating it if needed. Sealed files
+// and backends that cannot append are rewritten as a whole.
+func AppendFile(path string, data []byte, perm fs.FileMode) error {
+	fsys, name, _ := lookup(path)
+	if a, ok := fsys.(appender); ok && !(atrest.Enabled() && atrest.Protected(path)) {
+		return a.AppendFile(name, data, perm)
+	}
+	existing, err := ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return WriteFile(path, append(existing, data...), perm)
+}
+
+// ReadDir lists the directory at path, sorted by name.
+func ReadDir(path string) ([]fs.DirEntry, error) {
+	fsys, name, _ := lookup(path)
+	return fsys.ReadDir(name)
+}
+
+// WalkDir walks the tree at root like filepath.WalkDir, calling fn for
+// each file and directory in it, in lexical order.
+func WalkDir(root string, fn fs.WalkDirFunc) error {
+	info, err := Stat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkDir(root, fs.FileInfoToDirEntry(info), fn)
+	}
+	if err == fs.SkipDir || err == fs.SkipAll {
+		return nil
+	}
+	return err
+}
+
+func walkDir(path string, d fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := fn(path, d, nil); err != nil || !d.IsDir() {
+		if err == fs.SkipDir && d.IsDir() {
+			err = nil
+		}
+		return err
+	}
+	entries, err := ReadDir(path)
+	if err != nil {
+		if err = fn(path, d, err); err != nil {
+			if err == fs.SkipDir {
+				err = nil
+			}
+			return err
+		}
+	}
+	for _, e := range entries {
+		if err := walkDir(filepath.Join(path, e.Name()), e, fn); err != nil {
+			if err == fs.SkipDir {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func Stat(path string) (fs.FileInfo, error) {
+	fsys, name, _ := lookup(path)
+	return fsys.Stat(name)
+}
+
+func MkdirAll(path string, perm fs.FileMode) error {
+	fsys, name, _ := lookup(path)
+	return fsys.MkdirAll(name, perm)
+}
+
+func Remove(path string) error {
+	fsys, name, _ := lookup(path)
+	return fsys.Remove(name)
+}
+
+// dirFS is a backend in a local directory.
+type dirFS struct {
+	root string
+}
+
+// Dir returns a backend storing files in the local directory root, such as
+// a network share mounted by the operating system.
+func Dir(root string) FS {
+	return &dirFS{root: root}
+}
+
+func (d *dirFS) path(name string) string {
+	return filepath.Join(d.root, filepath.FromSlash(name))
+}
+
+func (d *dirFS) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(d.path(name))
+}
+
+// WriteFile writes to a temporary file that replaces the old one once
+// complete, keeping the old file's permissions.
+func (d *dirFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	path := d.path(name)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	cleanup := true
+	defer func() {
+		if cleanup {
+			_ = os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	cleanup = false
+	return nil
+}
+
+func (d *dirFS) AppendFile(name string, data []byte, perm fs.FileMode) error {
+	f, err := os.OpenFile(d.path(name), os.O_APPEND|os.O_CREATE|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (d *dirFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(d.path(name))
+}
+
+func (d *dirFS) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(d.path(name))
+}
+
+func (d *dirFS) MkdirAll(name string, perm fs.FileMode) error {
+	return os.MkdirAll(d.path(name), perm)
+}
+
+func (d *dirFS) Remove(name string) error {
+	return os.Remove(d.path(name))
+}
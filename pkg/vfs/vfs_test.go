@@ -0,0 +1,201 @@
+package vfs
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/atrest"
+)
+
+// memFS is a backend holding files in memory.
+type memFS struct {
+	files map[string][]byte
+}
+
+func (m *memFS) ReadFile(name string) ([]byte, error) {
+	data, ok := m.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+	return data, nil
+}
+
+func (m *memFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	m.files[name] = append([]byte(nil), data...)
+	return nil
+}
+
+func (m *memFS) ReadDir(name string) ([]fs.DirEntry, error)   { return nil, nil }
+func (m *memFS) Stat(name string) (fs.FileInfo, error)        { return nil, fs.ErrNotExist }
+func (m *memFS) MkdirAll(name string, perm fs.FileMode) error { return nil }
+func (m *memFS) Remove(name string) error                     { delete(m.files, name); return nil }
+
+func TestMount_RoutesPathsInside(t *testing.T) {
+	dir := t.TempDir()
+	workspace := filepath.Join(dir, "workspace")
+	remote := &memFS{files: map[string][]byte{}}
+	if err := Mount(workspace, remote); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { Unmount(workspace) })
+
+	if err := WriteFile(filepath.Join(workspace, "memory", "MEMORY.md"), []byte("likes tea"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(remote.files["memory/MEMORY.md"]); got != "likes tea" {
+		t.Errorf("backend file = %q, want %q", got, "likes tea")
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "memory", "MEMORY.md")); !errors.Is(err, fs.ErrNotExist) {
+		t.Error("mounted write reached the local disk")
+	}
+
+	// A sibling sharing the prefix is not inside the mount.
+	sibling := filepath.Join(dir, "workspace2", "notes.txt")
+	if Mounted(sibling) {
+		t.Errorf("Mounted(%q) = true", sibling)
+	}
+	if err := MkdirAll(filepath.Dir(sibling), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFile(sibling, []byte("local"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(sibling); string(got) != "local" {
+		t.Errorf("local file = %q, want %q", got, "local")
+	}
+
+	Unmount(workspace)
+	if Mounted(filepath.Join(workspace, "memory")) {
+		t.Error("path still mounted after Unmount")
+	}
+}
+
+func TestWalkDir(t *testing.T) {
+	dir := t.TempDir()
+	workspace := filepath.Join(dir, "workspace")
+	if err := Mount(workspace, Dir(filepath.Join(dir, "remote"))); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { Unmount(workspace) })
+
+	for _, name := range []string{"skills/b/SKILL.md", "skills/a/SKILL.md", "skills/a/notes/x.md", "skills/c/SKILL.md"} {
+		path := filepath.Join(workspace, filepath.FromSlash(name))
+		if err := MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	root := filepath.Join(workspace, "skills")
+	err := WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == "notes" || d.Name() == "c" {
+			return fs.SkipDir
+		}
+		rel, _ := filepath.Rel(root, path)
+		got = append(got, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkDir() error: %v", err)
+	}
+	want := []string{".", "a", "a/SKILL.md", "b", "b/SKILL.md"}
+	if len(got) != len(want) {
+		t.Fatalf("WalkDir() visited %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("WalkDir() visited %v, want %v", got, want)
+		}
+	}
+
+	if err := WalkDir(filepath.Join(workspace, "missing"), func(path string, d fs.DirEntry, err error) error {
+		return err
+	}); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("WalkDir() of a missing directory = %v, want not exist", err)
+	}
+}
+
+func TestWriteFile_ReplacesAndKeepsMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := WriteFile(path, []byte("first"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFile(path, []byte("second"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadFile(path)
+	if err != nil || string(got) != "second" {
+		t.Errorf("ReadFile() = %q, %v", got, err)
+	}
+	info, _ := os.Stat(path)
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("directory has %d entries, want only the file", len(entries))
+	}
+}
+
+func TestAppendFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.txt")
+	for _, s := range []string{"one\n", "two\n"} {
+		if err := AppendFile(path, []byte(s), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, _ := ReadFile(path); string(got) != "one\ntwo\n" {
+		t.Errorf("local append = %q", got)
+	}
+
+	workspace := t.TempDir()
+	remote := &memFS{files: map[string][]byte{}}
+	Mount(workspace, remote)
+	t.Cleanup(func() { Unmount(workspace) })
+	for _, s := range []string{"one\n", "two\n"} {
+		if err := AppendFile(filepath.Join(workspace, "log.txt"), []byte(s), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := string(remote.files["log.txt"]); got != "one\ntwo\n" {
+		t.Errorf("append without appender = %q", got)
+	}
+}
+
+func TestWriteFile_SealsProtectedPaths(t *testing.T) {
+	if err := atrest.SetKey(bytes.Repeat([]byte{7}, atrest.KeySize)); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { atrest.SetKey(nil) })
+
+	workspace := t.TempDir()
+	remote := &memFS{files: map[string][]byte{}}
+	Mount(workspace, remote)
+	t.Cleanup(func() { Unmount(workspace) })
+	atrest.Protect(filepath.Join(workspace, "memory"))
+
+	path := filepath.Join(workspace, "memory", "MEMORY.md")
+	if err := WriteFile(path, []byte("likes tea"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := AppendFile(path, []byte(", not coffee"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	raw := remote.files["memory/MEMORY.md"]
+	if !atrest.IsSealed(raw) || bytes.Contains(raw, []byte("tea")) {
+		t.Fatalf("backend file not sealed: %q", raw)
+	}
+	if got, err := ReadFile(path); err != nil || string(got) != "likes tea, not coffee" {
+		t.Errorf("ReadFile() = %q, %v", got, err)
+	}
+}
@@ -0,0 +1,267 @@
+package vfs
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/egress"
+)
+
+// webdavFS is a backend on a WebDAV server, such as Nextcloud or
+// `rclone serve webdav` in front of S3 or other remote storage.
+type webdavFS struct {
+	base     *url.URL
+	username string
+	password string
+	client   *http.Client
+}
+
+// WebDAV returns a backend storing files under baseURL on a WebDAV
+// server, authenticating with username and password if set.
+func WebDAV(baseURL, username, password string) (FS, error) {
+	u, err := url.Parse(strings.TrimRight(baseURL, "/") + "/")
+	if err != nil {
+		return nil, fmt.Errorf("invalid WebDAV URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid WebDAV URL %q: scheme must be http or https", baseURL)
+	}
+	return &webdavFS{
+		base:     u,
+		username: username,
+		password: password,
+		client: &http.Client{
+			Timeout:   60 * time.Second,
+			Transport: egress.Transport(egress.Storage, nil),
+		},
+	}, nil
+}
+
+// url returns the URL of name. A trailing slash, which names a
+// collection, is kept.
+func (w *webdavFS) url(name string) string {
+	u := *w.base
+	u.Path = path.Join(w.base.Path, path.Clean("/"+name))
+	if strings.HasSuffix(name, "/") || path.Clean("/"+name) == "/" {
+		u.Path += "/"
+	}
+	return u.String()
+}
+
+func (w *webdavFS) do(method, name string, body []byte, header map[string]string) (*http.Response, error) {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, w.url(name), r)
+	if err != nil {
+		return nil, err
+	}
+	if w.username != "" || w.password != "" {
+		req.SetBasicAuth(w.username, w.password)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	return w.client.Do(req)
+}
+
+// statusError converts an unexpected response to an error, matching
+// fs.ErrNotExist for 404.
+func statusError(op, name string, resp *http.Response) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return &fs.PathError{Op: op, Path: name, Err: fmt.Errorf("WebDAV server returned %s: %s", resp.Status, bytes.TrimSpace(body))}
+}
+
+func (w *webdavFS) ReadFile(name string) ([]byte, error) {
+	resp, err := w.do("GET", name, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError("read", name, resp)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// WriteFile uploads the file with a single PUT, which servers store as a
+// whole. Missing parent directories are created.
+func (w *webdavFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	for attempt := 0; ; attempt++ {
+		resp, err := w.do("PUT", name, data, nil)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		switch {
+		case resp.StatusCode >= 200 && resp.StatusCode < 300:
+			return nil
+		case resp.StatusCode == http.StatusConflict && attempt == 0:
+			if err := w.MkdirAll(path.Dir(name), perm); err != nil {
+				return err
+			}
+		default:
+			return statusError("write", name, resp)
+		}
+	}
+}
+
+func (w *webdavFS) MkdirAll(name string, perm fs.FileMode) error {
+	name = strings.Trim(path.Clean("/"+name), "/")
+	if name == "" {
+		return nil
+	}
+	dir := ""
+	for _, part := range strings.Split(name, "/") {
+		dir = path.Join(dir, part)
+		resp, err := w.do("MKCOL", dir+"/", nil, nil)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		// 405 means it exists already.
+		if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusMethodNotAllowed {
+			return statusError("mkdir", dir, resp)
+		}
+	}
+	return nil
+}
+
+func (w *webdavFS) Remove(name string) error {
+	resp, err := w.do("DELETE", name, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return statusError("remove", name, resp)
+	}
+	return nil
+}
+
+func (w *webdavFS) Stat(name string) (fs.FileInfo, error) {
+	infos, err := w.propfind(name, "0")
+	if err != nil {
+		return nil, err
+	}
+	if len(infos) == 0 {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	info := infos[0]
+	info.name = path.Base(path.Clean("/" + name))
+	return info, nil
+}
+
+func (w *webdavFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	infos, err := w.propfind(name, "1")
+	if err != nil {
+		return nil, err
+	}
+	self := strings.TrimSuffix(w.url(name), "/")
+	entries := make([]fs.DirEntry, 0, len(infos))
+	for _, info := range infos {
+		if strings.TrimSuffix(info.href, "/") == self {
+			continue
+		}
+		entries = append(entries, fs.FileInfoToDirEntry(info))
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:"><d:prop><d:resourcetype/><d:getcontentlength/><d:getlastmodified/></d:prop></d:propfind>`
+
+type multistatus struct {
+	Responses []struct {
+		Href     string `xml:"href"`
+		Propstat []struct {
+			Prop struct {
+				ResourceType struct {
+					Collection *struct{} `xml:"collection"`
+				} `xml:"resourcetype"`
+				ContentLength string `xml:"getcontentlength"`
+				LastModified  string `xml:"getlastmodified"`
+			} `xml:"prop"`
+			Status string `xml:"status"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+// propfind returns the properties of name and, at depth 1, of the
+// entries of the directory it names.
+func (w *webdavFS) propfind(name, depth string) ([]*webdavInfo, error) {
+	resp, err := w.do("PROPFIND", name, []byte(propfindBody), map[string]string{
+		"Depth":        depth,
+		"Content-Type": "application/xml; charset=utf-8",
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, statusError("stat", name, resp)
+	}
+
+	var ms multistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("invalid WebDAV response: %w", err)
+	}
+	infos := make([]*webdavInfo, 0, len(ms.Responses))
+	for _, r := range ms.Responses {
+		href, err := w.base.Parse(r.Href)
+		if err != nil {
+			continue
+		}
+		info := &webdavInfo{href: href.String()}
+		if unescaped, err := url.PathUnescape(path.Base(strings.TrimSuffix(href.Path, "/"))); err == nil {
+			info.name = unescaped
+		}
+		for _, ps := range r.Propstat {
+			if !strings.Contains(ps.Status, " 200 ") {
+				continue
+			}
+			info.dir = ps.Prop.ResourceType.Collection != nil
+			info.size, _ = strconv.ParseInt(ps.Prop.ContentLength, 10, 64)
+			info.modTime, _ = http.ParseTime(ps.Prop.LastModified)
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// webdavInfo describes a file or directory on a WebDAV server.
+type webdavInfo struct {
+	href    string
+	name    string
+	dir     bool
+	size    int64
+	modTime time.Time
+}
+
+func (i *webdavInfo) Name() string       { return i.name }
+func (i *webdavInfo) Size() int64        { return i.size }
+func (i *webdavInfo) ModTime() time.Time { return i.modTime }
+func (i *webdavInfo) IsDir() bool        { return i.dir }
+func (i *webdavInfo) Sys() interface{}   { return nil }
+
+func (i *webdavInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0755
+	}
+	return 0644
+}
@@ -0,0 +1,174 @@
+package vfs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// davServer is a minimal in-memory WebDAV server. Directories are keys
+// ending in "/".
+type davServer struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+func newDAVServer(t *testing.T) (*davServer, *httptest.Server) {
+	s := &davServer{files: map[string][]byte{"/dav/": nil}}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	return s, srv
+}
+
+func (s *davServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user, pass, _ := r.BasicAuth(); user != "pico" || pass != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	p := r.URL.Path
+	parent := path.Dir(strings.TrimSuffix(p, "/")) + "/"
+	switch r.Method {
+	case "GET":
+		data, ok := s.files[p]
+		if !ok || strings.HasSuffix(p, "/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case "PUT":
+		if _, ok := s.files[parent]; !ok {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		s.files[p], _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+	case "MKCOL":
+		if _, ok := s.files[p]; ok {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if _, ok := s.files[parent]; !ok {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		s.files[p] = nil
+		w.WriteHeader(http.StatusCreated)
+	case "DELETE":
+		if _, ok := s.files[p]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(s.files, p)
+		w.WriteHeader(http.StatusNoContent)
+	case "PROPFIND":
+		dir := strings.TrimSuffix(p, "/") + "/"
+		var matches []string
+		if _, ok := s.files[p]; ok {
+			matches = append(matches, p)
+		} else if _, ok := s.files[dir]; ok {
+			matches = append(matches, dir)
+		} else {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Depth") == "1" && strings.HasSuffix(matches[0], "/") {
+			for name := range s.files {
+				rest := strings.TrimPrefix(name, dir)
+				if name != dir && strings.HasPrefix(name, dir) && !strings.Contains(strings.TrimSuffix(rest, "/"), "/") {
+					matches = append(matches, name)
+				}
+			}
+		}
+		w.WriteHeader(http.StatusMultiStatus)
+		fmt.Fprint(w, `<?xml version="1.0"?><d:multistatus xmlns:d="DAV:">`)
+		for _, name := range matches {
+			prop := fmt.Sprintf("<d:resourcetype/><d:getcontentlength>%d</d:getcontentlength>", len(s.files[name]))
+			if strings.HasSuffix(name, "/") {
+				prop = "<d:resourcetype><d:collection/></d:resourcetype>"
+			}
+			fmt.Fprintf(w, `<d:response><d:href>%s</d:href><d:propstat><d:prop>%s<d:getlastmodified>Mon, 12 Jan 2026 10:00:00 GMT</d:getlastmodified></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`, name, prop)
+		}
+		fmt.Fprint(w, `</d:multistatus>`)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestWebDAV(t *testing.T) {
+	server, srv := newDAVServer(t)
+	fsys, err := WebDAV(srv.URL+"/dav", "pico", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := fsys.ReadFile("memory/MEMORY.md"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ReadFile(missing) error = %v, want fs.ErrNotExist", err)
+	}
+
+	// Parent directories are created on demand.
+	if err := fsys.WriteFile("memory/202601/20260112.md", []byte("walked the dog"), 0644); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	if got := string(server.files["/dav/memory/202601/20260112.md"]); got != "walked the dog" {
+		t.Errorf("server file = %q", got)
+	}
+	if got, err := fsys.ReadFile("memory/202601/20260112.md"); err != nil || string(got) != "walked the dog" {
+		t.Errorf("ReadFile() = %q, %v", got, err)
+	}
+	if err := fsys.WriteFile("memory/MEMORY.md", []byte("likes tea"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := fsys.ReadDir("memory")
+	if err != nil {
+		t.Fatalf("ReadDir() error: %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, fmt.Sprintf("%s:%v", e.Name(), e.IsDir()))
+	}
+	if got := strings.Join(names, " "); got != "202601:true MEMORY.md:false" {
+		t.Errorf("ReadDir() = %s", got)
+	}
+
+	info, err := fsys.Stat("memory/MEMORY.md")
+	if err != nil {
+		t.Fatalf("Stat() error: %v", err)
+	}
+	if info.Name() != "MEMORY.md" || info.Size() != 9 || info.IsDir() || info.ModTime().IsZero() {
+		t.Errorf("Stat() = %s size %d dir %v modified %v", info.Name(), info.Size(), info.IsDir(), info.ModTime())
+	}
+
+	if err := fsys.MkdirAll("memory/202601", 0755); err != nil {
+		t.Errorf("MkdirAll(existing) error: %v", err)
+	}
+	if err := fsys.Remove("memory/MEMORY.md"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fsys.Stat("memory/MEMORY.md"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat(removed) error = %v, want fs.ErrNotExist", err)
+	}
+}
+
+func TestWebDAV_Errors(t *testing.T) {
+	_, srv := newDAVServer(t)
+	fsys, _ := WebDAV(srv.URL+"/dav", "pico", "wrong")
+	err := fsys.WriteFile("notes.txt", []byte("x"), 0644)
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("WriteFile() with bad password error = %v, want 401", err)
+	}
+
+	if _, err := WebDAV("ftp://example.com/dav", "", ""); err == nil {
+		t.Error("WebDAV() accepted an ftp URL")
+	}
+}